@@ -0,0 +1,170 @@
+// Command doctor checks that the server can start and serve requests with the
+// current configuration, and prints what to fix when it cannot:
+//
+//	go run ./cmd/doctor
+//
+// It validates the configuration, connects to MongoDB, looks for missing
+// indexes and makes one OMDb request (counted against the API key's daily
+// limit). The configuration is loaded like the server's, so the same
+// environment variables, .env and CONFIG_FILE apply. It exits with status 1
+// when a check fails; warnings do not fail it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/config"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// omdbCheckID is the movie requested to check the OMDb API key
+const omdbCheckID = "tt0111161"
+
+type status string
+
+const (
+	statusOK   status = "ok"
+	statusWarn status = "warn"
+	statusFail status = "FAIL"
+	statusSkip status = "skip"
+)
+
+type report struct {
+	failed bool
+}
+
+// print writes one line of the report, followed by hint on the next line
+// when the check did not pass
+func (r *report) print(check string, result status, detail, hint string) {
+	fmt.Printf("%-9s %-5s %s\n", check, result, detail)
+	if hint != "" && result != statusOK {
+		fmt.Printf("%-15s %s\n", "", hint)
+	}
+	if result == statusFail {
+		r.failed = true
+	}
+}
+
+func main() {
+	// The server's .env is optional here too
+	_ = godotenv.Load()
+
+	r := &report{}
+	cfg, err := config.Load()
+	if err != nil {
+		r.print("config", statusFail, err.Error(), "fix the settings above in the environment, .env or CONFIG_FILE")
+		r.print("mongodb", statusSkip, "configuration is invalid", "")
+		r.print("indexes", statusSkip, "configuration is invalid", "")
+		r.print("omdb", statusSkip, "configuration is invalid", "")
+		os.Exit(1)
+	}
+	r.print("config", statusOK, fmt.Sprintf("APP_ENV=%s PORT=%s TLS=%t", cfg.Environment, cfg.Port, cfg.TLSEnabled()), "")
+
+	db := checkDatabase(r, cfg)
+	if db != nil {
+		checkIndexes(r, db)
+		db.Close()
+	} else {
+		r.print("indexes", statusSkip, "no database connection", "")
+	}
+	checkOMDb(r, cfg)
+
+	if r.failed {
+		os.Exit(1)
+	}
+}
+
+// checkDatabase connects to DATABASE_URL without creating indexes, so that
+// checkIndexes sees the database as the server left it
+func checkDatabase(r *report, cfg *config.Config) *database.MongoDB {
+	host := "DATABASE_URL"
+	if u, err := url.Parse(cfg.DatabaseURL); err == nil {
+		host = u.Host
+	}
+
+	start := time.Now()
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		r.print("mongodb", statusFail, err.Error(), "check that MongoDB is running and reachable at "+host+" and that DATABASE_URL has the right credentials")
+		return nil
+	}
+	r.print("mongodb", statusOK, fmt.Sprintf("connected to %s in %s", host, time.Since(start).Round(time.Millisecond)), "")
+	return db
+}
+
+func checkIndexes(r *report, db *database.MongoDB) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	missing, err := db.MissingIndexes(ctx)
+	switch {
+	case err != nil:
+		r.print("indexes", statusFail, err.Error(), "the database user needs the listIndexes permission")
+	case len(missing) > 0:
+		r.print("indexes", statusFail, "missing "+strings.Join(missing, ", "),
+			`the server creates indexes on startup; if one stays missing, its "failed to create indexes" warning names the cause, usually duplicate values under a unique index`)
+	default:
+		r.print("indexes", statusOK, "all indexes exist", "")
+	}
+}
+
+// checkOMDb requests a known movie with OMDB_API_KEY, the way the server looks
+// up movie details
+func checkOMDb(r *report, cfg *config.Config) {
+	if cfg.OMDbFixtures == services.OMDbFixturesReplay {
+		r.print("omdb", statusSkip, "OMDB_FIXTURES=replay answers from "+cfg.OMDbFixturesDir, "")
+		return
+	}
+	if cfg.OMDbAPIKey == "" {
+		r.print("omdb", statusWarn, "OMDB_API_KEY is not set", "searches only find cached movies; get a key at https://www.omdbapi.com/apikey.aspx")
+		return
+	}
+
+	requestURL := fmt.Sprintf("%s/?apikey=%s&i=%s", cfg.OMDbBaseURL, url.QueryEscape(cfg.OMDbAPIKey), omdbCheckID)
+	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(requestURL)
+	if err != nil {
+		// The error repeats the URL, which holds the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		r.print("omdb", statusFail, "request failed: "+err.Error(), "check outbound access to "+cfg.OMDbBaseURL+" and OMDB_BASE_URL")
+		return
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var body struct {
+		Title    string `json:"Title"`
+		Response string `json:"Response"`
+		Error    string `json:"Error"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && strings.Contains(body.Error, "limit"):
+		r.print("omdb", statusWarn, "daily request limit reached", "the key works again tomorrow; lower OMDB_DAILY_LIMIT to match the key's plan")
+	case resp.StatusCode == http.StatusUnauthorized:
+		r.print("omdb", statusFail, "API key rejected: "+body.Error, "check OMDB_API_KEY; new keys must be activated from the email OMDb sends")
+	case resp.StatusCode != http.StatusOK:
+		r.print("omdb", statusFail, fmt.Sprintf("status code %d", resp.StatusCode), "check OMDB_BASE_URL")
+	case decodeErr != nil:
+		r.print("omdb", statusFail, "unreadable response: "+decodeErr.Error(), "check that OMDB_BASE_URL points at the OMDb API")
+	case body.Response == "False":
+		r.print("omdb", statusFail, "OMDb error: "+body.Error, "check OMDB_API_KEY")
+	default:
+		r.print("omdb", statusOK, fmt.Sprintf("fetched %q in %s", body.Title, elapsed), "")
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"movie-watchlist/internal/middleware"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// benchResult is the outcome of one request; status is 0 when the request
+// failed before a response arrived
+type benchResult struct {
+	status  int
+	latency time.Duration
+}
+
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	baseURL := flags.String("url", "http://localhost:8080", "base URL of the running server")
+	path := flags.String("path", "/api/v1/recommendations", "endpoint to request, relative to -url")
+	requests := flags.Int("requests", 1000, "total number of requests")
+	concurrency := flags.Int("concurrency", 10, "number of requests in flight at once")
+	sample := flags.Int("users", 100, "number of generated users to spread requests across")
+	warmup := flags.Int("warmup", 0, "requests sent before measuring, e.g. to fill caches")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a single request")
+	flags.Parse(args)
+
+	if *requests < 1 || *concurrency < 1 || *sample < 1 {
+		return fmt.Errorf("-requests, -concurrency and -users must be at least 1")
+	}
+
+	cfg, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Requests are signed for generated users directly, since logging each
+	// one in would measure bcrypt rather than the endpoint
+	userIDs, err := sampleUsers(db.GetCollection("users"), *sample)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return fmt.Errorf("no generated users found; run loadgen generate first")
+	}
+	tokenConfig := middleware.TokenConfig{
+		Secret:   cfg.JWTSecret,
+		TTL:      time.Duration(cfg.JWTAccessTTLMinutes) * time.Minute,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+	}
+	tokens := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		token, err := middleware.GenerateToken(id, tokenConfig)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, token)
+	}
+
+	client := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+	}
+	url := strings.TrimRight(*baseURL, "/") + *path
+
+	if *warmup > 0 {
+		run(client, url, tokens, *warmup, *concurrency)
+	}
+	started := time.Now()
+	results := run(client, url, tokens, *requests, *concurrency)
+	report(url, results, time.Since(started), len(tokens), *concurrency)
+	return nil
+}
+
+// sampleUsers returns up to limit generated users picked at random
+func sampleUsers(collection *mongo.Collection, limit int) ([]primitive.ObjectID, error) {
+	ctx := context.Background()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"username": bson.M{"$regex": "^" + usernamePrefix}}},
+		{"$sample": bson.M{"size": limit}},
+		{"$project": bson.M{"_id": 1}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// run sends count GET requests to url from concurrency workers, cycling
+// through tokens so requests are spread across users
+func run(client *http.Client, url string, tokens []string, count, concurrency int) []benchResult {
+	results := make([]benchResult, count)
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= count {
+					return
+				}
+				results[i] = send(client, url, tokens[i%len(tokens)])
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func send(client *http.Client, url, token string) benchResult {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return benchResult{}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return benchResult{latency: time.Since(started)}
+	}
+	// Reading the whole body keeps the connection reusable and counts the
+	// transfer in the latency
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return benchResult{status: resp.StatusCode, latency: time.Since(started)}
+}
+
+// report prints throughput, status counts and latency percentiles. Failed
+// requests count towards the latencies too.
+func report(url string, results []benchResult, elapsed time.Duration, users, concurrency int) {
+	statuses := map[int]int{}
+	latencies := make([]time.Duration, 0, len(results))
+	failed := 0
+	for _, result := range results {
+		statuses[result.status]++
+		latencies = append(latencies, result.latency)
+		if result.status < 200 || result.status >= 300 {
+			failed++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("endpoint:     GET %s\n", url)
+	fmt.Printf("requests:     %d (%d users, concurrency %d)\n", len(results), users, concurrency)
+	fmt.Printf("duration:     %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("throughput:   %.1f req/s\n", float64(len(results))/elapsed.Seconds())
+	fmt.Printf("failed:       %d\n", failed)
+
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		label := strconv.Itoa(code)
+		if code == 0 {
+			label = "no response"
+		}
+		fmt.Printf("  %-11s %d\n", label, statuses[code])
+	}
+
+	fmt.Printf("latency p50:  %s\n", percentile(latencies, 50))
+	fmt.Printf("latency p90:  %s\n", percentile(latencies, 90))
+	fmt.Printf("latency p99:  %s\n", percentile(latencies, 99))
+	fmt.Printf("latency max:  %s\n", latencies[len(latencies)-1].Round(time.Microsecond))
+}
+
+// percentile returns the p-th percentile of sorted latencies by the
+// nearest-rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Microsecond)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"movie-watchlist/internal/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func runClean(args []string) error {
+	flags := flag.NewFlagSet("clean", flag.ExitOnError)
+	flags.Parse(args)
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users, err := deleteGenerated(db, "users", bson.M{"username": bson.M{"$regex": "^" + usernamePrefix}}, "user_id")
+	if err != nil {
+		return err
+	}
+	movies, err := deleteGenerated(db, "movies", bson.M{"source": movieSource}, "movie_id")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("deleted %d users and %d movies with their data\n", users, movies)
+	return nil
+}
+
+// deleteGenerated deletes the documents of collection matching filter in
+// batches, first removing the documents that reference them by field from
+// every other collection. That covers what the server wrote for generated
+// users during a benchmark, such as snapshots and activity, without keeping
+// a list of collections here.
+func deleteGenerated(db *database.MongoDB, collection string, filter bson.M, field string) (int, error) {
+	ctx := context.Background()
+
+	names, err := db.Database.ListCollectionNames(ctx, bson.M{"name": bson.M{"$ne": collection}})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for {
+		cursor, err := db.GetCollection(collection).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(insertBatch))
+		if err != nil {
+			return deleted, err
+		}
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &docs); err != nil {
+			return deleted, err
+		}
+		if len(docs) == 0 {
+			return deleted, nil
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(docs))
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		for _, name := range names {
+			if _, err := db.GetCollection(name).DeleteMany(ctx, bson.M{field: bson.M{"$in": ids}}); err != nil {
+				return deleted, fmt.Errorf("failed to clean %s: %w", name, err)
+			}
+		}
+		result, err := db.GetCollection(collection).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int(result.DeletedCount)
+	}
+}
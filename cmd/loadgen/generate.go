@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// usernamePrefix and movieSource mark generated documents for clean
+	usernamePrefix = "loadgen_"
+	movieSource    = "loadgen"
+	// userPassword is the password of every generated user, for trying the
+	// API by hand
+	userPassword = "loadgen-password"
+	// insertBatch is the number of documents sent in one InsertMany
+	insertBatch = 1000
+	// history is how far back generated activity reaches
+	history = 365 * 24 * time.Hour
+)
+
+var (
+	genres = []string{
+		"Action", "Adventure", "Animation", "Biography", "Comedy", "Crime", "Documentary", "Drama",
+		"Family", "Fantasy", "History", "Horror", "Music", "Mystery", "Romance", "Sci-Fi", "Sport",
+		"Thriller", "War", "Western",
+	}
+	titleAdjectives = []string{
+		"Silent", "Last", "Broken", "Golden", "Hidden", "Endless", "Crimson", "Lost", "Distant", "Frozen",
+		"Savage", "Quiet", "Burning", "Electric", "Hollow", "Midnight", "Restless", "Wild", "Secret", "Fallen",
+	}
+	titleNouns = []string{
+		"River", "Empire", "Garden", "Signal", "Harbor", "Kingdom", "Shadow", "Voyage", "Machine", "Frontier",
+		"Letter", "Orchard", "Station", "Storm", "Promise", "Island", "Mirror", "Engine", "Winter", "Crown",
+	}
+	ratedLevels = []string{"G", "PG", "PG-13", "PG-13", "R", "R", "Not Rated"}
+)
+
+type generateOptions struct {
+	users          int
+	movies         int
+	ratingsMean    float64
+	watchlistsMean float64
+	seed           int64
+}
+
+// generatedMovie keeps what the rating model needs about each movie
+type generatedMovie struct {
+	id      primitive.ObjectID
+	genres  []string
+	quality float64
+}
+
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	opts := generateOptions{}
+	flags.IntVar(&opts.users, "users", 1000, "number of users to create")
+	flags.IntVar(&opts.movies, "movies", 5000, "number of movies to create")
+	flags.Float64Var(&opts.ratingsMean, "ratings", 40, "mean ratings per user; counts are log-normal, so a few users rate far more")
+	flags.Float64Var(&opts.watchlistsMean, "watchlist", 15, "mean watchlist entries per user")
+	flags.Int64Var(&opts.seed, "seed", 1, "random seed; the same seed generates the same data")
+	flags.Parse(args)
+
+	if opts.users < 1 || opts.movies < 1 {
+		return fmt.Errorf("-users and -movies must be at least 1")
+	}
+
+	_, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	started := time.Now()
+	r := rand.New(rand.NewSource(opts.seed))
+	now := time.Now().UTC()
+
+	movies, err := generateMovies(db, r, opts.movies, now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("inserted %d movies\n", len(movies))
+
+	userIDs, err := generateUsers(db, opts.users, now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("inserted %d users (password %q)\n", len(userIDs), userPassword)
+
+	ratings, entries, err := generateActivity(db, r, opts, movies, userIDs, now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("inserted %d ratings and %d watchlist entries in %s\n", ratings, entries, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// generateMovies inserts movies with one to three genres, weighted towards
+// drama and comedy the way real catalogues are, and an IMDb score around 6.5
+func generateMovies(db *database.MongoDB, r *rand.Rand, count int, now time.Time) ([]generatedMovie, error) {
+	// Earlier genres in the list are picked more often
+	genreZipf := rand.NewZipf(r, 1.2, 2, uint64(len(genres)-1))
+	order := r.Perm(len(genres))
+
+	// IMDb IDs with a "tt99" prefix and 10 digits are far beyond real ones;
+	// numbering continues after movies from earlier runs
+	base, err := db.GetCollection("movies").CountDocuments(context.Background(), bson.M{"source": movieSource})
+	if err != nil {
+		return nil, err
+	}
+	movies := make([]generatedMovie, 0, count)
+	docs := make([]interface{}, 0, insertBatch)
+	for i := 0; i < count; i++ {
+		picked := map[string]bool{}
+		movieGenres := []string{}
+		for n := 1 + r.Intn(3); len(movieGenres) < n; {
+			genre := genres[order[genreZipf.Uint64()]]
+			if !picked[genre] {
+				picked[genre] = true
+				movieGenres = append(movieGenres, genre)
+			}
+		}
+
+		score := math.Round(clamp(r.NormFloat64()*1.0+6.5, 1.5, 9.5)*10) / 10
+		year := 1950 + int(math.Sqrt(r.Float64())*float64(now.Year()-1950))
+		title := fmt.Sprintf("The %s %s", titleAdjectives[r.Intn(len(titleAdjectives))], titleNouns[r.Intn(len(titleNouns))])
+		if r.Intn(3) == 0 {
+			title += " " + strconv.Itoa(2+r.Intn(3))
+		}
+
+		movie := models.Movie{
+			ID:         primitive.NewObjectID(),
+			IMDbID:     fmt.Sprintf("tt99%08d", int(base)+i+1),
+			Title:      title,
+			Year:       strconv.Itoa(year),
+			Genre:      strings.Join(movieGenres, ", "),
+			Director:   "Loadgen Director " + strconv.Itoa(r.Intn(count/10+1)),
+			Plot:       fmt.Sprintf("A synthetic %s movie generated for load testing.", strings.ToLower(movieGenres[0])),
+			Poster:     "N/A",
+			Runtime:    fmt.Sprintf("%d min", 80+r.Intn(70)),
+			IMDbRating: strconv.FormatFloat(score, 'f', 1, 64),
+			Language:   "English",
+			Rated:      ratedLevels[r.Intn(len(ratedLevels))],
+			YearStart:  year,
+			IMDbScore:  score,
+			Source:     movieSource,
+			CachedAt:   now,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		docs = append(docs, movie)
+		movies = append(movies, generatedMovie{id: movie.ID, genres: movieGenres, quality: score / 2})
+
+		if len(docs) == insertBatch || i == count-1 {
+			if err := insertAll(db.GetCollection("movies"), docs); err != nil {
+				return nil, fmt.Errorf("failed to insert movies: %w", err)
+			}
+			docs = docs[:0]
+		}
+	}
+	return movies, nil
+}
+
+// generateUsers inserts users sharing one password hash, since hashing per
+// user would dominate the run
+func generateUsers(db *database.MongoDB, count int, now time.Time) ([]primitive.ObjectID, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(userPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Continue numbering after users from earlier runs
+	existing, err := db.GetCollection("users").CountDocuments(context.Background(), bson.M{"username": bson.M{"$regex": "^" + usernamePrefix}})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, count)
+	docs := make([]interface{}, 0, insertBatch)
+	for i := 0; i < count; i++ {
+		n := int(existing) + i + 1
+		user := models.User{
+			ID:        primitive.NewObjectID(),
+			Username:  fmt.Sprintf("%s%06d", usernamePrefix, n),
+			Email:     fmt.Sprintf("%s%06d@loadgen.invalid", usernamePrefix, n),
+			Password:  string(hash),
+			CreatedAt: now.Add(-history),
+			UpdatedAt: now,
+		}
+		docs = append(docs, user)
+		ids = append(ids, user.ID)
+
+		if len(docs) == insertBatch || i == count-1 {
+			if err := insertAll(db.GetCollection("users"), docs); err != nil {
+				return nil, fmt.Errorf("failed to insert users: %w", err)
+			}
+			docs = docs[:0]
+		}
+	}
+	return ids, nil
+}
+
+// generateActivity gives each user one to three favourite genres and a
+// log-normal number of ratings and watchlist entries. Movies are drawn by
+// Zipf popularity, mostly from the favourite genres, and rated around their
+// quality with a per-user bias, higher for favourite genres.
+func generateActivity(db *database.MongoDB, r *rand.Rand, opts generateOptions, movies []generatedMovie, userIDs []primitive.ObjectID, now time.Time) (int, int, error) {
+	byGenre := map[string][]int{}
+	for i, movie := range movies {
+		for _, genre := range movie.genres {
+			byGenre[genre] = append(byGenre[genre], i)
+		}
+	}
+	popularity := rand.NewZipf(r, 1.1, 1, uint64(len(movies)-1))
+
+	ratingDocs := make([]interface{}, 0, insertBatch)
+	watchlistDocs := make([]interface{}, 0, insertBatch)
+	ratings, entries := 0, 0
+	flush := func(final bool) error {
+		if len(ratingDocs) >= insertBatch || (final && len(ratingDocs) > 0) {
+			if err := insertAll(db.GetCollection("ratings"), ratingDocs); err != nil {
+				return fmt.Errorf("failed to insert ratings: %w", err)
+			}
+			ratings += len(ratingDocs)
+			ratingDocs = ratingDocs[:0]
+		}
+		if len(watchlistDocs) >= insertBatch || (final && len(watchlistDocs) > 0) {
+			if err := insertAll(db.GetCollection("watchlists"), watchlistDocs); err != nil {
+				return fmt.Errorf("failed to insert watchlist entries: %w", err)
+			}
+			entries += len(watchlistDocs)
+			watchlistDocs = watchlistDocs[:0]
+		}
+		return nil
+	}
+
+	for _, userID := range userIDs {
+		favourites := map[string]bool{}
+		for n := 1 + r.Intn(3); len(favourites) < n; {
+			favourites[genres[r.Intn(len(genres))]] = true
+		}
+		favouriteList := make([]string, 0, len(favourites))
+		for genre := range favourites {
+			if len(byGenre[genre]) > 0 {
+				favouriteList = append(favouriteList, genre)
+			}
+		}
+		bias := r.NormFloat64() * 0.5
+
+		// pick draws a movie the user has not touched yet, or -1 after too
+		// many collisions on a small catalogue
+		seen := map[int]bool{}
+		pick := func() int {
+			for attempt := 0; attempt < 20; attempt++ {
+				var i int
+				if len(favouriteList) > 0 && r.Float64() < 0.7 {
+					pool := byGenre[favouriteList[r.Intn(len(favouriteList))]]
+					i = pool[int(popularity.Uint64())%len(pool)]
+				} else {
+					i = int(popularity.Uint64())
+				}
+				if !seen[i] {
+					seen[i] = true
+					return i
+				}
+			}
+			return -1
+		}
+
+		for n := logNormalCount(r, opts.ratingsMean); n > 0; n-- {
+			i := pick()
+			if i < 0 {
+				break
+			}
+			movie := movies[i]
+			score := movie.quality + bias + r.NormFloat64()*0.8
+			for _, genre := range movie.genres {
+				if favourites[genre] {
+					score += 0.5
+					break
+				}
+			}
+			at := randomTime(r, now)
+			ratingDocs = append(ratingDocs, models.Rating{
+				UserID:    userID,
+				MovieID:   movie.id,
+				Rating:    int(clamp(math.Round(score), 1, 5)),
+				Version:   1,
+				CreatedAt: at,
+				UpdatedAt: at,
+			})
+		}
+
+		for n := logNormalCount(r, opts.watchlistsMean); n > 0; n-- {
+			i := pick()
+			if i < 0 {
+				break
+			}
+			addedAt := randomTime(r, now)
+			entry := models.Watchlist{
+				UserID:    userID,
+				MovieID:   movies[i].id,
+				AddedAt:   addedAt,
+				Priority:  int(clamp(math.Round(r.NormFloat64()+float64(models.DefaultWatchlistPriority)), models.MinWatchlistPriority, models.MaxWatchlistPriority)),
+				Version:   1,
+				CreatedAt: addedAt,
+				UpdatedAt: addedAt,
+			}
+			// About a third of the watchlist has been watched since
+			if r.Float64() < 0.35 {
+				watchedAt := addedAt.Add(time.Duration(r.Float64() * float64(now.Sub(addedAt))))
+				entry.WatchedAt = &watchedAt
+			}
+			watchlistDocs = append(watchlistDocs, entry)
+		}
+
+		if err := flush(false); err != nil {
+			return ratings, entries, err
+		}
+	}
+	if err := flush(true); err != nil {
+		return ratings, entries, err
+	}
+	return ratings, entries, nil
+}
+
+// logNormalCount draws a count with the given mean from a log-normal
+// distribution, so most users are light and a few are heavy
+func logNormalCount(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	const sigma = 0.9
+	mu := math.Log(mean) - sigma*sigma/2
+	return int(math.Round(math.Exp(mu + sigma*r.NormFloat64())))
+}
+
+// randomTime is a time within history before now, weighted towards recent
+func randomTime(r *rand.Rand, now time.Time) time.Time {
+	return now.Add(-time.Duration(r.Float64() * r.Float64() * float64(history)))
+}
+
+func clamp(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
+
+func insertAll(collection *mongo.Collection, docs []interface{}) error {
+	_, err := collection.InsertMany(context.Background(), docs, options.InsertMany().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,133 @@
+// Package authz decides who may act on what. Handlers build a Principal for
+// the request and ask the Policy before reading or changing a resource, so
+// ownership, admin and share-token rules live in one place instead of being
+// implied by repository filters. Repositories keep their user_id filters as
+// a second line of defence.
+package authz
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrUnauthenticated is returned when the request carries no user
+	ErrUnauthenticated = errors.New("user not authenticated")
+	// ErrForbidden is returned when the principal may not perform the action
+	ErrForbidden = errors.New("forbidden")
+	// ErrHidden is returned when the resource belongs to someone else. It
+	// should be reported like a missing resource so IDs cannot be probed.
+	ErrHidden = errors.New("resource not found")
+)
+
+// Principal is who a request acts as
+type Principal struct {
+	// UserID is the acting user, or the kids profile when one is selected
+	UserID primitive.ObjectID
+	// AccountID is the signed-in account; it equals UserID unless a kids
+	// profile is selected
+	AccountID primitive.ObjectID
+	Profile   bool
+	Demo      bool
+	Admin     bool
+}
+
+// Policy holds the authorization rules
+type Policy struct {
+	admins map[string]bool
+}
+
+func NewPolicy(adminUserIDs []string) *Policy {
+	admins := make(map[string]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+	return &Policy{admins: admins}
+}
+
+// Principal reads the principal set up by the auth, demo and profile
+// middleware. It must run after AuthMiddleware.
+func (p *Policy) Principal(c *gin.Context) (*Principal, error) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		return nil, ErrUnauthenticated
+	}
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	principal := &Principal{UserID: userID, AccountID: userID}
+	if accountValue, ok := c.Get("account_id"); ok {
+		if accountID, ok := accountValue.(primitive.ObjectID); ok {
+			principal.AccountID = accountID
+		}
+	}
+	_, principal.Profile = c.Get("profile")
+	principal.Demo = c.GetBool("demo")
+	// Admin rights stay with the account and are not passed to its profiles
+	principal.Admin = !principal.Profile && p.admins[principal.AccountID.Hex()]
+	return principal, nil
+}
+
+// RequireAdmin allows configured admins only
+func (p *Policy) RequireAdmin(principal *Principal) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if !principal.Admin {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// RequireOwner allows the principal to change a resource owned by ownerID,
+// such as a rating, watchlist entry or notification. Admins get no
+// exception; they act on their own data like everyone else.
+func (p *Policy) RequireOwner(principal *Principal, ownerID primitive.ObjectID) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if principal.UserID != ownerID {
+		return ErrHidden
+	}
+	return nil
+}
+
+// RequireAccountOwner allows the signed-in account, but not its kids
+// profiles, to manage a resource of the account such as a session or profile
+func (p *Policy) RequireAccountOwner(principal *Principal, accountID primitive.ObjectID) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if principal.Profile {
+		return ErrForbidden
+	}
+	if principal.AccountID != accountID {
+		return ErrHidden
+	}
+	return nil
+}
+
+// RequireShareAccess allows the owner, or anyone presenting the share token
+// of a resource, to read it, as with a calendar feed URL. principal is nil
+// for guests. tokenHash is the stored hash of the share token (hex SHA-256,
+// like every stored secret token); empty means the resource is not shared.
+func (p *Policy) RequireShareAccess(principal *Principal, ownerID primitive.ObjectID, tokenHash, presented string) error {
+	if principal != nil && principal.UserID == ownerID {
+		return nil
+	}
+	if tokenHash == "" || presented == "" {
+		return ErrHidden
+	}
+	sum := sha256.Sum256([]byte(presented))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(tokenHash)) != 1 {
+		return ErrHidden
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package authz_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"movie-watchlist/internal/authz"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRequireShareAccess(t *testing.T) {
+	policy := authz.NewPolicy(nil)
+	owner := primitive.NewObjectID()
+	ownerPrincipal := &authz.Principal{UserID: owner, AccountID: owner}
+	otherID := primitive.NewObjectID()
+	other := &authz.Principal{UserID: otherID, AccountID: otherID}
+	tokenHash := hashToken("feed-token")
+
+	tests := []struct {
+		name      string
+		principal *authz.Principal
+		tokenHash string
+		presented string
+		want      error
+	}{
+		{"owner without token", ownerPrincipal, tokenHash, "", nil},
+		{"owner of an unshared resource", ownerPrincipal, "", "", nil},
+		{"guest with the token", nil, tokenHash, "feed-token", nil},
+		{"other user with the token", other, tokenHash, "feed-token", nil},
+		{"guest with a wrong token", nil, tokenHash, "guess", authz.ErrHidden},
+		{"guest presenting the hash", nil, tokenHash, tokenHash, authz.ErrHidden},
+		{"guest without a token", nil, tokenHash, "", authz.ErrHidden},
+		{"unshared resource", nil, "", "feed-token", authz.ErrHidden},
+		{"other user without a token", other, tokenHash, "", authz.ErrHidden},
+	}
+	for _, tt := range tests {
+		err := policy.RequireShareAccess(tt.principal, owner, tt.tokenHash, tt.presented)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	owner := primitive.NewObjectID()
+	policy := authz.NewPolicy([]string{owner.Hex()})
+	otherID := primitive.NewObjectID()
+
+	if err := policy.RequireOwner(&authz.Principal{UserID: owner, AccountID: owner}, owner); err != nil {
+		t.Errorf("owner: error = %v, want nil", err)
+	}
+	if err := policy.RequireOwner(&authz.Principal{UserID: otherID, AccountID: otherID, Admin: true}, owner); !errors.Is(err, authz.ErrHidden) {
+		t.Errorf("admin: error = %v, want ErrHidden", err)
+	}
+	if err := policy.RequireOwner(nil, owner); !errors.Is(err, authz.ErrUnauthenticated) {
+		t.Errorf("guest: error = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestRequireAccountOwner(t *testing.T) {
+	policy := authz.NewPolicy(nil)
+	account := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+
+	if err := policy.RequireAccountOwner(&authz.Principal{UserID: account, AccountID: account}, account); err != nil {
+		t.Errorf("account: error = %v, want nil", err)
+	}
+	profile := &authz.Principal{UserID: primitive.NewObjectID(), AccountID: account, Profile: true}
+	if err := policy.RequireAccountOwner(profile, account); !errors.Is(err, authz.ErrForbidden) {
+		t.Errorf("kids profile: error = %v, want ErrForbidden", err)
+	}
+	if err := policy.RequireAccountOwner(&authz.Principal{UserID: otherID, AccountID: otherID}, account); !errors.Is(err, authz.ErrHidden) {
+		t.Errorf("other account: error = %v, want ErrHidden", err)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	policy := authz.NewPolicy(nil)
+	userID := primitive.NewObjectID()
+
+	if err := policy.RequireAdmin(&authz.Principal{UserID: userID, AccountID: userID, Admin: true}); err != nil {
+		t.Errorf("admin: error = %v, want nil", err)
+	}
+	if err := policy.RequireAdmin(&authz.Principal{UserID: userID, AccountID: userID}); !errors.Is(err, authz.ErrForbidden) {
+		t.Errorf("user: error = %v, want ErrForbidden", err)
+	}
+	if err := policy.RequireAdmin(nil); !errors.Is(err, authz.ErrUnauthenticated) {
+		t.Errorf("guest: error = %v, want ErrUnauthenticated", err)
+	}
+}
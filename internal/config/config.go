@@ -1,23 +1,686 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultJWTSecret is the placeholder secret only accepted in development
+const DefaultJWTSecret = "your-secret-key"
+
 type Config struct {
-	Port        string
-	DatabaseURL string
-	JWTSecret   string
-	OMDbAPIKey  string
+	Environment    string   `yaml:"environment" json:"environment"`
+	Port           string   `yaml:"port" json:"port"`
+	DatabaseURL    string   `yaml:"database_url" json:"database_url"`
+	JWTSecret      string   `yaml:"jwt_secret" json:"jwt_secret"`
+	OMDbAPIKey     string   `yaml:"omdb_api_key" json:"omdb_api_key"`
+	OMDbDailyLimit int      `yaml:"omdb_daily_limit" json:"omdb_daily_limit"`
+	AdminUserIDs   []string `yaml:"admin_user_ids" json:"admin_user_ids"`
+	JobWorkers     int      `yaml:"job_workers" json:"job_workers"`
+
+	// MetadataProviders is the failover order for movie details: "omdb" and
+	// "tmdb". TMDb is only asked when TMDbAPIKey is set; the local cache is
+	// always the last resort.
+	MetadataProviders []string `yaml:"metadata_providers" json:"metadata_providers"`
+	TMDbAPIKey        string   `yaml:"tmdb_api_key" json:"tmdb_api_key"`
+
+	// OMDbBaseURL is where OMDb requests are sent; integration environments
+	// point it at a stub server
+	OMDbBaseURL string `yaml:"omdb_base_url" json:"omdb_base_url"`
+	// OMDbFixtures records OMDb responses to OMDbFixturesDir ("record") or
+	// answers from them without calling OMDb ("replay"); empty disables it
+	OMDbFixtures    string `yaml:"omdb_fixtures" json:"omdb_fixtures"`
+	OMDbFixturesDir string `yaml:"omdb_fixtures_dir" json:"omdb_fixtures_dir"`
+
+	// Access tokens: lifetime, and the issuer and audience they are minted
+	// for and checked against. Give each deployment its own values so tokens
+	// from one are rejected by the others even if they share a secret.
+	JWTAccessTTLMinutes int    `yaml:"jwt_access_ttl_minutes" json:"jwt_access_ttl_minutes"`
+	JWTIssuer           string `yaml:"jwt_issuer" json:"jwt_issuer"`
+	JWTAudience         string `yaml:"jwt_audience" json:"jwt_audience"`
+
+	// OpenID Connect provider mode lets companion apps (a TV app, a browser
+	// extension) sign in through the OAuth token endpoint with standard
+	// libraries. OIDCClientIDs are the client IDs accepted there; none turns
+	// the mode off. ID tokens are signed with the RSA key in the PEM file
+	// OIDCSigningKeyFile; development generates a throwaway key without one.
+	OIDCClientIDs      []string `yaml:"oidc_client_ids" json:"oidc_client_ids"`
+	OIDCSigningKeyFile string   `yaml:"oidc_signing_key_file" json:"oidc_signing_key_file"`
+
+	// OAuthConsentURL is the web app page where users approve third-party
+	// apps; /oauth/authorize sends them there with the app's request in the
+	// query. Empty turns /oauth/authorize off.
+	OAuthConsentURL string `yaml:"oauth_consent_url" json:"oauth_consent_url"`
+
+	// Trakt sync lets users link a Trakt account to import their history and
+	// ratings and push changes back. It is off until TraktClientID and
+	// TraktClientSecret, from a Trakt app whose redirect URI is
+	// PUBLIC_BASE_URL/integrations/trakt/callback, are set. Trakt tokens are
+	// stored encrypted, so it needs FieldEncryptionKeys. TraktBaseURL is where
+	// API requests are sent; integration environments point it at a stub.
+	TraktClientID     string `yaml:"trakt_client_id" json:"trakt_client_id"`
+	TraktClientSecret string `yaml:"trakt_client_secret" json:"trakt_client_secret"`
+	TraktBaseURL      string `yaml:"trakt_base_url" json:"trakt_base_url"`
+
+	// ChatWebhooks lets users post their movie night polls to a Slack or
+	// Discord incoming webhook. Webhook URLs are stored encrypted, so it
+	// needs FieldEncryptionKeys. SlackSigningSecret, from a Slack app whose
+	// slash command points at PUBLIC_BASE_URL/integrations/slack/commands,
+	// turns on the slash command.
+	ChatWebhooks       bool   `yaml:"chat_webhooks" json:"chat_webhooks"`
+	SlackSigningSecret string `yaml:"slack_signing_secret" json:"slack_signing_secret"`
+
+	// TelegramBotToken, from @BotFather, turns on the Telegram bot. In
+	// polling mode the bot fetches updates itself, which suits a single
+	// instance; in webhook mode Telegram posts them to
+	// PUBLIC_BASE_URL/integrations/telegram/webhook with
+	// TelegramWebhookSecret. TelegramBaseURL is where Bot API requests are
+	// sent; integration environments point it at a stub.
+	TelegramBotToken      string `yaml:"telegram_bot_token" json:"telegram_bot_token"`
+	TelegramMode          string `yaml:"telegram_mode" json:"telegram_mode"`
+	TelegramWebhookSecret string `yaml:"telegram_webhook_secret" json:"telegram_webhook_secret"`
+	TelegramBaseURL       string `yaml:"telegram_base_url" json:"telegram_base_url"`
+
+	// InboundEmailProvider, mailgun or postmark, turns on email-in: each user
+	// can get an address at InboundEmailDomain, and mail the provider
+	// receives for it is posted to PUBLIC_BASE_URL/integrations/inbound-email.
+	// InboundEmailSecret is the Mailgun webhook signing key, or the password
+	// in the basic auth credentials of the Postmark webhook URL.
+	InboundEmailProvider string `yaml:"inbound_email_provider" json:"inbound_email_provider"`
+	InboundEmailDomain   string `yaml:"inbound_email_domain" json:"inbound_email_domain"`
+	InboundEmailSecret   string `yaml:"inbound_email_secret" json:"inbound_email_secret"`
+
+	// MaxBodyBytes caps the size of request bodies
+	MaxBodyBytes int64 `yaml:"max_body_bytes" json:"max_body_bytes"`
+
+	// Per-caller rate limits; 0 disables a limit
+	RateLimitPerMinute     int `yaml:"rate_limit_per_minute" json:"rate_limit_per_minute"`
+	SearchRateLimitPerHour int `yaml:"search_rate_limit_per_hour" json:"search_rate_limit_per_hour"`
+
+	// Password policy applied at registration
+	PasswordMinLength   int  `yaml:"password_min_length" json:"password_min_length"`
+	PasswordBreachCheck bool `yaml:"password_breach_check" json:"password_breach_check"`
+
+	// CAPTCHA against bot signups and password guessing: "turnstile",
+	// "hcaptcha" or "recaptcha", empty to disable. Registration always asks
+	// for one when CaptchaOnRegister is set; login asks once an IP or email
+	// has CaptchaLoginAfterFailures failed logins in 15 minutes (0 never).
+	CaptchaProvider           string `yaml:"captcha_provider" json:"captcha_provider"`
+	CaptchaSecret             string `yaml:"captcha_secret" json:"captcha_secret"`
+	CaptchaOnRegister         bool   `yaml:"captcha_on_register" json:"captcha_on_register"`
+	CaptchaLoginAfterFailures int    `yaml:"captcha_login_after_failures" json:"captcha_login_after_failures"`
+
+	// Outgoing email; without SMTPHost emails are only logged
+	PublicBaseURL string `yaml:"public_base_url" json:"public_base_url"`
+	SMTPHost      string `yaml:"smtp_host" json:"smtp_host"`
+	SMTPPort      int    `yaml:"smtp_port" json:"smtp_port"`
+	SMTPUsername  string `yaml:"smtp_username" json:"smtp_username"`
+	SMTPPassword  string `yaml:"smtp_password" json:"smtp_password"`
+	MailFrom      string `yaml:"mail_from" json:"mail_from"`
+
+	// TermsVersion is the current terms of service and privacy policy
+	// version. Users must accept it at registration, and again before their
+	// next write once it changes. Empty disables acceptance tracking.
+	TermsVersion string `yaml:"terms_version" json:"terms_version"`
+
+	// GeoCountryHeader names the header in which a proxy or CDN in front of
+	// the API reports the client's country (e.g. CF-IPCountry). Login alerts
+	// then compare countries instead of IP networks.
+	GeoCountryHeader string `yaml:"geo_country_header" json:"geo_country_header"`
+
+	// DevicePairingURL is the web app page where a signed-in user enters the
+	// code a TV app shows to sign it in; the API adds the code as ?code= for
+	// the QR code. Empty leaves it to the TV app to say where to go.
+	// DevicePairingsPerHour caps the pairings one IP may start (0 no limit).
+	DevicePairingURL      string `yaml:"device_pairing_url" json:"device_pairing_url"`
+	DevicePairingsPerHour int    `yaml:"device_pairings_per_hour" json:"device_pairings_per_hour"`
+
+	// TrustedProxies are the IPs and CIDRs of load balancers allowed to
+	// report the client IP in RealIPHeaders. Requests from other addresses
+	// are attributed to the connecting address, so with none the headers are
+	// ignored. The client IP feeds rate limits, sessions and login history.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+	RealIPHeaders  []string `yaml:"real_ip_headers" json:"real_ip_headers"`
+	// TrustedPlatform reads the client IP from a header the hosting platform
+	// sets on every request, ignoring TrustedProxies: "cloudflare",
+	// "appengine" or a header name. Only set it when the API cannot be
+	// reached around the platform.
+	TrustedPlatform string `yaml:"trusted_platform" json:"trusted_platform"`
+
+	// TLS termination for deployments without a proxy in front: either a
+	// certificate and key file, or certificates from Let's Encrypt for
+	// TLSAutocertDomains, kept in TLSAutocertCacheDir. TLS connections
+	// negotiate HTTP/2.
+	TLSCertFile         string   `yaml:"tls_cert_file" json:"tls_cert_file"`
+	TLSKeyFile          string   `yaml:"tls_key_file" json:"tls_key_file"`
+	TLSAutocertDomains  []string `yaml:"tls_autocert_domains" json:"tls_autocert_domains"`
+	TLSAutocertEmail    string   `yaml:"tls_autocert_email" json:"tls_autocert_email"`
+	TLSAutocertCacheDir string   `yaml:"tls_autocert_cache_dir" json:"tls_autocert_cache_dir"`
+	// HTTPRedirectPort serves plain HTTP next to TLS, redirecting to HTTPS
+	// and answering Let's Encrypt HTTP challenges; empty disables it
+	HTTPRedirectPort string `yaml:"http_redirect_port" json:"http_redirect_port"`
+	// UnixSocket also serves the API, without TLS, on a Unix domain socket
+	// at this path for a proxy on the same host; empty disables it
+	UnixSocket string `yaml:"unix_socket" json:"unix_socket"`
+	// AdminListen moves the /api/v1/admin routes off the public listeners to
+	// a separate plain HTTP address, "host:port" or "unix:/path", so the
+	// admin surface can be kept on an internal network; empty serves them
+	// with the rest of the API
+	AdminListen string `yaml:"admin_listen" json:"admin_listen"`
+
+	// Rating reminders for movies marked watched; 0 days disables them
+	RatingReminderDays  int  `yaml:"rating_reminder_days" json:"rating_reminder_days"`
+	RatingReminderEmail bool `yaml:"rating_reminder_email" json:"rating_reminder_email"`
+
+	// MaxWatchlistEntries caps each user's watchlist; 0 leaves it unlimited.
+	// Admins can override it per user.
+	MaxWatchlistEntries int `yaml:"max_watchlist_entries" json:"max_watchlist_entries"`
+
+	// MovieCacheMaxAgeDays evicts cached movies whose details are older than
+	// this many days and that no user has on a list; 0 keeps them forever
+	MovieCacheMaxAgeDays int `yaml:"movie_cache_max_age_days" json:"movie_cache_max_age_days"`
+
+	// Anomaly detection flags accounts for admin review when they rate more
+	// than AnomalyRatingBurst movies, or add and remove more than
+	// AnomalyWatchlistChurn watchlist entries, within ten minutes; 0 turns a
+	// check off. With AnomalyThrottleMinutes set, flagged accounts are also
+	// held to AnomalyThrottlePerMinute writes per minute for that long.
+	AnomalyRatingBurst       int `yaml:"anomaly_rating_burst" json:"anomaly_rating_burst"`
+	AnomalyWatchlistChurn    int `yaml:"anomaly_watchlist_churn" json:"anomaly_watchlist_churn"`
+	AnomalyThrottleMinutes   int `yaml:"anomaly_throttle_minutes" json:"anomaly_throttle_minutes"`
+	AnomalyThrottlePerMinute int `yaml:"anomaly_throttle_per_minute" json:"anomaly_throttle_per_minute"`
+
+	// Plot embeddings for semantic search: "local" hashes words in-process,
+	// "api" calls an OpenAI-compatible embeddings endpoint. With a vector
+	// index name, lookups use Atlas Vector Search instead of scanning vectors.
+	EmbeddingProvider    string `yaml:"embedding_provider" json:"embedding_provider"`
+	EmbeddingAPIURL      string `yaml:"embedding_api_url" json:"embedding_api_url"`
+	EmbeddingAPIKey      string `yaml:"embedding_api_key" json:"embedding_api_key"`
+	EmbeddingModel       string `yaml:"embedding_model" json:"embedding_model"`
+	EmbeddingVectorIndex string `yaml:"embedding_vector_index" json:"embedding_vector_index"`
+
+	// Parser for natural-language discovery queries: "rules" uses a fixed
+	// vocabulary, "llm" asks an OpenAI-compatible chat endpoint and falls back
+	// to the rules when the call fails
+	DiscoveryParser   string `yaml:"discovery_parser" json:"discovery_parser"`
+	DiscoveryLLMURL   string `yaml:"discovery_llm_url" json:"discovery_llm_url"`
+	DiscoveryLLMKey   string `yaml:"discovery_llm_key" json:"discovery_llm_key"`
+	DiscoveryLLMModel string `yaml:"discovery_llm_model" json:"discovery_llm_model"`
+
+	// Outbound streaming of domain events: "kafka" produces through the
+	// Kafka REST Proxy at EventStreamURL, "nats" publishes to the NATS
+	// server at EventStreamURL (nats://); empty disables streaming. Topics
+	// and subjects are EventStreamPrefix, a dot and the event name.
+	EventStream       string `yaml:"event_stream" json:"event_stream"`
+	EventStreamURL    string `yaml:"event_stream_url" json:"event_stream_url"`
+	EventStreamPrefix string `yaml:"event_stream_prefix" json:"event_stream_prefix"`
+
+	// Public demo mode: POST /demo/session hands out short-lived sandbox
+	// accounts with seeded data. Sessions are limited per IP per hour and
+	// demo users get their own, lower rate limit.
+	DemoMode               bool `yaml:"demo_mode" json:"demo_mode"`
+	DemoSessionMinutes     int  `yaml:"demo_session_minutes" json:"demo_session_minutes"`
+	DemoSessionsPerHour    int  `yaml:"demo_sessions_per_hour" json:"demo_sessions_per_hour"`
+	DemoRateLimitPerMinute int  `yaml:"demo_rate_limit_per_minute" json:"demo_rate_limit_per_minute"`
+
+	// FieldEncryptionKeys encrypt sensitive fields before they are stored, as
+	// "id:base64key" entries with the current key first. Older keys stay
+	// listed until a rotation run has re-encrypted everything.
+	FieldEncryptionKeys []string `yaml:"field_encryption_keys" json:"field_encryption_keys"`
+
+	// ErrorReportingDSN is a Sentry-compatible DSN; without it panics are only logged
+	ErrorReportingDSN string `yaml:"error_reporting_dsn" json:"error_reporting_dsn"`
+
+	// Environment profile settings, defaulted per APP_ENV when unset
+	GinMode   string `yaml:"gin_mode" json:"gin_mode"`
+	LogLevel  string `yaml:"log_level" json:"log_level"`
+	LogFormat string `yaml:"log_format" json:"log_format"`
+	// Per-module level overrides ("jobs=debug") and sampling of repeated
+	// debug/info messages per second; initial 0 disables sampling
+	LogModuleLevels     []string `yaml:"log_module_levels" json:"log_module_levels"`
+	LogSampleInitial    int      `yaml:"log_sample_initial" json:"log_sample_initial"`
+	LogSampleThereafter int      `yaml:"log_sample_thereafter" json:"log_sample_thereafter"`
+	CORSAllowedOrigins  []string `yaml:"cors_allowed_origins" json:"cors_allowed_origins"`
+	SeedData            *bool    `yaml:"seed_data" json:"seed_data"`
+
+	// environmentSet records whether APP_ENV or the config file chose the
+	// environment, rather than it falling back to dev
+	environmentSet bool
+}
+
+// Load builds the configuration from defaults, an optional config file
+// (CONFIG_FILE, YAML or JSON), the per-environment config.{env}.yaml and
+// environment variables, in increasing order of precedence, then fills unset
+// profile settings for APP_ENV and validates the result
+func Load() (*Config, error) {
+	cfg := defaults()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.Environment = getEnv("APP_ENV", cfg.Environment)
+	cfg.environmentSet = cfg.Environment != ""
+	if !cfg.environmentSet {
+		cfg.Environment = "dev"
+	}
+	if err := loadProfileFile(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.applyProfileDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// TrustedPlatformHeader returns the header named by TrustedPlatform, or ""
+// when it is not set
+func (c *Config) TrustedPlatformHeader() string {
+	switch strings.ToLower(c.TrustedPlatform) {
+	case "cloudflare":
+		return "CF-Connecting-IP"
+	case "appengine":
+		return "X-Appengine-Remote-Addr"
+	default:
+		return c.TrustedPlatform
+	}
+}
+
+// TLSEnabled reports whether the server terminates TLS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.TLSAutocertDomains) > 0
+}
+
+// TraktEnabled reports whether users can link a Trakt account
+func (c *Config) TraktEnabled() bool {
+	return c.TraktClientID != "" && c.TraktClientSecret != ""
+}
+
+// TelegramWebhookURL returns where Telegram posts updates, or "" when the
+// bot long polls
+func (c *Config) TelegramWebhookURL() string {
+	if c.TelegramMode != "webhook" {
+		return ""
+	}
+	return c.PublicBaseURL + "/integrations/telegram/webhook"
+}
+
+// IsDevelopment reports whether the application runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == "dev"
 }
 
-func Load() *Config {
+func defaults() *Config {
 	return &Config{
-		Port:        getEnv("PORT", "8080"),
-		DatabaseURL: getEnv("DATABASE_URL", "mongodb://localhost:27017/movie_watchlist"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
-		OMDbAPIKey:  getEnv("OMDB_API_KEY", ""),
+		Port:           "8080",
+		DatabaseURL:    "mongodb://localhost:27017/movie_watchlist",
+		JWTSecret:      DefaultJWTSecret,
+		OMDbDailyLimit: 1000,
+		JobWorkers:     2,
+
+		RealIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+
+		TLSAutocertCacheDir: "certs",
+
+		MetadataProviders: []string{"omdb", "tmdb"},
+
+		OMDbBaseURL:     "http://www.omdbapi.com",
+		OMDbFixturesDir: "fixtures/omdb",
+
+		TraktBaseURL:    "https://api.trakt.tv",
+		TelegramMode:    "polling",
+		TelegramBaseURL: "https://api.telegram.org",
+
+		JWTAccessTTLMinutes: 24 * 60,
+		JWTIssuer:           "movie-watchlist-api",
+		JWTAudience:         "movie-watchlist-api",
+
+		MaxBodyBytes: 1 << 20,
+
+		RateLimitPerMinute:     120,
+		SearchRateLimitPerHour: 300,
+
+		PasswordMinLength: 8,
+
+		CaptchaOnRegister:         true,
+		CaptchaLoginAfterFailures: 5,
+
+		SMTPPort: 587,
+
+		DevicePairingsPerHour: 10,
+
+		RatingReminderDays: 3,
+
+		EmbeddingProvider: "local",
+		DiscoveryParser:   "rules",
+
+		EventStreamPrefix: "movie-watchlist",
+
+		DemoSessionMinutes:     60,
+		DemoSessionsPerHour:    5,
+		DemoRateLimitPerMinute: 20,
+
+		AnomalyRatingBurst:       60,
+		AnomalyWatchlistChurn:    100,
+		AnomalyThrottlePerMinute: 10,
+
+		LogSampleInitial:    100,
+		LogSampleThereafter: 100,
+	}
+}
+
+// loadFile merges a YAML or JSON config file into cfg based on its extension
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file format %q: use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides cfg with any environment variables that are set
+func applyEnv(cfg *Config) error {
+	cfg.Environment = getEnv("APP_ENV", cfg.Environment)
+	cfg.Port = getEnv("PORT", cfg.Port)
+	cfg.DatabaseURL = getEnv("DATABASE_URL", cfg.DatabaseURL)
+	cfg.JWTSecret = getEnv("JWT_SECRET", cfg.JWTSecret)
+	cfg.JWTIssuer = getEnv("JWT_ISSUER", cfg.JWTIssuer)
+	cfg.JWTAudience = getEnv("JWT_AUDIENCE", cfg.JWTAudience)
+
+	accessTTL, err := getEnvInt("JWT_ACCESS_TTL_MINUTES", cfg.JWTAccessTTLMinutes)
+	if err != nil {
+		return err
+	}
+	cfg.JWTAccessTTLMinutes = accessTTL
+	if clients := getEnvList("OIDC_CLIENT_IDS"); clients != nil {
+		cfg.OIDCClientIDs = clients
 	}
+	cfg.OIDCSigningKeyFile = getEnv("OIDC_SIGNING_KEY_FILE", cfg.OIDCSigningKeyFile)
+	cfg.OAuthConsentURL = getEnv("OAUTH_CONSENT_URL", cfg.OAuthConsentURL)
+	cfg.TraktClientID = getEnv("TRAKT_CLIENT_ID", cfg.TraktClientID)
+	cfg.TraktClientSecret = getEnv("TRAKT_CLIENT_SECRET", cfg.TraktClientSecret)
+	cfg.TraktBaseURL = strings.TrimRight(getEnv("TRAKT_BASE_URL", cfg.TraktBaseURL), "/")
+	chatWebhooks, err := getEnvBool("CHAT_WEBHOOKS", cfg.ChatWebhooks)
+	if err != nil {
+		return err
+	}
+	cfg.ChatWebhooks = chatWebhooks
+	cfg.SlackSigningSecret = getEnv("SLACK_SIGNING_SECRET", cfg.SlackSigningSecret)
+	cfg.TelegramBotToken = getEnv("TELEGRAM_BOT_TOKEN", cfg.TelegramBotToken)
+	cfg.TelegramMode = getEnv("TELEGRAM_MODE", cfg.TelegramMode)
+	cfg.TelegramWebhookSecret = getEnv("TELEGRAM_WEBHOOK_SECRET", cfg.TelegramWebhookSecret)
+	cfg.TelegramBaseURL = strings.TrimRight(getEnv("TELEGRAM_BASE_URL", cfg.TelegramBaseURL), "/")
+	cfg.InboundEmailProvider = strings.ToLower(getEnv("INBOUND_EMAIL_PROVIDER", cfg.InboundEmailProvider))
+	cfg.InboundEmailDomain = strings.ToLower(getEnv("INBOUND_EMAIL_DOMAIN", cfg.InboundEmailDomain))
+	cfg.InboundEmailSecret = getEnv("INBOUND_EMAIL_SECRET", cfg.InboundEmailSecret)
+	cfg.OMDbAPIKey = getEnv("OMDB_API_KEY", cfg.OMDbAPIKey)
+	cfg.OMDbBaseURL = getEnv("OMDB_BASE_URL", cfg.OMDbBaseURL)
+	cfg.OMDbFixtures = getEnv("OMDB_FIXTURES", cfg.OMDbFixtures)
+	cfg.OMDbFixturesDir = getEnv("OMDB_FIXTURES_DIR", cfg.OMDbFixturesDir)
+	cfg.TMDbAPIKey = getEnv("TMDB_API_KEY", cfg.TMDbAPIKey)
+	if providers := getEnvList("METADATA_PROVIDERS"); providers != nil {
+		cfg.MetadataProviders = providers
+	}
+
+	limit, err := getEnvInt("OMDB_DAILY_LIMIT", cfg.OMDbDailyLimit)
+	if err != nil {
+		return err
+	}
+	cfg.OMDbDailyLimit = limit
+
+	workers, err := getEnvInt("JOB_WORKERS", cfg.JobWorkers)
+	if err != nil {
+		return err
+	}
+	cfg.JobWorkers = workers
+
+	maxBody, err := getEnvInt("MAX_BODY_BYTES", int(cfg.MaxBodyBytes))
+	if err != nil {
+		return err
+	}
+	cfg.MaxBodyBytes = int64(maxBody)
+
+	rateLimit, err := getEnvInt("RATE_LIMIT_PER_MINUTE", cfg.RateLimitPerMinute)
+	if err != nil {
+		return err
+	}
+	cfg.RateLimitPerMinute = rateLimit
+
+	searchRateLimit, err := getEnvInt("SEARCH_RATE_LIMIT_PER_HOUR", cfg.SearchRateLimitPerHour)
+	if err != nil {
+		return err
+	}
+	cfg.SearchRateLimitPerHour = searchRateLimit
+
+	minLength, err := getEnvInt("PASSWORD_MIN_LENGTH", cfg.PasswordMinLength)
+	if err != nil {
+		return err
+	}
+	cfg.PasswordMinLength = minLength
+
+	breachCheck, err := getEnvBool("PASSWORD_BREACH_CHECK", cfg.PasswordBreachCheck)
+	if err != nil {
+		return err
+	}
+	cfg.PasswordBreachCheck = breachCheck
+
+	cfg.CaptchaProvider = getEnv("CAPTCHA_PROVIDER", cfg.CaptchaProvider)
+	cfg.CaptchaSecret = getEnv("CAPTCHA_SECRET", cfg.CaptchaSecret)
+
+	captchaOnRegister, err := getEnvBool("CAPTCHA_ON_REGISTER", cfg.CaptchaOnRegister)
+	if err != nil {
+		return err
+	}
+	cfg.CaptchaOnRegister = captchaOnRegister
+
+	captchaAfterFailures, err := getEnvInt("CAPTCHA_LOGIN_AFTER_FAILURES", cfg.CaptchaLoginAfterFailures)
+	if err != nil {
+		return err
+	}
+	cfg.CaptchaLoginAfterFailures = captchaAfterFailures
+
+	cfg.PublicBaseURL = getEnv("PUBLIC_BASE_URL", cfg.PublicBaseURL)
+	if cfg.PublicBaseURL == "" {
+		cfg.PublicBaseURL = "http://localhost:" + cfg.Port
+	}
+	cfg.PublicBaseURL = strings.TrimRight(cfg.PublicBaseURL, "/")
+
+	cfg.SMTPHost = getEnv("SMTP_HOST", cfg.SMTPHost)
+	smtpPort, err := getEnvInt("SMTP_PORT", cfg.SMTPPort)
+	if err != nil {
+		return err
+	}
+	cfg.SMTPPort = smtpPort
+	cfg.SMTPUsername = getEnv("SMTP_USERNAME", cfg.SMTPUsername)
+	cfg.SMTPPassword = getEnv("SMTP_PASSWORD", cfg.SMTPPassword)
+	cfg.MailFrom = getEnv("MAIL_FROM", cfg.MailFrom)
+
+	cfg.GeoCountryHeader = getEnv("GEO_COUNTRY_HEADER", cfg.GeoCountryHeader)
+
+	cfg.DevicePairingURL = getEnv("DEVICE_PAIRING_URL", cfg.DevicePairingURL)
+	devicePairings, err := getEnvInt("DEVICE_PAIRINGS_PER_HOUR", cfg.DevicePairingsPerHour)
+	if err != nil {
+		return err
+	}
+	cfg.DevicePairingsPerHour = devicePairings
+
+	if proxies := getEnvList("TRUSTED_PROXIES"); proxies != nil {
+		cfg.TrustedProxies = proxies
+	}
+	if headers := getEnvList("REAL_IP_HEADERS"); headers != nil {
+		cfg.RealIPHeaders = headers
+	}
+	cfg.TrustedPlatform = getEnv("TRUSTED_PLATFORM", cfg.TrustedPlatform)
+
+	cfg.TLSCertFile = getEnv("TLS_CERT_FILE", cfg.TLSCertFile)
+	cfg.TLSKeyFile = getEnv("TLS_KEY_FILE", cfg.TLSKeyFile)
+	if domains := getEnvList("TLS_AUTOCERT_DOMAINS"); domains != nil {
+		cfg.TLSAutocertDomains = domains
+	}
+	cfg.TLSAutocertEmail = getEnv("TLS_AUTOCERT_EMAIL", cfg.TLSAutocertEmail)
+	cfg.TLSAutocertCacheDir = getEnv("TLS_AUTOCERT_CACHE_DIR", cfg.TLSAutocertCacheDir)
+	cfg.HTTPRedirectPort = getEnv("HTTP_REDIRECT_PORT", cfg.HTTPRedirectPort)
+	cfg.UnixSocket = getEnv("UNIX_SOCKET", cfg.UnixSocket)
+	cfg.AdminListen = getEnv("ADMIN_LISTEN", cfg.AdminListen)
+	cfg.TermsVersion = getEnv("TERMS_VERSION", cfg.TermsVersion)
+
+	reminderDays, err := getEnvInt("RATING_REMINDER_DAYS", cfg.RatingReminderDays)
+	if err != nil {
+		return err
+	}
+	cfg.RatingReminderDays = reminderDays
+
+	reminderEmail, err := getEnvBool("RATING_REMINDER_EMAIL", cfg.RatingReminderEmail)
+	if err != nil {
+		return err
+	}
+	cfg.RatingReminderEmail = reminderEmail
+
+	maxWatchlist, err := getEnvInt("MAX_WATCHLIST_ENTRIES", cfg.MaxWatchlistEntries)
+	if err != nil {
+		return err
+	}
+	cfg.MaxWatchlistEntries = maxWatchlist
+
+	cacheMaxAge, err := getEnvInt("MOVIE_CACHE_MAX_AGE_DAYS", cfg.MovieCacheMaxAgeDays)
+	if err != nil {
+		return err
+	}
+	cfg.MovieCacheMaxAgeDays = cacheMaxAge
+
+	ratingBurst, err := getEnvInt("ANOMALY_RATING_BURST", cfg.AnomalyRatingBurst)
+	if err != nil {
+		return err
+	}
+	cfg.AnomalyRatingBurst = ratingBurst
+
+	watchlistChurn, err := getEnvInt("ANOMALY_WATCHLIST_CHURN", cfg.AnomalyWatchlistChurn)
+	if err != nil {
+		return err
+	}
+	cfg.AnomalyWatchlistChurn = watchlistChurn
+
+	throttleMinutes, err := getEnvInt("ANOMALY_THROTTLE_MINUTES", cfg.AnomalyThrottleMinutes)
+	if err != nil {
+		return err
+	}
+	cfg.AnomalyThrottleMinutes = throttleMinutes
+
+	throttlePerMinute, err := getEnvInt("ANOMALY_THROTTLE_PER_MINUTE", cfg.AnomalyThrottlePerMinute)
+	if err != nil {
+		return err
+	}
+	cfg.AnomalyThrottlePerMinute = throttlePerMinute
+
+	cfg.EmbeddingProvider = getEnv("EMBEDDING_PROVIDER", cfg.EmbeddingProvider)
+	cfg.EmbeddingAPIURL = getEnv("EMBEDDING_API_URL", cfg.EmbeddingAPIURL)
+	cfg.EmbeddingAPIKey = getEnv("EMBEDDING_API_KEY", cfg.EmbeddingAPIKey)
+	cfg.EmbeddingModel = getEnv("EMBEDDING_MODEL", cfg.EmbeddingModel)
+	cfg.EmbeddingVectorIndex = getEnv("EMBEDDING_VECTOR_INDEX", cfg.EmbeddingVectorIndex)
+
+	cfg.DiscoveryParser = getEnv("DISCOVERY_PARSER", cfg.DiscoveryParser)
+	cfg.DiscoveryLLMURL = getEnv("DISCOVERY_LLM_URL", cfg.DiscoveryLLMURL)
+	cfg.DiscoveryLLMKey = getEnv("DISCOVERY_LLM_KEY", cfg.DiscoveryLLMKey)
+	cfg.DiscoveryLLMModel = getEnv("DISCOVERY_LLM_MODEL", cfg.DiscoveryLLMModel)
+
+	cfg.EventStream = getEnv("EVENT_STREAM", cfg.EventStream)
+	cfg.EventStreamURL = getEnv("EVENT_STREAM_URL", cfg.EventStreamURL)
+	cfg.EventStreamPrefix = getEnv("EVENT_STREAM_PREFIX", cfg.EventStreamPrefix)
+
+	demoMode, err := getEnvBool("DEMO_MODE", cfg.DemoMode)
+	if err != nil {
+		return err
+	}
+	cfg.DemoMode = demoMode
+
+	demoMinutes, err := getEnvInt("DEMO_SESSION_MINUTES", cfg.DemoSessionMinutes)
+	if err != nil {
+		return err
+	}
+	cfg.DemoSessionMinutes = demoMinutes
+
+	demoSessions, err := getEnvInt("DEMO_SESSIONS_PER_HOUR", cfg.DemoSessionsPerHour)
+	if err != nil {
+		return err
+	}
+	cfg.DemoSessionsPerHour = demoSessions
+
+	demoRateLimit, err := getEnvInt("DEMO_RATE_LIMIT_PER_MINUTE", cfg.DemoRateLimitPerMinute)
+	if err != nil {
+		return err
+	}
+	cfg.DemoRateLimitPerMinute = demoRateLimit
+
+	cfg.ErrorReportingDSN = getEnv("ERROR_REPORTING_DSN", cfg.ErrorReportingDSN)
+
+	if keys := getEnvList("FIELD_ENCRYPTION_KEYS"); keys != nil {
+		cfg.FieldEncryptionKeys = keys
+	}
+
+	if ids := getEnvList("ADMIN_USER_IDS"); ids != nil {
+		cfg.AdminUserIDs = ids
+	}
+
+	cfg.GinMode = getEnv("GIN_MODE", cfg.GinMode)
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+	cfg.LogFormat = getEnv("LOG_FORMAT", cfg.LogFormat)
+	if levels := getEnvList("LOG_MODULE_LEVELS"); levels != nil {
+		cfg.LogModuleLevels = levels
+	}
+
+	sampleInitial, err := getEnvInt("LOG_SAMPLE_INITIAL", cfg.LogSampleInitial)
+	if err != nil {
+		return err
+	}
+	cfg.LogSampleInitial = sampleInitial
+
+	sampleThereafter, err := getEnvInt("LOG_SAMPLE_THEREAFTER", cfg.LogSampleThereafter)
+	if err != nil {
+		return err
+	}
+	cfg.LogSampleThereafter = sampleThereafter
+	if origins := getEnvList("CORS_ALLOWED_ORIGINS"); origins != nil {
+		cfg.CORSAllowedOrigins = origins
+	}
+
+	if value := os.Getenv("SEED_DATA"); value != "" {
+		seed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("SEED_DATA must be true or false, got %q", value)
+		}
+		cfg.SeedData = &seed
+	}
+
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -26,3 +689,40 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// getEnvList reads a comma-separated environment variable into a slice
+func getEnvList(key string) []string {
+	var values []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
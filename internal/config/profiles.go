@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Profile holds the settings that vary by APP_ENV
+type Profile struct {
+	GinMode            string
+	LogLevel           string
+	LogFormat          string
+	CORSAllowedOrigins []string
+	SeedData           bool
+}
+
+// profiles are the built-in defaults for each environment; any value set in a
+// config file or environment variable takes precedence
+var profiles = map[string]Profile{
+	"dev": {
+		GinMode:            "debug",
+		LogLevel:           "debug",
+		LogFormat:          "text",
+		CORSAllowedOrigins: []string{"*"},
+		SeedData:           true,
+	},
+	"staging": {
+		GinMode:   "release",
+		LogLevel:  "info",
+		LogFormat: "json",
+		SeedData:  true,
+	},
+	"prod": {
+		GinMode:   "release",
+		LogLevel:  "info",
+		LogFormat: "json",
+		SeedData:  false,
+	},
+}
+
+// loadProfileFile merges config.{env}.yaml from CONFIG_DIR (default: working
+// directory) into cfg when the file exists
+func loadProfileFile(cfg *Config) error {
+	env := cfg.Environment
+	path := filepath.Join(getEnv("CONFIG_DIR", "."), "config."+env+".yaml")
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err := loadFile(path, cfg); err != nil {
+		return err
+	}
+
+	// The profile file cannot switch the environment it was selected for
+	cfg.Environment = env
+	return nil
+}
+
+// applyProfileDefaults fills any profile setting left unset with the
+// defaults for the current environment
+func (c *Config) applyProfileDefaults() {
+	profile, ok := profiles[c.Environment]
+	if !ok {
+		return
+	}
+
+	if c.GinMode == "" {
+		c.GinMode = profile.GinMode
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = profile.LogLevel
+	}
+	if c.LogFormat == "" {
+		c.LogFormat = profile.LogFormat
+	}
+	if c.CORSAllowedOrigins == nil {
+		c.CORSAllowedOrigins = profile.CORSAllowedOrigins
+	}
+	if c.SeedData == nil {
+		seed := profile.SeedData
+		c.SeedData = &seed
+	}
+}
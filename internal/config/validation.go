@@ -0,0 +1,426 @@
+package config
+
+import (
+	"fmt"
+	"movie-watchlist/internal/errorreport"
+	"movie-watchlist/internal/fieldcrypt"
+	"movie-watchlist/internal/logging"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MinJWTSecretLength is the minimum accepted length for a non-default JWT secret
+const MinJWTSecretLength = 32
+
+// MinPasswordLength is the lowest PASSWORD_MIN_LENGTH that may be configured
+const MinPasswordLength = 6
+
+// telegramSecretPattern is the form Telegram accepts for a webhook secret token
+var telegramSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// ValidationError collects every configuration problem found during validation
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks required fields and formats, returning a ValidationError
+// that lists every problem so they can be fixed in one pass
+func (c *Config) Validate() error {
+	var problems []string
+
+	switch c.Environment {
+	case "dev", "staging", "prod":
+	default:
+		problems = append(problems, fmt.Sprintf("APP_ENV must be one of dev, staging, prod (got %q)", c.Environment))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be a number between 1 and 65535 (got %q)", c.Port))
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSCertFile != "" && len(c.TLSAutocertDomains) > 0 {
+		problems = append(problems, "TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS cannot both be set; use a certificate file or Let's Encrypt")
+	}
+	for _, domain := range c.TLSAutocertDomains {
+		if domain == "" || strings.ContainsAny(domain, "/:* ") {
+			problems = append(problems, fmt.Sprintf("TLS_AUTOCERT_DOMAINS must list host names without scheme, port or wildcards (got %q)", domain))
+		}
+	}
+	if len(c.TLSAutocertDomains) > 0 && c.TLSAutocertCacheDir == "" {
+		problems = append(problems, "TLS_AUTOCERT_CACHE_DIR must not be empty when TLS_AUTOCERT_DOMAINS is set, or every restart requests new certificates")
+	}
+	if c.HTTPRedirectPort != "" {
+		if port, err := strconv.Atoi(c.HTTPRedirectPort); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("HTTP_REDIRECT_PORT must be a number between 1 and 65535 (got %q)", c.HTTPRedirectPort))
+		} else if !c.TLSEnabled() {
+			problems = append(problems, "HTTP_REDIRECT_PORT needs TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		} else if c.HTTPRedirectPort == c.Port {
+			problems = append(problems, "HTTP_REDIRECT_PORT must differ from PORT")
+		}
+	}
+	if c.UnixSocket != "" && !filepath.IsAbs(c.UnixSocket) {
+		problems = append(problems, fmt.Sprintf("UNIX_SOCKET must be an absolute path (got %q)", c.UnixSocket))
+	}
+	if path, ok := strings.CutPrefix(c.AdminListen, "unix:"); ok {
+		if !filepath.IsAbs(path) {
+			problems = append(problems, fmt.Sprintf("ADMIN_LISTEN must be host:port or unix: followed by an absolute path (got %q)", c.AdminListen))
+		} else if path == c.UnixSocket {
+			problems = append(problems, "ADMIN_LISTEN must use a different socket than UNIX_SOCKET")
+		}
+	} else if c.AdminListen != "" {
+		_, port, err := net.SplitHostPort(c.AdminListen)
+		if n, convErr := strconv.Atoi(port); err != nil || convErr != nil || n < 1 || n > 65535 {
+			problems = append(problems, fmt.Sprintf("ADMIN_LISTEN must be host:port or unix: followed by an absolute path (got %q)", c.AdminListen))
+		} else if port == c.Port || port == c.HTTPRedirectPort {
+			problems = append(problems, "ADMIN_LISTEN must use a different port than PORT and HTTP_REDIRECT_PORT")
+		}
+	}
+
+	if u, err := url.Parse(c.DatabaseURL); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") || u.Host == "" {
+		problems = append(problems, "DATABASE_URL must be a valid mongodb:// or mongodb+srv:// connection string")
+	}
+
+	if c.JWTSecret == DefaultJWTSecret {
+		if !c.IsDevelopment() {
+			problems = append(problems, fmt.Sprintf("JWT_SECRET is still the default placeholder; set a random secret of at least %d characters (e.g. `openssl rand -hex 32`)", MinJWTSecretLength))
+		} else if !c.environmentSet {
+			problems = append(problems, "JWT_SECRET is still the default placeholder, which is only accepted when APP_ENV=dev is set explicitly")
+		}
+	} else if len(c.JWTSecret) < MinJWTSecretLength {
+		problems = append(problems, fmt.Sprintf("JWT_SECRET must be at least %d characters (got %d)", MinJWTSecretLength, len(c.JWTSecret)))
+	}
+
+	if c.JWTAccessTTLMinutes < 1 || c.JWTAccessTTLMinutes > 7*24*60 {
+		problems = append(problems, fmt.Sprintf("JWT_ACCESS_TTL_MINUTES must be between 1 and 10080 (got %d)", c.JWTAccessTTLMinutes))
+	}
+	if strings.TrimSpace(c.JWTIssuer) == "" {
+		problems = append(problems, "JWT_ISSUER must not be empty")
+	}
+	if strings.TrimSpace(c.JWTAudience) == "" {
+		problems = append(problems, "JWT_AUDIENCE must not be empty")
+	}
+
+	if len(c.OIDCClientIDs) > 0 {
+		for _, id := range c.OIDCClientIDs {
+			if id == "" || strings.ContainsAny(id, " \t\r\n") {
+				problems = append(problems, fmt.Sprintf("OIDC_CLIENT_IDS contains an invalid client ID %q", id))
+			}
+		}
+		if c.OIDCSigningKeyFile == "" && !c.IsDevelopment() {
+			problems = append(problems, "OIDC_SIGNING_KEY_FILE must point to an RSA private key when OIDC_CLIENT_IDS is set (e.g. `openssl genrsa -out oidc.pem 2048`)")
+		}
+	}
+
+	if c.TraktEnabled() {
+		if len(c.FieldEncryptionKeys) == 0 {
+			problems = append(problems, "FIELD_ENCRYPTION_KEYS must be set when TRAKT_CLIENT_ID is set, since Trakt tokens are stored encrypted")
+		}
+		if u, err := url.Parse(c.TraktBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("TRAKT_BASE_URL must be an http(s) URL (got %q)", c.TraktBaseURL))
+		}
+	} else if c.TraktClientID != "" || c.TraktClientSecret != "" {
+		problems = append(problems, "TRAKT_CLIENT_ID and TRAKT_CLIENT_SECRET must be set together")
+	}
+
+	if c.ChatWebhooks && len(c.FieldEncryptionKeys) == 0 {
+		problems = append(problems, "FIELD_ENCRYPTION_KEYS must be set when CHAT_WEBHOOKS is on, since webhook URLs are stored encrypted")
+	}
+
+	if c.TelegramBotToken != "" {
+		switch c.TelegramMode {
+		case "polling":
+		case "webhook":
+			if !telegramSecretPattern.MatchString(c.TelegramWebhookSecret) {
+				problems = append(problems, "TELEGRAM_WEBHOOK_SECRET must be 1-256 letters, digits, _ or - when TELEGRAM_MODE is webhook")
+			}
+			if !strings.HasPrefix(c.PublicBaseURL, "https://") {
+				problems = append(problems, "PUBLIC_BASE_URL must be an https URL when TELEGRAM_MODE is webhook, since Telegram only posts to HTTPS")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("TELEGRAM_MODE must be polling or webhook (got %q)", c.TelegramMode))
+		}
+		if u, err := url.Parse(c.TelegramBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("TELEGRAM_BASE_URL must be an http(s) URL (got %q)", c.TelegramBaseURL))
+		}
+	}
+
+	switch c.InboundEmailProvider {
+	case "":
+	case "mailgun", "postmark":
+		if c.InboundEmailDomain == "" || strings.ContainsAny(c.InboundEmailDomain, "@/: ") {
+			problems = append(problems, fmt.Sprintf("INBOUND_EMAIL_DOMAIN must be a bare domain when INBOUND_EMAIL_PROVIDER is set (got %q)", c.InboundEmailDomain))
+		}
+		if c.InboundEmailSecret == "" {
+			problems = append(problems, "INBOUND_EMAIL_SECRET must be set when INBOUND_EMAIL_PROVIDER is set, so forged mail is rejected")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("INBOUND_EMAIL_PROVIDER must be empty, mailgun or postmark (got %q)", c.InboundEmailProvider))
+	}
+
+	// A demo deployment can run on the seed catalogue alone, and replayed
+	// fixtures need no key
+	if strings.TrimSpace(c.OMDbAPIKey) == "" && !c.DemoMode && c.OMDbFixtures != "replay" {
+		problems = append(problems, "OMDB_API_KEY is required; get a key at https://www.omdbapi.com/apikey.aspx")
+	}
+
+	if len(c.MetadataProviders) == 0 {
+		problems = append(problems, "METADATA_PROVIDERS must name at least one provider")
+	}
+	seenProviders := map[string]bool{}
+	for _, provider := range c.MetadataProviders {
+		if provider != "omdb" && provider != "tmdb" {
+			problems = append(problems, fmt.Sprintf("METADATA_PROVIDERS may only contain omdb and tmdb (got %q)", provider))
+		} else if seenProviders[provider] {
+			problems = append(problems, fmt.Sprintf("METADATA_PROVIDERS lists %q twice", provider))
+		}
+		seenProviders[provider] = true
+	}
+
+	if u, err := url.Parse(c.OMDbBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("OMDB_BASE_URL must be an http(s) URL (got %q)", c.OMDbBaseURL))
+	}
+	switch c.OMDbFixtures {
+	case "":
+	case "record", "replay":
+		if c.Environment == "prod" {
+			problems = append(problems, "OMDB_FIXTURES cannot be used with APP_ENV=prod")
+		}
+		if strings.TrimSpace(c.OMDbFixturesDir) == "" {
+			problems = append(problems, "OMDB_FIXTURES_DIR must not be empty when OMDB_FIXTURES is set")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("OMDB_FIXTURES must be empty, record or replay (got %q)", c.OMDbFixtures))
+	}
+	if c.OMDbDailyLimit < 0 {
+		problems = append(problems, fmt.Sprintf("OMDB_DAILY_LIMIT cannot be negative (got %d)", c.OMDbDailyLimit))
+	}
+
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		problems = append(problems, fmt.Sprintf("GIN_MODE must be one of debug, release, test (got %q)", c.GinMode))
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES must list IPs or CIDRs (got %q)", proxy))
+				continue
+			}
+		}
+		// Trusting every address would let clients choose their own IP
+		if !c.IsDevelopment() && (proxy == "0.0.0.0/0" || proxy == "::/0") {
+			problems = append(problems, "TRUSTED_PROXIES cannot contain 0.0.0.0/0 or ::/0 outside dev; list the proxy addresses explicitly")
+		}
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be one of debug, info, warn, error (got %q)", c.LogLevel))
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be text or json (got %q)", c.LogFormat))
+	}
+
+	if _, err := logging.ParseModuleLevels(c.LogModuleLevels); err != nil {
+		problems = append(problems, "LOG_MODULE_LEVELS: "+err.Error())
+	}
+	if c.LogSampleInitial < 0 || c.LogSampleThereafter < 0 {
+		problems = append(problems, fmt.Sprintf("LOG_SAMPLE_INITIAL and LOG_SAMPLE_THEREAFTER cannot be negative (got %d and %d)", c.LogSampleInitial, c.LogSampleThereafter))
+	}
+
+	if !c.IsDevelopment() {
+		for _, origin := range c.CORSAllowedOrigins {
+			if origin == "*" {
+				problems = append(problems, "CORS_ALLOWED_ORIGINS cannot contain \"*\" outside dev; list the allowed origins explicitly")
+				break
+			}
+		}
+	}
+
+	if c.JobWorkers < 1 {
+		problems = append(problems, fmt.Sprintf("JOB_WORKERS must be at least 1 (got %d)", c.JobWorkers))
+	}
+
+	if c.PasswordMinLength < MinPasswordLength || c.PasswordMinLength > 72 {
+		problems = append(problems, fmt.Sprintf("PASSWORD_MIN_LENGTH must be between %d and 72 (got %d)", MinPasswordLength, c.PasswordMinLength))
+	}
+
+	switch c.CaptchaProvider {
+	case "":
+	case "turnstile", "hcaptcha", "recaptcha":
+		if c.CaptchaSecret == "" {
+			problems = append(problems, "CAPTCHA_SECRET is required when CAPTCHA_PROVIDER is set")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("CAPTCHA_PROVIDER must be turnstile, hcaptcha or recaptcha (got %q)", c.CaptchaProvider))
+	}
+	if c.CaptchaLoginAfterFailures < 0 {
+		problems = append(problems, fmt.Sprintf("CAPTCHA_LOGIN_AFTER_FAILURES must not be negative (got %d)", c.CaptchaLoginAfterFailures))
+	}
+
+	if len(c.TermsVersion) > 64 || strings.ContainsAny(c.TermsVersion, " \t\r\n") {
+		problems = append(problems, fmt.Sprintf("TERMS_VERSION must be at most 64 characters without spaces (got %q)", c.TermsVersion))
+	}
+
+	if u, err := url.Parse(c.PublicBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("PUBLIC_BASE_URL must be an absolute http(s) URL (got %q)", c.PublicBaseURL))
+	}
+
+	if c.OAuthConsentURL != "" {
+		if u, err := url.Parse(c.OAuthConsentURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			problems = append(problems, fmt.Sprintf("OAUTH_CONSENT_URL must be an absolute http(s) URL without a query (got %q)", c.OAuthConsentURL))
+		}
+	}
+	if c.DevicePairingURL != "" {
+		if u, err := url.Parse(c.DevicePairingURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			problems = append(problems, fmt.Sprintf("DEVICE_PAIRING_URL must be an absolute http(s) URL without a query (got %q)", c.DevicePairingURL))
+		}
+	}
+	if c.DevicePairingsPerHour < 0 {
+		problems = append(problems, fmt.Sprintf("DEVICE_PAIRINGS_PER_HOUR cannot be negative (got %d)", c.DevicePairingsPerHour))
+	}
+
+	if c.SMTPHost != "" {
+		if c.MailFrom == "" {
+			problems = append(problems, "MAIL_FROM is required when SMTP_HOST is set")
+		}
+		if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+			problems = append(problems, fmt.Sprintf("SMTP_PORT must be between 1 and 65535 (got %d)", c.SMTPPort))
+		}
+	}
+
+	if c.MaxBodyBytes < 1 {
+		problems = append(problems, fmt.Sprintf("MAX_BODY_BYTES must be at least 1 (got %d)", c.MaxBodyBytes))
+	}
+
+	if c.RateLimitPerMinute < 0 {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_PER_MINUTE cannot be negative (got %d)", c.RateLimitPerMinute))
+	}
+	if c.SearchRateLimitPerHour < 0 {
+		problems = append(problems, fmt.Sprintf("SEARCH_RATE_LIMIT_PER_HOUR cannot be negative (got %d)", c.SearchRateLimitPerHour))
+	}
+
+	if c.RatingReminderDays < 0 {
+		problems = append(problems, fmt.Sprintf("RATING_REMINDER_DAYS cannot be negative (got %d)", c.RatingReminderDays))
+	}
+	if c.MaxWatchlistEntries < 0 {
+		problems = append(problems, fmt.Sprintf("MAX_WATCHLIST_ENTRIES cannot be negative (got %d)", c.MaxWatchlistEntries))
+	}
+	if c.MovieCacheMaxAgeDays < 0 {
+		problems = append(problems, fmt.Sprintf("MOVIE_CACHE_MAX_AGE_DAYS cannot be negative (got %d)", c.MovieCacheMaxAgeDays))
+	}
+
+	if c.AnomalyRatingBurst < 0 {
+		problems = append(problems, fmt.Sprintf("ANOMALY_RATING_BURST cannot be negative (got %d)", c.AnomalyRatingBurst))
+	}
+	if c.AnomalyWatchlistChurn < 0 {
+		problems = append(problems, fmt.Sprintf("ANOMALY_WATCHLIST_CHURN cannot be negative (got %d)", c.AnomalyWatchlistChurn))
+	}
+	if c.AnomalyThrottleMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("ANOMALY_THROTTLE_MINUTES cannot be negative (got %d)", c.AnomalyThrottleMinutes))
+	}
+	if c.AnomalyThrottleMinutes > 0 && c.AnomalyThrottlePerMinute < 1 {
+		problems = append(problems, fmt.Sprintf("ANOMALY_THROTTLE_PER_MINUTE must be at least 1 when ANOMALY_THROTTLE_MINUTES is set (got %d)", c.AnomalyThrottlePerMinute))
+	}
+
+	switch c.EmbeddingProvider {
+	case "local":
+	case "api":
+		if u, err := url.Parse(c.EmbeddingAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("EMBEDDING_API_URL must be an absolute http(s) URL when EMBEDDING_PROVIDER is api (got %q)", c.EmbeddingAPIURL))
+		}
+		if c.EmbeddingModel == "" {
+			problems = append(problems, "EMBEDDING_MODEL is required when EMBEDDING_PROVIDER is api")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("EMBEDDING_PROVIDER must be local or api (got %q)", c.EmbeddingProvider))
+	}
+
+	switch c.DiscoveryParser {
+	case "rules":
+	case "llm":
+		if u, err := url.Parse(c.DiscoveryLLMURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("DISCOVERY_LLM_URL must be an absolute http(s) URL when DISCOVERY_PARSER is llm (got %q)", c.DiscoveryLLMURL))
+		}
+		if c.DiscoveryLLMModel == "" {
+			problems = append(problems, "DISCOVERY_LLM_MODEL is required when DISCOVERY_PARSER is llm")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DISCOVERY_PARSER must be rules or llm (got %q)", c.DiscoveryParser))
+	}
+
+	switch c.EventStream {
+	case "":
+	case "kafka":
+		if u, err := url.Parse(c.EventStreamURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "EVENT_STREAM_URL must be the absolute http(s) URL of a Kafka REST Proxy when EVENT_STREAM is kafka")
+		}
+	case "nats":
+		if u, err := url.Parse(c.EventStreamURL); err != nil || u.Scheme != "nats" || u.Hostname() == "" {
+			problems = append(problems, "EVENT_STREAM_URL must be a nats://host[:port] URL when EVENT_STREAM is nats")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("EVENT_STREAM must be kafka or nats, or empty to disable streaming (got %q)", c.EventStream))
+	}
+	if c.EventStream != "" && (c.EventStreamPrefix == "" || strings.ContainsAny(c.EventStreamPrefix, " \t\r\n*>/")) {
+		problems = append(problems, fmt.Sprintf("EVENT_STREAM_PREFIX must be non-empty without spaces, wildcards or slashes (got %q)", c.EventStreamPrefix))
+	}
+
+	if c.DemoMode {
+		if c.DemoSessionMinutes < 1 || c.DemoSessionMinutes > 24*60 {
+			problems = append(problems, fmt.Sprintf("DEMO_SESSION_MINUTES must be between 1 and 1440 (got %d)", c.DemoSessionMinutes))
+		}
+		if c.DemoSessionsPerHour < 1 {
+			problems = append(problems, fmt.Sprintf("DEMO_SESSIONS_PER_HOUR must be at least 1 when DEMO_MODE is on (got %d)", c.DemoSessionsPerHour))
+		}
+		if c.DemoRateLimitPerMinute < 1 {
+			problems = append(problems, fmt.Sprintf("DEMO_RATE_LIMIT_PER_MINUTE must be at least 1 when DEMO_MODE is on (got %d)", c.DemoRateLimitPerMinute))
+		}
+	}
+
+	if c.ErrorReportingDSN != "" {
+		if _, _, err := errorreport.ParseDSN(c.ErrorReportingDSN); err != nil {
+			problems = append(problems, "ERROR_REPORTING_DSN: "+err.Error())
+		}
+	}
+
+	if _, err := fieldcrypt.ParseKeys(c.FieldEncryptionKeys); err != nil {
+		problems = append(problems, "FIELD_ENCRYPTION_KEYS: "+err.Error())
+	}
+
+	for _, id := range c.AdminUserIDs {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			problems = append(problems, fmt.Sprintf("ADMIN_USER_IDS contains an invalid user ID %q", id))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// RedactedDatabaseURL returns the database URL with any password masked, safe for logging
+func (c *Config) RedactedDatabaseURL() string {
+	u, err := url.Parse(c.DatabaseURL)
+	if err != nil {
+		return "<unparseable>"
+	}
+	return u.Redacted()
+}
@@ -2,9 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	
+	"movie-watchlist/internal/logging"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,16 +13,58 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// defaultCompressors are offered to the server for wire compression, in order
+// of preference, unless the connection string sets compressors itself. The
+// server picks the first one it supports; without any, traffic is sent
+// uncompressed.
+var defaultCompressors = []string{"zstd", "snappy", "zlib"}
+
 type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
 }
 
+// defaultDatabaseName is the database the server uses
+const defaultDatabaseName = "movie_watchlist"
+
 func Connect(mongoURI string) (*MongoDB, error) {
+	return ConnectTo(mongoURI, defaultDatabaseName)
+}
+
+// ConnectTo is Connect for the named database, such as a throwaway one in tests
+func ConnectTo(mongoURI, dbName string) (*MongoDB, error) {
+	database, err := open(mongoURI, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indexes are created one by one, so they get longer than the connection
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Create indexes; the server still starts with the ones that failed
+	// missing, and `go run ./cmd/doctor` lists them
+	if err := database.createIndexes(ctx); err != nil {
+		logging.For("database").Warn("failed to create indexes", "error", err)
+	}
+
+	return database, nil
+}
+
+// Open connects to MongoDB like Connect without creating indexes
+func Open(mongoURI string) (*MongoDB, error) {
+	return open(mongoURI, defaultDatabaseName)
+}
+
+func open(mongoURI, dbName string) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	clientOptions := options.Client().ApplyURI(mongoURI)
+	if clientOptions.Compressors == nil {
+		clientOptions.SetCompressors(defaultCompressors)
+	}
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -31,74 +74,370 @@ func Connect(mongoURI string) (*MongoDB, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	// Get database name from URI or use default
-	dbName := "movie_watchlist"
-	if mongoURI[len(mongoURI)-1] == '/' {
-		dbName = "movie_watchlist"
-	}
-
-	database := &MongoDB{
+	return &MongoDB{
 		Client:   client,
 		Database: client.Database(dbName),
-	}
-
-	// Create indexes
-	if err := database.createIndexes(ctx); err != nil {
-		log.Printf("Warning: Failed to create indexes: %v", err)
-	}
+	}, nil
+}
 
-	return database, nil
+// collectionIndexes are the indexes of one collection
+type collectionIndexes struct {
+	collection string
+	models     []mongo.IndexModel
 }
 
-func (db *MongoDB) createIndexes(ctx context.Context) error {
+// indexes lists every index the queries rely on. Connect creates them, and
+// MissingIndexes reports those that do not exist.
+var indexes = []collectionIndexes{
 	// Users collection indexes
-	usersCollection := db.Database.Collection("users")
-	_, err := usersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+	{"users", []mongo.IndexModel{
 		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create users indexes: %w", err)
-	}
+		// The recommendation scheduler looks up users on a daily or weekly schedule
+		{Keys: bson.D{{Key: "recommendation_settings.frequency", Value: 1}, {Key: "recommendations_refreshed_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Expired demo sandbox users are looked up for cleanup
+		{Keys: bson.D{{Key: "demo_expires_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Deactivated users are looked up for deletion once the grace period ends
+		{Keys: bson.D{{Key: "deactivated_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Calendar feeds are looked up by the hash of their token
+		{Keys: bson.D{{Key: "calendar_feed_token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		// Inbound email is routed by the hash of the address's local part
+		{Keys: bson.D{{Key: "inbound_email_token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	}},
 
 	// Movies collection indexes
-	moviesCollection := db.Database.Collection("movies")
-	_, err = moviesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+	{"movies", []mongo.IndexModel{
 		{Keys: bson.D{{Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Legacy duplicates are found from their canonical movie
+		{Keys: bson.D{{Key: "duplicate_of", Value: 1}}, Options: options.Index().SetSparse(true)},
 		{Keys: bson.D{{Key: "title", Value: 1}}},
+		{Keys: bson.D{{Key: "title", Value: "text"}}},
+		// Case-insensitive title index backing prefix typeahead lookups
+		{Keys: bson.D{{Key: "title", Value: 1}}, Options: options.Index().SetName("title_ci").SetCollation(&options.Collation{Locale: "en", Strength: 2})},
 		{Keys: bson.D{{Key: "genre", Value: 1}}},
 		{Keys: bson.D{{Key: "cached_at", Value: 1}}},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create movies indexes: %w", err)
-	}
+		// Browse queries filter on the release year and sort by score
+		{Keys: bson.D{{Key: "year_start", Value: 1}, {Key: "imdb_score", Value: -1}}},
+		{Keys: bson.D{{Key: "imdb_score", Value: -1}}},
+		// Keyword filters and keyword-overlap recommendations
+		{Keys: bson.D{{Key: "keywords", Value: 1}}},
+		// Provider reconciliation picks the longest unchecked movies first
+		{Keys: bson.D{{Key: "reconciled_at", Value: 1}}},
+		// The poster checker does the same for poster links
+		{Keys: bson.D{{Key: "poster_checked_at", Value: 1}}},
+	}},
 
 	// Watchlists collection indexes
-	watchlistsCollection := db.Database.Collection("watchlists")
-	_, err = watchlistsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+	{"watchlists", []mongo.IndexModel{
 		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.D{{Key: "user_id", Value: 1}}},
 		{Keys: bson.D{{Key: "movie_id", Value: 1}}},
 		{Keys: bson.D{{Key: "added_at", Value: 1}}},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create watchlists indexes: %w", err)
-	}
+	}},
 
 	// Ratings collection indexes
-	ratingsCollection := db.Database.Collection("ratings")
-	_, err = ratingsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+	{"ratings", []mongo.IndexModel{
 		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.D{{Key: "user_id", Value: 1}}},
 		{Keys: bson.D{{Key: "movie_id", Value: 1}}},
 		{Keys: bson.D{{Key: "rating", Value: 1}}},
 		{Keys: bson.D{{Key: "created_at", Value: 1}}},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create ratings indexes: %w", err)
+	}},
+
+	// Jobs collection indexes
+	{"jobs", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}}},
+	}},
+
+	// User activity collection indexes
+	{"user_activity", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "day", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "day", Value: 1}}},
+	}},
+
+	// Sessions collection indexes; expired sessions are removed by the TTL index
+	{"sessions", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_used_at", Value: -1}}},
+		{Keys: bson.D{{Key: "client_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+
+	// Pending email changes; expired confirmation links are removed by the TTL index
+	{"email_changes", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+
+	// Third-party apps and the authorization codes granted to them; unused
+	// codes are removed by the TTL index
+	{"oauth_apps", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}},
+	{"oauth_codes", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "client_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+
+	// Devices waiting to be signed in; expired pairings are removed by the TTL index
+	{"device_pairings", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "device_code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_code_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+
+	// Soft-deleted items awaiting undo; the TTL index removes them once the window closes
+	{"deleted_items", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+
+	// Login history; attempts are kept for 90 days
+	{"login_attempts", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "revoke_token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(90 * 24 * 60 * 60)},
+	}},
+
+	// Notifications collection indexes
+	{"notifications", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "movie_id", Value: 1}}},
+	}},
+
+	// Watch progress collection indexes
+	{"watch_progress", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		// Cache eviction checks whether anyone is watching a movie
+		{Keys: bson.D{{Key: "movie_id", Value: 1}}},
+	}},
+
+	// Recently viewed collection indexes
+	{"recently_viewed", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "viewed_at", Value: -1}}},
+		// Cache eviction checks whether anyone viewed a movie lately
+		{Keys: bson.D{{Key: "movie_id", Value: 1}}},
+	}},
+
+	// Kids profile indexes
+	{"profiles", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	}},
+
+	// Upcoming releases indexes
+	{"upcoming_releases", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "release_date", Value: 1}}},
+		// Calendar feeds look up the releases of watchlist movies
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "release_date", Value: 1}}},
+	}},
+
+	// Followed directors and actors: one follow per user, role and name,
+	// and followers looked up by name when a release is found
+	{"person_follows", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "role", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "role", Value: 1}, {Key: "name", Value: 1}}},
+	}},
+
+	// Movies shared between users: one share per sender, recipient and
+	// movie, the inbox newest first, the sender's daily limit, and cache
+	// eviction checking whether a movie is in anyone's inbox
+	{"movie_shares", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "sender_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "movie_id", Value: 1}}},
+	}},
+
+	// Written reviews: one per user and movie, and a movie's reviews by
+	// helpfulness or newest first. Votes are one per review and user, and
+	// looked up by voter when an account is deleted.
+	{"reviews", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "helpful_count", Value: -1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}},
+	{"review_votes", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "review_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}},
+
+	// Movie night polls, listed for their owner and invitees, with one
+	// ballot per poll and voter. Cache eviction checks the polls' movies.
+	{"polls", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "participants.user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "movie_ids", Value: 1}}},
+	}},
+	{"poll_ballots", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "poll_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}},
+
+	// Content advisory reports: one per user, movie and advisory, and the
+	// moderation queue by status
+	{"advisory_reports", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "advisory", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}},
+
+	// Linked Trakt accounts: one per user, found by OAuth state on callback
+	{"trakt_links", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+	}},
+
+	// Chat webhooks, one per user
+	{"chat_webhooks", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+
+	// Slack links: one per user, found by link code and by Slack user when a
+	// slash command arrives
+	{"slack_links", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "team_id", Value: 1}, {Key: "slack_user_id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"slack_user_id": bson.M{"$exists": true}})},
+	}},
+
+	// Telegram links: one per user, found by link code and by chat when an
+	// update arrives, and scanned for chats that receive notifications
+	{"telegram_links", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "code_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "chat_id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"chat_id": bson.M{"$exists": true}})},
+		{Keys: bson.D{{Key: "notify", Value: 1}}},
+	}},
+
+	// Plot embeddings, one per movie and embedding model
+	{"movie_embeddings", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "model", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "model", Value: 1}}},
+	}},
+
+	// Precomputed recommendation rows, one document per user
+	{"recommendation_snapshots", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+
+	// Awarded badges, one per user and badge
+	{"achievements", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "badge", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+
+	// Movie revisions, listed per movie and kept for a year
+	{"movie_revisions", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "movie_id", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "changed_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(365 * 24 * 60 * 60)},
+	}},
+
+	// Recommendation impressions, one per user, movie and row a day, matched
+	// to conversions by user and movie and kept for 180 days
+	{"recommendation_impressions", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "movie_id", Value: 1}, {Key: "row", Value: 1}, {Key: "day", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "shown_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(180 * 24 * 60 * 60)},
+	}},
+
+	// Event outbox, relayed in insertion order; delivered events are kept
+	// for 7 days
+	{"event_outbox", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "delivered_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "delivered_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+	}},
+
+	// Dashboard read models, one per user or kids profile
+	{"dashboards", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+
+	// Provider conflicts, one per movie, listed by status
+	{"metadata_conflicts", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "movie_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+	}},
+
+	// Accounts flagged by the anomaly detector: one open flag per account
+	// and reason, listed by status, and throttles reloaded on startup
+	{"account_flags", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "reason", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "open"})},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "throttled_until", Value: 1}}, Options: options.Index().SetSparse(true)},
+	}},
+
+	// Offline recommendation evaluation runs, listed newest first
+	{"recommendation_evaluations", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "finished_at", Value: -1}}},
+	}},
+
+	// Deprecation notices, one per route
+	{"route_deprecations", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "method", Value: 1}, {Key: "path", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+}
+
+// createIndexes creates every index in indexes, one at a time, so one that
+// fails, such as a unique index over duplicate data, does not keep the
+// others from being created. It returns the failures joined, each naming
+// its index.
+func (db *MongoDB) createIndexes(ctx context.Context) error {
+	var errs []error
+	for _, spec := range indexes {
+		collection := db.Database.Collection(spec.collection)
+		for _, model := range spec.models {
+			if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+				errs = append(errs, fmt.Errorf("failed to create index %s.%s: %w", spec.collection, indexName(model), err))
+			}
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// MissingIndexes returns "collection.index" for each index in indexes that
+// does not exist, such as a unique index that could not be created because
+// of duplicate values
+func (db *MongoDB) MissingIndexes(ctx context.Context) ([]string, error) {
+	var missing []string
+	for _, spec := range indexes {
+		existing := make(map[string]bool)
+		specs, err := db.Database.Collection(spec.collection).Indexes().ListSpecifications(ctx)
+		var commandErr mongo.CommandError
+		if err != nil && !(errors.As(err, &commandErr) && commandErr.Code == namespaceNotFound) {
+			return nil, fmt.Errorf("failed to list %s indexes: %w", spec.collection, err)
+		}
+		for _, index := range specs {
+			existing[index.Name] = true
+		}
+		for _, model := range spec.models {
+			if name := indexName(model); !existing[name] {
+				missing = append(missing, spec.collection+"."+name)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// namespaceNotFound is the error code for listing the indexes of a
+// collection that does not exist yet
+const namespaceNotFound = 26
+
+// indexName is the name MongoDB gives an index: the one set in its options,
+// or its keys and directions joined by underscores
+func indexName(model mongo.IndexModel) string {
+	if model.Options != nil && model.Options.Name != nil {
+		return *model.Options.Name
+	}
+	var parts []string
+	for _, key := range model.Keys.(bson.D) {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
 }
 
 func (db *MongoDB) Close() error {
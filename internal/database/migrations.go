@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BackfillMovieNumericFields derives year_start, year_end and imdb_score for
+// movies cached before those fields existed. Movies that already have them
+// are skipped, so running it on every startup is cheap.
+func (db *MongoDB) BackfillMovieNumericFields() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	collection := db.GetCollection("movies")
+	filter := bson.M{"$or": bson.A{
+		bson.M{"year_start": bson.M{"$exists": false}},
+		bson.M{"imdb_score": bson.M{"$exists": false}},
+	}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"year": 1, "imdb_rating": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find movies to backfill: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var movie models.Movie
+		if err := cursor.Decode(&movie); err != nil {
+			return updated, err
+		}
+		movie.DeriveNumericFields()
+
+		_, err := collection.UpdateByID(ctx, movie.ID, bson.M{"$set": bson.M{
+			"year_start": movie.YearStart,
+			"year_end":   movie.YearEnd,
+			"imdb_score": movie.IMDbScore,
+		}})
+		if err != nil {
+			return updated, fmt.Errorf("failed to backfill movie %s: %w", movie.ID.Hex(), err)
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
+// NormalizeMovieIMDbIDs rewrites IMDb IDs stored before they were normalized
+// on write, so lookups can match them exactly. A movie whose normalized ID is
+// already taken is a duplicate and is pointed at that movie instead.
+func (db *MongoDB) NormalizeMovieIMDbIDs() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	collection := db.GetCollection("movies")
+	// The pattern is checked against the imdb_id index keys, so only legacy
+	// spellings are fetched; duplicates already marked are skipped
+	filter := bson.M{
+		"imdb_id":      primitive.Regex{Pattern: `[A-Z]|^\s|\s$`},
+		"duplicate_of": bson.M{"$exists": false},
+	}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"imdb_id": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find movies to normalize: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var movie models.Movie
+		if err := cursor.Decode(&movie); err != nil {
+			return updated, err
+		}
+		imdbID := models.NormalizeIMDbID(movie.IMDbID)
+
+		_, err := collection.UpdateByID(ctx, movie.ID, bson.M{"$set": bson.M{"imdb_id": imdbID}})
+		if mongo.IsDuplicateKeyError(err) {
+			var canonical models.Movie
+			err = collection.FindOne(ctx, bson.M{"imdb_id": imdbID}, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&canonical)
+			if err == nil {
+				_, err = collection.UpdateByID(ctx, movie.ID, bson.M{"$set": bson.M{"duplicate_of": canonical.ID}})
+			}
+		}
+		if err != nil {
+			return updated, fmt.Errorf("failed to normalize IMDb ID of movie %s: %w", movie.ID.Hex(), err)
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seedMovies is a small catalogue used to populate an empty movies collection
+// in development and staging so recommendations work without OMDb calls
+var seedMovies = []models.Movie{
+	{IMDbID: "tt0111161", Title: "The Shawshank Redemption", Year: "1994", Genre: "Drama", Director: "Frank Darabont", Runtime: "142 min", IMDbRating: "9.3", Language: "English", Rated: "R"},
+	{IMDbID: "tt0068646", Title: "The Godfather", Year: "1972", Genre: "Crime, Drama", Director: "Francis Ford Coppola", Runtime: "175 min", IMDbRating: "9.2", Language: "English, Italian, Latin", Rated: "R"},
+	{IMDbID: "tt0468569", Title: "The Dark Knight", Year: "2008", Genre: "Action, Crime, Drama", Director: "Christopher Nolan", Runtime: "152 min", IMDbRating: "9.0", Language: "English", Rated: "PG-13"},
+	{IMDbID: "tt1375666", Title: "Inception", Year: "2010", Genre: "Action, Adventure, Sci-Fi", Director: "Christopher Nolan", Runtime: "148 min", IMDbRating: "8.8", Language: "English, Japanese, French", Rated: "PG-13"},
+	{IMDbID: "tt0133093", Title: "The Matrix", Year: "1999", Genre: "Action, Sci-Fi", Director: "Lana Wachowski, Lilly Wachowski", Runtime: "136 min", IMDbRating: "8.7", Language: "English", Rated: "R"},
+	{IMDbID: "tt0245429", Title: "Spirited Away", Year: "2001", Genre: "Animation, Adventure, Family", Director: "Hayao Miyazaki", Runtime: "125 min", IMDbRating: "8.6", Language: "Japanese", Rated: "PG"},
+	{IMDbID: "tt0110912", Title: "Pulp Fiction", Year: "1994", Genre: "Crime, Drama", Director: "Quentin Tarantino", Runtime: "154 min", IMDbRating: "8.9", Language: "English, Spanish, French", Rated: "R"},
+	{IMDbID: "tt0107290", Title: "Jurassic Park", Year: "1993", Genre: "Action, Adventure, Sci-Fi", Director: "Steven Spielberg", Runtime: "127 min", IMDbRating: "8.2", Language: "English", Rated: "PG-13"},
+}
+
+// SeedMovies inserts the seed catalogue when the movies collection is empty
+func (db *MongoDB) SeedMovies() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.GetCollection("movies")
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to count movies: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	docs := make([]interface{}, 0, len(seedMovies))
+	for _, movie := range seedMovies {
+		movie.ID = primitive.NewObjectID()
+		movie.Source = models.MovieSourceSeed
+		movie.CachedAt = now
+		movie.CreatedAt = now
+		movie.UpdatedAt = now
+		movie.DeriveNumericFields()
+		docs = append(docs, movie)
+	}
+
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to seed movies: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Package errorreport forwards unexpected failures, such as recovered panics,
+// to an external error tracker.
+package errorreport
+
+import (
+	"context"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"time"
+)
+
+// Event describes one failure to report
+type Event struct {
+	Message   string
+	Stack     string
+	RequestID string
+	Method    string
+	Path      string
+	UserID    string
+	Time      time.Time
+}
+
+// Reporter sends failure events somewhere they can be seen. Implementations
+// must not block the caller for long; Report is called on the request path.
+type Reporter interface {
+	Report(ctx context.Context, event Event)
+}
+
+// LogReporter writes events to the application log; used when no external
+// error tracker is configured
+type LogReporter struct {
+	logger *slog.Logger
+}
+
+func NewLogReporter() *LogReporter {
+	return &LogReporter{logger: logging.For("errors")}
+}
+
+func (r *LogReporter) Report(_ context.Context, event Event) {
+	r.logger.Error(event.Message,
+		"request_id", event.RequestID,
+		"method", event.Method,
+		"path", event.Path,
+		"user_id", event.UserID,
+		"stack", event.Stack)
+}
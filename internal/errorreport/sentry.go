@@ -0,0 +1,152 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sentryTimeout bounds each delivery; events are sent in the background
+const sentryTimeout = 5 * time.Second
+
+// SentryReporter posts events to a Sentry-compatible store endpoint derived
+// from a DSN such as https://<key>@sentry.example.com/<project>. Delivery is
+// asynchronous and best-effort; failures are logged and events are also
+// written to the log so nothing is lost silently.
+type SentryReporter struct {
+	endpoint    string
+	authHeader  string
+	environment string
+	client      *http.Client
+	fallback    *LogReporter
+	logger      *slog.Logger
+}
+
+// NewSentryReporter parses the DSN and returns a reporter for it
+func NewSentryReporter(dsn, environment string) (*SentryReporter, error) {
+	endpoint, key, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SentryReporter{
+		endpoint:    endpoint,
+		authHeader:  fmt.Sprintf("Sentry sentry_version=7, sentry_client=movie-watchlist/1.0, sentry_key=%s", key),
+		environment: environment,
+		client:      &http.Client{Timeout: sentryTimeout},
+		fallback:    NewLogReporter(),
+		logger:      logging.For("errors"),
+	}, nil
+}
+
+// ParseDSN splits a Sentry DSN into its store endpoint and public key
+func ParseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("error reporting DSN must be an http(s) URL")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("error reporting DSN is missing the public key")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	prefix, project := "", path
+	if i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+	if project == "" {
+		return "", "", fmt.Errorf("error reporting DSN is missing the project ID")
+	}
+
+	endpoint = fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project)
+	return endpoint, u.User.Username(), nil
+}
+
+func (r *SentryReporter) Report(_ context.Context, event Event) {
+	r.fallback.Report(context.Background(), event)
+
+	payload := r.buildPayload(event)
+	go func() {
+		if err := r.send(payload); err != nil {
+			r.logger.Warn("failed to deliver error report", "request_id", event.RequestID, "error", err)
+		}
+	}()
+}
+
+func (r *SentryReporter) buildPayload(event Event) map[string]interface{} {
+	timestamp := event.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    newEventID(),
+		"timestamp":   timestamp.UTC().Format(time.RFC3339),
+		"level":       "error",
+		"platform":    "go",
+		"logger":      "movie-watchlist",
+		"environment": r.environment,
+		"message":     event.Message,
+		"tags": map[string]string{
+			"request_id": event.RequestID,
+		},
+		"request": map[string]string{
+			"method": event.Method,
+			"url":    event.Path,
+		},
+		"extra": map[string]string{
+			"stack": event.Stack,
+		},
+	}
+	if event.UserID != "" {
+		payload["user"] = map[string]string{"id": event.UserID}
+	}
+	return payload
+}
+
+func (r *SentryReporter) send(payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.authHeader)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracker returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newEventID returns a random 32-character hex event ID
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strings.Repeat("0", 32)
+	}
+	return hex.EncodeToString(b)
+}
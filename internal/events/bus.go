@@ -0,0 +1,73 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"sync"
+)
+
+// Event is something that happened in the domain. Name identifies the event
+// type that subscribers register for.
+type Event interface {
+	Name() string
+}
+
+// Handler reacts to a published event. Its error is logged; it does not fail
+// the publisher or keep other handlers from running.
+type Handler func(event Event) error
+
+type subscription struct {
+	consumer string
+	handler  Handler
+}
+
+// Bus delivers domain events from the services that publish them to the
+// consumers with side effects, so adding a side effect does not mean editing
+// the originating service. Delivery is in-process and synchronous: handlers
+// run in subscription order before Publish returns, and work that must
+// survive a restart should be handed to the job queue.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[string][]subscription
+	logger        *slog.Logger
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: map[string][]subscription{},
+		logger:        logging.For("events"),
+	}
+}
+
+// Subscribe registers handler for events with the given name; consumer names
+// the subscriber in logs
+func (b *Bus) Subscribe(name, consumer string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[name] = append(b.subscriptions[name], subscription{consumer: consumer, handler: handler})
+}
+
+// Publish delivers the event to every subscriber of its name
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	subscriptions := b.subscriptions[event.Name()]
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		if err := b.deliver(sub, event); err != nil {
+			b.logger.Warn("event handler failed", "event", event.Name(), "consumer", sub.consumer, "error", err)
+		}
+	}
+}
+
+// deliver runs one handler, turning a panic into an error so a broken
+// consumer cannot fail the request that published the event
+func (b *Bus) deliver(sub subscription, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return sub.handler(event)
+}
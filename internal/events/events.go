@@ -0,0 +1,145 @@
+package events
+
+import (
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event names
+const (
+	NameUserRegistered           = "user.registered"
+	NameMovieRated               = "movie.rated"
+	NameRatingUpdated            = "movie.rating_updated"
+	NameWatchlistItemAdded       = "watchlist.item_added"
+	NameWatchlistItemRemoved     = "watchlist.item_removed"
+	NameWatchlistItemUpdated     = "watchlist.item_updated"
+	NameRecommendationsRefreshed = "recommendations.refreshed"
+	NamePollCreated              = "poll.created"
+	NamePollUpdated              = "poll.updated"
+	NamePollClosed               = "poll.closed"
+)
+
+// UserRegistered is published when an account signs up. The email address
+// is left out of streamed events.
+type UserRegistered struct {
+	UserID   primitive.ObjectID `json:"user_id"`
+	Username string             `json:"username"`
+	Email    string             `json:"-"`
+	At       time.Time          `json:"at"`
+}
+
+func (UserRegistered) Name() string { return NameUserRegistered }
+
+// MovieRated is published when a user rates a movie for the first time.
+// MovieID is the canonical copy; EquivalentIDs also lists its duplicates.
+type MovieRated struct {
+	UserID        primitive.ObjectID   `json:"user_id"`
+	MovieID       primitive.ObjectID   `json:"movie_id"`
+	EquivalentIDs []primitive.ObjectID `json:"-"`
+	Rating        int                  `json:"rating"`
+	At            time.Time            `json:"at"`
+}
+
+func (MovieRated) Name() string { return NameMovieRated }
+
+// RatingUpdated is published when a user changes their rating of a movie
+type RatingUpdated struct {
+	UserID        primitive.ObjectID   `json:"user_id"`
+	MovieID       primitive.ObjectID   `json:"movie_id"`
+	EquivalentIDs []primitive.ObjectID `json:"-"`
+	Rating        int                  `json:"rating"`
+	At            time.Time            `json:"at"`
+}
+
+func (RatingUpdated) Name() string { return NameRatingUpdated }
+
+// WatchlistItemAdded is published when a user adds a movie to their
+// watchlist. MovieID is the canonical copy; EquivalentIDs also lists its
+// duplicates.
+type WatchlistItemAdded struct {
+	UserID        primitive.ObjectID      `json:"user_id"`
+	MovieID       primitive.ObjectID      `json:"movie_id"`
+	EquivalentIDs []primitive.ObjectID    `json:"-"`
+	Source        *models.WatchlistSource `json:"source,omitempty"`
+	At            time.Time               `json:"at"`
+}
+
+func (WatchlistItemAdded) Name() string { return NameWatchlistItemAdded }
+
+// WatchlistItemRemoved is published when a user removes a movie from their
+// watchlist
+type WatchlistItemRemoved struct {
+	UserID  primitive.ObjectID `json:"user_id"`
+	MovieID primitive.ObjectID `json:"movie_id"`
+	At      time.Time          `json:"at"`
+}
+
+func (WatchlistItemRemoved) Name() string { return NameWatchlistItemRemoved }
+
+// Watchlist entry changes reported by WatchlistItemUpdated
+const (
+	ChangeWatched   = "watched"
+	ChangeUnwatched = "unwatched"
+	ChangePriority  = "priority"
+)
+
+// WatchlistItemUpdated is published when a watchlist entry is marked watched
+// or unwatched or its priority changes
+type WatchlistItemUpdated struct {
+	UserID   primitive.ObjectID `json:"user_id"`
+	MovieID  primitive.ObjectID `json:"movie_id"`
+	Change   string             `json:"change"`
+	Priority int                `json:"priority,omitempty"`
+	At       time.Time          `json:"at"`
+}
+
+func (WatchlistItemUpdated) Name() string { return NameWatchlistItemUpdated }
+
+// RecommendationsRefreshed is published when a user's precomputed
+// recommendation rows are recomputed
+type RecommendationsRefreshed struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	At     time.Time          `json:"at"`
+}
+
+func (RecommendationsRefreshed) Name() string { return NameRecommendationsRefreshed }
+
+// PollCreated is published when a user starts a movie night poll
+type PollCreated struct {
+	PollID       primitive.ObjectID `json:"poll_id"`
+	UserID       primitive.ObjectID `json:"user_id"`
+	Title        string             `json:"title"`
+	Movies       int                `json:"movies"`
+	ClosesAt     *time.Time         `json:"closes_at,omitempty"`
+	ScheduledFor *time.Time         `json:"scheduled_for,omitempty"`
+	At           time.Time          `json:"at"`
+}
+
+func (PollCreated) Name() string { return NamePollCreated }
+
+// PollUpdated is published when the owner changes a poll's title, closing
+// time or movie night time. Rescheduled is set when the movie night time
+// changed.
+type PollUpdated struct {
+	PollID       primitive.ObjectID `json:"poll_id"`
+	UserID       primitive.ObjectID `json:"user_id"`
+	Title        string             `json:"title"`
+	ClosesAt     *time.Time         `json:"closes_at,omitempty"`
+	ScheduledFor *time.Time         `json:"scheduled_for,omitempty"`
+	Rescheduled  bool               `json:"rescheduled"`
+	At           time.Time          `json:"at"`
+}
+
+func (PollUpdated) Name() string { return NamePollUpdated }
+
+// PollClosed is published when the owner ends voting by hand. Polls that
+// reach their closing time close without an event.
+type PollClosed struct {
+	PollID primitive.ObjectID `json:"poll_id"`
+	UserID primitive.ObjectID `json:"user_id"`
+	At     time.Time          `json:"at"`
+}
+
+func (PollClosed) Name() string { return NamePollClosed }
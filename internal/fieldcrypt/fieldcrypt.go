@@ -0,0 +1,205 @@
+// Package fieldcrypt encrypts individual document fields, such as second
+// factor secrets, third-party refresh tokens and webhook signing secrets,
+// before they are written to MongoDB. Values are sealed with AES-256-GCM and
+// carry the ID of the key that sealed them, so keys can be rotated: new
+// values use the current key while older keys stay available for reading
+// until everything has been re-encrypted.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks an encrypted value: "enc:v1:<key id>:<base64 nonce+ciphertext>"
+const prefix = "enc:v1:"
+
+// KeySize is the length of an AES-256 key in bytes
+const KeySize = 32
+
+var (
+	// ErrNoKey is returned when encrypting without any configured key
+	ErrNoKey = errors.New("no field encryption key configured")
+	// ErrUnknownKey is returned when a value was sealed with a key that is no
+	// longer configured
+	ErrUnknownKey = errors.New("unknown field encryption key")
+	// ErrMalformed is returned for values that are not encrypted values
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Key is one data encryption key
+type Key struct {
+	ID       string
+	Material []byte
+}
+
+// KeyProvider supplies data encryption keys. StaticKeys reads them from the
+// configuration; a KMS-backed provider can fetch or unwrap them instead.
+type KeyProvider interface {
+	// CurrentKey is the key new values are encrypted with
+	CurrentKey() (*Key, error)
+	// KeyByID returns the key with the ID, or ErrUnknownKey
+	KeyByID(id string) (*Key, error)
+}
+
+// StaticKeys is a fixed key ring. The first key is the current one.
+type StaticKeys struct {
+	keys []Key
+	byID map[string]*Key
+}
+
+// ParseKeys builds a key ring from "id:base64key" entries, current key first.
+// Keys are 32 random bytes in standard base64, e.g. from
+// `openssl rand -base64 32`.
+func ParseKeys(entries []string) (*StaticKeys, error) {
+	ring := &StaticKeys{byID: make(map[string]*Key, len(entries))}
+	for _, entry := range entries {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q must have the form id:base64key", entry)
+		}
+		if _, exists := ring.byID[id]; exists {
+			return nil, fmt.Errorf("key ID %q is used twice", id)
+		}
+		material, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", id)
+		}
+		if len(material) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes (got %d)", id, KeySize, len(material))
+		}
+		ring.keys = append(ring.keys, Key{ID: id, Material: material})
+	}
+	for i := range ring.keys {
+		ring.byID[ring.keys[i].ID] = &ring.keys[i]
+	}
+	return ring, nil
+}
+
+func (s *StaticKeys) CurrentKey() (*Key, error) {
+	if len(s.keys) == 0 {
+		return nil, ErrNoKey
+	}
+	return &s.keys[0], nil
+}
+
+func (s *StaticKeys) KeyByID(id string) (*Key, error) {
+	key, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// Cipher seals and opens field values. The associated data binds a value to
+// where it is stored, e.g. "users.totp_secret:<user id>", so an encrypted
+// value copied into another document or field does not decrypt.
+type Cipher struct {
+	keys KeyProvider
+}
+
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// Encrypt seals plaintext with the current key
+func (c *Cipher) Encrypt(plaintext, associatedData string) (string, error) {
+	key, err := c.keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return prefix + key.ID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with any key the provider knows
+func (c *Cipher) Decrypt(value, associatedData string) (string, error) {
+	keyID, sealed, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	key, err := c.keys.KeyByID(keyID)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt field: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether the value was sealed with a key other than
+// the current one
+func (c *Cipher) NeedsRotation(value string) (bool, error) {
+	keyID, _, err := parse(value)
+	if err != nil {
+		return false, err
+	}
+	current, err := c.keys.CurrentKey()
+	if err != nil {
+		return false, err
+	}
+	return keyID != current.ID, nil
+}
+
+// Rotate re-encrypts the value with the current key
+func (c *Cipher) Rotate(value, associatedData string) (string, error) {
+	plaintext, err := c.Decrypt(value, associatedData)
+	if err != nil {
+		return "", err
+	}
+	return c.Encrypt(plaintext, associatedData)
+}
+
+// IsEncrypted reports whether the value looks like an encrypted value, e.g.
+// to tell values written before a field was encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func parse(value string) (string, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", nil, ErrMalformed
+	}
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok || keyID == "" {
+		return "", nil, ErrMalformed
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, ErrMalformed
+	}
+	return keyID, sealed, nil
+}
+
+func newAEAD(key *Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Material)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
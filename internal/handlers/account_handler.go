@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AccountHandler struct {
+	emailChangeService  *services.EmailChangeService
+	userService         *services.UserService
+	deactivationService *services.AccountDeactivationService
+}
+
+func NewAccountHandler(emailChangeService *services.EmailChangeService, userService *services.UserService, deactivationService *services.AccountDeactivationService) *AccountHandler {
+	return &AccountHandler{
+		emailChangeService:  emailChangeService,
+		userService:         userService,
+		deactivationService: deactivationService,
+	}
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" binding:"required,email" sanitize:"line,max=254"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// RequestEmailChange sends a confirmation link to the new address
+func (h *AccountHandler) RequestEmailChange(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.emailChangeService.RequestChange(userID, req.CurrentPassword, req.NewEmail)
+	if err != nil {
+		switch err.Error() {
+		case "invalid credentials":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		case "email already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "new email matches current email":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Confirmation link sent to the new email address",
+		"new_email":  change.NewEmail,
+		"expires_at": change.ExpiresAt,
+	})
+}
+
+// ConfirmEmailChange completes an email change from the emailed link
+func (h *AccountHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Confirmation token is required"})
+		return
+	}
+
+	change, err := h.emailChangeService.ConfirmChange(token)
+	if err != nil {
+		switch err.Error() {
+		case "invalid or expired confirmation link":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "email already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address updated",
+		"email":   change.NewEmail,
+	})
+}
+
+type UpdateLanguagesRequest struct {
+	Audio     []string `json:"audio" sanitize:"line,max=40"`
+	Subtitles []string `json:"subtitles" sanitize:"line,max=40"`
+}
+
+// GetLanguages returns the user's preferred audio and subtitle languages
+func (h *AccountHandler) GetLanguages(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	prefs, err := h.userService.GetLanguagePreferences(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateLanguages replaces the user's preferred audio and subtitle languages.
+// Languages may be ISO 639-1 codes or English names and are stored as names.
+func (h *AccountHandler) UpdateLanguages(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateLanguagesRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.userService.UpdateLanguagePreferences(userID, req.Audio, req.Subtitles)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLanguage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+type DeactivateAccountRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// Deactivate hides the account and signs it out everywhere. Logging in again
+// within the grace period reactivates it; after that it is deleted.
+func (h *AccountHandler) Deactivate(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req DeactivateAccountRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deleteAt, err := h.deactivationService.Deactivate(userID, req.CurrentPassword)
+	if err != nil {
+		switch err.Error() {
+		case "invalid credentials":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Account deactivated. Log in again before the deletion date to reactivate it.",
+		"delete_at": deleteAt,
+	})
+}
+
+type UpdateTimezoneRequest struct {
+	Timezone string `json:"timezone" sanitize:"line,max=64"`
+}
+
+// GetTimezone returns the user's timezone
+func (h *AccountHandler) GetTimezone(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	timezone, err := h.userService.GetTimezone(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": timezone})
+}
+
+// UpdateTimezone sets the IANA timezone used for reminders, digests and
+// stats; an empty timezone resets it to UTC
+func (h *AccountHandler) UpdateTimezone(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateTimezoneRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timezone, err := h.userService.UpdateTimezone(userID, req.Timezone)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTimezone):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": timezone})
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AdminHandler struct {
+	usageService      *services.OMDbUsageService
+	statsService      *services.StatsService
+	userService       *services.UserService
+	evaluationService *services.EvaluationService
+	encryptionService *services.EncryptionService
+	reconciliation    *services.ReconciliationService
+	movieHistory      *services.MovieHistoryService
+	recAnalytics      *services.RecommendationAnalyticsService
+	recommendations   *services.RecommendationService
+	scheduler         *services.RecommendationScheduler
+	jobQueue          *jobs.Queue
+}
+
+func NewAdminHandler(usageService *services.OMDbUsageService, statsService *services.StatsService, userService *services.UserService, evaluationService *services.EvaluationService, encryptionService *services.EncryptionService, reconciliation *services.ReconciliationService, movieHistory *services.MovieHistoryService, recAnalytics *services.RecommendationAnalyticsService, recommendations *services.RecommendationService, scheduler *services.RecommendationScheduler, jobQueue *jobs.Queue) *AdminHandler {
+	return &AdminHandler{
+		usageService:      usageService,
+		statsService:      statsService,
+		userService:       userService,
+		evaluationService: evaluationService,
+		encryptionService: encryptionService,
+		reconciliation:    reconciliation,
+		movieHistory:      movieHistory,
+		recAnalytics:      recAnalytics,
+		recommendations:   recommendations,
+		scheduler:         scheduler,
+		jobQueue:          jobQueue,
+	}
+}
+
+// GetStats returns system-wide usage statistics, cached for a minute
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	stats, err := h.statsService.GetSystemStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetUsers lists user accounts with cursor pagination (?after=<id>&limit=n)
+func (h *AdminHandler) GetUsers(c *gin.Context) {
+	cursor, err := pagination.Parse(c.Query("after"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, more, err := h.userService.ListUsers(cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	usersResponse := []gin.H{}
+	var lastID primitive.ObjectID
+	for _, user := range users {
+		usersResponse = append(usersResponse, gin.H{
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"last_active_at": user.LastActiveAt,
+			"created_at":     user.CreatedAt,
+		})
+		lastID = user.ID
+	}
+
+	var next *primitive.ObjectID
+	if more {
+		next = &lastID
+	}
+	respondCursorList(c, usersResponse, cursor, next)
+}
+
+// GetRecommendationEvaluations returns the latest offline evaluation runs
+func (h *AdminHandler) GetRecommendationEvaluations(c *gin.Context) {
+	evaluations, err := h.evaluationService.GetRecentEvaluations(10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"evaluations": evaluations,
+		"cutoffs":     services.EvaluationCutoffs,
+	})
+}
+
+// RunRecommendationEvaluation queues an offline evaluation run
+func (h *AdminHandler) RunRecommendationEvaluation(c *gin.Context) {
+	if err := h.evaluationService.RequestEvaluation(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Recommendation evaluation queued"})
+}
+
+// GetRecommendationAnalytics returns the click-through rate of recommendations
+// shown in the last days days (default 30) per algorithm, row and genre
+func (h *AdminHandler) GetRecommendationAnalytics(c *gin.Context) {
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed < 1 || parsed > 90 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+			return
+		}
+		days = parsed
+	}
+
+	analytics, err := h.recAnalytics.GetAnalytics(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
+
+// ExplainRecommendations computes a user's recommendations with stage
+// timings and candidate counts (?user_id=<id>&limit=n). The pipeline runs
+// live even for users on a schedule, whose endpoint serves the stored rows
+// from scheduled_at instead.
+func (h *AdminHandler) ExplainRecommendations(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit := 10
+	if limitParam := c.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxRecommendations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxRecommendations)})
+			return
+		}
+		limit = parsed
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	explanation, err := h.recommendations.ExplainRecommendations(userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	_, scheduledAt, err := h.scheduler.ScheduledRecommendations(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"explanation": explanation, "scheduled_at": scheduledAt})
+}
+
+// RotateEncryptionKeys queues re-encryption of stored secrets with the
+// current field encryption key
+func (h *AdminHandler) RotateEncryptionKeys(c *gin.Context) {
+	if err := h.encryptionService.RequestRotation(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Encryption key rotation queued"})
+}
+
+// GetOMDbUsage returns daily OMDb request counts and the current quota state
+func (h *AdminHandler) GetOMDbUsage(c *gin.Context) {
+	days := 7 // Default history window
+	if daysParam := c.Query("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed < 1 || parsed > 90 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+			return
+		}
+		days = parsed
+	}
+
+	summary, err := h.usageService.GetUsageSummary(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetJobsStatus returns background job counts per status and recent dead letter jobs
+func (h *AdminHandler) GetJobsStatus(c *gin.Context) {
+	status, err := h.jobQueue.Status(20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RetryJob moves a dead letter job back to the queue
+func (h *AdminHandler) RetryJob(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID format"})
+		return
+	}
+
+	requeued, err := h.jobQueue.Requeue(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !requeued {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job requeued successfully",
+		"job_id":  id.Hex(),
+	})
+}
+
+// GetMovieHistory lists what changed on a movie, oldest first, with cursor
+// pagination
+func (h *AdminHandler) GetMovieHistory(c *gin.Context) {
+	movieID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	cursor, err := pagination.Parse(c.Query("after"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revisions, more, err := h.movieHistory.GetRevisions(movieID, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *primitive.ObjectID
+	if more {
+		next = &revisions[len(revisions)-1].ID
+	}
+	respondCursorList(c, revisions, cursor, next)
+}
+
+// GetReconciliationReport returns how many provider conflicts are open,
+// accepted and overridden
+func (h *AdminHandler) GetReconciliationReport(c *gin.Context) {
+	counts, err := h.reconciliation.CountConflicts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counts": counts})
+}
+
+// GetConflicts lists provider conflicts with cursor pagination
+// (?status=open|accepted|overridden|all, default open)
+func (h *AdminHandler) GetConflicts(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ConflictOpen)
+	switch status {
+	case models.ConflictOpen, models.ConflictAccepted, models.ConflictOverridden:
+	case "all":
+		status = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, accepted, overridden or all"})
+		return
+	}
+
+	cursor, err := pagination.Parse(c.Query("after"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conflicts, more, err := h.reconciliation.ListConflicts(status, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *primitive.ObjectID
+	if more {
+		next = &conflicts[len(conflicts)-1].ID
+	}
+	respondCursorList(c, conflicts, cursor, next)
+}
+
+// AcceptConflict copies the secondary provider's values onto the movie
+func (h *AdminHandler) AcceptConflict(c *gin.Context) {
+	h.resolveConflict(c, h.reconciliation.Accept)
+}
+
+// OverrideConflict keeps the cached values; the conflict is not raised again
+// unless the secondary provider's values change
+func (h *AdminHandler) OverrideConflict(c *gin.Context) {
+	h.resolveConflict(c, h.reconciliation.Override)
+}
+
+func (h *AdminHandler) resolveConflict(c *gin.Context, resolve func(conflictID, adminID primitive.ObjectID) (*models.MetadataConflict, error)) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	adminID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	conflictID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conflict ID format"})
+		return
+	}
+
+	conflict, err := resolve(conflictID, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConflictNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conflict not found"})
+		case errors.Is(err, services.ErrConflictResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": "Conflict was already resolved", "code": "CONFLICT_RESOLVED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, conflict)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AdvisoryHandler struct {
+	advisoryService *services.AdvisoryService
+}
+
+func NewAdvisoryHandler(advisoryService *services.AdvisoryService) *AdvisoryHandler {
+	return &AdvisoryHandler{advisoryService: advisoryService}
+}
+
+type ReportAdvisoryRequest struct {
+	Advisory string `json:"advisory" binding:"required" sanitize:"line,max=30"`
+}
+
+type UpdateAvoidedAdvisoriesRequest struct {
+	Avoid []string `json:"avoid" binding:"required" sanitize:"line,max=30"`
+}
+
+// ReportAdvisory reports that a movie carries a content advisory, for an
+// admin to review
+func (h *AdvisoryHandler) ReportAdvisory(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	var req ReportAdvisoryRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.advisoryService.Report(userID, movieID, req.Advisory)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAdvisory):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAlreadyReported):
+			c.JSON(http.StatusConflict, gin.H{"error": "You already reported this advisory for this movie"})
+		case err.Error() == "movie not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetAvoided returns the content advisories the user avoids
+func (h *AdvisoryHandler) GetAvoided(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	avoid, err := h.advisoryService.GetAvoided(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"avoid": avoid})
+}
+
+// UpdateAvoided replaces the content advisories the user avoids
+func (h *AdvisoryHandler) UpdateAvoided(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateAvoidedAdvisoriesRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	avoid, err := h.advisoryService.SetAvoided(userID, req.Avoid)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAdvisory):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"avoid": avoid})
+}
+
+// GetReports lists the advisory moderation queue, pending reports by default
+func (h *AdvisoryHandler) GetReports(c *gin.Context) {
+	status := c.DefaultQuery("status", models.AdvisoryReportPending)
+	switch status {
+	case models.AdvisoryReportPending, models.AdvisoryReportApproved, models.AdvisoryReportRejected:
+	case "all":
+		status = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved, rejected or all"})
+		return
+	}
+
+	cursor, err := pagination.Parse(c.Query("after"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reports, more, err := h.advisoryService.ListReports(status, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *primitive.ObjectID
+	if more {
+		next = &reports[len(reports)-1].ID
+	}
+	respondCursorList(c, reports, cursor, next)
+}
+
+// ApproveReport adds a reported advisory to its movie
+func (h *AdvisoryHandler) ApproveReport(c *gin.Context) {
+	h.reviewReport(c, h.advisoryService.Approve)
+}
+
+// RejectReport turns a reported advisory down
+func (h *AdvisoryHandler) RejectReport(c *gin.Context) {
+	h.reviewReport(c, h.advisoryService.Reject)
+}
+
+func (h *AdvisoryHandler) reviewReport(c *gin.Context, review func(reportID, adminID primitive.ObjectID) (*models.AdvisoryReport, error)) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	adminID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID format"})
+		return
+	}
+
+	report, err := review(reportID, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAdvisoryReportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		case errors.Is(err, services.ErrReportReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Report was already reviewed", "code": "REPORT_REVIEWED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// avoidedAdvisories returns the advisories the signed-in account avoids, or
+// none for guests. Kids profiles follow their parent's list.
+func avoidedAdvisories(c *gin.Context, advisoryService *services.AdvisoryService) ([]string, error) {
+	userID := optionalUserID(c)
+	if userID == nil {
+		return nil, nil
+	}
+	return advisoryService.GetAvoided(accountUserID(c, *userID))
+}
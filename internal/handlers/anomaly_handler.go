@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AnomalyHandler struct {
+	anomalyService *services.AnomalyService
+}
+
+func NewAnomalyHandler(anomalyService *services.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{anomalyService: anomalyService}
+}
+
+// GetFlags lists the review queue of flagged accounts, open ones by default
+func (h *AnomalyHandler) GetFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", models.FlagOpen)
+	switch status {
+	case models.FlagOpen, models.FlagDismissed, models.FlagConfirmed:
+	case "all":
+		status = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, dismissed, confirmed or all"})
+		return
+	}
+
+	cursor, err := pagination.Parse(c.Query("after"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flags, more, err := h.anomalyService.ListFlags(status, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *primitive.ObjectID
+	if more {
+		next = &flags[len(flags)-1].ID
+	}
+	respondCursorList(c, flags, cursor, next)
+}
+
+// DismissFlag closes a flag as a false alarm and lifts the account's throttle
+func (h *AnomalyHandler) DismissFlag(c *gin.Context) {
+	h.reviewFlag(c, h.anomalyService.Dismiss)
+}
+
+// ConfirmFlag closes a flag as abuse; a running throttle stays until it
+// expires
+func (h *AnomalyHandler) ConfirmFlag(c *gin.Context) {
+	h.reviewFlag(c, h.anomalyService.Confirm)
+}
+
+func (h *AnomalyHandler) reviewFlag(c *gin.Context, review func(flagID, adminID primitive.ObjectID) (*models.AccountFlag, error)) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	adminID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	flagID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID format"})
+		return
+	}
+
+	flag, err := review(flagID, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFlagNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Flag not found"})
+		case errors.Is(err, services.ErrFlagReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Flag was already reviewed", "code": "FLAG_REVIEWED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ArchiveHandler struct {
+	importService *services.ArchiveImportService
+}
+
+func NewArchiveHandler(importService *services.ArchiveImportService) *ArchiveHandler {
+	return &ArchiveHandler{importService: importService}
+}
+
+// ImportArchive restores an account archive ZIP sent either as the raw body
+// or as a multipart "file" field. ?on_conflict=skip|overwrite|merge (default
+// skip) resolves entries the account already has; with dry_run=true it only
+// reports what would change.
+func (h *ArchiveHandler) ImportArchive(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var input io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Archive file is required in the file field"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// ZIP files are read from the end, so the archive is buffered
+	data, err := io.ReadAll(input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read archive"})
+		return
+	}
+
+	onConflict := c.DefaultQuery("on_conflict", services.ArchiveConflictSkip)
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := h.importService.ImportArchive(userID, data, onConflict, dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidArchive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import archive"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AssistantHandler struct {
+	assistantService *services.AssistantService
+}
+
+func NewAssistantHandler(assistantService *services.AssistantService) *AssistantHandler {
+	return &AssistantHandler{
+		assistantService: assistantService,
+	}
+}
+
+type AssistantIntentRequest struct {
+	Intent string `json:"intent" binding:"required"`
+	// Query is the movie title as spoken
+	Query string `json:"query" sanitize:"line,max=200"`
+	// IMDbID picks one of the candidates of a needs_choice response
+	IMDbID string `json:"imdb_id" sanitize:"line,max=12"`
+	Rating int    `json:"rating"`
+}
+
+// HandleIntent carries out one intent from a voice assistant skill. Outcomes
+// the user should hear, including not finding the movie, are 200 responses
+// with a status and a sentence to speak.
+func (h *AssistantHandler) HandleIntent(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req AssistantIntentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowed := func(movie *models.Movie) bool {
+		return movieAllowed(c, movie)
+	}
+	response, err := h.assistantService.Handle(c.Request.Context(), userID, services.AssistantRequest{
+		Intent: req.Intent,
+		Query:  req.Query,
+		IMDbID: req.IMDbID,
+		Rating: req.Rating,
+	}, allowed)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownIntent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "UNKNOWN_INTENT"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,62 +1,122 @@
 package handlers
 
 import (
+	"errors"
 	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/services"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	userService *services.UserService
-	jwtSecret   string
+	userService          *services.UserService
+	sessionService       *services.SessionService
+	loginSecurityService *services.LoginSecurityService
+	tokens               middleware.TokenConfig
+	// countryHeader names the request header in which a proxy reports the
+	// client's country, e.g. CF-IPCountry; empty when there is none
+	countryHeader string
+	captcha       *services.CaptchaPolicy
+	terms         *services.TermsService
+	// loginFailures counts failed logins per IP and per email, to ask for a
+	// CAPTCHA once either has too many
+	loginFailures *middleware.RateLimiter
 }
 
-func NewAuthHandler(userService *services.UserService, jwtSecret string) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, sessionService *services.SessionService, loginSecurityService *services.LoginSecurityService, tokens middleware.TokenConfig, countryHeader string, captcha *services.CaptchaPolicy, terms *services.TermsService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtSecret:   jwtSecret,
+		userService:          userService,
+		sessionService:       sessionService,
+		loginSecurityService: loginSecurityService,
+		tokens:               tokens,
+		countryHeader:        countryHeader,
+		captcha:              captcha,
+		terms:                terms,
+		loginFailures:        middleware.NewRateLimiter(captcha.LoginAfterFailures, captcha.LoginFailureWindow),
 	}
 }
 
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username string `json:"username" binding:"required,min=3,max=50" sanitize:"line,max=50"`
+	Email    string `json:"email" binding:"required,email" sanitize:"line,max=254"`
+	Password string `json:"password" binding:"required"`
+	// CaptchaToken is the solved CAPTCHA, required when CAPTCHA is enabled
+	CaptchaToken string `json:"captcha_token"`
+	// TermsVersion is the terms version the user accepted, required when
+	// terms are tracked
+	TermsVersion string `json:"terms_version" sanitize:"line,max=64"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required,email" sanitize:"line,max=254"`
 	Password string `json:"password" binding:"required"`
+	// CaptchaToken is required after repeated failed logins
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  interface{} `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	User         interface{} `json:"user,omitempty"`
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := h.userService.Register(req.Username, req.Email, req.Password)
+	if h.captcha.Enabled() && h.captcha.OnRegister && !h.checkCaptcha(c, req.CaptchaToken) {
+		return
+	}
+
+	if err := h.terms.CheckRegistration(req.TermsVersion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "The current terms must be accepted",
+			"code":          "TERMS_NOT_ACCEPTED",
+			"terms_version": h.terms.Version(),
+		})
+		return
+	}
+
+	user, err := h.userService.Register(req.Username, req.Email, req.Password, h.terms.Version())
 	if err != nil {
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    "Password does not meet the password policy",
+				"code":     "WEAK_PASSWORD",
+				"problems": policyErr.Problems,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID, h.jwtSecret)
+	session, refreshToken, err := h.sessionService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	token, err := middleware.GenerateSessionToken(user.ID, session.ID.Hex(), h.tokens)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, AuthResponse{
-		Token: token,
+		Token:        token,
+		RefreshToken: refreshToken,
 		User: gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -67,29 +127,163 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := h.userService.Login(req.Email, req.Password)
+	login, authErr := h.passwordLogin(c, req.Email, req.Password, req.CaptchaToken)
+	if authErr != nil {
+		authErr.respond(c)
+		return
+	}
+
+	response := gin.H{
+		"id":       login.user.ID,
+		"username": login.user.Username,
+		"email":    login.user.Email,
+	}
+	if login.user.DeactivatedAt != nil {
+		response["reactivated"] = true
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        login.token,
+		RefreshToken: login.refreshToken,
+		User:         response,
+	})
+}
+
+// loginResult is a successful login and the session it started
+type loginResult struct {
+	user         *models.User
+	session      *models.Session
+	token        string
+	refreshToken string
+}
+
+// authError is a failed login with the status and body Login responds with
+type authError struct {
+	status  int
+	message string
+	// code is the machine-readable error code, empty for bad credentials
+	code string
+}
+
+func (e *authError) respond(c *gin.Context) {
+	body := gin.H{"error": e.message}
+	if e.code != "" {
+		body["code"] = e.code
+	}
+	c.JSON(e.status, body)
+}
+
+// passwordLogin checks the credentials, asking for a CAPTCHA after repeated
+// failures, reactivates a deactivated account and starts a session. Both
+// POST /login and the OAuth token endpoint log in through it.
+func (h *AuthHandler) passwordLogin(c *gin.Context, email, password, captchaToken string) (*loginResult, *authError) {
+	var country string
+	if h.countryHeader != "" {
+		country = c.GetHeader(h.countryHeader)
+	}
+
+	ipKey := "ip:" + c.ClientIP()
+	emailKey := "email:" + strings.ToLower(email)
+	if h.captcha.Enabled() && (!h.loginFailures.Peek(ipKey).Allowed || !h.loginFailures.Peek(emailKey).Allowed) {
+		if authErr := h.verifyCaptcha(c, captchaToken); authErr != nil {
+			return nil, authErr
+		}
+	}
+
+	user, err := h.userService.Login(email, password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		h.loginFailures.Allow(ipKey)
+		h.loginFailures.Allow(emailKey)
+		h.loginSecurityService.RecordFailedLogin(email, c.ClientIP(), c.Request.UserAgent(), country)
+		return nil, &authError{status: http.StatusUnauthorized, message: err.Error()}
+	}
+
+	// Logging in within the grace period undoes a deactivation
+	if user.DeactivatedAt != nil {
+		if err := h.userService.Reactivate(user.ID); err != nil {
+			return nil, &authError{status: http.StatusInternalServerError, message: "Failed to reactivate account"}
+		}
+	}
+
+	return h.startSession(c, user, country)
+}
+
+// startSession starts a session for a user who proved who they are and
+// records the login
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User, country string) (*loginResult, *authError) {
+	session, refreshToken, err := h.sessionService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, &authError{status: http.StatusInternalServerError, message: "Failed to create session"}
+	}
+
+	token, err := middleware.GenerateSessionToken(user.ID, session.ID.Hex(), h.tokens)
+	if err != nil {
+		return nil, &authError{status: http.StatusInternalServerError, message: "Failed to generate token"}
+	}
+
+	h.loginSecurityService.RecordLogin(user, c.ClientIP(), c.Request.UserAgent(), country)
+
+	return &loginResult{user: user, session: session, token: token, refreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, refreshToken, err := h.sessionService.Refresh(req.RefreshToken, "", c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		}
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID, h.jwtSecret)
+	token, err := middleware.GenerateSessionToken(session.UserID, session.ID.Hex(), h.tokens)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, AuthResponse{
-		Token: token,
-		User: gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-		},
+		Token:        token,
+		RefreshToken: refreshToken,
 	})
 }
+
+// checkCaptcha verifies the CAPTCHA token with the provider. It writes the
+// error response and returns false when the token is missing or rejected,
+// and fails closed while the provider cannot be reached.
+func (h *AuthHandler) checkCaptcha(c *gin.Context, token string) bool {
+	if authErr := h.verifyCaptcha(c, token); authErr != nil {
+		authErr.respond(c)
+		return false
+	}
+	return true
+}
+
+// verifyCaptcha is checkCaptcha without the response
+func (h *AuthHandler) verifyCaptcha(c *gin.Context, token string) *authError {
+	if token == "" {
+		return &authError{status: http.StatusBadRequest, message: "CAPTCHA is required", code: "CAPTCHA_REQUIRED"}
+	}
+
+	valid, err := h.captcha.Verify(token, c.ClientIP())
+	if err != nil {
+		return &authError{status: http.StatusServiceUnavailable, message: "CAPTCHA verification is unavailable, try again later", code: "CAPTCHA_UNAVAILABLE"}
+	}
+	if !valid {
+		return &authError{status: http.StatusBadRequest, message: "CAPTCHA verification failed", code: "CAPTCHA_FAILED"}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/handlers"
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterLoginAndUseToken(t *testing.T) {
+	server := newTestServer(t)
+
+	var registered handlers.AuthResponse
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/register", "", gin.H{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"password": testutil.Password,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, &registered)
+	if registered.Token == "" || registered.RefreshToken == "" {
+		t.Fatalf("register returned no tokens: %+v", registered)
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", registered.Token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, nil)
+
+	var loggedIn handlers.AuthResponse
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/login", "", gin.H{
+		"email":    "alice@example.com",
+		"password": testutil.Password,
+	})
+	testutil.Decode(t, recorder, http.StatusOK, &loggedIn)
+	if loggedIn.Token == "" {
+		t.Fatal("login returned no token")
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", loggedIn.Token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, nil)
+}
+
+func TestRegisterRejectsTakenEmail(t *testing.T) {
+	server := newTestServer(t)
+	testutil.CreateUser(t, server.db, "alice")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/register", "", gin.H{
+		"username": "alice2",
+		"email":    "alice@example.com",
+		"password": testutil.Password,
+	})
+	testutil.Decode(t, recorder, http.StatusBadRequest, nil)
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	server := newTestServer(t)
+	testutil.CreateUser(t, server.db, "alice")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/login", "", gin.H{
+		"email":    "alice@example.com",
+		"password": "not-the-password",
+	})
+	testutil.Decode(t, recorder, http.StatusUnauthorized, nil)
+}
+
+func TestProtectedRoutesNeedAToken(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, token := range []string{"", "not-a-jwt"} {
+		recorder := testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", token, nil)
+		testutil.Decode(t, recorder, http.StatusUnauthorized, nil)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/authz"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondAuthzError writes the response for a failed policy check. Resources
+// owned by someone else are reported with notFound, like missing ones.
+func respondAuthzError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, authz.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	case errors.Is(err, authz.ErrHidden):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, authz.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed", "code": "FORBIDDEN"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// respondAuthzErrorV2 is respondAuthzError with the v2 error envelope
+func respondAuthzErrorV2(c *gin.Context, err error, notFoundCode, notFound string) {
+	switch {
+	case errors.Is(err, authz.ErrUnauthenticated):
+		respondErrorV2(c, http.StatusUnauthorized, "UNAUTHENTICATED", "User not authenticated")
+	case errors.Is(err, authz.ErrHidden):
+		respondErrorV2(c, http.StatusNotFound, notFoundCode, notFound)
+	case errors.Is(err, authz.ErrForbidden):
+		respondErrorV2(c, http.StatusForbidden, "FORBIDDEN", "Not allowed")
+	default:
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", "Internal server error")
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/sanitize"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindJSON decodes a JSON request body, sanitizes its tagged text fields and
+// then validates it, so length rules apply to the cleaned values. On strict
+// endpoints unknown fields are rejected.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("request body is required")
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	if middleware.IsStrictJSON(c) {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(obj); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("request body is required")
+		}
+		return err
+	}
+
+	if err := sanitize.Struct(obj); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CalendarFeedHandler struct {
+	feedService *services.CalendarFeedService
+	policy      *authz.Policy
+}
+
+func NewCalendarFeedHandler(feedService *services.CalendarFeedService, policy *authz.Policy) *CalendarFeedHandler {
+	return &CalendarFeedHandler{feedService: feedService, policy: policy}
+}
+
+// CreateCalendarFeed returns a new secret calendar feed URL for the user,
+// replacing any earlier one
+func (h *CalendarFeedHandler) CreateCalendarFeed(c *gin.Context) {
+	userID, ok := calendarFeedUserID(c)
+	if !ok {
+		return
+	}
+
+	feedURL, err := h.feedService.CreateFeed(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"url": feedURL})
+}
+
+// DeleteCalendarFeed turns the user's calendar feed off
+func (h *CalendarFeedHandler) DeleteCalendarFeed(c *gin.Context) {
+	userID, ok := calendarFeedUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.feedService.DeleteFeed(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed turned off"})
+}
+
+// GetCalendarFeed serves the iCalendar feed. It needs no token: calendar
+// apps cannot send one, so the secret in the URL identifies the user.
+func (h *CalendarFeedHandler) GetCalendarFeed(c *gin.Context) {
+	token, ok := strings.CutSuffix(c.Param("token"), ".ics")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrInvalidFeedToken.Error()})
+		return
+	}
+
+	owner, err := h.feedService.FeedOwner(token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Calendar apps are guests: the token in the URL is the only credential
+	if err := h.policy.RequireShareAccess(nil, owner.ID, owner.CalendarFeedTokenHash, token); err != nil {
+		respondAuthzError(c, err, services.ErrInvalidFeedToken.Error())
+		return
+	}
+
+	feed, err := h.feedService.Feed(owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
+
+func calendarFeedUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
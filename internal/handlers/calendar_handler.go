@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+}
+
+func NewCalendarHandler(calendarService *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{calendarService: calendarService}
+}
+
+// GetCalendar lists upcoming releases from the directors and franchises of
+// movies the user rated highly and from the people they follow, soonest first
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := services.DefaultCalendarDays
+	if value := c.Query("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > services.MaxCalendarDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", services.MaxCalendarDays)})
+			return
+		}
+	}
+
+	entries, err := h.calendarService.GetCalendar(userID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := []gin.H{}
+	profile := currentProfile(c)
+	for _, entry := range entries {
+		release := entry.Release
+		if profile != nil && !services.CertificationAllowed(release.Rated, profile.MaxCertification) {
+			continue
+		}
+		items = append(items, gin.H{
+			"movie_id":     release.MovieID,
+			"imdb_id":      release.IMDbID,
+			"title":        release.Title,
+			"directors":    release.Directors,
+			"actors":       release.Actors,
+			"poster":       release.Poster,
+			"release_date": release.ReleaseDate.Format("2006-01-02"),
+			"reasons":      entry.Reasons,
+			"_links":       Links{"movie": {Href: apiBase(c) + "/movies/" + release.MovieID.Hex()}},
+		})
+	}
+
+	start, end := paginateSlice(len(items), pagination)
+	respondList(c, items[start:end], pagination, int64(len(items)), gin.H{"days": days})
+}
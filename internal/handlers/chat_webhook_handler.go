@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ChatWebhookHandler struct {
+	webhookService *services.ChatWebhookService
+}
+
+func NewChatWebhookHandler(webhookService *services.ChatWebhookService) *ChatWebhookHandler {
+	return &ChatWebhookHandler{webhookService: webhookService}
+}
+
+type SetChatWebhookRequest struct {
+	URL string `json:"url" binding:"required" sanitize:"line,max=512"`
+}
+
+// GetChatWebhook returns the kind of the user's webhook and the result of
+// the last post; the URL is never shown again
+func (h *ChatWebhookHandler) GetChatWebhook(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.Get(userID)
+	if err != nil {
+		respondChatWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// SetChatWebhook stores the Slack or Discord webhook URL polls are posted to
+func (h *ChatWebhookHandler) SetChatWebhook(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetChatWebhookRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.webhookService.Set(userID, req.URL)
+	if err != nil {
+		respondChatWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// TestChatWebhook queues a test post to the user's webhook
+func (h *ChatWebhookHandler) TestChatWebhook(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.SendTest(userID); err != nil {
+		respondChatWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Test post queued"})
+}
+
+// DeleteChatWebhook stops posting to the user's webhook
+func (h *ChatWebhookHandler) DeleteChatWebhook(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(userID); err != nil {
+		respondChatWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat webhook removed"})
+}
+
+func chatUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func respondChatWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChatWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChatWebhookNotSet):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "CHAT_WEBHOOK_NOT_SET"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CompatibilityHandler struct {
+	compatibilityService *services.CompatibilityService
+}
+
+func NewCompatibilityHandler(compatibilityService *services.CompatibilityService) *CompatibilityHandler {
+	return &CompatibilityHandler{compatibilityService: compatibilityService}
+}
+
+type UpdateCompatibilityVisibilityRequest struct {
+	Public *bool `json:"public" binding:"required"`
+}
+
+// GetCompatibility compares the user's taste with another user's
+func (h *CompatibilityHandler) GetCompatibility(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	compatibility, err := h.compatibilityService.Compare(userID, c.Param("username"))
+	if err != nil {
+		if errors.Is(err, services.ErrCompareWithSelf) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	// Users who do not allow comparisons look the same as unknown ones
+	if compatibility == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, compatibility)
+}
+
+// UpdateVisibility sets whether other users can compare their taste with
+// the user's
+func (h *CompatibilityHandler) UpdateVisibility(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateCompatibilityVisibilityRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.compatibilityService.SetPublic(userID, *req.Public); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"public": *req.Public})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DemoHandler struct {
+	demoService *services.DemoService
+	tokens      middleware.TokenConfig
+}
+
+func NewDemoHandler(demoService *services.DemoService, tokens middleware.TokenConfig) *DemoHandler {
+	return &DemoHandler{
+		demoService: demoService,
+		tokens:      tokens,
+	}
+}
+
+// StartSession provisions a sandbox account with a few ratings and watchlist
+// entries and returns a token for it. There is no refresh token: the sandbox
+// and everything in it is deleted when expires_at passes.
+func (h *DemoHandler) StartSession(c *gin.Context) {
+	demo, err := h.demoService.StartSession(c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start demo session"})
+		return
+	}
+
+	token, err := middleware.GenerateDemoToken(demo.User.ID, demo.Session.ID.Hex(), demo.ExpiresAt, h.tokens)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"expires_at": demo.ExpiresAt,
+		"user": gin.H{
+			"id":       demo.User.ID,
+			"username": demo.User.Username,
+		},
+	})
+}
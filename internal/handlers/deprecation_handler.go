@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeprecationHandler lets admins announce that routes are being retired
+type DeprecationHandler struct {
+	deprecationService *services.DeprecationService
+}
+
+func NewDeprecationHandler(deprecationService *services.DeprecationService) *DeprecationHandler {
+	return &DeprecationHandler{deprecationService: deprecationService}
+}
+
+type UpdateDeprecationRequest struct {
+	// Method is optional; without it every method of the path is changed
+	Method   string     `json:"method" sanitize:"line,max=10"`
+	Path     string     `json:"path" binding:"required" sanitize:"line,max=200"`
+	Enabled  *bool      `json:"enabled" binding:"required"`
+	SunsetAt *time.Time `json:"sunset_at"`
+	Message  string     `json:"message" sanitize:"line,max=500"`
+}
+
+// GetDeprecations lists the routes that can be deprecated with their notices
+func (h *DeprecationHandler) GetDeprecations(c *gin.Context) {
+	deprecations, err := h.deprecationService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deprecations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"routes": deprecations})
+}
+
+// UpdateDeprecation turns the deprecation notice of a route on or off
+func (h *DeprecationHandler) UpdateDeprecation(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	adminID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateDeprecationRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.deprecationService.Update(adminID, services.DeprecationUpdate{
+		Method:   strings.ToUpper(req.Method),
+		Path:     req.Path,
+		Enabled:  *req.Enabled,
+		SunsetAt: req.SunsetAt,
+		Message:  req.Message,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRouteNotRegistered):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Route cannot be deprecated", "code": "ROUTE_NOT_REGISTERED"})
+		case errors.Is(err, services.ErrInvalidDeprecation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_DEPRECATION"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"routes": updated})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DevicePairingHandler signs in TV apps and other devices by a code the user
+// approves from a signed-in session
+type DevicePairingHandler struct {
+	pairingService *services.DevicePairingService
+	userService    *services.UserService
+	auth           *AuthHandler
+}
+
+func NewDevicePairingHandler(pairingService *services.DevicePairingService, userService *services.UserService, auth *AuthHandler) *DevicePairingHandler {
+	return &DevicePairingHandler{
+		pairingService: pairingService,
+		userService:    userService,
+		auth:           auth,
+	}
+}
+
+type StartDevicePairingRequest struct {
+	// DeviceName is shown to the user approving the device
+	DeviceName string `json:"device_name" sanitize:"line,max=64"`
+}
+
+type PollDevicePairingRequest struct {
+	DeviceCode string `json:"device_code" binding:"required"`
+}
+
+// StartDevicePairing starts a pairing for the device and returns the code
+// to show and the secret to poll with. The body is optional.
+func (h *DevicePairingHandler) StartDevicePairing(c *gin.Context) {
+	var req StartDevicePairingRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	start, err := h.pairingService.Start(req.DeviceName, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start pairing"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, start)
+}
+
+// PollDevicePairing returns tokens for the device once its pairing is
+// approved, and 202 while it waits
+func (h *DevicePairingHandler) PollDevicePairing(c *gin.Context) {
+	var req PollDevicePairingRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	interval := int(services.DevicePairingInterval.Seconds())
+	userID, err := h.pairingService.Poll(req.DeviceCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPairingPending):
+			c.JSON(http.StatusAccepted, gin.H{"status": "pending", "interval": interval})
+		case errors.Is(err, services.ErrPairingSlowDown):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Polling too often", "code": "SLOW_DOWN", "interval": interval})
+		case errors.Is(err, services.ErrPairingDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Pairing was denied", "code": "PAIRING_DENIED"})
+		case errors.Is(err, services.ErrPairingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pairing not found or expired", "code": "PAIRING_EXPIRED"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
+	// The account was deleted or deactivated after approving
+	if user == nil || user.DeactivatedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pairing not found or expired", "code": "PAIRING_EXPIRED"})
+		return
+	}
+
+	var country string
+	if h.auth.countryHeader != "" {
+		country = c.GetHeader(h.auth.countryHeader)
+	}
+	login, authErr := h.auth.startSession(c, user, country)
+	if authErr != nil {
+		authErr.respond(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        login.token,
+		RefreshToken: login.refreshToken,
+		User: gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+		},
+	})
+}
+
+// GetDevicePairing shows the device waiting under a code, so the user can
+// check it before approving
+func (h *DevicePairingHandler) GetDevicePairing(c *gin.Context) {
+	if _, ok := devicePairingUserID(c); !ok {
+		return
+	}
+
+	pairing, err := h.pairingService.GetPending(c.Param("code"))
+	if err != nil {
+		respondDevicePairingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pairing)
+}
+
+// ApproveDevicePairing signs the device waiting under a code in to the
+// user's account
+func (h *DevicePairingHandler) ApproveDevicePairing(c *gin.Context) {
+	userID, ok := devicePairingUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.pairingService.Approve(userID, c.Param("code")); err != nil {
+		respondDevicePairingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device approved"})
+}
+
+// DenyDevicePairing turns the device waiting under a code away
+func (h *DevicePairingHandler) DenyDevicePairing(c *gin.Context) {
+	userID, ok := devicePairingUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.pairingService.Deny(userID, c.Param("code")); err != nil {
+		respondDevicePairingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device denied"})
+}
+
+func devicePairingUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func respondDevicePairingError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPairingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No device is waiting with this code", "code": "PAIRING_NOT_FOUND"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
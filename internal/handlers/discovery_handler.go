@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DiscoveryHandler struct {
+	discoveryService *services.DiscoveryService
+}
+
+func NewDiscoveryHandler(discoveryService *services.DiscoveryService) *DiscoveryHandler {
+	return &DiscoveryHandler{discoveryService: discoveryService}
+}
+
+// Discover finds cached movies from a natural-language request, e.g.
+// ?q=feel-good heist movies under 2 hours. The filters the query was
+// understood as are returned in meta so clients can show or refine them.
+func (h *DiscoveryHandler) Discover(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxCertification := ""
+	if profile := currentProfile(c); profile != nil {
+		maxCertification = profile.MaxCertification
+	}
+
+	query := c.Query("q")
+	movies, filters, err := h.discoveryService.Discover(c.Request.Context(), userID, query, maxCertification)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDiscoveryQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	start, end := paginateSlice(len(movies), pagination)
+	items := make([]gin.H, 0, end-start)
+	for _, movie := range movies[start:end] {
+		items = append(items, gin.H{
+			"movie_id":    movie.ID,
+			"imdb_id":     movie.IMDbID,
+			"title":       movie.Title,
+			"year":        movie.Year,
+			"genre":       movie.Genre,
+			"runtime":     movie.Runtime,
+			"poster":      movie.Poster,
+			"imdb_rating": movie.IMDbRating,
+			"_links":      Links{"movie": {Href: apiBase(c) + "/movies/" + movie.ID.Hex()}},
+		})
+	}
+
+	respondList(c, items, pagination, int64(len(movies)), gin.H{"query": query, "filters": filters})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"io"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var exportLogger = logging.For("handlers.export")
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// ExportRatings downloads the user's ratings (?format=json|csv, default
+// json). The CSV can be sent back to the ratings import.
+func (h *ExportHandler) ExportRatings(c *gin.Context) {
+	userID, format, ok := exportRequest(c)
+	if !ok {
+		return
+	}
+	streamExport(c, "ratings."+format, exportContentType(format), func(w io.Writer) error {
+		return h.exportService.WriteRatings(userID, format, w)
+	})
+}
+
+// ExportWatchlist downloads the user's watchlist (?format=json|csv, default json)
+func (h *ExportHandler) ExportWatchlist(c *gin.Context) {
+	userID, format, ok := exportRequest(c)
+	if !ok {
+		return
+	}
+	streamExport(c, "watchlist."+format, exportContentType(format), func(w io.Writer) error {
+		return h.exportService.WriteWatchlist(userID, format, w)
+	})
+}
+
+// ExportArchive downloads an account archive ZIP, the format read by
+// ImportArchive
+func (h *ExportHandler) ExportArchive(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	filename := "movie-watchlist-" + time.Now().UTC().Format("2006-01-02") + ".zip"
+	streamExport(c, filename, "application/zip", func(w io.Writer) error {
+		return h.exportService.WriteArchive(userID, w)
+	})
+}
+
+// ExportUsers downloads every user account with the fields of the admin
+// user listing (?format=json|csv, default json)
+func (h *ExportHandler) ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", services.ExportFormatJSON)
+	if !services.ValidExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidExportFormat.Error()})
+		return
+	}
+	streamExport(c, "users."+format, exportContentType(format), func(w io.Writer) error {
+		return h.exportService.WriteUsers(format, w)
+	})
+}
+
+// exportRequest reads the authenticated user and the export format,
+// responding with an error when either is missing or invalid
+func exportRequest(c *gin.Context) (primitive.ObjectID, string, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, "", false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, "", false
+	}
+
+	format := c.DefaultQuery("format", services.ExportFormatJSON)
+	if !services.ValidExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidExportFormat.Error()})
+		return primitive.NilObjectID, "", false
+	}
+	return userID, format, true
+}
+
+func exportContentType(format string) string {
+	if format == services.ExportFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// streamExport sends what write produces as a file download while it is being
+// written. Without a Content-Length the body goes out chunked, and writes
+// block while the client is behind, which in turn holds back the database
+// cursor. Once part of the body is out the status can no longer change, so a
+// failure midway aborts the connection and the client sees an incomplete
+// download instead of a truncated file.
+func streamExport(c *gin.Context, filename, contentType string, write func(io.Writer) error) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	err := write(c.Writer)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		// Nothing was sent yet, so the error replaces the file; gin keeps a
+		// Content-Type that is already set, so the export's is dropped
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export"})
+		return
+	}
+	exportLogger.Error("export failed midway", "path", c.Request.URL.Path, "error", err)
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HabitHandler struct {
+	habitService *services.HabitService
+}
+
+func NewHabitHandler(habitService *services.HabitService) *HabitHandler {
+	return &HabitHandler{habitService: habitService}
+}
+
+type UpdateGoalsRequest struct {
+	MonthlyMovies *int `json:"monthly_movies" binding:"required"`
+}
+
+// GetStats returns the user's watch streaks and monthly goal progress
+func (h *HabitHandler) GetStats(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	habits, err := h.habitService.GetHabits(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, habits)
+}
+
+// UpdateGoals sets the monthly movie goal; 0 removes it
+func (h *HabitHandler) UpdateGoals(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateGoalsRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goals, err := h.habitService.UpdateGoals(userID, *req.MonthlyMovies)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWatchGoal):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
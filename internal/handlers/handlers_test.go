@@ -0,0 +1,134 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/handlers"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/mailer"
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"movie-watchlist/internal/services"
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	testutil.Main(m)
+}
+
+// testServer is the auth, watchlist, rating and recommendation routes of the
+// server, in v1 and v2, wired like main.go against a test database and the
+// OMDb stub
+type testServer struct {
+	router http.Handler
+	db     *database.MongoDB
+	omdb   *testutil.OMDbStub
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db := testutil.Database(t)
+	omdb := testutil.NewOMDbStub(t)
+
+	userRepo := repositories.NewUserRepository(db)
+	movieRepo := repositories.NewMovieRepository(db)
+	watchlistRepo := repositories.NewWatchlistRepository(db)
+	ratingRepo := repositories.NewRatingRepository(db)
+
+	jobQueue := jobs.NewQueue(repositories.NewJobRepository(db), 1)
+	eventBus := events.NewBus()
+	var mail mailer.Mailer = mailer.LogMailer{}
+
+	userService := services.NewUserService(userRepo, &services.PasswordPolicy{MinLength: 8}, eventBus)
+	sessionService := services.NewSessionService(repositories.NewSessionRepository(db))
+	notificationRepo := repositories.NewNotificationRepository(db)
+	loginSecurityService := services.NewLoginSecurityService(repositories.NewLoginAttemptRepository(db), userRepo, notificationRepo, sessionService, mail, "http://localhost")
+	termsService := services.NewTermsService(userRepo, "")
+	omdbUsageService := services.NewOMDbUsageService(repositories.NewOMDbUsageRepository(db), 0)
+	movieHistoryService := services.NewMovieHistoryService(repositories.NewMovieRevisionRepository(db))
+	movieService := services.NewMovieService(movieRepo, omdbUsageService, movieHistoryService, jobQueue, "test-key", omdb.URL, nil, []string{models.MovieSourceOMDb})
+	undoService := services.NewUndoService(repositories.NewDeletedItemRepository(db), watchlistRepo)
+	storageQuotaService := services.NewStorageQuotaService(userRepo, watchlistRepo, services.StorageLimits{})
+	watchlistService := services.NewWatchlistService(watchlistRepo, movieRepo, userRepo, undoService, storageQuotaService, eventBus, jobQueue, 0)
+	ratingService := services.NewRatingService(ratingRepo, movieRepo, eventBus)
+	ratingImportService := services.NewRatingImportService(ratingRepo, movieRepo, movieService)
+	recommendationService := services.NewRecommendationService(movieRepo, ratingRepo, watchlistRepo)
+	followService := services.NewFollowService(repositories.NewPersonFollowRepository(db), movieRepo)
+	recommendationScheduler := services.NewRecommendationScheduler(userRepo, repositories.NewRecommendationSnapshotRepository(db), movieRepo, recommendationService, followService, mail, eventBus, jobQueue)
+	analyticsService := services.NewRecommendationAnalyticsService(repositories.NewRecommendationImpressionRepository(db))
+	advisoryService := services.NewAdvisoryService(services.NewPlotAdvisoryProvider(), movieRepo, repositories.NewAdvisoryReportRepository(db), userRepo, movieHistoryService, jobQueue)
+	progressService := services.NewProgressService(repositories.NewProgressRepository(db), movieRepo, watchlistService)
+	recentViewService := services.NewRecentViewService(repositories.NewRecentViewRepository(db), movieRepo)
+	policy := authz.NewPolicy(nil)
+
+	authHandler := handlers.NewAuthHandler(userService, sessionService, loginSecurityService, testutil.Tokens, "", &services.CaptchaPolicy{LoginFailureWindow: 15 * time.Minute}, termsService)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistService, movieService, policy)
+	ratingHandler := handlers.NewRatingHandler(ratingService, movieService, ratingImportService, policy)
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, userService, recommendationScheduler, analyticsService, followService, advisoryService)
+	v2Handler := handlers.NewV2Handler(movieService, watchlistService, ratingService, recommendationService, recentViewService, userService, recommendationScheduler, analyticsService, advisoryService, undoService, progressService, policy)
+
+	r := gin.New()
+	r.POST("/register", authHandler.Register)
+	r.POST("/login", authHandler.Login)
+
+	api := r.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(testutil.Tokens))
+	api.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	{
+		api.POST("/watchlist", watchlistHandler.AddToWatchlist)
+		api.DELETE("/watchlist/:movieId", watchlistHandler.RemoveFromWatchlist)
+		api.GET("/watchlist", watchlistHandler.GetWatchlist)
+		api.GET("/watchlist/:movieId", watchlistHandler.GetWatchlistItem)
+		api.POST("/ratings", ratingHandler.RateMovie)
+		api.PUT("/ratings/:movieId", ratingHandler.UpdateRating)
+		api.GET("/ratings/:movieId", ratingHandler.GetRating)
+		api.GET("/recommendations", recommendationHandler.GetRecommendations)
+	}
+
+	v2 := r.Group("/api/v2")
+	v2.Use(middleware.AuthMiddleware(testutil.Tokens))
+	v2.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	{
+		v2.POST("/watchlist", v2Handler.AddToWatchlist)
+		v2.DELETE("/watchlist/:movieId", v2Handler.RemoveFromWatchlist)
+		v2.PATCH("/watchlist/:movieId", v2Handler.UpdateWatchlistItem)
+		v2.POST("/undo", v2Handler.Undo)
+		v2.POST("/ratings", v2Handler.RateMovie)
+		v2.PUT("/ratings/:movieId", v2Handler.UpdateRating)
+	}
+
+	return &testServer{router: r, db: db, omdb: omdb}
+}
+
+// seedMovie returns a movie of the seed catalogue, seeding it first
+func (s *testServer) seedMovie(t *testing.T, imdbID string) *models.Movie {
+	t.Helper()
+
+	if err := s.db.SeedMovies(); err != nil {
+		t.Fatalf("seed movies: %v", err)
+	}
+	movie, err := repositories.NewMovieRepository(s.db).FindByIMDbID(imdbID)
+	if err != nil || movie == nil {
+		t.Fatalf("find seeded movie %s: %v", imdbID, err)
+	}
+	return movie
+}
+
+// listResponse is the envelope of paginated lists
+type listResponse struct {
+	Data []map[string]interface{} `json:"data"`
+	Meta struct {
+		Total int64 `json:"total"`
+	} `json:"meta"`
+}
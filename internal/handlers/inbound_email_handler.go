@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InboundEmailHandler struct {
+	inboundService *services.InboundEmailService
+}
+
+func NewInboundEmailHandler(inboundService *services.InboundEmailService) *InboundEmailHandler {
+	return &InboundEmailHandler{inboundService: inboundService}
+}
+
+// CreateInboundAddress returns a new inbound email address for the user,
+// replacing any earlier one
+func (h *InboundEmailHandler) CreateInboundAddress(c *gin.Context) {
+	userID, ok := inboundEmailUserID(c)
+	if !ok {
+		return
+	}
+
+	address, err := h.inboundService.CreateAddress(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"address": address})
+}
+
+// DeleteInboundAddress turns the user's inbound address off
+func (h *InboundEmailHandler) DeleteInboundAddress(c *gin.Context) {
+	userID, ok := inboundEmailUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.inboundService.DeleteAddress(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Inbound email turned off"})
+}
+
+// ReceiveInboundEmail is the inbound mail provider's webhook. It needs no
+// token: the provider's signature or credentials are checked instead.
+func (h *InboundEmailHandler) ReceiveInboundEmail(c *gin.Context) {
+	if err := h.inboundService.Receive(c.Request.Context(), c.Request); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInboundMail):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMalformedInboundMail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			// The provider retries the message later
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func inboundEmailUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
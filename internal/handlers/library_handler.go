@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LibraryHandler struct {
+	libraryService *services.LibraryService
+}
+
+func NewLibraryHandler(libraryService *services.LibraryService) *LibraryHandler {
+	return &LibraryHandler{libraryService: libraryService}
+}
+
+// SearchLibrary searches the movies on the user's watchlist and among their
+// ratings, e.g. ?q=korean thriller
+func (h *LibraryHandler) SearchLibrary(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hits, err := h.libraryService.Search(userID, query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLibraryQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	start, end := paginateSlice(len(hits), pagination)
+	results := make([]gin.H, 0, end-start)
+	for _, hit := range hits[start:end] {
+		item := gin.H{
+			"movie_id":     hit.Movie.ID,
+			"imdb_id":      hit.Movie.IMDbID,
+			"title":        hit.Movie.Title,
+			"year":         hit.Movie.Year,
+			"genre":        hit.Movie.Genre,
+			"language":     hit.Movie.Language,
+			"director":     hit.Movie.Director,
+			"poster":       hit.Movie.Poster,
+			"in_watchlist": hit.InWatchlist,
+			"watched_at":   hit.WatchedAt,
+			"rating":       nil,
+			"matched":      hit.Matched,
+		}
+		links := Links{"movie": {Href: apiBase(c) + "/movies/" + hit.Movie.ID.Hex()}}
+		if hit.InWatchlist {
+			links["watchlist"] = Link{Href: apiBase(c) + "/watchlist/" + hit.Movie.ID.Hex()}
+		}
+		if hit.Rating > 0 {
+			item["rating"] = hit.Rating
+			links["rating"] = Link{Href: apiBase(c) + "/ratings/" + hit.Movie.ID.Hex()}
+		}
+		item["_links"] = links
+		results = append(results, item)
+	}
+
+	respondList(c, results, pagination, int64(len(hits)), nil)
+}
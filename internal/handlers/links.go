@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Link is a hypermedia link; Method is omitted for plain GET links
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// Links maps link relations to links, rendered as an item's _links field
+type Links map[string]Link
+
+// apiBase returns the versioned API prefix of the matched route so links stay
+// within the API version the client is using
+func apiBase(c *gin.Context) string {
+	if strings.HasPrefix(c.FullPath(), "/api/v2") {
+		return "/api/v2"
+	}
+	return "/api/v1"
+}
+
+func watchlistItemLinks(base string, movieID primitive.ObjectID) Links {
+	id := movieID.Hex()
+	return Links{
+		"self":             {Href: base + "/watchlist/" + id},
+		"movie":            {Href: base + "/movies/" + id},
+		"rate":             {Href: base + "/ratings", Method: http.MethodPost},
+		"watchlist-remove": {Href: base + "/watchlist/" + id, Method: http.MethodDelete},
+	}
+}
+
+func ratingItemLinks(base string, movieID primitive.ObjectID) Links {
+	id := movieID.Hex()
+	return Links{
+		"self":  {Href: base + "/ratings/" + id},
+		"movie": {Href: base + "/movies/" + id},
+		"rate":  {Href: base + "/ratings/" + id, Method: http.MethodPut},
+	}
+}
+
+func recommendationItemLinks(base string, movieID primitive.ObjectID) Links {
+	id := movieID.Hex()
+	return Links{
+		"self":          {Href: base + "/movies/" + id},
+		"movie":         {Href: base + "/movies/" + id},
+		"rate":          {Href: base + "/ratings", Method: http.MethodPost},
+		"watchlist-add": {Href: base + "/watchlist", Method: http.MethodPost},
+	}
+}
+
+func progressItemLinks(base string, movieID primitive.ObjectID) Links {
+	id := movieID.Hex()
+	return Links{
+		"movie":    {Href: base + "/movies/" + id},
+		"progress": {Href: base + "/movies/" + id + "/progress", Method: http.MethodPost},
+	}
+}
@@ -1,19 +1,30 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"movie-watchlist/internal/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MovieHandler struct {
-	movieService *services.MovieService
+	movieService      *services.MovieService
+	recentViewService *services.RecentViewService
+	semanticService   *services.SemanticService
+	advisoryService   *services.AdvisoryService
 }
 
-func NewMovieHandler(movieService *services.MovieService) *MovieHandler {
-	return &MovieHandler{movieService: movieService}
+func NewMovieHandler(movieService *services.MovieService, recentViewService *services.RecentViewService, semanticService *services.SemanticService, advisoryService *services.AdvisoryService) *MovieHandler {
+	return &MovieHandler{
+		movieService:      movieService,
+		recentViewService: recentViewService,
+		semanticService:   semanticService,
+		advisoryService:   advisoryService,
+	}
 }
 
 func (h *MovieHandler) SearchMovies(c *gin.Context) {
@@ -23,13 +34,143 @@ func (h *MovieHandler) SearchMovies(c *gin.Context) {
 		return
 	}
 
-	movies, err := h.movieService.SearchMovies(c.Request.Context(), query)
+	pagination, err := parsePaginationWithDefault(c, services.SearchPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// OMDb serves fixed-size pages
+	pagination.PerPage = services.SearchPageSize
+
+	// Kids profiles search with their cap applied before paging, so the
+	// total only counts movies they may see
+	var result *services.SearchResult
+	if profile := currentProfile(c); profile != nil {
+		result, err = h.movieService.SearchMoviesUpTo(query, pagination.Page, profile.MaxCertification)
+	} else {
+		result, err = h.movieService.SearchMovies(c.Request.Context(), query, pagination.Page, optionalUserID(c))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	meta := gin.H{"cache_only": result.CacheOnly}
+	if result.CacheOnly {
+		meta["notice"] = "Daily OMDb quota nearly exhausted; results are limited to locally cached movies"
+	}
+	if len(result.DidYouMean) > 0 {
+		meta["did_you_mean"] = result.DidYouMean
+	}
+
+	respondList(c, result.Movies, pagination, result.Total, meta)
+}
+
+// BrowseMovies lists cached movies by decade or era, genre and keyword. It serves
+// guests and never calls OMDb.
+func (h *MovieHandler) BrowseMovies(c *gin.Context) {
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := services.BrowseQuery{
+		Decade:  c.Query("decade"),
+		Genre:   c.Query("genre"),
+		Keyword: c.Query("keyword"),
+		Sort:    c.Query("sort"),
+	}
+	if query.YearFrom, err = parseYearParam(c, "year_from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.YearTo, err = parseYearParam(c, "year_to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if profile := currentProfile(c); profile != nil {
+		query.MaxCertification = profile.MaxCertification
+	}
+
+	movies, total, err := h.movieService.BrowseMovies(query, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBrowseQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	items := []gin.H{}
+	for _, movie := range movies {
+		items = append(items, gin.H{
+			"id":          movie.ID,
+			"imdb_id":     movie.IMDbID,
+			"title":       movie.Title,
+			"year":        movie.Year,
+			"genre":       movie.Genre,
+			"director":    movie.Director,
+			"poster":      movie.Poster,
+			"imdb_rating": movie.IMDbRating,
+			"rated":       movie.Rated,
+			"keywords":    movie.Keywords,
+			"_links":      Links{"self": {Href: apiBase(c) + "/movies/" + movie.ID.Hex()}},
+		})
+	}
+
+	respondList(c, items, pagination, total, nil)
+}
+
+// parseYearParam reads an optional year query parameter, returning 0 when absent
+func parseYearParam(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1 {
+		return 0, fmt.Errorf("%s must be a year", name)
+	}
+	return year, nil
+}
+
+// SuggestMovies returns title suggestions for search-as-you-type UIs, served
+// from the local cache
+func (h *MovieHandler) SuggestMovies(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	movies, err := h.movieService.SuggestTitles(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if profile := currentProfile(c); profile != nil {
+		movies = services.FilterByCertification(movies, profile.MaxCertification)
+	}
+
+	suggestions := make([]gin.H, 0, len(movies))
+	for _, movie := range movies {
+		suggestions = append(suggestions, gin.H{
+			"movie_id": movie.ID,
+			"imdb_id":  movie.IMDbID,
+			"title":    movie.Title,
+			"year":     movie.Year,
+			"poster":   movie.Poster,
+		})
+	}
 
-	c.JSON(http.StatusOK, gin.H{"movies": movies})
+	// Suggestions only change as the cache grows; let clients reuse them briefly
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{
+		"query":       query,
+		"suggestions": suggestions,
+	})
 }
 
 func (h *MovieHandler) GetMovie(c *gin.Context) {
@@ -45,8 +186,51 @@ func (h *MovieHandler) GetMovie(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
 		return
 	}
+	if movie != nil && !movieAllowed(c, movie) {
+		respondCertificationRestricted(c)
+		return
+	}
+
+	if userID := optionalUserID(c); userID != nil && movie != nil {
+		h.recentViewService.RecordView(*userID, movie.ID)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"movie": movie})
+	response := gin.H{"movie": movie}
+	if movie != nil {
+		avoid, err := avoidedAdvisories(c, h.advisoryService)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Warnings name the movie's advisories the signed-in user avoids
+		if avoid != nil {
+			response["warnings"] = services.AdvisoryWarnings(*movie, avoid)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPosterPlaceholder serves the generated poster that replaces a dead
+// poster link
+func (h *MovieHandler) GetPosterPlaceholder(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	movie, err := h.movieService.GetMovieByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if movie == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/svg+xml", services.PosterPlaceholderSVG(movie))
 }
 
 // GetMovieByIMDbID fetches movie details by IMDb ID
@@ -62,6 +246,156 @@ func (h *MovieHandler) GetMovieByIMDbID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !movieAllowed(c, movie) {
+		respondCertificationRestricted(c)
+		return
+	}
+
+	if userID := optionalUserID(c); userID != nil {
+		h.recentViewService.RecordView(*userID, movie.ID)
+	}
 
 	c.JSON(http.StatusOK, movie)
 }
+
+// GetSimilarMovies lists the movies whose plots are closest in meaning to the movie's
+func (h *MovieHandler) GetSimilarMovies(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	// Plot similarity is the only mode for now; the parameter leaves room for others
+	if mode := c.DefaultQuery("mode", "semantic"); mode != "semantic" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be semantic"})
+		return
+	}
+
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matches, err := h.semanticService.SimilarMovies(c.Request.Context(), id, services.MaxSemanticResults)
+	if err != nil {
+		if err.Error() == "movie not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			return
+		}
+		if errors.Is(err, services.ErrNoPlot) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondSemanticMatches(c, matches, pagination, gin.H{"movie_id": id, "mode": "semantic"})
+}
+
+// SemanticSearch finds movies from a free-text description of their plot,
+// such as "movies about time loops"
+func (h *MovieHandler) SemanticSearch(c *gin.Context) {
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := c.Query("q")
+	matches, err := h.semanticService.SearchByText(c.Request.Context(), query, services.MaxSemanticResults)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSemanticQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondSemanticMatches(c, matches, pagination, gin.H{"query": query})
+}
+
+// respondSemanticMatches pages through plot matches the active profile may see
+func respondSemanticMatches(c *gin.Context, matches []services.SemanticMatch, pagination Pagination, meta gin.H) {
+	if profile := currentProfile(c); profile != nil {
+		allowed := matches[:0]
+		for _, match := range matches {
+			if services.CertificationAllowed(match.Movie.Rated, profile.MaxCertification) {
+				allowed = append(allowed, match)
+			}
+		}
+		matches = allowed
+	}
+
+	start, end := paginateSlice(len(matches), pagination)
+	items := make([]gin.H, 0, end-start)
+	for _, match := range matches[start:end] {
+		items = append(items, gin.H{
+			"movie_id":   match.Movie.ID,
+			"imdb_id":    match.Movie.IMDbID,
+			"title":      match.Movie.Title,
+			"year":       match.Movie.Year,
+			"genre":      match.Movie.Genre,
+			"poster":     match.Movie.Poster,
+			"similarity": match.Similarity,
+			"_links":     Links{"movie": {Href: apiBase(c) + "/movies/" + match.Movie.ID.Hex()}},
+		})
+	}
+
+	respondList(c, items, pagination, int64(len(matches)), meta)
+}
+
+// GetRecentlyViewed lists the movies whose detail pages the user opened most recently
+func (h *MovieHandler) GetRecentlyViewed(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	views, total, err := h.recentViewService.GetRecentlyViewedPage(userID, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	movieIDs := make([]primitive.ObjectID, 0, len(views))
+	for _, view := range views {
+		movieIDs = append(movieIDs, view.MovieID)
+	}
+	movies, err := h.movieService.GetMoviesByIDs(movieIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := []gin.H{}
+	for _, view := range views {
+		movie := movies[view.MovieID]
+		items = append(items, gin.H{
+			"movie_id":  view.MovieID,
+			"title":     movie.Title,
+			"year":      movie.Year,
+			"poster":    movie.Poster,
+			"viewed_at": view.ViewedAt,
+			"_links":    Links{"movie": {Href: apiBase(c) + "/movies/" + view.MovieID.Hex()}},
+		})
+	}
+
+	respondList(c, items, pagination, total, nil)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+	policy              *authz.Policy
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService, policy *authz.Policy) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService, policy: policy}
+}
+
+// GetNotifications lists the user's notifications, newest first; ?unread=true limits to unread ones
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+	notifications, total, err := h.notificationService.GetUserNotificationsPage(userID, unreadOnly, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, notifications, pagination, total, nil)
+}
+
+// MarkNotificationRead marks a notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "notification not found")
+		return
+	}
+
+	notificationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	notification, err := h.notificationService.GetNotification(notificationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if notification == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	if err := h.policy.RequireOwner(principal, notification.UserID); err != nil {
+		respondAuthzError(c, err, "notification not found")
+		return
+	}
+
+	if err := h.notificationService.MarkRead(principal.UserID, notificationID); err != nil {
+		if err.Error() == "notification not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthAppHandler manages the third-party apps a user registered and the
+// consent step of the authorization code flow. Tokens are issued by the
+// token endpoint in OIDCHandler.
+type OAuthAppHandler struct {
+	appService *services.OAuthAppService
+}
+
+func NewOAuthAppHandler(appService *services.OAuthAppService) *OAuthAppHandler {
+	return &OAuthAppHandler{appService: appService}
+}
+
+type OAuthAppRequest struct {
+	Name         string   `json:"name" binding:"required" sanitize:"line,max=64"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+}
+
+type AuthorizeOAuthAppRequest struct {
+	ClientID            string `json:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" binding:"required"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	// Approve is false when the user turns the app away
+	Approve bool `json:"approve"`
+}
+
+// GetOAuthApps lists the apps the user registered
+func (h *OAuthAppHandler) GetOAuthApps(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	apps, err := h.appService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get apps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"apps": apps, "scopes": services.OAuthScopes})
+}
+
+// GetOAuthApp returns one of the user's apps
+func (h *OAuthAppHandler) GetOAuthApp(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	app, err := h.appService.Get(userID, c.Param("clientId"))
+	if err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
+
+// CreateOAuthApp registers an app. Its client secret is in the response and
+// cannot be shown again.
+func (h *OAuthAppHandler) CreateOAuthApp(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	var req OAuthAppRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	app, secret, err := h.appService.Register(userID, req.Name, req.RedirectURIs, req.Scopes)
+	if err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{"app": app, "client_secret": secret})
+}
+
+// UpdateOAuthApp replaces the name, redirect URIs and scopes of an app
+func (h *OAuthAppHandler) UpdateOAuthApp(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	var req OAuthAppRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	app, err := h.appService.Update(userID, c.Param("clientId"), req.Name, req.RedirectURIs, req.Scopes)
+	if err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
+
+// RotateOAuthAppSecret gives an app a new client secret. Tokens the app
+// already holds keep working.
+func (h *OAuthAppHandler) RotateOAuthAppSecret(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	secret, err := h.appService.RotateSecret(userID, c.Param("clientId"))
+	if err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"client_secret": secret})
+}
+
+// DeleteOAuthApp removes an app and signs it out of every account it was
+// authorized for
+func (h *OAuthAppHandler) DeleteOAuthApp(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.appService.Delete(userID, c.Param("clientId")); err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "App deleted"})
+}
+
+// GetAuthorization checks an app's authorization request and returns the
+// app and scopes the consent page asks the user to approve
+func (h *OAuthAppHandler) GetAuthorization(c *gin.Context) {
+	if _, ok := oauthAppUserID(c); !ok {
+		return
+	}
+
+	authorization, _, err := h.appService.CheckAuthorization(services.OAuthAuthorizationRequest{
+		ClientID:            c.Query("client_id"),
+		RedirectURI:         c.Query("redirect_uri"),
+		Scope:               c.Query("scope"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+	})
+	if err != nil {
+		respondOAuthAppError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// Authorize records the user's answer to an authorization request and
+// returns where to send the browser: the app's redirect URI with a code, or
+// with error=access_denied. Requests that fail validation are answered here
+// and never redirected, since the redirect URI may not be the app's.
+func (h *OAuthAppHandler) Authorize(c *gin.Context) {
+	userID, ok := oauthAppUserID(c)
+	if !ok {
+		return
+	}
+
+	var req AuthorizeOAuthAppRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authReq := services.OAuthAuthorizationRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+	params := url.Values{}
+	if req.Approve {
+		code, err := h.appService.Authorize(userID, authReq)
+		if err != nil {
+			respondOAuthAppError(c, err)
+			return
+		}
+		params.Set("code", code)
+	} else {
+		if _, _, err := h.appService.CheckAuthorization(authReq); err != nil {
+			respondOAuthAppError(c, err)
+			return
+		}
+		params.Set("error", "access_denied")
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	redirect, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect_uri", "code": "INVALID_AUTHORIZATION_REQUEST"})
+		return
+	}
+	query := redirect.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	redirect.RawQuery = query.Encode()
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"redirect_to": redirect.String()})
+}
+
+// RedirectToConsent sends a browser following an app's authorization URL to
+// the web app's consent page, keeping the request's parameters
+func (h *OAuthAppHandler) RedirectToConsent(c *gin.Context) {
+	target := h.appService.ConsentURL()
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+func oauthAppUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func respondOAuthAppError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOAuthAppNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "App not found", "code": "APP_NOT_FOUND"})
+	case errors.Is(err, services.ErrOAuthAppLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "APP_LIMIT_REACHED"})
+	case errors.Is(err, services.ErrInvalidOAuthApp):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_APP"})
+	case errors.Is(err, services.ErrInvalidAuthorization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_AUTHORIZATION_REQUEST"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCHandler serves the OpenID Connect endpoints companion apps sign in
+// through, and the OAuth 2.0 token endpoint third-party apps use. Errors
+// follow OAuth 2.0 ({"error": "invalid_grant", ...}) rather than the API's
+// usual shape, since standard client libraries parse them.
+type OIDCHandler struct {
+	provider       *services.OIDCProvider
+	appService     *services.OAuthAppService
+	auth           *AuthHandler
+	userService    *services.UserService
+	sessionService *services.SessionService
+	tokens         middleware.TokenConfig
+}
+
+func NewOIDCHandler(provider *services.OIDCProvider, appService *services.OAuthAppService, auth *AuthHandler, userService *services.UserService, sessionService *services.SessionService, tokens middleware.TokenConfig) *OIDCHandler {
+	return &OIDCHandler{
+		provider:       provider,
+		appService:     appService,
+		auth:           auth,
+		userService:    userService,
+		sessionService: sessionService,
+		tokens:         tokens,
+	}
+}
+
+// GetConfiguration returns the OpenID provider metadata
+func (h *OIDCHandler) GetConfiguration(c *gin.Context) {
+	metadata := h.provider.Discovery()
+	if h.appService.ConsentURL() != "" {
+		issuer := metadata["issuer"].(string)
+		metadata["authorization_endpoint"] = issuer + "/oauth/authorize"
+		metadata["response_types_supported"] = []string{"code"}
+		metadata["code_challenge_methods_supported"] = []string{"S256"}
+	}
+	c.JSON(http.StatusOK, metadata)
+}
+
+// GetKeys returns the public keys ID tokens are signed with
+func (h *OIDCHandler) GetKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, h.provider.JWKS())
+}
+
+// Token is the OAuth 2.0 token endpoint. It takes a form-encoded password
+// or refresh_token grant from a companion app in OIDC_CLIENT_IDS and returns
+// the API's access and refresh tokens, plus an ID token when openid is in
+// scope. Registered third-party apps are served by appToken.
+func (h *OIDCHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	clientID, clientSecret := c.PostForm("client_id"), c.PostForm("client_secret")
+	if basicID, basicSecret, ok := c.Request.BasicAuth(); ok && clientID == "" {
+		clientID, clientSecret = basicID, basicSecret
+	}
+	if !h.provider.AllowsClient(clientID) {
+		h.appToken(c, clientID, clientSecret)
+		return
+	}
+
+	var (
+		login        *loginResult
+		token        string
+		refreshToken string
+	)
+	switch c.PostForm("grant_type") {
+	case "password":
+		username, password := c.PostForm("username"), c.PostForm("password")
+		if username == "" || password == "" {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "username and password are required")
+			return
+		}
+		var authErr *authError
+		login, authErr = h.auth.passwordLogin(c, username, password, c.PostForm("captcha_token"))
+		if authErr != nil {
+			respondOAuthLoginError(c, authErr)
+			return
+		}
+		token, refreshToken = login.token, login.refreshToken
+
+	case "refresh_token":
+		if c.PostForm("refresh_token") == "" {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+			return
+		}
+		session, newRefreshToken, err := h.sessionService.Refresh(c.PostForm("refresh_token"), "", c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			if err.Error() == "invalid refresh token" {
+				oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			} else {
+				oauthError(c, http.StatusInternalServerError, "server_error", "Failed to refresh session")
+			}
+			return
+		}
+		token, err = middleware.GenerateSessionToken(session.UserID, session.ID.Hex(), h.tokens)
+		if err != nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate token")
+			return
+		}
+		user, err := h.userService.GetByID(session.UserID)
+		if err != nil || user == nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to load user")
+			return
+		}
+		login = &loginResult{user: user, session: session}
+		refreshToken = newRefreshToken
+
+	case "":
+		oauthError(c, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be password or refresh_token")
+		return
+	}
+
+	scopes := h.provider.GrantedScopes(c.PostForm("scope"))
+	response := gin.H{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.tokens.TTL.Seconds()),
+		"refresh_token": refreshToken,
+		"scope":         strings.Join(scopes, " "),
+	}
+	if containsScope(scopes, "openid") {
+		idToken, err := h.provider.IDToken(login.user, clientID, login.session)
+		if err != nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate ID token")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// appToken serves the token endpoint for registered third-party apps, which
+// authenticate with their client secret. The authorization_code and
+// refresh_token grants return tokens of the app's session with the user,
+// limited to the granted scopes; client_credentials returns a token of the
+// app itself, without a user or refresh token.
+func (h *OIDCHandler) appToken(c *gin.Context, clientID, clientSecret string) {
+	app, err := h.appService.Authenticate(clientID, clientSecret)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidClient) {
+			oauthError(c, http.StatusUnauthorized, "invalid_client", "Unknown client or wrong client secret")
+		} else {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to authenticate client")
+		}
+		return
+	}
+
+	var (
+		session      *models.Session
+		refreshToken string
+	)
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		if c.PostForm("code") == "" {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "code is required")
+			return
+		}
+		session, refreshToken, err = h.appService.ExchangeCode(app, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"), c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidGrant) {
+				oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid, expired or used authorization code")
+			} else {
+				oauthError(c, http.StatusInternalServerError, "server_error", "Failed to exchange authorization code")
+			}
+			return
+		}
+
+	case "refresh_token":
+		if c.PostForm("refresh_token") == "" {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+			return
+		}
+		session, refreshToken, err = h.sessionService.Refresh(c.PostForm("refresh_token"), app.ClientID, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			if err.Error() == "invalid refresh token" {
+				oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			} else {
+				oauthError(c, http.StatusInternalServerError, "server_error", "Failed to refresh session")
+			}
+			return
+		}
+		// Scopes the app no longer has end with the next refresh
+		session.Scopes = h.appService.LimitScopes(app, session.Scopes)
+
+	case "client_credentials":
+		token, err := middleware.GenerateAppToken(primitive.NilObjectID, "", app.ClientID, nil, h.tokens)
+		if err != nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate token")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   int(h.tokens.TTL.Seconds()),
+		})
+		return
+
+	case "":
+		oauthError(c, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code, refresh_token or client_credentials")
+		return
+	}
+
+	token, err := middleware.GenerateAppToken(session.UserID, session.ID.Hex(), app.ClientID, session.Scopes, h.tokens)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.tokens.TTL.Seconds()),
+		"refresh_token": refreshToken,
+		"scope":         strings.Join(session.Scopes, " "),
+	})
+}
+
+// GetUserInfo returns the claims about the user the access token belongs to
+func (h *OIDCHandler) GetUserInfo(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	user, err := h.userService.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.provider.UserInfo(user))
+}
+
+func oauthError(c *gin.Context, status int, code, description string) {
+	if code == "invalid_client" {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// respondOAuthLoginError maps a failed password login onto an OAuth error.
+// A required CAPTCHA keeps its code so an app can send captcha_token.
+func respondOAuthLoginError(c *gin.Context, authErr *authError) {
+	switch authErr.status {
+	case http.StatusUnauthorized:
+		oauthError(c, http.StatusBadRequest, "invalid_grant", authErr.message)
+	case http.StatusBadRequest:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": authErr.message, "code": authErr.code})
+	case http.StatusServiceUnavailable:
+		oauthError(c, http.StatusServiceUnavailable, "temporarily_unavailable", authErr.message)
+	default:
+		oauthError(c, http.StatusInternalServerError, "server_error", authErr.message)
+	}
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
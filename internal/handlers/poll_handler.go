@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PollHandler struct {
+	pollService *services.PollService
+}
+
+func NewPollHandler(pollService *services.PollService) *PollHandler {
+	return &PollHandler{pollService: pollService}
+}
+
+type CreatePollRequest struct {
+	Title     string     `json:"title" binding:"required" sanitize:"line,max=100"`
+	MovieIDs  []string   `json:"movie_ids" binding:"required" sanitize:"line,max=24"`
+	Usernames []string   `json:"usernames" sanitize:"line,max=50"`
+	ClosesAt  *time.Time `json:"closes_at"`
+	// ScheduledFor is when the movie night starts
+	ScheduledFor *time.Time `json:"scheduled_for"`
+}
+
+type UpdatePollRequest struct {
+	Title        string     `json:"title" binding:"required" sanitize:"line,max=100"`
+	ClosesAt     *time.Time `json:"closes_at"`
+	ScheduledFor *time.Time `json:"scheduled_for"`
+}
+
+type VotePollRequest struct {
+	Ranking []string `json:"ranking" binding:"required" sanitize:"line,max=24"`
+}
+
+// CreatePoll starts a movie night poll and invites users to vote
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	userID, ok := pollUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePollRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	movieIDs, ok := parseMovieIDs(c, req.MovieIDs)
+	if !ok {
+		return
+	}
+
+	poll, err := h.pollService.Create(userID, req.Title, movieIDs, req.Usernames, req.ClosesAt, req.ScheduledFor)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, poll)
+}
+
+// GetPolls lists the polls the user owns or was invited to, newest first
+func (h *PollHandler) GetPolls(c *gin.Context) {
+	userID, ok := pollUserID(c)
+	if !ok {
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	polls, total, err := h.pollService.List(userID, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, polls, pagination, total, nil)
+}
+
+// GetPoll returns a poll with its movies and the user's ballot
+func (h *PollHandler) GetPoll(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	poll, err := h.pollService.Get(userID, pollID)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// UpdatePoll changes the title and closing time of an open poll
+func (h *PollHandler) UpdatePoll(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePollRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poll, err := h.pollService.Update(userID, pollID, req.Title, req.ClosesAt, req.ScheduledFor)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// ClosePoll ends voting on a poll
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	poll, err := h.pollService.Close(userID, pollID)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// DeletePoll removes a poll with its ballots
+func (h *PollHandler) DeletePoll(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.pollService.Delete(userID, pollID); err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Poll deleted"})
+}
+
+// VotePoll records the user's ranking of a poll's movies
+func (h *PollHandler) VotePoll(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	var req VotePollRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ranking, ok := parseMovieIDs(c, req.Ranking)
+	if !ok {
+		return
+	}
+
+	ballot, err := h.pollService.Vote(userID, pollID, ranking)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ballot)
+}
+
+// GetPollResults counts a poll's ballots by instant runoff
+func (h *PollHandler) GetPollResults(c *gin.Context) {
+	userID, pollID, ok := pollIDs(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.pollService.Results(userID, pollID)
+	if err != nil {
+		respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func pollUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func pollIDs(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, ok := pollUserID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	pollID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid poll ID format"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return userID, pollID, true
+}
+
+func parseMovieIDs(c *gin.Context, values []string) ([]primitive.ObjectID, bool) {
+	movieIDs := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		movieID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+			return nil, false
+		}
+		movieIDs = append(movieIDs, movieID)
+	}
+	return movieIDs, true
+}
+
+func respondPollError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPoll), errors.Is(err, services.ErrInvalidBallot):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPollNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll not found"})
+	case errors.Is(err, services.ErrNotPollOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPollClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Poll is closed", "code": "POLL_CLOSED"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProfileHandler struct {
+	profileService *services.ProfileService
+	policy         *authz.Policy
+}
+
+func NewProfileHandler(profileService *services.ProfileService, policy *authz.Policy) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService, policy: policy}
+}
+
+// ProfileRequest creates or updates a kids profile; max_certification
+// defaults to PG
+type ProfileRequest struct {
+	Name             string `json:"name" binding:"required" sanitize:"line,max=40"`
+	MaxCertification string `json:"max_certification" sanitize:"line,max=10"`
+}
+
+// GetProfiles lists the account's kids profiles
+func (h *ProfileHandler) GetProfiles(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	profiles, err := h.profileService.ListProfiles(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profiles":       profiles,
+		"max_profiles":   services.MaxProfilesPerAccount,
+		"certifications": services.ProfileCertifications,
+	})
+}
+
+// CreateProfile adds a kids profile to the account
+func (h *ProfileHandler) CreateProfile(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req ProfileRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.profileService.CreateProfile(userID, req.Name, req.MaxCertification)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCertification), err.Error() == "profile name is required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "profile limit reached":
+			c.JSON(http.StatusConflict, gin.H{"error": "An account can have at most 6 profiles"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+// UpdateProfile renames a kids profile or changes its certification cap
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Profile not found")
+		return
+	}
+
+	profileID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+		return
+	}
+
+	if !h.authorizeProfile(c, principal, profileID) {
+		return
+	}
+
+	var req ProfileRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(profileID, principal.AccountID, req.Name, req.MaxCertification)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCertification), err.Error() == "profile name is required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "profile not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteProfile removes a kids profile along with its watchlist and ratings
+func (h *ProfileHandler) DeleteProfile(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Profile not found")
+		return
+	}
+
+	profileID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile ID"})
+		return
+	}
+
+	if !h.authorizeProfile(c, principal, profileID) {
+		return
+	}
+
+	if err := h.profileService.DeleteProfile(profileID, principal.AccountID); err != nil {
+		if err.Error() == "profile not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile deleted"})
+}
+
+// authorizeProfile checks that the principal's account owns the profile and
+// writes the error response when it does not
+func (h *ProfileHandler) authorizeProfile(c *gin.Context, principal *authz.Principal, profileID primitive.ObjectID) bool {
+	profile, err := h.profileService.GetProfileByID(profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if profile == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return false
+	}
+	if err := h.policy.RequireAccountOwner(principal, profile.ParentID); err != nil {
+		respondAuthzError(c, err, "Profile not found")
+		return false
+	}
+	return true
+}
+
+// currentProfile returns the kids profile selected for the request, or nil
+// when the request acts as the account itself
+func currentProfile(c *gin.Context) *models.Profile {
+	value, exists := c.Get("profile")
+	if !exists {
+		return nil
+	}
+	profile, _ := value.(*models.Profile)
+	return profile
+}
+
+// accountUserID returns the signed-in account's ID. It differs from user_id
+// while a kids profile is selected; account-level settings such as languages
+// live on the account.
+func accountUserID(c *gin.Context, userID primitive.ObjectID) primitive.ObjectID {
+	if accountID, ok := c.Get("account_id"); ok {
+		if id, ok := accountID.(primitive.ObjectID); ok {
+			return id
+		}
+	}
+	return userID
+}
+
+// movieAllowed reports whether the movie may be shown to the selected profile
+func movieAllowed(c *gin.Context, movie *models.Movie) bool {
+	profile := currentProfile(c)
+	return profile == nil || services.CertificationAllowed(movie.Rated, profile.MaxCertification)
+}
+
+// respondCertificationRestricted rejects a movie above the profile's certification cap
+func respondCertificationRestricted(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "This movie is not available on this profile",
+		"code":  "CERTIFICATION_RESTRICTED",
+	})
+}
+
+// checkCertification rejects movies above the selected kids profile's cap,
+// writing the response and returning false
+func checkCertification(c *gin.Context, movieService *services.MovieService, movieID primitive.ObjectID) bool {
+	if currentProfile(c) == nil {
+		return true
+	}
+
+	movie, err := movieService.GetMovieByID(movieID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if movie == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		return false
+	}
+	if !movieAllowed(c, movie) {
+		respondCertificationRestricted(c)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgressHandler struct {
+	progressService *services.ProgressService
+	movieService    *services.MovieService
+}
+
+func NewProgressHandler(progressService *services.ProgressService, movieService *services.MovieService) *ProgressHandler {
+	return &ProgressHandler{
+		progressService: progressService,
+		movieService:    movieService,
+	}
+}
+
+type RecordProgressRequest struct {
+	MinutesWatched *int `json:"minutes_watched" binding:"required,min=0"`
+}
+
+// RecordProgress stores how far the user got into a movie
+func (h *ProgressHandler) RecordProgress(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	var req RecordProgressRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	progress, err := h.progressService.RecordProgress(userID, movieID, *req.MinutesWatched)
+	if err != nil {
+		if err.Error() == "movie not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"movie_id":        progress.MovieID,
+		"minutes_watched": progress.MinutesWatched,
+		"completed":       progress.CompletedAt != nil,
+		"completed_at":    progress.CompletedAt,
+		"updated_at":      progress.UpdatedAt,
+	})
+}
+
+// GetContinueWatching lists movies the user started but hasn't finished
+func (h *ProgressHandler) GetContinueWatching(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, total, err := h.progressService.GetContinueWatchingPage(userID, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	movieIDs := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		movieIDs = append(movieIDs, entry.MovieID)
+	}
+	movies, err := h.movieService.GetMoviesByIDs(movieIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := []gin.H{}
+	for _, entry := range entries {
+		movie := movies[entry.MovieID]
+		runtime := movie.RuntimeMinutes()
+
+		var percent interface{}
+		if runtime > 0 {
+			percent = entry.MinutesWatched * 100 / runtime
+		}
+
+		items = append(items, gin.H{
+			"movie_id":        entry.MovieID,
+			"title":           movie.Title,
+			"poster":          movie.Poster,
+			"runtime_minutes": runtime,
+			"minutes_watched": entry.MinutesWatched,
+			"percent_watched": percent,
+			"updated_at":      entry.UpdatedAt,
+			"_links":          progressItemLinks(apiBase(c), entry.MovieID),
+		})
+	}
+
+	respondList(c, items, pagination, total, nil)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QuickAddHandler struct {
+	quickAddService  *services.QuickAddService
+	watchlistService *services.WatchlistService
+	movieService     *services.MovieService
+}
+
+func NewQuickAddHandler(quickAddService *services.QuickAddService, watchlistService *services.WatchlistService, movieService *services.MovieService) *QuickAddHandler {
+	return &QuickAddHandler{
+		quickAddService:  quickAddService,
+		watchlistService: watchlistService,
+		movieService:     movieService,
+	}
+}
+
+type QuickAddRequest struct {
+	// URL is the page the extension was on, e.g. an IMDb or Netflix title page
+	URL string `json:"url" sanitize:"line,max=2048"`
+	// Title is the page or movie title the extension read from the page
+	Title string `json:"title" sanitize:"line,max=300"`
+	// IMDbID confirms one of the candidates offered for an unsure match
+	IMDbID   string `json:"imdb_id" sanitize:"line,max=12"`
+	Priority int    `json:"priority"`
+}
+
+// QuickAdd resolves a page URL or title to a movie and adds it to the
+// watchlist, returning the match and how confident it was
+func (h *QuickAddHandler) QuickAdd(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req QuickAddRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	match, err := h.quickAddService.Resolve(c.Request.Context(), req.IMDbID, req.URL, req.Title, userID)
+	if err != nil {
+		var unsure *services.QuickAddError
+		switch {
+		case errors.Is(err, services.ErrQuickAddEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.As(err, &unsure):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "No confident match, pick one of the candidates",
+				"code":       "NO_CONFIDENT_MATCH",
+				"query":      unsure.Query,
+				"candidates": unsure.Candidates,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !checkCertification(c, h.movieService, match.Movie.ID) {
+		return
+	}
+
+	source := &models.WatchlistSource{Type: models.WatchlistSourceExtension, Detail: sourceSite(req.URL)}
+	entry, err := h.watchlistService.AddWithServerSource(userID, match.Movie.ID, req.Priority, source)
+	if err != nil {
+		if errors.Is(err, services.ErrQuotaExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		} else if err.Error() == "movie already in watchlist" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Movie is already in your watchlist", "match": match})
+		} else if err.Error() == "invalid priority" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Movie added to watchlist successfully",
+		"movie_id": entry.MovieID.Hex(),
+		"match":    match,
+	})
+}
+
+// sourceSite returns the site a page URL belongs to, such as netflix.com,
+// for the watchlist source; empty without a URL
+func sourceSite(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
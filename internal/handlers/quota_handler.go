@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QuotaHandler struct {
+	requestLimiter *middleware.RateLimiter
+	searchLimiter  *middleware.RateLimiter
+	usageService   *services.OMDbUsageService
+	storageQuota   *services.StorageQuotaService
+}
+
+func NewQuotaHandler(requestLimiter, searchLimiter *middleware.RateLimiter, usageService *services.OMDbUsageService, storageQuota *services.StorageQuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		requestLimiter: requestLimiter,
+		searchLimiter:  searchLimiter,
+		usageService:   usageService,
+		storageQuota:   storageQuota,
+	}
+}
+
+// GetQuota summarizes the caller's remaining request and search allowances
+// without spending any of them, and their storage limits and usage
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	key := middleware.RateLimitKey(c)
+
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	storage, err := h.storageQuota.Usage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": presentRateStatus(h.requestLimiter, key),
+		"search":   presentRateStatus(h.searchLimiter, key),
+		// Searches fall back to cached movies only once the shared daily OMDb quota runs low
+		"search_cache_only": h.usageService.IsQuotaNearlyExhausted(),
+		"storage":           storage,
+	})
+}
+
+// GetUserStorageQuota shows a user's storage limits and usage (admin only)
+func (h *QuotaHandler) GetUserStorageQuota(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	usage, err := h.storageQuota.Usage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if usage == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// SetUserStorageQuota overrides a user's storage limits (admin only). A limit
+// of 0 lifts it, and a null or missing limit restores the configured default.
+func (h *QuotaHandler) SetUserStorageQuota(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var quota models.StorageQuota
+	if err := bindJSON(c, &quota); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.saveStorageQuota(c, userID, &quota)
+}
+
+// ResetUserStorageQuota restores the configured storage limits for a user
+// (admin only)
+func (h *QuotaHandler) ResetUserStorageQuota(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	h.saveStorageQuota(c, userID, nil)
+}
+
+func (h *QuotaHandler) saveStorageQuota(c *gin.Context, userID primitive.ObjectID, quota *models.StorageQuota) {
+	found, err := h.storageQuota.SetOverride(userID, quota)
+	if errors.Is(err, services.ErrInvalidStorageQuota) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.GetUserStorageQuota(c)
+}
+
+// presentRateStatus renders a limiter's standing; disabled limiters report unlimited
+func presentRateStatus(limiter *middleware.RateLimiter, key string) gin.H {
+	if !limiter.Enabled() {
+		return gin.H{"unlimited": true}
+	}
+
+	status := limiter.Peek(key)
+	return gin.H{
+		"unlimited": false,
+		"limit":     status.Limit,
+		"remaining": status.Remaining,
+		"reset_at":  status.Reset.UTC(),
+	}
+}
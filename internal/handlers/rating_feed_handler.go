@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RatingFeedHandler struct {
+	ratingFeedService *services.RatingFeedService
+}
+
+func NewRatingFeedHandler(ratingFeedService *services.RatingFeedService) *RatingFeedHandler {
+	return &RatingFeedHandler{ratingFeedService: ratingFeedService}
+}
+
+type UpdateRatingVisibilityRequest struct {
+	Public *bool `json:"public" binding:"required"`
+}
+
+// UpdateVisibility sets whether the user's ratings are published as a feed
+func (h *RatingFeedHandler) UpdateVisibility(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateRatingVisibilityRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.ratingFeedService.SetPublic(userID, *req.Public); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"public": *req.Public})
+}
+
+// GetRatingFeed serves the RSS feed of a user who made their ratings
+// public. It is available to guests.
+func (h *RatingFeedHandler) GetRatingFeed(c *gin.Context) {
+	feed, err := h.ratingFeedService.PublicFeed(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Private ratings look the same as an unknown user
+	if feed == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", feed)
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"movie-watchlist/internal/authz"
 	"movie-watchlist/internal/services"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,48 +14,75 @@ import (
 
 type RatingHandler struct {
 	ratingService *services.RatingService
+	movieService  *services.MovieService
+	importService *services.RatingImportService
+	policy        *authz.Policy
 }
 
-func NewRatingHandler(ratingService *services.RatingService) *RatingHandler {
-	return &RatingHandler{ratingService: ratingService}
+func NewRatingHandler(ratingService *services.RatingService, movieService *services.MovieService, importService *services.RatingImportService, policy *authz.Policy) *RatingHandler {
+	return &RatingHandler{
+		ratingService: ratingService,
+		movieService:  movieService,
+		importService: importService,
+		policy:        policy,
+	}
 }
 
+// RateMovieRequest identifies the movie either by movie_id or, when rating
+// straight from a search result, by imdb_id
 type RateMovieRequest struct {
-	MovieID string `json:"movie_id" binding:"required"`
+	MovieID string `json:"movie_id" sanitize:"line,max=24"`
+	IMDbID  string `json:"imdb_id" sanitize:"line,max=12"`
 	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
 }
 
 type UpdateRatingRequest struct {
 	Rating int `json:"rating" binding:"required,min=1,max=5"`
+	// Version makes the update conditional, like an If-Match header
+	Version *int `json:"version"`
 }
 
 func (h *RatingHandler) RateMovie(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Rating not found")
 		return
 	}
+	userID := principal.UserID
 
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	var req RateMovieRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req RateMovieRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var movieID primitive.ObjectID
+	switch {
+	case req.MovieID != "":
+		// Parse movie ID from string to ObjectID
+		parsed, err := primitive.ObjectIDFromHex(req.MovieID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+			return
+		}
+		movieID = parsed
+	case req.IMDbID != "":
+		movie, err := h.movieService.GetOrCreateByIMDbID(req.IMDbID)
+		if err != nil {
+			respondMovieLookupError(c, err)
+			return
+		}
+		movieID = movie.ID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either movie_id or imdb_id is required"})
 		return
 	}
 
-	// Parse movie ID from string to ObjectID
-	movieID, err := primitive.ObjectIDFromHex(req.MovieID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+	if !checkCertification(c, h.movieService, movieID) {
 		return
 	}
 
-	err = h.ratingService.RateMovie(userID, movieID, req.Rating)
+	rating, err := h.ratingService.RateMovie(userID, movieID, req.Rating)
 	if err != nil {
 		if err.Error() == "user has already rated this movie" {
 			c.JSON(http.StatusConflict, gin.H{"error": "You have already rated this movie. Use the update endpoint to change your rating."})
@@ -63,24 +94,20 @@ func (h *RatingHandler) RateMovie(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Movie rated successfully",
-		"movie_id": req.MovieID,
+		"movie_id": rating.MovieID.Hex(),
 		"rating":   req.Rating,
 		"stars":   h.getStarDisplay(req.Rating),
+		"version":  rating.Version,
 	})
 }
 
 func (h *RatingHandler) UpdateRating(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Rating not found")
 		return
 	}
+	userID := principal.UserID
 
 	movieIDParam := c.Param("movieId")
 	movieID, err := primitive.ObjectIDFromHex(movieIDParam)
@@ -90,14 +117,22 @@ func (h *RatingHandler) UpdateRating(c *gin.Context) {
 	}
 
 	var req UpdateRatingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.ratingService.UpdateRating(userID, movieID, req.Rating)
+	version, err := expectedVersion(c, req.Version)
 	if err != nil {
-		if err.Error() == "rating not found" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.ratingService.UpdateRating(userID, movieID, req.Rating, version)
+	if err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			respondVersionConflict(c)
+		} else if err.Error() == "rating not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "You haven't rated this movie yet. Use the rate endpoint to add a rating."})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -105,52 +140,139 @@ func (h *RatingHandler) UpdateRating(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, updated.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Rating updated successfully",
-		"movie_id": movieIDParam,
+		"movie_id": updated.MovieID.Hex(),
 		"rating":   req.Rating,
 		"stars":   h.getStarDisplay(req.Rating),
+		"version":  updated.Version,
 	})
 }
 
 func (h *RatingHandler) GetUserRatings(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Rating not found")
 		return
 	}
+	userID := principal.UserID
 
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ratings, err := h.ratingService.GetUserRatings(userID)
+	ratings, total, err := h.ratingService.GetUserRatingsPage(userID, pagination.Offset(), pagination.PerPage)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Format response with star display
-	var ratingsResponse []gin.H
+	ratingsResponse := []gin.H{}
 	for _, rating := range ratings {
 		ratingsResponse = append(ratingsResponse, gin.H{
 			"id":         rating.ID,
 			"movie_id":   rating.MovieID,
 			"rating":     rating.Rating,
 			"stars":      h.getStarDisplay(rating.Rating),
+			"version":    rating.Version,
 			"created_at": rating.CreatedAt,
 			"updated_at": rating.UpdatedAt,
+			"_links":     ratingItemLinks(apiBase(c), rating.MovieID),
 		})
 	}
 
+	respondList(c, ratingsResponse, pagination, total, nil)
+}
+
+// GetRating returns the user's rating for a single movie
+func (h *RatingHandler) GetRating(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Rating not found")
+		return
+	}
+	userID := principal.UserID
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("movieId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+		return
+	}
+
+	rating, err := h.ratingService.GetUserRating(userID, movieID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rating == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You haven't rated this movie yet"})
+		return
+	}
+	if err := h.policy.RequireOwner(principal, rating.UserID); err != nil {
+		respondAuthzError(c, err, "You haven't rated this movie yet")
+		return
+	}
+
+	setVersionETag(c, rating.Version)
 	c.JSON(http.StatusOK, gin.H{
-		"ratings": ratingsResponse,
-		"count":   len(ratingsResponse),
+		"id":         rating.ID,
+		"movie_id":   rating.MovieID,
+		"rating":     rating.Rating,
+		"stars":      h.getStarDisplay(rating.Rating),
+		"version":    rating.Version,
+		"created_at": rating.CreatedAt,
+		"updated_at": rating.UpdatedAt,
+		"_links":     ratingItemLinks(apiBase(c), rating.MovieID),
 	})
 }
 
+// ImportRatings imports ratings from a CSV of imdb_id, rating, date sent
+// either as the raw body or as a multipart "file" field. With dry_run=true it
+// only reports what would be created, updated or conflicted.
+func (h *RatingHandler) ImportRatings(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Rating not found")
+		return
+	}
+	userID := principal.UserID
+
+	var input io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required in the file field"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	overwrite := c.Query("overwrite") == "true"
+
+	report, err := h.importService.ImportCSV(userID, input, dryRun, overwrite)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRatingImport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import ratings"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // Helper function to convert rating to star display
 func (h *RatingHandler) getStarDisplay(rating int) string {
 	stars := ""
@@ -163,3 +285,17 @@ func (h *RatingHandler) getStarDisplay(rating int) string {
 	}
 	return stars
 }
+
+// respondMovieLookupError answers a failed lookup of a movie by IMDb ID:
+// 404 when no provider knows the ID, 502 when the providers could not be
+// reached or answered badly, 500 for anything else, such as the database
+func respondMovieLookupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMetadataUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Movie details are unavailable right now", "code": "METADATA_UNAVAILABLE"})
+	case errors.Is(err, services.ErrMovieNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found", "code": "MOVIE_NOT_FOUND"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up movie"})
+	}
+}
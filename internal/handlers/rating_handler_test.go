@@ -0,0 +1,110 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateUpdateAndGetRating(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	movie := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", token, gin.H{
+		"movie_id": movie.ID.Hex(),
+		"rating":   4,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", token, gin.H{
+		"movie_id": movie.ID.Hex(),
+		"rating":   5,
+	})
+	testutil.Decode(t, recorder, http.StatusConflict, nil)
+
+	recorder = testutil.Do(t, server.router, http.MethodPut, "/api/v1/ratings/"+movie.ID.Hex(), token, gin.H{"rating": 2})
+	testutil.Decode(t, recorder, http.StatusOK, nil)
+
+	var rating struct {
+		Rating int `json:"rating"`
+	}
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/ratings/"+movie.ID.Hex(), token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &rating)
+	if rating.Rating != 2 {
+		t.Errorf("rating = %d, want 2", rating.Rating)
+	}
+}
+
+func TestRateByIMDbIDFetchesFromOMDb(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+
+	var rated struct {
+		MovieID string `json:"movie_id"`
+	}
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", token, gin.H{
+		"imdb_id": "tt1375666",
+		"rating":  5,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, &rated)
+
+	requests := server.omdb.Requests()
+	if len(requests) != 1 || requests[0] != "i=tt1375666" {
+		t.Errorf("OMDb requests = %v, want [i=tt1375666]", requests)
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/ratings/"+rated.MovieID, token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, nil)
+}
+
+func TestRateUnknownIMDbIDIsNotFound(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", testutil.Token(t, user.ID), gin.H{
+		"imdb_id": "tt9999999999",
+		"rating":  3,
+	})
+	testutil.Decode(t, recorder, http.StatusNotFound, &body)
+	if body.Code != "MOVIE_NOT_FOUND" {
+		t.Errorf("code = %q, want MOVIE_NOT_FOUND", body.Code)
+	}
+}
+
+func TestRatingsArePrivate(t *testing.T) {
+	server := newTestServer(t)
+	alice := testutil.CreateUser(t, server.db, "alice")
+	bob := testutil.CreateUser(t, server.db, "bob")
+	movie := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", testutil.Token(t, alice.ID), gin.H{
+		"movie_id": movie.ID.Hex(),
+		"rating":   4,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/ratings/"+movie.ID.Hex(), testutil.Token(t, bob.ID), nil)
+	testutil.Decode(t, recorder, http.StatusNotFound, nil)
+}
+
+func TestRateRejectsOutOfRangeRating(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	movie := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", testutil.Token(t, user.ID), gin.H{
+		"movie_id": movie.ID.Hex(),
+		"rating":   6,
+	})
+	testutil.Decode(t, recorder, http.StatusBadRequest, nil)
+}
@@ -1,19 +1,37 @@
 package handlers
 
 import (
+	"errors"
+	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/services"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// maxRecommendations caps the recommendation set that is paginated over
+const maxRecommendations = 50
+
 type RecommendationHandler struct {
 	recommendationService *services.RecommendationService
+	userService           *services.UserService
+	scheduler             *services.RecommendationScheduler
+	analyticsService      *services.RecommendationAnalyticsService
+	followService         *services.FollowService
+	advisoryService       *services.AdvisoryService
 }
 
-func NewRecommendationHandler(recommendationService *services.RecommendationService) *RecommendationHandler {
-	return &RecommendationHandler{recommendationService: recommendationService}
+func NewRecommendationHandler(recommendationService *services.RecommendationService, userService *services.UserService, scheduler *services.RecommendationScheduler, analyticsService *services.RecommendationAnalyticsService, followService *services.FollowService, advisoryService *services.AdvisoryService) *RecommendationHandler {
+	return &RecommendationHandler{
+		recommendationService: recommendationService,
+		userService:           userService,
+		scheduler:             scheduler,
+		analyticsService:      analyticsService,
+		followService:         followService,
+		advisoryService:       advisoryService,
+	}
 }
 
 func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
@@ -29,33 +47,231 @@ func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
 		return
 	}
 
-	limit := 10 // Default limit
-	recommendations, err := h.recommendationService.GetRecommendations(userID, limit)
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recommendations, refreshedAt, err := loadRecommendations(c, h.scheduler, h.recommendationService, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if profile := currentProfile(c); profile != nil {
+		recommendations = services.FilterByCertification(recommendations, profile.MaxCertification)
+	}
+
+	prefs, err := h.userService.GetLanguagePreferences(accountUserID(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("audio_language") == "match" {
+		recommendations = services.FilterByAudioLanguage(recommendations, *prefs)
+	}
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recommendations = services.FilterByAdvisories(recommendations, avoid)
 
 	// Format response with additional metadata
-	var formattedRecommendations []gin.H
-	for _, movie := range recommendations {
+	start, end := paginateSlice(len(recommendations), pagination)
+	h.analyticsService.RecordImpressions(userID, models.RecommendationRowForYou, recommendations[start:end], start)
+	formattedRecommendations := []gin.H{}
+	for _, movie := range recommendations[start:end] {
 		formattedRecommendations = append(formattedRecommendations, gin.H{
-			"id":          movie.ID,
-			"title":       movie.Title,
-			"year":        movie.Year,
-			"genre":       movie.Genre,
-			"director":    movie.Director,
-			"poster":      movie.Poster,
-			"imdb_rating": movie.IMDbRating,
-			"imdb_id":     movie.IMDbID,
+			"id":                   movie.ID,
+			"title":                movie.Title,
+			"year":                 movie.Year,
+			"genre":                movie.Genre,
+			"director":             movie.Director,
+			"poster":               movie.Poster,
+			"imdb_rating":          movie.IMDbRating,
+			"imdb_id":              movie.IMDbID,
+			"language":             movie.Language,
+			"audio_language_match": services.AudioLanguageMatch(movie, *prefs),
+			"_links":               recommendationItemLinks(apiBase(c), movie.ID),
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"recommendations": formattedRecommendations,
-		"count":         len(formattedRecommendations),
-		"limit":         limit,
-		"algorithm":     "rule-based",
-		"criteria":      "Genres rated 4+ stars, excluding rated and watchlist movies",
+	meta := gin.H{
+		"algorithm": "rule-based",
+		"criteria":  "Genres rated 4+ stars, excluding rated and watchlist movies",
+	}
+	if refreshedAt != nil {
+		meta["refreshed_at"] = refreshedAt
+	}
+	respondList(c, formattedRecommendations, pagination, int64(len(recommendations)), meta)
+}
+
+// loadRecommendations returns the precomputed row of an account on a daily or
+// weekly schedule with its refresh time, and otherwise computes fresh
+// recommendations. Kids profiles always get fresh recommendations.
+func loadRecommendations(c *gin.Context, scheduler *services.RecommendationScheduler, recommendationService *services.RecommendationService, userID primitive.ObjectID) ([]models.Movie, *time.Time, error) {
+	if accountUserID(c, userID) == userID {
+		movies, refreshedAt, err := scheduler.ScheduledRecommendations(userID)
+		if err != nil || refreshedAt != nil {
+			return movies, refreshedAt, err
+		}
+	}
+	movies, err := recommendationService.GetRecommendations(userID, maxRecommendations)
+	return movies, nil, err
+}
+
+type UpdateRecommendationSettingsRequest struct {
+	Frequency   string   `json:"frequency" binding:"required" sanitize:"line,max=20"`
+	Count       int      `json:"count" binding:"required"`
+	Rows        []string `json:"rows" binding:"required" sanitize:"line,max=20"`
+	EmailDigest bool     `json:"email_digest"`
+}
+
+// GetRecommendationSettings returns how the user's recommendations refresh
+func (h *RecommendationHandler) GetRecommendationSettings(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	settings, err := h.scheduler.GetSettings(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateRecommendationSettings replaces when and how the user's
+// recommendations refresh, how many items each row holds and which rows the
+// scheduler and email digest include
+func (h *RecommendationHandler) UpdateRecommendationSettings(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UpdateRecommendationSettingsRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.scheduler.UpdateSettings(userID, models.RecommendationSettings{
+		Frequency:   req.Frequency,
+		Count:       req.Count,
+		Rows:        req.Rows,
+		EmailDigest: req.EmailDigest,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRecommendationSettings):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetTrendingMovies lists the movies with the most recent watchlist and rating activity.
+// It is available to guests.
+func (h *RecommendationHandler) GetTrendingMovies(c *gin.Context) {
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	movies, err := h.recommendationService.GetTrendingMovies(maxRecommendations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if profile := currentProfile(c); profile != nil {
+		movies = services.FilterByCertification(movies, profile.MaxCertification)
+	}
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	movies = services.FilterByAdvisories(movies, avoid)
+
+	start, end := paginateSlice(len(movies), pagination)
+	if userID := optionalUserID(c); userID != nil {
+		h.analyticsService.RecordImpressions(*userID, models.RecommendationRowTrending, movies[start:end], start)
+	}
+	respondList(c, movies[start:end], pagination, int64(len(movies)), gin.H{
+		"criteria": "Most added to watchlists and rated in the last 7 days",
+	})
+}
+
+// GetFollowingRecommendations lists recent movies from the directors and
+// actors the user follows that they have not rated or added to their
+// watchlist, newest first
+func (h *RecommendationHandler) GetFollowingRecommendations(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	movies, err := h.followService.GetNewFromFollowed(userID, maxRecommendations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if profile := currentProfile(c); profile != nil {
+		movies = services.FilterByCertification(movies, profile.MaxCertification)
+	}
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	movies = services.FilterByAdvisories(movies, avoid)
+
+	start, end := paginateSlice(len(movies), pagination)
+	h.analyticsService.RecordImpressions(userID, models.RecommendationRowFollowing, movies[start:end], start)
+	respondList(c, movies[start:end], pagination, int64(len(movies)), gin.H{
+		"criteria": "Movies from the last 3 years by directors and actors you follow",
 	})
 }
@@ -0,0 +1,76 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecommendationsFollowHighlyRatedGenres(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	inception := server.seedMovie(t, "tt1375666")
+	matrix := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/ratings", token, gin.H{
+		"movie_id": inception.ID.Hex(),
+		"rating":   5,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", token, gin.H{"movie_id": matrix.ID.Hex()})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+
+	var list listResponse
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/recommendations", token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &list)
+	if len(list.Data) == 0 {
+		t.Fatal("no recommendations after rating Inception 5 stars")
+	}
+
+	// Genre matches come before the top rated movies that fill the list
+	if genre, _ := list.Data[0]["genre"].(string); !sharesGenre(genre, inception.Genre) {
+		t.Errorf("first recommendation %v (%s) shares no genre with %s", list.Data[0]["title"], genre, inception.Genre)
+	}
+	for _, item := range list.Data {
+		switch item["id"] {
+		case inception.ID.Hex():
+			t.Error("recommended the rated movie")
+		case matrix.ID.Hex():
+			t.Error("recommended a movie on the watchlist")
+		}
+	}
+}
+
+func TestRecommendationsWithoutRatingsFallBackToTopRated(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	shawshank := server.seedMovie(t, "tt0111161")
+
+	var list listResponse
+	recorder := testutil.Do(t, server.router, http.MethodGet, "/api/v1/recommendations", testutil.Token(t, user.ID), nil)
+	testutil.Decode(t, recorder, http.StatusOK, &list)
+	if len(list.Data) == 0 {
+		t.Fatal("no recommendations for a user without ratings")
+	}
+	if list.Data[0]["id"] != shawshank.ID.Hex() {
+		t.Errorf("first recommendation = %v, want the top rated %s", list.Data[0]["title"], shawshank.Title)
+	}
+}
+
+// sharesGenre reports whether two comma-separated genre lists overlap
+func sharesGenre(a, b string) bool {
+	for _, x := range strings.Split(a, ",") {
+		for _, y := range strings.Split(b, ",") {
+			if strings.TrimSpace(x) == strings.TrimSpace(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
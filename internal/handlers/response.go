@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"movie-watchlist/internal/pagination"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	// maxPage keeps Offset and the next-link check far from int overflow
+	maxPage = 1000000
+)
+
+// Pagination holds the page parameters parsed from a list request
+type Pagination struct {
+	Page    int
+	PerPage int
+}
+
+// Offset returns the number of items to skip for the current page
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// parsePagination reads page and per_page query parameters, applying defaults
+func parsePagination(c *gin.Context) (Pagination, error) {
+	return parsePaginationWithDefault(c, defaultPerPage)
+}
+
+func parsePaginationWithDefault(c *gin.Context, perPageDefault int) (Pagination, error) {
+	p := Pagination{Page: 1, PerPage: perPageDefault}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 || page > maxPage {
+			return p, fmt.Errorf("page must be between 1 and %d", maxPage)
+		}
+		p.Page = page
+	}
+
+	if value := c.Query("per_page"); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return p, fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+		}
+		p.PerPage = perPage
+	}
+
+	return p, nil
+}
+
+// respondList writes the shared list envelope:
+//
+//	{"data": [...], "meta": {"page", "per_page", "total", ...}, "links": {"self", "next", "prev"}}
+//
+// extraMeta is merged into meta for endpoint-specific metadata.
+func respondList(c *gin.Context, data interface{}, p Pagination, total int64, extraMeta gin.H) {
+	meta := gin.H{
+		"page":     p.Page,
+		"per_page": p.PerPage,
+		"total":    total,
+	}
+	for key, value := range extraMeta {
+		meta[key] = value
+	}
+
+	links := gin.H{
+		"self": pageURL(c, p.Page, p.PerPage),
+		"next": nil,
+		"prev": nil,
+	}
+	if int64(p.Page)*int64(p.PerPage) < total {
+		links["next"] = pageURL(c, p.Page+1, p.PerPage)
+	}
+	if p.Page > 1 {
+		links["prev"] = pageURL(c, p.Page-1, p.PerPage)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  data,
+		"meta":  meta,
+		"links": links,
+	})
+}
+
+// respondCursorList writes the list envelope for keyset-paginated endpoints:
+//
+//	{"data": [...], "meta": {"limit", "next_cursor"}, "links": {"self", "next"}}
+//
+// next is the cursor of the following page, or nil on the last page.
+func respondCursorList(c *gin.Context, data interface{}, cursor pagination.Cursor, next *primitive.ObjectID) {
+	meta := gin.H{
+		"limit":       cursor.Limit,
+		"next_cursor": nil,
+	}
+	links := gin.H{
+		"self": c.Request.URL.RequestURI(),
+		"next": nil,
+	}
+	if next != nil {
+		meta["next_cursor"] = next.Hex()
+		links["next"] = cursorURL(c, next.Hex(), cursor.Limit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  data,
+		"meta":  meta,
+		"links": links,
+	})
+}
+
+// cursorURL returns the current request URL with after and limit replaced
+func cursorURL(c *gin.Context, after string, limit int) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("after", after)
+	query.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// pageURL returns the current request URL with page and per_page replaced
+func pageURL(c *gin.Context, page, perPage int) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// paginateSlice returns the bounds of the current page within a slice of length n
+func paginateSlice(n int, p Pagination) (int, int) {
+	start := p.Offset()
+	if start > n {
+		start = n
+	}
+	end := start + p.PerPage
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// optionalUserID returns the signed-in user's ID on routes that also serve
+// guests, or nil when the request is anonymous
+func optionalUserID(c *gin.Context) *primitive.ObjectID {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		return nil
+	}
+	return &userID
+}
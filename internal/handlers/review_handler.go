@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+	movieService  *services.MovieService
+	policy        *authz.Policy
+}
+
+func NewReviewHandler(reviewService *services.ReviewService, movieService *services.MovieService, policy *authz.Policy) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		movieService:  movieService,
+		policy:        policy,
+	}
+}
+
+type WriteReviewRequest struct {
+	Body string `json:"body" binding:"required" sanitize:"text,max=5000"`
+}
+
+// WriteReview stores the user's review of a movie, replacing their earlier
+// one. Helpful votes on it are kept.
+func (h *ReviewHandler) WriteReview(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Review not found")
+		return
+	}
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	var req WriteReviewRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.Write(principal.UserID, movieID, req.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidReview):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrReviewMovieNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// GetMovieReviews lists a movie's reviews (?sort=helpful|newest, default
+// helpful). Signed-in readers see which reviews they voted helpful.
+func (h *ReviewHandler) GetMovieReviews(c *gin.Context) {
+	movieID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID"})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", services.ReviewSortHelpful)
+	if !services.ValidReviewSort(sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be helpful or newest"})
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkCertification(c, h.movieService, movieID) {
+		return
+	}
+
+	reviews, total, err := h.reviewService.ListForMovie(movieID, sort, optionalUserID(c), pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondList(c, reviews, pagination, total, gin.H{"sort": sort})
+}
+
+// DeleteReview deletes one of the user's reviews with its votes
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Review not found")
+		return
+	}
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	review, err := h.reviewService.Get(reviewID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if review == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if err := h.policy.RequireOwner(principal, review.UserID); err != nil {
+		respondAuthzError(c, err, "Review not found")
+		return
+	}
+
+	if err := h.reviewService.Delete(reviewID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+// VoteHelpful marks another user's review helpful. Voting twice counts once.
+func (h *ReviewHandler) VoteHelpful(c *gin.Context) {
+	h.setVote(c, true)
+}
+
+// UnvoteHelpful withdraws the user's helpful vote on a review
+func (h *ReviewHandler) UnvoteHelpful(c *gin.Context) {
+	h.setVote(c, false)
+}
+
+func (h *ReviewHandler) setVote(c *gin.Context, helpful bool) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Review not found")
+		return
+	}
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	vote := h.reviewService.Unvote
+	if helpful {
+		vote = h.reviewService.Vote
+	}
+	review, err := vote(principal.UserID, reviewID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrReviewNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		case errors.Is(err, services.ErrVoteOwnReview):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "OWN_REVIEW"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// GetTopReviewers lists the reviewers whose reviews got the most helpful votes
+func (h *ReviewHandler) GetTopReviewers(c *gin.Context) {
+	standings, err := h.reviewService.TopReviewers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviewers": standings})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SecurityHandler struct {
+	loginSecurityService *services.LoginSecurityService
+}
+
+func NewSecurityHandler(loginSecurityService *services.LoginSecurityService) *SecurityHandler {
+	return &SecurityHandler{loginSecurityService: loginSecurityService}
+}
+
+// GetLogins lists the account's recent login attempts
+func (h *SecurityHandler) GetLogins(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	logins, err := h.loginSecurityService.GetRecentLogins(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logins": logins})
+}
+
+// RevokeSessions is the target of the link in a new-location alert and logs
+// the account out everywhere
+func (h *SecurityHandler) RevokeSessions(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revoke token is required"})
+		return
+	}
+
+	revoked, err := h.loginSecurityService.RevokeSessions(token)
+	if err != nil {
+		if err.Error() == "invalid or expired revoke link" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All sessions revoked. Change your password to keep your account safe.",
+		"revoked": revoked,
+	})
+}
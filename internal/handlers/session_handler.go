@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	policy         *authz.Policy
+}
+
+func NewSessionHandler(sessionService *services.SessionService, policy *authz.Policy) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService, policy: policy}
+}
+
+// GetSessions lists the devices the user is logged in on
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	sessions, err := h.sessionService.GetActiveSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentID, _ := c.Get("session_id")
+	formattedSessions := []gin.H{}
+	for _, session := range sessions {
+		formattedSessions = append(formattedSessions, gin.H{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      currentID == session.ID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": formattedSessions})
+}
+
+// RevokeSession logs out a single device
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "session not found")
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	session, err := h.sessionService.GetSession(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err := h.policy.RequireAccountOwner(principal, session.UserID); err != nil {
+		respondAuthzError(c, err, "session not found")
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(principal.AccountID, sessionID); err != nil {
+		if err.Error() == "session not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeAllSessions logs the user out everywhere, including the current device
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeAllSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out everywhere", "revoked": revoked})
+}
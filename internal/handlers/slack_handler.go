@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SlackHandler struct {
+	slackService *services.SlackCommandService
+}
+
+func NewSlackHandler(slackService *services.SlackCommandService) *SlackHandler {
+	return &SlackHandler{slackService: slackService}
+}
+
+// GetSlackLink returns the Slack user linked to the account
+func (h *SlackHandler) GetSlackLink(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.slackService.Status(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CreateSlackLinkCode returns a one-time code to type into the slash
+// command to link a Slack user
+func (h *SlackHandler) CreateSlackLinkCode(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	code, expiresAt, err := h.slackService.CreateLinkCode(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": code, "expires_at": expiresAt})
+}
+
+// UnlinkSlack removes the linked Slack user
+func (h *SlackHandler) UnlinkSlack(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.slackService.Unlink(userID); err != nil {
+		if errors.Is(err, services.ErrSlackNotLinked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "SLACK_NOT_LINKED"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Slack user unlinked"})
+}
+
+// SlackCommand answers the slash command. It needs no token: Slack signs
+// each request, and the command acts as the account the Slack user linked.
+func (h *SlackHandler) SlackCommand(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if err := h.slackService.Verify(c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body, time.Now()); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form body"})
+		return
+	}
+
+	reply := h.slackService.Run(c.Request.Context(), form.Get("command"), form.Get("team_id"), form.Get("user_id"), form.Get("text"))
+	c.JSON(http.StatusOK, gin.H{"response_type": "ephemeral", "text": reply})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelegramHandler struct {
+	botService *services.TelegramBotService
+}
+
+func NewTelegramHandler(botService *services.TelegramBotService) *TelegramHandler {
+	return &TelegramHandler{botService: botService}
+}
+
+type UpdateTelegramRequest struct {
+	Notify *bool `json:"notify" binding:"required"`
+}
+
+// GetTelegramLink returns the Telegram chat linked to the account
+func (h *TelegramHandler) GetTelegramLink(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.botService.Status(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CreateTelegramLinkCode returns a one-time code to send to the bot to link
+// a Telegram chat
+func (h *TelegramHandler) CreateTelegramLinkCode(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	code, linkURL, expiresAt, err := h.botService.CreateLinkCode(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"code": code, "expires_at": expiresAt}
+	if linkURL != "" {
+		response["link_url"] = linkURL
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateTelegramLink sets whether notifications are sent to the chat
+func (h *TelegramHandler) UpdateTelegramLink(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateTelegramRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.botService.SetNotify(userID, *req.Notify); err != nil {
+		respondTelegramError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notify": *req.Notify})
+}
+
+// UnlinkTelegram removes the linked Telegram chat
+func (h *TelegramHandler) UnlinkTelegram(c *gin.Context) {
+	userID, ok := chatUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.botService.Unlink(userID); err != nil {
+		respondTelegramError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram chat unlinked"})
+}
+
+// TelegramWebhook receives updates in webhook mode. It needs no token:
+// Telegram sends the secret registered with the webhook instead. Updates
+// are always acknowledged, since Telegram would otherwise keep resending
+// them.
+func (h *TelegramHandler) TelegramWebhook(c *gin.Context) {
+	if !h.botService.VerifyWebhook(c.GetHeader("X-Telegram-Bot-Api-Secret-Token")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var update services.TelegramUpdate
+	if err := json.NewDecoder(c.Request.Body).Decode(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update"})
+		return
+	}
+
+	h.botService.HandleUpdate(c.Request.Context(), update)
+	c.Status(http.StatusOK)
+}
+
+func respondTelegramError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrTelegramNotLinked) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "TELEGRAM_NOT_LINKED"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
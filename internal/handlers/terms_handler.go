@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TermsHandler struct {
+	termsService *services.TermsService
+}
+
+func NewTermsHandler(termsService *services.TermsService) *TermsHandler {
+	return &TermsHandler{termsService: termsService}
+}
+
+type AcceptTermsRequest struct {
+	Version string `json:"version" binding:"required" sanitize:"line,max=64"`
+}
+
+// AcceptTerms records that the user accepted the current terms version
+func (h *TermsHandler) AcceptTerms(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req AcceptTermsRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	acceptedAt, err := h.termsService.Accept(userID, req.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoTerms):
+			c.JSON(http.StatusNotFound, gin.H{"error": "There are no terms to accept"})
+		case errors.Is(err, services.ErrTermsNotAccepted):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Only the current terms version can be accepted",
+				"code":          "TERMS_VERSION_MISMATCH",
+				"terms_version": h.termsService.Version(),
+			})
+		case err.Error() == "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"terms_version": req.Version,
+		"accepted_at":   acceptedAt,
+	})
+}
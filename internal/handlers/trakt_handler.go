@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TraktHandler struct {
+	traktService *services.TraktService
+}
+
+func NewTraktHandler(traktService *services.TraktService) *TraktHandler {
+	return &TraktHandler{traktService: traktService}
+}
+
+type UpdateTraktRequest struct {
+	Push       *bool   `json:"push"`
+	OnConflict *string `json:"on_conflict" sanitize:"line,max=20"`
+}
+
+// GetTrakt returns the user's Trakt link with the state of the last sync
+func (h *TraktHandler) GetTrakt(c *gin.Context) {
+	userID, ok := traktUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.traktService.Status(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// AuthorizeTrakt starts linking a Trakt account and returns the URL of
+// Trakt's consent screen
+func (h *TraktHandler) AuthorizeTrakt(c *gin.Context) {
+	userID, ok := traktUserID(c)
+	if !ok {
+		return
+	}
+
+	authorizeURL, err := h.traktService.Authorize(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorize_url": authorizeURL})
+}
+
+// TraktCallback is where Trakt sends the user back after the consent
+// screen. It needs no token: the state ties it to the user who started.
+func (h *TraktHandler) TraktCallback(c *gin.Context) {
+	if c.Query("error") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trakt authorization was denied"})
+		return
+	}
+
+	link, err := h.traktService.CompleteAuthorization(c.Request.Context(), c.Query("code"), c.Query("state"))
+	if err != nil {
+		respondTraktError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Trakt account linked",
+		"username": link.Username,
+	})
+}
+
+// UpdateTrakt changes whether changes are pushed to Trakt and how imports
+// resolve conflicts
+func (h *TraktHandler) UpdateTrakt(c *gin.Context) {
+	userID, ok := traktUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateTraktRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.traktService.UpdateSettings(userID, req.Push, req.OnConflict)
+	if err != nil {
+		respondTraktError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SyncTrakt queues an import from the linked Trakt account
+func (h *TraktHandler) SyncTrakt(c *gin.Context) {
+	userID, ok := traktUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.traktService.RequestSync(userID)
+	if err != nil {
+		respondTraktError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// UnlinkTrakt removes the linked Trakt account
+func (h *TraktHandler) UnlinkTrakt(c *gin.Context) {
+	userID, ok := traktUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.traktService.Unlink(c.Request.Context(), userID); err != nil {
+		respondTraktError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trakt account unlinked"})
+}
+
+func traktUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func respondTraktError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTraktSettings), errors.Is(err, services.ErrInvalidTraktState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTraktNotLinked):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "TRAKT_NOT_LINKED"})
+	case errors.Is(err, services.ErrTraktSyncInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "TRAKT_SYNC_IN_PROGRESS"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UndoHandler struct {
+	undoService *services.UndoService
+}
+
+func NewUndoHandler(undoService *services.UndoService) *UndoHandler {
+	return &UndoHandler{undoService: undoService}
+}
+
+type UndoRequest struct {
+	UndoToken string `json:"undo_token" binding:"required" sanitize:"line,max=64"`
+}
+
+// Undo redeems an undo token from a destructive response, restoring what was removed
+func (h *UndoHandler) Undo(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req UndoRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kind, restored, err := h.undoService.Undo(userID, req.UndoToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUndoExpired):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Undo token is invalid or has expired"})
+		case errors.Is(err, services.ErrUndoConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "The item was added again since it was removed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Action undone",
+		"kind":    kind,
+		"item":    restored,
+	})
+}
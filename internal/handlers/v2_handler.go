@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// V2Handler serves /api/v2. v2 differs from v1 in that every response uses
+// the data envelope, errors carry a code, IMDb ratings are numeric, genres are
+// arrays and watchlist and rating items embed their movie. Writes are in
+// v2_write_handler.go.
+type V2Handler struct {
+	movieService          *services.MovieService
+	watchlistService      *services.WatchlistService
+	ratingService         *services.RatingService
+	recommendationService *services.RecommendationService
+	recentViewService     *services.RecentViewService
+	userService           *services.UserService
+	scheduler             *services.RecommendationScheduler
+	analyticsService      *services.RecommendationAnalyticsService
+	advisoryService       *services.AdvisoryService
+	undoService           *services.UndoService
+	progressService       *services.ProgressService
+	policy                *authz.Policy
+}
+
+func NewV2Handler(movieService *services.MovieService, watchlistService *services.WatchlistService, ratingService *services.RatingService, recommendationService *services.RecommendationService, recentViewService *services.RecentViewService, userService *services.UserService, scheduler *services.RecommendationScheduler, analyticsService *services.RecommendationAnalyticsService, advisoryService *services.AdvisoryService, undoService *services.UndoService, progressService *services.ProgressService, policy *authz.Policy) *V2Handler {
+	return &V2Handler{
+		movieService:          movieService,
+		watchlistService:      watchlistService,
+		ratingService:         ratingService,
+		recommendationService: recommendationService,
+		recentViewService:     recentViewService,
+		userService:           userService,
+		scheduler:             scheduler,
+		analyticsService:      analyticsService,
+		advisoryService:       advisoryService,
+		undoService:           undoService,
+		progressService:       progressService,
+		policy:                policy,
+	}
+}
+
+func (h *V2Handler) SearchMovies(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_QUERY", "Search query is required")
+		return
+	}
+
+	pagination, err := parsePaginationWithDefault(c, services.SearchPageSize)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+	pagination.PerPage = services.SearchPageSize
+
+	// The profile's cap is applied before paging, as in v1
+	var result *services.SearchResult
+	if profile := currentProfile(c); profile != nil {
+		result, err = h.movieService.SearchMoviesUpTo(query, pagination.Page, profile.MaxCertification)
+	} else {
+		result, err = h.movieService.SearchMovies(c.Request.Context(), query, pagination.Page, optionalUserID(c))
+	}
+	if err != nil {
+		respondErrorV2(c, http.StatusBadGateway, "SEARCH_FAILED", err.Error())
+		return
+	}
+
+	items := make([]SearchResultV2, 0, len(result.Movies))
+	for _, movie := range result.Movies {
+		items = append(items, presentSearchResultV2(movie))
+	}
+
+	meta := gin.H{"cache_only": result.CacheOnly}
+	if len(result.DidYouMean) > 0 {
+		meta["did_you_mean"] = result.DidYouMean
+	}
+
+	respondList(c, items, pagination, result.Total, meta)
+}
+
+func (h *V2Handler) GetMovie(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_MOVIE_ID", "Invalid movie ID")
+		return
+	}
+
+	movie, err := h.movieService.GetMovieByID(id)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	if movie == nil {
+		respondErrorV2(c, http.StatusNotFound, "MOVIE_NOT_FOUND", "Movie not found")
+		return
+	}
+	if !movieAllowed(c, movie) {
+		respondErrorV2(c, http.StatusForbidden, "CERTIFICATION_RESTRICTED", "This movie is not available on this profile")
+		return
+	}
+
+	if userID := optionalUserID(c); userID != nil {
+		h.recentViewService.RecordView(*userID, movie.ID)
+	}
+
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	item := presentMovieV2(*movie)
+	if avoid != nil {
+		item.Warnings = services.AdvisoryWarnings(*movie, avoid)
+	}
+	respondData(c, http.StatusOK, item)
+}
+
+func (h *V2Handler) GetMovieByIMDbID(c *gin.Context) {
+	imdbID := c.Query("imdb_id")
+	if imdbID == "" {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_IMDB_ID", "IMDb ID is required")
+		return
+	}
+
+	movie, err := h.movieService.GetOrCreateByIMDbID(imdbID)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadGateway, "LOOKUP_FAILED", err.Error())
+		return
+	}
+	if !movieAllowed(c, movie) {
+		respondErrorV2(c, http.StatusForbidden, "CERTIFICATION_RESTRICTED", "This movie is not available on this profile")
+		return
+	}
+
+	if userID := optionalUserID(c); userID != nil {
+		h.recentViewService.RecordView(*userID, movie.ID)
+	}
+
+	respondData(c, http.StatusOK, presentMovieV2(*movie))
+}
+
+func (h *V2Handler) GetWatchlist(c *gin.Context) {
+	userID, ok := currentUserIDV2(c)
+	if !ok {
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+
+	watchlist, total, err := h.watchlistService.GetUserWatchlistPage(userID, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	movieIDs := make([]primitive.ObjectID, 0, len(watchlist))
+	for _, item := range watchlist {
+		movieIDs = append(movieIDs, item.MovieID)
+	}
+	movies, err := h.movieService.GetMoviesByIDs(movieIDs)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	items := make([]WatchlistItemV2, 0, len(watchlist))
+	for _, item := range watchlist {
+		items = append(items, presentWatchlistItemV2(c, item, embeddedMovie(movies, item.MovieID)))
+	}
+
+	respondList(c, items, pagination, total, nil)
+}
+
+func (h *V2Handler) GetRatings(c *gin.Context) {
+	userID, ok := currentUserIDV2(c)
+	if !ok {
+		return
+	}
+
+	pagination, err := parsePagination(c)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+
+	ratings, total, err := h.ratingService.GetUserRatingsPage(userID, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	movieIDs := make([]primitive.ObjectID, 0, len(ratings))
+	for _, rating := range ratings {
+		movieIDs = append(movieIDs, rating.MovieID)
+	}
+	movies, err := h.movieService.GetMoviesByIDs(movieIDs)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	items := make([]RatingItemV2, 0, len(ratings))
+	for _, rating := range ratings {
+		items = append(items, presentRatingItemV2(c, rating, embeddedMovie(movies, rating.MovieID)))
+	}
+
+	respondList(c, items, pagination, total, nil)
+}
+
+func (h *V2Handler) GetRecommendations(c *gin.Context) {
+	userID, ok := currentUserIDV2(c)
+	if !ok {
+		return
+	}
+
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+
+	recommendations, refreshedAt, err := loadRecommendations(c, h.scheduler, h.recommendationService, userID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	if profile := currentProfile(c); profile != nil {
+		recommendations = services.FilterByCertification(recommendations, profile.MaxCertification)
+	}
+
+	prefs, err := h.userService.GetLanguagePreferences(accountUserID(c, userID))
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	if c.Query("audio_language") == "match" {
+		recommendations = services.FilterByAudioLanguage(recommendations, *prefs)
+	}
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	recommendations = services.FilterByAdvisories(recommendations, avoid)
+
+	start, end := paginateSlice(len(recommendations), pagination)
+	h.analyticsService.RecordImpressions(userID, models.RecommendationRowForYou, recommendations[start:end], start)
+	items := make([]MovieV2, 0, end-start)
+	for _, movie := range recommendations[start:end] {
+		item := presentMovieV2(movie)
+		item.AudioLanguageMatch = services.AudioLanguageMatch(movie, *prefs)
+		item.Links = recommendationItemLinks(apiBase(c), movie.ID)
+		items = append(items, item)
+	}
+
+	meta := gin.H{"algorithm": "rule-based"}
+	if refreshedAt != nil {
+		meta["refreshed_at"] = refreshedAt
+	}
+	respondList(c, items, pagination, int64(len(recommendations)), meta)
+}
+
+func (h *V2Handler) GetTrendingMovies(c *gin.Context) {
+	pagination, err := parsePaginationWithDefault(c, 10)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+
+	movies, err := h.recommendationService.GetTrendingMovies(maxRecommendations)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	if profile := currentProfile(c); profile != nil {
+		movies = services.FilterByCertification(movies, profile.MaxCertification)
+	}
+	avoid, err := avoidedAdvisories(c, h.advisoryService)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	movies = services.FilterByAdvisories(movies, avoid)
+
+	start, end := paginateSlice(len(movies), pagination)
+	if userID := optionalUserID(c); userID != nil {
+		h.analyticsService.RecordImpressions(*userID, models.RecommendationRowTrending, movies[start:end], start)
+	}
+	items := make([]MovieV2, 0, end-start)
+	for _, movie := range movies[start:end] {
+		items = append(items, presentMovieV2(movie))
+	}
+
+	respondList(c, items, pagination, int64(len(movies)), nil)
+}
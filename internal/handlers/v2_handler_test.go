@@ -0,0 +1,157 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponseV2 is the v2 error envelope
+type errorResponseV2 struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func TestV2WatchlistWritesReturnItems(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	movie := server.seedMovie(t, "tt0133093")
+
+	var added struct {
+		Data struct {
+			Priority int `json:"priority"`
+			Version  int `json:"version"`
+			Movie    *struct {
+				ID     string   `json:"id"`
+				Genres []string `json:"genres"`
+			} `json:"movie"`
+		} `json:"data"`
+	}
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v2/watchlist", token, gin.H{"movie_id": movie.ID.Hex(), "priority": 4})
+	testutil.Decode(t, recorder, http.StatusCreated, &added)
+	if added.Data.Movie == nil || added.Data.Movie.ID != movie.ID.Hex() {
+		t.Fatalf("added item embeds %+v, want movie %s", added.Data.Movie, movie.ID.Hex())
+	}
+	if len(added.Data.Movie.Genres) == 0 || added.Data.Priority != 4 {
+		t.Errorf("added item = %+v, want genres and priority 4", added.Data)
+	}
+
+	var updated struct {
+		Data struct {
+			Priority int `json:"priority"`
+		} `json:"data"`
+	}
+	recorder = testutil.Do(t, server.router, http.MethodPatch, "/api/v2/watchlist/"+movie.ID.Hex(), token, gin.H{"priority": 2})
+	testutil.Decode(t, recorder, http.StatusOK, &updated)
+	if updated.Data.Priority != 2 {
+		t.Errorf("priority = %d after update, want 2", updated.Data.Priority)
+	}
+
+	var removed struct {
+		Data struct {
+			UndoToken string `json:"undo_token"`
+		} `json:"data"`
+	}
+	recorder = testutil.Do(t, server.router, http.MethodDelete, "/api/v2/watchlist/"+movie.ID.Hex(), token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &removed)
+	if removed.Data.UndoToken == "" {
+		t.Fatal("removal returned no undo_token")
+	}
+
+	var undone struct {
+		Data struct {
+			Kind string `json:"kind"`
+			Item struct {
+				Movie *struct {
+					ID string `json:"id"`
+				} `json:"movie"`
+			} `json:"item"`
+		} `json:"data"`
+	}
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/api/v2/undo", token, gin.H{"undo_token": removed.Data.UndoToken})
+	testutil.Decode(t, recorder, http.StatusOK, &undone)
+	if undone.Data.Item.Movie == nil || undone.Data.Item.Movie.ID != movie.ID.Hex() {
+		t.Errorf("undo restored %+v, want the watchlist entry of %s", undone.Data, movie.ID.Hex())
+	}
+}
+
+func TestV2WriteErrorsCarryCodes(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	movie := server.seedMovie(t, "tt0133093")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"invalid movie ID", http.MethodPost, "/api/v2/watchlist", gin.H{"movie_id": "not-an-id"}, http.StatusBadRequest, "INVALID_MOVIE_ID"},
+		{"not on watchlist", http.MethodPatch, "/api/v2/watchlist/" + movie.ID.Hex(), gin.H{"priority": 2}, http.StatusNotFound, "NOT_IN_WATCHLIST"},
+		{"rating out of range", http.MethodPost, "/api/v2/ratings", gin.H{"movie_id": movie.ID.Hex(), "rating": 6}, http.StatusBadRequest, "INVALID_BODY"},
+		{"not rated yet", http.MethodPut, "/api/v2/ratings/" + movie.ID.Hex(), gin.H{"rating": 3}, http.StatusNotFound, "RATING_NOT_FOUND"},
+		{"unknown IMDb ID", http.MethodPost, "/api/v2/ratings", gin.H{"imdb_id": "tt9999999999", "rating": 3}, http.StatusNotFound, "MOVIE_NOT_FOUND"},
+		{"expired undo token", http.MethodPost, "/api/v2/undo", gin.H{"undo_token": "no-such-token"}, http.StatusNotFound, "UNDO_EXPIRED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body errorResponseV2
+			recorder := testutil.Do(t, server.router, tt.method, tt.path, token, tt.body)
+			testutil.Decode(t, recorder, tt.status, &body)
+			if body.Error.Code != tt.code || body.Error.Message == "" {
+				t.Errorf("error = %+v, want code %s with a message", body.Error, tt.code)
+			}
+		})
+	}
+}
+
+func TestV2RateAndUpdateReturnRating(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	movie := server.seedMovie(t, "tt1375666")
+
+	var rated struct {
+		Data struct {
+			Rating  int `json:"rating"`
+			Version int `json:"version"`
+			Movie   *struct {
+				IMDbRating *float64 `json:"imdb_rating"`
+			} `json:"movie"`
+		} `json:"data"`
+	}
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v2/ratings", token, gin.H{"movie_id": movie.ID.Hex(), "rating": 4})
+	testutil.Decode(t, recorder, http.StatusCreated, &rated)
+	if rated.Data.Rating != 4 || rated.Data.Movie == nil || rated.Data.Movie.IMDbRating == nil {
+		t.Fatalf("rated item = %+v, want rating 4 with a numeric imdb_rating", rated.Data)
+	}
+
+	var body errorResponseV2
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/api/v2/ratings", token, gin.H{"movie_id": movie.ID.Hex(), "rating": 5})
+	testutil.Decode(t, recorder, http.StatusConflict, &body)
+	if body.Error.Code != "ALREADY_RATED" {
+		t.Errorf("code = %q, want ALREADY_RATED", body.Error.Code)
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodPut, "/api/v2/ratings/"+movie.ID.Hex(), token, gin.H{"rating": 2, "version": rated.Data.Version + 1})
+	testutil.Decode(t, recorder, http.StatusPreconditionFailed, &body)
+	if body.Error.Code != "VERSION_CONFLICT" {
+		t.Errorf("code = %q, want VERSION_CONFLICT", body.Error.Code)
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodPut, "/api/v2/ratings/"+movie.ID.Hex(), token, gin.H{"rating": 2, "version": rated.Data.Version})
+	testutil.Decode(t, recorder, http.StatusOK, &rated)
+	if rated.Data.Rating != 2 {
+		t.Errorf("rating = %d after update, want 2", rated.Data.Rating)
+	}
+}
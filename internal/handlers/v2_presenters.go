@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MovieV2 is the v2 movie representation with typed rating and genre fields
+type MovieV2 struct {
+	ID         primitive.ObjectID `json:"id"`
+	IMDbID     string             `json:"imdb_id"`
+	Title      string             `json:"title"`
+	Year       string             `json:"year"`
+	Genres     []string           `json:"genres"`
+	Director   string             `json:"director"`
+	Plot       string             `json:"plot"`
+	Poster     string             `json:"poster"`
+	Runtime    string             `json:"runtime"`
+	IMDbRating *float64           `json:"imdb_rating"`
+	Languages  []string           `json:"languages"`
+	Keywords   []string           `json:"keywords"`
+	Advisories []string           `json:"advisories"`
+	// Warnings are the advisories the signed-in user avoids; only set on
+	// single-movie responses
+	Warnings []string `json:"warnings,omitempty"`
+	// Freshness is only set on single-movie responses
+	Freshness *models.MetadataFreshness `json:"freshness,omitempty"`
+	// AudioLanguageMatch is only set on recommendations for users with
+	// preferred audio languages
+	AudioLanguageMatch *bool `json:"audio_language_match,omitempty"`
+	Links              Links `json:"_links,omitempty"`
+}
+
+// SearchResultV2 is a v2 search hit; OMDb search results carry no database ID
+type SearchResultV2 struct {
+	IMDbID     string   `json:"imdb_id"`
+	Title      string   `json:"title"`
+	Year       string   `json:"year"`
+	Genres     []string `json:"genres"`
+	Poster     string   `json:"poster"`
+	IMDbRating *float64 `json:"imdb_rating"`
+	Source     string   `json:"source"`
+}
+
+type WatchlistItemV2 struct {
+	ID        primitive.ObjectID `json:"id"`
+	AddedAt   time.Time          `json:"added_at"`
+	WatchedAt *time.Time         `json:"watched_at"`
+	Priority  int                `json:"priority"`
+	Version   int                `json:"version"`
+	Movie     *MovieV2           `json:"movie"`
+	Links     Links              `json:"_links"`
+}
+
+type RatingItemV2 struct {
+	ID        primitive.ObjectID `json:"id"`
+	Rating    int                `json:"rating"`
+	Version   int                `json:"version"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Movie     *MovieV2           `json:"movie"`
+	Links     Links              `json:"_links"`
+}
+
+func presentMovieV2(movie models.Movie) MovieV2 {
+	return MovieV2{
+		ID:         movie.ID,
+		IMDbID:     movie.IMDbID,
+		Title:      movie.Title,
+		Year:       movie.Year,
+		Genres:     splitGenres(movie.Genre),
+		Director:   movie.Director,
+		Plot:       movie.Plot,
+		Poster:     movie.Poster,
+		Runtime:    movie.Runtime,
+		IMDbRating: parseIMDbRating(movie.IMDbRating),
+		Languages:  splitGenres(movie.Language),
+		Keywords:   keywordsOrEmpty(movie.Keywords),
+		Advisories: services.MovieAdvisories(movie),
+		Freshness:  movie.Freshness,
+	}
+}
+
+// presentWatchlistItemV2 presents a watchlist entry with its movie embedded
+func presentWatchlistItemV2(c *gin.Context, item models.Watchlist, movie *MovieV2) WatchlistItemV2 {
+	return WatchlistItemV2{
+		ID:        item.ID,
+		AddedAt:   item.AddedAt,
+		WatchedAt: item.WatchedAt,
+		Priority:  item.EffectivePriority(),
+		Version:   item.Version,
+		Movie:     movie,
+		Links:     watchlistItemLinks(apiBase(c), item.MovieID),
+	}
+}
+
+// presentRatingItemV2 presents a rating with its movie embedded
+func presentRatingItemV2(c *gin.Context, rating models.Rating, movie *MovieV2) RatingItemV2 {
+	return RatingItemV2{
+		ID:        rating.ID,
+		Rating:    rating.Rating,
+		Version:   rating.Version,
+		CreatedAt: rating.CreatedAt,
+		UpdatedAt: rating.UpdatedAt,
+		Movie:     movie,
+		Links:     ratingItemLinks(apiBase(c), rating.MovieID),
+	}
+}
+
+// keywordsOrEmpty keeps v2 keywords an array for movies not tagged yet
+func keywordsOrEmpty(keywords []string) []string {
+	if keywords == nil {
+		return []string{}
+	}
+	return keywords
+}
+
+func presentSearchResultV2(result services.OMDbResponse) SearchResultV2 {
+	return SearchResultV2{
+		IMDbID:     result.IMDbID,
+		Title:      result.Title,
+		Year:       result.Year,
+		Genres:     splitGenres(result.Genre),
+		Poster:     result.Poster,
+		IMDbRating: parseIMDbRating(result.IMDbRating),
+		Source:     result.Source,
+	}
+}
+
+// embeddedMovie looks up a movie for embedding, returning nil when it is missing
+func embeddedMovie(movies map[primitive.ObjectID]models.Movie, id primitive.ObjectID) *MovieV2 {
+	movie, ok := movies[id]
+	if !ok {
+		return nil
+	}
+	presented := presentMovieV2(movie)
+	return &presented
+}
+
+// splitGenres turns OMDb's comma-separated genre (or language) string into a list
+func splitGenres(genre string) []string {
+	genres := []string{}
+	for _, part := range strings.Split(genre, ",") {
+		if part = strings.TrimSpace(part); part != "" && part != "N/A" {
+			genres = append(genres, part)
+		}
+	}
+	return genres
+}
+
+// parseIMDbRating converts OMDb's rating string to a number, or nil for "N/A"
+func parseIMDbRating(rating string) *float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(rating), 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// respondData writes a single-resource v2 envelope
+func respondData(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, gin.H{"data": data})
+}
+
+// respondErrorV2 writes a v2 error envelope with a machine-readable code
+func respondErrorV2(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// currentUserIDV2 reads the authenticated user ID, writing a v2 error when absent
+func currentUserIDV2(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		respondErrorV2(c, http.StatusUnauthorized, "UNAUTHENTICATED", "User not authenticated")
+		return primitive.NilObjectID, false
+	}
+
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", "Invalid user ID format")
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The v2 watchlist, rating, progress and undo endpoints. They take the same
+// requests as v1 and answer with v2 items and coded errors.
+
+func (h *V2Handler) AddToWatchlist(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	var req AddToWatchlistRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	var movieID primitive.ObjectID
+	switch {
+	case req.MovieID != "":
+		parsed, err := primitive.ObjectIDFromHex(req.MovieID)
+		if err != nil {
+			respondErrorV2(c, http.StatusBadRequest, "INVALID_MOVIE_ID", "Invalid movie ID")
+			return
+		}
+		movieID = parsed
+	case req.Movie != nil:
+		movie, err := h.movieService.UpsertFromSearchResult(*req.Movie)
+		if err != nil {
+			respondErrorV2(c, http.StatusBadRequest, "INVALID_MOVIE", err.Error())
+			return
+		}
+		movieID = movie.ID
+	default:
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", "Either movie_id or movie is required")
+		return
+	}
+
+	if !h.checkCertification(c, movieID) {
+		return
+	}
+
+	var source *models.WatchlistSource
+	if req.Source != nil {
+		source = &models.WatchlistSource{Type: req.Source.Type, Detail: req.Source.Detail}
+	}
+
+	entry, err := h.watchlistService.AddToWatchlist(principal.UserID, movieID, req.Priority, source)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWatchlistSource):
+			respondErrorV2(c, http.StatusBadRequest, "INVALID_SOURCE", err.Error())
+		case errors.Is(err, services.ErrQuotaExceeded):
+			respondErrorV2(c, http.StatusUnprocessableEntity, "QUOTA_EXCEEDED", err.Error())
+		case err.Error() == "movie already in watchlist":
+			respondErrorV2(c, http.StatusConflict, "ALREADY_IN_WATCHLIST", "Movie is already in your watchlist")
+		default:
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		}
+		return
+	}
+
+	h.respondWatchlistItem(c, http.StatusCreated, entry)
+}
+
+func (h *V2Handler) RemoveFromWatchlist(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	undo, err := h.watchlistService.RemoveFromWatchlist(principal.UserID, movieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	removed := gin.H{
+		"movie_id":        movieID,
+		"undo_token":      nil,
+		"undo_expires_at": nil,
+	}
+	if undo != nil {
+		removed["undo_token"] = undo.Token
+		removed["undo_expires_at"] = undo.ExpiresAt
+	}
+	respondData(c, http.StatusOK, removed)
+}
+
+func (h *V2Handler) GetWatchlistItem(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	item, err := h.watchlistService.GetWatchlistEntry(principal.UserID, movieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	if item == nil {
+		respondErrorV2(c, http.StatusNotFound, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+	if err := h.policy.RequireOwner(principal, item.UserID); err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	h.respondWatchlistItem(c, http.StatusOK, item)
+}
+
+// UpdateWatchlistItem changes the priority of a watchlist entry
+func (h *V2Handler) UpdateWatchlistItem(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	var req UpdateWatchlistItemRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_VERSION", err.Error())
+		return
+	}
+
+	item, err := h.watchlistService.SetPriority(principal.UserID, movieID, req.Priority, version)
+	if err != nil {
+		respondWatchlistUpdateErrorV2(c, err)
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	h.respondWatchlistItem(c, http.StatusOK, item)
+}
+
+// MarkWatched marks a watchlist entry as watched
+func (h *V2Handler) MarkWatched(c *gin.Context) {
+	h.setWatched(c, true)
+}
+
+// MarkUnwatched clears the watched state of a watchlist entry
+func (h *V2Handler) MarkUnwatched(c *gin.Context) {
+	h.setWatched(c, false)
+}
+
+func (h *V2Handler) setWatched(c *gin.Context, watched bool) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	version, err := expectedVersion(c, nil)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_VERSION", err.Error())
+		return
+	}
+
+	var item *models.Watchlist
+	if watched {
+		item, err = h.watchlistService.MarkWatched(principal.UserID, movieID, version)
+	} else {
+		item, err = h.watchlistService.MarkUnwatched(principal.UserID, movieID, version)
+	}
+	if err != nil {
+		respondWatchlistUpdateErrorV2(c, err)
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	h.respondWatchlistItem(c, http.StatusOK, item)
+}
+
+func (h *V2Handler) RateMovie(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "RATING_NOT_FOUND", "Rating not found")
+		return
+	}
+
+	var req RateMovieRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	var movieID primitive.ObjectID
+	switch {
+	case req.MovieID != "":
+		parsed, err := primitive.ObjectIDFromHex(req.MovieID)
+		if err != nil {
+			respondErrorV2(c, http.StatusBadRequest, "INVALID_MOVIE_ID", "Invalid movie ID")
+			return
+		}
+		movieID = parsed
+	case req.IMDbID != "":
+		movie, err := h.movieService.GetOrCreateByIMDbID(req.IMDbID)
+		if err != nil {
+			respondMovieLookupErrorV2(c, err)
+			return
+		}
+		movieID = movie.ID
+	default:
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", "Either movie_id or imdb_id is required")
+		return
+	}
+
+	if !h.checkCertification(c, movieID) {
+		return
+	}
+
+	rating, err := h.ratingService.RateMovie(principal.UserID, movieID, req.Rating)
+	if err != nil {
+		if err.Error() == "user has already rated this movie" {
+			respondErrorV2(c, http.StatusConflict, "ALREADY_RATED", "You have already rated this movie. Use the update endpoint to change your rating.")
+		} else {
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		}
+		return
+	}
+
+	h.respondRatingItem(c, http.StatusCreated, rating)
+}
+
+func (h *V2Handler) UpdateRating(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "RATING_NOT_FOUND", "Rating not found")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	var req UpdateRatingRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_VERSION", err.Error())
+		return
+	}
+
+	updated, err := h.ratingService.UpdateRating(principal.UserID, movieID, req.Rating, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVersionConflict):
+			respondVersionConflictV2(c)
+		case err.Error() == "rating not found":
+			respondErrorV2(c, http.StatusNotFound, "RATING_NOT_FOUND", "You haven't rated this movie yet. Use the rate endpoint to add a rating.")
+		default:
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		}
+		return
+	}
+
+	setVersionETag(c, updated.Version)
+	h.respondRatingItem(c, http.StatusOK, updated)
+}
+
+func (h *V2Handler) GetRating(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzErrorV2(c, err, "RATING_NOT_FOUND", "Rating not found")
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "movieId")
+	if !ok {
+		return
+	}
+
+	rating, err := h.ratingService.GetUserRating(principal.UserID, movieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	if rating == nil {
+		respondErrorV2(c, http.StatusNotFound, "RATING_NOT_FOUND", "You haven't rated this movie yet")
+		return
+	}
+	if err := h.policy.RequireOwner(principal, rating.UserID); err != nil {
+		respondAuthzErrorV2(c, err, "RATING_NOT_FOUND", "You haven't rated this movie yet")
+		return
+	}
+
+	setVersionETag(c, rating.Version)
+	h.respondRatingItem(c, http.StatusOK, rating)
+}
+
+// RecordProgress stores how far the user got into a movie
+func (h *V2Handler) RecordProgress(c *gin.Context) {
+	userID, ok := currentUserIDV2(c)
+	if !ok {
+		return
+	}
+
+	movieID, ok := movieIDParamV2(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RecordProgressRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	progress, err := h.progressService.RecordProgress(userID, movieID, *req.MinutesWatched)
+	if err != nil {
+		if err.Error() == "movie not found" {
+			respondErrorV2(c, http.StatusNotFound, "MOVIE_NOT_FOUND", "Movie not found")
+		} else {
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		}
+		return
+	}
+
+	respondData(c, http.StatusOK, gin.H{
+		"movie_id":        progress.MovieID,
+		"minutes_watched": progress.MinutesWatched,
+		"completed":       progress.CompletedAt != nil,
+		"completed_at":    progress.CompletedAt,
+		"updated_at":      progress.UpdatedAt,
+	})
+}
+
+// Undo redeems an undo token from a destructive response, restoring what was removed
+func (h *V2Handler) Undo(c *gin.Context) {
+	userID, ok := currentUserIDV2(c)
+	if !ok {
+		return
+	}
+
+	var req UndoRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+
+	kind, restored, err := h.undoService.Undo(userID, req.UndoToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUndoExpired):
+			respondErrorV2(c, http.StatusNotFound, "UNDO_EXPIRED", "Undo token is invalid or has expired")
+		case errors.Is(err, services.ErrUndoConflict):
+			respondErrorV2(c, http.StatusConflict, "UNDO_CONFLICT", "The item was added again since it was removed")
+		default:
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", "Failed to undo")
+		}
+		return
+	}
+
+	var item interface{} = restored
+	if entry, ok := restored.(*models.Watchlist); ok {
+		movie, err := h.embedMovie(entry.MovieID)
+		if err != nil {
+			respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+			return
+		}
+		item = presentWatchlistItemV2(c, *entry, movie)
+	}
+	respondData(c, http.StatusOK, gin.H{
+		"kind": kind,
+		"item": item,
+	})
+}
+
+// respondWatchlistItem writes a watchlist entry as a v2 item
+func (h *V2Handler) respondWatchlistItem(c *gin.Context, status int, item *models.Watchlist) {
+	movie, err := h.embedMovie(item.MovieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	respondData(c, status, presentWatchlistItemV2(c, *item, movie))
+}
+
+// respondRatingItem writes a rating as a v2 item
+func (h *V2Handler) respondRatingItem(c *gin.Context, status int, rating *models.Rating) {
+	movie, err := h.embedMovie(rating.MovieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	respondData(c, status, presentRatingItemV2(c, *rating, movie))
+}
+
+// embedMovie loads a movie for embedding in an item, returning nil when it is missing
+func (h *V2Handler) embedMovie(id primitive.ObjectID) (*MovieV2, error) {
+	movie, err := h.movieService.GetMovieByID(id)
+	if err != nil || movie == nil {
+		return nil, err
+	}
+	presented := presentMovieV2(*movie)
+	return &presented, nil
+}
+
+// checkCertification is the v2 checkCertification: it rejects movies above
+// the selected kids profile's cap, writing the response and returning false
+func (h *V2Handler) checkCertification(c *gin.Context, movieID primitive.ObjectID) bool {
+	if currentProfile(c) == nil {
+		return true
+	}
+
+	movie, err := h.movieService.GetMovieByID(movieID)
+	if err != nil {
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return false
+	}
+	if movie == nil {
+		respondErrorV2(c, http.StatusNotFound, "MOVIE_NOT_FOUND", "Movie not found")
+		return false
+	}
+	if !movieAllowed(c, movie) {
+		respondErrorV2(c, http.StatusForbidden, "CERTIFICATION_RESTRICTED", "This movie is not available on this profile")
+		return false
+	}
+	return true
+}
+
+// movieIDParamV2 parses a movie ID path parameter, writing a v2 error when invalid
+func movieIDParamV2(c *gin.Context, name string) (primitive.ObjectID, bool) {
+	movieID, err := primitive.ObjectIDFromHex(c.Param(name))
+	if err != nil {
+		respondErrorV2(c, http.StatusBadRequest, "INVALID_MOVIE_ID", "Invalid movie ID")
+		return primitive.NilObjectID, false
+	}
+	return movieID, true
+}
+
+// respondWatchlistUpdateErrorV2 answers a failed change to a watchlist entry
+func respondWatchlistUpdateErrorV2(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		respondVersionConflictV2(c)
+	case err.Error() == "movie not in watchlist":
+		respondErrorV2(c, http.StatusNotFound, "NOT_IN_WATCHLIST", "Movie is not in your watchlist")
+	default:
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", err.Error())
+	}
+}
+
+// respondVersionConflictV2 is respondVersionConflict with the v2 error envelope
+func respondVersionConflictV2(c *gin.Context) {
+	respondErrorV2(c, http.StatusPreconditionFailed, "VERSION_CONFLICT", "This entry was changed by another request. Fetch it again and retry with its current version")
+}
+
+// respondMovieLookupErrorV2 is respondMovieLookupError with the v2 error envelope
+func respondMovieLookupErrorV2(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMetadataUnavailable):
+		respondErrorV2(c, http.StatusBadGateway, "METADATA_UNAVAILABLE", "Movie details are unavailable right now")
+	case errors.Is(err, services.ErrMovieNotFound):
+		respondErrorV2(c, http.StatusNotFound, "MOVIE_NOT_FOUND", "Movie not found")
+	default:
+		respondErrorV2(c, http.StatusInternalServerError, "INTERNAL", "Failed to look up movie")
+	}
+}
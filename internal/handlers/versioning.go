@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expectedVersion returns the version an update is conditional on, taken from
+// the If-Match header (the entry's ETag, such as "3") or else the version field
+// of the request body. It returns nil for unconditional updates and If-Match: *.
+func expectedVersion(c *gin.Context, bodyVersion *int) (*int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if bodyVersion != nil && *bodyVersion < 0 {
+			return nil, errors.New("version must not be negative")
+		}
+		return bodyVersion, nil
+	}
+	if header == "*" {
+		return nil, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 0 {
+		return nil, errors.New("If-Match must be the entry's ETag")
+	}
+	if bodyVersion != nil && *bodyVersion != version {
+		return nil, errors.New("If-Match and version disagree")
+	}
+	return &version, nil
+}
+
+// setVersionETag exposes an entry's version as its ETag for later If-Match updates
+func setVersionETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+func respondVersionConflict(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "This entry was changed by another request. Fetch it again and retry with its current version"})
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"math"
+	"movie-watchlist/internal/authz"
+	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,45 +15,95 @@ import (
 
 type WatchlistHandler struct {
 	watchlistService *services.WatchlistService
+	movieService     *services.MovieService
+	policy           *authz.Policy
 }
 
-func NewWatchlistHandler(watchlistService *services.WatchlistService) *WatchlistHandler {
-	return &WatchlistHandler{watchlistService: watchlistService}
+func NewWatchlistHandler(watchlistService *services.WatchlistService, movieService *services.MovieService, policy *authz.Policy) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistService: watchlistService,
+		movieService:     movieService,
+		policy:           policy,
+	}
 }
 
+// AddToWatchlistRequest references a cached movie by movie_id, or carries an
+// OMDb search result in movie so it can be added before its details are cached
 type AddToWatchlistRequest struct {
-	MovieID string `json:"movie_id" binding:"required"`
+	MovieID  string                 `json:"movie_id" sanitize:"line,max=24"`
+	Movie    *services.OMDbResponse `json:"movie"`
+	Priority int                    `json:"priority" binding:"omitempty,min=1,max=5"`
+	// Source is the surface the movie was added from, for engagement stats
+	Source *WatchlistSourceRequest `json:"source"`
+}
+
+type WatchlistSourceRequest struct {
+	Type   string `json:"type" binding:"required" sanitize:"line,max=32"`
+	Detail string `json:"detail" sanitize:"line,max=64"`
+}
+
+type UpdateWatchlistItemRequest struct {
+	Priority int `json:"priority" binding:"required,min=1,max=5"`
+	// Version makes the update conditional, like an If-Match header
+	Version *int `json:"version"`
 }
 
+// tonightPicks is how many movies the tonight endpoint suggests
+const tonightPicks = 3
+
 func (h *WatchlistHandler) AddToWatchlist(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
 		return
 	}
+	userID := principal.UserID
 
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	var req AddToWatchlistRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req AddToWatchlistRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var movieID primitive.ObjectID
+	switch {
+	case req.MovieID != "":
+		// Parse movie ID from string to ObjectID
+		parsed, err := primitive.ObjectIDFromHex(req.MovieID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+			return
+		}
+		movieID = parsed
+	case req.Movie != nil:
+		// Upsert the movie from the search payload so it can be added right away
+		movie, err := h.movieService.UpsertFromSearchResult(*req.Movie)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		movieID = movie.ID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either movie_id or movie is required"})
 		return
 	}
 
-	// Parse movie ID from string to ObjectID
-	movieID, err := primitive.ObjectIDFromHex(req.MovieID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+	if !checkCertification(c, h.movieService, movieID) {
 		return
 	}
 
-	err = h.watchlistService.AddToWatchlist(userID, movieID)
+	var source *models.WatchlistSource
+	if req.Source != nil {
+		source = &models.WatchlistSource{Type: req.Source.Type, Detail: req.Source.Detail}
+	}
+
+	entry, err := h.watchlistService.AddToWatchlist(userID, movieID, req.Priority, source)
 	if err != nil {
-		if err.Error() == "movie already in watchlist" {
+		if errors.Is(err, services.ErrInvalidWatchlistSource) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrQuotaExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		} else if err.Error() == "movie already in watchlist" {
 			c.JSON(http.StatusConflict, gin.H{"error": "Movie is already in your watchlist"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -58,22 +113,17 @@ func (h *WatchlistHandler) AddToWatchlist(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Movie added to watchlist successfully",
-		"movie_id": req.MovieID,
+		"movie_id": entry.MovieID.Hex(),
 	})
 }
 
 func (h *WatchlistHandler) RemoveFromWatchlist(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
 		return
 	}
+	userID := principal.UserID
 
 	movieIDParam := c.Param("movieId")
 	movieID, err := primitive.ObjectIDFromHex(movieIDParam)
@@ -82,49 +132,251 @@ func (h *WatchlistHandler) RemoveFromWatchlist(c *gin.Context) {
 		return
 	}
 
-	err = h.watchlistService.RemoveFromWatchlist(userID, movieID)
+	undo, err := h.watchlistService.RemoveFromWatchlist(userID, movieID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "Movie removed from watchlist successfully",
 		"movie_id": movieIDParam,
-	})
+	}
+	if undo != nil {
+		response["undo_token"] = undo.Token
+		response["undo_expires_at"] = undo.ExpiresAt
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
-	userIDValue, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
 		return
 	}
+	userID := principal.UserID
 
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+	pagination, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	watchlist, err := h.watchlistService.GetUserWatchlist(userID)
+	watchlist, total, err := h.watchlistService.GetUserWatchlistPage(userID, pagination.Offset(), pagination.PerPage)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Format response with movie details
-	var watchlistResponse []gin.H
+	watchlistResponse := []gin.H{}
 	for _, item := range watchlist {
 		watchlistResponse = append(watchlistResponse, gin.H{
-			"id":        item.ID,
-			"added_at":  item.AddedAt,
-			"movie_id":  item.MovieID,
+			"id":         item.ID,
+			"added_at":   item.AddedAt,
+			"watched_at": item.WatchedAt,
+			"priority":   item.EffectivePriority(),
+			"version":    item.Version,
+			"movie_id":   item.MovieID,
+			"_links":     watchlistItemLinks(apiBase(c), item.MovieID),
+		})
+	}
+
+	respondList(c, watchlistResponse, pagination, total, nil)
+}
+
+// GetWatchlistItem returns the user's watchlist entry for a single movie
+func (h *WatchlistHandler) GetWatchlistItem(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
+		return
+	}
+	userID := principal.UserID
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("movieId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+		return
+	}
+
+	item, err := h.watchlistService.GetWatchlistEntry(userID, movieID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movie is not in your watchlist"})
+		return
+	}
+	if err := h.policy.RequireOwner(principal, item.UserID); err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"id":         item.ID,
+		"added_at":   item.AddedAt,
+		"watched_at": item.WatchedAt,
+		"priority":   item.EffectivePriority(),
+		"version":    item.Version,
+		"movie_id":   item.MovieID,
+		"_links":     watchlistItemLinks(apiBase(c), item.MovieID),
+	})
+}
+
+// MarkWatched marks a watchlist entry as watched
+func (h *WatchlistHandler) MarkWatched(c *gin.Context) {
+	h.setWatched(c, true)
+}
+
+// MarkUnwatched clears the watched state of a watchlist entry
+func (h *WatchlistHandler) MarkUnwatched(c *gin.Context) {
+	h.setWatched(c, false)
+}
+
+func (h *WatchlistHandler) setWatched(c *gin.Context, watched bool) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
+		return
+	}
+	userID := principal.UserID
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("movieId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+		return
+	}
+
+	version, err := expectedVersion(c, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var item *models.Watchlist
+	if watched {
+		item, err = h.watchlistService.MarkWatched(userID, movieID, version)
+	} else {
+		item, err = h.watchlistService.MarkUnwatched(userID, movieID, version)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			respondVersionConflict(c)
+		} else if err.Error() == "movie not in watchlist" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie is not in your watchlist"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"id":         item.ID,
+		"added_at":   item.AddedAt,
+		"watched_at": item.WatchedAt,
+		"priority":   item.EffectivePriority(),
+		"version":    item.Version,
+		"movie_id":   item.MovieID,
+		"_links":     watchlistItemLinks(apiBase(c), item.MovieID),
+	})
+}
+
+// UpdateWatchlistItem changes the priority of a watchlist entry
+func (h *WatchlistHandler) UpdateWatchlistItem(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
+		return
+	}
+	userID := principal.UserID
+
+	movieID, err := primitive.ObjectIDFromHex(c.Param("movieId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie ID format"})
+		return
+	}
+
+	var req UpdateWatchlistItemRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.watchlistService.SetPriority(userID, movieID, req.Priority, version)
+	if err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			respondVersionConflict(c)
+		} else if err.Error() == "movie not in watchlist" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie is not in your watchlist"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setVersionETag(c, item.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"id":         item.ID,
+		"added_at":   item.AddedAt,
+		"watched_at": item.WatchedAt,
+		"priority":   item.EffectivePriority(),
+		"version":    item.Version,
+		"movie_id":   item.MovieID,
+		"_links":     watchlistItemLinks(apiBase(c), item.MovieID),
+	})
+}
+
+// GetTonightPicks suggests unwatched watchlist movies that fit in ?available_minutes
+func (h *WatchlistHandler) GetTonightPicks(c *gin.Context) {
+	principal, err := h.policy.Principal(c)
+	if err != nil {
+		respondAuthzError(c, err, "Movie is not in your watchlist")
+		return
+	}
+	userID := principal.UserID
+
+	availableMinutes, err := strconv.Atoi(c.Query("available_minutes"))
+	if err != nil || availableMinutes < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "available_minutes must be a positive integer"})
+		return
+	}
+
+	picks, err := h.watchlistService.GetTonightPicks(userID, availableMinutes, tonightPicks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	formattedPicks := []gin.H{}
+	for _, pick := range picks {
+		formattedPicks = append(formattedPicks, gin.H{
+			"movie_id":        pick.Movie.ID,
+			"title":           pick.Movie.Title,
+			"year":            pick.Movie.Year,
+			"runtime_minutes": pick.Movie.RuntimeMinutes(),
+			"imdb_rating":     pick.Movie.IMDbRating,
+			"poster":          pick.Movie.Poster,
+			"priority":        pick.Entry.EffectivePriority(),
+			"added_at":        pick.Entry.AddedAt,
+			"score":           math.Round(pick.Score*1000) / 1000,
+			"reasons":         pick.Reasons,
+			"_links":          watchlistItemLinks(apiBase(c), pick.Movie.ID),
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"watchlist": watchlistResponse,
-		"count":     len(watchlistResponse),
+		"available_minutes": availableMinutes,
+		"picks":             formattedPicks,
 	})
 }
@@ -0,0 +1,100 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"movie-watchlist/internal/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWatchlistAddListRemove(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+	movie := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", token, gin.H{
+		"movie_id": movie.ID.Hex(),
+		"priority": 5,
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+
+	recorder = testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", token, gin.H{"movie_id": movie.ID.Hex()})
+	testutil.Decode(t, recorder, http.StatusConflict, nil)
+
+	var list listResponse
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &list)
+	if list.Meta.Total != 1 || len(list.Data) != 1 {
+		t.Fatalf("watchlist has %d entries (total %d), want 1", len(list.Data), list.Meta.Total)
+	}
+	if got := list.Data[0]["movie_id"]; got != movie.ID.Hex() {
+		t.Errorf("movie_id = %v, want %s", got, movie.ID.Hex())
+	}
+	if got := list.Data[0]["priority"]; got != float64(5) {
+		t.Errorf("priority = %v, want 5", got)
+	}
+
+	var removed struct {
+		UndoToken string `json:"undo_token"`
+	}
+	recorder = testutil.Do(t, server.router, http.MethodDelete, "/api/v1/watchlist/"+movie.ID.Hex(), token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &removed)
+	if removed.UndoToken == "" {
+		t.Error("removal returned no undo_token")
+	}
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, &list)
+	if list.Meta.Total != 0 {
+		t.Errorf("watchlist total = %d after removal, want 0", list.Meta.Total)
+	}
+}
+
+func TestWatchlistAddsMovieFromSearchResult(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+	token := testutil.Token(t, user.ID)
+
+	var added struct {
+		MovieID string `json:"movie_id"`
+	}
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", token, gin.H{
+		"movie": gin.H{"Title": "Inception", "Year": "2010", "imdbID": "tt1375666", "Type": "movie", "Poster": "N/A"},
+	})
+	testutil.Decode(t, recorder, http.StatusCreated, &added)
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist/"+added.MovieID, token, nil)
+	testutil.Decode(t, recorder, http.StatusOK, nil)
+}
+
+func TestWatchlistEntriesArePrivate(t *testing.T) {
+	server := newTestServer(t)
+	alice := testutil.CreateUser(t, server.db, "alice")
+	bob := testutil.CreateUser(t, server.db, "bob")
+	movie := server.seedMovie(t, "tt0133093")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", testutil.Token(t, alice.ID), gin.H{"movie_id": movie.ID.Hex()})
+	testutil.Decode(t, recorder, http.StatusCreated, nil)
+
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist/"+movie.ID.Hex(), testutil.Token(t, bob.ID), nil)
+	testutil.Decode(t, recorder, http.StatusNotFound, nil)
+
+	var list listResponse
+	recorder = testutil.Do(t, server.router, http.MethodGet, "/api/v1/watchlist", testutil.Token(t, bob.ID), nil)
+	testutil.Decode(t, recorder, http.StatusOK, &list)
+	if list.Meta.Total != 0 {
+		t.Errorf("bob's watchlist total = %d, want 0", list.Meta.Total)
+	}
+}
+
+func TestWatchlistRejectsInvalidMovieID(t *testing.T) {
+	server := newTestServer(t)
+	user := testutil.CreateUser(t, server.db, "alice")
+
+	recorder := testutil.Do(t, server.router, http.MethodPost, "/api/v1/watchlist", testutil.Token(t, user.ID), gin.H{"movie_id": "not-an-id"})
+	testutil.Decode(t, recorder, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,277 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job types handled by the queue
+const (
+	TypeRefreshMovieMetadata = "movie.refresh_metadata"
+	TypeRatingReminder       = "notification.rating_reminder"
+	TypeUpcomingReleases     = "calendar.upcoming_releases"
+	TypeEvaluateRecommender  = "recommendations.evaluate"
+	TypeEmbedPlots           = "movies.embed_plots"
+	TypeTagKeywords          = "movies.tag_keywords"
+	TypeTagAdvisories        = "movies.tag_advisories"
+	TypePrecomputeRecs       = "recommendations.precompute"
+	TypeDemoCleanup          = "demo.cleanup"
+	TypeCheckWatchGoal       = "habits.check_goal"
+	TypeAwardAchievements    = "achievements.evaluate"
+	TypeRotateEncryptionKeys = "crypto.rotate_keys"
+	TypePurgeDeactivated     = "accounts.purge_deactivated"
+	TypeReconcileMetadata    = "movies.reconcile_metadata"
+	TypeCheckPosters         = "movies.check_posters"
+	TypeEvictMovies          = "movies.evict_unreferenced"
+	TypeTraktSync            = "trakt.sync"
+	TypeTraktPush            = "trakt.push"
+	TypeChatPost             = "chat.post"
+	TypeChatPollResult       = "chat.poll_result"
+)
+
+// Handler processes a single job payload; returning an error schedules a retry
+type Handler func(ctx context.Context, payload map[string]interface{}) error
+
+// RetryPolicy controls how often and how quickly a failing job is retried.
+// The delay doubles after every failed attempt, capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Timeout     time.Duration
+}
+
+// DefaultRetryPolicy retries five times over roughly fifteen minutes
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    10 * time.Minute,
+	Timeout:     time.Minute,
+}
+
+type registration struct {
+	handler Handler
+	policy  RetryPolicy
+}
+
+// Queue is a MongoDB-backed job queue. Jobs survive restarts, are claimed
+// atomically so several workers (or instances) can share the queue, and are
+// moved to the dead letter state once their retries are exhausted.
+type Queue struct {
+	jobRepo      *repositories.JobRepository
+	handlers     map[string]registration
+	workers      int
+	pollInterval time.Duration
+	mu           sync.RWMutex
+	logger       *slog.Logger
+}
+
+func NewQueue(jobRepo *repositories.JobRepository, workers int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Queue{
+		jobRepo:      jobRepo,
+		handlers:     make(map[string]registration),
+		workers:      workers,
+		pollInterval: time.Second,
+		logger:       logging.For("jobs"),
+	}
+}
+
+// Register installs the handler and retry policy for a job type
+func (q *Queue) Register(jobType string, handler Handler, policy RetryPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = registration{handler: handler, policy: policy}
+}
+
+// Enqueue persists a job to run as soon as a worker is free
+func (q *Queue) Enqueue(jobType string, payload map[string]interface{}) error {
+	return q.EnqueueAt(jobType, payload, time.Now().UTC())
+}
+
+// EnqueueAt persists a job to run no earlier than runAt
+func (q *Queue) EnqueueAt(jobType string, payload map[string]interface{}, runAt time.Time) error {
+	q.mu.RLock()
+	reg, ok := q.handlers[jobType]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+
+	return q.jobRepo.Create(&models.Job{
+		Type:        jobType,
+		Payload:     payload,
+		Status:      models.JobStatusPending,
+		MaxAttempts: reg.policy.MaxAttempts,
+		RunAt:       runAt,
+	})
+}
+
+// EnsureScheduled enqueues a job of the type at runAt unless one is already
+// pending or running. Recurring jobs call it on startup and reschedule
+// themselves with EnqueueAt when they finish.
+func (q *Queue) EnsureScheduled(jobType string, payload map[string]interface{}, runAt time.Time) error {
+	active, err := q.jobRepo.HasActive(jobType)
+	if err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+	return q.EnqueueAt(jobType, payload, runAt)
+}
+
+// Start launches the workers; they stop when ctx is cancelled
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every due job before waiting for the next tick
+		for q.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs one job, reporting whether a job was found
+func (q *Queue) runNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// Lock claimed jobs for twice the longest timeout so that only jobs whose
+	// worker died are picked up again
+	q.mu.RLock()
+	types := make([]string, 0, len(q.handlers))
+	lockFor := DefaultRetryPolicy.Timeout
+	for jobType, reg := range q.handlers {
+		types = append(types, jobType)
+		if reg.policy.Timeout > lockFor {
+			lockFor = reg.policy.Timeout
+		}
+	}
+	q.mu.RUnlock()
+	if len(types) == 0 {
+		return false
+	}
+
+	job, err := q.jobRepo.ClaimNext(types, lockFor*2)
+	if err != nil {
+		q.logger.Warn("failed to claim job", "error", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	q.mu.RLock()
+	reg := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	q.execute(ctx, job, reg)
+	return true
+}
+
+func (q *Queue) execute(ctx context.Context, job *models.Job, reg registration) {
+	timeout := reg.policy.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRetryPolicy.Timeout
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := runSafely(jobCtx, reg.handler, job.Payload)
+	if err == nil {
+		if err := q.jobRepo.MarkSucceeded(job.ID); err != nil {
+			q.logger.Warn("failed to mark job succeeded", "job_id", job.ID.Hex(), "error", err)
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		q.logger.Error("job moved to dead letter", "job_id", job.ID.Hex(), "type", job.Type, "attempts", job.Attempts, "error", err)
+		if markErr := q.jobRepo.MarkDead(job.ID, err.Error()); markErr != nil {
+			q.logger.Warn("failed to mark job dead", "job_id", job.ID.Hex(), "error", markErr)
+		}
+		return
+	}
+
+	runAt := time.Now().UTC().Add(reg.policy.backoff(job.Attempts))
+	if markErr := q.jobRepo.MarkRetry(job.ID, err.Error(), runAt); markErr != nil {
+		q.logger.Warn("failed to reschedule job", "job_id", job.ID.Hex(), "error", markErr)
+	}
+}
+
+// Status summarises the queue for the admin jobs endpoint
+type Status struct {
+	Counts     map[string]int64 `json:"counts"`
+	DeadLetter []models.Job     `json:"dead_letter"`
+	Workers    int              `json:"workers"`
+}
+
+// Status returns job counts per status and the most recent dead letter jobs
+func (q *Queue) Status(deadLimit int64) (*Status, error) {
+	counts, err := q.jobRepo.CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	dead, err := q.jobRepo.FindByStatus(models.JobStatusDead, deadLimit)
+	if err != nil {
+		return nil, err
+	}
+	if dead == nil {
+		dead = []models.Job{}
+	}
+
+	return &Status{Counts: counts, DeadLetter: dead, Workers: q.workers}, nil
+}
+
+// Requeue moves a dead letter job back to the queue with a fresh retry budget
+func (q *Queue) Requeue(id primitive.ObjectID) (bool, error) {
+	return q.jobRepo.Requeue(id)
+}
+
+// runSafely converts a handler panic into an error so it is retried like any failure
+func runSafely(ctx context.Context, handler Handler, payload map[string]interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Options configures the application logger
+type Options struct {
+	// Level is the default minimum level: debug, info, warn or error
+	Level string
+	// Format is text or json
+	Format string
+	// ModuleLevels overrides Level per module as "module=level" entries; a
+	// module also matches its dotted children, e.g. "services" covers
+	// "services.search" unless that has its own entry
+	ModuleLevels []string
+	// SampleInitial and SampleThereafter sample repeated debug and info
+	// messages: within each second the first SampleInitial occurrences of a
+	// message are logged, then every SampleThereafter-th. 0 disables sampling.
+	SampleInitial    int
+	SampleThereafter int
+}
+
+// state is the installed configuration shared by all module loggers
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	modules map[string]slog.Level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}),
+		level:   slog.LevelInfo,
+		modules: map[string]slog.Level{},
+	})
+}
+
+// Setup installs the application logger. Calls to the standard log package
+// and slog's default logger are routed through the same handler, with
+// secrets redacted.
+func Setup(opts Options) error {
+	modules, err := ParseModuleLevels(opts.ModuleLevels)
+	if err != nil {
+		return err
+	}
+
+	// Levels are enforced by the module gate, so the base handler accepts everything
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	if opts.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, handlerOpts)
+	}
+	if opts.SampleInitial > 0 {
+		handler = newSamplingHandler(handler, opts.SampleInitial, opts.SampleThereafter)
+	}
+
+	current.Store(&state{
+		handler: handler,
+		level:   ParseLevel(opts.Level),
+		modules: modules,
+	})
+	slog.SetDefault(slog.New(&gateHandler{}))
+	return nil
+}
+
+// For returns the logger for a module such as "jobs" or "services.search".
+// It follows later Setup calls, so it is safe to create before Setup runs.
+func For(module string) *slog.Logger {
+	return slog.New(&gateHandler{module: module})
+}
+
+// ParseLevel maps a level name to its slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ParseModuleLevels parses "module=level" entries
+func ParseModuleLevels(entries []string) (map[string]slog.Level, error) {
+	modules := make(map[string]slog.Level, len(entries))
+	for _, entry := range entries {
+		module, level, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		module, level = strings.TrimSpace(module), strings.ToLower(strings.TrimSpace(level))
+		if !ok || module == "" {
+			return nil, fmt.Errorf("log module level %q must look like module=level", entry)
+		}
+		switch level {
+		case "debug", "info", "warn", "error":
+		default:
+			return nil, fmt.Errorf("log module level %q: level must be one of debug, info, warn, error", entry)
+		}
+		modules[module] = ParseLevel(level)
+	}
+	return modules, nil
+}
+
+// levelFor resolves a module's minimum level by longest dotted prefix
+func (s *state) levelFor(module string) slog.Level {
+	for name := module; name != ""; {
+		if level, ok := s.modules[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return s.level
+}
+
+// gateHandler applies the module's level and tags records with the module,
+// delegating to whichever handler is currently installed
+type gateHandler struct {
+	module string
+	ops    []func(slog.Handler) slog.Handler
+}
+
+func (h *gateHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levelFor(h.module)
+}
+
+func (h *gateHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := current.Load().handler
+	if h.module != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("module", h.module)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *gateHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *gateHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *gateHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &gateHandler{module: h.module, ops: append(ops, op)}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secret values in log output
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute key fragments whose values are never logged
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "api_key", "apikey", "cookie"}
+
+// secretPatterns find secrets embedded in free text such as error messages,
+// e.g. request URLs carrying the OMDb key or connection strings with passwords
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)(apikey=)[^&\s"']+`), "${1}" + redacted},
+	{regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`), "${1}" + redacted + "@"},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_.=]+`), "${1}" + redacted},
+}
+
+// redactAttr masks sensitive attributes and secrets inside string values,
+// including the log message itself
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, fragment := range sensitiveKeys {
+		if strings.Contains(key, fragment) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactString masks secrets embedded in s
+func RedactString(s string) string {
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
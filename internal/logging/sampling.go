@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// samplingHandler thins out repeated debug and info messages so hot paths
+// cannot flood the logs; warnings and errors are always kept
+type samplingHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+type sampler struct {
+	initial    int
+	thereafter int
+
+	mu     sync.Mutex
+	second int64
+	counts map[string]int
+}
+
+func newSamplingHandler(next slog.Handler, initial, thereafter int) *samplingHandler {
+	return &samplingHandler{
+		next:    next,
+		sampler: &sampler{initial: initial, thereafter: thereafter, counts: make(map[string]int)},
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.sampler.keep(r.Level.String()+"|"+r.Message, r.Time) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// keep counts one occurrence of key in the current second and reports
+// whether it should be logged
+func (s *sampler) keep(key string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if second := at.Unix(); second != s.second {
+		s.second = second
+		s.counts = make(map[string]int)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"movie-watchlist/internal/logging"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer sends plain-text emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer writes emails to the log instead of sending them; used when no
+// SMTP server is configured
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	logging.For("mailer").Info("email not sent; SMTP is not configured", "to", to, "subject", subject, "body", body)
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP server using PLAIN auth when a
+// username is configured
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, fmt.Sprint(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	// Header values must not contain line breaks
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	msg := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
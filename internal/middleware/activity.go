@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityMiddleware reports the authenticated user as active.
+// It must run after AuthMiddleware so that user_id is present in the context.
+func ActivityMiddleware(record func(userID primitive.ObjectID)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userIDValue, exists := c.Get("user_id"); exists {
+			if userID, ok := userIDValue.(primitive.ObjectID); ok {
+				record(userID)
+			}
+		}
+
+		c.Next()
+	}
+}
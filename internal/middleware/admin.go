@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"errors"
+	"movie-watchlist/internal/authz"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware restricts access to the admins known to the policy.
+// It must run after AuthMiddleware so that user_id is present in the context.
+func AdminMiddleware(policy *authz.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := policy.Principal(c)
+		if err == nil {
+			err = policy.RequireAdmin(principal)
+		}
+		if errors.Is(err, authz.ErrUnauthenticated) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+				"code":  "MISSING_USER",
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Admin access required",
+				"code":  "FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
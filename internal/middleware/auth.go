@@ -11,13 +11,31 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// TokenConfig is how access tokens are signed and checked. Tokens carry the
+// issuer and audience of the deployment that minted them and are rejected
+// elsewhere, even when another deployment shares the secret.
+type TokenConfig struct {
+	Secret   string
+	TTL      time.Duration
+	Issuer   string
+	Audience string
+}
+
 type Claims struct {
-	UserID primitive.ObjectID `json:"user_id"`
+	UserID    primitive.ObjectID `json:"user_id"`
+	SessionID string             `json:"sid,omitempty"`
+	// Demo marks a short-lived sandbox account from POST /demo/session
+	Demo bool `json:"demo,omitempty"`
+	// ClientID marks a token issued to a third-party app, limited to the
+	// space-separated Scope. Without a UserID the token is the app's own,
+	// from the client credentials grant.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // AuthMiddleware creates a JWT authentication middleware
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(tokens TokenConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Step 1: Extract Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -30,37 +48,87 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Step 2: Validate Bearer token format
-		tokenString, err := extractBearerToken(authHeader)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": err.Error(),
-				"code":  "INVALID_TOKEN_FORMAT",
-			})
-			c.Abort()
+		// Steps 2-4: Validate the token and inject the user into the context
+		if !authenticate(c, authHeader, tokens) {
 			return
 		}
-
-		// Step 3: Parse and validate JWT token
-		claims, err := parseAndValidateToken(tokenString, jwtSecret)
-		if err != nil {
+		if _, exists := c.Get("user_id"); !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": err.Error(),
-				"code":  "INVALID_TOKEN",
+				"error": "this endpoint needs a token issued for a user",
+				"code":  "USER_TOKEN_REQUIRED",
 			})
 			c.Abort()
 			return
 		}
 
-		// Step 4: Inject user_id into request context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_claims", claims)
-		
 		// Step 5: Continue to next handler
 		c.Next()
 	}
 }
 
+// OptionalAuthMiddleware authenticates the request when an Authorization
+// header is present and lets it through as a guest otherwise. A header that
+// is present but invalid is still rejected, so clients notice expired tokens.
+func OptionalAuthMiddleware(tokens TokenConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Set("guest", true)
+			c.Next()
+			return
+		}
+
+		if !authenticate(c, authHeader, tokens) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate validates the bearer token in authHeader and injects the user
+// into the context. It aborts the request and returns false on failure.
+func authenticate(c *gin.Context, authHeader string, tokens TokenConfig) bool {
+	// Validate Bearer token format
+	tokenString, err := extractBearerToken(authHeader)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_TOKEN_FORMAT",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Parse and validate JWT token
+	claims, err := parseAndValidateToken(tokenString, tokens)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_TOKEN",
+		})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_claims", claims)
+	if claims.ClientID != "" {
+		c.Set("client_id", claims.ClientID)
+	}
+	// An app's own token acts for no user and is treated as a guest
+	if claims.UserID.IsZero() {
+		c.Set("guest", true)
+		return true
+	}
+
+	// Inject user_id into request context
+	c.Set("user_id", claims.UserID)
+	if claims.Demo {
+		c.Set("demo", true)
+	}
+	return true
+}
+
 // extractBearerToken extracts the Bearer token from the Authorization header
 func extractBearerToken(authHeader string) (string, error) {
 	const bearerPrefix = "Bearer "
@@ -77,8 +145,9 @@ func extractBearerToken(authHeader string) (string, error) {
 	return token, nil
 }
 
-// parseAndValidateToken parses and validates the JWT token
-func parseAndValidateToken(tokenString, jwtSecret string) (*Claims, error) {
+// parseAndValidateToken parses and validates the JWT token, including its
+// issuer and audience
+func parseAndValidateToken(tokenString string, tokens TokenConfig) (*Claims, error) {
 	claims := &Claims{}
 	
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -88,8 +157,8 @@ func parseAndValidateToken(tokenString, jwtSecret string) (*Claims, error) {
 		}
 		
 		// Return the secret key for validation
-		return []byte(jwtSecret), nil
-	})
+		return []byte(tokens.Secret), nil
+	}, jwt.WithIssuer(tokens.Issuer), jwt.WithAudience(tokens.Audience))
 	
 	if err != nil {
 		return nil, fmt.Errorf("token parsing failed: %w", err)
@@ -113,32 +182,78 @@ func parseAndValidateToken(tokenString, jwtSecret string) (*Claims, error) {
 }
 
 // GenerateToken generates a JWT token for the given user ID
-func GenerateToken(userID primitive.ObjectID, jwtSecret string) (string, error) {
+func GenerateToken(userID primitive.ObjectID, tokens TokenConfig) (string, error) {
+	return GenerateSessionToken(userID, "", tokens)
+}
+
+// GenerateSessionToken generates a JWT token bound to a device session, so
+// that revoking the session also rejects its access tokens
+func GenerateSessionToken(userID primitive.ObjectID, sessionID string, tokens TokenConfig) (string, error) {
+	return generateToken(userID, sessionID, false, time.Now().Add(tokens.TTL), tokens)
+}
+
+// GenerateDemoToken generates a JWT token for a demo sandbox session that
+// expires with the sandbox
+func GenerateDemoToken(userID primitive.ObjectID, sessionID string, expiresAt time.Time, tokens TokenConfig) (string, error) {
+	return generateToken(userID, sessionID, true, expiresAt, tokens)
+}
+
+// GenerateAppToken generates a JWT token for a third-party app limited to
+// scopes. With a user it is bound to the app's session with the user; with
+// a zero userID it is the app's own token from client credentials.
+func GenerateAppToken(userID primitive.ObjectID, sessionID, clientID string, scopes []string, tokens TokenConfig) (string, error) {
+	if clientID == "" {
+		return "", fmt.Errorf("client ID cannot be empty")
+	}
+
+	subject := clientID
+	if !userID.IsZero() {
+		subject = userID.Hex()
+	}
+	claims := newClaims(subject, time.Now().Add(tokens.TTL), tokens)
+	claims.UserID = userID
+	claims.SessionID = sessionID
+	claims.ClientID = clientID
+	claims.Scope = strings.Join(scopes, " ")
+	return signClaims(claims, tokens)
+}
+
+func generateToken(userID primitive.ObjectID, sessionID string, demo bool, expiresAt time.Time, tokens TokenConfig) (string, error) {
 	if userID.IsZero() {
 		return "", fmt.Errorf("user ID cannot be empty")
 	}
 	
-	if jwtSecret == "" {
-		return "", fmt.Errorf("JWT secret cannot be empty")
-	}
-	
-	// Create claims with expiration and issued at
-	claims := &Claims{
-		UserID: userID,
+	claims := newClaims(userID.Hex(), expiresAt, tokens)
+	claims.UserID = userID
+	claims.SessionID = sessionID
+	claims.Demo = demo
+	return signClaims(claims, tokens)
+}
+
+// newClaims creates claims with expiration and issued at
+func newClaims(subject string, expiresAt time.Time, tokens TokenConfig) *Claims {
+	return &Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "movie-watchlist-api",
-			Subject:   userID.Hex(),
+			Issuer:    tokens.Issuer,
+			Audience:  jwt.ClaimStrings{tokens.Audience},
+			Subject:   subject,
 		},
 	}
+}
+
+func signClaims(claims *Claims, tokens TokenConfig) (string, error) {
+	if tokens.Secret == "" {
+		return "", fmt.Errorf("JWT secret cannot be empty")
+	}
 	
 	// Create token with signing method
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	
 	// Sign token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString([]byte(tokens.Secret))
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -147,18 +262,18 @@ func GenerateToken(userID primitive.ObjectID, jwtSecret string) (string, error)
 }
 
 // ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString, jwtSecret string) (*Claims, error) {
-	return parseAndValidateToken(tokenString, jwtSecret)
+func ValidateToken(tokenString string, tokens TokenConfig) (*Claims, error) {
+	return parseAndValidateToken(tokenString, tokens)
 }
 
 // RefreshToken generates a new token with extended expiration
-func RefreshToken(oldTokenString, jwtSecret string) (string, error) {
+func RefreshToken(oldTokenString string, tokens TokenConfig) (string, error) {
 	// Parse old token
-	claims, err := parseAndValidateToken(oldTokenString, jwtSecret)
+	claims, err := parseAndValidateToken(oldTokenString, tokens)
 	if err != nil {
 		return "", fmt.Errorf("invalid token for refresh: %w", err)
 	}
 	
-	// Generate new token with same user ID
-	return GenerateToken(claims.UserID, jwtSecret)
+	// Generate new token with same user ID and session
+	return GenerateSessionToken(claims.UserID, claims.SessionID, tokens)
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// strictJSONKey marks requests whose JSON bodies may not contain unknown fields
+const strictJSONKey = "strict_json"
+
+// BodyLimitMiddleware rejects request bodies larger than maxBytes. Bodies that
+// declare their size are refused up front with 413; others are cut off while
+// being read, which makes binding fail.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Request body must be at most %d bytes", maxBytes),
+				"code":  "BODY_TOO_LARGE",
+			})
+			c.Abort()
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
+
+// StrictJSONMiddleware makes JSON binding reject fields the endpoint does not
+// define, so typos and unexpected input fail loudly instead of being ignored
+func StrictJSONMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(strictJSONKey, true)
+		c.Next()
+	}
+}
+
+// IsStrictJSON reports whether StrictJSONMiddleware applies to the request
+func IsStrictJSON(c *gin.Context) bool {
+	return c.GetBool(strictJSONKey)
+}
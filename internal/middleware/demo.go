@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IsDemo reports whether the request is authenticated with a demo sandbox token
+func IsDemo(c *gin.Context) bool {
+	return c.GetBool("demo")
+}
+
+// DemoRateLimitMiddleware applies the stricter demo limiter to demo sandbox
+// users only. It must run after the auth middleware.
+func DemoRateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	limit := RateLimitMiddleware(limiter)
+	return func(c *gin.Context) {
+		if !IsDemo(c) {
+			c.Next()
+			return
+		}
+		limit(c)
+	}
+}
+
+// DemoForbiddenMiddleware rejects demo sandbox users on endpoints that reach
+// outside the sandbox, such as sending email
+func DemoForbiddenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsDemo(c) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not available in the demo; register an account to use this",
+				"code":  "DEMO_FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deprecationNotice is the "deprecation" field added to JSON object
+// responses of deprecated routes
+type deprecationNotice struct {
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
+	Successor    string     `json:"successor,omitempty"`
+	Message      string     `json:"message,omitempty"`
+}
+
+// DeprecationMiddleware announces that a route is deprecated with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, a successor-version
+// Link, and a "deprecation" field in JSON object responses, for clients that
+// only look at the body. Once the sunset passes the route answers 410.
+func DeprecationMiddleware(lookup func(method, path string) *models.RouteDeprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		deprecation := lookup(c.Request.Method, route)
+		if deprecation == nil {
+			c.Next()
+			return
+		}
+
+		notice := deprecationNotice{
+			DeprecatedAt: deprecation.DeprecatedAt,
+			SunsetAt:     deprecation.SunsetAt,
+			Message:      deprecation.Message,
+		}
+		if deprecation.Successor != "" {
+			notice.Successor = fillRouteParams(deprecation.Successor, c.Params)
+			c.Writer.Header().Add("Link", "<"+notice.Successor+`>; rel="successor-version"`)
+		}
+		if deprecation.DeprecatedAt != nil {
+			c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.DeprecatedAt.Unix()))
+		}
+		if deprecation.SunsetAt != nil {
+			c.Header("Sunset", deprecation.SunsetAt.UTC().Format(http.TimeFormat))
+			if !time.Now().Before(*deprecation.SunsetAt) {
+				body := gin.H{
+					"error": "This endpoint has been retired",
+					"code":  "ENDPOINT_SUNSET",
+				}
+				if notice.Successor != "" {
+					body["successor"] = notice.Successor
+				}
+				c.JSON(http.StatusGone, body)
+				c.Abort()
+				return
+			}
+		}
+
+		encoded, err := json.Marshal(notice)
+		if err != nil {
+			c.Next()
+			return
+		}
+		writer := &deprecationWriter{ResponseWriter: c.Writer, notice: encoded}
+		c.Writer = writer
+		// Deferred so the response is released even when a handler panics
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			writer.release()
+		}()
+		c.Next()
+	}
+}
+
+// deprecationWriter holds back JSON responses so the deprecation notice can
+// be added to them. Other responses, such as CSV exports and event streams,
+// pass straight through.
+type deprecationWriter struct {
+	gin.ResponseWriter
+	notice  []byte
+	body    bytes.Buffer
+	decided bool
+	held    bool
+}
+
+func (w *deprecationWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.held = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if w.held {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *deprecationWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *deprecationWriter) Flush() {
+	if !w.held {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// release writes the held response with the notice as its first field, if
+// it is a JSON object
+func (w *deprecationWriter) release() {
+	if !w.held {
+		return
+	}
+
+	body := w.body.Bytes()
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		rest := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+		merged := append([]byte(`{"deprecation":`), w.notice...)
+		if len(rest) > 0 && rest[0] != '}' {
+			merged = append(merged, ',')
+		}
+		body = append(merged, rest...)
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.Write(body)
+}
+
+// fillRouteParams turns a route such as /api/v2/watchlist/:movieId into the
+// path with the request's parameters
+func fillRouteParams(route string, params gin.Params) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = strings.TrimPrefix(params.ByName(segment[1:]), "/")
+		}
+	}
+	return strings.Join(segments, "/")
+}
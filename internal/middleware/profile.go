@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"movie-watchlist/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProfileHeader selects one of the signed-in account's kids profiles
+const ProfileHeader = "X-Profile-ID"
+
+// ProfileMiddleware switches the request to a kids profile when ProfileHeader
+// is set. user_id becomes the profile ID, so watchlist, rating and
+// recommendation handlers are scoped to the profile without knowing about it;
+// account_id keeps the parent account and profile holds the profile itself.
+// It must run after the auth, session, activity and rate limit middleware so
+// those keep working against the parent account.
+func ProfileMiddleware(lookup func(profileID, parentID primitive.ObjectID) (*models.Profile, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(ProfileHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		userIDValue, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Selecting a profile requires authentication",
+				"code":  "MISSING_USER",
+			})
+			c.Abort()
+			return
+		}
+		parentID, ok := userIDValue.(primitive.ObjectID)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+			c.Abort()
+			return
+		}
+
+		profileID, err := primitive.ObjectIDFromHex(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid profile ID",
+				"code":  "INVALID_PROFILE_ID",
+			})
+			c.Abort()
+			return
+		}
+
+		profile, err := lookup(profileID, parentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if profile == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Profile not found",
+				"code":  "PROFILE_NOT_FOUND",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("account_id", parentID)
+		c.Set("user_id", profile.ID)
+		c.Set("profile", profile)
+		c.Next()
+	}
+}
+
+// AccountOnlyMiddleware rejects requests made as a kids profile. Login,
+// account settings, sessions and profile management stay with the parent.
+func AccountOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isProfile := c.Get("profile"); isProfile {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not available to kids profiles",
+				"code":  "PROFILE_FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RateLimiter counts requests per caller in fixed windows. It is in-memory,
+// so each API instance enforces its own limit.
+type RateLimiter struct {
+	limit     int
+	window    time.Duration
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	nextSweep time.Time
+}
+
+type rateWindow struct {
+	count int
+	reset time.Time
+}
+
+// RateStatus is a caller's standing in the current window
+type RateStatus struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	Allowed   bool
+}
+
+// NewRateLimiter allows limit requests per window for each caller; a limit of
+// 0 or less disables limiting
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Enabled reports whether the limiter enforces a limit
+func (l *RateLimiter) Enabled() bool {
+	return l.limit > 0
+}
+
+// Allow counts one request for key and reports whether it is within the limit
+func (l *RateLimiter) Allow(key string) RateStatus {
+	return l.status(key, true)
+}
+
+// Peek reports key's standing without counting a request
+func (l *RateLimiter) Peek(key string) RateStatus {
+	return l.status(key, false)
+}
+
+func (l *RateLimiter) status(key string, count bool) RateStatus {
+	if !l.Enabled() {
+		return RateStatus{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = &rateWindow{reset: now.Add(l.window)}
+		if count {
+			l.windows[key] = w
+		}
+	}
+
+	allowed := w.count < l.limit
+	if count && allowed {
+		w.count++
+	}
+
+	remaining := l.limit - w.count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return RateStatus{Limit: l.limit, Remaining: remaining, Reset: w.reset, Allowed: allowed}
+}
+
+// sweep drops expired windows at most once per window length
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, w := range l.windows {
+		if !now.Before(w.reset) {
+			delete(l.windows, key)
+		}
+	}
+	l.nextSweep = now.Add(l.window)
+}
+
+// RateLimitKey identifies the caller: the authenticated user when known,
+// then an app calling as itself, otherwise the client IP
+func RateLimitKey(c *gin.Context) string {
+	if userIDValue, exists := c.Get("user_id"); exists {
+		if userID, ok := userIDValue.(primitive.ObjectID); ok {
+			return "user:" + userID.Hex()
+		}
+	}
+	// Apps calling with their own token share an allowance wherever they run
+	if clientID := c.GetString("client_id"); clientID != "" {
+		return "client:" + clientID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitMiddleware enforces the limiter and reports the caller's standing in
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (Unix
+// seconds). It must run after the auth middleware so users are keyed by ID.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limiter.Enabled() {
+			c.Next()
+			return
+		}
+
+		status := limiter.Allow(RateLimitKey(c))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+
+		if !status.Allowed {
+			retryAfter := int(time.Until(status.Reset).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Rate limit exceeded",
+				"code":  "RATE_LIMITED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/errorreport"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecoveryMiddleware turns a panicking handler into a 500 response carrying
+// the request ID and forwards the panic with its stack trace to reporter.
+// It should run after RequestIDMiddleware.
+func RecoveryMiddleware(reporter errorreport.Reporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The client went away mid-response; there is nothing to report
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			requestID := RequestID(c)
+			event := errorreport.Event{
+				Message:   fmt.Sprintf("panic: %v", recovered),
+				Stack:     string(debug.Stack()),
+				RequestID: requestID,
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Time:      time.Now(),
+			}
+			if userIDValue, exists := c.Get("user_id"); exists {
+				if userID, ok := userIDValue.(primitive.ObjectID); ok {
+					event.UserID = userID.Hex()
+				}
+			}
+			reporter.Report(c.Request.Context(), event)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "Internal server error",
+				"request_id": requestID,
+			})
+		}()
+
+		c.Next()
+	}
+}
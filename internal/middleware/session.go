@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionMiddleware rejects access tokens whose device session has been revoked.
+// It must run after AuthMiddleware or OptionalAuthMiddleware. Tokens issued
+// without a session are accepted.
+func SessionMiddleware(isActive func(sessionID primitive.ObjectID) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsValue, exists := c.Get("user_claims")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		claims, ok := claimsValue.(*Claims)
+		if !ok || claims.SessionID == "" {
+			c.Next()
+			return
+		}
+
+		sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+		if err != nil || !isActive(sessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "session has been revoked",
+				"code":  "SESSION_REVOKED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("session_id", sessionID)
+		c.Next()
+	}
+}
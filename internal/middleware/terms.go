@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TermsMiddleware blocks writes by users who have not accepted the current
+// terms version, so a changed policy must be accepted before they continue.
+// Reads stay available, as do the routes in exempt (full route paths) such
+// as the accept endpoint itself. It must run after the auth middleware and
+// before ProfileMiddleware so it checks the parent account.
+func TermsMiddleware(version string, hasAccepted func(userID primitive.ObjectID) (bool, error), exempt ...string) gin.HandlerFunc {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if version == "" || IsDemo(c) || exemptPaths[c.FullPath()] {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		userIDValue, exists := c.Get("user_id")
+		if !exists {
+			c.Next()
+			return
+		}
+		userID, ok := userIDValue.(primitive.ObjectID)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+			c.Abort()
+			return
+		}
+
+		accepted, err := hasAccepted(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check terms acceptance"})
+			c.Abort()
+			return
+		}
+		if !accepted {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "The terms have changed; accept them to continue",
+				"code":          "TERMS_NOT_ACCEPTED",
+				"terms_version": version,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ThrottleMiddleware applies the stricter limiter to writes by users the
+// anomaly detector throttled. Reads are left alone so a wrongly flagged user
+// can still use the app. It must run after the profile middleware, and a
+// kids profile is throttled along with its parent account.
+func ThrottleMiddleware(isThrottled func(userID primitive.ObjectID) bool, limiter *RateLimiter) gin.HandlerFunc {
+	limit := RateLimitMiddleware(limiter)
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		for _, key := range []string{"user_id", "account_id"} {
+			value, exists := c.Get(key)
+			if !exists {
+				continue
+			}
+			if userID, ok := value.(primitive.ObjectID); ok && isThrottled(userID) {
+				limit(c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -11,6 +14,50 @@ type User struct {
 	Username  string            `bson:"username" json:"username"`
 	Email     string            `bson:"email" json:"email"`
 	Password  string            `bson:"password" json:"-"`
+	LastActiveAt *time.Time      `bson:"last_active_at,omitempty" json:"last_active_at,omitempty"`
+	// Preferred languages, most preferred first, as English language names
+	AudioLanguages    []string `bson:"audio_languages,omitempty" json:"audio_languages,omitempty"`
+	SubtitleLanguages []string `bson:"subtitle_languages,omitempty" json:"subtitle_languages,omitempty"`
+	// RecommendationSettings is nil until the user changes the defaults;
+	// RecommendationsRefreshedAt is when scheduled recommendations last ran
+	RecommendationSettings     *RecommendationSettings `bson:"recommendation_settings,omitempty" json:"recommendation_settings,omitempty"`
+	RecommendationsRefreshedAt *time.Time              `bson:"recommendations_refreshed_at,omitempty" json:"-"`
+	// Timezone is the user's IANA timezone, e.g. "Europe/Berlin"; empty means
+	// UTC. Kids profiles use their account's timezone.
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	// WatchGoals is nil until the user sets a goal; GoalMetMonth is the last
+	// month ("2006-01") whose goal was celebrated
+	WatchGoals   *WatchGoals `bson:"watch_goals,omitempty" json:"watch_goals,omitempty"`
+	GoalMetMonth string      `bson:"goal_met_month,omitempty" json:"-"`
+	// AchievementsPublic shows the user's badges at /users/{username}/achievements
+	AchievementsPublic bool `bson:"achievements_public,omitempty" json:"achievements_public"`
+	// CompatibilityPublic lets other users compare their taste with this
+	// user's at /users/{username}/compatibility
+	CompatibilityPublic bool `bson:"compatibility_public,omitempty" json:"compatibility_public"`
+	// RatingsPublic publishes the user's ratings as an RSS feed at
+	// /users/{username}/ratings.rss
+	RatingsPublic bool `bson:"ratings_public,omitempty" json:"ratings_public"`
+	// AvoidAdvisories are the content advisories the user wants left out of
+	// recommendations and warned about on movie details
+	AvoidAdvisories []string `bson:"avoid_advisories,omitempty" json:"avoid_advisories,omitempty"`
+	// DemoExpiresAt is set on demo sandbox users, which are deleted with
+	// their data once it passes
+	DemoExpiresAt *time.Time `bson:"demo_expires_at,omitempty" json:"demo_expires_at,omitempty"`
+	// TermsVersion is the terms of service version the user last accepted
+	TermsVersion    string     `bson:"terms_version,omitempty" json:"-"`
+	TermsAcceptedAt *time.Time `bson:"terms_accepted_at,omitempty" json:"-"`
+	// DeactivatedAt is set while the user has deactivated the account; it is
+	// deleted with its data unless the user logs in again in time
+	DeactivatedAt *time.Time `bson:"deactivated_at,omitempty" json:"-"`
+	// StorageQuota holds the limits an admin set for this user in place of
+	// the configured defaults
+	StorageQuota *StorageQuota `bson:"storage_quota,omitempty" json:"-"`
+	// CalendarFeedTokenHash is the hash of the secret token in the user's
+	// calendar feed URL; empty while the feed is off
+	CalendarFeedTokenHash string `bson:"calendar_feed_token_hash,omitempty" json:"-"`
+	// InboundEmailTokenHash is the hash of the secret local part of the
+	// user's inbound email address; empty while email-in is off
+	InboundEmailTokenHash string `bson:"inbound_email_token_hash,omitempty" json:"-"`
 	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
 }
@@ -22,29 +69,949 @@ type Movie struct {
 	Year        string            `bson:"year" json:"year"`
 	Genre       string            `bson:"genre" json:"genre"`
 	Director    string            `bson:"director" json:"director"`
+	// Actors lists the top-billed cast, comma-separated like Director
+	Actors      string            `bson:"actors,omitempty" json:"actors,omitempty"`
 	Plot        string            `bson:"plot" json:"plot"`
 	Poster      string            `bson:"poster" json:"poster"`
+	// PosterBroken is set once the provider's poster URL stopped resolving;
+	// Poster then points at a generated placeholder and BrokenPoster keeps
+	// the dead URL. PosterCheckedAt is when the link was last checked.
+	PosterBroken    bool          `bson:"poster_broken,omitempty" json:"-"`
+	BrokenPoster    string        `bson:"broken_poster,omitempty" json:"-"`
+	PosterCheckedAt *time.Time    `bson:"poster_checked_at,omitempty" json:"-"`
 	Runtime     string            `bson:"runtime" json:"runtime"`
 	IMDbRating  string            `bson:"imdb_rating" json:"imdb_rating"`
+	// Language lists the spoken languages reported by OMDb, comma-separated
+	Language    string            `bson:"language,omitempty" json:"language,omitempty"`
+	// Rated is the OMDb certification, e.g. PG-13; used to cap kids profiles
+	Rated       string            `bson:"rated,omitempty" json:"rated,omitempty"`
+	// Numeric copies of Year and IMDbRating for indexed filtering and sorting;
+	// YearEnd is 0 for series that are still running
+	YearStart   int               `bson:"year_start" json:"year_start,omitempty"`
+	YearEnd     int               `bson:"year_end" json:"year_end,omitempty"`
+	IMDbScore   float64           `bson:"imdb_score" json:"-"`
+	// Keywords are themes such as "heist" or "time-loop" tagged from the
+	// plot; KeywordsSource names the extractor that produced them
+	Keywords       []string       `bson:"keywords,omitempty" json:"keywords,omitempty"`
+	KeywordsSource string         `bson:"keywords_source,omitempty" json:"-"`
+	// Advisories are sensitive themes such as "violence" tagged by the
+	// advisory provider, named by AdvisoriesSource. ReportedAdvisories were
+	// reported by users and approved by an admin.
+	Advisories         []string `bson:"advisories,omitempty" json:"advisories,omitempty"`
+	AdvisoriesSource   string   `bson:"advisories_source,omitempty" json:"-"`
+	ReportedAdvisories []string `bson:"reported_advisories,omitempty" json:"reported_advisories,omitempty"`
+	Source      string            `bson:"source,omitempty" json:"source,omitempty"`
+	// DuplicateOf points at the canonical movie when this document was written
+	// with a legacy spelling of an IMDb ID another movie already holds
+	DuplicateOf *primitive.ObjectID `bson:"duplicate_of,omitempty" json:"-"`
+	// MetadataProvider names the provider that served the cached details;
+	// CachedAt is when they were fetched
+	MetadataProvider string       `bson:"metadata_provider,omitempty" json:"metadata_provider,omitempty"`
 	CachedAt    time.Time         `bson:"cached_at" json:"cached_at"`
+	// ReconciledAt is when the details were last cross-checked against a
+	// secondary provider
+	ReconciledAt *time.Time       `bson:"reconciled_at,omitempty" json:"-"`
 	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time         `bson:"updated_at" json:"updated_at"`
+	// Freshness is filled in on single-movie responses and never stored
+	Freshness *MetadataFreshness `bson:"-" json:"freshness,omitempty"`
+	// RecommendedBy names the recommender that suggested the movie; it is
+	// only set on recommendation results and never stored
+	RecommendedBy string `bson:"-" json:"-"`
 }
 
+// MetadataFreshness tells clients where a movie's details came from and how old they are
+type MetadataFreshness struct {
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Stale     bool      `json:"stale"`
+}
+
+// HasDetails reports whether the full OMDb details have been cached; movies
+// added straight from a search result start as stubs without a genre
+func (m *Movie) HasDetails() bool {
+	return m.Genre != ""
+}
+
+// Movie sources record which provider created a movie document
+const (
+	MovieSourceOMDb = "omdb"
+	MovieSourceTMDb = "tmdb"
+	MovieSourceSeed = "seed"
+)
+
+// NormalizeIMDbID returns the canonical spelling of an IMDb ID. Providers
+// differ in case and padding whitespace, and movies are deduplicated on it.
+func NormalizeIMDbID(imdbID string) string {
+	return strings.ToLower(strings.TrimSpace(imdbID))
+}
+
+// ParseYearRange parses OMDb years such as "1994", "2008–2013" or "2008–"
+// (still running). It returns zeros when the year is unknown.
+func ParseYearRange(year string) (start, end int) {
+	year = strings.TrimSpace(year)
+	parts := strings.FieldsFunc(year, func(r rune) bool { return r == '–' || r == '-' })
+	if len(parts) == 0 {
+		return 0, 0
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || start <= 0 {
+		return 0, 0
+	}
+	if len(parts) == 1 {
+		// A trailing dash marks a series that has not ended
+		if strings.HasSuffix(year, "–") || strings.HasSuffix(year, "-") {
+			return start, 0
+		}
+		return start, start
+	}
+
+	end, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || end < start {
+		return start, 0
+	}
+	return start, end
+}
+
+// ParseIMDbScore parses an OMDb rating such as "8.7", returning 0 for "N/A"
+func ParseIMDbScore(rating string) float64 {
+	score, err := strconv.ParseFloat(strings.TrimSpace(rating), 64)
+	if err != nil || score < 0 {
+		return 0
+	}
+	return score
+}
+
+// DeriveNumericFields fills YearStart, YearEnd and IMDbScore from the OMDb strings
+func (m *Movie) DeriveNumericFields() {
+	m.YearStart, m.YearEnd = ParseYearRange(m.Year)
+	m.IMDbScore = ParseIMDbScore(m.IMDbRating)
+}
+
+// RuntimeMinutes parses the OMDb runtime ("148 min"), returning 0 when unknown
+func (m *Movie) RuntimeMinutes() int {
+	minutes, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(m.Runtime, "min")))
+	if err != nil || minutes < 0 {
+		return 0
+	}
+	return minutes
+}
+
+// Watchlist priorities; entries created before priorities existed have none
+// and are treated as DefaultWatchlistPriority
+const (
+	MinWatchlistPriority     = 1
+	MaxWatchlistPriority     = 5
+	DefaultWatchlistPriority = 3
+)
+
 type Watchlist struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
 	MovieID   primitive.ObjectID `bson:"movie_id" json:"movie_id"`
 	AddedAt   time.Time         `bson:"added_at" json:"added_at"`
+	WatchedAt *time.Time        `bson:"watched_at,omitempty" json:"watched_at,omitempty"`
+	Priority  int               `bson:"priority,omitempty" json:"priority"`
+	// Source is how the entry was added; entries added before sources were
+	// recorded, or without one, have none
+	Source    *WatchlistSource  `bson:"source,omitempty" json:"source,omitempty"`
+	// Note is text kept with the entry, such as the email it was added from
+	Note      string            `bson:"note,omitempty" json:"note,omitempty"`
+	// Version increases on every change, for If-Match checks on updates
+	Version   int               `bson:"version" json:"version"`
 	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
 }
 
+// WatchlistSource is the discovery surface a watchlist entry was added from.
+// Detail narrows it down, e.g. the recommendation row or the friend's username.
+type WatchlistSource struct {
+	Type   string `bson:"type" json:"type"`
+	Detail string `bson:"detail,omitempty" json:"detail,omitempty"`
+}
+
+// Watchlist source types. Clients report the surface they add from; import,
+// demo, extension, chat, email and assistant are set by the server.
+const (
+	WatchlistSourceSearch         = "search"
+	WatchlistSourceBrowse         = "browse"
+	WatchlistSourceDiscover       = "discover"
+	WatchlistSourceRecommendation = "recommendation"
+	WatchlistSourceSimilar        = "similar"
+	WatchlistSourceCalendar       = "calendar"
+	WatchlistSourceSharedList     = "shared_list"
+	WatchlistSourceProfile        = "profile"
+	WatchlistSourceFriend         = "friend"
+	WatchlistSourceImport         = "import"
+	WatchlistSourceDemo           = "demo"
+	WatchlistSourceExtension      = "extension"
+	WatchlistSourceChat           = "chat"
+	WatchlistSourceEmail          = "email"
+	WatchlistSourceAssistant      = "assistant"
+)
+
+// EffectivePriority returns the entry's priority, defaulting unset priorities
+func (w *Watchlist) EffectivePriority() int {
+	if w.Priority == 0 {
+		return DefaultWatchlistPriority
+	}
+	return w.Priority
+}
+
 type Rating struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
 	MovieID   primitive.ObjectID `bson:"movie_id" json:"movie_id"`
 	Rating    int               `bson:"rating" json:"rating"` // Changed to int for 1-5 star system
+	// Version increases on every change, for If-Match checks on updates
+	Version   int               `bson:"version" json:"version"`
 	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
 }
+
+// OMDbUsage tracks the number of OMDb API requests made on a given UTC day
+type OMDbUsage struct {
+	Date      string    `bson:"_id" json:"date"`
+	Count     int64     `bson:"count" json:"count"`
+	Errors    int64     `bson:"errors" json:"errors"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Job statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead"
+)
+
+// Job is a persisted background job; failed attempts are retried until
+// MaxAttempts is reached, after which the job is moved to the dead letter state
+type Job struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Type        string                 `bson:"type" json:"type"`
+	Payload     map[string]interface{} `bson:"payload" json:"payload"`
+	Status      string                 `bson:"status" json:"status"`
+	Attempts    int                    `bson:"attempts" json:"attempts"`
+	MaxAttempts int                    `bson:"max_attempts" json:"max_attempts"`
+	LastError   string                 `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	RunAt       time.Time              `bson:"run_at" json:"run_at"`
+	LockedUntil time.Time              `bson:"locked_until,omitempty" json:"-"`
+	CompletedAt *time.Time             `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt   time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// UserActivity marks a user as active on a given UTC day
+type UserActivity struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Day        string             `bson:"day" json:"day"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+}
+
+// Session is a device login backed by a refresh token. Only a hash of the
+// refresh token is stored.
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID `bson:"user_id" json:"-"`
+	RefreshTokenHash string             `bson:"refresh_token_hash" json:"-"`
+	UserAgent        string             `bson:"user_agent" json:"user_agent"`
+	IP               string             `bson:"ip" json:"ip"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt       time.Time          `bson:"last_used_at" json:"last_used_at"`
+	ExpiresAt        time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt        *time.Time         `bson:"revoked_at,omitempty" json:"-"`
+	// ClientID and Scopes are set when a third-party app holds the session,
+	// whose access tokens are then limited to the scopes
+	ClientID string   `bson:"client_id,omitempty" json:"client_id,omitempty"`
+	Scopes   []string `bson:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// OAuthApp is a third-party application registered by a user to act on
+// the API through OAuth 2.0. Only a hash of its client secret is stored.
+type OAuthApp struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID primitive.ObjectID `bson:"user_id" json:"-"`
+	Name   string             `bson:"name" json:"name"`
+	// ClientID is public and identifies the app at the token endpoint
+	ClientID         string   `bson:"client_id" json:"client_id"`
+	ClientSecretHash string   `bson:"client_secret_hash" json:"-"`
+	RedirectURIs     []string `bson:"redirect_uris" json:"redirect_uris"`
+	// Scopes are the most the app may ask users for
+	Scopes    []string  `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// OAuth scopes a third-party app can be granted
+const (
+	ScopeWatchlistRead       = "watchlist:read"
+	ScopeWatchlistWrite      = "watchlist:write"
+	ScopeRatingsRead         = "ratings:read"
+	ScopeRatingsWrite        = "ratings:write"
+	ScopeRecommendationsRead = "recommendations:read"
+)
+
+// OAuthCode is an authorization code a user granted an app, exchanged once
+// at the token endpoint. Only its hash is stored.
+type OAuthCode struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	CodeHash    string             `bson:"code_hash"`
+	ClientID    string             `bson:"client_id"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	RedirectURI string             `bson:"redirect_uri"`
+	Scopes      []string           `bson:"scopes"`
+	// CodeChallenge is the PKCE S256 challenge, empty without PKCE
+	CodeChallenge string    `bson:"code_challenge,omitempty"`
+	ExpiresAt     time.Time `bson:"expires_at"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+// EmailChange is a pending email address change awaiting confirmation from
+// the new address. Only a hash of the confirmation token is stored.
+type EmailChange struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	OldEmail  string             `bson:"old_email" json:"old_email"`
+	NewEmail  string             `bson:"new_email" json:"new_email"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// DevicePairing is a device, such as a TV app, waiting to be signed in by a
+// user who enters its code from a signed-in session. Only hashes of the
+// device's secret and the code are stored.
+type DevicePairing struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	DeviceCodeHash string             `bson:"device_code_hash" json:"-"`
+	UserCodeHash   string             `bson:"user_code_hash" json:"-"`
+	// DeviceName is the name the device gave itself, e.g. "Living room TV"
+	DeviceName string              `bson:"device_name,omitempty" json:"device_name,omitempty"`
+	UserAgent  string              `bson:"user_agent" json:"user_agent"`
+	IP         string              `bson:"ip" json:"ip"`
+	Status     string              `bson:"status" json:"status"`
+	UserID     *primitive.ObjectID `bson:"user_id,omitempty" json:"-"`
+	// LastPolledAt is when the device last asked whether it was approved
+	LastPolledAt *time.Time `bson:"last_polled_at,omitempty" json:"-"`
+	ExpiresAt    time.Time  `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// Device pairing statuses
+const (
+	DevicePairingPending  = "pending"
+	DevicePairingApproved = "approved"
+	DevicePairingDenied   = "denied"
+)
+
+// LoginAttempt is a login to an account, successful or not. Location is the
+// country reported by the proxy in front of the API when one is configured,
+// otherwise the network of the IP address. Successful logins from a location
+// not seen before carry a one-time link for revoking all sessions; only its
+// hash is stored.
+type LoginAttempt struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID            primitive.ObjectID `bson:"user_id" json:"-"`
+	Success           bool               `bson:"success" json:"success"`
+	IP                string             `bson:"ip" json:"ip"`
+	Location          string             `bson:"location" json:"location"`
+	UserAgent         string             `bson:"user_agent" json:"user_agent"`
+	DeviceFingerprint string             `bson:"device_fingerprint" json:"device_fingerprint"`
+	NewLocation       bool               `bson:"new_location" json:"new_location"`
+	NewDevice         bool               `bson:"new_device" json:"new_device"`
+	RevokeTokenHash   string             `bson:"revoke_token_hash,omitempty" json:"-"`
+	RevokeExpiresAt   *time.Time         `bson:"revoke_expires_at,omitempty" json:"-"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Kinds of deleted items that can be restored during the undo window
+const (
+	DeletedWatchlistEntry = "watchlist_entry"
+)
+
+// DeletedItem keeps a copy of a removed document for a short undo window.
+// Only a hash of the undo token is stored.
+type DeletedItem struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Kind      string             `bson:"kind" json:"kind"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	Document  bson.Raw           `bson:"document" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Notification types
+const (
+	NotificationRatingReminder  = "rating_reminder"
+	NotificationUpcomingRelease = "upcoming_release"
+	NotificationGoalMet         = "goal_met"
+	NotificationAchievement     = "achievement"
+	NotificationSuspiciousLogin = "suspicious_login"
+	NotificationWelcome         = "welcome"
+	NotificationMovieShared     = "movie_shared"
+	NotificationPollInvite      = "poll_invite"
+	NotificationEmailIn         = "email_in"
+)
+
+// StorageQuota overrides the configured storage limits for one user. A nil
+// limit keeps the configured default, and 0 lifts the limit.
+type StorageQuota struct {
+	MaxWatchlistEntries *int `bson:"max_watchlist_entries,omitempty" json:"max_watchlist_entries"`
+}
+
+// WatchGoals are a user's viewing targets
+type WatchGoals struct {
+	// MonthlyMovies is how many movies the user wants to watch per calendar month
+	MonthlyMovies int `bson:"monthly_movies" json:"monthly_movies"`
+}
+
+// Notification is an in-app message for a user
+type Notification struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"-"`
+	Type      string              `bson:"type" json:"type"`
+	Title     string              `bson:"title" json:"title"`
+	Message   string              `bson:"message" json:"message"`
+	MovieID   *primitive.ObjectID `bson:"movie_id,omitempty" json:"movie_id,omitempty"`
+	ReadAt    *time.Time          `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// WatchProgress is how far a user got into a movie, reported by playback clients
+type WatchProgress struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	MovieID        primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	MinutesWatched int                `bson:"minutes_watched" json:"minutes_watched"`
+	CompletedAt    *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// RecentView records the last time a user opened a movie's detail page
+type RecentView struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	MovieID  primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	ViewedAt time.Time          `bson:"viewed_at" json:"viewed_at"`
+}
+
+// Profile is a kids sub-profile of a parent account. It has no login of its
+// own: the parent's token selects it with the X-Profile-ID header, and its
+// watchlist, ratings and recommendations are stored under the profile's ID.
+type Profile struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ParentID         primitive.ObjectID `bson:"parent_id" json:"-"`
+	Name             string             `bson:"name" json:"name"`
+	MaxCertification string             `bson:"max_certification" json:"max_certification"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// UpcomingRelease is an announced movie found by the upcoming releases job.
+// FranchiseKey is the normalized title keyword it was found with.
+type UpcomingRelease struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MovieID      primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	IMDbID       string             `bson:"imdb_id" json:"imdb_id"`
+	Title        string             `bson:"title" json:"title"`
+	Directors    []string           `bson:"directors" json:"directors"`
+	Actors       []string           `bson:"actors,omitempty" json:"actors,omitempty"`
+	Poster       string             `bson:"poster" json:"poster"`
+	Rated        string             `bson:"rated,omitempty" json:"rated,omitempty"`
+	ReleaseDate  time.Time          `bson:"release_date" json:"release_date"`
+	FranchiseKey string             `bson:"franchise_key" json:"-"`
+	DiscoveredAt time.Time          `bson:"discovered_at" json:"discovered_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Roles a followed person can have in a movie's credits
+const (
+	PersonRoleDirector = "director"
+	PersonRoleActor    = "actor"
+)
+
+// PersonFollow is a director or actor a user follows. Name is spelled as in
+// the credits stored on movies, which are matched by exact name.
+type PersonFollow struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"-"`
+	Name      string             `bson:"name" json:"name"`
+	Role      string             `bson:"role" json:"role"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MovieShare is a movie one user recommended to another. It sits in the
+// recipient's inbox, apart from the algorithmic recommendations.
+type MovieShare struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// UserID is the recipient
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	SenderID   primitive.ObjectID `bson:"sender_id" json:"-"`
+	SenderName string             `bson:"sender_username" json:"from"`
+	MovieID    primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Note       string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	// Movie is filled in when listing the inbox
+	Movie *Movie `bson:"-" json:"movie,omitempty"`
+}
+
+// Review is a user's written review of a movie, one per user and movie.
+// HelpfulCount follows the review's votes so reviews can be sorted by it.
+type Review struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"-"`
+	Username     string             `bson:"username" json:"username"`
+	MovieID      primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Body         string             `bson:"body" json:"body"`
+	HelpfulCount int                `bson:"helpful_count" json:"helpful_count"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	// VotedHelpful is filled in for signed-in readers when listing
+	VotedHelpful *bool `bson:"-" json:"voted_helpful,omitempty"`
+}
+
+// ReviewVote is one user's "helpful" vote on a review
+type ReviewVote struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReviewID  primitive.ObjectID `bson:"review_id" json:"review_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReviewerStanding is a reviewer's line on the top reviewers board
+type ReviewerStanding struct {
+	UserID       primitive.ObjectID `bson:"_id" json:"-"`
+	Username     string             `bson:"username" json:"username"`
+	Reviews      int                `bson:"reviews" json:"reviews"`
+	HelpfulVotes int                `bson:"helpful_votes" json:"helpful_votes"`
+}
+
+// Poll is a movie night vote among invited users. Voters rank the movies
+// and the winner is found by instant runoff. Movies and invitees are fixed
+// once the poll is created.
+type Poll struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// UserID is the owner
+	UserID       primitive.ObjectID   `bson:"user_id" json:"-"`
+	OwnerName    string               `bson:"owner_username" json:"owner"`
+	Title        string               `bson:"title" json:"title"`
+	MovieIDs     []primitive.ObjectID `bson:"movie_ids" json:"movie_ids"`
+	Participants []PollParticipant    `bson:"participants" json:"participants"`
+	ClosesAt     *time.Time           `bson:"closes_at,omitempty" json:"closes_at,omitempty"`
+	ClosedAt     *time.Time           `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	// ScheduledFor is when the movie night starts; it puts the poll on the
+	// calendar feeds of its owner and participants
+	ScheduledFor *time.Time `bson:"scheduled_for,omitempty" json:"scheduled_for,omitempty"`
+	// ResultPostedAt is when the winner was posted to the owner's chat
+	// webhook, so it is posted once
+	ResultPostedAt *time.Time `bson:"result_posted_at,omitempty" json:"-"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at" json:"updated_at"`
+	// Closed is filled in when the poll is returned
+	Closed bool `bson:"-" json:"closed"`
+}
+
+// PollParticipant is a user invited to vote in a poll
+type PollParticipant struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"-"`
+	Username string             `bson:"username" json:"username"`
+}
+
+// IsClosed reports whether voting has ended, by hand or because the closing
+// time passed
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.ClosesAt != nil && !now.Before(*p.ClosesAt))
+}
+
+// PollBallot is one user's ranking of a poll's movies, most preferred first.
+// Movies left out are ranked below all the others.
+type PollBallot struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"-"`
+	PollID    primitive.ObjectID   `bson:"poll_id" json:"-"`
+	UserID    primitive.ObjectID   `bson:"user_id" json:"-"`
+	Ranking   []primitive.ObjectID `bson:"ranking" json:"ranking"`
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// Content advisories for sensitive themes
+const (
+	AdvisoryViolence       = "violence"
+	AdvisoryAnimalHarm     = "animal_harm"
+	AdvisoryFlashingLights = "flashing_lights"
+)
+
+// AdvisoryReport is a user's report that a movie has a content advisory.
+// Approved reports add the advisory to the movie.
+type AdvisoryReport struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	MovieID    primitive.ObjectID  `bson:"movie_id" json:"movie_id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Advisory   string              `bson:"advisory" json:"advisory"`
+	Status     string              `bson:"status" json:"status"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	ReviewedAt *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	ReviewedBy *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+}
+
+// Advisory report statuses
+const (
+	AdvisoryReportPending  = "pending"
+	AdvisoryReportApproved = "approved"
+	AdvisoryReportRejected = "rejected"
+)
+
+// TraktLink is a user's linked Trakt account. The OAuth tokens are stored
+// encrypted. While the user is on Trakt's consent screen the link only holds
+// the hashed OAuth state; it is linked once LinkedAt is set.
+type TraktLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"-"`
+	Username       string             `bson:"username,omitempty" json:"username,omitempty"`
+	AccessToken    string             `bson:"access_token,omitempty" json:"-"`
+	RefreshToken   string             `bson:"refresh_token,omitempty" json:"-"`
+	TokenExpiresAt time.Time          `bson:"token_expires_at,omitempty" json:"-"`
+	StateHash      string             `bson:"state_hash,omitempty" json:"-"`
+	StateExpiresAt *time.Time         `bson:"state_expires_at,omitempty" json:"-"`
+	// Push sends the user's new ratings and watchlist changes to Trakt
+	Push bool `bson:"push" json:"push"`
+	// OnConflict resolves imported entries that already exist with different
+	// values: skip, overwrite or merge, as for archive imports
+	OnConflict    string        `bson:"on_conflict" json:"on_conflict"`
+	LinkedAt      *time.Time    `bson:"linked_at,omitempty" json:"linked_at,omitempty"`
+	LastSync      *TraktSyncRun `bson:"last_sync,omitempty" json:"last_sync,omitempty"`
+	LastPushAt    *time.Time    `bson:"last_push_at,omitempty" json:"last_push_at,omitempty"`
+	LastPushError string        `bson:"last_push_error,omitempty" json:"last_push_error,omitempty"`
+	CreatedAt     time.Time     `bson:"created_at" json:"-"`
+	UpdatedAt     time.Time     `bson:"updated_at" json:"-"`
+}
+
+// TraktSyncRun is the state of the latest import from Trakt. Summary counts
+// the outcomes per list ("watchlist", "ratings") as in archive import reports.
+type TraktSyncRun struct {
+	Status  string                    `bson:"status" json:"status"`
+	At      time.Time                 `bson:"at" json:"at"`
+	Summary map[string]map[string]int `bson:"summary,omitempty" json:"summary,omitempty"`
+	Error   string                    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Trakt sync run statuses
+const (
+	TraktSyncQueued    = "queued"
+	TraktSyncRunning   = "running"
+	TraktSyncSucceeded = "succeeded"
+	TraktSyncFailed    = "failed"
+)
+
+// ChatWebhook is the Slack or Discord incoming webhook that a user's movie
+// night polls are posted to. The URL is a secret and stored encrypted.
+type ChatWebhook struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"-"`
+	Kind         string             `bson:"kind" json:"kind"`
+	URL          string             `bson:"url" json:"-"`
+	LastPostedAt *time.Time         `bson:"last_posted_at,omitempty" json:"last_posted_at,omitempty"`
+	LastError    string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Chat webhook kinds
+const (
+	ChatWebhookSlack   = "slack"
+	ChatWebhookDiscord = "discord"
+)
+
+// SlackLink connects a Slack user to an account so the slash command acts as
+// that account. Until the user types the link code in Slack it only holds
+// the code's hash; it is linked once LinkedAt is set.
+type SlackLink struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"-"`
+	CodeHash      string             `bson:"code_hash,omitempty" json:"-"`
+	CodeExpiresAt *time.Time         `bson:"code_expires_at,omitempty" json:"-"`
+	TeamID        string             `bson:"team_id,omitempty" json:"team_id,omitempty"`
+	SlackUserID   string             `bson:"slack_user_id,omitempty" json:"slack_user_id,omitempty"`
+	LinkedAt      *time.Time         `bson:"linked_at,omitempty" json:"linked_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"-"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"-"`
+}
+
+// TelegramLink connects a Telegram chat to an account so the bot acts as
+// that account and sends it the account's notifications. Until the user
+// starts the bot with the link code it only holds the code's hash; it is
+// linked once LinkedAt is set. NotifiedUntil is the creation time of the
+// last notification sent to the chat.
+type TelegramLink struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"-"`
+	CodeHash      string             `bson:"code_hash,omitempty" json:"-"`
+	CodeExpiresAt *time.Time         `bson:"code_expires_at,omitempty" json:"-"`
+	ChatID        int64              `bson:"chat_id,omitempty" json:"-"`
+	Username      string             `bson:"username,omitempty" json:"username,omitempty"`
+	Notify        bool               `bson:"notify" json:"notify"`
+	NotifiedUntil *time.Time         `bson:"notified_until,omitempty" json:"-"`
+	LinkedAt      *time.Time         `bson:"linked_at,omitempty" json:"linked_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"-"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"-"`
+}
+
+// Recommendation refresh frequencies
+const (
+	RecommendationFrequencyDaily    = "daily"
+	RecommendationFrequencyWeekly   = "weekly"
+	RecommendationFrequencyOnDemand = "on-demand"
+)
+
+// Recommendation rows a user can include in scheduled refreshes and digests
+const (
+	RecommendationRowForYou   = "for_you"
+	RecommendationRowTrending = "trending"
+	// RecommendationRowFollowing holds recent movies from followed people
+	RecommendationRowFollowing = "following"
+)
+
+// RecommendationSettings control when a user's recommendations are refreshed,
+// how many items each row holds and which rows the email digest includes
+type RecommendationSettings struct {
+	Frequency   string   `bson:"frequency" json:"frequency"`
+	Count       int      `bson:"count" json:"count"`
+	Rows        []string `bson:"rows" json:"rows"`
+	EmailDigest bool     `bson:"email_digest" json:"email_digest"`
+}
+
+// Achievement is a badge awarded to a user; a badge is awarded once
+type Achievement struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"-"`
+	Badge     string             `bson:"badge" json:"badge"`
+	AwardedAt time.Time          `bson:"awarded_at" json:"awarded_at"`
+}
+
+// MovieRevision records the fields one write changed on a movie and who
+// made it: "request", "job:<job type>" or "admin:<user id>"
+type MovieRevision struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MovieID   primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Changes   []FieldChange      `bson:"changes" json:"changes"`
+	ChangedBy string             `bson:"changed_by" json:"changed_by"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// FieldChange is one movie field before and after a write
+type FieldChange struct {
+	Field string `bson:"field" json:"field"`
+	Old   string `bson:"old" json:"old"`
+	New   string `bson:"new" json:"new"`
+}
+
+// Metadata conflict statuses
+const (
+	ConflictOpen       = "open"
+	ConflictAccepted   = "accepted"
+	ConflictOverridden = "overridden"
+)
+
+// MetadataConflict records where a movie's cached details disagree with a
+// secondary provider; there is at most one per movie. Accepting copies the
+// secondary values onto the movie, overriding keeps the cached ones.
+type MetadataConflict struct {
+	ID                primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	MovieID           primitive.ObjectID  `bson:"movie_id" json:"movie_id"`
+	IMDbID            string              `bson:"imdb_id" json:"imdb_id"`
+	Title             string              `bson:"title" json:"title"`
+	CachedProvider    string              `bson:"cached_provider" json:"cached_provider"`
+	SecondaryProvider string              `bson:"secondary_provider" json:"secondary_provider"`
+	Fields            []FieldConflict     `bson:"fields" json:"fields"`
+	Status            string              `bson:"status" json:"status"`
+	DetectedAt        time.Time           `bson:"detected_at" json:"detected_at"`
+	ResolvedAt        *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	ResolvedBy        *primitive.ObjectID `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+}
+
+// FieldConflict is one movie field with the cached and the secondary value
+type FieldConflict struct {
+	Field     string `bson:"field" json:"field"`
+	Cached    string `bson:"cached" json:"cached"`
+	Secondary string `bson:"secondary" json:"secondary"`
+}
+
+// Account flag reasons and statuses
+const (
+	FlagRatingBurst    = "rating_burst"
+	FlagWatchlistChurn = "watchlist_churn"
+
+	FlagOpen      = "open"
+	FlagDismissed = "dismissed"
+	FlagConfirmed = "confirmed"
+)
+
+// AccountFlag puts an account in the admin review queue after the anomaly
+// detector saw it act faster than people do. There is at most one open flag
+// per account (or kids profile) and reason.
+type AccountFlag struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Reason string             `bson:"reason" json:"reason"`
+	// Threshold is the number of actions per window that was exceeded, and
+	// Bursts how many windows exceeded it while the flag was open
+	Threshold   int       `bson:"threshold" json:"threshold"`
+	Bursts      int       `bson:"bursts" json:"bursts"`
+	Status      string    `bson:"status" json:"status"`
+	FlaggedAt   time.Time `bson:"flagged_at" json:"flagged_at"`
+	LastBurstAt time.Time `bson:"last_burst_at" json:"last_burst_at"`
+	// ThrottledUntil is set while the account's writes are rate limited
+	ThrottledUntil *time.Time          `bson:"throttled_until,omitempty" json:"throttled_until,omitempty"`
+	ReviewedAt     *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	ReviewedBy     *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+}
+
+// RecommendationSnapshot holds the rows precomputed for a user on a daily or
+// weekly schedule; there is at most one per user
+type RecommendationSnapshot struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"-"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"-"`
+	Rows       []RecommendationRow `bson:"rows" json:"rows"`
+	ComputedAt time.Time           `bson:"computed_at" json:"computed_at"`
+}
+
+// RecommendationRow is one named list of recommended movies, best first
+type RecommendationRow struct {
+	Name     string               `bson:"name" json:"name"`
+	MovieIDs []primitive.ObjectID `bson:"movie_ids" json:"movie_ids"`
+	// Algorithms names the recommender of each movie, in MovieIDs order
+	Algorithms []string `bson:"algorithms,omitempty" json:"-"`
+}
+
+// RecommendationEvaluation is the result of one offline evaluation run: each
+// user's latest ratings were held out and the recommenders were scored on how
+// many of the held-out movies rated 4+ stars they would have suggested.
+type RecommendationEvaluation struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	HoldoutFraction float64            `bson:"holdout_fraction" json:"holdout_fraction"`
+	// Users is how many users had held-out movies they liked to score against
+	Users      int                `bson:"users" json:"users"`
+	Algorithms []AlgorithmMetrics `bson:"algorithms" json:"algorithms"`
+	StartedAt  time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt time.Time          `bson:"finished_at" json:"finished_at"`
+}
+
+// AlgorithmMetrics are one recommender's precision and recall averaged over users
+type AlgorithmMetrics struct {
+	Algorithm string     `bson:"algorithm" json:"algorithm"`
+	AtK       []KMetrics `bson:"at_k" json:"at_k"`
+}
+
+// KMetrics are precision@k and recall@k for one cut-off k
+type KMetrics struct {
+	K         int     `bson:"k" json:"k"`
+	Precision float64 `bson:"precision" json:"precision"`
+	Recall    float64 `bson:"recall" json:"recall"`
+}
+
+// MovieEmbedding is the vector embedding of a movie's plot under one embedding
+// model. PlotHash detects plots that changed since they were embedded.
+type MovieEmbedding struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MovieID   primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Model     string             `bson:"model" json:"model"`
+	Vector    []float32          `bson:"vector" json:"-"`
+	PlotHash  string             `bson:"plot_hash" json:"-"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// RecommendationImpression is a recommended movie shown to a user, recorded
+// at most once per user, movie and row a day, and when the user went on to
+// add it to their watchlist or rate it
+type RecommendationImpression struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	MovieID       primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Row           string             `bson:"row" json:"row"`
+	Algorithm     string             `bson:"algorithm" json:"algorithm"`
+	Genres        []string           `bson:"genres" json:"genres"`
+	Position      int                `bson:"position" json:"position"`
+	// Day is the UTC date shown, e.g. "2024-05-01"
+	Day           string             `bson:"day" json:"day"`
+	ShownAt       time.Time          `bson:"shown_at" json:"shown_at"`
+	WatchlistedAt *time.Time         `bson:"watchlisted_at,omitempty" json:"watchlisted_at,omitempty"`
+	RatedAt       *time.Time         `bson:"rated_at,omitempty" json:"rated_at,omitempty"`
+}
+
+// OutboxEvent is a domain event waiting to be streamed to the external
+// broker, or delivered already. LockedUntil holds the event while a relay
+// sends it and, after a failure, until the next attempt.
+type OutboxEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"`
+	Key         string             `bson:"key" json:"key"`
+	// Payload is the JSON envelope sent to the broker
+	Payload     string             `bson:"payload" json:"payload"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	LastError   string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	DeliveredAt *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+// Dashboard is a user's home screen summary. It is a read model rebuilt by
+// event consumers whenever the user's watchlist, ratings or recommendations
+// change, so serving it is a single read.
+type Dashboard struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID primitive.ObjectID `bson:"user_id" json:"-"`
+	// Version is the layout the document was built with; documents of an
+	// older layout are rebuilt when read
+	Version        int               `bson:"version" json:"-"`
+	WatchlistCount int64             `bson:"watchlist_count" json:"watchlist_count"`
+	WatchedCount   int64             `bson:"watched_count" json:"watched_count"`
+	RatingCount    int64             `bson:"rating_count" json:"rating_count"`
+	TopGenres      []GenreCount      `bson:"top_genres" json:"top_genres"`
+	// NextUp are unwatched watchlist entries, highest priority and oldest first
+	NextUp          []DashboardMovie `bson:"next_up" json:"next_up"`
+	Recommendations []DashboardMovie `bson:"recommendations" json:"recommendations"`
+	// RecommendationsAt is when Recommendations were last computed
+	RecommendationsAt *time.Time `bson:"recommendations_at,omitempty" json:"recommendations_at"`
+	UpdatedAt         time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// GenreCount is how many of a user's movies have a genre
+type GenreCount struct {
+	Genre string `bson:"genre" json:"genre"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// DashboardMovie is a movie on the dashboard with what its card shows
+type DashboardMovie struct {
+	MovieID  primitive.ObjectID `bson:"movie_id" json:"movie_id"`
+	Title    string             `bson:"title" json:"title"`
+	Year     string             `bson:"year" json:"year"`
+	Poster   string             `bson:"poster" json:"poster"`
+	Priority int                `bson:"priority,omitempty" json:"priority,omitempty"`
+	AddedAt  *time.Time         `bson:"added_at,omitempty" json:"added_at,omitempty"`
+}
+
+// RouteDeprecation announces that a route is being retired. Which routes can
+// be deprecated, and their successors, come from a registry in code; this
+// stores the notice an admin turned on for one of them.
+type RouteDeprecation struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Method string             `bson:"method" json:"method"`
+	// Path is the route as registered, e.g. /api/v1/watchlist/:movieId
+	Path string `bson:"path" json:"path"`
+	// Successor is the route that replaces it, from the registry
+	Successor string `bson:"-" json:"successor,omitempty"`
+	Enabled   bool   `bson:"enabled" json:"enabled"`
+	// DeprecatedAt is when the route was first announced as deprecated
+	DeprecatedAt *time.Time `bson:"deprecated_at,omitempty" json:"deprecated_at,omitempty"`
+	// SunsetAt is when the route stops working; it answers 410 after that
+	SunsetAt  *time.Time          `bson:"sunset_at,omitempty" json:"sunset_at,omitempty"`
+	Message   string              `bson:"message,omitempty" json:"message,omitempty"`
+	UpdatedBy *primitive.ObjectID `bson:"updated_by,omitempty" json:"-"`
+	UpdatedAt *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
@@ -0,0 +1,74 @@
+// Package pagination implements keyset (cursor) pagination for collections
+// too large for skip/limit. Pages are ordered by _id, which every collection
+// indexes, and a page resumes after the last _id of the previous one, so the
+// cost of a page does not grow with its position.
+package pagination
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+)
+
+// Cursor is the position and size of a keyset page
+type Cursor struct {
+	// After is the _id the page starts after, or nil for the first page
+	After *primitive.ObjectID
+	Limit int
+}
+
+// Parse reads the after and limit query values; either may be empty
+func Parse(after, limit string) (Cursor, error) {
+	cursor := Cursor{Limit: DefaultLimit}
+
+	if after != "" {
+		id, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return cursor, fmt.Errorf("after must be an ID from a previous page")
+		}
+		cursor.After = &id
+	}
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return cursor, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		cursor.Limit = n
+	}
+
+	return cursor, nil
+}
+
+// Filter narrows filter to documents after the cursor
+func (c Cursor) Filter(filter bson.M) bson.M {
+	if c.After != nil {
+		filter["_id"] = bson.M{"$gt": *c.After}
+	}
+	return filter
+}
+
+// FindOptions sorts by _id and fetches one document more than the limit, so
+// that Trim can tell whether another page follows
+func (c Cursor) FindOptions() *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(c.Limit + 1))
+}
+
+// Trim returns how many of the fetched documents belong to the page and
+// whether more follow it
+func (c Cursor) Trim(fetched int) (int, bool) {
+	if fetched > c.Limit {
+		return c.Limit, true
+	}
+	return fetched, false
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountFlagRepository stores the accounts the anomaly detector flagged for
+// admin review
+type AccountFlagRepository struct {
+	db *database.MongoDB
+}
+
+func NewAccountFlagRepository(db *database.MongoDB) *AccountFlagRepository {
+	return &AccountFlagRepository{db: db}
+}
+
+func (r *AccountFlagRepository) FindByID(id primitive.ObjectID) (*models.AccountFlag, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("account_flags")
+
+	var flag models.AccountFlag
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&flag)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// Raise records a burst against the user's open flag for reason, opening one
+// when there is none. A throttledUntil later than the flag's extends its
+// throttle.
+func (r *AccountFlagRepository) Raise(userID primitive.ObjectID, reason string, threshold int, at time.Time, throttledUntil *time.Time) (*models.AccountFlag, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("account_flags")
+
+	update := bson.M{
+		"$set":         bson.M{"threshold": threshold, "last_burst_at": at},
+		"$inc":         bson.M{"bursts": 1},
+		"$setOnInsert": bson.M{"flagged_at": at},
+	}
+	if throttledUntil != nil {
+		update["$max"] = bson.M{"throttled_until": *throttledUntil}
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var flag models.AccountFlag
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": userID, "reason": reason, "status": models.FlagOpen},
+		update, opts,
+	).Decode(&flag)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// Review closes an open flag with the given status, reporting false when it
+// is not open (any more). Dismissing a flag also lifts its throttle.
+func (r *AccountFlagRepository) Review(id primitive.ObjectID, status string, by primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("account_flags")
+
+	update := bson.M{"$set": bson.M{"status": status, "reviewed_at": at, "reviewed_by": by}}
+	if status == models.FlagDismissed {
+		update["$unset"] = bson.M{"throttled_until": ""}
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "status": models.FlagOpen}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindThrottled returns the flags whose throttle is still running at now
+func (r *AccountFlagRepository) FindThrottled(now time.Time) ([]models.AccountFlag, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("account_flags")
+
+	cursor, err := collection.Find(ctx, bson.M{"throttled_until": bson.M{"$gt": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	flags := []models.AccountFlag{}
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// List returns a cursor page of flags with the given status, or of all flags
+// when status is empty
+func (r *AccountFlagRepository) List(status string, cursor pagination.Cursor) ([]models.AccountFlag, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("account_flags")
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := collection.Find(ctx, cursor.Filter(filter), cursor.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	flags := []models.AccountFlag{}
+	if err := cur.All(ctx, &flags); err != nil {
+		return nil, false, err
+	}
+
+	n, more := cursor.Trim(len(flags))
+	return flags[:n], more, nil
+}
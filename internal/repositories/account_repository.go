@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountRepository finds and deletes users whose deactivation grace period ended
+type AccountRepository struct {
+	db *database.MongoDB
+}
+
+func NewAccountRepository(db *database.MongoDB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// FindDeactivatedBefore returns up to limit users deactivated before cutoff
+func (r *AccountRepository) FindDeactivatedBefore(cutoff time.Time, limit int64) ([]primitive.ObjectID, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"deactivated_at": bson.M{"$lte": cutoff}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// DeleteDeactivatedUser removes a user deactivated before cutoff with its
+// profiles and all their data
+func (r *AccountRepository) DeleteDeactivatedUser(userID primitive.ObjectID, cutoff time.Time) error {
+	// A user who logged in again since being listed must be kept
+	guard := bson.M{"deactivated_at": bson.M{"$lte": cutoff}}
+	count, err := r.db.GetCollection("users").CountDocuments(context.Background(), bson.M{"_id": userID, "deactivated_at": guard["deactivated_at"]})
+	if err != nil || count == 0 {
+		return err
+	}
+	return deleteUserCascade(r.db, userID, guard)
+}
+
+// deleteUserCascade removes a user with its profiles and all their data. The
+// user document goes last, and only while it still matches guard, so an
+// interrupted delete is picked up again.
+func deleteUserCascade(db *database.MongoDB, userID primitive.ObjectID, guard bson.M) error {
+	ctx := context.Background()
+
+	owners := []primitive.ObjectID{userID}
+	cursor, err := db.GetCollection("profiles").Find(ctx, bson.M{"parent_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var profiles []models.Profile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		owners = append(owners, profile.ID)
+	}
+
+	for _, name := range profileScopedCollections {
+		if _, err := db.GetCollection(name).DeleteMany(ctx, bson.M{"user_id": bson.M{"$in": owners}}); err != nil {
+			return err
+		}
+	}
+	// Ballots in the user's own polls go before the polls themselves
+	cursor, err = db.GetCollection("polls").Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var polls []models.Poll
+	if err := cursor.All(ctx, &polls); err != nil {
+		return err
+	}
+	pollIDs := make([]primitive.ObjectID, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+	if len(pollIDs) > 0 {
+		if _, err := db.GetCollection("poll_ballots").DeleteMany(ctx, bson.M{"poll_id": bson.M{"$in": pollIDs}}); err != nil {
+			return err
+		}
+	}
+
+	// Other users' sessions with the user's apps end with the apps
+	cursor, err = db.GetCollection("oauth_apps").Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"client_id": 1}))
+	if err != nil {
+		return err
+	}
+	var apps []models.OAuthApp
+	if err := cursor.All(ctx, &apps); err != nil {
+		return err
+	}
+	for _, app := range apps {
+		if _, err := db.GetCollection("sessions").DeleteMany(ctx, bson.M{"client_id": app.ClientID}); err != nil {
+			return err
+		}
+	}
+
+	// Helpful votes are taken back from the reviews they were cast on
+	if err := deleteUserReviews(ctx, db, userID); err != nil {
+		return err
+	}
+	for _, name := range accountScopedCollections {
+		if _, err := db.GetCollection(name).DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+			return err
+		}
+	}
+	if _, err := db.GetCollection("polls").UpdateMany(ctx,
+		bson.M{"participants.user_id": userID},
+		bson.M{"$pull": bson.M{"participants": bson.M{"user_id": userID}}},
+	); err != nil {
+		return err
+	}
+	// Movies the user shared leave the recipients' inboxes too
+	if _, err := db.GetCollection("movie_shares").DeleteMany(ctx, bson.M{"sender_id": userID}); err != nil {
+		return err
+	}
+	if _, err := db.GetCollection("profiles").DeleteMany(ctx, bson.M{"parent_id": userID}); err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": userID}
+	for key, value := range guard {
+		filter[key] = value
+	}
+	_, err = db.GetCollection("users").DeleteOne(ctx, filter)
+	return err
+}
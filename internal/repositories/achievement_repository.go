@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AchievementRepository struct {
+	db *database.MongoDB
+}
+
+func NewAchievementRepository(db *database.MongoDB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+// Award gives the user a badge, reporting false when they already had it
+func (r *AchievementRepository) Award(userID primitive.ObjectID, badge string, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("achievements")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "badge": badge},
+		bson.M{"$setOnInsert": bson.M{"awarded_at": at}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+// FindByUser returns the user's badges, oldest first
+func (r *AchievementRepository) FindByUser(userID primitive.ObjectID) ([]models.Achievement, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("achievements")
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "awarded_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var achievements []models.Achievement
+	if err := cursor.All(ctx, &achievements); err != nil {
+		return nil, err
+	}
+	return achievements, nil
+}
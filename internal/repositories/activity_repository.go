@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// activityDayFormat is the layout of UserActivity.Day
+const activityDayFormat = "2006-01-02"
+
+type ActivityRepository struct {
+	db *database.MongoDB
+}
+
+func NewActivityRepository(db *database.MongoDB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// RecordActivity marks the user active for the day of at and updates their last-active timestamp
+func (r *ActivityRepository) RecordActivity(userID primitive.ObjectID, at time.Time) error {
+	ctx := context.Background()
+
+	at = at.UTC()
+	_, err := r.db.GetCollection("user_activity").UpdateOne(ctx,
+		bson.M{"user_id": userID, "day": at.Format(activityDayFormat)},
+		bson.M{"$set": bson.M{"last_seen_at": at}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"last_active_at": at}},
+	)
+	return err
+}
+
+// FindSince returns all activity records from the given day (inclusive)
+func (r *ActivityRepository) FindSince(since time.Time) ([]models.UserActivity, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("user_activity")
+
+	findOptions := options.Find().SetProjection(bson.M{"user_id": 1, "day": 1})
+	cursor, err := collection.Find(ctx, bson.M{"day": bson.M{"$gte": since.UTC().Format(activityDayFormat)}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var activity []models.UserActivity
+	if err := cursor.All(ctx, &activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// FindActiveUserIDsSince returns the IDs of users active at or after since,
+// e.g. to limit precomputation work to users who are likely to come back
+func (r *ActivityRepository) FindActiveUserIDsSince(since time.Time) ([]primitive.ObjectID, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	ids, err := collection.Distinct(ctx, "_id", bson.M{"last_active_at": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			userIDs = append(userIDs, oid)
+		}
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AdvisoryReportRepository stores users' content advisory reports awaiting
+// or past moderation
+type AdvisoryReportRepository struct {
+	db *database.MongoDB
+}
+
+func NewAdvisoryReportRepository(db *database.MongoDB) *AdvisoryReportRepository {
+	return &AdvisoryReportRepository{db: db}
+}
+
+// Create stores a pending report, reporting false when the user already
+// reported the advisory for the movie
+func (r *AdvisoryReportRepository) Create(report *models.AdvisoryReport) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("advisory_reports")
+
+	report.Status = models.AdvisoryReportPending
+	stampCreated(report)
+	result, err := collection.InsertOne(ctx, report)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+func (r *AdvisoryReportRepository) FindByID(id primitive.ObjectID) (*models.AdvisoryReport, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("advisory_reports")
+
+	var report models.AdvisoryReport
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Review closes the pending reports of the advisory for the movie with the
+// given status, so duplicates of a reviewed report leave the queue with it.
+// It reports false when none were pending.
+func (r *AdvisoryReportRepository) Review(movieID primitive.ObjectID, advisory, status string, by primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("advisory_reports")
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"movie_id": movieID, "advisory": advisory, "status": models.AdvisoryReportPending},
+		bson.M{"$set": bson.M{"status": status, "reviewed_at": at, "reviewed_by": by}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// List returns a cursor page of reports with the given status, or of all
+// reports when status is empty
+func (r *AdvisoryReportRepository) List(status string, cursor pagination.Cursor) ([]models.AdvisoryReport, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("advisory_reports")
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := collection.Find(ctx, cursor.Filter(filter), cursor.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	reports := []models.AdvisoryReport{}
+	if err := cur.All(ctx, &reports); err != nil {
+		return nil, false, err
+	}
+
+	n, more := cursor.Trim(len(reports))
+	return reports[:n], more, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CalendarRepository struct {
+	db *database.MongoDB
+}
+
+func NewCalendarRepository(db *database.MongoDB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+// UpsertRelease stores a release by IMDb ID, reporting whether it is new
+func (r *CalendarRepository) UpsertRelease(release *models.UpcomingRelease) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("upcoming_releases")
+
+	now := getCurrentTime()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"imdb_id": release.IMDbID},
+		withUpdatedAt(bson.M{
+			"$set": bson.M{
+				"movie_id":      release.MovieID,
+				"title":         release.Title,
+				"directors":     release.Directors,
+				"actors":        release.Actors,
+				"poster":        release.Poster,
+				"rated":         release.Rated,
+				"release_date":  release.ReleaseDate,
+				"franchise_key": release.FranchiseKey,
+			},
+			"$setOnInsert": bson.M{"discovered_at": now},
+		}),
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+// FindUpcoming returns releases between from and to that belong to one of the
+// franchises, are directed by one of the directors or star one of the
+// actors, soonest first
+func (r *CalendarRepository) FindUpcoming(from, to time.Time, franchiseKeys, directors, actors []string) ([]models.UpcomingRelease, error) {
+	releases := []models.UpcomingRelease{}
+	if len(franchiseKeys) == 0 && len(directors) == 0 && len(actors) == 0 {
+		return releases, nil
+	}
+
+	ctx := context.Background()
+	collection := r.db.GetCollection("upcoming_releases")
+
+	filter := bson.M{
+		"release_date": bson.M{"$gte": from, "$lte": to},
+		"$or": bson.A{
+			bson.M{"franchise_key": bson.M{"$in": franchiseKeys}},
+			bson.M{"directors": bson.M{"$in": directors}},
+			bson.M{"actors": bson.M{"$in": actors}},
+		},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "release_date", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// FindByMovieIDs returns releases of the given movies between from and to,
+// soonest first
+func (r *CalendarRepository) FindByMovieIDs(movieIDs []primitive.ObjectID, from, to time.Time) ([]models.UpcomingRelease, error) {
+	releases := []models.UpcomingRelease{}
+	if len(movieIDs) == 0 {
+		return releases, nil
+	}
+
+	ctx := context.Background()
+	collection := r.db.GetCollection("upcoming_releases")
+
+	filter := bson.M{
+		"movie_id":     bson.M{"$in": movieIDs},
+		"release_date": bson.M{"$gte": from, "$lte": to},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "release_date", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
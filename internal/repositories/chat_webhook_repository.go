@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChatWebhookRepository stores users' chat webhooks, one per user
+type ChatWebhookRepository struct {
+	db *database.MongoDB
+}
+
+func NewChatWebhookRepository(db *database.MongoDB) *ChatWebhookRepository {
+	return &ChatWebhookRepository{db: db}
+}
+
+// Save stores the webhook with the given ID, creating it when it does not
+// exist. The URL must already be encrypted for that ID. A replaced webhook
+// loses the result of its last post.
+func (r *ChatWebhookRepository) Save(id, userID primitive.ObjectID, kind, url string) (*models.ChatWebhook, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("chat_webhooks")
+
+	var webhook models.ChatWebhook
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "user_id": userID},
+		withUpsertTimestamps(bson.M{
+			"$set":   bson.M{"kind": kind, "url": url},
+			"$unset": bson.M{"last_posted_at": "", "last_error": ""},
+		}),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *ChatWebhookRepository) FindByUser(userID primitive.ObjectID) (*models.ChatWebhook, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("chat_webhooks")
+
+	var webhook models.ChatWebhook
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// SetResult records when the webhook was last posted to and the error, if
+// any
+func (r *ChatWebhookRepository) SetResult(id primitive.ObjectID, at time.Time, postErr string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("chat_webhooks")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_posted_at": at, "last_error": postErr}})
+	return err
+}
+
+// Delete removes the user's webhook, reporting false when there was none
+func (r *ChatWebhookRepository) Delete(userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("chat_webhooks")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DashboardRepository stores the dashboard read model and runs the queries
+// it is built from
+type DashboardRepository struct {
+	db *database.MongoDB
+}
+
+func NewDashboardRepository(db *database.MongoDB) *DashboardRepository {
+	return &DashboardRepository{db: db}
+}
+
+// FindByUser returns the user's dashboard, or nil if none was built
+func (r *DashboardRepository) FindByUser(userID primitive.ObjectID) (*models.Dashboard, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("dashboards")
+
+	var dashboard models.Dashboard
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&dashboard)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dashboard, nil
+}
+
+// Upsert replaces the user's dashboard
+func (r *DashboardRepository) Upsert(dashboard *models.Dashboard) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("dashboards")
+
+	_, err := collection.ReplaceOne(ctx,
+		bson.M{"user_id": dashboard.UserID},
+		dashboard,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Delete removes the user's dashboard so the next read rebuilds it
+func (r *DashboardRepository) Delete(userID primitive.ObjectID) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("dashboards")
+
+	_, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// CountWatchlist returns how many movies are on the user's watchlist and how
+// many of them were watched
+func (r *DashboardRepository) CountWatchlist(userID primitive.ObjectID) (int64, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID}},
+		{"$group": bson.M{
+			"_id":   nil,
+			"total": bson.M{"$sum": 1},
+			"watched": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$watched_at", nil}}, 1, 0,
+			}}},
+		}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total   int64 `bson:"total"`
+		Watched int64 `bson:"watched"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, 0, err
+	}
+	if len(results) == 0 {
+		return 0, 0, nil
+	}
+	return results[0].Total, results[0].Watched, nil
+}
+
+// CountRatings returns how many movies the user rated
+func (r *DashboardRepository) CountRatings(userID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	return r.db.GetCollection("ratings").CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// CountWatchlistGenres counts the genres of the movies on the user's watchlist
+func (r *DashboardRepository) CountWatchlistGenres(userID primitive.ObjectID) ([]models.GenreCount, error) {
+	return r.countGenres("watchlists", bson.M{"user_id": userID})
+}
+
+// CountRatedGenres counts the genres of the movies the user rated at least
+// threshold stars
+func (r *DashboardRepository) CountRatedGenres(userID primitive.ObjectID, threshold int) ([]models.GenreCount, error) {
+	return r.countGenres("ratings", bson.M{"user_id": userID, "rating": bson.M{"$gte": threshold}})
+}
+
+// countGenres counts the genres of the movies referenced by the documents of
+// collection matching filter
+func (r *DashboardRepository) countGenres(collection string, filter bson.M) ([]models.GenreCount, error) {
+	ctx := context.Background()
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$project": bson.M{"genres": bson.M{"$split": bson.A{"$movie.genre", ","}}}},
+		{"$unwind": "$genres"},
+		{"$project": bson.M{"genre": bson.M{"$trim": bson.M{"input": "$genres"}}}},
+		{"$match": bson.M{"genre": bson.M{"$nin": bson.A{"", "N/A"}}}},
+		{"$group": bson.M{"_id": "$genre", "count": bson.M{"$sum": 1}}},
+		{"$project": bson.M{"_id": 0, "genre": "$_id", "count": 1}},
+	}
+
+	cursor, err := r.db.GetCollection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := []models.GenreCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// FindNextUp returns up to limit unwatched watchlist entries, highest
+// priority first and then oldest first. Entries without a priority count as
+// the default priority.
+func (r *DashboardRepository) FindNextUp(userID primitive.ObjectID, limit int64) ([]models.Watchlist, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID, "watched_at": nil}},
+		{"$addFields": bson.M{"priority": bson.M{"$ifNull": bson.A{"$priority", models.DefaultWatchlistPriority}}}},
+		{"$sort": bson.D{{Key: "priority", Value: -1}, {Key: "added_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{"$limit": limit},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.Watchlist
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DeletedItemRepository struct {
+	db *database.MongoDB
+}
+
+func NewDeletedItemRepository(db *database.MongoDB) *DeletedItemRepository {
+	return &DeletedItemRepository{db: db}
+}
+
+func (r *DeletedItemRepository) Create(item *models.DeletedItem) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("deleted_items")
+
+	stampCreated(item)
+	result, err := collection.InsertOne(ctx, item)
+	if err != nil {
+		return err
+	}
+
+	item.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Take returns and deletes the user's unexpired deleted item with the given
+// token hash, so that an undo token can only be redeemed once
+func (r *DeletedItemRepository) Take(userID primitive.ObjectID, tokenHash string) (*models.DeletedItem, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("deleted_items")
+
+	var item models.DeletedItem
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"user_id":    userID,
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": getCurrentTime()},
+	}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// accountScopedCollections hold data keyed by the account's user_id that is
+// not shared with its kids profiles
+var accountScopedCollections = []string{"sessions", "email_changes", "user_activity", "recommendation_snapshots", "deleted_items", "achievements", "login_attempts", "movie_shares", "polls", "poll_ballots", "advisory_reports", "trakt_links", "chat_webhooks", "slack_links", "telegram_links", "device_pairings", "oauth_apps", "oauth_codes"}
+
+// DemoRepository finds and deletes expired demo sandbox users
+type DemoRepository struct {
+	db *database.MongoDB
+}
+
+func NewDemoRepository(db *database.MongoDB) *DemoRepository {
+	return &DemoRepository{db: db}
+}
+
+// FindExpiredUserIDs returns up to limit demo users whose sandbox expired before now
+func (r *DemoRepository) FindExpiredUserIDs(now time.Time, limit int64) ([]primitive.ObjectID, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"demo_expires_at": bson.M{"$lte": now}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// DeleteUser removes a demo user with its profiles and all their data
+func (r *DemoRepository) DeleteUser(userID primitive.ObjectID) error {
+	// Only ever delete users that are still demo users
+	return deleteUserCascade(r.db, userID, bson.M{"demo_expires_at": bson.M{"$exists": true}})
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeprecationRepository stores the deprecation notices admins turned on
+type DeprecationRepository struct {
+	db *database.MongoDB
+}
+
+func NewDeprecationRepository(db *database.MongoDB) *DeprecationRepository {
+	return &DeprecationRepository{db: db}
+}
+
+func (r *DeprecationRepository) FindAll() ([]models.RouteDeprecation, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("route_deprecations")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deprecations []models.RouteDeprecation
+	if err := cursor.All(ctx, &deprecations); err != nil {
+		return nil, err
+	}
+	return deprecations, nil
+}
+
+// Upsert stores the notice for its method and path, replacing any earlier one
+func (r *DeprecationRepository) Upsert(deprecation *models.RouteDeprecation) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("route_deprecations")
+
+	now := getCurrentTime()
+	deprecation.UpdatedAt = &now
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"method": deprecation.Method, "path": deprecation.Path},
+		bson.M{"$set": bson.M{
+			"enabled":       deprecation.Enabled,
+			"deprecated_at": deprecation.DeprecatedAt,
+			"sunset_at":     deprecation.SunsetAt,
+			"message":       deprecation.Message,
+			"updated_by":    deprecation.UpdatedBy,
+			"updated_at":    deprecation.UpdatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
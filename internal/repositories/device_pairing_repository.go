@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DevicePairingRepository stores devices waiting to be signed in
+type DevicePairingRepository struct {
+	db *database.MongoDB
+}
+
+func NewDevicePairingRepository(db *database.MongoDB) *DevicePairingRepository {
+	return &DevicePairingRepository{db: db}
+}
+
+func (r *DevicePairingRepository) Create(pairing *models.DevicePairing) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	stampCreated(pairing)
+	result, err := collection.InsertOne(ctx, pairing)
+	if err != nil {
+		return err
+	}
+
+	pairing.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindPending returns the unexpired pairing awaiting approval whose code
+// hashes to userCodeHash
+func (r *DevicePairingRepository) FindPending(userCodeHash string, now time.Time) (*models.DevicePairing, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	var pairing models.DevicePairing
+	err := collection.FindOne(ctx, bson.M{
+		"user_code_hash": userCodeHash,
+		"status":         models.DevicePairingPending,
+		"expires_at":     bson.M{"$gt": now},
+	}).Decode(&pairing)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pairing, nil
+}
+
+// Decide approves or denies a pending pairing for the user, reporting
+// whether an unexpired pending pairing had the code
+func (r *DevicePairingRepository) Decide(userCodeHash string, userID primitive.ObjectID, status string, now time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{
+			"user_code_hash": userCodeHash,
+			"status":         models.DevicePairingPending,
+			"expires_at":     bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"status": status, "user_id": userID}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Poll records a poll of the unexpired pairing whose device code hashes to
+// deviceCodeHash and returns the pairing as it was before, so the caller
+// can tell when the device polled last
+func (r *DevicePairingRepository) Poll(deviceCodeHash string, now time.Time) (*models.DevicePairing, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	var pairing models.DevicePairing
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"device_code_hash": deviceCodeHash, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"last_polled_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&pairing)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pairing, nil
+}
+
+// TakeApproved returns and deletes the approved pairing with the given
+// device code hash, so that a pairing signs in only once
+func (r *DevicePairingRepository) TakeApproved(deviceCodeHash string) (*models.DevicePairing, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	var pairing models.DevicePairing
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"device_code_hash": deviceCodeHash,
+		"status":           models.DevicePairingApproved,
+	}).Decode(&pairing)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pairing, nil
+}
+
+func (r *DevicePairingRepository) Delete(id primitive.ObjectID) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("device_pairings")
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
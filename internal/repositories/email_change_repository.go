@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type EmailChangeRepository struct {
+	db *database.MongoDB
+}
+
+func NewEmailChangeRepository(db *database.MongoDB) *EmailChangeRepository {
+	return &EmailChangeRepository{db: db}
+}
+
+// Replace stores the pending change, discarding any earlier pending change of the user
+func (r *EmailChangeRepository) Replace(change *models.EmailChange) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("email_changes")
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": change.UserID}); err != nil {
+		return err
+	}
+
+	stampCreated(change)
+	result, err := collection.InsertOne(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	change.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Take returns and deletes the unexpired pending change with the given token hash,
+// so that a confirmation link can only be used once
+func (r *EmailChangeRepository) Take(tokenHash string) (*models.EmailChange, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("email_changes")
+
+	var change models.EmailChange
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": getCurrentTime()},
+	}).Decode(&change)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &change, nil
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VectorMatch is a movie found by similarity to a query vector
+type VectorMatch struct {
+	MovieID primitive.ObjectID `bson:"movie_id"`
+	Score   float64            `bson:"score"`
+}
+
+type EmbeddingRepository struct {
+	db *database.MongoDB
+}
+
+func NewEmbeddingRepository(db *database.MongoDB) *EmbeddingRepository {
+	return &EmbeddingRepository{db: db}
+}
+
+// Upsert stores the embedding of a movie under its model, replacing an older one
+func (r *EmbeddingRepository) Upsert(embedding *models.MovieEmbedding) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_embeddings")
+
+	embedding.UpdatedAt = getCurrentTime()
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"movie_id": embedding.MovieID, "model": embedding.Model},
+		bson.M{"$set": bson.M{
+			"vector":     embedding.Vector,
+			"plot_hash":  embedding.PlotHash,
+			"updated_at": embedding.UpdatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindForMovie returns the movie's embedding under the model, or nil
+func (r *EmbeddingRepository) FindForMovie(movieID primitive.ObjectID, model string) (*models.MovieEmbedding, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_embeddings")
+
+	var embedding models.MovieEmbedding
+	err := collection.FindOne(ctx, bson.M{"movie_id": movieID, "model": model}).Decode(&embedding)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &embedding, nil
+}
+
+// PlotHashes returns the plot hash of every movie embedded under the model
+func (r *EmbeddingRepository) PlotHashes(model string) (map[primitive.ObjectID]string, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_embeddings")
+
+	findOptions := options.Find().SetProjection(bson.M{"movie_id": 1, "plot_hash": 1})
+	cursor, err := collection.Find(ctx, bson.M{"model": model}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var embeddings []models.MovieEmbedding
+	if err := cursor.All(ctx, &embeddings); err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[primitive.ObjectID]string, len(embeddings))
+	for _, embedding := range embeddings {
+		hashes[embedding.MovieID] = embedding.PlotHash
+	}
+	return hashes, nil
+}
+
+// FindAllVectors returns the movie ID and vector of every embedding under the model
+func (r *EmbeddingRepository) FindAllVectors(model string) ([]models.MovieEmbedding, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_embeddings")
+
+	findOptions := options.Find().SetProjection(bson.M{"movie_id": 1, "vector": 1})
+	cursor, err := collection.Find(ctx, bson.M{"model": model}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	embeddings := []models.MovieEmbedding{}
+	if err := cursor.All(ctx, &embeddings); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// VectorSearch finds the nearest embeddings with an Atlas Vector Search
+// index. The index must cover the vector field and have model as a filter field.
+func (r *EmbeddingRepository) VectorSearch(index, model string, vector []float32, limit int) ([]VectorMatch, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_embeddings")
+
+	pipeline := []bson.M{
+		{"$vectorSearch": bson.M{
+			"index":         index,
+			"path":          "vector",
+			"queryVector":   vector,
+			"numCandidates": limit * 10,
+			"limit":         limit,
+			"filter":        bson.M{"model": model},
+		}},
+		{"$project": bson.M{
+			"_id":      0,
+			"movie_id": 1,
+			"score":    bson.M{"$meta": "vectorSearchScore"},
+		}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	matches := []VectorMatch{}
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EncryptedFieldRepository walks encrypted string fields across collections,
+// e.g. to re-encrypt them after a key rotation
+type EncryptedFieldRepository struct {
+	db *database.MongoDB
+}
+
+func NewEncryptedFieldRepository(db *database.MongoDB) *EncryptedFieldRepository {
+	return &EncryptedFieldRepository{db: db}
+}
+
+// RewriteField passes every string value of field (a dotted path) in the
+// collection to rewrite and stores the result when rewrite reports a change.
+// A value that changed in the meantime is left alone. It returns how many
+// values were rewritten.
+func (r *EncryptedFieldRepository) RewriteField(collectionName, field string, rewrite func(id primitive.ObjectID, value string) (string, bool, error)) (int, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection(collectionName)
+
+	cursor, err := collection.Find(ctx,
+		bson.M{field: bson.M{"$type": "string"}},
+		options.Find().SetProjection(bson.M{field: 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rewritten := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return rewritten, err
+		}
+		value, ok := cursor.Current.Lookup(strings.Split(field, ".")...).StringValueOK()
+		if !ok {
+			continue
+		}
+
+		updated, changed, err := rewrite(doc.ID, value)
+		if err != nil {
+			return rewritten, err
+		}
+		if !changed {
+			continue
+		}
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": doc.ID, field: value},
+			bson.M{"$set": bson.M{field: updated}},
+		)
+		if err != nil {
+			return rewritten, err
+		}
+		if result.ModifiedCount == 1 {
+			rewritten++
+		}
+	}
+	return rewritten, cursor.Err()
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EvaluationRepository struct {
+	db *database.MongoDB
+}
+
+func NewEvaluationRepository(db *database.MongoDB) *EvaluationRepository {
+	return &EvaluationRepository{db: db}
+}
+
+func (r *EvaluationRepository) Create(evaluation *models.RecommendationEvaluation) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_evaluations")
+
+	result, err := collection.InsertOne(ctx, evaluation)
+	if err != nil {
+		return err
+	}
+
+	evaluation.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindRecent returns the latest evaluation runs, newest first
+func (r *EvaluationRepository) FindRecent(limit int64) ([]models.RecommendationEvaluation, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_evaluations")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "finished_at", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	evaluations := []models.RecommendationEvaluation{}
+	if err := cursor.All(ctx, &evaluations); err != nil {
+		return nil, err
+	}
+	return evaluations, nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportBatchSize is how many documents an export cursor fetches at a time.
+// The next batch is only requested once the consumer has written the current
+// one, so a slow client holds at most one batch in memory.
+const exportBatchSize = 500
+
+// RatingExport is one rating with the IMDb ID and title of its movie
+type RatingExport struct {
+	IMDbID    string    `bson:"imdb_id" json:"imdb_id"`
+	Title     string    `bson:"title" json:"title"`
+	Rating    int       `bson:"rating" json:"rating"`
+	UpdatedAt time.Time `bson:"updated_at" json:"rated_at"`
+}
+
+// WatchlistExport is one watchlist entry with the IMDb ID and title of its movie
+type WatchlistExport struct {
+	IMDbID    string     `bson:"imdb_id" json:"imdb_id"`
+	Title     string     `bson:"title" json:"title"`
+	AddedAt   time.Time  `bson:"added_at" json:"added_at"`
+	WatchedAt *time.Time `bson:"watched_at" json:"watched_at"`
+	Priority  int        `bson:"priority" json:"priority"`
+}
+
+// ReviewExport is one written review with the IMDb ID and title of its movie
+type ReviewExport struct {
+	IMDbID    string    `bson:"imdb_id" json:"imdb_id"`
+	Title     string    `bson:"title" json:"title"`
+	Body      string    `bson:"body" json:"body"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ReviewVoteExport is one helpful vote, naming the review by its movie's
+// IMDb ID and its author's username
+type ReviewVoteExport struct {
+	IMDbID    string    `bson:"imdb_id" json:"imdb_id"`
+	Username  string    `bson:"username" json:"username"`
+	CreatedAt time.Time `bson:"created_at" json:"voted_at"`
+}
+
+// ExportRepository iterates over whole collections for exports, handing
+// documents to a callback one at a time instead of loading them all
+type ExportRepository struct {
+	db *database.MongoDB
+}
+
+func NewExportRepository(db *database.MongoDB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// EachRating calls fn for each of the user's ratings, oldest first. Ratings of
+// movies no longer cached are left out. An error from fn stops the iteration.
+func (r *ExportRepository) EachRating(userID primitive.ObjectID, fn func(RatingExport) error) error {
+	cursor, err := r.db.GetCollection("ratings").Aggregate(context.Background(), withMovie(userID, "created_at", bson.M{
+		"rating":     1,
+		"updated_at": 1,
+	}), options.Aggregate().SetBatchSize(exportBatchSize))
+	if err != nil {
+		return err
+	}
+	return each(cursor, fn)
+}
+
+// EachWatchlistEntry calls fn for each entry of the user's watchlist, oldest
+// first. Entries of movies no longer cached are left out. An error from fn
+// stops the iteration.
+func (r *ExportRepository) EachWatchlistEntry(userID primitive.ObjectID, fn func(WatchlistExport) error) error {
+	cursor, err := r.db.GetCollection("watchlists").Aggregate(context.Background(), withMovie(userID, "added_at", bson.M{
+		"added_at":   1,
+		"watched_at": 1,
+		"priority":   1,
+	}), options.Aggregate().SetBatchSize(exportBatchSize))
+	if err != nil {
+		return err
+	}
+	return each(cursor, fn)
+}
+
+// EachReview calls fn for each of the user's reviews, oldest first. Reviews of
+// movies no longer cached are left out. An error from fn stops the iteration.
+func (r *ExportRepository) EachReview(userID primitive.ObjectID, fn func(ReviewExport) error) error {
+	cursor, err := r.db.GetCollection("reviews").Aggregate(context.Background(), withMovie(userID, "created_at", bson.M{
+		"body":       1,
+		"created_at": 1,
+		"updated_at": 1,
+	}), options.Aggregate().SetBatchSize(exportBatchSize))
+	if err != nil {
+		return err
+	}
+	return each(cursor, fn)
+}
+
+// EachReviewVote calls fn for each helpful vote the user cast, oldest first.
+// Votes on reviews whose movie or author is gone are left out. An error from
+// fn stops the iteration.
+func (r *ExportRepository) EachReviewVote(userID primitive.ObjectID, fn func(ReviewVoteExport) error) error {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID}},
+		{"$sort": bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{"$lookup": bson.M{
+			"from":         "reviews",
+			"localField":   "review_id",
+			"foreignField": "_id",
+			"as":           "review",
+		}},
+		{"$unwind": "$review"},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "review.movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$lookup": bson.M{
+			"from":         "users",
+			"localField":   "review.user_id",
+			"foreignField": "_id",
+			"as":           "author",
+		}},
+		{"$unwind": "$author"},
+		{"$project": bson.M{
+			"imdb_id":    "$movie.imdb_id",
+			"username":   "$author.username",
+			"created_at": 1,
+		}},
+	}
+	cursor, err := r.db.GetCollection("review_votes").Aggregate(context.Background(), pipeline, options.Aggregate().SetBatchSize(exportBatchSize))
+	if err != nil {
+		return err
+	}
+	return each(cursor, fn)
+}
+
+// EachUser calls fn for every user, oldest first, without password hashes.
+// An error from fn stops the iteration.
+func (r *ExportRepository) EachUser(fn func(models.User) error) error {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"password": 0}).
+		SetBatchSize(exportBatchSize)
+
+	cursor, err := r.db.GetCollection("users").Find(context.Background(), bson.M{}, findOptions)
+	if err != nil {
+		return err
+	}
+	return each(cursor, fn)
+}
+
+// withMovie selects the user's documents sorted by field and replaces their
+// movie_id with the movie's IMDb ID and title
+func withMovie(userID primitive.ObjectID, field string, project bson.M) []bson.M {
+	project["imdb_id"] = "$movie.imdb_id"
+	project["title"] = "$movie.title"
+	return []bson.M{
+		{"$match": bson.M{"user_id": userID}},
+		{"$sort": bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$project": project},
+	}
+}
+
+// each decodes the cursor's documents into T one at a time and passes them to
+// fn, closing the cursor when done
+func each[T any](cursor *mongo.Cursor, fn func(T) error) error {
+	ctx := context.Background()
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobRepository struct {
+	db *database.MongoDB
+}
+
+func NewJobRepository(db *database.MongoDB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) Create(job *models.Job) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	stampCreated(job)
+
+	result, err := collection.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ClaimNext atomically locks the next due job of one of the given types,
+// including running jobs whose lock expired because their worker died.
+// It returns nil when no job is due.
+func (r *JobRepository) ClaimNext(types []string, lockFor time.Duration) (*models.Job, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	now := getCurrentTime()
+	filter := bson.M{
+		"type": bson.M{"$in": types},
+		"$or": []bson.M{
+			{"status": models.JobStatusPending, "run_at": bson.M{"$lte": now}},
+			{"status": models.JobStatusRunning, "locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := withUpdatedAt(bson.M{
+		"$set": bson.M{
+			"status":       models.JobStatusRunning,
+			"locked_until": now.Add(lockFor),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) MarkSucceeded(id primitive.ObjectID) error {
+	return r.update(id, bson.M{"status": models.JobStatusSucceeded}, "completed_at")
+}
+
+// MarkRetry returns a failed job to the queue to run again at runAt
+func (r *JobRepository) MarkRetry(id primitive.ObjectID, lastError string, runAt time.Time) error {
+	return r.update(id, bson.M{
+		"status":     models.JobStatusPending,
+		"last_error": lastError,
+		"run_at":     runAt,
+	})
+}
+
+// MarkDead moves a job that exhausted its retries to the dead letter state
+func (r *JobRepository) MarkDead(id primitive.ObjectID, lastError string) error {
+	return r.update(id, bson.M{
+		"status":     models.JobStatusDead,
+		"last_error": lastError,
+	}, "completed_at")
+}
+
+// Requeue resets a dead job so it is retried from scratch; it reports false
+// when no dead job with the ID exists
+func (r *JobRepository) Requeue(id primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "status": models.JobStatusDead}, withUpdatedAt(bson.M{
+		"$set":   bson.M{"status": models.JobStatusPending, "attempts": 0},
+		"$unset": bson.M{"completed_at": ""},
+	}, "run_at"))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// HasActive reports whether a pending or running job of the type exists
+func (r *JobRepository) HasActive(jobType string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"type":   jobType,
+		"status": bson.M{"$in": bson.A{models.JobStatusPending, models.JobStatusRunning}},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountByStatus returns the number of jobs in each status
+func (r *JobRepository) CountByStatus() (map[string]int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{
+		models.JobStatusPending:   0,
+		models.JobStatusRunning:   0,
+		models.JobStatusSucceeded: 0,
+		models.JobStatusDead:      0,
+	}
+	for _, result := range results {
+		counts[result.Status] = result.Count
+	}
+	return counts, nil
+}
+
+// FindByStatus returns the most recently updated jobs with the given status
+func (r *JobRepository) FindByStatus(status string, limit int64) ([]models.Job, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"status": status}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *JobRepository) update(id primitive.ObjectID, set bson.M, also ...string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("jobs")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{"$set": set}, also...))
+	return err
+}
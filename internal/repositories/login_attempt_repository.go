@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginAttemptRepository struct {
+	db *database.MongoDB
+}
+
+func NewLoginAttemptRepository(db *database.MongoDB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Create(attempt *models.LoginAttempt) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("login_attempts")
+
+	stampCreated(attempt)
+	result, err := collection.InsertOne(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	attempt.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindRecentByUser returns the user's latest login attempts, newest first
+func (r *LoginAttemptRepository) FindRecentByUser(userID primitive.ObjectID, limit int64) ([]models.LoginAttempt, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("login_attempts")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []models.LoginAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// KnownLocations returns the locations and device fingerprints of the user's
+// successful logins
+func (r *LoginAttemptRepository) KnownLocations(userID primitive.ObjectID) (locations, devices map[string]bool, err error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("login_attempts")
+	filter := bson.M{"user_id": userID, "success": true}
+
+	locations, err = r.distinct(ctx, collection, "location", filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	devices, err = r.distinct(ctx, collection, "device_fingerprint", filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return locations, devices, nil
+}
+
+func (r *LoginAttemptRepository) distinct(ctx context.Context, collection *mongo.Collection, field string, filter bson.M) (map[string]bool, error) {
+	values, err := collection.Distinct(ctx, field, filter)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			set[s] = true
+		}
+	}
+	return set, nil
+}
+
+// TakeRevokeToken returns the attempt with the unexpired revoke token hash
+// and clears the token, so that a revoke link can only be used once
+func (r *LoginAttemptRepository) TakeRevokeToken(tokenHash string) (*models.LoginAttempt, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("login_attempts")
+
+	var attempt models.LoginAttempt
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"revoke_token_hash": tokenHash,
+			"revoke_expires_at": bson.M{"$gt": getCurrentTime()},
+		},
+		bson.M{"$unset": bson.M{"revoke_token_hash": "", "revoke_expires_at": ""}},
+	).Decode(&attempt)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MetadataConflictRepository stores where cached movie details disagree with
+// a secondary provider
+type MetadataConflictRepository struct {
+	db *database.MongoDB
+}
+
+func NewMetadataConflictRepository(db *database.MongoDB) *MetadataConflictRepository {
+	return &MetadataConflictRepository{db: db}
+}
+
+func (r *MetadataConflictRepository) FindByID(id primitive.ObjectID) (*models.MetadataConflict, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+func (r *MetadataConflictRepository) FindByMovie(movieID primitive.ObjectID) (*models.MetadataConflict, error) {
+	return r.findOne(bson.M{"movie_id": movieID})
+}
+
+func (r *MetadataConflictRepository) findOne(filter bson.M) (*models.MetadataConflict, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	var conflict models.MetadataConflict
+	err := collection.FindOne(ctx, filter).Decode(&conflict)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &conflict, nil
+}
+
+// Open stores a newly detected conflict for the movie, replacing any earlier
+// one and its resolution
+func (r *MetadataConflictRepository) Open(conflict *models.MetadataConflict) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	conflict.Status = models.ConflictOpen
+	conflict.ResolvedAt = nil
+	conflict.ResolvedBy = nil
+	update := bson.M{
+		"$set": bson.M{
+			"imdb_id":            conflict.IMDbID,
+			"title":              conflict.Title,
+			"cached_provider":    conflict.CachedProvider,
+			"secondary_provider": conflict.SecondaryProvider,
+			"fields":             conflict.Fields,
+			"status":             conflict.Status,
+			"detected_at":        conflict.DetectedAt,
+		},
+		"$unset": bson.M{"resolved_at": "", "resolved_by": ""},
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"movie_id": conflict.MovieID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteOpen drops the movie's open conflict once the providers agree again
+func (r *MetadataConflictRepository) DeleteOpen(movieID primitive.ObjectID) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	_, err := collection.DeleteOne(ctx, bson.M{"movie_id": movieID, "status": models.ConflictOpen})
+	return err
+}
+
+// Resolve closes an open conflict with the given status, reporting false
+// when it is not open (any more)
+func (r *MetadataConflictRepository) Resolve(id primitive.ObjectID, status string, by primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ConflictOpen},
+		bson.M{"$set": bson.M{"status": status, "resolved_at": at, "resolved_by": by}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// List returns a cursor page of conflicts with the given status, or of all
+// conflicts when status is empty
+func (r *MetadataConflictRepository) List(status string, cursor pagination.Cursor) ([]models.MetadataConflict, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := collection.Find(ctx, cursor.Filter(filter), cursor.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	conflicts := []models.MetadataConflict{}
+	if err := cur.All(ctx, &conflicts); err != nil {
+		return nil, false, err
+	}
+
+	n, more := cursor.Trim(len(conflicts))
+	return conflicts[:n], more, nil
+}
+
+// CountByStatus counts conflicts per status
+func (r *MetadataConflictRepository) CountByStatus() (map[string]int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("metadata_conflicts")
+
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{
+		models.ConflictOpen:       0,
+		models.ConflictAccepted:   0,
+		models.ConflictOverridden: 0,
+	}
+	for _, result := range results {
+		counts[result.Status] = result.Count
+	}
+	return counts, nil
+}
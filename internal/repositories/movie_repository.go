@@ -2,57 +2,34 @@ package repositories
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"movie-watchlist/internal/database"
 	"movie-watchlist/internal/models"
-	"net/http"
-	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MovieRepository struct {
-	db     *database.MongoDB
-	apiKey string
-	client *http.Client
-}
-
-type OMDbResponse struct {
-	Title      string `json:"Title"`
-	Year       string `json:"Year"`
-	IMDbID     string `json:"imdbID"`
-	Genre      string `json:"Genre"`
-	Director   string `json:"Director"`
-	Plot       string `json:"Plot"`
-	Poster     string `json:"Poster"`
-	Runtime    string `json:"Runtime"`
-	IMDbRating string `json:"imdbRating"`
-	Response   string `json:"Response"`
-	Error      string `json:"Error"`
-}
-
-func NewMovieRepository(db *database.MongoDB, apiKey string) *MovieRepository {
-	return &MovieRepository{
-		db:     db,
-		apiKey: apiKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+	db *database.MongoDB
+}
+
+func NewMovieRepository(db *database.MongoDB) *MovieRepository {
+	return &MovieRepository{db: db}
 }
 
 func (r *MovieRepository) Create(movie *models.Movie) error {
 	ctx := context.Background()
 	collection := r.db.GetCollection("movies")
 	
-	movie.CreatedAt = getCurrentTime()
-	movie.UpdatedAt = getCurrentTime()
-	movie.CachedAt = time.Now()
+	movie.IMDbID = models.NormalizeIMDbID(movie.IMDbID)
+	movie.CachedAt = stampCreated(movie)
+	movie.DeriveNumericFields()
 	
 	// Only set ID if it's empty (zero value)
 	if movie.ID.IsZero() {
@@ -86,12 +63,79 @@ func (r *MovieRepository) FindByID(id primitive.ObjectID) (*models.Movie, error)
 	return &movie, nil
 }
 
+// FindByIDs fetches all movies with the given IDs, keyed by ID. Given fields,
+// only those are read.
+func (r *MovieRepository) FindByIDs(ids []primitive.ObjectID, fields ...string) (map[primitive.ObjectID]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	movies := make(map[primitive.ObjectID]models.Movie, len(ids))
+	if len(ids) == 0 {
+		return movies, nil
+	}
+
+	findOptions := options.Find()
+	if fields != nil {
+		findOptions.SetProjection(projection(fields))
+	}
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.Movie
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, movie := range results {
+		movies[movie.ID] = movie
+	}
+	return movies, nil
+}
+
+// SearchWithin returns the movies among ids where every term appears, case
+// insensitively, in the title, genre, language, director or plot
+func (r *MovieRepository) SearchWithin(ids []primitive.ObjectID, terms []string) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	if len(ids) == 0 || len(terms) == 0 {
+		return []models.Movie{}, nil
+	}
+
+	clauses := bson.A{}
+	for _, term := range terms {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(term), Options: "i"}
+		clauses = append(clauses, bson.M{"$or": bson.A{
+			bson.M{"title": pattern},
+			bson.M{"genre": pattern},
+			bson.M{"language": pattern},
+			bson.M{"director": pattern},
+			bson.M{"plot": pattern},
+		}})
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "$and": clauses})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
 func (r *MovieRepository) FindByIMDbID(imdbID string) (*models.Movie, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("movies")
 	
 	var movie models.Movie
-	err := collection.FindOne(ctx, bson.M{"imdb_id": imdbID}).Decode(&movie)
+	err := collection.FindOne(ctx, bson.M{"imdb_id": models.NormalizeIMDbID(imdbID)}).Decode(&movie)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -101,6 +145,50 @@ func (r *MovieRepository) FindByIMDbID(imdbID string) (*models.Movie, error) {
 	return &movie, nil
 }
 
+// ResolveCanonicalID maps a movie ID to the canonical document for its IMDb ID
+// and returns every ID sharing that IMDb ID, canonical first. IMDb IDs are
+// normalized on write and unique, so the only other documents are legacy
+// duplicates marked with duplicate_of. Unknown movies resolve to themselves.
+func (r *MovieRepository) ResolveCanonicalID(movieID primitive.ObjectID) (primitive.ObjectID, []primitive.ObjectID, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	movie, err := r.FindByID(movieID)
+	if err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	if movie == nil {
+		return movieID, []primitive.ObjectID{movieID}, nil
+	}
+	canonicalID := movie.ID
+	if movie.DuplicateOf != nil {
+		canonicalID = *movie.DuplicateOf
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(ctx, bson.M{"duplicate_of": canonicalID}, findOptions)
+	if err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(docs)+1)
+	ids = append(ids, canonicalID)
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return canonicalID, ids, nil
+}
+
 func (r *MovieRepository) FindByGenre(genre string) ([]models.Movie, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("movies")
@@ -118,11 +206,516 @@ func (r *MovieRepository) FindByGenre(genre string) ([]models.Movie, error) {
 	return movies, nil
 }
 
-func (r *MovieRepository) FindAll() ([]models.Movie, error) {
+// SearchByTitle performs a case-insensitive title search against cached movies,
+// returning one page of matches and the total match count. When rated is
+// non-empty only movies with one of those certifications match.
+func (r *MovieRepository) SearchByTitle(query string, rated []string, skip, limit int64) ([]models.Movie, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{"title": bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"}}
+	if len(rated) > 0 {
+		filter["rated"] = bson.M{"$in": rated}
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "title", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(movies))
+	if err != nil {
+		return nil, 0, err
+	}
+	return movies, total, nil
+}
+
+// BrowseFilter narrows a catalogue browse; zero values leave a criterion out
+type BrowseFilter struct {
+	YearFrom int
+	YearTo   int
+	Genre    string
+	// Keyword is a tagged theme such as heist
+	Keyword string
+	// Rated limits results to these certifications when non-empty
+	Rated []string
+	// Sort is one of imdb_rating, year or title
+	Sort string
+}
+
+// Browse returns one page of cached movies matching the filter and the total
+// match count. A movie matches a year range when its release years overlap it.
+func (r *MovieRepository) Browse(f BrowseFilter, skip, limit int64) ([]models.Movie, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{}
+	addYearRange(filter, f.YearFrom, f.YearTo)
+	if f.Genre != "" {
+		filter["genre"] = genrePattern(f.Genre)
+	}
+	if f.Keyword != "" {
+		filter["keywords"] = f.Keyword
+	}
+	if len(f.Rated) > 0 {
+		filter["rated"] = bson.M{"$in": f.Rated}
+	}
+
+	var sort bson.D
+	switch f.Sort {
+	case "year":
+		sort = bson.D{{Key: "year_start", Value: -1}, {Key: "_id", Value: 1}}
+	case "title":
+		sort = bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}
+	default:
+		sort = bson.D{{Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}
+	}
+
+	findOptions := options.Find().
+		SetSort(sort).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(movies))
+	if err != nil {
+		return nil, 0, err
+	}
+	return movies, total, nil
+}
+
+// addYearRange restricts filter to movies whose release years overlap
+// from-to; zero leaves that end of the range open
+func addYearRange(filter bson.M, from, to int) {
+	if from <= 0 && to <= 0 {
+		return
+	}
+	yearStart := bson.M{"$gt": 0}
+	if to > 0 {
+		yearStart["$lte"] = to
+	}
+	filter["year_start"] = yearStart
+	if from > 0 {
+		// Running series (year_end 0) overlap every later range
+		filter["$or"] = bson.A{
+			bson.M{"year_end": bson.M{"$gte": from}},
+			bson.M{"year_end": 0},
+		}
+	}
+}
+
+// genrePattern matches one entry of OMDb's comma-separated genre list
+func genrePattern(genre string) bson.M {
+	return bson.M{"$regex": `(^|,\s*)` + regexp.QuoteMeta(genre) + `\s*(,|$)`, "$options": "i"}
+}
+
+// DiscoverFilter narrows a natural-language discovery query; zero values
+// leave a criterion out
+type DiscoverFilter struct {
+	// Genres matches movies in any of the genres
+	Genres []string
+	// Keywords must all match the title or plot; each keyword is a list
+	// of interchangeable spellings such as heist, robbery
+	Keywords [][]string
+	YearFrom int
+	YearTo   int
+	Rated    []string
+	// UnseenBy leaves out the movies this user rated or has on their
+	// watchlist
+	UnseenBy primitive.ObjectID
+}
+
+// Discover returns up to limit cached movies matching the filter, highest
+// IMDb rating first
+func (r *MovieRepository) Discover(f DiscoverFilter, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{}
+	addYearRange(filter, f.YearFrom, f.YearTo)
+
+	clauses := bson.A{}
+	if len(f.Genres) > 0 {
+		genres := bson.A{}
+		for _, genre := range f.Genres {
+			genres = append(genres, bson.M{"genre": genrePattern(genre)})
+		}
+		clauses = append(clauses, bson.M{"$or": genres})
+	}
+	for _, spellings := range f.Keywords {
+		quoted := make([]string, len(spellings))
+		for i, spelling := range spellings {
+			quoted[i] = regexp.QuoteMeta(spelling)
+		}
+		pattern := primitive.Regex{Pattern: `\b(` + strings.Join(quoted, "|") + `)`, Options: "i"}
+		clauses = append(clauses, bson.M{"$or": bson.A{
+			bson.M{"title": pattern},
+			bson.M{"plot": pattern},
+		}})
+	}
+	if len(clauses) > 0 {
+		filter["$and"] = clauses
+	}
+	if len(f.Rated) > 0 {
+		filter["rated"] = bson.M{"$in": f.Rated}
+	}
+
+	// Matches are ranked before they are anti-joined with the user's
+	// ratings and watchlist, so the lookups stop at limit
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$sort": bson.D{{Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+	if !f.UnseenBy.IsZero() {
+		pipeline = append(pipeline, unseenByStages(f.UnseenBy)...)
+	}
+	pipeline = append(pipeline, bson.M{"$limit": limit})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// creditFields are the movie fields holding each role's comma-separated names
+var creditFields = map[string]string{
+	models.PersonRoleDirector: "director",
+	models.PersonRoleActor:    "actors",
+}
+
+// creditPattern matches movies crediting any of the names, ignoring case
+func creditPattern(names []string) bson.M {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return bson.M{"$regex": `(^|,\s*)(` + strings.Join(quoted, "|") + `)\s*(,|$)`, "$options": "i"}
+}
+
+// FindByCredit returns a movie crediting name in the role, or nil when no
+// cached movie does
+func (r *MovieRepository) FindByCredit(role, name string) (*models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	field, ok := creditFields[role]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+
+	var movie models.Movie
+	err := collection.FindOne(ctx, bson.M{field: creditPattern([]string{name})}).Decode(&movie)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// FindByPeople returns up to limit movies from yearFrom on that credit one
+// of the directors or actors, newest first, leaving out the movies unseenBy
+// rated or has on their watchlist
+func (r *MovieRepository) FindByPeople(directors, actors []string, yearFrom int, unseenBy primitive.ObjectID, limit int64) ([]models.Movie, error) {
+	movies := []models.Movie{}
+	credits := bson.A{}
+	if len(directors) > 0 {
+		credits = append(credits, bson.M{"director": creditPattern(directors)})
+	}
+	if len(actors) > 0 {
+		credits = append(credits, bson.M{"actors": creditPattern(actors)})
+	}
+	if len(credits) == 0 {
+		return movies, nil
+	}
+
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"$or": credits, "year_start": bson.M{"$gte": yearFrom}}},
+		{"$sort": bson.D{{Key: "year_start", Value: -1}, {Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+	pipeline = append(pipeline, unseenByStages(unseenBy)...)
+	pipeline = append(pipeline, bson.M{"$limit": limit})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// titleCollation compares titles case-insensitively; it must match the
+// collation of the title_ci index so prefix lookups can use that index
+var titleCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// SuggestByTitlePrefix returns up to limit cached movies whose title starts
+// with prefix, ignoring case. The range query is served by the title_ci index.
+func (r *MovieRepository) SuggestByTitlePrefix(prefix string, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{"title": bson.M{"$gte": prefix, "$lt": prefix + "\uffff"}}
+	findOptions := options.Find().
+		SetCollation(titleCollation).
+		SetSort(bson.D{{Key: "title", Value: 1}}).
+		SetProjection(bson.M{"imdb_id": 1, "title": 1, "year": 1, "poster": 1}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// FindTitles returns the IMDb ID, title and year of every cached movie
+func (r *MovieRepository) FindTitles() ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	findOptions := options.Find().SetProjection(bson.M{"imdb_id": 1, "title": 1, "year": 1})
+	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// ScoredMovie is a movie matched by the title text index together with its
+// text relevance score
+type ScoredMovie struct {
+	models.Movie `bson:",inline"`
+	Score        float64 `bson:"score"`
+}
+
+// SearchByText runs a text-index search over cached movie titles and returns
+// up to limit matches ordered by text relevance
+func (r *MovieRepository) SearchByText(query string, limit int64) ([]ScoredMovie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{"$text": bson.M{"$search": query}}
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []ScoredMovie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// FindByIMDbIDs returns the cached movies for the given IMDb IDs keyed by
+// normalized IMDb ID; IDs that are not cached are simply absent. Given
+// fields, only those and the IMDb ID are read.
+func (r *MovieRepository) FindByIMDbIDs(imdbIDs []string, fields ...string) (map[string]models.Movie, error) {
+	movies := make(map[string]models.Movie)
+	if len(imdbIDs) == 0 {
+		return movies, nil
+	}
+
+	normalized := make([]string, 0, len(imdbIDs))
+	for _, id := range imdbIDs {
+		normalized = append(normalized, models.NormalizeIMDbID(id))
+	}
+
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	findOptions := options.Find()
+	if fields != nil {
+		findOptions.SetProjection(projection(append(fields, "imdb_id")))
+	}
+	cursor, err := collection.Find(ctx, bson.M{"imdb_id": bson.M{"$in": normalized}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.Movie
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for _, movie := range results {
+		movies[models.NormalizeIMDbID(movie.IMDbID)] = movie
+	}
+	return movies, nil
+}
+
+// FindPlots returns the ID and plot of every movie with a known plot
+func (r *MovieRepository) FindPlots() ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{"plot": bson.M{"$nin": bson.A{"", "N/A"}}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"plot": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// FindUntagged returns up to limit movies with a known plot that have not
+// been tagged with keywords by source
+func (r *MovieRepository) FindUntagged(source string, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{
+		"plot":            bson.M{"$nin": bson.A{"", "N/A"}},
+		"keywords_source": bson.M{"$ne": source},
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"title": 1, "genre": 1, "plot": 1, "keywords": 1}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// SetKeywords stores the keywords tagged on a movie by source
+func (r *MovieRepository) SetKeywords(id primitive.ObjectID, keywords []string, source string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"keywords":        keywords,
+		"keywords_source": source,
+	}})
+	return err
+}
+
+// FindUnadvised returns up to limit movies with a known plot whose content
+// advisories have not been tagged by source
+func (r *MovieRepository) FindUnadvised(source string, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{
+		"plot":              bson.M{"$nin": bson.A{"", "N/A"}},
+		"advisories_source": bson.M{"$ne": source},
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"title": 1, "genre": 1, "plot": 1, "rated": 1, "advisories": 1}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// SetAdvisories stores the content advisories tagged on a movie by source
+func (r *MovieRepository) SetAdvisories(id primitive.ObjectID, advisories []string, source string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"advisories":        advisories,
+		"advisories_source": source,
+	}})
+	return err
+}
+
+// AddReportedAdvisory adds an approved user-reported advisory to the movie
+func (r *MovieRepository) AddReportedAdvisory(id primitive.ObjectID, advisory string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"reported_advisories": advisory}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindAll returns every cached movie. Given fields, only those are read.
+func (r *MovieRepository) FindAll(fields ...string) ([]models.Movie, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("movies")
 	
-	cursor, err := collection.Find(ctx, bson.M{})
+	findOptions := options.Find()
+	if fields != nil {
+		findOptions.SetProjection(projection(fields))
+	}
+	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -135,95 +728,354 @@ func (r *MovieRepository) FindAll() ([]models.Movie, error) {
 	return movies, nil
 }
 
-func (r *MovieRepository) GetOrCreateByIMDbID(imdbID string) (*models.Movie, error) {
+// FindTopRated returns up to limit movies with the highest IMDb rating,
+// leaving out the excluded IDs
+func (r *MovieRepository) FindTopRated(exclude []primitive.ObjectID, limit int64) ([]models.Movie, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("movies")
-	var movie models.Movie
 
-	// 1. Try to find existing movie
-	err := collection.FindOne(ctx, bson.M{"imdb_id": imdbID}).Decode(&movie)
-	if err == nil {
-		return &movie, nil
+	filter := bson.M{}
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
 	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
 
-	if err != mongo.ErrNoDocuments {
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// 2. Fetch full movie details from OMDb using i= endpoint
-	if r.apiKey == "" {
-		return nil, fmt.Errorf("OMDb API key not configured")
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
 	}
+	return movies, nil
+}
 
-	// URL encode the IMDb ID for safe HTTP requests
-	encodedIMDbID := url.QueryEscape(imdbID)
-	requestURL := fmt.Sprintf("http://www.omdbapi.com/?apikey=%s&i=%s", r.apiKey, encodedIMDbID)
+// UpsertStub ensures a movie exists for the given IMDb ID, inserting the
+// provided (possibly partial) data only when no document exists yet
+func (r *MovieRepository) UpsertStub(movie *models.Movie) (*models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	movie.DeriveNumericFields()
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":         primitive.NewObjectID(),
+			"title":       movie.Title,
+			"year":        movie.Year,
+			"genre":       movie.Genre,
+			"director":    movie.Director,
+			"plot":        movie.Plot,
+			"poster":      movie.Poster,
+			"runtime":     movie.Runtime,
+			"imdb_rating": movie.IMDbRating,
+			"imdb_score":  movie.IMDbScore,
+			"year_start":  movie.YearStart,
+			"year_end":    movie.YearEnd,
+			"language":    movie.Language,
+			"rated":       movie.Rated,
+			"source":      movie.Source,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	imdbID := models.NormalizeIMDbID(movie.IMDbID)
+	var result models.Movie
+	err := collection.FindOneAndUpdate(ctx, bson.M{"imdb_id": imdbID}, withUpsertTimestamps(update, "cached_at"), opts).Decode(&result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		// A concurrent upsert won the race on the unique index; read its document
+		if mongo.IsDuplicateKeyError(err) {
+			return r.FindByIMDbID(imdbID)
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpsertDetails stores full movie details by IMDb ID, completing a stub
+// document in place or inserting a new one. It returns the document as it
+// was before, or nil when it was inserted.
+func (r *MovieRepository) UpsertDetails(movie *models.Movie) (*models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	movie.DeriveNumericFields()
+	update := bson.M{
+		"$set": bson.M{
+			"title":       movie.Title,
+			"year":        movie.Year,
+			"genre":       movie.Genre,
+			"director":    movie.Director,
+			"actors":      movie.Actors,
+			"plot":        movie.Plot,
+			"poster":      movie.Poster,
+			"runtime":     movie.Runtime,
+			"imdb_rating": movie.IMDbRating,
+			"imdb_score":  movie.IMDbScore,
+			"year_start":  movie.YearStart,
+			"year_end":    movie.YearEnd,
+			"language":    movie.Language,
+			"rated":       movie.Rated,
+			"metadata_provider": movie.MetadataProvider,
+		},
+		// The plot may have changed, so the movie is tagged again, and the
+		// new poster link is checked again
+		"$unset": bson.M{"keywords_source": "", "advisories_source": "", "poster_broken": "", "broken_poster": "", "poster_checked_at": ""},
+		"$setOnInsert": bson.M{
+			"_id":    primitive.NewObjectID(),
+			"source": movie.Source,
+		},
 	}
 
-	resp, err := r.client.Do(req)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	var previous models.Movie
+	err := collection.FindOneAndUpdate(ctx, bson.M{"imdb_id": models.NormalizeIMDbID(movie.IMDbID)}, withUpsertTimestamps(update, "cached_at"), opts).Decode(&previous)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to OMDb API: %w", err)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &previous, nil
+}
+
+// FindForReconciliation returns up to limit movies with full details that
+// were not cross-checked since before, the longest unchecked first
+func (r *MovieRepository) FindForReconciliation(before time.Time, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OMDb API returned status code: %d", resp.StatusCode)
+	filter := bson.M{
+		"genre": bson.M{"$ne": ""},
+		"$or": bson.A{
+			bson.M{"reconciled_at": bson.M{"$exists": false}},
+			bson.M{"reconciled_at": bson.M{"$lt": before}},
+		},
 	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "reconciled_at", Value: 1}}).
+		SetLimit(limit)
 
-	var omdbResp OMDbResponse
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&omdbResp); err != nil {
-		return nil, fmt.Errorf("failed to decode OMDb API response: %w", err)
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// Check for API-level errors
-	if omdbResp.Response == "False" {
-		if omdbResp.Error != "" {
-			return nil, fmt.Errorf("OMDb API error: %s", omdbResp.Error)
-		}
-		return nil, fmt.Errorf("OMDb API returned an error response")
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
 	}
+	return movies, nil
+}
+
+// MarkReconciled records when a movie was cross-checked
+func (r *MovieRepository) MarkReconciled(id primitive.ObjectID, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"reconciled_at": at}})
+	return err
+}
+
+// SetReconciledFields overwrites the given detail fields of a movie with
+// values accepted from a secondary provider
+func (r *MovieRepository) SetReconciledFields(id primitive.ObjectID, fields map[string]string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
 
-	// Validate required fields
-	if omdbResp.IMDbID == "" {
-		return nil, fmt.Errorf("invalid movie data: missing IMDb ID")
+	set := bson.M{}
+	for field, value := range fields {
+		set[field] = value
 	}
-	if omdbResp.Title == "" {
-		return nil, fmt.Errorf("invalid movie data: missing title")
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{"$set": set}))
+	if err != nil {
+		return false, err
 	}
-	if omdbResp.Genre == "" {
-		return nil, fmt.Errorf("invalid movie data: missing genre")
+	return result.MatchedCount > 0, nil
+}
+
+// FindPostersDue returns up to limit movies with a poster that was not
+// checked since before, the longest unchecked first
+func (r *MovieRepository) FindPostersDue(before time.Time, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	filter := bson.M{
+		"poster": bson.M{"$nin": bson.A{"", "N/A"}},
+		"$or": bson.A{
+			bson.M{"poster_checked_at": bson.M{"$exists": false}},
+			bson.M{"poster_checked_at": bson.M{"$lt": before}},
+		},
 	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"imdb_id": 1, "title": 1, "poster": 1, "poster_broken": 1, "broken_poster": 1}).
+		SetSort(bson.D{{Key: "poster_checked_at", Value: 1}}).
+		SetLimit(limit)
 
-	// 3. Construct MongoDB movie with full details
-	movie = models.Movie{
-		ID:         primitive.NewObjectID(),
-		IMDbID:     omdbResp.IMDbID,
-		Title:      strings.TrimSpace(omdbResp.Title),
-		Year:       strings.TrimSpace(omdbResp.Year),
-		Genre:      strings.TrimSpace(omdbResp.Genre),
-		Director:   strings.TrimSpace(omdbResp.Director),
-		Plot:       strings.TrimSpace(omdbResp.Plot),
-		Poster:     strings.TrimSpace(omdbResp.Poster),
-		Runtime:    strings.TrimSpace(omdbResp.Runtime),
-		IMDbRating: strings.TrimSpace(omdbResp.IMDbRating),
-		CachedAt:   time.Now(),
-		CreatedAt:  getCurrentTime(),
-		UpdatedAt:  getCurrentTime(),
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	// 4. Insert into MongoDB
-	_, err = collection.InsertOne(ctx, movie)
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// MarkPosterChecked records when a movie's poster was checked
+func (r *MovieRepository) MarkPosterChecked(id primitive.ObjectID, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"poster_checked_at": at}})
+	return err
+}
+
+// SetPoster stores a working poster URL, clearing any broken state
+func (r *MovieRepository) SetPoster(id primitive.ObjectID, poster string, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{
+		"$set":   bson.M{"poster": poster, "poster_checked_at": at},
+		"$unset": bson.M{"poster_broken": "", "broken_poster": ""},
+	}))
+	return err
+}
+
+// MarkPosterBroken swaps a dead poster URL for the placeholder, keeping the
+// dead URL in broken_poster
+func (r *MovieRepository) MarkPosterBroken(id primitive.ObjectID, brokenPoster, placeholder string, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{"$set": bson.M{
+		"poster":            placeholder,
+		"poster_broken":     true,
+		"broken_poster":     brokenPoster,
+		"poster_checked_at": at,
+	}}))
+	return err
+}
+
+// movieReferences are the collections and fields whose documents keep a
+// movie from being evicted from the cache
+var movieReferences = []struct{ collection, field string }{
+	{"watchlists", "movie_id"},
+	{"ratings", "movie_id"},
+	{"watch_progress", "movie_id"},
+	{"recently_viewed", "movie_id"},
+	{"movie_shares", "movie_id"},
+	{"reviews", "movie_id"},
+	// Entries that can still be restored with undo
+	{"deleted_items", "document.movie_id"},
+}
+
+// movieListReferences are like movieReferences for fields holding an array
+// of movie IDs
+var movieListReferences = []struct{ collection, field string }{
+	{"polls", "movie_ids"},
+}
+
+// FindUnreferenced scans up to scan movies after the given ID, in ID order,
+// and returns those cached before cachedBefore that nothing in
+// movieReferences points at, leaving out seed movies. last is the last
+// scanned ID, or NilObjectID once the scan reached the end.
+func (r *MovieRepository) FindUnreferenced(after primitive.ObjectID, cachedBefore time.Time, scan int64) (ids []primitive.ObjectID, last primitive.ObjectID, err error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movies")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(scan).
+		SetProjection(bson.M{"cached_at": 1, "source": 1})
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$gt": after}}, findOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to cache movie data: %w", err)
+		return nil, primitive.NilObjectID, err
+	}
+	var scanned []models.Movie
+	if err := cursor.All(ctx, &scanned); err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	if int64(len(scanned)) == scan {
+		last = scanned[len(scanned)-1].ID
 	}
 
-	// 5. RETURN THE MOVIE
-	return &movie, nil
+	var candidates []primitive.ObjectID
+	for _, movie := range scanned {
+		if movie.CachedAt.Before(cachedBefore) && movie.Source != models.MovieSourceSeed {
+			candidates = append(candidates, movie.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, last, nil
+	}
+
+	stages := []bson.M{
+		{"$match": bson.M{"_id": bson.M{"$in": candidates}}},
+		{"$project": bson.M{"_id": 1}},
+	}
+	unreferenced := bson.M{}
+	lookup := func(collection string, match bson.M) {
+		as := fmt.Sprintf("_ref%d", len(unreferenced))
+		stages = append(stages, bson.M{"$lookup": bson.M{
+			"from": collection,
+			"let":  bson.M{"movie_id": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$expr": match}},
+				{"$limit": 1},
+				{"$project": bson.M{"_id": 1}},
+			},
+			"as": as,
+		}})
+		unreferenced[as] = bson.M{"$size": 0}
+	}
+	for _, ref := range movieReferences {
+		lookup(ref.collection, bson.M{"$eq": bson.A{"$" + ref.field, "$$movie_id"}})
+	}
+	for _, ref := range movieListReferences {
+		lookup(ref.collection, bson.M{"$in": bson.A{"$$movie_id", "$" + ref.field}})
+	}
+	stages = append(stages, bson.M{"$match": unreferenced}, bson.M{"$project": bson.M{"_id": 1}})
+
+	cursor, err = collection.Aggregate(ctx, stages)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	var unused []models.Movie
+	if err := cursor.All(ctx, &unused); err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	for _, movie := range unused {
+		ids = append(ids, movie.ID)
+	}
+	return ids, last, nil
+}
+
+// DeleteMovies deletes the movies with the given IDs together with their
+// plot embeddings, revisions and provider conflicts, returning how many
+// movies were deleted
+func (r *MovieRepository) DeleteMovies(ids []primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+
+	for _, name := range []string{"movie_embeddings", "movie_revisions", "metadata_conflicts"} {
+		if _, err := r.db.GetCollection(name).DeleteMany(ctx, bson.M{"movie_id": bson.M{"$in": ids}}); err != nil {
+			return 0, err
+		}
+	}
+	result, err := r.db.GetCollection("movies").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
 // GetDB returns the underlying MongoDB database instance
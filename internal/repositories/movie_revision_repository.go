@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MovieRevisionRepository stores what changed on movie documents
+type MovieRevisionRepository struct {
+	db *database.MongoDB
+}
+
+func NewMovieRevisionRepository(db *database.MongoDB) *MovieRevisionRepository {
+	return &MovieRevisionRepository{db: db}
+}
+
+func (r *MovieRevisionRepository) Create(revision *models.MovieRevision) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_revisions")
+
+	revision.ID = primitive.NewObjectID()
+	_, err := collection.InsertOne(ctx, revision)
+	return err
+}
+
+// FindByMovie returns a cursor page of the movie's revisions, oldest first
+func (r *MovieRevisionRepository) FindByMovie(movieID primitive.ObjectID, cursor pagination.Cursor) ([]models.MovieRevision, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_revisions")
+
+	cur, err := collection.Find(ctx, cursor.Filter(bson.M{"movie_id": movieID}), cursor.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	revisions := []models.MovieRevision{}
+	if err := cur.All(ctx, &revisions); err != nil {
+		return nil, false, err
+	}
+
+	n, more := cursor.Trim(len(revisions))
+	return revisions[:n], more, nil
+}
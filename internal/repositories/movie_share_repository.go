@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MovieShareRepository stores the movies users share with each other
+type MovieShareRepository struct {
+	db *database.MongoDB
+}
+
+func NewMovieShareRepository(db *database.MongoDB) *MovieShareRepository {
+	return &MovieShareRepository{db: db}
+}
+
+// Create stores the share, reporting false when the sender already shared
+// the movie with the recipient
+func (r *MovieShareRepository) Create(share *models.MovieShare) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_shares")
+
+	stampCreated(share)
+	result, err := collection.InsertOne(ctx, share)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	share.ID = result.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+// CountSentSince counts the shares the user sent since the given time
+func (r *MovieShareRepository) CountSentSince(senderID primitive.ObjectID, since time.Time) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_shares")
+
+	return collection.CountDocuments(ctx, bson.M{"sender_id": senderID, "created_at": bson.M{"$gte": since}})
+}
+
+// FindByUserPage returns one page of the movies shared with the user, newest
+// first, and the total count
+func (r *MovieShareRepository) FindByUserPage(userID primitive.ObjectID, skip, limit int64) ([]models.MovieShare, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("movie_shares")
+
+	filter := bson.M{"user_id": userID}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	shares := []models.MovieShare{}
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(shares))
+	if err != nil {
+		return nil, 0, err
+	}
+	return shares, total, nil
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepository struct {
+	db *database.MongoDB
+}
+
+func NewNotificationRepository(db *database.MongoDB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) Create(notification *models.Notification) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	stampCreated(notification)
+
+	result, err := collection.InsertOne(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	notification.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ExistsForMovie reports whether the user already has a notification of the type for the movie
+func (r *NotificationRepository) ExistsForMovie(userID primitive.ObjectID, notificationType string, movieID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"user_id":  userID,
+		"type":     notificationType,
+		"movie_id": movieID,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetUserNotificationsPage returns one page of the user's notifications, newest first, and the total count
+func (r *NotificationRepository) GetUserNotificationsPage(userID primitive.ObjectID, unreadOnly bool, skip, limit int64) ([]models.Notification, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(notifications))
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+// FindCreatedAfter returns up to limit of the user's notifications created
+// after the given time, oldest first
+func (r *NotificationRepository) FindCreatedAfter(userID primitive.ObjectID, after time.Time, limit int64) ([]models.Notification, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "created_at": bson.M{"$gt": after}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// FindByID returns the notification, or nil when it does not exist
+func (r *NotificationRepository) FindByID(id primitive.ObjectID) (*models.Notification, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	var notification models.Notification
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&notification)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkRead marks one of the user's notifications read, returning false if it does not exist
+func (r *NotificationRepository) MarkRead(userID, id primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("notifications")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{"$set": bson.M{"read_at": getCurrentTime()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OAuthAppRepository stores the third-party apps users registered
+type OAuthAppRepository struct {
+	db *database.MongoDB
+}
+
+func NewOAuthAppRepository(db *database.MongoDB) *OAuthAppRepository {
+	return &OAuthAppRepository{db: db}
+}
+
+func (r *OAuthAppRepository) Create(app *models.OAuthApp) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	stampCreated(app)
+	result, err := collection.InsertOne(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	app.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *OAuthAppRepository) FindByClientID(clientID string) (*models.OAuthApp, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	var app models.OAuthApp
+	err := collection.FindOne(ctx, bson.M{"client_id": clientID}).Decode(&app)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &app, nil
+}
+
+// FindByUser returns the apps the user registered, newest first
+func (r *OAuthAppRepository) FindByUser(userID primitive.ObjectID) ([]models.OAuthApp, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var apps []models.OAuthApp
+	if err := cursor.All(ctx, &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// CountByUser returns how many apps the user registered
+func (r *OAuthAppRepository) CountByUser(userID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	return collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// Update changes the name, redirect URIs and scopes of one of the user's
+// apps, returning the updated app or nil when the user has no such app
+func (r *OAuthAppRepository) Update(userID primitive.ObjectID, clientID, name string, redirectURIs, scopes []string) (*models.OAuthApp, error) {
+	return r.update(userID, clientID, bson.M{"name": name, "redirect_uris": redirectURIs, "scopes": scopes})
+}
+
+// SetSecret replaces the client secret hash of one of the user's apps
+func (r *OAuthAppRepository) SetSecret(userID primitive.ObjectID, clientID, secretHash string) (*models.OAuthApp, error) {
+	return r.update(userID, clientID, bson.M{"client_secret_hash": secretHash})
+}
+
+func (r *OAuthAppRepository) update(userID primitive.ObjectID, clientID string, set bson.M) (*models.OAuthApp, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	var app models.OAuthApp
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": userID, "client_id": clientID},
+		withUpdatedAt(bson.M{"$set": set}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&app)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &app, nil
+}
+
+// Delete removes one of the user's apps, returning false if there was none
+func (r *OAuthAppRepository) Delete(userID primitive.ObjectID, clientID string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_apps")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID, "client_id": clientID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OAuthCodeRepository stores authorization codes until the app exchanges them
+type OAuthCodeRepository struct {
+	db *database.MongoDB
+}
+
+func NewOAuthCodeRepository(db *database.MongoDB) *OAuthCodeRepository {
+	return &OAuthCodeRepository{db: db}
+}
+
+func (r *OAuthCodeRepository) Create(code *models.OAuthCode) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_codes")
+
+	stampCreated(code)
+	result, err := collection.InsertOne(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	code.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Take returns and deletes the unexpired code with the given hash, so that
+// a code can only be exchanged once
+func (r *OAuthCodeRepository) Take(codeHash string) (*models.OAuthCode, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_codes")
+
+	var code models.OAuthCode
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"code_hash":  codeHash,
+		"expires_at": bson.M{"$gt": getCurrentTime()},
+	}).Decode(&code)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// DeleteByClient removes the codes granted to an app
+func (r *OAuthCodeRepository) DeleteByClient(clientID string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("oauth_codes")
+
+	_, err := collection.DeleteMany(ctx, bson.M{"client_id": clientID})
+	return err
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// usageDayFormat is the layout used for daily OMDb usage keys
+const usageDayFormat = "2006-01-02"
+
+type OMDbUsageRepository struct {
+	db *database.MongoDB
+}
+
+func NewOMDbUsageRepository(db *database.MongoDB) *OMDbUsageRepository {
+	return &OMDbUsageRepository{db: db}
+}
+
+// RecordRequest increments today's OMDb request counter
+func (r *OMDbUsageRepository) RecordRequest() error {
+	return r.increment("count")
+}
+
+// RecordError increments today's failed OMDb request counter
+func (r *OMDbUsageRepository) RecordError() error {
+	return r.increment("errors")
+}
+
+func (r *OMDbUsageRepository) increment(field string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("omdb_usage")
+
+	now := getCurrentTime()
+	update := withUpdatedAt(bson.M{"$inc": bson.M{field: 1}})
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": now.Format(usageDayFormat)}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetTodayCount returns the number of OMDb requests made today
+func (r *OMDbUsageRepository) GetTodayCount() (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("omdb_usage")
+
+	var usage models.OMDbUsage
+	err := collection.FindOne(ctx, bson.M{"_id": getCurrentTime().Format(usageDayFormat)}).Decode(&usage)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return usage.Count, nil
+}
+
+// GetRecentUsage returns daily usage records for the last N days, newest first
+func (r *OMDbUsageRepository) GetRecentUsage(days int) ([]models.OMDbUsage, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("omdb_usage")
+
+	since := getCurrentTime().AddDate(0, 0, -(days - 1)).Format(usageDayFormat)
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$gte": since}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var usage []models.OMDbUsage
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OutboxRepository stores domain events until they are streamed
+type OutboxRepository struct {
+	db *database.MongoDB
+}
+
+func NewOutboxRepository(db *database.MongoDB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Create stores the event; its ID must be set, as the payload refers to it
+func (r *OutboxRepository) Create(event *models.OutboxEvent) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("event_outbox")
+
+	stampCreated(event)
+	_, err := collection.InsertOne(ctx, event)
+	return err
+}
+
+// ClaimNext locks the oldest undelivered event that is not locked until the
+// given time and counts the attempt. It returns nil when none is due.
+func (r *OutboxRepository) ClaimNext(now time.Time, lockUntil time.Time) (*models.OutboxEvent, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("event_outbox")
+
+	filter := bson.M{
+		"delivered_at": nil,
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"locked_until": lockUntil},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var event models.OutboxEvent
+	if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(id primitive.ObjectID, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("event_outbox")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"delivered_at": at},
+			"$unset": bson.M{"locked_until": "", "last_error": ""},
+		},
+	)
+	return err
+}
+
+// MarkFailed records why delivery failed and holds the event until retryAt
+func (r *OutboxRepository) MarkFailed(id primitive.ObjectID, lastError string, retryAt time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("event_outbox")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_error": lastError, "locked_until": retryAt}},
+	)
+	return err
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PersonFollowRepository stores the directors and actors users follow
+type PersonFollowRepository struct {
+	db *database.MongoDB
+}
+
+func NewPersonFollowRepository(db *database.MongoDB) *PersonFollowRepository {
+	return &PersonFollowRepository{db: db}
+}
+
+// Create stores the follow, reporting false when the user already follows
+// the person in that role
+func (r *PersonFollowRepository) Create(follow *models.PersonFollow) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("person_follows")
+
+	stampCreated(follow)
+	result, err := collection.InsertOne(ctx, follow)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	follow.ID = result.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+// FindByUser returns the people the user follows, most recently followed first
+func (r *PersonFollowRepository) FindByUser(userID primitive.ObjectID) ([]models.PersonFollow, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("person_follows")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	follows := []models.PersonFollow{}
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+// FindFollowers returns the follows of any of the directors or actors
+func (r *PersonFollowRepository) FindFollowers(directors, actors []string) ([]models.PersonFollow, error) {
+	follows := []models.PersonFollow{}
+	if len(directors) == 0 && len(actors) == 0 {
+		return follows, nil
+	}
+
+	ctx := context.Background()
+	collection := r.db.GetCollection("person_follows")
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"role": models.PersonRoleDirector, "name": bson.M{"$in": directors}},
+		bson.M{"role": models.PersonRoleActor, "name": bson.M{"$in": actors}},
+	}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+// Delete removes one of the user's follows, reporting whether it existed
+func (r *PersonFollowRepository) Delete(userID, followID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("person_follows")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": followID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
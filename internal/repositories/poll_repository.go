@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PollRepository stores movie night polls and their ballots
+type PollRepository struct {
+	db *database.MongoDB
+}
+
+func NewPollRepository(db *database.MongoDB) *PollRepository {
+	return &PollRepository{db: db}
+}
+
+func (r *PollRepository) Create(poll *models.Poll) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	stampCreated(poll)
+
+	result, err := collection.InsertOne(ctx, poll)
+	if err != nil {
+		return err
+	}
+	poll.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *PollRepository) FindByID(id primitive.ObjectID) (*models.Poll, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	var poll models.Poll
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&poll)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// FindForUserPage returns one page of the polls the user owns or was invited
+// to, newest first, and the total count
+func (r *PollRepository) FindForUserPage(userID primitive.ObjectID, skip, limit int64) ([]models.Poll, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"user_id": userID},
+		bson.M{"participants.user_id": userID},
+	}}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	polls := []models.Poll{}
+	if err := cursor.All(ctx, &polls); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(polls))
+	if err != nil {
+		return nil, 0, err
+	}
+	return polls, total, nil
+}
+
+// FindScheduled returns the polls the user owns or was invited to whose
+// movie night starts between from and to, soonest first
+func (r *PollRepository) FindScheduled(userID primitive.ObjectID, from, to time.Time) ([]models.Poll, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"user_id": userID},
+			bson.M{"participants.user_id": userID},
+		},
+		"scheduled_for": bson.M{"$gte": from, "$lte": to},
+	}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "scheduled_for", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	polls := []models.Poll{}
+	if err := cursor.All(ctx, &polls); err != nil {
+		return nil, err
+	}
+	return polls, nil
+}
+
+// Update sets the poll's title, closing time and movie night time; a nil
+// closing time leaves the poll open until closed by hand, and a nil movie
+// night time unschedules it
+func (r *PollRepository) Update(id primitive.ObjectID, title string, closesAt, scheduledFor *time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	set := bson.M{"title": title}
+	unset := bson.M{}
+	if closesAt != nil {
+		set["closes_at"] = *closesAt
+	} else {
+		unset["closes_at"] = ""
+	}
+	if scheduledFor != nil {
+		set["scheduled_for"] = *scheduledFor
+	} else {
+		unset["scheduled_for"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Close ends voting as of at, reporting false when the poll was already
+// closed by hand or does not exist
+func (r *PollRepository) Close(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "closed_at": bson.M{"$exists": false}},
+		withUpdatedAt(bson.M{"$set": bson.M{"closed_at": at}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// MarkResultPosted records that the poll's winner was posted to chat,
+// reporting false when it already was
+func (r *PollRepository) MarkResultPosted(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("polls")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "result_posted_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"result_posted_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Delete removes the poll with its ballots
+func (r *PollRepository) Delete(id primitive.ObjectID) error {
+	ctx := context.Background()
+
+	if _, err := r.db.GetCollection("polls").DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return err
+	}
+	_, err := r.db.GetCollection("poll_ballots").DeleteMany(ctx, bson.M{"poll_id": id})
+	return err
+}
+
+// SaveBallot stores the user's ranking for the poll, replacing an earlier one
+func (r *PollRepository) SaveBallot(pollID, userID primitive.ObjectID, ranking []primitive.ObjectID) (*models.PollBallot, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("poll_ballots")
+
+	findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var ballot models.PollBallot
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"poll_id": pollID, "user_id": userID},
+		withUpsertTimestamps(bson.M{"$set": bson.M{"ranking": ranking}}),
+		findOptions,
+	).Decode(&ballot)
+	if err != nil {
+		return nil, err
+	}
+	return &ballot, nil
+}
+
+// FindBallot returns the user's ballot for the poll, or nil when they have
+// not voted
+func (r *PollRepository) FindBallot(pollID, userID primitive.ObjectID) (*models.PollBallot, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("poll_ballots")
+
+	var ballot models.PollBallot
+	err := collection.FindOne(ctx, bson.M{"poll_id": pollID, "user_id": userID}).Decode(&ballot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ballot, nil
+}
+
+// FindBallots returns every ballot cast in the poll
+func (r *PollRepository) FindBallots(pollID primitive.ObjectID) ([]models.PollBallot, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("poll_ballots")
+
+	cursor, err := collection.Find(ctx, bson.M{"poll_id": pollID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ballots := []models.PollBallot{}
+	if err := cursor.All(ctx, &ballots); err != nil {
+		return nil, err
+	}
+	return ballots, nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// profileScopedCollections hold per-user data keyed by user_id, which for a
+// kids profile is the profile ID
+var profileScopedCollections = []string{"watchlists", "ratings", "watch_progress", "recently_viewed", "notifications", "dashboards", "person_follows"}
+
+type ProfileRepository struct {
+	db *database.MongoDB
+}
+
+func NewProfileRepository(db *database.MongoDB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+func (r *ProfileRepository) Create(profile *models.Profile) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	stampCreated(profile)
+
+	result, err := collection.InsertOne(ctx, profile)
+	if err != nil {
+		return err
+	}
+
+	profile.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByID returns the profile, or nil when it does not exist
+func (r *ProfileRepository) FindByID(profileID primitive.ObjectID) (*models.Profile, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	var profile models.Profile
+	err := collection.FindOne(ctx, bson.M{"_id": profileID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// FindForParent returns the profile when it belongs to the parent account
+func (r *ProfileRepository) FindForParent(profileID, parentID primitive.ObjectID) (*models.Profile, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	var profile models.Profile
+	err := collection.FindOne(ctx, bson.M{"_id": profileID, "parent_id": parentID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// ListByParent returns the account's profiles, oldest first
+func (r *ProfileRepository) ListByParent(parentID primitive.ObjectID) ([]models.Profile, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	cursor, err := collection.Find(ctx, bson.M{"parent_id": parentID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	profiles := []models.Profile{}
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (r *ProfileRepository) CountByParent(parentID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	return collection.CountDocuments(ctx, bson.M{"parent_id": parentID})
+}
+
+// Update replaces the profile's name and certification cap, reporting whether
+// the parent owns a profile with that ID
+func (r *ProfileRepository) Update(profileID, parentID primitive.ObjectID, name, maxCertification string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": profileID, "parent_id": parentID},
+		withUpdatedAt(bson.M{"$set": bson.M{
+			"name":              name,
+			"max_certification": maxCertification,
+		}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Delete removes the profile together with its watchlist, ratings and other
+// per-profile data, reporting whether the parent owned it
+func (r *ProfileRepository) Delete(profileID, parentID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("profiles")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": profileID, "parent_id": parentID})
+	if err != nil {
+		return false, err
+	}
+	if result.DeletedCount == 0 {
+		return false, nil
+	}
+
+	for _, name := range profileScopedCollections {
+		if _, err := r.db.GetCollection(name).DeleteMany(ctx, bson.M{"user_id": profileID}); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProgressRepository struct {
+	db *database.MongoDB
+}
+
+func NewProgressRepository(db *database.MongoDB) *ProgressRepository {
+	return &ProgressRepository{db: db}
+}
+
+// Upsert stores the user's progress on a movie. A nil completedAt marks the
+// movie as in progress again, e.g. when it is rewatched.
+func (r *ProgressRepository) Upsert(userID, movieID primitive.ObjectID, minutesWatched int, completedAt *time.Time) (*models.WatchProgress, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watch_progress")
+
+	update := bson.M{"$set": bson.M{"minutes_watched": minutesWatched}}
+	if completedAt != nil {
+		update["$set"].(bson.M)["completed_at"] = completedAt
+	} else {
+		update["$unset"] = bson.M{"completed_at": ""}
+	}
+
+	findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var progress models.WatchProgress
+	err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userID, "movie_id": movieID}, withUpsertTimestamps(update), findOptions).Decode(&progress)
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// GetInProgressPage returns one page of the user's unfinished movies, most recently watched first, and the total count
+func (r *ProgressRepository) GetInProgressPage(userID primitive.ObjectID, skip, limit int64) ([]models.WatchProgress, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watch_progress")
+
+	filter := bson.M{
+		"user_id":         userID,
+		"minutes_watched": bson.M{"$gt": 0},
+		"completed_at":    bson.M{"$exists": false},
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var progress []models.WatchProgress
+	if err := cursor.All(ctx, &progress); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(progress))
+	if err != nil {
+		return nil, 0, err
+	}
+	return progress, total, nil
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"movie-watchlist/internal/database"
 	"movie-watchlist/internal/models"
-	
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type RatingRepository struct {
@@ -23,8 +24,9 @@ func (r *RatingRepository) Create(rating *models.Rating) error {
 	ctx := context.Background()
 	collection := r.db.GetCollection("ratings")
 	
-	rating.CreatedAt = getCurrentTime()
-	rating.UpdatedAt = getCurrentTime()
+	// Imports carry their own timestamps; everything else is rated now
+	stampCreated(rating)
+	rating.Version = 1
 	
 	result, err := collection.InsertOne(ctx, rating)
 	if err != nil {
@@ -35,23 +37,30 @@ func (r *RatingRepository) Create(rating *models.Rating) error {
 	return nil
 }
 
-func (r *RatingRepository) Update(userID, movieID primitive.ObjectID, rating int) error {
+// Update changes a rating if it is still at expectedVersion (nil skips the
+// check), returning false when no rating matched
+func (r *RatingRepository) Update(userID, movieID primitive.ObjectID, rating int, expectedVersion *int) (bool, error) {
+	return r.update(userID, movieID, bson.M{"rating": rating}, expectedVersion)
+}
+
+// UpdateAt is Update recording updatedAt as the time the rating changed
+func (r *RatingRepository) UpdateAt(userID, movieID primitive.ObjectID, rating int, updatedAt time.Time, expectedVersion *int) (bool, error) {
+	return r.update(userID, movieID, bson.M{"rating": rating, "updated_at": updatedAt}, expectedVersion)
+}
+
+func (r *RatingRepository) update(userID, movieID primitive.ObjectID, set bson.M, expectedVersion *int) (bool, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("ratings")
-	
-	update := bson.M{
-		"$set": bson.M{
-			"rating":     rating,
-			"updated_at": getCurrentTime(),
-		},
-	}
-	
-	_, err := collection.UpdateOne(ctx, bson.M{
+
+	update := withUpdatedAt(bson.M{"$set": set, "$inc": bson.M{"version": 1}})
+	result, err := collection.UpdateOne(ctx, withVersion(bson.M{
 		"user_id":  userID,
 		"movie_id": movieID,
-	}, update)
-	
-	return err
+	}, expectedVersion), update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
 }
 
 func (r *RatingRepository) GetUserRating(userID, movieID primitive.ObjectID) (*models.Rating, error) {
@@ -73,6 +82,26 @@ func (r *RatingRepository) GetUserRating(userID, movieID primitive.ObjectID) (*m
 	return &rating, nil
 }
 
+// GetUserRatingForAny returns the user's rating on any of the given movie IDs, or nil
+func (r *RatingRepository) GetUserRatingForAny(userID primitive.ObjectID, movieIDs []primitive.ObjectID) (*models.Rating, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("ratings")
+
+	var rating models.Rating
+	err := collection.FindOne(ctx, bson.M{
+		"user_id":  userID,
+		"movie_id": bson.M{"$in": movieIDs},
+	}).Decode(&rating)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rating, nil
+}
+
 func (r *RatingRepository) GetUserRatings(userID primitive.ObjectID) ([]models.Rating, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("ratings")
@@ -91,6 +120,35 @@ func (r *RatingRepository) GetUserRatings(userID primitive.ObjectID) ([]models.R
 	return ratings, nil
 }
 
+// GetUserRatingsPage returns one page of a user's ratings, most recently updated first, with the total count
+func (r *RatingRepository) GetUserRatingsPage(userID primitive.ObjectID, skip, limit int64) ([]models.Rating, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("ratings")
+
+	filter := bson.M{"user_id": userID}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var ratings []models.Rating
+	if err := cursor.All(ctx, &ratings); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(ratings))
+	if err != nil {
+		return nil, 0, err
+	}
+	return ratings, total, nil
+}
+
 func (r *RatingRepository) GetHighRatedGenres(userID primitive.ObjectID, threshold int) ([]string, error) {
 	ctx := context.Background()
 	ratingsCollection := r.db.GetCollection("ratings")
@@ -165,3 +223,107 @@ func (r *RatingRepository) GetRatedMovieIDs(userID primitive.ObjectID) ([]primit
 	
 	return movieIDs, nil
 }
+
+// GetHighRatedMovies returns the movies the user rated at or above threshold.
+// Given fields, only those are returned.
+func (r *RatingRepository) GetHighRatedMovies(userID primitive.ObjectID, threshold int, fields ...string) ([]models.Movie, error) {
+	ctx := context.Background()
+	ratingsCollection := r.db.GetCollection("ratings")
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": userID,
+			"rating":  bson.M{"$gte": threshold},
+		}},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$replaceRoot": bson.M{"newRoot": "$movie"}},
+	}
+	if fields != nil {
+		pipeline = append(pipeline, bson.M{"$project": projection(fields)})
+	}
+
+	cursor, err := ratingsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movies []models.Movie
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// MovieFans is a movie together with the users who rated it highly. Only the
+// movie's ID, title and director are read.
+type MovieFans struct {
+	Movie   models.Movie         `bson:"movie"`
+	UserIDs []primitive.ObjectID `bson:"user_ids"`
+}
+
+// GetAllRatings returns every rating of every user, for offline evaluation
+func (r *RatingRepository) GetAllRatings() ([]models.Rating, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("ratings")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ratings := []models.Rating{}
+	if err := cursor.All(ctx, &ratings); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// GetHighRatedMovieFans returns every movie rated at or above threshold by
+// anyone, with the users who did, most fans first
+func (r *RatingRepository) GetHighRatedMovieFans(threshold int) ([]MovieFans, error) {
+	ctx := context.Background()
+	ratingsCollection := r.db.GetCollection("ratings")
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"rating": bson.M{"$gte": threshold}}},
+		{"$group": bson.M{
+			"_id":      "$movie_id",
+			"user_ids": bson.M{"$addToSet": "$user_id"},
+		}},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$project": bson.M{
+			"user_ids":       1,
+			"movie._id":      1,
+			"movie.title":    1,
+			"movie.director": 1,
+			"fans":           bson.M{"$size": "$user_ids"},
+		}},
+		{"$sort": bson.D{{Key: "fans", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := ratingsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []MovieFans
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
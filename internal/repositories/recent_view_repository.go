@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecentViewRepository struct {
+	db *database.MongoDB
+}
+
+func NewRecentViewRepository(db *database.MongoDB) *RecentViewRepository {
+	return &RecentViewRepository{db: db}
+}
+
+// Record moves the movie to the top of the user's history, adding it if new
+func (r *RecentViewRepository) Record(userID, movieID primitive.ObjectID, viewedAt time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recently_viewed")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "movie_id": movieID},
+		bson.M{"$set": bson.M{"viewed_at": viewedAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Trim deletes all but the user's keep most recent views
+func (r *RecentViewRepository) Trim(userID primitive.ObjectID, keep int64) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recently_viewed")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "viewed_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(keep).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []models.RecentView
+	if err := cursor.All(ctx, &stale); err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(stale))
+	for _, view := range stale {
+		ids = append(ids, view.ID)
+	}
+	_, err = collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// GetRecentPage returns one page of the user's history, most recent first, and the total count
+func (r *RecentViewRepository) GetRecentPage(userID primitive.ObjectID, skip, limit int64) ([]models.RecentView, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recently_viewed")
+
+	filter := bson.M{"user_id": userID}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "viewed_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var views []models.RecentView
+	if err := cursor.All(ctx, &views); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(views))
+	if err != nil {
+		return nil, 0, err
+	}
+	return views, total, nil
+}
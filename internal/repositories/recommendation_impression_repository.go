@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImpressionCount is how many impressions share one algorithm, row or genre
+// and how many of them led to a watchlist add, a rating or either
+type ImpressionCount struct {
+	Key         string `bson:"_id"`
+	Impressions int64  `bson:"impressions"`
+	Watchlisted int64  `bson:"watchlisted"`
+	Rated       int64  `bson:"rated"`
+	Converted   int64  `bson:"converted"`
+}
+
+// ImpressionSummary breaks impressions down by algorithm, row and genre
+type ImpressionSummary struct {
+	Algorithms []ImpressionCount `bson:"algorithms"`
+	Rows       []ImpressionCount `bson:"rows"`
+	Genres     []ImpressionCount `bson:"genres"`
+}
+
+// RecommendationImpressionRepository stores the recommendations shown to
+// users and whether they converted
+type RecommendationImpressionRepository struct {
+	db *database.MongoDB
+}
+
+func NewRecommendationImpressionRepository(db *database.MongoDB) *RecommendationImpressionRepository {
+	return &RecommendationImpressionRepository{db: db}
+}
+
+// Record stores the impressions, skipping movies already shown to the user in
+// the same row that day
+func (r *RecommendationImpressionRepository) Record(impressions []models.RecommendationImpression) error {
+	if len(impressions) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_impressions")
+
+	writes := make([]mongo.WriteModel, 0, len(impressions))
+	for _, impression := range impressions {
+		filter := bson.M{
+			"user_id":  impression.UserID,
+			"movie_id": impression.MovieID,
+			"row":      impression.Row,
+			"day":      impression.Day,
+		}
+		update := bson.M{"$setOnInsert": bson.M{
+			"algorithm": impression.Algorithm,
+			"genres":    impression.Genres,
+			"position":  impression.Position,
+			"shown_at":  impression.ShownAt,
+		}}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	_, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// MarkConverted sets field, watchlisted_at or rated_at, on the user's
+// impressions of any of the movies shown since the given time that have not
+// converted that way yet
+func (r *RecommendationImpressionRepository) MarkConverted(userID primitive.ObjectID, movieIDs []primitive.ObjectID, field string, since, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_impressions")
+
+	filter := bson.M{
+		"user_id":  userID,
+		"movie_id": bson.M{"$in": movieIDs},
+		"shown_at": bson.M{"$gte": since},
+		field:      bson.M{"$exists": false},
+	}
+	_, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{field: at}})
+	return err
+}
+
+// Summarize counts the impressions shown since the given time and their
+// conversions per algorithm, row and genre
+func (r *RecommendationImpressionRepository) Summarize(since time.Time) (*ImpressionSummary, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_impressions")
+
+	counts := func(key string) bson.M {
+		return bson.M{"$group": bson.M{
+			"_id":         key,
+			"impressions": bson.M{"$sum": 1},
+			"watchlisted": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$watchlisted_at", false}}, 1, 0}}},
+			"rated":       bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$rated_at", false}}, 1, 0}}},
+			"converted": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$or": bson.A{
+				bson.M{"$ifNull": bson.A{"$watchlisted_at", false}},
+				bson.M{"$ifNull": bson.A{"$rated_at", false}},
+			}}, 1, 0}}},
+		}}
+	}
+	byImpressions := bson.M{"$sort": bson.D{{Key: "impressions", Value: -1}, {Key: "_id", Value: 1}}}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"shown_at": bson.M{"$gte": since}}},
+		{"$facet": bson.M{
+			"algorithms": []bson.M{counts("$algorithm"), byImpressions},
+			"rows":       []bson.M{counts("$row"), byImpressions},
+			"genres":     []bson.M{{"$unwind": "$genres"}, counts("$genres"), byImpressions},
+		}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []ImpressionSummary
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &ImpressionSummary{}, nil
+	}
+	return &results[0], nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"movie-watchlist/internal/database"
 	"movie-watchlist/internal/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -115,6 +116,116 @@ func (r *RecommendationRepository) GetHighRatedGenres(userID primitive.ObjectID,
 	return genres, nil
 }
 
+// GetRecentlyViewedGenres returns the genres of the user's most recently
+// viewed movies, most frequent first
+func (r *RecommendationRepository) GetRecentlyViewedGenres(userID primitive.ObjectID, recent int64) ([]string, error) {
+	ctx := context.Background()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID}},
+		{"$sort": bson.M{"viewed_at": -1}},
+		{"$limit": recent},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$project": bson.M{"genres": bson.M{"$split": bson.A{"$movie.genre", ","}}}},
+		{"$unwind": "$genres"},
+		{"$project": bson.M{"genre": bson.M{"$trim": bson.M{"input": "$genres"}}}},
+		{"$match": bson.M{"genre": bson.M{"$ne": ""}}},
+		{"$group": bson.M{"_id": "$genre", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := r.db.GetCollection("recently_viewed").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Genre string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	genres := make([]string, 0, len(results))
+	for _, result := range results {
+		genres = append(genres, result.Genre)
+	}
+	return genres, nil
+}
+
+// GetHighRatedKeywords returns up to limit keywords of the movies the user
+// rated at least threshold, most frequent first
+func (r *RecommendationRepository) GetHighRatedKeywords(userID primitive.ObjectID, threshold int, limit int64) ([]string, error) {
+	ctx := context.Background()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID, "rating": bson.M{"$gte": threshold}}},
+		{"$lookup": bson.M{
+			"from":         "movies",
+			"localField":   "movie_id",
+			"foreignField": "_id",
+			"as":           "movie",
+		}},
+		{"$unwind": "$movie"},
+		{"$unwind": "$movie.keywords"},
+		{"$group": bson.M{"_id": "$movie.keywords", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": limit},
+	}
+
+	cursor, err := r.db.GetCollection("ratings").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Keyword string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	keywords := make([]string, 0, len(results))
+	for _, result := range results {
+		keywords = append(keywords, result.Keyword)
+	}
+	return keywords, nil
+}
+
+// GetMoviesByKeywordsExcludingIDs fetches up to limit movies tagged with any
+// of the keywords, highest IMDb rating first
+func (r *RecommendationRepository) GetMoviesByKeywordsExcludingIDs(keywords []string, excludeIDs []primitive.ObjectID, limit int64) ([]models.Movie, error) {
+	ctx := context.Background()
+
+	filter := bson.M{"keywords": bson.M{"$in": keywords}}
+	if len(excludeIDs) > 0 {
+		filter["_id"] = bson.M{"$nin": excludeIDs}
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := r.db.GetCollection("movies").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []models.Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
 // GetRatedMovieIDs fetches movie IDs from ratings collection
 func (r *RecommendationRepository) GetRatedMovieIDs(userID primitive.ObjectID) ([]primitive.ObjectID, error) {
 	ctx := context.Background()
@@ -352,3 +463,393 @@ func (r *RecommendationRepository) GetMovieCountByGenre(userID primitive.ObjectI
 	
 	return genreCounts, nil
 }
+
+// GetActivityCountsSince counts watchlist adds and ratings per movie since the given time
+func (r *RecommendationRepository) GetActivityCountsSince(since time.Time) (map[primitive.ObjectID]int64, error) {
+	return r.getActivityCounts(func(timeField string) bson.M {
+		return bson.M{timeField: bson.M{"$gte": since}}
+	})
+}
+
+// GetActivityCountsForMovies counts all-time watchlist adds and ratings for the given movies
+func (r *RecommendationRepository) GetActivityCountsForMovies(movieIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	if len(movieIDs) == 0 {
+		return map[primitive.ObjectID]int64{}, nil
+	}
+	return r.getActivityCounts(func(string) bson.M {
+		return bson.M{"movie_id": bson.M{"$in": movieIDs}}
+	})
+}
+
+// getActivityCounts sums watchlist adds and ratings per movie; match builds the
+// $match stage given each collection's timestamp field
+func (r *RecommendationRepository) getActivityCounts(match func(timeField string) bson.M) (map[primitive.ObjectID]int64, error) {
+	ctx := context.Background()
+
+	counts := make(map[primitive.ObjectID]int64)
+	sources := []struct {
+		collection string
+		timeField  string
+	}{
+		{"watchlists", "added_at"},
+		{"ratings", "created_at"},
+	}
+
+	for _, source := range sources {
+		pipeline := []bson.M{
+			{"$match": match(source.timeField)},
+			{"$group": bson.M{"_id": "$movie_id", "count": bson.M{"$sum": 1}}},
+		}
+
+		cursor, err := r.db.GetCollection(source.collection).Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []struct {
+			MovieID primitive.ObjectID `bson:"_id"`
+			Count   int64              `bson:"count"`
+		}
+		err = cursor.All(ctx, &results)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			counts[result.MovieID] += result.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// Steps of the recommendation aggregation, in order
+const (
+	RecommendationStepGenres     = "genre_inference"
+	RecommendationStepExclusions = "exclusion_build"
+	RecommendationStepCandidates = "candidate_fetch"
+)
+
+// RecommendationQuery describes one recommendation run
+type RecommendationQuery struct {
+	UserID primitive.ObjectID
+	// Threshold is the lowest rating whose movies' genres and keywords count
+	// as the user's taste
+	Threshold int
+	// RecentViews is how many recently viewed movies add genres after the
+	// rated ones, and Keywords how many of the user's themes are matched
+	RecentViews int64
+	Keywords    int64
+	// KeywordLimit caps the keyword matches and Limit the genre matches and
+	// top rated movies
+	KeywordLimit int
+	Limit        int
+	// ExcludedIDs are the movies to leave out when the caller has them.
+	// When nil, rated and watchlisted movies are left out by anti-joining
+	// the candidates with the user's ratings and watchlist instead, and
+	// CollectExclusions above zero also returns up to that many of their IDs
+	// plus one, so the caller can tell whether the set is small enough to
+	// pass in next time.
+	ExcludedIDs       []primitive.ObjectID
+	CollectExclusions int
+}
+
+// RecommendationCandidates is what one recommendation aggregation returns.
+// Each candidate list leaves out the rated and watchlisted movies; keyword matches are
+// ranked by the number of themes they share with the user's favourites,
+// genre matches by the first preferred genre they have, and all of them then
+// by IMDb score.
+type RecommendationCandidates struct {
+	RatedGenres  []string `bson:"rated_genres"`
+	ViewedGenres []string `bson:"viewed_genres"`
+	// PreferredGenres are the rated genres followed by the viewed genres not
+	// already among them
+	PreferredGenres []string             `bson:"preferred_genres"`
+	Keywords        []string             `bson:"keywords"`
+	ExcludedIDs     []primitive.ObjectID `bson:"excluded_ids"`
+	KeywordMovies   []models.Movie       `bson:"keyword_movies"`
+	GenreMovies     []models.Movie       `bson:"genre_movies"`
+	TopRatedMovies  []models.Movie       `bson:"top_rated_movies"`
+}
+
+// recommendationStep is a named part of the recommendation aggregation
+type recommendationStep struct {
+	name   string
+	stages []bson.M
+}
+
+// GetRecommendationCandidates gathers everything a recommendation run needs
+// in a single aggregation: the user's rated and viewed genres and keywords,
+// the movies to exclude, and the keyword, genre and top rated candidates
+func (r *RecommendationRepository) GetRecommendationCandidates(q RecommendationQuery) (*RecommendationCandidates, error) {
+	var pipeline []bson.M
+	for _, step := range recommendationSteps(q) {
+		pipeline = append(pipeline, step.stages...)
+	}
+	return r.aggregateCandidates(pipeline)
+}
+
+// ProfileRecommendationCandidates runs the recommendation aggregation once
+// per step, each time up to and including that step, and returns the time
+// each step added. The timings are for diagnosis only: every run repeats the
+// earlier steps.
+func (r *RecommendationRepository) ProfileRecommendationCandidates(q RecommendationQuery) (*RecommendationCandidates, map[string]time.Duration, error) {
+	timings := map[string]time.Duration{}
+	var pipeline []bson.M
+	var candidates *RecommendationCandidates
+	var previous time.Duration
+	for _, step := range recommendationSteps(q) {
+		pipeline = append(pipeline, step.stages...)
+		start := time.Now()
+		var err error
+		if candidates, err = r.aggregateCandidates(pipeline); err != nil {
+			return nil, nil, err
+		}
+		elapsed := time.Since(start)
+		timings[step.name] = elapsed - previous
+		if timings[step.name] < 0 {
+			timings[step.name] = 0
+		}
+		previous = elapsed
+	}
+	return candidates, timings, nil
+}
+
+func (r *RecommendationRepository) aggregateCandidates(pipeline []bson.M) (*RecommendationCandidates, error) {
+	ctx := context.Background()
+
+	cursor, err := r.db.GetCollection("movies").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []RecommendationCandidates
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	// An empty catalogue has nothing to recommend
+	if len(results) == 0 {
+		return &RecommendationCandidates{}, nil
+	}
+	return &results[0], nil
+}
+
+// recommendationSteps builds the recommendation aggregation. It runs on the
+// movies collection, reduced to a single document that the user's signals
+// are looked up into, and ends with one $lookup that scans the catalogue
+// once and splits it into the three candidate lists with $facet.
+func recommendationSteps(q RecommendationQuery) []recommendationStep {
+	steps := []recommendationStep{{
+		name: RecommendationStepGenres,
+		stages: []bson.M{
+			{"$limit": 1},
+			{"$replaceRoot": bson.M{"newRoot": bson.M{"user_id": q.UserID}}},
+			{"$lookup": bson.M{
+				"from": "ratings",
+				"pipeline": []bson.M{
+					{"$match": bson.M{"user_id": q.UserID, "rating": bson.M{"$gte": q.Threshold}}},
+					{"$lookup": bson.M{
+						"from":         "movies",
+						"localField":   "movie_id",
+						"foreignField": "_id",
+						"as":           "movie",
+					}},
+					{"$unwind": "$movie"},
+					{"$facet": bson.M{
+						"genres": genreCountStages("$movie.genre"),
+						"keywords": []bson.M{
+							{"$unwind": "$movie.keywords"},
+							{"$group": bson.M{"_id": "$movie.keywords", "count": bson.M{"$sum": 1}}},
+							{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+							{"$limit": q.Keywords},
+						},
+					}},
+				},
+				"as": "liked",
+			}},
+			{"$lookup": bson.M{
+				"from": "recently_viewed",
+				"pipeline": append([]bson.M{
+					{"$match": bson.M{"user_id": q.UserID}},
+					{"$sort": bson.M{"viewed_at": -1}},
+					{"$limit": q.RecentViews},
+					{"$lookup": bson.M{
+						"from":         "movies",
+						"localField":   "movie_id",
+						"foreignField": "_id",
+						"as":           "movie",
+					}},
+					{"$unwind": "$movie"},
+				}, genreCountStages("$movie.genre")...),
+				"as": "viewed",
+			}},
+			{"$addFields": bson.M{
+				"rated_genres":  bson.M{"$map": bson.M{"input": firstOf("$liked.genres"), "in": "$$this._id"}},
+				"keywords":      bson.M{"$map": bson.M{"input": firstOf("$liked.keywords"), "in": "$$this._id"}},
+				"viewed_genres": bson.M{"$map": bson.M{"input": "$viewed", "in": "$$this._id"}},
+			}},
+			// Viewed genres are a weaker signal, so they only extend the
+			// rated ones
+			{"$addFields": bson.M{
+				"preferred_genres": bson.M{"$concatArrays": bson.A{
+					"$rated_genres",
+					bson.M{"$filter": bson.M{
+						"input": "$viewed_genres",
+						"cond": bson.M{"$not": bson.A{bson.M{"$in": bson.A{
+							bson.M{"$toLower": "$$this"},
+							bson.M{"$map": bson.M{"input": "$rated_genres", "in": bson.M{"$toLower": "$$this"}}},
+						}}}},
+					}},
+				}},
+			}},
+		},
+	}}
+
+	exclusions := recommendationStep{name: RecommendationStepExclusions}
+	if q.ExcludedIDs == nil && q.CollectExclusions > 0 {
+		exclusions.stages = []bson.M{
+			{"$lookup": bson.M{
+				"from": "ratings",
+				"pipeline": []bson.M{
+					{"$match": bson.M{"user_id": q.UserID}},
+					{"$limit": q.CollectExclusions + 1},
+					{"$project": bson.M{"_id": 0, "movie_id": 1}},
+					{"$unionWith": bson.M{
+						"coll": "watchlists",
+						"pipeline": []bson.M{
+							{"$match": bson.M{"user_id": q.UserID}},
+							{"$limit": q.CollectExclusions + 1},
+							{"$project": bson.M{"_id": 0, "movie_id": 1}},
+						},
+					}},
+					{"$group": bson.M{"_id": nil, "ids": bson.M{"$addToSet": "$movie_id"}}},
+				},
+				"as": "excluded",
+			}},
+			{"$addFields": bson.M{"excluded_ids": firstOf("$excluded.ids")}},
+		}
+	}
+	steps = append(steps, exclusions)
+
+	// A short exclusion list is filtered out before ranking. Otherwise each
+	// candidate list is ranked first and anti-joined with the user's
+	// ratings and watchlist afterwards, so the lookups stop once the list is
+	// full instead of running for the whole catalogue.
+	var unseen []bson.M
+	candidateMatch := []bson.M{}
+	if q.ExcludedIDs != nil {
+		candidateMatch = append(candidateMatch, bson.M{"$match": bson.M{"_id": bson.M{"$nin": q.ExcludedIDs}}})
+	} else {
+		unseen = unseenByStages(q.UserID)
+	}
+	ranked := func(match bson.M, sort bson.D, limit int) []bson.M {
+		stages := []bson.M{}
+		if match != nil {
+			stages = append(stages, bson.M{"$match": match})
+		}
+		stages = append(stages, bson.M{"$sort": sort})
+		stages = append(stages, unseen...)
+		return append(stages, bson.M{"$limit": limit})
+	}
+
+	facets := bson.M{
+		"genre": ranked(bson.M{"_genre_rank": bson.M{"$gte": 0}},
+			bson.D{{Key: "_genre_rank", Value: 1}, {Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}, q.Limit),
+		"top_rated": ranked(nil,
+			bson.D{{Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}, q.Limit),
+	}
+	if q.KeywordLimit > 0 {
+		facets["keyword"] = ranked(bson.M{"_overlap": bson.M{"$gt": 0}},
+			bson.D{{Key: "_overlap", Value: -1}, {Key: "imdb_score", Value: -1}, {Key: "_id", Value: 1}}, q.KeywordLimit)
+	}
+
+	// Intermediate results are not sent back
+	hidden := bson.M{"liked": 0, "viewed": 0, "excluded": 0, "candidates": 0}
+	steps = append(steps, recommendationStep{
+		name: RecommendationStepCandidates,
+		stages: []bson.M{
+			{"$lookup": bson.M{
+				"from": "movies",
+				"let": bson.M{
+					"keywords": "$keywords",
+					"genres":   bson.M{"$map": bson.M{"input": "$preferred_genres", "in": bson.M{"$toLower": "$$this"}}},
+				},
+				"pipeline": append(candidateMatch,
+					bson.M{"$addFields": bson.M{
+						// Themes shared with the user's favourites
+						"_overlap": bson.M{"$size": bson.M{"$setIntersection": bson.A{bson.M{"$ifNull": bson.A{"$keywords", bson.A{}}}, "$$keywords"}}},
+						// Position of the movie's first preferred genre, or -1
+						"_genre_rank": bson.M{"$ifNull": bson.A{
+							bson.M{"$min": bson.M{"$filter": bson.M{
+								"input": bson.M{"$map": bson.M{
+									"input": bson.M{"$split": bson.A{bson.M{"$ifNull": bson.A{"$genre", ""}}, ","}},
+									"in":    bson.M{"$indexOfArray": bson.A{"$$genres", bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$$this"}}}}},
+								}},
+								"cond": bson.M{"$gte": bson.A{"$$this", 0}},
+							}}},
+							-1,
+						}},
+					}},
+					bson.M{"$facet": facets},
+				),
+				"as": "candidates",
+			}},
+			{"$addFields": bson.M{
+				"keyword_movies":   firstOf("$candidates.keyword"),
+				"genre_movies":     firstOf("$candidates.genre"),
+				"top_rated_movies": firstOf("$candidates.top_rated"),
+			}},
+			{"$project": hidden},
+		},
+	})
+	return steps
+}
+
+// unseenByStages drops movies the user rated or has on their watchlist. Each
+// movie costs one lookup per collection on the unique (user_id, movie_id)
+// index, however many movies the user has, so it replaces $nin with
+// thousands of IDs for heavy users.
+func unseenByStages(userID primitive.ObjectID) []bson.M {
+	stages := []bson.M{}
+	for _, collection := range []string{"ratings", "watchlists"} {
+		stages = append(stages,
+			bson.M{"$lookup": bson.M{
+				"from": collection,
+				"let":  bson.M{"movie_id": "$_id"},
+				"pipeline": []bson.M{
+					{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$user_id", userID}},
+						bson.M{"$eq": bson.A{"$movie_id", "$$movie_id"}},
+					}}}},
+					{"$limit": 1},
+					{"$project": bson.M{"_id": 1}},
+				},
+				"as": "_seen",
+			}},
+			bson.M{"$match": bson.M{"_seen": bson.M{"$size": 0}}},
+		)
+	}
+	return append(stages, bson.M{"$project": bson.M{"_seen": 0}})
+}
+
+// genreCountStages splits the comma-separated genres at path and counts
+// them, most frequent first
+func genreCountStages(path string) []bson.M {
+	return []bson.M{
+		{"$project": bson.M{"genres": bson.M{"$split": bson.A{path, ","}}}},
+		{"$unwind": "$genres"},
+		{"$project": bson.M{"genre": bson.M{"$trim": bson.M{"input": "$genres"}}}},
+		{"$match": bson.M{"genre": bson.M{"$nin": bson.A{"", "N/A"}}}},
+		{"$group": bson.M{"_id": "$genre", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+}
+
+// firstOf is the first element of the array at path, or an empty array. A
+// $lookup into a $facet or $group yields at most one document, so its
+// fields come back wrapped in an array.
+func firstOf(path string) bson.M {
+	return bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{path, 0}}, bson.A{}}}
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecommendationSnapshotRepository struct {
+	db *database.MongoDB
+}
+
+func NewRecommendationSnapshotRepository(db *database.MongoDB) *RecommendationSnapshotRepository {
+	return &RecommendationSnapshotRepository{db: db}
+}
+
+// Upsert replaces the user's snapshot
+func (r *RecommendationSnapshotRepository) Upsert(snapshot *models.RecommendationSnapshot) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_snapshots")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": snapshot.UserID},
+		bson.M{"$set": bson.M{
+			"rows":        snapshot.Rows,
+			"computed_at": snapshot.ComputedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// UpdateRows replaces the rows of the user's snapshot, keeping its computed time
+func (r *RecommendationSnapshotRepository) UpdateRows(userID primitive.ObjectID, rows []models.RecommendationRow) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_snapshots")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"rows": rows}})
+	return err
+}
+
+// FindByUser returns the user's snapshot, or nil if none was computed
+func (r *RecommendationSnapshotRepository) FindByUser(userID primitive.ObjectID) (*models.RecommendationSnapshot, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("recommendation_snapshots")
+
+	var snapshot models.RecommendationSnapshot
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReviewRepository stores written reviews and the "helpful" votes on them.
+// A review's helpful_count is changed together with its votes.
+type ReviewRepository struct {
+	db *database.MongoDB
+}
+
+func NewReviewRepository(db *database.MongoDB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// Save stores the user's review of the movie, replacing the text of an
+// earlier one, and returns the stored review. Votes on it are kept.
+func (r *ReviewRepository) Save(review *models.Review) (*models.Review, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	var saved models.Review
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": review.UserID, "movie_id": review.MovieID},
+		withUpsertTimestamps(bson.M{
+			"$set":         bson.M{"username": review.Username, "body": review.Body},
+			"$setOnInsert": bson.M{"helpful_count": 0},
+		}),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (r *ReviewRepository) FindByID(id primitive.ObjectID) (*models.Review, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	var review models.Review
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// FindByUserForAny returns the user's review of any of the given equivalent
+// movies, or nil when there is none
+func (r *ReviewRepository) FindByUserForAny(userID primitive.ObjectID, movieIDs []primitive.ObjectID) (*models.Review, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	var review models.Review
+	err := collection.FindOne(ctx, bson.M{"user_id": userID, "movie_id": bson.M{"$in": movieIDs}}).Decode(&review)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Restore writes an imported review with the timestamps it was exported
+// with. A review without ID is inserted without votes; one with an ID gets
+// the new text and keeps its votes.
+func (r *ReviewRepository) Restore(review *models.Review) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	if review.ID.IsZero() {
+		review.HelpfulCount = 0
+		result, err := collection.InsertOne(ctx, review)
+		if err != nil {
+			return err
+		}
+		review.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": review.ID}, bson.M{"$set": bson.M{
+		"body":       review.Body,
+		"updated_at": review.UpdatedAt,
+	}})
+	return err
+}
+
+// FindByMoviePage returns one page of the movie's reviews and the total
+// count, the most helpful first or, with byHelpful false, the newest first
+func (r *ReviewRepository) FindByMoviePage(movieID primitive.ObjectID, byHelpful bool, skip, limit int64) ([]models.Review, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	sort := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	if byHelpful {
+		sort = append(bson.D{{Key: "helpful_count", Value: -1}}, sort...)
+	}
+	filter := bson.M{"movie_id": movieID}
+	findOptions := options.Find().
+		SetSort(sort).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []models.Review{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(reviews))
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// Delete removes a review with the votes cast on it
+func (r *ReviewRepository) Delete(id primitive.ObjectID) error {
+	ctx := context.Background()
+
+	if _, err := r.db.GetCollection("review_votes").DeleteMany(ctx, bson.M{"review_id": id}); err != nil {
+		return err
+	}
+	_, err := r.db.GetCollection("reviews").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// AddVote records the user's helpful vote on a review and counts it,
+// reporting false when the user had already voted
+func (r *ReviewRepository) AddVote(reviewID, userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+
+	vote := &models.ReviewVote{ReviewID: reviewID, UserID: userID}
+	stampCreated(vote)
+	if _, err := r.db.GetCollection("review_votes").InsertOne(ctx, vote); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err := r.db.GetCollection("reviews").UpdateOne(ctx, bson.M{"_id": reviewID}, bson.M{"$inc": bson.M{"helpful_count": 1}})
+	return true, err
+}
+
+// RemoveVote withdraws the user's helpful vote on a review, reporting false
+// when there was none
+func (r *ReviewRepository) RemoveVote(reviewID, userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+
+	result, err := r.db.GetCollection("review_votes").DeleteOne(ctx, bson.M{"review_id": reviewID, "user_id": userID})
+	if err != nil || result.DeletedCount == 0 {
+		return false, err
+	}
+	_, err = r.db.GetCollection("reviews").UpdateOne(ctx, bson.M{"_id": reviewID}, bson.M{"$inc": bson.M{"helpful_count": -1}})
+	return true, err
+}
+
+// FindVotedIDs returns which of the given reviews the user voted helpful
+func (r *ReviewRepository) FindVotedIDs(userID primitive.ObjectID, reviewIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	voted := make(map[primitive.ObjectID]bool)
+	if len(reviewIDs) == 0 {
+		return voted, nil
+	}
+	ctx := context.Background()
+	collection := r.db.GetCollection("review_votes")
+
+	cursor, err := collection.Find(ctx,
+		bson.M{"user_id": userID, "review_id": bson.M{"$in": reviewIDs}},
+		options.Find().SetProjection(bson.M{"review_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var votes []models.ReviewVote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, err
+	}
+	for _, vote := range votes {
+		voted[vote.ReviewID] = true
+	}
+	return voted, nil
+}
+
+// TopReviewers returns up to limit reviewers whose reviews were voted
+// helpful, the most votes across their reviews first
+func (r *ReviewRepository) TopReviewers(limit int64) ([]models.ReviewerStanding, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("reviews")
+
+	pipeline := []bson.M{
+		{"$group": bson.M{
+			"_id":           "$user_id",
+			"username":      bson.M{"$last": "$username"},
+			"reviews":       bson.M{"$sum": 1},
+			"helpful_votes": bson.M{"$sum": "$helpful_count"},
+		}},
+		{"$match": bson.M{"helpful_votes": bson.M{"$gt": 0}}},
+		{"$sort": bson.D{{Key: "helpful_votes", Value: -1}, {Key: "reviews", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": limit},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	standings := []models.ReviewerStanding{}
+	if err := cursor.All(ctx, &standings); err != nil {
+		return nil, err
+	}
+	return standings, nil
+}
+
+// deleteUserReviews removes the user's reviews with the votes cast on them,
+// and takes the user's own votes back from the reviews they were cast on
+func deleteUserReviews(ctx context.Context, db *database.MongoDB, userID primitive.ObjectID) error {
+	votes := db.GetCollection("review_votes")
+	reviews := db.GetCollection("reviews")
+
+	cursor, err := votes.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"review_id": 1}))
+	if err != nil {
+		return err
+	}
+	var cast []models.ReviewVote
+	if err := cursor.All(ctx, &cast); err != nil {
+		return err
+	}
+	votedIDs := make([]primitive.ObjectID, 0, len(cast))
+	for _, vote := range cast {
+		votedIDs = append(votedIDs, vote.ReviewID)
+	}
+	if len(votedIDs) > 0 {
+		if _, err := reviews.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": votedIDs}}, bson.M{"$inc": bson.M{"helpful_count": -1}}); err != nil {
+			return err
+		}
+		if _, err := votes.DeleteMany(ctx, bson.M{"user_id": userID, "review_id": bson.M{"$in": votedIDs}}); err != nil {
+			return err
+		}
+	}
+
+	cursor, err = reviews.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var written []models.Review
+	if err := cursor.All(ctx, &written); err != nil {
+		return err
+	}
+	reviewIDs := make([]primitive.ObjectID, 0, len(written))
+	for _, review := range written {
+		reviewIDs = append(reviewIDs, review.ID)
+	}
+	if len(reviewIDs) > 0 {
+		if _, err := votes.DeleteMany(ctx, bson.M{"review_id": bson.M{"$in": reviewIDs}}); err != nil {
+			return err
+		}
+	}
+	_, err = reviews.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository struct {
+	db *database.MongoDB
+}
+
+func NewSessionRepository(db *database.MongoDB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// activeFilter matches sessions that are neither revoked nor expired
+func activeFilter(filter bson.M) bson.M {
+	filter["revoked_at"] = bson.M{"$exists": false}
+	filter["expires_at"] = bson.M{"$gt": getCurrentTime()}
+	return filter
+}
+
+func (r *SessionRepository) Create(session *models.Session) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	session.LastUsedAt = stampCreated(session)
+
+	result, err := collection.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindActiveByTokenHash returns the active session holding the given refresh token hash
+func (r *SessionRepository) FindActiveByTokenHash(hash string) (*models.Session, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	var session models.Session
+	err := collection.FindOne(ctx, activeFilter(bson.M{"refresh_token_hash": hash})).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveByID returns the session if it is still active
+func (r *SessionRepository) FindActiveByID(id primitive.ObjectID) (*models.Session, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	var session models.Session
+	err := collection.FindOne(ctx, activeFilter(bson.M{"_id": id})).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveByUser returns the user's active sessions, most recently used first
+func (r *SessionRepository) FindActiveByUser(userID primitive.ObjectID) ([]models.Session, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+	cursor, err := collection.Find(ctx, activeFilter(bson.M{"user_id": userID}), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Rotate replaces the refresh token of an active session and records the device that used it.
+// It returns false if the session was revoked or rotated concurrently.
+func (r *SessionRepository) Rotate(id primitive.ObjectID, oldHash, newHash, userAgent, ip string, expiresAt time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	result, err := collection.UpdateOne(ctx,
+		activeFilter(bson.M{"_id": id, "refresh_token_hash": oldHash}),
+		bson.M{"$set": bson.M{
+			"refresh_token_hash": newHash,
+			"user_agent":         userAgent,
+			"ip":                 ip,
+			"last_used_at":       getCurrentTime(),
+			"expires_at":         expiresAt,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Touch updates the last used time of a session
+func (r *SessionRepository) Touch(id primitive.ObjectID) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": getCurrentTime()}})
+	return err
+}
+
+// Revoke revokes one of the user's active sessions, returning false if there was none
+func (r *SessionRepository) Revoke(userID, id primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	result, err := collection.UpdateOne(ctx,
+		activeFilter(bson.M{"_id": id, "user_id": userID}),
+		bson.M{"$set": bson.M{"revoked_at": getCurrentTime()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RevokeAll revokes all of the user's active sessions and returns how many were revoked
+func (r *SessionRepository) RevokeAll(userID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	result, err := collection.UpdateMany(ctx,
+		activeFilter(bson.M{"user_id": userID}),
+		bson.M{"$set": bson.M{"revoked_at": getCurrentTime()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindActiveByClient returns the active sessions held by a third-party app
+func (r *SessionRepository) FindActiveByClient(clientID string) ([]models.Session, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	cursor, err := collection.Find(ctx, activeFilter(bson.M{"client_id": clientID}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeByClient revokes all active sessions held by a third-party app
+func (r *SessionRepository) RevokeByClient(clientID string) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("sessions")
+
+	result, err := collection.UpdateMany(ctx,
+		activeFilter(bson.M{"client_id": clientID}),
+		bson.M{"$set": bson.M{"revoked_at": getCurrentTime()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
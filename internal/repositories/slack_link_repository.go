@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SlackLinkRepository stores which Slack user acts as which account in the
+// slash command, one Slack user per account
+type SlackLinkRepository struct {
+	db *database.MongoDB
+}
+
+func NewSlackLinkRepository(db *database.MongoDB) *SlackLinkRepository {
+	return &SlackLinkRepository{db: db}
+}
+
+// SetCode records the hash of a new link code for the user, creating the
+// user's link if there is none. An existing link stays linked until the
+// code is used.
+func (r *SlackLinkRepository) SetCode(userID primitive.ObjectID, codeHash string, expiresAt time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("slack_links")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		withUpsertTimestamps(bson.M{"$set": bson.M{"code_hash": codeHash, "code_expires_at": expiresAt}}),
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindByCode returns the link whose unexpired link code hashes to codeHash
+func (r *SlackLinkRepository) FindByCode(codeHash string, now time.Time) (*models.SlackLink, error) {
+	return r.findOne(bson.M{"code_hash": codeHash, "code_expires_at": bson.M{"$gt": now}})
+}
+
+// FindBySlackUser returns the linked account of a Slack user
+func (r *SlackLinkRepository) FindBySlackUser(teamID, slackUserID string) (*models.SlackLink, error) {
+	return r.findOne(bson.M{"team_id": teamID, "slack_user_id": slackUserID, "linked_at": bson.M{"$exists": true}})
+}
+
+func (r *SlackLinkRepository) FindByUser(userID primitive.ObjectID) (*models.SlackLink, error) {
+	return r.findOne(bson.M{"user_id": userID})
+}
+
+func (r *SlackLinkRepository) findOne(filter bson.M) (*models.SlackLink, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("slack_links")
+
+	var link models.SlackLink
+	err := collection.FindOne(ctx, filter).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Complete links the Slack user through the link's code and consumes it,
+// first unlinking the Slack user from any other account. It reports false
+// when the code was already used.
+func (r *SlackLinkRepository) Complete(id primitive.ObjectID, codeHash, teamID, slackUserID string, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("slack_links")
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$ne": id}, "team_id": teamID, "slack_user_id": slackUserID}); err != nil {
+		return false, err
+	}
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "code_hash": codeHash},
+		withUpdatedAt(bson.M{
+			"$set":   bson.M{"team_id": teamID, "slack_user_id": slackUserID, "linked_at": at},
+			"$unset": bson.M{"code_hash": "", "code_expires_at": ""},
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Delete removes the user's link, reporting false when there was none
+func (r *SlackLinkRepository) Delete(userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("slack_links")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DailyCount is a single point of a per-day time series
+type DailyCount struct {
+	Date  string `bson:"_id" json:"date"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// StatsRepository runs the aggregate queries behind the admin stats endpoint
+type StatsRepository struct {
+	db *database.MongoDB
+}
+
+func NewStatsRepository(db *database.MongoDB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// CountDocuments returns the number of documents in a collection
+func (r *StatsRepository) CountDocuments(collectionName string) (int64, error) {
+	ctx := context.Background()
+	return r.db.GetCollection(collectionName).EstimatedDocumentCount(ctx)
+}
+
+// CountCreatedSince returns the number of documents created at or after since
+func (r *StatsRepository) CountCreatedSince(collectionName string, since time.Time) (int64, error) {
+	ctx := context.Background()
+	return r.db.GetCollection(collectionName).CountDocuments(ctx, bson.M{"created_at": bson.M{"$gte": since}})
+}
+
+// DailyCreatedCounts returns per-day document counts by created_at for the
+// last N days (UTC), oldest first; days without documents are omitted
+func (r *StatsRepository) DailyCreatedCounts(collectionName string, days int) ([]DailyCount, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection(collectionName)
+
+	since := startOfDay(getCurrentTime()).AddDate(0, 0, -(days - 1))
+	pipeline := []bson.M{
+		{"$match": bson.M{"created_at": bson.M{"$gte": since}}},
+		{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []DailyCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
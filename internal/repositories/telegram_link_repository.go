@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TelegramLinkRepository stores which Telegram chat the bot serves for
+// which account, one chat per account
+type TelegramLinkRepository struct {
+	db *database.MongoDB
+}
+
+func NewTelegramLinkRepository(db *database.MongoDB) *TelegramLinkRepository {
+	return &TelegramLinkRepository{db: db}
+}
+
+// SetCode records the hash of a new link code for the user, creating the
+// user's link if there is none. An existing link stays linked until the
+// code is used.
+func (r *TelegramLinkRepository) SetCode(userID primitive.ObjectID, codeHash string, expiresAt time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		withUpsertTimestamps(bson.M{
+			"$set":         bson.M{"code_hash": codeHash, "code_expires_at": expiresAt},
+			"$setOnInsert": bson.M{"notify": true},
+		}),
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FindByCode returns the link whose unexpired link code hashes to codeHash
+func (r *TelegramLinkRepository) FindByCode(codeHash string, now time.Time) (*models.TelegramLink, error) {
+	return r.findOne(bson.M{"code_hash": codeHash, "code_expires_at": bson.M{"$gt": now}})
+}
+
+// FindByChat returns the linked account of a Telegram chat
+func (r *TelegramLinkRepository) FindByChat(chatID int64) (*models.TelegramLink, error) {
+	return r.findOne(bson.M{"chat_id": chatID, "linked_at": bson.M{"$exists": true}})
+}
+
+func (r *TelegramLinkRepository) FindByUser(userID primitive.ObjectID) (*models.TelegramLink, error) {
+	return r.findOne(bson.M{"user_id": userID})
+}
+
+func (r *TelegramLinkRepository) findOne(filter bson.M) (*models.TelegramLink, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	var link models.TelegramLink
+	err := collection.FindOne(ctx, filter).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// FindNotified returns the linked chats that receive notifications
+func (r *TelegramLinkRepository) FindNotified() ([]models.TelegramLink, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	cursor, err := collection.Find(ctx, bson.M{"notify": true, "linked_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.TelegramLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Complete links the chat through the link's code and consumes it, first
+// unlinking the chat from any other account. Notifications created before
+// at are not sent. It reports false when the code was already used.
+func (r *TelegramLinkRepository) Complete(id primitive.ObjectID, codeHash string, chatID int64, username string, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$ne": id}, "chat_id": chatID}); err != nil {
+		return false, err
+	}
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "code_hash": codeHash},
+		withUpdatedAt(bson.M{
+			"$set":   bson.M{"chat_id": chatID, "username": username, "notified_until": at, "linked_at": at},
+			"$unset": bson.M{"code_hash": "", "code_expires_at": ""},
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetNotify sets whether the user's chat receives notifications, reporting
+// false when no chat is linked. Turning them on skips the notifications
+// created while they were off.
+func (r *TelegramLinkRepository) SetNotify(userID primitive.ObjectID, notify bool) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	now := getCurrentTime()
+	set := bson.M{"notify": notify}
+	if notify {
+		set["notified_until"] = now
+	}
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "linked_at": bson.M{"$exists": true}, "notify": !notify},
+		withUpdatedAt(bson.M{"$set": set}),
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+	// Already set as asked
+	count, err := collection.CountDocuments(ctx, bson.M{"user_id": userID, "linked_at": bson.M{"$exists": true}})
+	return count > 0, err
+}
+
+// AdvanceNotified moves the link's notification cursor from from to to. It
+// reports false when another instance moved it first, so each notification
+// is sent once.
+func (r *TelegramLinkRepository) AdvanceNotified(id primitive.ObjectID, from, to time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "notified_until": from},
+		bson.M{"$set": bson.M{"notified_until": to}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Delete removes the user's link, reporting false when there was none
+func (r *TelegramLinkRepository) Delete(userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("telegram_links")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TraktLinkRepository stores users' linked Trakt accounts, one per user
+type TraktLinkRepository struct {
+	db *database.MongoDB
+}
+
+func NewTraktLinkRepository(db *database.MongoDB) *TraktLinkRepository {
+	return &TraktLinkRepository{db: db}
+}
+
+// BeginAuthorization records the hashed OAuth state of a consent screen the
+// user is sent to, creating the user's link if there is none. An existing
+// link keeps its tokens until the new authorization completes.
+func (r *TraktLinkRepository) BeginAuthorization(userID primitive.ObjectID, stateHash string, expiresAt time.Time, onConflict string) (*models.TraktLink, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	var link models.TraktLink
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": userID},
+		withUpsertTimestamps(bson.M{
+			"$set": bson.M{"state_hash": stateHash, "state_expires_at": expiresAt},
+			"$setOnInsert": bson.M{
+				"user_id":     userID,
+				"push":        false,
+				"on_conflict": onConflict,
+			},
+		}),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *TraktLinkRepository) FindByUser(userID primitive.ObjectID) (*models.TraktLink, error) {
+	return r.findOne(bson.M{"user_id": userID})
+}
+
+// FindByState returns the link whose unexpired OAuth state hashes to
+// stateHash
+func (r *TraktLinkRepository) FindByState(stateHash string, now time.Time) (*models.TraktLink, error) {
+	return r.findOne(bson.M{"state_hash": stateHash, "state_expires_at": bson.M{"$gt": now}})
+}
+
+func (r *TraktLinkRepository) findOne(filter bson.M) (*models.TraktLink, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	var link models.TraktLink
+	err := collection.FindOne(ctx, filter).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// CompleteAuthorization stores the tokens of a finished authorization and
+// consumes its state. It reports false when the state was already used.
+func (r *TraktLinkRepository) CompleteAuthorization(id primitive.ObjectID, stateHash, username, accessToken, refreshToken string, expiresAt, linkedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "state_hash": stateHash},
+		withUpdatedAt(bson.M{
+			"$set": bson.M{
+				"username":         username,
+				"access_token":     accessToken,
+				"refresh_token":    refreshToken,
+				"token_expires_at": expiresAt,
+				"linked_at":        linkedAt,
+			},
+			"$unset": bson.M{"state_hash": "", "state_expires_at": ""},
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SaveTokens replaces the tokens after a refresh
+func (r *TraktLinkRepository) SaveTokens(id primitive.ObjectID, accessToken, refreshToken string, expiresAt time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{
+			"access_token":     accessToken,
+			"refresh_token":    refreshToken,
+			"token_expires_at": expiresAt,
+		}}),
+	)
+	return err
+}
+
+// UpdateSettings changes whether changes are pushed and how imports resolve
+// conflicts. It reports false when the user has no linked account.
+func (r *TraktLinkRepository) UpdateSettings(userID primitive.ObjectID, push bool, onConflict string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "linked_at": bson.M{"$exists": true}},
+		withUpdatedAt(bson.M{"$set": bson.M{"push": push, "on_conflict": onConflict}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetSyncRun records the state of the latest import
+func (r *TraktLinkRepository) SetSyncRun(id primitive.ObjectID, run models.TraktSyncRun) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_sync": run}})
+	return err
+}
+
+// SetPushResult records when changes were last pushed and the error, if any
+func (r *TraktLinkRepository) SetPushResult(id primitive.ObjectID, at time.Time, pushErr string) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_push_at": at, "last_push_error": pushErr}})
+	return err
+}
+
+// Delete removes the user's link, reporting false when there was none
+func (r *TraktLinkRepository) Delete(userID primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("trakt_links")
+
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"movie-watchlist/internal/database"
 	"movie-watchlist/internal/models"
-	
+	"movie-watchlist/internal/pagination"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepository struct {
@@ -23,8 +25,7 @@ func (r *UserRepository) Create(user *models.User) error {
 	ctx := context.Background()
 	collection := r.db.GetCollection("users")
 	
-	user.CreatedAt = getCurrentTime()
-	user.UpdatedAt = getCurrentTime()
+	stampCreated(user)
 	
 	result, err := collection.InsertOne(ctx, user)
 	if err != nil {
@@ -65,6 +66,27 @@ func (r *UserRepository) FindByID(id primitive.ObjectID) (*models.User, error) {
 	return &user, nil
 }
 
+// List returns one keyset page of users in _id order and whether more follow
+func (r *UserRepository) List(cursor pagination.Cursor) ([]models.User, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	findOptions := cursor.FindOptions().SetProjection(bson.M{"password": 0})
+	cur, err := collection.Find(ctx, cursor.Filter(bson.M{}), findOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	users := []models.User{}
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, false, err
+	}
+
+	n, more := cursor.Trim(len(users))
+	return users[:n], more, nil
+}
+
 func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("users")
@@ -79,3 +101,425 @@ func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// UpdateLanguages replaces the user's preferred audio and subtitle languages.
+// It returns false if the user does not exist.
+func (r *UserRepository) UpdateLanguages(id primitive.ObjectID, audio, subtitles []string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{"$set": bson.M{
+		"audio_languages":    audio,
+		"subtitle_languages": subtitles,
+	}}))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// UpdateRecommendationSettings replaces the user's recommendation settings and
+// clears the last refresh time so a scheduled frequency takes effect on the
+// next run. It returns false if the user does not exist.
+func (r *UserRepository) UpdateRecommendationSettings(id primitive.ObjectID, settings models.RecommendationSettings) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{
+		"$set":   bson.M{"recommendation_settings": settings},
+		"$unset": bson.M{"recommendations_refreshed_at": ""},
+	}))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindDueForRecommendations returns up to limit users on a daily or weekly
+// recommendation schedule whose last refresh is older than the frequency
+func (r *UserRepository) FindDueForRecommendations(now time.Time, limit int64) ([]models.User, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	due := func(frequency string, interval time.Duration) bson.M {
+		return bson.M{
+			"recommendation_settings.frequency": frequency,
+			"$or": bson.A{
+				bson.M{"recommendations_refreshed_at": bson.M{"$exists": false}},
+				bson.M{"recommendations_refreshed_at": bson.M{"$lte": now.Add(-interval)}},
+			},
+		}
+	}
+	filter := bson.M{
+		"$or": bson.A{
+			due(models.RecommendationFrequencyDaily, 24*time.Hour),
+			due(models.RecommendationFrequencyWeekly, 7*24*time.Hour),
+		},
+		"deactivated_at": bson.M{"$exists": false},
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"password": 0}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// MarkRecommendationsRefreshed records when the user's scheduled
+// recommendations were last computed
+func (r *UserRepository) MarkRecommendationsRefreshed(id primitive.ObjectID, at time.Time) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"recommendations_refreshed_at": at}})
+	return err
+}
+
+// UpdateEmail changes the user's email address. It returns false if the
+// address is already used by another account.
+// UpdateWatchGoals stores the user's goals, or clears them when nil. The
+// current month may be celebrated again against the new goal.
+func (r *UserRepository) UpdateWatchGoals(id primitive.ObjectID, goals *models.WatchGoals) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	update := bson.M{
+		"$set":   bson.M{"watch_goals": goals},
+		"$unset": bson.M{"goal_met_month": ""},
+	}
+	if goals == nil {
+		update = bson.M{"$unset": bson.M{"watch_goals": "", "goal_met_month": ""}}
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetStorageQuota stores the user's storage limit overrides, or clears them
+// when nil
+func (r *UserRepository) SetStorageQuota(id primitive.ObjectID, quota *models.StorageQuota) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	update := bson.M{"$set": bson.M{"storage_quota": quota}}
+	if quota == nil {
+		update = bson.M{"$unset": bson.M{"storage_quota": ""}}
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// MarkGoalMet records that the goal for month was celebrated, reporting false
+// when it already was
+func (r *UserRepository) MarkGoalMet(id primitive.ObjectID, month string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "goal_met_month": bson.M{"$ne": month}},
+		bson.M{"$set": bson.M{"goal_met_month": month}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// SetAchievementsPublic sets whether the user's badges are shown publicly
+func (r *UserRepository) SetAchievementsPublic(id primitive.ObjectID, public bool) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{"achievements_public": public}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetCompatibilityPublic sets whether other users can compare their taste
+// with the user's, reporting whether the user exists
+func (r *UserRepository) SetCompatibilityPublic(id primitive.ObjectID, public bool) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{"compatibility_public": public}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetRatingsPublic sets whether the user's ratings are published as a feed,
+// reporting whether the user exists
+func (r *UserRepository) SetRatingsPublic(id primitive.ObjectID, public bool) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{"ratings_public": public}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetAvoidAdvisories replaces the content advisories the user avoids,
+// reporting whether the user exists
+func (r *UserRepository) SetAvoidAdvisories(id primitive.ObjectID, advisories []string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{"avoid_advisories": advisories}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Deactivate marks the user deactivated as of at, keeping the earliest time
+// when the user was already deactivated
+func (r *UserRepository) Deactivate(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$min": bson.M{"deactivated_at": at}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Reactivate clears the user's deactivation
+func (r *UserRepository) Reactivate(id primitive.ObjectID) error {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "deactivated_at": bson.M{"$exists": true}},
+		withUpdatedAt(bson.M{"$unset": bson.M{"deactivated_at": ""}}),
+	)
+	return err
+}
+
+// FindDeactivatedIDs returns which of the given users are deactivated
+func (r *UserRepository) FindDeactivatedIDs(ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	deactivated := map[primitive.ObjectID]bool{}
+	if len(ids) == 0 {
+		return deactivated, nil
+	}
+
+	cursor, err := collection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "deactivated_at": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		deactivated[user.ID] = true
+	}
+	return deactivated, nil
+}
+
+// AcceptTerms records that the user accepted the given terms version at at
+func (r *UserRepository) AcceptTerms(id primitive.ObjectID, version string, at time.Time) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		withUpdatedAt(bson.M{"$set": bson.M{"terms_version": version, "terms_accepted_at": at}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindTermsVersion returns the terms version the user last accepted, and
+// false when the user does not exist
+func (r *UserRepository) FindTermsVersion(id primitive.ObjectID) (string, bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"terms_version": 1})).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return user.TermsVersion, true, nil
+}
+
+// SetCalendarFeedToken stores the hash of the user's calendar feed token,
+// replacing the previous one; empty turns the feed off
+func (r *UserRepository) SetCalendarFeedToken(id primitive.ObjectID, tokenHash string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	update := bson.M{"$set": bson.M{"calendar_feed_token_hash": tokenHash}}
+	if tokenHash == "" {
+		update = bson.M{"$unset": bson.M{"calendar_feed_token_hash": ""}}
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindByCalendarFeedToken returns the user whose calendar feed token hashes
+// to tokenHash
+func (r *UserRepository) FindByCalendarFeedToken(tokenHash string) (*models.User, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"calendar_feed_token_hash": tokenHash}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetInboundEmailToken stores the hash of the user's inbound email token,
+// replacing the previous one; empty turns email-in off
+func (r *UserRepository) SetInboundEmailToken(id primitive.ObjectID, tokenHash string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	update := bson.M{"$set": bson.M{"inbound_email_token_hash": tokenHash}}
+	if tokenHash == "" {
+		update = bson.M{"$unset": bson.M{"inbound_email_token_hash": ""}}
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindByInboundEmailToken returns the user whose inbound email token hashes
+// to tokenHash
+func (r *UserRepository) FindByInboundEmailToken(tokenHash string) (*models.User, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"inbound_email_token_hash": tokenHash}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateTimezone sets the user's IANA timezone; empty resets it to UTC
+func (r *UserRepository) UpdateTimezone(id primitive.ObjectID, timezone string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	update := bson.M{"$set": bson.M{"timezone": timezone}}
+	if timezone == "" {
+		update = bson.M{"$unset": bson.M{"timezone": ""}}
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// FindTimezone returns the timezone of the user, or of the parent account
+// when id is a kids profile. It is empty when none is set or id is unknown.
+func (r *UserRepository) FindTimezone(id primitive.ObjectID) (string, error) {
+	ctx := context.Background()
+	projection := options.FindOne().SetProjection(bson.M{"timezone": 1})
+
+	var user models.User
+	err := r.db.GetCollection("users").FindOne(ctx, bson.M{"_id": id}, projection).Decode(&user)
+	if err == nil {
+		return user.Timezone, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	var profile models.Profile
+	err = r.db.GetCollection("profiles").FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"parent_id": 1})).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
+	}
+	err = r.db.GetCollection("users").FindOne(ctx, bson.M{"_id": profile.ParentID}, projection).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
+	}
+	return user.Timezone, nil
+}
+
+func (r *UserRepository) UpdateEmail(id primitive.ObjectID, email string) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("users")
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, withUpdatedAt(bson.M{"$set": bson.M{"email": email}}))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -1,9 +1,141 @@
 package repositories
 
-import "time"
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 // getCurrentTime returns the current UTC time
-// This is a centralized helper function to avoid duplicate definitions
+// This is a centralized helper function to avoid duplicate definitions.
+// It is cut to milliseconds, the precision MongoDB stores, so a document
+// returned after a write shows the same times as when it is read back.
 func getCurrentTime() time.Time {
-	return time.Now().UTC()
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// stampCreated is the hook repositories run on a document before inserting
+// it. It fills in the model's CreatedAt and UpdatedAt fields, where it has
+// them, and returns the time used. Times that are already set, such as the
+// original dates of imported ratings, are kept but moved to UTC, and
+// UpdatedAt defaults to CreatedAt.
+func stampCreated(doc interface{}) time.Time {
+	now := getCurrentTime()
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return now
+	}
+
+	created := stampField(v.Elem().FieldByName("CreatedAt"), now)
+	stampField(v.Elem().FieldByName("UpdatedAt"), created)
+	return now
+}
+
+// stampField sets a time.Time field to t when it is zero, otherwise to its
+// UTC form, and returns its new value. Other fields are left alone.
+func stampField(field reflect.Value, t time.Time) time.Time {
+	if !field.IsValid() || !field.CanSet() || field.Type() != reflect.TypeOf(time.Time{}) {
+		return t
+	}
+	if current := field.Interface().(time.Time); !current.IsZero() {
+		t = current.UTC()
+	}
+	field.Set(reflect.ValueOf(t))
+	return t
+}
+
+// withUpdatedAt is the hook repositories run on an update document before
+// sending it, the counterpart of stampCreated. It sets updated_at, and any
+// fields named in also, to the current time in $set, creating $set when the
+// update has none. An updated_at the update already sets, such as the
+// original date of an imported rating, is kept.
+func withUpdatedAt(update bson.M, also ...string) bson.M {
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	stampUpdate(set, also)
+	return update
+}
+
+// withUpsertTimestamps is withUpdatedAt for upserts that also record when a
+// document was created: created_at goes in $setOnInsert, so only an inserted
+// document gets it. An upsert without $set, which leaves existing documents
+// alone, gets updated_at and the fields in also on insert only.
+func withUpsertTimestamps(update bson.M, also ...string) bson.M {
+	setOnInsert, ok := update["$setOnInsert"].(bson.M)
+	if !ok {
+		setOnInsert = bson.M{}
+		update["$setOnInsert"] = setOnInsert
+	}
+
+	var now time.Time
+	if set, ok := update["$set"].(bson.M); ok {
+		now = stampUpdate(set, also)
+	} else {
+		now = stampUpdate(setOnInsert, also)
+	}
+	if _, ok := setOnInsert["created_at"]; !ok {
+		setOnInsert["created_at"] = now
+	}
+	return update
+}
+
+// stampUpdate sets updated_at and the fields in also in fields to the current
+// time, keeping an updated_at that is already there, and returns the time
+func stampUpdate(fields bson.M, also []string) time.Time {
+	now := getCurrentTime()
+	if current, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = now
+	} else if t, ok := current.(time.Time); ok {
+		now = t
+	}
+	for _, field := range also {
+		fields[field] = now
+	}
+	return now
+}
+
+// withVersion narrows an update filter to the expected document version, for
+// optimistic concurrency. A nil version leaves the filter unchanged. Documents
+// written before versioning have no version field and count as version 0.
+func withVersion(filter bson.M, expected *int) bson.M {
+	if expected == nil {
+		return filter
+	}
+	if *expected == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = *expected
+	}
+	return filter
+}
+
+// projection limits a read to the given fields; _id is always included. No
+// fields means whole documents, so reads can take an optional field list and
+// pass it straight through.
+func projection(fields []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+	p := bson.M{}
+	for _, field := range fields {
+		p[field] = 1
+	}
+	return p
+}
+
+// countPage returns how many documents match filter, given that a page read
+// with skip and limit held n of them. A page that is neither full nor past the
+// end of the results already tells the total, so the collection is only
+// counted for full and empty pages.
+func countPage(ctx context.Context, collection *mongo.Collection, filter bson.M, skip, limit int64, n int) (int64, error) {
+	if int64(n) < limit && (n > 0 || skip == 0) {
+		return skip + int64(n), nil
+	}
+	return collection.CountDocuments(ctx, filter)
 }
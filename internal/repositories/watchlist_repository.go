@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type WatchlistRepository struct {
@@ -23,9 +24,13 @@ func (r *WatchlistRepository) Add(watchlist *models.Watchlist) error {
 	ctx := context.Background()
 	collection := r.db.GetCollection("watchlists")
 	
-	watchlist.CreatedAt = getCurrentTime()
-	watchlist.UpdatedAt = getCurrentTime()
-	watchlist.AddedAt = time.Now()
+	now := stampCreated(watchlist)
+	// Archive imports carry their own time; everything else is added now
+	if watchlist.AddedAt.IsZero() {
+		watchlist.AddedAt = now
+	}
+	watchlist.AddedAt = watchlist.AddedAt.UTC()
+	watchlist.Version = 1
 	
 	result, err := collection.InsertOne(ctx, watchlist)
 	if err != nil {
@@ -36,15 +41,39 @@ func (r *WatchlistRepository) Add(watchlist *models.Watchlist) error {
 	return nil
 }
 
-func (r *WatchlistRepository) Remove(userID, movieID primitive.ObjectID) error {
+// RemoveForAny deletes the user's entry for any of the given movie IDs and
+// returns it, or nil when none of them was on the watchlist
+func (r *WatchlistRepository) RemoveForAny(userID primitive.ObjectID, movieIDs []primitive.ObjectID) (*models.Watchlist, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("watchlists")
-	
-	_, err := collection.DeleteOne(ctx, bson.M{
-		"user_id": userID,
-		"movie_id": movieID,
-	})
-	return err
+
+	var entry models.Watchlist
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"user_id":  userID,
+		"movie_id": bson.M{"$in": movieIDs},
+	}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Restore re-inserts a removed entry as it was. It returns false when the
+// movie has been added to the watchlist again in the meantime.
+func (r *WatchlistRepository) Restore(entry *models.Watchlist) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 func (r *WatchlistRepository) GetUserWatchlist(userID primitive.ObjectID) ([]models.Watchlist, error) {
@@ -62,17 +91,146 @@ func (r *WatchlistRepository) GetUserWatchlist(userID primitive.ObjectID) ([]mod
 		return nil, err
 	}
 	
-	// Populate movie details for each watchlist entry
-	for i := range watchlist {
-		_, err := r.getMovieByID(ctx, watchlist[i].MovieID)
-		if err == nil {
-			// Note: In MongoDB, we don't have automatic population like GORM
-			// We would need to manually populate or use aggregation pipeline
-			// For simplicity, we'll fetch movie details separately
+	return watchlist, nil
+}
+
+// GetUserWatchlistPage returns one page of a user's watchlist, newest first, with the total entry count
+func (r *WatchlistRepository) GetUserWatchlistPage(userID primitive.ObjectID, skip, limit int64) ([]models.Watchlist, int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	filter := bson.M{"user_id": userID}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "added_at", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var watchlist []models.Watchlist
+	if err := cursor.All(ctx, &watchlist); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := countPage(ctx, collection, filter, skip, limit, len(watchlist))
+	if err != nil {
+		return nil, 0, err
+	}
+	return watchlist, total, nil
+}
+
+// FindEntry returns the user's watchlist entry for a movie, or nil when absent
+func (r *WatchlistRepository) FindEntry(userID, movieID primitive.ObjectID) (*models.Watchlist, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	var entry models.Watchlist
+	err := collection.FindOne(ctx, bson.M{
+		"user_id":  userID,
+		"movie_id": movieID,
+	}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
 		}
+		return nil, err
 	}
-	
-	return watchlist, nil
+	return &entry, nil
+}
+
+// FindEntryForAny returns the user's watchlist entry for any of the given
+// movie IDs, or nil when absent
+func (r *WatchlistRepository) FindEntryForAny(userID primitive.ObjectID, movieIDs []primitive.ObjectID) (*models.Watchlist, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	var entry models.Watchlist
+	err := collection.FindOne(ctx, bson.M{
+		"user_id":  userID,
+		"movie_id": bson.M{"$in": movieIDs},
+	}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetWatchedTimes returns when the user watched each movie marked watched
+func (r *WatchlistRepository) GetWatchedTimes(userID primitive.ObjectID) ([]time.Time, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	cursor, err := collection.Find(ctx,
+		bson.M{"user_id": userID, "watched_at": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"watched_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.Watchlist
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	times := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		if entry.WatchedAt != nil {
+			times = append(times, *entry.WatchedAt)
+		}
+	}
+	return times, nil
+}
+
+// SetWatched sets or clears (nil) the watched time of an entry at
+// expectedVersion (nil skips the check), returning false if no entry matched
+func (r *WatchlistRepository) SetWatched(userID, movieID primitive.ObjectID, watchedAt *time.Time, expectedVersion *int) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	update := bson.M{"$set": bson.M{"watched_at": watchedAt}, "$inc": bson.M{"version": 1}}
+	if watchedAt == nil {
+		update = bson.M{"$unset": bson.M{"watched_at": ""}, "$inc": bson.M{"version": 1}}
+	}
+
+	filter := withVersion(bson.M{"user_id": userID, "movie_id": movieID}, expectedVersion)
+	result, err := collection.UpdateOne(ctx, filter, withUpdatedAt(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// SetPriority updates the priority of an entry at expectedVersion (nil skips
+// the check), returning false if no entry matched
+func (r *WatchlistRepository) SetPriority(userID, movieID primitive.ObjectID, priority int, expectedVersion *int) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	result, err := collection.UpdateOne(ctx,
+		withVersion(bson.M{"user_id": userID, "movie_id": movieID}, expectedVersion),
+		withUpdatedAt(bson.M{"$set": bson.M{"priority": priority}, "$inc": bson.M{"version": 1}}),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// CountByUser returns how many entries the user's watchlist has
+func (r *WatchlistRepository) CountByUser(userID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	return collection.CountDocuments(ctx, bson.M{"user_id": userID})
 }
 
 func (r *WatchlistRepository) Exists(userID, movieID primitive.ObjectID) (bool, error) {
@@ -89,6 +247,21 @@ func (r *WatchlistRepository) Exists(userID, movieID primitive.ObjectID) (bool,
 	return count > 0, nil
 }
 
+// ExistsAny reports whether any of the given movie IDs is on the user's watchlist
+func (r *WatchlistRepository) ExistsAny(userID primitive.ObjectID, movieIDs []primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"user_id":  userID,
+		"movie_id": bson.M{"$in": movieIDs},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (r *WatchlistRepository) GetWatchlistWithMovies(userID primitive.ObjectID) ([]models.Watchlist, error) {
 	ctx := context.Background()
 	collection := r.db.GetCollection("watchlists")
@@ -131,17 +304,54 @@ func (r *WatchlistRepository) GetWatchlistWithMovies(userID primitive.ObjectID)
 	return watchlist, nil
 }
 
-// Helper function to get movie by ID
-func (r *WatchlistRepository) getMovieByID(ctx context.Context, movieID primitive.ObjectID) (*models.Movie, error) {
-	collection := r.db.GetCollection("movies")
-	
-	var movie models.Movie
-	err := collection.FindOne(ctx, bson.M{"_id": movieID}).Decode(&movie)
+// SourceCount is how many watchlist entries were added from one source and
+// how many of them were watched
+type SourceCount struct {
+	Type    string `bson:"type" json:"type"`
+	Detail  string `bson:"detail" json:"detail,omitempty"`
+	Added   int64  `bson:"added" json:"added"`
+	Watched int64  `bson:"watched" json:"watched"`
+}
+
+// CountBySource counts watchlist entries per source type and detail, for one
+// user or, with a nil userID, for everyone. Entries without a source are
+// counted under an empty type.
+func (r *WatchlistRepository) CountBySource(userID *primitive.ObjectID) ([]SourceCount, error) {
+	ctx := context.Background()
+	collection := r.db.GetCollection("watchlists")
+
+	match := bson.M{}
+	if userID != nil {
+		match["user_id"] = *userID
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":   bson.M{"type": "$source.type", "detail": "$source.detail"},
+			"added": bson.M{"$sum": 1},
+			"watched": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$watched_at", nil}}, 1, 0,
+			}}},
+		}},
+		{"$project": bson.M{
+			"_id":     0,
+			"type":    bson.M{"$ifNull": bson.A{"$_id.type", ""}},
+			"detail":  bson.M{"$ifNull": bson.A{"$_id.detail", ""}},
+			"added":   1,
+			"watched": 1,
+		}},
+		{"$sort": bson.D{{Key: "added", Value: -1}, {Key: "type", Value: 1}, {Key: "detail", Value: 1}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
 		return nil, err
 	}
-	return &movie, nil
+	defer cursor.Close(ctx)
+
+	counts := []SourceCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
 }
@@ -0,0 +1,142 @@
+// Package sanitize cleans user-provided text before it reaches the services.
+//
+// String fields opt in with a `sanitize` struct tag:
+//
+//	Username string `json:"username" sanitize:"line,max=50"`
+//	Notes    string `json:"notes" sanitize:"text,max=2000"`
+//
+// "line" values lose every control character, "text" values keep newlines and
+// tabs. Surrounding whitespace is trimmed. A value still longer than max runes
+// is rejected rather than silently truncated.
+package sanitize
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldError reports a field whose value cannot be accepted
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// Struct sanitizes the tagged string fields of the struct v points to,
+// descending into nested structs, pointers and slices
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	return walk(rv.Elem(), "")
+}
+
+func walk(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return walk(v.Elem(), path)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := fieldName(field, path)
+			value := v.Field(i)
+
+			if tag, ok := field.Tag.Lookup("sanitize"); ok {
+				if err := apply(value, tag, name); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := walk(value, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// apply sanitizes a tagged string or string slice field in place
+func apply(v reflect.Value, tag, name string) error {
+	multiline, max := parseTag(tag)
+
+	switch {
+	case v.Kind() == reflect.String:
+		cleaned := clean(v.String(), multiline)
+		if max > 0 && utf8.RuneCountInString(cleaned) > max {
+			return &FieldError{Field: name, Message: fmt.Sprintf("must be at most %d characters", max)}
+		}
+		v.SetString(cleaned)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			if err := apply(v.Index(i), tag, fmt.Sprintf("%s[%d]", name, i)); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		return apply(v.Elem(), tag, name)
+	}
+	return nil
+}
+
+func parseTag(tag string) (multiline bool, max int) {
+	for _, part := range strings.Split(tag, ",") {
+		switch {
+		case part == "text":
+			multiline = true
+		case strings.HasPrefix(part, "max="):
+			max, _ = strconv.Atoi(strings.TrimPrefix(part, "max="))
+		}
+	}
+	return multiline, max
+}
+
+// clean drops control and invalid characters and trims surrounding whitespace
+func clean(s string, multiline bool) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.Map(func(r rune) rune {
+		if multiline && (r == '\n' || r == '\t') {
+			return r
+		}
+		// Unicode line and paragraph separators break single-line values and logs
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// fieldName uses the JSON name so errors match what the client sent
+func fieldName(field reflect.StructField, path string) string {
+	name := field.Name
+	if tag := field.Tag.Get("json"); tag != "" {
+		if jsonName := strings.Split(tag, ",")[0]; jsonName != "" && jsonName != "-" {
+			name = jsonName
+		}
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DeactivationGracePeriod is how long a deactivated account can be
+	// reactivated by logging in before it is deleted
+	DeactivationGracePeriod = 30 * 24 * time.Hour
+	// deactivatedPurgePerRun caps the accounts deleted by one purge run
+	deactivatedPurgePerRun = 100
+	// deactivatedPurgeInterval is how long the purge waits once caught up
+	deactivatedPurgeInterval = 24 * time.Hour
+)
+
+// AccountDeactivationService deactivates accounts on request and deletes
+// them once the grace period has passed without a login
+type AccountDeactivationService struct {
+	userRepo       *repositories.UserRepository
+	accountRepo    *repositories.AccountRepository
+	sessionService *SessionService
+	jobQueue       *jobs.Queue
+	logger         *slog.Logger
+}
+
+func NewAccountDeactivationService(userRepo *repositories.UserRepository, accountRepo *repositories.AccountRepository, sessionService *SessionService, jobQueue *jobs.Queue) *AccountDeactivationService {
+	return &AccountDeactivationService{
+		userRepo:       userRepo,
+		accountRepo:    accountRepo,
+		sessionService: sessionService,
+		jobQueue:       jobQueue,
+		logger:         logging.For("services.accounts"),
+	}
+}
+
+// Deactivate verifies the current password, hides the account and signs it
+// out everywhere. It returns when the account will be deleted.
+func (s *AccountDeactivationService) Deactivate(userID primitive.ObjectID, currentPassword string) (time.Time, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user == nil {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return time.Time{}, errors.New("invalid credentials")
+	}
+
+	now := time.Now().UTC()
+	if user.DeactivatedAt != nil {
+		now = *user.DeactivatedAt
+	}
+	found, err := s.userRepo.Deactivate(userID, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !found {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	if _, err := s.sessionService.RevokeAllSessions(userID); err != nil {
+		return time.Time{}, err
+	}
+	s.logger.Info("account deactivated", "user_id", userID.Hex())
+	return now.Add(DeactivationGracePeriod), nil
+}
+
+// EnsureScheduled queues the first purge run if none is pending
+func (s *AccountDeactivationService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypePurgeDeactivated, nil, time.Now().UTC())
+}
+
+// PurgeJob deletes accounts deactivated longer than the grace period with all
+// their data. It runs again right away while such accounts are left over and
+// daily once it has caught up.
+func (s *AccountDeactivationService) PurgeJob(ctx context.Context, payload map[string]interface{}) error {
+	cutoff := time.Now().UTC().Add(-DeactivationGracePeriod)
+	ids, err := s.accountRepo.FindDeactivatedBefore(cutoff, deactivatedPurgePerRun)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.accountRepo.DeleteDeactivatedUser(id, cutoff); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		s.logger.Info("deleted deactivated accounts", "users", len(ids))
+	}
+
+	next := time.Now().UTC().Add(deactivatedPurgeInterval)
+	if len(ids) == deactivatedPurgePerRun {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypePurgeDeactivated, nil, next)
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// achievementInterval is how often badges are evaluated
+	achievementInterval = time.Hour
+	// firstBadgeDecade is where "every decade" starts counting
+	firstBadgeDecade = 1920
+)
+
+// badge is one achievement and how close a user is to it
+type badge struct {
+	ID          string
+	Name        string
+	Description string
+	progress    func(stats *achievementStats) (current, target int)
+}
+
+// badges are evaluated in this order and listed in it
+var badges = []badge{
+	{"first-rating", "First rating", "Rated your first movie", func(s *achievementStats) (int, int) { return s.rated, 1 }},
+	{"rated-100", "Centurion", "Rated 100 movies", func(s *achievementStats) (int, int) { return s.rated, 100 }},
+	{"watched-50", "Cinephile", "Watched 50 movies from your watchlist", func(s *achievementStats) (int, int) { return s.watched, 50 }},
+	{"every-decade", "Time traveller", fmt.Sprintf("Watched or rated a film from every decade since the %ds", firstBadgeDecade), func(s *achievementStats) (int, int) {
+		target := 0
+		current := 0
+		for decade := firstBadgeDecade; decade <= s.now.Year(); decade += 10 {
+			target++
+			if s.decades[decade] {
+				current++
+			}
+		}
+		return current, target
+	}},
+	{"genre-explorer", "Genre explorer", "Watched or rated films from 10 different genres", func(s *achievementStats) (int, int) { return len(s.genres), 10 }},
+	{"four-week-streak", "Regular", "Watched a movie four weeks in a row", func(s *achievementStats) (int, int) { return s.longestStreak, 4 }},
+}
+
+// achievementStats are the counts badges are judged on
+type achievementStats struct {
+	now           time.Time
+	rated         int
+	watched       int
+	decades       map[int]bool
+	genres        map[string]bool
+	longestStreak int
+}
+
+// AchievementStatus is one badge with the user's progress towards it
+type AchievementStatus struct {
+	Badge       string     `json:"badge"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Earned      bool       `json:"earned"`
+	AwardedAt   *time.Time `json:"awarded_at,omitempty"`
+	Progress    int        `json:"progress"`
+	Target      int        `json:"target"`
+}
+
+// AchievementService awards badges from users' ratings and watch history.
+// Badges are awarded by a background job; progress is computed on request.
+type AchievementService struct {
+	achievementRepo  *repositories.AchievementRepository
+	userRepo         *repositories.UserRepository
+	ratingRepo       *repositories.RatingRepository
+	watchlistRepo    *repositories.WatchlistRepository
+	movieRepo        *repositories.MovieRepository
+	activityRepo     *repositories.ActivityRepository
+	notificationRepo *repositories.NotificationRepository
+	jobQueue         *jobs.Queue
+	logger           *slog.Logger
+}
+
+func NewAchievementService(achievementRepo *repositories.AchievementRepository, userRepo *repositories.UserRepository, ratingRepo *repositories.RatingRepository, watchlistRepo *repositories.WatchlistRepository, movieRepo *repositories.MovieRepository, activityRepo *repositories.ActivityRepository, notificationRepo *repositories.NotificationRepository, jobQueue *jobs.Queue) *AchievementService {
+	return &AchievementService{
+		achievementRepo:  achievementRepo,
+		userRepo:         userRepo,
+		ratingRepo:       ratingRepo,
+		watchlistRepo:    watchlistRepo,
+		movieRepo:        movieRepo,
+		activityRepo:     activityRepo,
+		notificationRepo: notificationRepo,
+		jobQueue:         jobQueue,
+		logger:           logging.For("services.achievements"),
+	}
+}
+
+// GetAchievements lists every badge with the user's progress and whether it
+// was awarded, and whether the earned badges are public
+func (s *AchievementService) GetAchievements(userID primitive.ObjectID) ([]AchievementStatus, bool, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if user == nil {
+		return nil, false, errors.New("user not found")
+	}
+	stats, err := s.collectStats(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	awarded, err := s.awardedBadges(userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	statuses := make([]AchievementStatus, 0, len(badges))
+	for _, b := range badges {
+		current, target := b.progress(stats)
+		status := AchievementStatus{
+			Badge:       b.ID,
+			Name:        b.Name,
+			Description: b.Description,
+			Progress:    min(current, target),
+			Target:      target,
+		}
+		if at, ok := awarded[b.ID]; ok {
+			status.Earned = true
+			status.AwardedAt = &at
+			status.Progress = target
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, user.AchievementsPublic, nil
+}
+
+// GetPublicAchievements returns the earned badges of the user with the given
+// username, or nil when the user does not exist, is deactivated or keeps
+// badges private
+func (s *AchievementService) GetPublicAchievements(username string) ([]AchievementStatus, error) {
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil || user == nil || user.DeactivatedAt != nil || !user.AchievementsPublic {
+		return nil, err
+	}
+
+	awarded, err := s.awardedBadges(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []AchievementStatus{}
+	for _, b := range badges {
+		at, ok := awarded[b.ID]
+		if !ok {
+			continue
+		}
+		statuses = append(statuses, AchievementStatus{Badge: b.ID, Name: b.Name, Description: b.Description, Earned: true, AwardedAt: &at})
+	}
+	return statuses, nil
+}
+
+// SetPublic sets whether the user's earned badges are shown publicly
+func (s *AchievementService) SetPublic(userID primitive.ObjectID, public bool) error {
+	found, err := s.userRepo.SetAchievementsPublic(userID, public)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// EnsureScheduled queues the first evaluation run if none is pending
+func (s *AchievementService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeAwardAchievements, nil, time.Now().UTC())
+}
+
+// AwardJob evaluates the badges of users active since the previous run and
+// notifies them of new ones. It runs hourly; the look-back overlaps the
+// previous run so activity at the boundary is not missed.
+func (s *AchievementService) AwardJob(ctx context.Context, payload map[string]interface{}) error {
+	userIDs, err := s.activityRepo.FindActiveUserIDsSince(time.Now().UTC().Add(-2 * achievementInterval))
+	if err != nil {
+		return err
+	}
+
+	// Deactivated accounts keep their progress but are not notified
+	deactivated, err := s.userRepo.FindDeactivatedIDs(userIDs)
+	if err != nil {
+		return err
+	}
+
+	awarded := 0
+	for _, userID := range userIDs {
+		if deactivated[userID] {
+			continue
+		}
+		count, err := s.evaluate(userID)
+		if err != nil {
+			// One failing user should not hold up the others
+			s.logger.Warn("failed to evaluate achievements", "user_id", userID.Hex(), "error", err)
+		}
+		awarded += count
+	}
+	if awarded > 0 {
+		s.logger.Info("awarded achievements", "users", len(userIDs), "badges", awarded)
+	}
+
+	return s.jobQueue.EnqueueAt(jobs.TypeAwardAchievements, nil, time.Now().UTC().Add(achievementInterval))
+}
+
+// evaluate awards the badges the user has earned and returns how many were new
+func (s *AchievementService) evaluate(userID primitive.ObjectID) (int, error) {
+	stats, err := s.collectStats(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	awarded := 0
+	for _, b := range badges {
+		current, target := b.progress(stats)
+		if current < target {
+			continue
+		}
+		isNew, err := s.achievementRepo.Award(userID, b.ID, stats.now.UTC())
+		if err != nil {
+			return awarded, err
+		}
+		if !isNew {
+			continue
+		}
+		awarded++
+
+		notification := &models.Notification{
+			UserID:  userID,
+			Type:    models.NotificationAchievement,
+			Title:   fmt.Sprintf("Badge earned: %s", b.Name),
+			Message: b.Description + ".",
+		}
+		if err := s.notificationRepo.Create(notification); err != nil {
+			// The badge is awarded either way; only the notice is lost
+			s.logger.Warn("failed to notify achievement", "user_id", userID.Hex(), "badge", b.ID, "error", err)
+		}
+	}
+	return awarded, nil
+}
+
+// collectStats gathers the user's ratings and watched entries with their movies
+func (s *AchievementService) collectStats(userID primitive.ObjectID) (*achievementStats, error) {
+	ratings, err := s.ratingRepo.GetUserRatings(userID)
+	if err != nil {
+		return nil, err
+	}
+	watched, err := s.watchlistRepo.GetWatchedTimes(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.watchlistRepo.GetUserWatchlist(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	ids := []primitive.ObjectID{}
+	for _, rating := range ratings {
+		if !seen[rating.MovieID] {
+			seen[rating.MovieID] = true
+			ids = append(ids, rating.MovieID)
+		}
+	}
+	for _, entry := range entries {
+		if entry.WatchedAt != nil && !seen[entry.MovieID] {
+			seen[entry.MovieID] = true
+			ids = append(ids, entry.MovieID)
+		}
+	}
+	movies, err := s.movieRepo.FindByIDs(ids, "year_start", "genre")
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Streak weeks follow the user's timezone, as in the watch stats
+	now := time.Now().In(loc)
+	stats := &achievementStats{
+		now:           now,
+		rated:         len(ratings),
+		watched:       len(watched),
+		decades:       map[int]bool{},
+		genres:        map[string]bool{},
+		longestStreak: computeHabits(watched, nil, now).Streak.LongestWeeks,
+	}
+	for _, movie := range movies {
+		if movie.YearStart > 0 {
+			stats.decades[movie.YearStart/10*10] = true
+		}
+		for _, genre := range strings.Split(movie.Genre, ",") {
+			if genre = strings.TrimSpace(genre); genre != "" && genre != "N/A" {
+				stats.genres[genre] = true
+			}
+		}
+	}
+	return stats, nil
+}
+
+func (s *AchievementService) awardedBadges(userID primitive.ObjectID) (map[string]time.Time, error) {
+	achievements, err := s.achievementRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	awarded := make(map[string]time.Time, len(achievements))
+	for _, achievement := range achievements {
+		awarded[achievement.Badge] = achievement.AwardedAt
+	}
+	return awarded, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/repositories"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// activityThrottle is the minimum interval between activity writes per user
+const activityThrottle = time.Hour
+
+// ActiveUsersPoint is one day of the DAU/WAU/MAU series
+type ActiveUsersPoint struct {
+	Date string `json:"date"`
+	DAU  int    `json:"dau"`
+	WAU  int    `json:"wau"`
+	MAU  int    `json:"mau"`
+}
+
+type ActivityService struct {
+	activityRepo *repositories.ActivityRepository
+	lastRecorded sync.Map // primitive.ObjectID -> time.Time
+	logger       *slog.Logger
+}
+
+func NewActivityService(activityRepo *repositories.ActivityRepository) *ActivityService {
+	return &ActivityService{
+		activityRepo: activityRepo,
+		logger:       logging.For("services.activity"),
+	}
+}
+
+// RecordActivity marks the user as active. Writes are throttled to once per
+// hour per user and happen in the background so requests are not slowed down.
+func (s *ActivityService) RecordActivity(userID primitive.ObjectID) {
+	now := time.Now().UTC()
+	if last, ok := s.lastRecorded.Load(userID); ok && now.Sub(last.(time.Time)) < activityThrottle {
+		return
+	}
+	s.lastRecorded.Store(userID, now)
+
+	go func() {
+		if err := s.activityRepo.RecordActivity(userID, now); err != nil {
+			s.logger.Warn("failed to record activity", "user_id", userID.Hex(), "error", err)
+		}
+	}()
+}
+
+// GetActiveUsersSeries returns daily, weekly and monthly active user counts
+// for each of the last N days (UTC), oldest first. WAU and MAU are rolling
+// 7- and 30-day windows ending on each day.
+func (s *ActivityService) GetActiveUsersSeries(days int) ([]ActiveUsersPoint, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	first := today.AddDate(0, 0, -(days - 1))
+
+	activity, err := s.activityRepo.FindSince(first.AddDate(0, 0, -29))
+	if err != nil {
+		return nil, err
+	}
+
+	// Group users by activity day
+	usersByDay := make(map[string][]primitive.ObjectID)
+	for _, record := range activity {
+		usersByDay[record.Day] = append(usersByDay[record.Day], record.UserID)
+	}
+
+	series := make([]ActiveUsersPoint, 0, days)
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		series = append(series, ActiveUsersPoint{
+			Date: day.Format("2006-01-02"),
+			DAU:  distinctUsers(usersByDay, day, 1),
+			WAU:  distinctUsers(usersByDay, day, 7),
+			MAU:  distinctUsers(usersByDay, day, 30),
+		})
+	}
+
+	return series, nil
+}
+
+// distinctUsers counts users active in the window of n days ending on day
+func distinctUsers(usersByDay map[string][]primitive.ObjectID, day time.Time, n int) int {
+	seen := make(map[primitive.ObjectID]bool)
+	for i := 0; i < n; i++ {
+		for _, id := range usersByDay[day.AddDate(0, 0, -i).Format("2006-01-02")] {
+			seen[id] = true
+		}
+	}
+	return len(seen)
+}
+
+// ActiveUserIDs returns the users active within the given window. Work that is
+// only worth doing for returning users, such as precomputing recommendations,
+// should be limited to this set.
+func (s *ActivityService) ActiveUserIDs(within time.Duration) ([]primitive.ObjectID, error) {
+	return s.activityRepo.FindActiveUserIDsSince(time.Now().UTC().Add(-within))
+}
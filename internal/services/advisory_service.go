@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// advisoryPerRun caps the movies tagged by one advisory job run
+const advisoryPerRun = 500
+
+var (
+	ErrInvalidAdvisory        = errors.New("invalid advisory")
+	ErrAlreadyReported        = errors.New("advisory already reported")
+	ErrAdvisoryReportNotFound = errors.New("advisory report not found")
+	ErrReportReviewed         = errors.New("advisory report was already reviewed")
+)
+
+// knownAdvisories are the content advisories movies can carry and users can
+// avoid, in display order
+var knownAdvisories = []string{
+	models.AdvisoryViolence,
+	models.AdvisoryAnimalHarm,
+	models.AdvisoryFlashingLights,
+}
+
+// AdvisoryProvider tags a movie with content advisories. Source names the
+// provider and its rules version; movies tagged by another source are tagged
+// again.
+type AdvisoryProvider interface {
+	Source() string
+	Advisories(ctx context.Context, movie models.Movie) ([]string, error)
+}
+
+// advisoryPhrases maps each advisory to the words or phrases that signal it
+// in a plot. Bump advisoryRulesVersion when editing the list so the catalogue
+// is tagged again.
+var advisoryPhrases = map[string][]string{
+	models.AdvisoryViolence:       {"murder", "massacre", "slaughter", "torture", "brutal", "bloody", "violent", "violence", "killing spree", "gunfight", "shootout", "stabbing"},
+	models.AdvisoryAnimalHarm:     {"animal cruelty", "dog dies", "dog fighting", "dogfighting", "poacher", "poaching", "animal abuse", "bullfight"},
+	models.AdvisoryFlashingLights: {"strobe", "flashing lights", "epilepsy", "seizure"},
+}
+
+// advisoryRulesVersion versions advisoryPhrases in the provider's Source
+const advisoryRulesVersion = 1
+
+var advisoryPatterns = compileThemePatterns(advisoryPhrases)
+
+// PlotAdvisoryProvider tags advisories from the words in a movie's plot
+type PlotAdvisoryProvider struct{}
+
+func NewPlotAdvisoryProvider() *PlotAdvisoryProvider {
+	return &PlotAdvisoryProvider{}
+}
+
+func (p *PlotAdvisoryProvider) Source() string {
+	return fmt.Sprintf("plot-advisories-v%d", advisoryRulesVersion)
+}
+
+func (p *PlotAdvisoryProvider) Advisories(ctx context.Context, movie models.Movie) ([]string, error) {
+	text := strings.ToLower(movie.Plot)
+	advisories := []string{}
+	for advisory, pattern := range advisoryPatterns {
+		if pattern.MatchString(text) {
+			advisories = append(advisories, advisory)
+		}
+	}
+	sort.Strings(advisories)
+	return advisories, nil
+}
+
+// MovieAdvisories returns every advisory on the movie, from the provider and
+// from approved reports
+func MovieAdvisories(movie models.Movie) []string {
+	advisories := []string{}
+	for _, advisory := range knownAdvisories {
+		if containsString(movie.Advisories, advisory) || containsString(movie.ReportedAdvisories, advisory) {
+			advisories = append(advisories, advisory)
+		}
+	}
+	return advisories
+}
+
+// AdvisoryWarnings returns the movie's advisories the user avoids
+func AdvisoryWarnings(movie models.Movie, avoid []string) []string {
+	warnings := []string{}
+	for _, advisory := range MovieAdvisories(movie) {
+		if containsString(avoid, advisory) {
+			warnings = append(warnings, advisory)
+		}
+	}
+	return warnings
+}
+
+// FilterByAdvisories drops movies carrying any of the avoided advisories
+func FilterByAdvisories(movies []models.Movie, avoid []string) []models.Movie {
+	if len(avoid) == 0 {
+		return movies
+	}
+	filtered := make([]models.Movie, 0, len(movies))
+	for _, movie := range movies {
+		if len(AdvisoryWarnings(movie, avoid)) > 0 {
+			continue
+		}
+		filtered = append(filtered, movie)
+	}
+	return filtered
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AdvisoryService tags movies with content advisories, moderates user
+// reports of them and keeps each user's avoid list
+type AdvisoryService struct {
+	provider   AdvisoryProvider
+	movieRepo  *repositories.MovieRepository
+	reportRepo *repositories.AdvisoryReportRepository
+	userRepo   *repositories.UserRepository
+	history    *MovieHistoryService
+	jobQueue   *jobs.Queue
+	logger     *slog.Logger
+}
+
+func NewAdvisoryService(provider AdvisoryProvider, movieRepo *repositories.MovieRepository, reportRepo *repositories.AdvisoryReportRepository, userRepo *repositories.UserRepository, history *MovieHistoryService, jobQueue *jobs.Queue) *AdvisoryService {
+	return &AdvisoryService{
+		provider:   provider,
+		movieRepo:  movieRepo,
+		reportRepo: reportRepo,
+		userRepo:   userRepo,
+		history:    history,
+		jobQueue:   jobQueue,
+		logger:     logging.For("services.advisories"),
+	}
+}
+
+// EnsureScheduled queues the first advisory tagging run if none is pending
+func (s *AdvisoryService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeTagAdvisories, nil, time.Now().UTC())
+}
+
+// TagAdvisoriesJob tags movies not yet tagged by the current provider,
+// including movies whose details were refreshed since. It runs again right
+// away while movies are left over and hourly once it has caught up.
+func (s *AdvisoryService) TagAdvisoriesJob(ctx context.Context, payload map[string]interface{}) error {
+	source := s.provider.Source()
+	movies, err := s.movieRepo.FindUnadvised(source, advisoryPerRun)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		advisories, err := s.provider.Advisories(ctx, movie)
+		if err != nil {
+			return err
+		}
+		if err := s.movieRepo.SetAdvisories(movie.ID, advisories, source); err != nil {
+			return err
+		}
+		s.history.Record(&movie, map[string]string{"advisories": strings.Join(advisories, ",")}, changedByJob(jobs.TypeTagAdvisories))
+	}
+	if len(movies) > 0 {
+		s.logger.Info("tagged movie advisories", "movies", len(movies), "source", source)
+	}
+
+	next := time.Now().UTC().Add(tagInterval)
+	if len(movies) == advisoryPerRun {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeTagAdvisories, nil, next)
+}
+
+// Report files the user's report that the movie carries the advisory, for an
+// admin to review
+func (s *AdvisoryService) Report(userID, movieID primitive.ObjectID, advisory string) (*models.AdvisoryReport, error) {
+	if !containsString(knownAdvisories, advisory) {
+		return nil, fmt.Errorf("%w: %q, expected one of %s", ErrInvalidAdvisory, advisory, strings.Join(knownAdvisories, ", "))
+	}
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, errors.New("movie not found")
+	}
+
+	report := &models.AdvisoryReport{MovieID: movieID, UserID: userID, Advisory: advisory}
+	created, err := s.reportRepo.Create(report)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyReported
+	}
+	return report, nil
+}
+
+// ListReports returns a cursor page of reports with the given status, or of
+// all reports when status is empty
+func (s *AdvisoryService) ListReports(status string, cursor pagination.Cursor) ([]models.AdvisoryReport, bool, error) {
+	return s.reportRepo.List(status, cursor)
+}
+
+// Approve accepts a report, adding its advisory to the movie. Other pending
+// reports of the same advisory for the movie are approved with it.
+func (s *AdvisoryService) Approve(reportID, adminID primitive.ObjectID) (*models.AdvisoryReport, error) {
+	report, err := s.review(reportID, models.AdvisoryReportApproved, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.movieRepo.AddReportedAdvisory(report.MovieID, report.Advisory); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Reject turns a report down, along with other pending reports of the same
+// advisory for the movie
+func (s *AdvisoryService) Reject(reportID, adminID primitive.ObjectID) (*models.AdvisoryReport, error) {
+	return s.review(reportID, models.AdvisoryReportRejected, adminID)
+}
+
+func (s *AdvisoryService) review(reportID primitive.ObjectID, status string, adminID primitive.ObjectID) (*models.AdvisoryReport, error) {
+	report, err := s.reportRepo.FindByID(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrAdvisoryReportNotFound
+	}
+	if report.Status != models.AdvisoryReportPending {
+		return nil, ErrReportReviewed
+	}
+
+	now := time.Now().UTC()
+	reviewed, err := s.reportRepo.Review(report.MovieID, report.Advisory, status, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrReportReviewed
+	}
+	report.Status = status
+	report.ReviewedAt = &now
+	report.ReviewedBy = &adminID
+	return report, nil
+}
+
+// GetAvoided returns the advisories the user avoids
+func (s *AdvisoryService) GetAvoided(userID primitive.ObjectID) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.AvoidAdvisories == nil {
+		return []string{}, nil
+	}
+	return user.AvoidAdvisories, nil
+}
+
+// SetAvoided validates and replaces the advisories the user avoids
+func (s *AdvisoryService) SetAvoided(userID primitive.ObjectID, avoid []string) ([]string, error) {
+	normalized := []string{}
+	for _, advisory := range knownAdvisories {
+		if containsString(avoid, advisory) {
+			normalized = append(normalized, advisory)
+		}
+	}
+	for _, advisory := range avoid {
+		if !containsString(knownAdvisories, advisory) {
+			return nil, fmt.Errorf("%w: %q, expected one of %s", ErrInvalidAdvisory, advisory, strings.Join(knownAdvisories, ", "))
+		}
+	}
+
+	found, err := s.userRepo.SetAvoidAdvisories(userID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("user not found")
+	}
+	return normalized, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/repositories"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// anomalyWindow is how long the anomaly detector counts a user's actions
+// before starting over
+const anomalyWindow = 10 * time.Minute
+
+var (
+	ErrFlagNotFound = errors.New("account flag not found")
+	ErrFlagReviewed = errors.New("account flag already reviewed")
+)
+
+type anomalyCount struct {
+	count   int
+	reset   time.Time
+	flagged bool
+}
+
+// AnomalyService watches rating and watchlist events for accounts acting
+// faster than people do, such as scripts rating hundreds of movies or
+// adding and removing the same entries over and over, and flags them for
+// admin review. Flagged accounts can be throttled automatically until an
+// admin dismisses the flag or the throttle runs out.
+//
+// Counting is in-memory, so each API instance judges the requests it
+// serves; throttles are stored with the flags and reloaded on startup.
+type AnomalyService struct {
+	flagRepo       *repositories.AccountFlagRepository
+	ratingBurst    int
+	watchlistChurn int
+	throttle       time.Duration
+	logger         *slog.Logger
+
+	mu        sync.Mutex
+	counts    map[string]*anomalyCount
+	throttled map[primitive.ObjectID]time.Time
+	nextSweep time.Time
+}
+
+// NewAnomalyService flags users who rate more than ratingBurst movies, or
+// add and remove more than watchlistChurn watchlist entries, within the
+// window; a threshold of 0 turns that check off. A throttle of 0 only flags.
+func NewAnomalyService(flagRepo *repositories.AccountFlagRepository, ratingBurst, watchlistChurn int, throttle time.Duration) *AnomalyService {
+	return &AnomalyService{
+		flagRepo:       flagRepo,
+		ratingBurst:    ratingBurst,
+		watchlistChurn: watchlistChurn,
+		throttle:       throttle,
+		logger:         logging.For("services.anomaly"),
+		counts:         make(map[string]*anomalyCount),
+		throttled:      make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// Subscribe counts first-time ratings and watchlist additions and removals
+func (s *AnomalyService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameMovieRated, "anomaly", func(event events.Event) error {
+		return s.record(event.(events.MovieRated).UserID, models.FlagRatingBurst, s.ratingBurst)
+	})
+	bus.Subscribe(events.NameWatchlistItemAdded, "anomaly", func(event events.Event) error {
+		return s.record(event.(events.WatchlistItemAdded).UserID, models.FlagWatchlistChurn, s.watchlistChurn)
+	})
+	bus.Subscribe(events.NameWatchlistItemRemoved, "anomaly", func(event events.Event) error {
+		return s.record(event.(events.WatchlistItemRemoved).UserID, models.FlagWatchlistChurn, s.watchlistChurn)
+	})
+}
+
+// LoadThrottles restores the throttles that are still running, so a restart
+// does not lift them
+func (s *AnomalyService) LoadThrottles() error {
+	flags, err := s.flagRepo.FindThrottled(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, flag := range flags {
+		s.throttleUntil(flag.UserID, *flag.ThrottledUntil)
+	}
+	return nil
+}
+
+// IsThrottled reports whether the user's writes are rate limited
+func (s *AnomalyService) IsThrottled(userID primitive.ObjectID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.throttled[userID]
+	if !ok {
+		return false
+	}
+	if !time.Now().Before(until) {
+		delete(s.throttled, userID)
+		return false
+	}
+	return true
+}
+
+// record counts one action and flags the user the first time a window goes
+// over the threshold
+func (s *AnomalyService) record(userID primitive.ObjectID, reason string, threshold int) error {
+	if threshold <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	s.sweep(now)
+	key := reason + ":" + userID.Hex()
+	w, ok := s.counts[key]
+	if !ok || !now.Before(w.reset) {
+		w = &anomalyCount{reset: now.Add(anomalyWindow)}
+		s.counts[key] = w
+	}
+	w.count++
+	burst := w.count > threshold && !w.flagged
+	if burst {
+		w.flagged = true
+	}
+	s.mu.Unlock()
+
+	if !burst {
+		return nil
+	}
+	return s.flag(userID, reason, threshold, now)
+}
+
+func (s *AnomalyService) flag(userID primitive.ObjectID, reason string, threshold int, now time.Time) error {
+	var throttledUntil *time.Time
+	if s.throttle > 0 {
+		until := now.Add(s.throttle)
+		throttledUntil = &until
+	}
+
+	flag, err := s.flagRepo.Raise(userID, reason, threshold, now, throttledUntil)
+	if err != nil {
+		return err
+	}
+	s.logger.Warn("account flagged for review", "user_id", userID.Hex(), "reason", reason, "threshold", threshold, "bursts", flag.Bursts)
+
+	if flag.ThrottledUntil != nil {
+		s.mu.Lock()
+		s.throttleUntil(userID, *flag.ThrottledUntil)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// throttleUntil keeps the later of the user's throttles; the caller holds mu
+func (s *AnomalyService) throttleUntil(userID primitive.ObjectID, until time.Time) {
+	if current, ok := s.throttled[userID]; !ok || until.After(current) {
+		s.throttled[userID] = until
+	}
+}
+
+// sweep drops expired counts at most once per window; the caller holds mu
+func (s *AnomalyService) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, w := range s.counts {
+		if !now.Before(w.reset) {
+			delete(s.counts, key)
+		}
+	}
+	s.nextSweep = now.Add(anomalyWindow)
+}
+
+// ListFlags returns a cursor page of flags with the given status, or of all
+// flags when status is empty
+func (s *AnomalyService) ListFlags(status string, cursor pagination.Cursor) ([]models.AccountFlag, bool, error) {
+	return s.flagRepo.List(status, cursor)
+}
+
+// Dismiss closes a flag as a false alarm and lifts the user's throttle
+func (s *AnomalyService) Dismiss(flagID, adminID primitive.ObjectID) (*models.AccountFlag, error) {
+	flag, err := s.review(flagID, models.FlagDismissed, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.throttled, flag.UserID)
+	s.mu.Unlock()
+	// The user's other open flag may still hold a throttle
+	if err := s.LoadThrottles(); err != nil {
+		s.logger.Warn("failed to reload throttles", "error", err)
+	}
+	flag.ThrottledUntil = nil
+	return flag, nil
+}
+
+// Confirm closes a flag as abuse; a running throttle stays until it expires
+func (s *AnomalyService) Confirm(flagID, adminID primitive.ObjectID) (*models.AccountFlag, error) {
+	return s.review(flagID, models.FlagConfirmed, adminID)
+}
+
+func (s *AnomalyService) review(flagID primitive.ObjectID, status string, adminID primitive.ObjectID) (*models.AccountFlag, error) {
+	flag, err := s.flagRepo.FindByID(flagID)
+	if err != nil {
+		return nil, err
+	}
+	if flag == nil {
+		return nil, ErrFlagNotFound
+	}
+	if flag.Status != models.FlagOpen {
+		return nil, ErrFlagReviewed
+	}
+
+	now := time.Now().UTC()
+	reviewed, err := s.flagRepo.Review(flagID, status, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrFlagReviewed
+	}
+	flag.Status = status
+	flag.ReviewedAt = &now
+	flag.ReviewedBy = &adminID
+	return flag, nil
+}
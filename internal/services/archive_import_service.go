@@ -0,0 +1,858 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ArchiveFormat and ArchiveVersion identify account archives in manifest.json
+	ArchiveFormat  = "movie-watchlist-archive"
+	ArchiveVersion = 1
+	// MaxArchiveEntries caps the entries read from each file of one archive
+	MaxArchiveEntries = 5000
+	// maxArchiveFileBytes caps one uncompressed file in the archive
+	maxArchiveFileBytes = 16 << 20
+)
+
+// Conflict resolution for archive imports, for entries that already exist
+// with different values
+const (
+	// ArchiveConflictSkip keeps the account's values
+	ArchiveConflictSkip = "skip"
+	// ArchiveConflictOverwrite replaces them with the archive's
+	ArchiveConflictOverwrite = "overwrite"
+	// ArchiveConflictMerge keeps whichever rating is newer, fills in what the
+	// account lacks and combines language lists
+	ArchiveConflictMerge = "merge"
+)
+
+// ImportActionSkipped is reported for entries left alone by the conflict mode
+const ImportActionSkipped = "skipped"
+
+// ErrInvalidArchive is returned when the archive as a whole cannot be read
+var ErrInvalidArchive = errors.New("invalid account archive")
+
+// archiveManifest is manifest.json, required in every archive
+type archiveManifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// archiveWatchlistEntry is one entry of watchlist.json
+type archiveWatchlistEntry struct {
+	IMDbID    string     `json:"imdb_id"`
+	AddedAt   time.Time  `json:"added_at"`
+	WatchedAt *time.Time `json:"watched_at"`
+	Priority  int        `json:"priority"`
+}
+
+// archiveRating is one entry of ratings.json
+type archiveRating struct {
+	IMDbID  string    `json:"imdb_id"`
+	Rating  int       `json:"rating"`
+	RatedAt time.Time `json:"rated_at"`
+}
+
+// archiveReview is one entry of reviews.json
+type archiveReview struct {
+	IMDbID    string    `json:"imdb_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// archiveReviewVote is one entry of review_votes.json, a helpful vote on the
+// review that the user named by username wrote of the movie
+type archiveReviewVote struct {
+	IMDbID   string    `json:"imdb_id"`
+	Username string    `json:"username"`
+	VotedAt  time.Time `json:"voted_at"`
+}
+
+// archivePreferences is preferences.json
+type archivePreferences struct {
+	AudioLanguages         []string                       `json:"audio_languages"`
+	SubtitleLanguages      []string                       `json:"subtitle_languages"`
+	RecommendationSettings *models.RecommendationSettings `json:"recommendation_settings"`
+}
+
+// ArchiveImportItem is the outcome for one entry of the archive
+type ArchiveImportItem struct {
+	File   string `json:"file"`
+	IMDbID string `json:"imdb_id,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Action string `json:"action"`
+	// MovieCached is false when the movie still has to be fetched from OMDb
+	MovieCached bool   `json:"movie_cached,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ArchiveImportReport summarises an import per file; in a dry run nothing
+// was written
+type ArchiveImportReport struct {
+	DryRun     bool                      `json:"dry_run"`
+	OnConflict string                    `json:"on_conflict"`
+	Summary    map[string]map[string]int `json:"summary"`
+	Items      []ArchiveImportItem       `json:"items"`
+}
+
+func (r *ArchiveImportReport) add(item ArchiveImportItem) {
+	if r.Summary[item.File] == nil {
+		r.Summary[item.File] = map[string]int{}
+	}
+	r.Summary[item.File][item.Action]++
+	r.Items = append(r.Items, item)
+}
+
+// ArchiveImportService restores an account archive (a ZIP of JSON files, see
+// ArchiveFormat) onto the current account. Movies are matched by IMDb ID, so
+// archives move between instances.
+type ArchiveImportService struct {
+	userRepo                *repositories.UserRepository
+	ratingRepo              *repositories.RatingRepository
+	watchlistRepo           *repositories.WatchlistRepository
+	reviewRepo              *repositories.ReviewRepository
+	movieRepo               *repositories.MovieRepository
+	movieService            *MovieService
+	quotaService            *StorageQuotaService
+	recommendationScheduler *RecommendationScheduler
+	logger                  *slog.Logger
+}
+
+func NewArchiveImportService(userRepo *repositories.UserRepository, ratingRepo *repositories.RatingRepository, watchlistRepo *repositories.WatchlistRepository, reviewRepo *repositories.ReviewRepository, movieRepo *repositories.MovieRepository, movieService *MovieService, quotaService *StorageQuotaService, recommendationScheduler *RecommendationScheduler) *ArchiveImportService {
+	return &ArchiveImportService{
+		userRepo:                userRepo,
+		ratingRepo:              ratingRepo,
+		watchlistRepo:           watchlistRepo,
+		reviewRepo:              reviewRepo,
+		movieRepo:               movieRepo,
+		movieService:            movieService,
+		quotaService:            quotaService,
+		recommendationScheduler: recommendationScheduler,
+		logger:                  logging.For("services.archive_import"),
+	}
+}
+
+// ImportArchive reads the archive and restores its watchlist, ratings,
+// reviews, helpful votes and preferences. Entries that already exist with different values are resolved
+// by onConflict. Unless dryRun is set, the planned changes are applied.
+func (s *ArchiveImportService) ImportArchive(userID primitive.ObjectID, data []byte, onConflict string, dryRun bool) (*ArchiveImportReport, error) {
+	switch onConflict {
+	case ArchiveConflictSkip, ArchiveConflictOverwrite, ArchiveConflictMerge:
+	default:
+		return nil, fmt.Errorf("%w: on_conflict must be skip, overwrite or merge", ErrInvalidArchive)
+	}
+
+	files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest archiveManifest
+	if err := decodeArchiveFile(files, "manifest.json", &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != ArchiveFormat {
+		return nil, fmt.Errorf("%w: manifest.json format must be %q", ErrInvalidArchive, ArchiveFormat)
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("%w: unsupported archive version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	var watchlist []archiveWatchlistEntry
+	var ratings []archiveRating
+	var reviews []archiveReview
+	var votes []archiveReviewVote
+	var preferences *archivePreferences
+	if _, ok := files["watchlist.json"]; ok {
+		if err := decodeArchiveFile(files, "watchlist.json", &watchlist); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := files["ratings.json"]; ok {
+		if err := decodeArchiveFile(files, "ratings.json", &ratings); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := files["reviews.json"]; ok {
+		if err := decodeArchiveFile(files, "reviews.json", &reviews); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := files["review_votes.json"]; ok {
+		if err := decodeArchiveFile(files, "review_votes.json", &votes); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := files["preferences.json"]; ok {
+		preferences = &archivePreferences{}
+		if err := decodeArchiveFile(files, "preferences.json", preferences); err != nil {
+			return nil, err
+		}
+	}
+	for _, count := range []int{len(watchlist), len(ratings), len(reviews), len(votes)} {
+		if count > MaxArchiveEntries {
+			return nil, fmt.Errorf("%w: at most %d entries of each file can be imported at once", ErrInvalidArchive, MaxArchiveEntries)
+		}
+	}
+
+	report := &ArchiveImportReport{DryRun: dryRun, OnConflict: onConflict, Summary: map[string]map[string]int{}, Items: []ArchiveImportItem{}}
+	if err := s.importEntries(userID, watchlist, ratings, archiveOrigin, onConflict, dryRun, report); err != nil {
+		return nil, err
+	}
+	if err := s.importReviews(userID, reviews, votes, onConflict, dryRun, report); err != nil {
+		return nil, err
+	}
+	if preferences != nil {
+		items, err := s.importPreferences(userID, preferences, onConflict, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			report.add(item)
+		}
+	}
+	return report, nil
+}
+
+// importOrigin is where imported entries come from: the names their outcomes
+// are reported under and the source recorded on new watchlist entries
+type importOrigin struct {
+	watchlistFile string
+	ratingsFile   string
+	source        models.WatchlistSource
+}
+
+var archiveOrigin = importOrigin{
+	watchlistFile: "watchlist.json",
+	ratingsFile:   "ratings.json",
+	source:        models.WatchlistSource{Type: models.WatchlistSourceImport},
+}
+
+// importEntries restores watchlist entries and ratings, adding each outcome
+// to the report. Entries are written directly, so no domain events are
+// published for them.
+func (s *ArchiveImportService) importEntries(userID primitive.ObjectID, watchlist []archiveWatchlistEntry, ratings []archiveRating, origin importOrigin, onConflict string, dryRun bool, report *ArchiveImportReport) error {
+	movies, err := s.cachedMovies(watchlist, ratings)
+	if err != nil {
+		return err
+	}
+	quota := watchlistQuota{room: -1}
+	if len(watchlist) > 0 {
+		quota.room, quota.limit, err = s.quotaService.WatchlistRoom(userID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, entry := range watchlist {
+		item := s.importWatchlistEntry(userID, entry, movies, &quota, origin.source, onConflict, dryRun)
+		item.File = origin.watchlistFile
+		report.add(item)
+	}
+	for _, rating := range ratings {
+		item := s.importRating(userID, rating, movies, onConflict, dryRun)
+		item.File = origin.ratingsFile
+		report.add(item)
+	}
+	return nil
+}
+
+// readArchive unzips the archive into memory by base file name
+func readArchive(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a ZIP file", ErrInvalidArchive)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Base(file.Name)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if file.UncompressedSize64 > maxArchiveFileBytes {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidArchive, name, maxArchiveFileBytes)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+		}
+		// The header size can lie, so the read is bounded as well
+		content, err := io.ReadAll(io.LimitReader(rc, maxArchiveFileBytes+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+		}
+		if len(content) > maxArchiveFileBytes {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidArchive, name, maxArchiveFileBytes)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+func decodeArchiveFile(files map[string][]byte, name string, target interface{}) error {
+	content, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: %s is missing", ErrInvalidArchive, name)
+	}
+	if err := json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	return nil
+}
+
+// cachedMovies loads the archive's movies that are already cached, by IMDb ID
+func (s *ArchiveImportService) cachedMovies(watchlist []archiveWatchlistEntry, ratings []archiveRating) (map[string]models.Movie, error) {
+	imdbIDs := make([]string, 0, len(watchlist)+len(ratings))
+	for _, entry := range watchlist {
+		imdbIDs = append(imdbIDs, models.NormalizeIMDbID(entry.IMDbID))
+	}
+	for _, rating := range ratings {
+		imdbIDs = append(imdbIDs, models.NormalizeIMDbID(rating.IMDbID))
+	}
+	return s.movieRepo.FindByIMDbIDs(imdbIDs, "_id")
+}
+
+// resolveMovie returns the canonical ID and all equivalent IDs of a cached
+// movie, fetching an uncached one from OMDb unless dryRun is set. ok is false
+// with the item marked failed when the movie cannot be resolved.
+func (s *ArchiveImportService) resolveMovie(item *ArchiveImportItem, movies map[string]models.Movie, dryRun bool) (primitive.ObjectID, []primitive.ObjectID, bool) {
+	movie, cached := movies[item.IMDbID]
+	item.MovieCached = cached
+	if !cached {
+		if dryRun {
+			return primitive.NilObjectID, nil, true
+		}
+		if s.movieService.usageService.IsQuotaNearlyExhausted() {
+			item.Action = ImportActionFailed
+			item.Error = "daily OMDb quota nearly exhausted; import the archive again tomorrow"
+			return primitive.NilObjectID, nil, false
+		}
+		fetched, err := s.movieService.GetOrCreateByIMDbID(item.IMDbID)
+		if err != nil {
+			item.Action = ImportActionFailed
+			item.Error = err.Error()
+			return primitive.NilObjectID, nil, false
+		}
+		movie = *fetched
+		movies[item.IMDbID] = movie
+		item.MovieCached = true
+	}
+
+	canonicalID, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movie.ID)
+	if err != nil {
+		item.Action = ImportActionFailed
+		item.Error = "failed to look up movie"
+		return primitive.NilObjectID, nil, false
+	}
+	return canonicalID, equivalentIDs, true
+}
+
+// watchlistQuota counts down the entries an import may still add; room is -1
+// when the watchlist is unlimited
+type watchlistQuota struct {
+	room  int
+	limit int
+}
+
+func (s *ArchiveImportService) importWatchlistEntry(userID primitive.ObjectID, entry archiveWatchlistEntry, movies map[string]models.Movie, quota *watchlistQuota, source models.WatchlistSource, onConflict string, dryRun bool) ArchiveImportItem {
+	item := ArchiveImportItem{File: "watchlist.json", IMDbID: models.NormalizeIMDbID(entry.IMDbID)}
+	if !imdbIDPattern.MatchString(item.IMDbID) {
+		item.Action = ImportActionInvalid
+		item.Error = "imdb_id must look like tt0111161"
+		return item
+	}
+	if entry.Priority != 0 && (entry.Priority < models.MinWatchlistPriority || entry.Priority > models.MaxWatchlistPriority) {
+		item.Action = ImportActionInvalid
+		item.Error = fmt.Sprintf("priority must be between %d and %d", models.MinWatchlistPriority, models.MaxWatchlistPriority)
+		return item
+	}
+
+	movieID, equivalentIDs, ok := s.resolveMovie(&item, movies, dryRun)
+	if !ok {
+		return item
+	}
+
+	var existing *models.Watchlist
+	if equivalentIDs != nil {
+		var err error
+		existing, err = s.watchlistRepo.FindEntryForAny(userID, equivalentIDs)
+		if err != nil {
+			item.Action = ImportActionFailed
+			item.Error = "failed to look up watchlist"
+			return item
+		}
+	}
+
+	if existing == nil {
+		if quota.room == 0 {
+			item.Action = ImportActionFailed
+			item.Error = watchlistQuotaError(quota.limit).Error()
+			return item
+		}
+		if quota.room > 0 {
+			quota.room--
+		}
+		item.Action = ImportActionCreate
+		if !dryRun {
+			err := s.watchlistRepo.Add(&models.Watchlist{
+				UserID:    userID,
+				MovieID:   movieID,
+				AddedAt:   entry.AddedAt,
+				WatchedAt: entry.WatchedAt,
+				Priority:  entry.Priority,
+				Source:    &source,
+			})
+			if err != nil {
+				s.logger.Warn("failed to import watchlist entry", "user_id", userID.Hex(), "imdb_id", item.IMDbID, "error", err)
+				item.Action = ImportActionFailed
+				item.Error = "failed to save watchlist entry"
+			}
+		}
+		return item
+	}
+
+	// Decide the watched time and priority the entry should end up with
+	watchedAt, priority := existing.WatchedAt, existing.Priority
+	switch onConflict {
+	case ArchiveConflictOverwrite:
+		watchedAt, priority = entry.WatchedAt, entry.Priority
+	case ArchiveConflictMerge:
+		if watchedAt == nil {
+			watchedAt = entry.WatchedAt
+		}
+		if priority == 0 {
+			priority = entry.Priority
+		}
+	}
+
+	watchedChanged := (watchedAt == nil) != (existing.WatchedAt == nil) ||
+		(watchedAt != nil && existing.WatchedAt != nil && !watchedAt.Equal(*existing.WatchedAt))
+	priorityChanged := priority != existing.Priority
+	differs := (entry.WatchedAt == nil) != (existing.WatchedAt == nil) || entry.Priority != existing.Priority
+
+	switch {
+	case watchedChanged || priorityChanged:
+		item.Action = ImportActionUpdate
+	case differs:
+		item.Action = ImportActionSkipped
+		return item
+	default:
+		item.Action = ImportActionUnchanged
+		return item
+	}
+	if dryRun {
+		return item
+	}
+
+	// Only change the entry that was planned against
+	version := existing.Version
+	var updated bool
+	var err error
+	if watchedChanged {
+		updated, err = s.watchlistRepo.SetWatched(userID, existing.MovieID, watchedAt, &version)
+		version++
+	}
+	if err == nil && priorityChanged && (updated || !watchedChanged) {
+		updated, err = s.watchlistRepo.SetPriority(userID, existing.MovieID, priority, &version)
+	}
+	if err != nil {
+		s.logger.Warn("failed to import watchlist entry", "user_id", userID.Hex(), "imdb_id", item.IMDbID, "error", err)
+		item.Action = ImportActionFailed
+		item.Error = "failed to save watchlist entry"
+	} else if !updated {
+		item.Action = ImportActionFailed
+		item.Error = "the watchlist entry changed while importing"
+	}
+	return item
+}
+
+func (s *ArchiveImportService) importRating(userID primitive.ObjectID, rating archiveRating, movies map[string]models.Movie, onConflict string, dryRun bool) ArchiveImportItem {
+	item := ArchiveImportItem{File: "ratings.json", IMDbID: models.NormalizeIMDbID(rating.IMDbID)}
+	if !imdbIDPattern.MatchString(item.IMDbID) {
+		item.Action = ImportActionInvalid
+		item.Error = "imdb_id must look like tt0111161"
+		return item
+	}
+	if rating.Rating < 1 || rating.Rating > 5 {
+		item.Action = ImportActionInvalid
+		item.Error = "rating must be a whole number from 1 to 5"
+		return item
+	}
+	if rating.RatedAt.After(time.Now()) {
+		item.Action = ImportActionInvalid
+		item.Error = "rated_at cannot be in the future"
+		return item
+	}
+
+	movieID, equivalentIDs, ok := s.resolveMovie(&item, movies, dryRun)
+	if !ok {
+		return item
+	}
+
+	var existing *models.Rating
+	if equivalentIDs != nil {
+		var err error
+		existing, err = s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
+		if err != nil {
+			item.Action = ImportActionFailed
+			item.Error = "failed to look up rating"
+			return item
+		}
+	}
+
+	ratedAt := rating.RatedAt.UTC()
+	if ratedAt.IsZero() {
+		ratedAt = time.Now().UTC()
+	}
+
+	switch {
+	case existing == nil:
+		item.Action = ImportActionCreate
+	case existing.Rating == rating.Rating:
+		item.Action = ImportActionUnchanged
+	case onConflict == ArchiveConflictOverwrite,
+		onConflict == ArchiveConflictMerge && !rating.RatedAt.IsZero() && rating.RatedAt.After(existing.UpdatedAt):
+		item.Action = ImportActionUpdate
+	default:
+		item.Action = ImportActionSkipped
+	}
+	if dryRun || (item.Action != ImportActionCreate && item.Action != ImportActionUpdate) {
+		return item
+	}
+
+	var err error
+	updated := true
+	if item.Action == ImportActionCreate {
+		err = s.ratingRepo.Create(&models.Rating{
+			UserID:    userID,
+			MovieID:   movieID,
+			Rating:    rating.Rating,
+			CreatedAt: ratedAt,
+			UpdatedAt: ratedAt,
+		})
+	} else {
+		version := existing.Version
+		updated, err = s.ratingRepo.UpdateAt(userID, existing.MovieID, rating.Rating, ratedAt, &version)
+	}
+	if err != nil {
+		s.logger.Warn("failed to import rating", "user_id", userID.Hex(), "imdb_id", item.IMDbID, "error", err)
+		item.Action = ImportActionFailed
+		item.Error = "failed to save rating"
+	} else if !updated {
+		item.Action = ImportActionFailed
+		item.Error = "the rating changed while importing"
+	}
+	return item
+}
+
+// importReviews restores the user's reviews and the helpful votes they cast,
+// adding each outcome to the report. Restored reviews start without votes,
+// since the votes of other users stay with their accounts.
+func (s *ArchiveImportService) importReviews(userID primitive.ObjectID, reviews []archiveReview, votes []archiveReviewVote, onConflict string, dryRun bool, report *ArchiveImportReport) error {
+	if len(reviews) == 0 && len(votes) == 0 {
+		return nil
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	imdbIDs := make([]string, 0, len(reviews)+len(votes))
+	for _, review := range reviews {
+		imdbIDs = append(imdbIDs, review.IMDbID)
+	}
+	for _, vote := range votes {
+		imdbIDs = append(imdbIDs, vote.IMDbID)
+	}
+	movies, err := s.movieRepo.FindByIMDbIDs(imdbIDs, "_id")
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		report.add(s.importReview(user, review, movies, onConflict, dryRun))
+	}
+	for _, vote := range votes {
+		report.add(s.importReviewVote(userID, vote, movies, dryRun))
+	}
+	return nil
+}
+
+func (s *ArchiveImportService) importReview(user *models.User, review archiveReview, movies map[string]models.Movie, onConflict string, dryRun bool) ArchiveImportItem {
+	item := ArchiveImportItem{File: "reviews.json", IMDbID: models.NormalizeIMDbID(review.IMDbID)}
+	if !imdbIDPattern.MatchString(item.IMDbID) {
+		item.Action = ImportActionInvalid
+		item.Error = "imdb_id must look like tt0111161"
+		return item
+	}
+	body := strings.TrimSpace(review.Body)
+	if body == "" || len([]rune(body)) > maxReviewLength {
+		item.Action = ImportActionInvalid
+		item.Error = fmt.Sprintf("body must be 1 to %d characters", maxReviewLength)
+		return item
+	}
+	if review.UpdatedAt.After(time.Now()) {
+		item.Action = ImportActionInvalid
+		item.Error = "updated_at cannot be in the future"
+		return item
+	}
+
+	movieID, equivalentIDs, ok := s.resolveMovie(&item, movies, dryRun)
+	if !ok {
+		return item
+	}
+
+	var existing *models.Review
+	if equivalentIDs != nil {
+		var err error
+		existing, err = s.reviewRepo.FindByUserForAny(user.ID, equivalentIDs)
+		if err != nil {
+			item.Action = ImportActionFailed
+			item.Error = "failed to look up review"
+			return item
+		}
+	}
+
+	switch {
+	case existing == nil:
+		item.Action = ImportActionCreate
+	case existing.Body == body:
+		item.Action = ImportActionUnchanged
+	case onConflict == ArchiveConflictOverwrite,
+		onConflict == ArchiveConflictMerge && review.UpdatedAt.After(existing.UpdatedAt):
+		item.Action = ImportActionUpdate
+	default:
+		item.Action = ImportActionSkipped
+	}
+	if dryRun || (item.Action != ImportActionCreate && item.Action != ImportActionUpdate) {
+		return item
+	}
+
+	updatedAt := review.UpdatedAt.UTC()
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+	createdAt := review.CreatedAt.UTC()
+	if createdAt.IsZero() || createdAt.After(updatedAt) {
+		createdAt = updatedAt
+	}
+
+	restored := &models.Review{UserID: user.ID, Username: user.Username, MovieID: movieID, Body: body, CreatedAt: createdAt, UpdatedAt: updatedAt}
+	if existing != nil {
+		restored.ID = existing.ID
+	}
+	if err := s.reviewRepo.Restore(restored); err != nil {
+		s.logger.Warn("failed to import review", "user_id", user.ID.Hex(), "imdb_id", item.IMDbID, "error", err)
+		item.Action = ImportActionFailed
+		item.Error = "failed to save review"
+	}
+	return item
+}
+
+// importReviewVote restores a helpful vote when the review it was cast on,
+// found by its movie and author, exists on this instance
+func (s *ArchiveImportService) importReviewVote(userID primitive.ObjectID, vote archiveReviewVote, movies map[string]models.Movie, dryRun bool) ArchiveImportItem {
+	item := ArchiveImportItem{File: "review_votes.json", IMDbID: models.NormalizeIMDbID(vote.IMDbID)}
+	if !imdbIDPattern.MatchString(item.IMDbID) {
+		item.Action = ImportActionInvalid
+		item.Error = "imdb_id must look like tt0111161"
+		return item
+	}
+	if vote.Username == "" {
+		item.Action = ImportActionInvalid
+		item.Error = "username is required"
+		return item
+	}
+
+	review, err := s.votedReview(vote, movies)
+	if err != nil {
+		s.logger.Warn("failed to look up voted review", "user_id", userID.Hex(), "imdb_id", item.IMDbID, "error", err)
+		item.Action = ImportActionFailed
+		item.Error = "failed to look up review"
+		return item
+	}
+	switch {
+	case review == nil:
+		item.Action = ImportActionFailed
+		item.Error = "the review is not on this instance"
+		return item
+	case review.UserID == userID:
+		item.Action = ImportActionInvalid
+		item.Error = ErrVoteOwnReview.Error()
+		return item
+	}
+
+	voted, err := s.reviewRepo.FindVotedIDs(userID, []primitive.ObjectID{review.ID})
+	if err != nil {
+		item.Action = ImportActionFailed
+		item.Error = "failed to look up vote"
+		return item
+	}
+	if voted[review.ID] {
+		item.Action = ImportActionUnchanged
+		return item
+	}
+	item.Action = ImportActionCreate
+	if !dryRun {
+		if _, err := s.reviewRepo.AddVote(review.ID, userID); err != nil {
+			s.logger.Warn("failed to import review vote", "user_id", userID.Hex(), "imdb_id", item.IMDbID, "error", err)
+			item.Action = ImportActionFailed
+			item.Error = "failed to save vote"
+		}
+	}
+	return item
+}
+
+// votedReview finds the review a vote in the archive was cast on, or nil
+// when its movie, author or the review itself is not on this instance
+func (s *ArchiveImportService) votedReview(vote archiveReviewVote, movies map[string]models.Movie) (*models.Review, error) {
+	movie, ok := movies[models.NormalizeIMDbID(vote.IMDbID)]
+	if !ok {
+		return nil, nil
+	}
+	author, err := s.userRepo.FindByUsername(vote.Username)
+	if err != nil || author == nil {
+		return nil, err
+	}
+	_, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movie.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.FindByUserForAny(author.ID, equivalentIDs)
+}
+
+// importPreferences restores the language lists and recommendation settings.
+// Values the account has never set are always filled in.
+func (s *ArchiveImportService) importPreferences(userID primitive.ObjectID, preferences *archivePreferences, onConflict string, dryRun bool) ([]ArchiveImportItem, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	items := []ArchiveImportItem{}
+	if preferences.AudioLanguages != nil || preferences.SubtitleLanguages != nil {
+		items = append(items, s.importLanguages(user, preferences, onConflict, dryRun))
+	}
+
+	if preferences.RecommendationSettings != nil {
+		settings := ArchiveImportItem{File: "preferences.json", Field: "recommendation_settings"}
+		switch {
+		case user.RecommendationSettings != nil && onConflict != ArchiveConflictOverwrite:
+			// Merging keeps settings the account already chose
+			settings.Action = ImportActionSkipped
+		case dryRun:
+			settings.Action = ImportActionUpdate
+		default:
+			settings.Action = ImportActionUpdate
+			if _, err := s.recommendationScheduler.UpdateSettings(userID, *preferences.RecommendationSettings); err != nil {
+				settings.Action, settings.Error = ImportActionFailed, err.Error()
+				if errors.Is(err, ErrInvalidRecommendationSettings) {
+					settings.Action = ImportActionInvalid
+				}
+			}
+		}
+		items = append(items, settings)
+	}
+	return items, nil
+}
+
+// importLanguages restores the audio and subtitle language lists; a list
+// missing from the archive is left as it is
+func (s *ArchiveImportService) importLanguages(user *models.User, preferences *archivePreferences, onConflict string, dryRun bool) ArchiveImportItem {
+	item := ArchiveImportItem{File: "preferences.json", Field: "languages"}
+
+	audio, subtitles := user.AudioLanguages, user.SubtitleLanguages
+	conflict := false
+	for _, list := range []struct {
+		imported []string
+		target   *[]string
+	}{{preferences.AudioLanguages, &audio}, {preferences.SubtitleLanguages, &subtitles}} {
+		if list.imported == nil {
+			continue
+		}
+		imported, err := normalizeLanguages(list.imported)
+		if err != nil {
+			item.Action, item.Error = ImportActionInvalid, err.Error()
+			return item
+		}
+		existing := *list.target
+		*list.target = resolveLanguageConflict(existing, imported, onConflict)
+		if len(existing) > 0 && !sameLanguages(existing, imported) {
+			conflict = true
+		}
+	}
+
+	switch {
+	case !sameLanguages(audio, user.AudioLanguages) || !sameLanguages(subtitles, user.SubtitleLanguages):
+		item.Action = ImportActionUpdate
+	case conflict:
+		item.Action = ImportActionSkipped
+		return item
+	default:
+		item.Action = ImportActionUnchanged
+		return item
+	}
+
+	if !dryRun {
+		if _, err := s.userRepo.UpdateLanguages(user.ID, audio, subtitles); err != nil {
+			s.logger.Warn("failed to import languages", "user_id", user.ID.Hex(), "error", err)
+			item.Action, item.Error = ImportActionFailed, "failed to save languages"
+		}
+	}
+	return item
+}
+
+// resolveLanguageConflict returns the language list the account should end
+// up with
+func resolveLanguageConflict(existing, imported []string, onConflict string) []string {
+	if len(existing) == 0 || onConflict == ArchiveConflictOverwrite {
+		return imported
+	}
+	if onConflict == ArchiveConflictMerge {
+		merged := append([]string{}, existing...)
+		for _, language := range imported {
+			merged = appendUnique(merged, language)
+		}
+		return merged
+	}
+	return existing
+}
+
+func sameLanguages(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
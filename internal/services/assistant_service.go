@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Assistant intents
+const (
+	IntentAddToWatchlist      = "add_to_watchlist"
+	IntentRemoveFromWatchlist = "remove_from_watchlist"
+	IntentMarkWatched         = "mark_watched"
+	IntentRateMovie           = "rate_movie"
+	IntentNextOnWatchlist     = "next_on_watchlist"
+)
+
+// Assistant response statuses
+const (
+	// AssistantDone means the intent was carried out
+	AssistantDone = "done"
+	// AssistantAlreadyDone means there was nothing to do, such as adding a
+	// movie that is already on the watchlist
+	AssistantAlreadyDone = "already_done"
+	// AssistantNeedsChoice means the query matched several movies; the
+	// intent is sent again with the imdb_id of one of the candidates
+	AssistantNeedsChoice = "needs_choice"
+	// AssistantNeedsInput means a slot such as the query or rating is missing
+	AssistantNeedsInput = "needs_input"
+	AssistantNotFound   = "not_found"
+	// AssistantRejected means the intent cannot be carried out, such as
+	// adding to a full watchlist or a movie the profile may not see
+	AssistantRejected = "rejected"
+)
+
+const (
+	// assistantChoices caps the candidates offered in one spoken question
+	assistantChoices = 3
+	// assistantMinConfidence is the lowest confidence at which a watchlist
+	// entry is offered as a candidate
+	assistantMinConfidence = 0.4
+)
+
+// ErrUnknownIntent is returned for intents the assistant does not handle
+var ErrUnknownIntent = errors.New("unknown intent")
+
+// AssistantRequest is one spoken command after the voice platform parsed it
+type AssistantRequest struct {
+	Intent string
+	// Query is the movie title as spoken
+	Query string
+	// IMDbID picks one of the candidates of an earlier needs_choice response
+	IMDbID string
+	Rating int
+}
+
+// AssistantMovie is a movie as named in a response
+type AssistantMovie struct {
+	ID     string `json:"id"`
+	IMDbID string `json:"imdb_id"`
+	Title  string `json:"title"`
+	Year   string `json:"year"`
+}
+
+// AssistantResponse is the outcome of an intent, with a short sentence for
+// the assistant to speak. Reprompt is set when the assistant should listen
+// for an answer.
+type AssistantResponse struct {
+	Intent     string              `json:"intent"`
+	Status     string              `json:"status"`
+	Speech     string              `json:"speech"`
+	Reprompt   string              `json:"reprompt,omitempty"`
+	Movie      *AssistantMovie     `json:"movie,omitempty"`
+	Candidates []QuickAddCandidate `json:"candidates,omitempty"`
+	Undo       *UndoToken          `json:"undo,omitempty"`
+}
+
+// AssistantService carries out simple spoken intents for voice assistant
+// skills: it resolves the spoken title, asks back when the title is
+// ambiguous and confirms the result in a sentence short enough to speak
+type AssistantService struct {
+	quickAddService  *QuickAddService
+	watchlistService *WatchlistService
+	ratingService    *RatingService
+	movieRepo        *repositories.MovieRepository
+	dashboardRepo    *repositories.DashboardRepository
+}
+
+func NewAssistantService(quickAddService *QuickAddService, watchlistService *WatchlistService, ratingService *RatingService, movieRepo *repositories.MovieRepository, dashboardRepo *repositories.DashboardRepository) *AssistantService {
+	return &AssistantService{
+		quickAddService:  quickAddService,
+		watchlistService: watchlistService,
+		ratingService:    ratingService,
+		movieRepo:        movieRepo,
+		dashboardRepo:    dashboardRepo,
+	}
+}
+
+// Handle carries out an intent. allowed reports whether the movie may be
+// added or rated on the current profile.
+func (s *AssistantService) Handle(ctx context.Context, userID primitive.ObjectID, req AssistantRequest, allowed func(*models.Movie) bool) (*AssistantResponse, error) {
+	var response *AssistantResponse
+	var err error
+	switch req.Intent {
+	case IntentAddToWatchlist:
+		response, err = s.addToWatchlist(ctx, userID, req, allowed)
+	case IntentRemoveFromWatchlist:
+		response, err = s.removeFromWatchlist(userID, req)
+	case IntentMarkWatched:
+		response, err = s.markWatched(userID, req)
+	case IntentRateMovie:
+		response, err = s.rateMovie(ctx, userID, req, allowed)
+	case IntentNextOnWatchlist:
+		response, err = s.nextOnWatchlist(userID)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownIntent, req.Intent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	response.Intent = req.Intent
+	return response, nil
+}
+
+func (s *AssistantService) addToWatchlist(ctx context.Context, userID primitive.ObjectID, req AssistantRequest, allowed func(*models.Movie) bool) (*AssistantResponse, error) {
+	movie, response, err := s.resolve(ctx, userID, req, "Which movie should I add?")
+	if movie == nil {
+		return response, err
+	}
+	if !allowed(movie) {
+		return &AssistantResponse{Status: AssistantRejected, Speech: "That movie isn't available on this profile.", Movie: assistantMovie(movie)}, nil
+	}
+
+	source := &models.WatchlistSource{Type: models.WatchlistSourceAssistant}
+	if _, err := s.watchlistService.AddWithServerSource(userID, movie.ID, 0, source); err != nil {
+		switch {
+		case errors.Is(err, ErrQuotaExceeded):
+			return &AssistantResponse{Status: AssistantRejected, Speech: "Your watchlist is full.", Movie: assistantMovie(movie)}, nil
+		case err.Error() == "movie already in watchlist":
+			return &AssistantResponse{Status: AssistantAlreadyDone, Speech: spokenTitle(movie.Title, movie.Year) + " is already on your watchlist.", Movie: assistantMovie(movie)}, nil
+		}
+		return nil, err
+	}
+	return &AssistantResponse{Status: AssistantDone, Speech: "Added " + spokenTitle(movie.Title, movie.Year) + " to your watchlist.", Movie: assistantMovie(movie)}, nil
+}
+
+func (s *AssistantService) removeFromWatchlist(userID primitive.ObjectID, req AssistantRequest) (*AssistantResponse, error) {
+	movie, response, err := s.findOnWatchlist(userID, req, false, "Which movie should I remove?")
+	if movie == nil {
+		return response, err
+	}
+
+	undo, err := s.watchlistService.RemoveFromWatchlist(userID, movie.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &AssistantResponse{Status: AssistantDone, Speech: "Removed " + movie.Title + " from your watchlist.", Movie: assistantMovie(movie), Undo: undo}, nil
+}
+
+func (s *AssistantService) markWatched(userID primitive.ObjectID, req AssistantRequest) (*AssistantResponse, error) {
+	movie, response, err := s.findOnWatchlist(userID, req, true, "Which movie did you watch?")
+	if movie == nil {
+		return response, err
+	}
+
+	if _, err := s.watchlistService.MarkWatched(userID, movie.ID, nil); err != nil {
+		return nil, err
+	}
+	return &AssistantResponse{Status: AssistantDone, Speech: "Marked " + movie.Title + " as watched.", Movie: assistantMovie(movie)}, nil
+}
+
+func (s *AssistantService) rateMovie(ctx context.Context, userID primitive.ObjectID, req AssistantRequest, allowed func(*models.Movie) bool) (*AssistantResponse, error) {
+	movie, response, err := s.resolve(ctx, userID, req, "Which movie do you want to rate?")
+	if movie == nil {
+		return response, err
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		speech := fmt.Sprintf("How many stars would you give %s, from one to five?", movie.Title)
+		return &AssistantResponse{Status: AssistantNeedsInput, Speech: speech, Reprompt: "How many stars, from one to five?", Movie: assistantMovie(movie)}, nil
+	}
+	if !allowed(movie) {
+		return &AssistantResponse{Status: AssistantRejected, Speech: "That movie isn't available on this profile.", Movie: assistantMovie(movie)}, nil
+	}
+
+	_, err = s.ratingService.RateMovie(userID, movie.ID, req.Rating)
+	if err != nil && err.Error() == "user has already rated this movie" {
+		_, err = s.ratingService.UpdateRating(userID, movie.ID, req.Rating, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &AssistantResponse{Status: AssistantDone, Speech: fmt.Sprintf("Rated %s %s.", movie.Title, spokenStars(req.Rating)), Movie: assistantMovie(movie)}, nil
+}
+
+func (s *AssistantService) nextOnWatchlist(userID primitive.ObjectID) (*AssistantResponse, error) {
+	entries, err := s.dashboardRepo.FindNextUp(userID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return &AssistantResponse{Status: AssistantNotFound, Speech: "There's nothing left to watch on your watchlist."}, nil
+	}
+	movie, err := s.movieRepo.FindByID(entries[0].MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return &AssistantResponse{Status: AssistantNotFound, Speech: "There's nothing left to watch on your watchlist."}, nil
+	}
+	return &AssistantResponse{Status: AssistantDone, Speech: "Next on your watchlist is " + spokenTitle(movie.Title, movie.Year) + ".", Movie: assistantMovie(movie)}, nil
+}
+
+// resolve finds any movie by the spoken title, or by IMDb ID once the user
+// picked a candidate. Without a movie it returns the response to give.
+func (s *AssistantService) resolve(ctx context.Context, userID primitive.ObjectID, req AssistantRequest, question string) (*models.Movie, *AssistantResponse, error) {
+	if req.IMDbID == "" && req.Query == "" {
+		return nil, &AssistantResponse{Status: AssistantNeedsInput, Speech: question, Reprompt: question}, nil
+	}
+
+	match, err := s.quickAddService.Resolve(ctx, req.IMDbID, "", req.Query, userID)
+	if err != nil {
+		var unsure *QuickAddError
+		if !errors.As(err, &unsure) {
+			return nil, nil, err
+		}
+		return nil, choiceResponse(req.Query, unsure.Candidates), nil
+	}
+	return match.Movie, nil, nil
+}
+
+// findOnWatchlist matches the spoken title against the movies on the user's
+// watchlist, only unwatched ones when unwatched is set. Without a movie it
+// returns the response to give.
+func (s *AssistantService) findOnWatchlist(userID primitive.ObjectID, req AssistantRequest, unwatched bool, question string) (*models.Movie, *AssistantResponse, error) {
+	if req.IMDbID == "" && req.Query == "" {
+		return nil, &AssistantResponse{Status: AssistantNeedsInput, Speech: question, Reprompt: question}, nil
+	}
+
+	entries, err := s.watchlistService.GetUserWatchlist(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		if !unwatched || entry.WatchedAt == nil {
+			ids = append(ids, entry.MovieID)
+		}
+	}
+	movies, err := s.movieRepo.FindByIDs(ids, "_id", "imdb_id", "title", "year")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byIMDbID := make(map[string]models.Movie, len(movies))
+	candidates := make([]QuickAddCandidate, 0, len(movies))
+	for _, id := range ids {
+		movie, ok := movies[id]
+		if !ok {
+			continue
+		}
+		byIMDbID[movie.IMDbID] = movie
+		candidates = append(candidates, QuickAddCandidate{IMDbID: movie.IMDbID, Title: movie.Title, Year: movie.Year})
+	}
+	notFound := &AssistantResponse{Status: AssistantNotFound, Speech: "I couldn't find that on your watchlist."}
+
+	if req.IMDbID != "" {
+		movie, ok := byIMDbID[req.IMDbID]
+		if !ok {
+			return nil, notFound, nil
+		}
+		return &movie, nil, nil
+	}
+
+	query, year := cleanPageTitle(req.Query)
+	ranked := rankQuickAddCandidates(query, year, candidates)
+	likely := ranked[:0]
+	for _, candidate := range ranked {
+		if candidate.Confidence >= assistantMinConfidence {
+			likely = append(likely, candidate)
+		}
+	}
+	if len(likely) == 0 {
+		notFound.Speech = fmt.Sprintf("I couldn't find %s on your watchlist.", req.Query)
+		return nil, notFound, nil
+	}
+	// A clear winner is taken; titles that tie, such as remakes, are asked
+	// about
+	if likely[0].Confidence >= quickAddThreshold && (len(likely) == 1 || likely[1].Confidence < likely[0].Confidence) {
+		movie := byIMDbID[likely[0].IMDbID]
+		return &movie, nil, nil
+	}
+	return nil, choiceResponse(req.Query, likely), nil
+}
+
+// choiceResponse asks which of the best candidates the user meant
+func choiceResponse(query string, candidates []QuickAddCandidate) *AssistantResponse {
+	if len(candidates) == 0 {
+		return &AssistantResponse{Status: AssistantNotFound, Speech: fmt.Sprintf("I couldn't find a movie called %s.", query)}
+	}
+	if len(candidates) > assistantChoices {
+		candidates = candidates[:assistantChoices]
+	}
+	if len(candidates) == 1 {
+		speech := fmt.Sprintf("Did you mean %s?", spokenTitle(candidates[0].Title, candidates[0].Year))
+		return &AssistantResponse{Status: AssistantNeedsChoice, Speech: speech, Reprompt: speech, Candidates: candidates}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		names = append(names, spokenTitle(candidate.Title, candidate.Year))
+	}
+	last := len(names) - 1
+	speech := fmt.Sprintf("Did you mean %s, or %s?", strings.Join(names[:last], ", "), names[last])
+	return &AssistantResponse{Status: AssistantNeedsChoice, Speech: speech, Reprompt: "Which one?", Candidates: candidates}
+}
+
+// spokenTitle names a movie with its year, which tells remakes apart
+func spokenTitle(title, year string) string {
+	if year == "" {
+		return title
+	}
+	return title + " from " + year
+}
+
+func spokenStars(rating int) string {
+	if rating == 1 {
+		return "one star"
+	}
+	return [...]string{"", "", "two", "three", "four", "five"}[rating] + " stars"
+}
+
+func assistantMovie(movie *models.Movie) *AssistantMovie {
+	return &AssistantMovie{ID: movie.ID.Hex(), IMDbID: movie.IMDbID, Title: movie.Title, Year: movie.Year}
+}
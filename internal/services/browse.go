@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strconv"
+	"strings"
+)
+
+// Browse sort orders
+const (
+	BrowseSortIMDbRating = "imdb_rating"
+	BrowseSortYear       = "year"
+	BrowseSortTitle      = "title"
+)
+
+// ErrInvalidBrowseQuery is returned for malformed browse parameters
+var ErrInvalidBrowseQuery = errors.New("invalid browse query")
+
+// BrowseQuery describes a catalogue browse request
+type BrowseQuery struct {
+	// Decade such as "1990s"; mutually exclusive with YearFrom/YearTo
+	Decade   string
+	YearFrom int
+	YearTo   int
+	Genre    string
+	// Keyword is a tagged theme such as heist
+	Keyword string
+	Sort    string
+	// MaxCertification restricts results for kids profiles when set
+	MaxCertification string
+}
+
+// ParseDecade turns "1990s" (or "1990") into the years 1990-1999
+func ParseDecade(decade string) (int, int, error) {
+	value := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(decade)), "s")
+	start, err := strconv.Atoi(value)
+	if err != nil || len(value) != 4 || start%10 != 0 {
+		return 0, 0, fmt.Errorf("%w: decade must look like 1990s", ErrInvalidBrowseQuery)
+	}
+	return start, start + 9, nil
+}
+
+// CertificationsUpTo lists the certifications allowed under the cap
+func CertificationsUpTo(maxCertification string) []string {
+	limit := certificationLevels[maxCertification]
+	allowed := []string{}
+	for certification, level := range certificationLevels {
+		if level <= limit {
+			allowed = append(allowed, certification)
+		}
+	}
+	return allowed
+}
+
+// BrowseMovies lists cached movies by decade or era, genre and keyword. It only reads
+// the local catalogue and never calls OMDb.
+func (s *MovieService) BrowseMovies(q BrowseQuery, offset, limit int) ([]models.Movie, int64, error) {
+	filter := repositories.BrowseFilter{
+		YearFrom: q.YearFrom,
+		YearTo:   q.YearTo,
+		Genre:    strings.TrimSpace(q.Genre),
+		Keyword:  strings.ToLower(strings.TrimSpace(q.Keyword)),
+		Sort:     q.Sort,
+	}
+
+	if q.Decade != "" {
+		if q.YearFrom != 0 || q.YearTo != 0 {
+			return nil, 0, fmt.Errorf("%w: use either decade or year_from/year_to", ErrInvalidBrowseQuery)
+		}
+		from, to, err := ParseDecade(q.Decade)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.YearFrom, filter.YearTo = from, to
+	}
+	if filter.YearFrom < 0 || filter.YearTo < 0 || (filter.YearTo > 0 && filter.YearFrom > filter.YearTo) {
+		return nil, 0, fmt.Errorf("%w: year_from must not be after year_to", ErrInvalidBrowseQuery)
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = BrowseSortIMDbRating
+	case BrowseSortIMDbRating, BrowseSortYear, BrowseSortTitle:
+	default:
+		return nil, 0, fmt.Errorf("%w: sort must be one of imdb_rating, year or title", ErrInvalidBrowseQuery)
+	}
+
+	if q.MaxCertification != "" {
+		filter.Rated = CertificationsUpTo(q.MaxCertification)
+	}
+
+	return s.movieRepo.Browse(filter, int64(offset), int64(limit))
+}
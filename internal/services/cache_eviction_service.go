@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// cacheEvictionScanPerRun caps the movies one eviction run looks at
+	cacheEvictionScanPerRun = 500
+	// cacheEvictionInterval is how long eviction waits after a full pass
+	// over the movies before starting the next
+	cacheEvictionInterval = 24 * time.Hour
+)
+
+// CacheEvictionService deletes cached movies that have not been fetched for
+// longer than the configured age and that no user has on their watchlist,
+// rated, is watching or viewed lately. An evicted movie is fetched again from
+// the metadata providers the next time it is searched or opened.
+type CacheEvictionService struct {
+	movieRepo *repositories.MovieRepository
+	jobQueue  *jobs.Queue
+	maxAge    time.Duration
+	logger    *slog.Logger
+}
+
+func NewCacheEvictionService(movieRepo *repositories.MovieRepository, jobQueue *jobs.Queue, maxAge time.Duration) *CacheEvictionService {
+	return &CacheEvictionService{
+		movieRepo: movieRepo,
+		jobQueue:  jobQueue,
+		maxAge:    maxAge,
+		logger:    logging.For("services.cache_eviction"),
+	}
+}
+
+// EnsureScheduled queues the first eviction run if eviction is enabled and
+// none is pending
+func (s *CacheEvictionService) EnsureScheduled() error {
+	if s.maxAge <= 0 {
+		return nil
+	}
+	return s.jobQueue.EnsureScheduled(jobs.TypeEvictMovies, nil, time.Now().UTC())
+}
+
+// EvictJob passes over the movies in batches, one batch per run, deleting
+// the unreferenced ones among those cached too long ago. The payload's
+// "after" is where the pass continues; once it reaches the end, the next
+// pass starts a day later. With eviction turned off the job stops
+// rescheduling itself.
+func (s *CacheEvictionService) EvictJob(ctx context.Context, payload map[string]interface{}) error {
+	if s.maxAge <= 0 {
+		return nil
+	}
+
+	after := primitive.NilObjectID
+	if hex, ok := payload["after"].(string); ok {
+		after, _ = primitive.ObjectIDFromHex(hex)
+	}
+
+	ids, last, err := s.movieRepo.FindUnreferenced(after, time.Now().UTC().Add(-s.maxAge), cacheEvictionScanPerRun)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		deleted, err := s.movieRepo.DeleteMovies(ids)
+		if err != nil {
+			return err
+		}
+		s.logger.Info("evicted unreferenced movies", "movies", deleted)
+	}
+
+	if last.IsZero() {
+		return s.jobQueue.EnqueueAt(jobs.TypeEvictMovies, nil, time.Now().UTC().Add(cacheEvictionInterval))
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeEvictMovies, map[string]interface{}{"after": last.Hex()}, time.Now().UTC())
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// calendarFeedPastNights is how far back movie nights stay on the feed
+	calendarFeedPastNights = 30 * 24 * time.Hour
+	// calendarFeedNights is how far ahead movie nights are listed
+	calendarFeedNights = 365 * 24 * time.Hour
+	// defaultMovieNightLength is how long a movie night lasts when its
+	// winner's runtime is unknown or there is no winner yet
+	defaultMovieNightLength = 2 * time.Hour
+	// calendarFeedRefresh is how often calendar apps are asked to fetch the
+	// feed again
+	calendarFeedRefresh = "PT6H"
+)
+
+// ErrInvalidFeedToken is returned for unknown and revoked calendar feed
+// tokens and for feeds of deactivated users
+var ErrInvalidFeedToken = errors.New("calendar feed not found")
+
+// CalendarFeedService serves each user's movie nights and the releases of
+// their watchlist movies as an iCalendar feed at a secret URL
+type CalendarFeedService struct {
+	userRepo      *repositories.UserRepository
+	pollRepo      *repositories.PollRepository
+	movieRepo     *repositories.MovieRepository
+	watchlistRepo *repositories.WatchlistRepository
+	calendarRepo  *repositories.CalendarRepository
+	baseURL       string
+}
+
+func NewCalendarFeedService(userRepo *repositories.UserRepository, pollRepo *repositories.PollRepository, movieRepo *repositories.MovieRepository, watchlistRepo *repositories.WatchlistRepository, calendarRepo *repositories.CalendarRepository, baseURL string) *CalendarFeedService {
+	return &CalendarFeedService{
+		userRepo:      userRepo,
+		pollRepo:      pollRepo,
+		movieRepo:     movieRepo,
+		watchlistRepo: watchlistRepo,
+		calendarRepo:  calendarRepo,
+		baseURL:       baseURL,
+	}
+}
+
+// CreateFeed gives the user a new feed URL, which stops the previous one
+// from working. Only the hash of its token is stored, so the URL cannot be
+// shown again.
+func (s *CalendarFeedService) CreateFeed(userID primitive.ObjectID) (string, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	found, err := s.userRepo.SetCalendarFeedToken(userID, hashSecretToken(token))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("user not found")
+	}
+	return s.baseURL + "/calendar/" + token + ".ics", nil
+}
+
+// DeleteFeed turns the user's feed off
+func (s *CalendarFeedService) DeleteFeed(userID primitive.ObjectID) error {
+	_, err := s.userRepo.SetCalendarFeedToken(userID, "")
+	return err
+}
+
+// FeedOwner returns the user whose calendar feed token is token. Callers
+// check access to the feed with the authz policy.
+func (s *CalendarFeedService) FeedOwner(token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidFeedToken
+	}
+	user, err := s.userRepo.FindByCalendarFeedToken(hashSecretToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DeactivatedAt != nil {
+		return nil, ErrInvalidFeedToken
+	}
+	return user, nil
+}
+
+// Feed returns the user's iCalendar feed: movie nights from the last 30
+// days and the next year, and the releases of unwatched watchlist movies in
+// the next MaxCalendarDays days
+func (s *CalendarFeedService) Feed(user *models.User) ([]byte, error) {
+	now := time.Now().UTC()
+	polls, err := s.pollRepo.FindScheduled(user.ID, now.Add(-calendarFeedPastNights), now.Add(calendarFeedNights))
+	if err != nil {
+		return nil, err
+	}
+	winners, err := s.pollWinners(polls, now)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist, err := s.watchlistRepo.GetUserWatchlist(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	movieIDs := make([]primitive.ObjectID, 0, len(watchlist))
+	for _, entry := range watchlist {
+		if entry.WatchedAt == nil {
+			movieIDs = append(movieIDs, entry.MovieID)
+		}
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	releases, err := s.calendarRepo.FindByMovieIDs(movieIDs, today, today.AddDate(0, 0, MaxCalendarDays))
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCalendarFeed(user.Username, polls, winners, releases, now), nil
+}
+
+// pollWinners returns the winning movie of each closed poll that has one
+func (s *CalendarFeedService) pollWinners(polls []models.Poll, now time.Time) (map[primitive.ObjectID]models.Movie, error) {
+	winnerIDs := make(map[primitive.ObjectID]primitive.ObjectID)
+	for _, poll := range polls {
+		if !poll.IsClosed(now) {
+			continue
+		}
+		ballots, err := s.pollRepo.FindBallots(poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, winner, _ := instantRunoff(poll.MovieIDs, ballots); winner != nil {
+			winnerIDs[poll.ID] = *winner
+		}
+	}
+
+	winners := make(map[primitive.ObjectID]models.Movie, len(winnerIDs))
+	if len(winnerIDs) == 0 {
+		return winners, nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(winnerIDs))
+	for _, movieID := range winnerIDs {
+		ids = append(ids, movieID)
+	}
+	movies, err := s.movieRepo.FindByIDs(ids, "_id", "title", "runtime")
+	if err != nil {
+		return nil, err
+	}
+	for pollID, movieID := range winnerIDs {
+		if movie, ok := movies[movieID]; ok {
+			winners[pollID] = movie
+		}
+	}
+	return winners, nil
+}
+
+// buildCalendarFeed writes an RFC 5545 calendar with a timed event per movie
+// night and an all-day event per release
+func buildCalendarFeed(username string, polls []models.Poll, winners map[primitive.ObjectID]models.Movie, releases []models.UpcomingRelease, now time.Time) []byte {
+	var buf bytes.Buffer
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeICSLine(&buf, "BEGIN:VCALENDAR")
+	writeICSLine(&buf, "VERSION:2.0")
+	writeICSLine(&buf, "PRODID:-//movie-watchlist//calendar feed//EN")
+	writeICSLine(&buf, "CALSCALE:GREGORIAN")
+	writeICSLine(&buf, "METHOD:PUBLISH")
+	writeICSLine(&buf, "X-WR-CALNAME:"+escapeICSText("Movie nights for "+username))
+	writeICSLine(&buf, "REFRESH-INTERVAL;VALUE=DURATION:"+calendarFeedRefresh)
+	writeICSLine(&buf, "X-PUBLISHED-TTL:"+calendarFeedRefresh)
+
+	for _, poll := range polls {
+		start := poll.ScheduledFor.UTC()
+		length := defaultMovieNightLength
+		summary := "Movie night: " + poll.Title
+		description := fmt.Sprintf("Poll by %s", poll.OwnerName)
+		if movie, ok := winners[poll.ID]; ok {
+			summary += " (" + movie.Title + ")"
+			description = fmt.Sprintf("%s won the poll by %s", movie.Title, poll.OwnerName)
+			if minutes := movie.RuntimeMinutes(); minutes > 0 {
+				length = time.Duration(minutes) * time.Minute
+			}
+		} else if !poll.IsClosed(now) {
+			description += "; voting is still open"
+		}
+
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, "UID:poll-"+poll.ID.Hex()+"@movie-watchlist")
+		writeICSLine(&buf, "DTSTAMP:"+stamp)
+		writeICSLine(&buf, "LAST-MODIFIED:"+poll.UpdatedAt.UTC().Format("20060102T150405Z"))
+		writeICSLine(&buf, "DTSTART:"+start.Format("20060102T150405Z"))
+		writeICSLine(&buf, "DTEND:"+start.Add(length).Format("20060102T150405Z"))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(summary))
+		writeICSLine(&buf, "DESCRIPTION:"+escapeICSText(description))
+		writeICSLine(&buf, "END:VEVENT")
+	}
+
+	for _, release := range releases {
+		day := release.ReleaseDate.UTC()
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, "UID:release-"+release.IMDbID+"@movie-watchlist")
+		writeICSLine(&buf, "DTSTAMP:"+stamp)
+		writeICSLine(&buf, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+		writeICSLine(&buf, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(release.Title+" is released"))
+		if len(release.Directors) > 0 {
+			writeICSLine(&buf, "DESCRIPTION:"+escapeICSText("Directed by "+strings.Join(release.Directors, ", ")))
+		}
+		writeICSLine(&buf, "URL:https://www.imdb.com/title/"+release.IMDbID+"/")
+		writeICSLine(&buf, "TRANSP:TRANSPARENT")
+		writeICSLine(&buf, "END:VEVENT")
+	}
+
+	writeICSLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// writeICSLine writes a content line ended by CRLF, folded so that no line
+// is longer than 75 octets without splitting a UTF-8 character
+func writeICSLine(buf *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = 74
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// icsTextEscaper escapes the characters RFC 5545 reserves in TEXT values
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeICSText escapes a TEXT value
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// calendarRatingThreshold is the rating at which a movie's director and
+	// franchise count as followed
+	calendarRatingThreshold = 4
+	// upcomingKeywordsPerRun caps provider lookups per job run to protect the OMDb quota
+	upcomingKeywordsPerRun = 15
+	// upcomingReleasesInterval is how often the upcoming releases job runs
+	upcomingReleasesInterval = 24 * time.Hour
+	// DefaultCalendarDays and MaxCalendarDays bound the calendar window
+	DefaultCalendarDays = 365
+	MaxCalendarDays     = 730
+)
+
+// Calendar match reasons
+const (
+	CalendarReasonDirector  = "director"
+	CalendarReasonActor     = "actor"
+	CalendarReasonFranchise = "franchise"
+)
+
+// CalendarReason explains why a release is on the user's calendar
+type CalendarReason struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// CalendarEntry is an upcoming release and why it was picked for the user
+type CalendarEntry struct {
+	Release models.UpcomingRelease
+	Reasons []CalendarReason
+}
+
+type CalendarService struct {
+	calendarRepo     *repositories.CalendarRepository
+	ratingRepo       *repositories.RatingRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	followService    *FollowService
+	movieService     *MovieService
+	provider         ReleaseProvider
+	jobQueue         *jobs.Queue
+	logger           *slog.Logger
+}
+
+func NewCalendarService(calendarRepo *repositories.CalendarRepository, ratingRepo *repositories.RatingRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, followService *FollowService, movieService *MovieService, provider ReleaseProvider, jobQueue *jobs.Queue) *CalendarService {
+	return &CalendarService{
+		calendarRepo:     calendarRepo,
+		ratingRepo:       ratingRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		followService:    followService,
+		movieService:     movieService,
+		provider:         provider,
+		jobQueue:         jobQueue,
+		logger:           logging.For("services.calendar"),
+	}
+}
+
+// GetCalendar returns releases in the next days days from the directors and
+// franchises of movies the user rated 4 stars or more, and from the
+// directors and actors they follow
+func (s *CalendarService) GetCalendar(userID primitive.ObjectID, days int) ([]CalendarEntry, error) {
+	favorites, err := s.ratingRepo.GetHighRatedMovies(userID, calendarRatingThreshold, "title", "director")
+	if err != nil {
+		return nil, err
+	}
+	followedDirectors, followedActors, err := s.followService.FollowedPeople(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	franchises := make(map[string]string)
+	directors := make(map[string]bool)
+	for _, movie := range favorites {
+		if key := franchiseKey(movie.Title); key != "" {
+			franchises[key] = movie.Title
+		}
+		for _, director := range splitCredits(movie.Director) {
+			directors[director] = true
+		}
+	}
+	for _, director := range followedDirectors {
+		directors[director] = true
+	}
+	actors := make(map[string]bool, len(followedActors))
+	for _, actor := range followedActors {
+		actors[actor] = true
+	}
+
+	franchiseKeys := make([]string, 0, len(franchises))
+	for key := range franchises {
+		franchiseKeys = append(franchiseKeys, key)
+	}
+	directorNames := make([]string, 0, len(directors))
+	for name := range directors {
+		directorNames = append(directorNames, name)
+	}
+
+	now := time.Now().UTC()
+	releases, err := s.calendarRepo.FindUpcoming(now, now.AddDate(0, 0, days), franchiseKeys, directorNames, followedActors)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CalendarEntry, 0, len(releases))
+	for _, release := range releases {
+		entry := CalendarEntry{Release: release}
+		for _, director := range release.Directors {
+			if directors[director] {
+				entry.Reasons = append(entry.Reasons, CalendarReason{Type: CalendarReasonDirector, Value: director})
+			}
+		}
+		for _, actor := range release.Actors {
+			if actors[actor] {
+				entry.Reasons = append(entry.Reasons, CalendarReason{Type: CalendarReasonActor, Value: actor})
+			}
+		}
+		if title, ok := franchises[release.FranchiseKey]; ok {
+			entry.Reasons = append(entry.Reasons, CalendarReason{Type: CalendarReasonFranchise, Value: title})
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// EnsureScheduled queues the first upcoming releases run if none is pending
+func (s *CalendarService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeUpcomingReleases, nil, time.Now().UTC())
+}
+
+// UpcomingReleasesJob looks up announced movies for the most followed
+// franchises, stores them for the calendar and notifies the fans of each
+// newly found release. It reschedules itself to run again a day later.
+func (s *CalendarService) UpcomingReleasesJob(ctx context.Context, payload map[string]interface{}) error {
+	fans, err := s.ratingRepo.GetHighRatedMovieFans(calendarRatingThreshold)
+	if err != nil {
+		return err
+	}
+
+	// Fans are sorted by popularity, so the first keywords are the most followed
+	keywords := []string{}
+	seen := make(map[string]bool)
+	for _, entry := range fans {
+		key := franchiseKey(entry.Movie.Title)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keywords = append(keywords, key)
+		if len(keywords) == upcomingKeywordsPerRun {
+			break
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, keyword := range keywords {
+		releases, err := s.provider.UpcomingForFranchise(ctx, keyword, now)
+		if err != nil {
+			s.logger.Warn("upcoming releases lookup failed", "keyword", keyword, "error", err)
+			continue
+		}
+		for _, info := range releases {
+			if err := s.storeRelease(info, keyword, fans); err != nil {
+				s.logger.Warn("failed to store upcoming release", "imdb_id", info.Details.IMDbID, "error", err)
+			}
+		}
+	}
+
+	if err := s.jobQueue.EnqueueAt(jobs.TypeUpcomingReleases, nil, now.Add(upcomingReleasesInterval)); err != nil {
+		return fmt.Errorf("failed to reschedule upcoming releases job: %w", err)
+	}
+	return nil
+}
+
+// storeRelease caches the movie, records the release and notifies its fans
+// when the release was not known before
+func (s *CalendarService) storeRelease(info ReleaseInfo, keyword string, fans []repositories.MovieFans) error {
+	if err := s.movieService.storeMovieDetails(&info.Details, models.MovieSourceOMDb, changedByJob(jobs.TypeUpcomingReleases)); err != nil {
+		return err
+	}
+	movie, err := s.movieService.movieRepo.FindByIMDbID(info.Details.IMDbID)
+	if err != nil {
+		return err
+	}
+	if movie == nil {
+		return fmt.Errorf("movie %s missing after caching", info.Details.IMDbID)
+	}
+
+	release := &models.UpcomingRelease{
+		MovieID:      movie.ID,
+		IMDbID:       movie.IMDbID,
+		Title:        movie.Title,
+		Directors:    splitCredits(movie.Director),
+		Actors:       splitCredits(movie.Actors),
+		Poster:       movie.Poster,
+		Rated:        movie.Rated,
+		ReleaseDate:  info.ReleaseDate,
+		FranchiseKey: keyword,
+	}
+	inserted, err := s.calendarRepo.UpsertRelease(release)
+	if err != nil || !inserted {
+		return err
+	}
+
+	s.notifyFans(release, fans)
+	return nil
+}
+
+// notifyFans tells every user who rated a movie of the same franchise or
+// director highly, or follows one of its directors or actors, about a newly
+// found release
+func (s *CalendarService) notifyFans(release *models.UpcomingRelease, fans []repositories.MovieFans) {
+	directors := make(map[string]bool, len(release.Directors))
+	for _, director := range release.Directors {
+		directors[director] = true
+	}
+
+	var userIDs []primitive.ObjectID
+	for _, entry := range fans {
+		if entry.Movie.ID == release.MovieID {
+			continue
+		}
+		related := franchiseKey(entry.Movie.Title) == release.FranchiseKey
+		for _, director := range splitCredits(entry.Movie.Director) {
+			related = related || directors[director]
+		}
+		if related {
+			userIDs = append(userIDs, entry.UserIDs...)
+		}
+	}
+	followers, err := s.followService.Followers(release.Directors, release.Actors)
+	if err != nil {
+		s.logger.Warn("failed to look up followers", "imdb_id", release.IMDbID, "error", err)
+	}
+	userIDs = append(userIDs, followers...)
+
+	// Deactivated accounts get no notifications; they count as notified
+	notified, err := s.userRepo.FindDeactivatedIDs(userIDs)
+	if err != nil {
+		s.logger.Warn("failed to look up deactivated users", "error", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+
+		exists, err := s.notificationRepo.ExistsForMovie(userID, models.NotificationUpcomingRelease, release.MovieID)
+		if err != nil || exists {
+			continue
+		}
+		movieID := release.MovieID
+		notification := &models.Notification{
+			UserID:  userID,
+			Type:    models.NotificationUpcomingRelease,
+			Title:   fmt.Sprintf("Coming soon: %s", release.Title),
+			Message: fmt.Sprintf("%s releases on %s. It's on your release calendar.", release.Title, release.ReleaseDate.Format("2 Jan 2006")),
+			MovieID: &movieID,
+		}
+		if err := s.notificationRepo.Create(notification); err != nil {
+			s.logger.Warn("failed to create upcoming release notification", "user_id", userID.Hex(), "error", err)
+		}
+	}
+}
+
+var (
+	// franchiseSubtitle cuts "Title: Subtitle" and "Title - Subtitle"
+	franchiseSubtitle = regexp.MustCompile(`\s*(:|\s-\s|\s–\s).*$`)
+	// franchiseSequel strips trailing sequel markers such as "2", "III" or "Part 3"
+	franchiseSequel = regexp.MustCompile(`(?i)\s+(part|chapter|vol\.?|volume)?\s*([0-9]+|[ivx]+)$`)
+)
+
+// franchiseKey derives a search keyword shared by the movies of a franchise
+// from a title: "Toy Story 3" and "Toy Story" both become "toy story", and
+// "Dune: Part Two" becomes "dune". It is a heuristic; sequels with unrelated
+// titles are not linked.
+func franchiseKey(title string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	key = franchiseSubtitle.ReplaceAllString(key, "")
+	key = franchiseSequel.ReplaceAllString(key, "")
+	key = strings.TrimPrefix(key, "the ")
+	key = strings.TrimSpace(key)
+	if len(key) < 3 {
+		return ""
+	}
+	return key
+}
+
+// splitCredits splits OMDb's comma-separated director or actor list
+func splitCredits(credits string) []string {
+	names := []string{}
+	for _, name := range strings.Split(credits, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && name != "N/A" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
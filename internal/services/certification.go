@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"strings"
+)
+
+// ErrInvalidCertification is returned for a certification cap outside ProfileCertifications
+var ErrInvalidCertification = errors.New("max_certification must be one of G, PG, PG-13 or R")
+
+// certificationLevels orders the US film and TV certifications OMDb reports.
+// Anything else (Not Rated, Unrated, N/A, foreign ratings) has no level.
+var certificationLevels = map[string]int{
+	"G":     1,
+	"TV-Y":  1,
+	"TV-Y7": 1,
+	"TV-G":  1,
+	"PG":    2,
+	"TV-PG": 2,
+	"PG-13": 3,
+	"TV-14": 3,
+	"R":     4,
+	"TV-MA": 4,
+	"NC-17": 5,
+}
+
+// ProfileCertifications are the caps a kids profile can be given, strictest first
+var ProfileCertifications = []string{"G", "PG", "PG-13", "R"}
+
+// DefaultProfileCertification is used when a profile is created without a cap
+const DefaultProfileCertification = "PG"
+
+// normalizeCertification validates a requested cap and returns its canonical spelling
+func normalizeCertification(value string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultProfileCertification, nil
+	}
+	for _, allowed := range ProfileCertifications {
+		if value == allowed {
+			return value, nil
+		}
+	}
+	return "", ErrInvalidCertification
+}
+
+// CertificationAllowed reports whether a movie rated rated may be shown under
+// the cap. Movies without a recognised certification are not allowed, since
+// a kids profile should only see titles known to be suitable.
+func CertificationAllowed(rated, maxCertification string) bool {
+	level, ok := certificationLevels[strings.ToUpper(strings.TrimSpace(rated))]
+	if !ok {
+		return false
+	}
+	return level <= certificationLevels[maxCertification]
+}
+
+// FilterByCertification keeps the movies allowed under the cap
+func FilterByCertification(movies []models.Movie, maxCertification string) []models.Movie {
+	filtered := make([]models.Movie, 0, len(movies))
+	for _, movie := range movies {
+		if CertificationAllowed(movie.Rated, maxCertification) {
+			filtered = append(filtered, movie)
+		}
+	}
+	return filtered
+}
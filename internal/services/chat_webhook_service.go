@@ -0,0 +1,373 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidChatWebhook = errors.New("url must be a Slack or Discord incoming webhook URL")
+	ErrChatWebhookNotSet  = errors.New("no chat webhook is set up")
+)
+
+// discordWebhookHosts are the hosts Discord hands out webhook URLs on
+var discordWebhookHosts = map[string]bool{
+	"discord.com":        true,
+	"discordapp.com":     true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+}
+
+// slackEscaper escapes the characters Slack reserves for links and mentions
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// ChatWebhookService posts a user's movie night polls to the Slack or
+// Discord channel behind their incoming webhook: new polls, movie nights
+// being scheduled and winners. Posts go through the job queue so a slow or
+// failing chat service is retried without holding up requests.
+type ChatWebhookService struct {
+	webhookRepo *repositories.ChatWebhookRepository
+	pollRepo    *repositories.PollRepository
+	movieRepo   *repositories.MovieRepository
+	encryption  *EncryptionService
+	jobQueue    *jobs.Queue
+	client      *http.Client
+	logger      *slog.Logger
+}
+
+func NewChatWebhookService(webhookRepo *repositories.ChatWebhookRepository, pollRepo *repositories.PollRepository, movieRepo *repositories.MovieRepository, encryption *EncryptionService, jobQueue *jobs.Queue) *ChatWebhookService {
+	return &ChatWebhookService{
+		webhookRepo: webhookRepo,
+		pollRepo:    pollRepo,
+		movieRepo:   movieRepo,
+		encryption:  encryption,
+		jobQueue:    jobQueue,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logging.For("services.chat_webhooks"),
+	}
+}
+
+// Subscribe posts poll changes of users with a webhook
+func (s *ChatWebhookService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NamePollCreated, "chat_webhooks", func(event events.Event) error {
+		created := event.(events.PollCreated)
+		webhook, err := s.webhookRepo.FindByUser(created.UserID)
+		if err != nil || webhook == nil {
+			return err
+		}
+		poll, err := s.pollRepo.FindByID(created.PollID)
+		if err != nil || poll == nil {
+			return err
+		}
+
+		text := fmt.Sprintf("%s started a movie night poll, %s, with %d movies.", poll.OwnerName, chatQuote(webhook.Kind, poll.Title), created.Movies)
+		if created.ScheduledFor != nil {
+			text += " Movie night is " + chatTime(webhook.Kind, *created.ScheduledFor) + "."
+		}
+		if created.ClosesAt != nil {
+			text += " Voting closes " + chatTime(webhook.Kind, *created.ClosesAt) + "."
+		}
+		if err := s.queuePost(created.UserID, text); err != nil {
+			return err
+		}
+		return s.queueResult(created.PollID, created.ClosesAt)
+	})
+	bus.Subscribe(events.NamePollUpdated, "chat_webhooks", func(event events.Event) error {
+		updated := event.(events.PollUpdated)
+		webhook, err := s.webhookRepo.FindByUser(updated.UserID)
+		if err != nil || webhook == nil {
+			return err
+		}
+
+		if updated.Rescheduled && updated.ScheduledFor != nil {
+			text := fmt.Sprintf("Movie night for %s is set for %s.", chatQuote(webhook.Kind, updated.Title), chatTime(webhook.Kind, *updated.ScheduledFor))
+			if err := s.queuePost(updated.UserID, text); err != nil {
+				return err
+			}
+		}
+		// A result queued for the old closing time finds the poll still open
+		// and does nothing
+		return s.queueResult(updated.PollID, updated.ClosesAt)
+	})
+	bus.Subscribe(events.NamePollClosed, "chat_webhooks", func(event events.Event) error {
+		closed := event.(events.PollClosed)
+		webhook, err := s.webhookRepo.FindByUser(closed.UserID)
+		if err != nil || webhook == nil {
+			return err
+		}
+		return s.queueResult(closed.PollID, &closed.At)
+	})
+}
+
+func (s *ChatWebhookService) queuePost(userID primitive.ObjectID, text string) error {
+	return s.jobQueue.Enqueue(jobs.TypeChatPost, map[string]interface{}{
+		"user_id": userID.Hex(),
+		"text":    text,
+	})
+}
+
+// queueResult posts the poll's winner once voting closes at closesAt
+func (s *ChatWebhookService) queueResult(pollID primitive.ObjectID, closesAt *time.Time) error {
+	if closesAt == nil {
+		return nil
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeChatPollResult, map[string]interface{}{"poll_id": pollID.Hex()}, *closesAt)
+}
+
+// Get returns the user's webhook without its URL
+func (s *ChatWebhookService) Get(userID primitive.ObjectID) (*models.ChatWebhook, error) {
+	webhook, err := s.webhookRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrChatWebhookNotSet
+	}
+	return webhook, nil
+}
+
+// Set stores the user's webhook URL, replacing an earlier one. Only Slack
+// and Discord incoming webhook URLs are accepted, which also keeps posts
+// from reaching other hosts.
+func (s *ChatWebhookService) Set(userID primitive.ObjectID, rawURL string) (*models.ChatWebhook, error) {
+	kind, err := chatWebhookKind(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	id := primitive.NewObjectID()
+	if existing != nil {
+		id = existing.ID
+	}
+	sealed, err := s.encryption.Encrypt("chat_webhooks", "url", id, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.Save(id, userID, kind, sealed)
+}
+
+// Delete removes the user's webhook
+func (s *ChatWebhookService) Delete(userID primitive.ObjectID) error {
+	deleted, err := s.webhookRepo.Delete(userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrChatWebhookNotSet
+	}
+	return nil
+}
+
+// SendTest queues a test post to the user's webhook
+func (s *ChatWebhookService) SendTest(userID primitive.ObjectID) error {
+	if _, err := s.Get(userID); err != nil {
+		return err
+	}
+	return s.queuePost(userID, "Movie night polls will be posted here.")
+}
+
+// PostJob posts a message to the user's webhook. Webhooks that Slack or
+// Discord no longer accept record the error and are not retried.
+func (s *ChatWebhookService) PostJob(ctx context.Context, payload map[string]interface{}) error {
+	userHex, _ := payload["user_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid user_id %q", userHex)
+	}
+	text, _ := payload["text"].(string)
+
+	webhook, err := s.webhookRepo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	// The webhook was removed after the post was queued
+	if webhook == nil {
+		return nil
+	}
+	webhookURL, err := s.encryption.Decrypt("chat_webhooks", "url", webhook.ID, webhook.URL)
+	if err != nil {
+		return err
+	}
+
+	status, err := s.post(ctx, webhook.Kind, webhookURL, text)
+	now := time.Now().UTC()
+	switch {
+	case err != nil:
+		s.recordResult(webhook.ID, now, err.Error())
+		return err
+	case status == http.StatusTooManyRequests || status >= 500:
+		s.recordResult(webhook.ID, now, fmt.Sprintf("%s returned status code %d", webhook.Kind, status))
+		return fmt.Errorf("%s webhook returned status code %d", webhook.Kind, status)
+	case status < 200 || status > 299:
+		s.recordResult(webhook.ID, now, fmt.Sprintf("%s rejected the webhook with status code %d", webhook.Kind, status))
+		return nil
+	}
+	s.recordResult(webhook.ID, now, "")
+	return nil
+}
+
+func (s *ChatWebhookService) recordResult(id primitive.ObjectID, at time.Time, postErr string) {
+	if err := s.webhookRepo.SetResult(id, at, postErr); err != nil {
+		s.logger.Warn("failed to record chat webhook result", "webhook_id", id.Hex(), "error", err)
+	}
+}
+
+// post sends text to the webhook and returns the response status
+func (s *ChatWebhookService) post(ctx context.Context, kind, webhookURL, text string) (int, error) {
+	var body interface{}
+	switch kind {
+	case models.ChatWebhookDiscord:
+		// Poll titles must not ping @everyone
+		body = map[string]interface{}{"content": text, "allowed_mentions": map[string]interface{}{"parse": []string{}}}
+	default:
+		body = map[string]string{"text": text}
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(encoded))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post to %s: %w", kind, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// PollResultJob posts the winner of a poll whose voting has closed. It does
+// nothing while the poll is open, since a later closing time queued its own
+// job, and posts each result once.
+func (s *ChatWebhookService) PollResultJob(ctx context.Context, payload map[string]interface{}) error {
+	pollHex, _ := payload["poll_id"].(string)
+	pollID, err := primitive.ObjectIDFromHex(pollHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid poll_id %q", pollHex)
+	}
+
+	poll, err := s.pollRepo.FindByID(pollID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if poll == nil || !poll.IsClosed(now) {
+		return nil
+	}
+	webhook, err := s.webhookRepo.FindByUser(poll.UserID)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	ballots, err := s.pollRepo.FindBallots(pollID)
+	if err != nil {
+		return err
+	}
+	_, winner, tied := instantRunoff(poll.MovieIDs, ballots)
+	movies, err := s.movieRepo.FindByIDs(poll.MovieIDs, "_id", "title", "year")
+	if err != nil {
+		return err
+	}
+
+	posted, err := s.pollRepo.MarkResultPosted(pollID, now)
+	if err != nil || !posted {
+		return err
+	}
+
+	title := chatQuote(webhook.Kind, poll.Title)
+	var text string
+	switch {
+	case len(ballots) == 0:
+		text = fmt.Sprintf("Voting on %s closed without any votes.", title)
+	case winner != nil:
+		text = fmt.Sprintf("Voting on %s closed: %s won after %d votes.", title, chatMovieTitle(webhook.Kind, movies, *winner), len(ballots))
+	default:
+		names := make([]string, 0, len(tied))
+		for _, movieID := range tied {
+			names = append(names, chatMovieTitle(webhook.Kind, movies, movieID))
+		}
+		text = fmt.Sprintf("Voting on %s closed in a tie between %s.", title, strings.Join(names, " and "))
+	}
+	if poll.ScheduledFor != nil && poll.ScheduledFor.After(now) {
+		text += " Movie night is " + chatTime(webhook.Kind, *poll.ScheduledFor) + "."
+	}
+	return s.queuePost(poll.UserID, text)
+}
+
+// chatWebhookKind returns whether a URL is a Slack or a Discord incoming
+// webhook
+func chatWebhookKind(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" {
+		return "", ErrInvalidChatWebhook
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com" && strings.HasPrefix(u.Path, "/services/"):
+		return models.ChatWebhookSlack, nil
+	case discordWebhookHosts[host] && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return models.ChatWebhookDiscord, nil
+	}
+	return "", ErrInvalidChatWebhook
+}
+
+// chatQuote quotes user-entered text such as a poll title, escaping what
+// Slack would read as markup
+func chatQuote(kind, text string) string {
+	if kind == models.ChatWebhookSlack {
+		text = slackEscaper.Replace(text)
+	}
+	return "“" + text + "”"
+}
+
+// chatTime formats a time so each reader sees it in their own timezone, with
+// a UTC fallback for clients that cannot
+func chatTime(kind string, t time.Time) string {
+	fallback := t.UTC().Format("Mon Jan 2 15:04 UTC")
+	switch kind {
+	case models.ChatWebhookDiscord:
+		return fmt.Sprintf("<t:%d:F>", t.Unix())
+	case models.ChatWebhookSlack:
+		return fmt.Sprintf("<!date^%d^{date_long_pretty} at {time}|%s>", t.Unix(), fallback)
+	}
+	return fallback
+}
+
+func chatMovieTitle(kind string, movies map[primitive.ObjectID]models.Movie, movieID primitive.ObjectID) string {
+	movie, ok := movies[movieID]
+	if !ok {
+		return "a removed movie"
+	}
+	title := movie.Title
+	if movie.Year != "" {
+		title += " (" + movie.Year + ")"
+	}
+	if kind == models.ChatWebhookDiscord {
+		return "**" + title + "**"
+	}
+	return "*" + slackEscaper.Replace(title) + "*"
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkCache remembers when keys last passed a check, such as a session
+// being active, so repeated checks within ttl skip the database. Entries
+// older than ttl are swept out as the cache is used, so it only holds keys
+// checked recently, however many come and go over the life of the process.
+type checkCache struct {
+	ttl     time.Duration
+	entries sync.Map // key -> time.Time
+	sweptAt atomic.Int64
+}
+
+func newCheckCache(ttl time.Duration) *checkCache {
+	c := &checkCache{ttl: ttl}
+	c.sweptAt.Store(time.Now().UnixNano())
+	return c
+}
+
+// Fresh reports whether the key passed its check less than ttl ago
+func (c *checkCache) Fresh(key interface{}) bool {
+	checked, ok := c.entries.Load(key)
+	return ok && time.Since(checked.(time.Time)) < c.ttl
+}
+
+// Store records that the key passed its check now
+func (c *checkCache) Store(key interface{}) {
+	now := time.Now()
+	c.entries.Store(key, now)
+	c.sweep(now)
+}
+
+// Delete forgets the key, so its next check goes to the database
+func (c *checkCache) Delete(key interface{}) {
+	c.entries.Delete(key)
+}
+
+// sweep drops expired entries, at most once per ttl
+func (c *checkCache) sweep(now time.Time) {
+	last := c.sweptAt.Load()
+	if now.UnixNano()-last < int64(c.ttl) || !c.sweptAt.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	c.entries.Range(func(key, checked interface{}) bool {
+		if now.Sub(checked.(time.Time)) >= c.ttl {
+			c.entries.Delete(key)
+		}
+		return true
+	})
+}
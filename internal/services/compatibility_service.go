@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// compatibilityMinCommon is how many movies both users must have rated
+	// before their ratings are correlated
+	compatibilityMinCommon = 5
+	// compatibilityRatingWeight is the share of the score from the rating
+	// correlation; genre overlap makes up the rest
+	compatibilityRatingWeight = 0.7
+	// compatibilityGenreThreshold is the rating from which a movie's genres
+	// count towards the user's taste
+	compatibilityGenreThreshold = 4
+)
+
+var ErrCompareWithSelf = errors.New("cannot compare taste with yourself")
+
+// Compatibility is how closely two users' tastes match
+type Compatibility struct {
+	Username string `json:"username"`
+	// Score runs from 0 to 100
+	Score int `json:"score"`
+	// RatingCorrelation is the Pearson correlation of the ratings both users
+	// gave the same movies, from -1 to 1. It is nil until they have rated
+	// enough movies in common or while either rated them all alike.
+	RatingCorrelation *float64 `json:"rating_correlation"`
+	CommonRatings     int      `json:"common_ratings"`
+	// GenreOverlap is the Jaccard similarity of the genres each user rates
+	// highly, from 0 to 1
+	GenreOverlap float64  `json:"genre_overlap"`
+	SharedGenres []string `json:"shared_genres"`
+}
+
+// CompatibilityService compares users' ratings for users who allowed it
+type CompatibilityService struct {
+	userRepo   *repositories.UserRepository
+	ratingRepo *repositories.RatingRepository
+}
+
+func NewCompatibilityService(userRepo *repositories.UserRepository, ratingRepo *repositories.RatingRepository) *CompatibilityService {
+	return &CompatibilityService{userRepo: userRepo, ratingRepo: ratingRepo}
+}
+
+// Compare returns how well the user's taste matches that of the user with
+// the given username, or nil when that user does not exist, is deactivated
+// or does not allow comparisons
+func (s *CompatibilityService) Compare(userID primitive.ObjectID, username string) (*Compatibility, error) {
+	other, err := s.userRepo.FindByUsername(username)
+	if err != nil || other == nil || other.DeactivatedAt != nil || !other.CompatibilityPublic {
+		return nil, err
+	}
+	if other.ID == userID {
+		return nil, ErrCompareWithSelf
+	}
+
+	result := &Compatibility{Username: other.Username, SharedGenres: []string{}}
+
+	mine, err := s.ratingRepo.GetUserRatings(userID)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := s.ratingRepo.GetUserRatings(other.ID)
+	if err != nil {
+		return nil, err
+	}
+	mineByMovie := make(map[primitive.ObjectID]int, len(mine))
+	for _, rating := range mine {
+		mineByMovie[rating.MovieID] = rating.Rating
+	}
+	var xs, ys []float64
+	for _, rating := range theirs {
+		if value, ok := mineByMovie[rating.MovieID]; ok {
+			xs = append(xs, float64(value))
+			ys = append(ys, float64(rating.Rating))
+		}
+	}
+	result.CommonRatings = len(xs)
+	if len(xs) >= compatibilityMinCommon {
+		result.RatingCorrelation = pearson(xs, ys)
+	}
+
+	myGenres, err := s.likedGenres(userID)
+	if err != nil {
+		return nil, err
+	}
+	theirGenres, err := s.likedGenres(other.ID)
+	if err != nil {
+		return nil, err
+	}
+	union := len(theirGenres)
+	for key, name := range myGenres {
+		if _, ok := theirGenres[key]; ok {
+			result.SharedGenres = append(result.SharedGenres, name)
+		} else {
+			union++
+		}
+	}
+	sort.Strings(result.SharedGenres)
+	if union > 0 {
+		result.GenreOverlap = round2(float64(len(result.SharedGenres)) / float64(union))
+	}
+
+	score := result.GenreOverlap
+	if result.RatingCorrelation != nil {
+		score = compatibilityRatingWeight*(*result.RatingCorrelation+1)/2 + (1-compatibilityRatingWeight)*result.GenreOverlap
+	}
+	result.Score = int(math.Round(score * 100))
+	return result, nil
+}
+
+// SetPublic sets whether other users can compare their taste with the user's
+func (s *CompatibilityService) SetPublic(userID primitive.ObjectID, public bool) error {
+	found, err := s.userRepo.SetCompatibilityPublic(userID, public)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// likedGenres returns the genres of the movies the user rated highly, keyed
+// by their lowercase name
+func (s *CompatibilityService) likedGenres(userID primitive.ObjectID) (map[string]string, error) {
+	combined, err := s.ratingRepo.GetHighRatedGenres(userID, compatibilityGenreThreshold)
+	if err != nil {
+		return nil, err
+	}
+	genres := make(map[string]string)
+	for _, genre := range combined {
+		for _, name := range strings.Split(genre, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || strings.EqualFold(name, "N/A") {
+				continue
+			}
+			genres[strings.ToLower(name)] = name
+		}
+	}
+	return genres, nil
+}
+
+// pearson returns the correlation of xs and ys, or nil when either does not
+// vary
+func pearson(xs, ys []float64) *float64 {
+	n := float64(len(xs))
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return nil
+	}
+	r := round2(cov / math.Sqrt(varX*varY))
+	return &r
+}
+
+// round2 keeps two decimals, which is all a score shown to users needs
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
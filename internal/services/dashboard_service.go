@@ -0,0 +1,248 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// dashboardVersion is bumped whenever the dashboard layout changes, so
+	// documents built with an older layout are rebuilt on read
+	dashboardVersion = 1
+	// dashboardMaxAge bounds how stale a dashboard gets from writes that
+	// publish no events, such as archive imports and undone removals
+	dashboardMaxAge = time.Hour
+	// dashboardRecommendationsMaxAge is how long computed recommendations are
+	// reused between rebuilds
+	dashboardRecommendationsMaxAge = 24 * time.Hour
+	// dashboardTopGenres, dashboardNextUp and dashboardRecommendations cap
+	// the lists on the dashboard
+	dashboardTopGenres       = 5
+	dashboardNextUp          = 5
+	dashboardRecommendations = 10
+)
+
+// DashboardService maintains the per-user dashboard read model. Consumers of
+// watchlist, rating and recommendation events rebuild the user's document,
+// so GET /me/dashboard reads it without running the aggregations behind the
+// stats, watchlist and recommendation endpoints.
+type DashboardService struct {
+	dashboardRepo         *repositories.DashboardRepository
+	movieRepo             *repositories.MovieRepository
+	recommendationService *RecommendationService
+	scheduler             *RecommendationScheduler
+	logger                *slog.Logger
+}
+
+func NewDashboardService(dashboardRepo *repositories.DashboardRepository, movieRepo *repositories.MovieRepository, recommendationService *RecommendationService, scheduler *RecommendationScheduler) *DashboardService {
+	return &DashboardService{
+		dashboardRepo:         dashboardRepo,
+		movieRepo:             movieRepo,
+		recommendationService: recommendationService,
+		scheduler:             scheduler,
+		logger:                logging.For("services.dashboard"),
+	}
+}
+
+// Subscribe rebuilds the user's dashboard on every event that changes it.
+// It must be subscribed after RecommendationScheduler so the precomputed
+// rows it reads already exclude the movie just added or rated.
+func (s *DashboardService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameWatchlistItemAdded, "dashboard", func(event events.Event) error {
+		added := event.(events.WatchlistItemAdded)
+		return s.rebuild(added.UserID, added.EquivalentIDs, false)
+	})
+	bus.Subscribe(events.NameMovieRated, "dashboard", func(event events.Event) error {
+		rated := event.(events.MovieRated)
+		return s.rebuild(rated.UserID, rated.EquivalentIDs, false)
+	})
+	bus.Subscribe(events.NameWatchlistItemRemoved, "dashboard", func(event events.Event) error {
+		return s.rebuild(event.(events.WatchlistItemRemoved).UserID, nil, false)
+	})
+	bus.Subscribe(events.NameWatchlistItemUpdated, "dashboard", func(event events.Event) error {
+		return s.rebuild(event.(events.WatchlistItemUpdated).UserID, nil, false)
+	})
+	bus.Subscribe(events.NameRecommendationsRefreshed, "dashboard", func(event events.Event) error {
+		return s.rebuild(event.(events.RecommendationsRefreshed).UserID, nil, true)
+	})
+}
+
+// GetDashboard returns the user's dashboard, building it first when there is
+// none yet or it is outdated
+func (s *DashboardService) GetDashboard(userID primitive.ObjectID) (*models.Dashboard, error) {
+	dashboard, err := s.dashboardRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if dashboard != nil && dashboard.Version == dashboardVersion && time.Since(dashboard.UpdatedAt) < dashboardMaxAge {
+		return dashboard, nil
+	}
+	return s.build(userID, dashboard, nil, false)
+}
+
+// rebuild refreshes the stored dashboard. A dashboard that failed to rebuild
+// is deleted rather than left stale, so the next read builds it.
+func (s *DashboardService) rebuild(userID primitive.ObjectID, handled []primitive.ObjectID, recompute bool) error {
+	previous, err := s.dashboardRepo.FindByUser(userID)
+	if err == nil {
+		_, err = s.build(userID, previous, handled, recompute)
+	}
+	if err != nil {
+		if deleteErr := s.dashboardRepo.Delete(userID); deleteErr != nil {
+			s.logger.Warn("failed to drop stale dashboard", "user_id", userID.Hex(), "error", deleteErr)
+		}
+	}
+	return err
+}
+
+// build computes and stores the user's dashboard. The recommendations of
+// previous are reused, minus the handled movies the user just added or
+// rated, unless recompute is set or they are older than
+// dashboardRecommendationsMaxAge.
+func (s *DashboardService) build(userID primitive.ObjectID, previous *models.Dashboard, handled []primitive.ObjectID, recompute bool) (*models.Dashboard, error) {
+	now := time.Now().UTC()
+	dashboard := &models.Dashboard{UserID: userID, Version: dashboardVersion, UpdatedAt: now}
+
+	var err error
+	dashboard.WatchlistCount, dashboard.WatchedCount, err = s.dashboardRepo.CountWatchlist(userID)
+	if err != nil {
+		return nil, err
+	}
+	if dashboard.RatingCount, err = s.dashboardRepo.CountRatings(userID); err != nil {
+		return nil, err
+	}
+	if dashboard.TopGenres, err = s.topGenres(userID); err != nil {
+		return nil, err
+	}
+	if dashboard.NextUp, err = s.nextUp(userID); err != nil {
+		return nil, err
+	}
+
+	reuse := !recompute && previous != nil && previous.Version == dashboardVersion &&
+		previous.RecommendationsAt != nil && now.Sub(*previous.RecommendationsAt) < dashboardRecommendationsMaxAge
+	if reuse {
+		drop := make(map[primitive.ObjectID]bool, len(handled))
+		for _, id := range handled {
+			drop[id] = true
+		}
+		dashboard.Recommendations = []models.DashboardMovie{}
+		for _, movie := range previous.Recommendations {
+			if !drop[movie.MovieID] {
+				dashboard.Recommendations = append(dashboard.Recommendations, movie)
+			}
+		}
+		dashboard.RecommendationsAt = previous.RecommendationsAt
+	} else {
+		movies, computedAt, err := s.recommendations(userID)
+		if err != nil {
+			return nil, err
+		}
+		dashboard.Recommendations = make([]models.DashboardMovie, 0, len(movies))
+		for _, movie := range movies {
+			dashboard.Recommendations = append(dashboard.Recommendations, dashboardMovie(movie))
+		}
+		dashboard.RecommendationsAt = computedAt
+	}
+
+	if err := s.dashboardRepo.Upsert(dashboard); err != nil {
+		return nil, err
+	}
+	return dashboard, nil
+}
+
+// topGenres ranks the genres of the user's watchlist and of the movies they
+// rated 4 stars or more
+func (s *DashboardService) topGenres(userID primitive.ObjectID) ([]models.GenreCount, error) {
+	watchlisted, err := s.dashboardRepo.CountWatchlistGenres(userID)
+	if err != nil {
+		return nil, err
+	}
+	liked, err := s.dashboardRepo.CountRatedGenres(userID, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]int64{}
+	for _, count := range append(watchlisted, liked...) {
+		totals[count.Genre] += count.Count
+	}
+	genres := make([]models.GenreCount, 0, len(totals))
+	for genre, count := range totals {
+		genres = append(genres, models.GenreCount{Genre: genre, Count: count})
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if genres[i].Count != genres[j].Count {
+			return genres[i].Count > genres[j].Count
+		}
+		return genres[i].Genre < genres[j].Genre
+	})
+	if len(genres) > dashboardTopGenres {
+		genres = genres[:dashboardTopGenres]
+	}
+	return genres, nil
+}
+
+func (s *DashboardService) nextUp(userID primitive.ObjectID) ([]models.DashboardMovie, error) {
+	entries, err := s.dashboardRepo.FindNextUp(userID, dashboardNextUp)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.MovieID)
+	}
+	movies, err := s.movieRepo.FindByIDs(ids, "title", "year", "poster")
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.DashboardMovie, 0, len(entries))
+	for _, entry := range entries {
+		movie, ok := movies[entry.MovieID]
+		if !ok {
+			continue
+		}
+		item := dashboardMovie(movie)
+		item.Priority = entry.EffectivePriority()
+		addedAt := entry.AddedAt
+		item.AddedAt = &addedAt
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// recommendations returns the precomputed "for you" row for users on a
+// schedule and computes it otherwise
+func (s *DashboardService) recommendations(userID primitive.ObjectID) ([]models.Movie, *time.Time, error) {
+	movies, computedAt, err := s.scheduler.ScheduledRecommendations(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if movies == nil {
+		if movies, err = s.recommendationService.GetRecommendations(userID, dashboardRecommendations); err != nil {
+			return nil, nil, err
+		}
+		now := time.Now().UTC()
+		computedAt = &now
+	}
+	if len(movies) > dashboardRecommendations {
+		movies = movies[:dashboardRecommendations]
+	}
+	return movies, computedAt, nil
+}
+
+func dashboardMovie(movie models.Movie) models.DashboardMovie {
+	return models.DashboardMovie{
+		MovieID: movie.ID,
+		Title:   movie.Title,
+		Year:    movie.Year,
+		Poster:  movie.Poster,
+	}
+}
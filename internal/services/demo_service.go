@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// demoEmailDomain is reserved (RFC 2606), so no mail is ever delivered
+	demoEmailDomain = "demo.invalid"
+	// demoCleanupPerRun caps the expired demo users deleted by one run
+	demoCleanupPerRun = 200
+	// demoCleanupInterval is how long the cleanup waits once caught up
+	demoCleanupInterval = 10 * time.Minute
+)
+
+// demoRatings and demoWatchlist are the seeded sandbox data, by IMDb ID from
+// the seed catalogue. Movies missing from the catalogue are skipped.
+var (
+	demoRatings = map[string]int{
+		"tt1375666": 5, // Inception
+		"tt0133093": 4, // The Matrix
+		"tt0245429": 5, // Spirited Away
+	}
+	demoWatchlist = []string{
+		"tt0468569", // The Dark Knight
+		"tt0107290", // Jurassic Park
+		"tt0111161", // The Shawshank Redemption
+	}
+)
+
+// DemoSession is a freshly provisioned sandbox account
+type DemoSession struct {
+	User      *models.User
+	Session   *models.Session
+	ExpiresAt time.Time
+}
+
+// DemoService provisions time-boxed sandbox accounts with seeded ratings and
+// watchlist entries, and deletes them once they expire
+type DemoService struct {
+	userRepo       *repositories.UserRepository
+	ratingRepo     *repositories.RatingRepository
+	watchlistRepo  *repositories.WatchlistRepository
+	movieRepo      *repositories.MovieRepository
+	demoRepo       *repositories.DemoRepository
+	sessionService *SessionService
+	jobQueue       *jobs.Queue
+	lifetime       time.Duration
+	logger         *slog.Logger
+}
+
+func NewDemoService(userRepo *repositories.UserRepository, ratingRepo *repositories.RatingRepository, watchlistRepo *repositories.WatchlistRepository, movieRepo *repositories.MovieRepository, demoRepo *repositories.DemoRepository, sessionService *SessionService, jobQueue *jobs.Queue, lifetime time.Duration) *DemoService {
+	return &DemoService{
+		userRepo:       userRepo,
+		ratingRepo:     ratingRepo,
+		watchlistRepo:  watchlistRepo,
+		movieRepo:      movieRepo,
+		demoRepo:       demoRepo,
+		sessionService: sessionService,
+		jobQueue:       jobQueue,
+		lifetime:       lifetime,
+		logger:         logging.For("services.demo"),
+	}
+}
+
+// StartSession creates a sandbox user with seeded data and a session that
+// ends when the sandbox expires
+func (s *DemoService) StartSession(userAgent, ip string) (*DemoSession, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	// Nobody can log in with the password; the demo token is the only way in
+	password, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.lifetime)
+	name := "demo-" + hex.EncodeToString(suffix)
+	user := &models.User{
+		Username:      name,
+		Email:         name + "@" + demoEmailDomain,
+		Password:      string(hashedPassword),
+		DemoExpiresAt: &expiresAt,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.seed(user); err != nil {
+		// The cleanup job removes the half-seeded user when it expires
+		return nil, fmt.Errorf("failed to seed demo data: %w", err)
+	}
+
+	session, err := s.sessionService.CreateDemoSession(user.ID, userAgent, ip, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("demo session started", "user_id", user.ID.Hex(), "expires_at", expiresAt)
+	return &DemoSession{User: user, Session: session, ExpiresAt: expiresAt}, nil
+}
+
+// seed gives the sandbox a few ratings and watchlist entries so
+// recommendations have something to work with
+func (s *DemoService) seed(user *models.User) error {
+	imdbIDs := append([]string{}, demoWatchlist...)
+	for imdbID := range demoRatings {
+		imdbIDs = append(imdbIDs, imdbID)
+	}
+	movies, err := s.movieRepo.FindByIMDbIDs(imdbIDs, "_id")
+	if err != nil {
+		return err
+	}
+
+	for imdbID, rating := range demoRatings {
+		movie, ok := movies[imdbID]
+		if !ok {
+			continue
+		}
+		if err := s.ratingRepo.Create(&models.Rating{UserID: user.ID, MovieID: movie.ID, Rating: rating}); err != nil {
+			return err
+		}
+	}
+	for _, imdbID := range demoWatchlist {
+		movie, ok := movies[imdbID]
+		if !ok {
+			continue
+		}
+		if err := s.watchlistRepo.Add(&models.Watchlist{UserID: user.ID, MovieID: movie.ID, Source: &models.WatchlistSource{Type: models.WatchlistSourceDemo}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureScheduled queues the first demo cleanup run if none is pending
+func (s *DemoService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeDemoCleanup, nil, time.Now().UTC())
+}
+
+// CleanupJob deletes expired demo users with all their data. It runs again
+// right away while expired users are left over and every ten minutes once it
+// has caught up.
+func (s *DemoService) CleanupJob(ctx context.Context, payload map[string]interface{}) error {
+	ids, err := s.demoRepo.FindExpiredUserIDs(time.Now().UTC(), demoCleanupPerRun)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.demoRepo.DeleteUser(id); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		s.logger.Info("deleted expired demo users", "users", len(ids))
+	}
+
+	next := time.Now().UTC().Add(demoCleanupInterval)
+	if len(ids) == demoCleanupPerRun {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeDemoCleanup, nil, next)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deprecationReloadInterval is how often notices are reloaded, so changes
+// made on one instance reach the others
+const deprecationReloadInterval = time.Minute
+
+var (
+	// ErrRouteNotRegistered is returned for routes that are not in the
+	// deprecation registry
+	ErrRouteNotRegistered = errors.New("route cannot be deprecated")
+	ErrInvalidDeprecation = errors.New("invalid deprecation")
+)
+
+// DeprecationUpdate turns the deprecation notice of a route on or off. An
+// empty Method applies it to every registered method of the path.
+type DeprecationUpdate struct {
+	Method   string
+	Path     string
+	Enabled  bool
+	SunsetAt *time.Time
+	Message  string
+}
+
+// DeprecationService keeps the registry of routes that can be deprecated,
+// such as v1 routes with a v2 successor, and the notices admins turned on
+// for them. DeprecationMiddleware announces the enabled notices to clients.
+type DeprecationService struct {
+	repo *repositories.DeprecationRepository
+
+	mu sync.RWMutex
+	// registry maps "METHOD path" to the successor route, which may be empty
+	registry map[string]string
+	active   map[string]*models.RouteDeprecation
+	loadedAt time.Time
+}
+
+func NewDeprecationService(repo *repositories.DeprecationRepository) *DeprecationService {
+	return &DeprecationService{
+		repo:     repo,
+		registry: make(map[string]string),
+		active:   make(map[string]*models.RouteDeprecation),
+	}
+}
+
+// Register adds a route, as registered with the router, that admins may
+// deprecate in favour of successor
+func (s *DeprecationService) Register(method, path, successor string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry[routeKey(method, path)] = successor
+}
+
+// List returns every registered route with its notice, ordered by path and
+// method
+func (s *DeprecationService) List() ([]models.RouteDeprecation, error) {
+	stored, err := s.stored()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	deprecations := make([]models.RouteDeprecation, 0, len(s.registry))
+	for key, successor := range s.registry {
+		deprecation, ok := stored[key]
+		if !ok {
+			method, path, _ := strings.Cut(key, " ")
+			deprecation = models.RouteDeprecation{Method: method, Path: path}
+		}
+		deprecation.Successor = successor
+		deprecations = append(deprecations, deprecation)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(deprecations, func(i, j int) bool {
+		if deprecations[i].Path != deprecations[j].Path {
+			return deprecations[i].Path < deprecations[j].Path
+		}
+		return deprecations[i].Method < deprecations[j].Method
+	})
+	return deprecations, nil
+}
+
+// Update changes the notice of the registered routes the update names and
+// returns them. A route keeps the date it was first deprecated when its
+// notice is turned off and on again.
+func (s *DeprecationService) Update(adminID primitive.ObjectID, update DeprecationUpdate) ([]models.RouteDeprecation, error) {
+	if update.SunsetAt != nil && update.SunsetAt.Before(time.Now()) && update.Enabled {
+		return nil, fmt.Errorf("%w: sunset_at must be in the future", ErrInvalidDeprecation)
+	}
+
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []models.RouteDeprecation
+	for _, deprecation := range all {
+		if deprecation.Path != update.Path || (update.Method != "" && deprecation.Method != update.Method) {
+			continue
+		}
+
+		deprecation.Enabled = update.Enabled
+		deprecation.SunsetAt = update.SunsetAt
+		deprecation.Message = update.Message
+		deprecation.UpdatedBy = &adminID
+		if update.Enabled && deprecation.DeprecatedAt == nil {
+			now := time.Now().UTC()
+			deprecation.DeprecatedAt = &now
+		}
+		if deprecation.SunsetAt != nil && deprecation.DeprecatedAt != nil && !deprecation.SunsetAt.After(*deprecation.DeprecatedAt) {
+			return nil, fmt.Errorf("%w: sunset_at must be after the route was deprecated (%s)", ErrInvalidDeprecation, deprecation.DeprecatedAt.Format(time.RFC3339))
+		}
+		updated = append(updated, deprecation)
+	}
+	if len(updated) == 0 {
+		return nil, ErrRouteNotRegistered
+	}
+
+	for i := range updated {
+		if err := s.repo.Upsert(&updated[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the change on this instance right away
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+	s.reload()
+	return updated, nil
+}
+
+// Lookup returns the enabled notice of a route, or nil
+func (s *DeprecationService) Lookup(method, path string) *models.RouteDeprecation {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) >= deprecationReloadInterval
+	s.mu.RUnlock()
+	if stale {
+		s.reload()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active[routeKey(method, path)]
+}
+
+// reload replaces the enabled notices with the stored ones. On failure the
+// notices already loaded are kept until the next attempt.
+func (s *DeprecationService) reload() {
+	s.mu.Lock()
+	if time.Since(s.loadedAt) < deprecationReloadInterval {
+		s.mu.Unlock()
+		return
+	}
+	// Requests arriving meanwhile use the current notices
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	stored, err := s.stored()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := make(map[string]*models.RouteDeprecation)
+	for key, deprecation := range stored {
+		successor, registered := s.registry[key]
+		if !registered || !deprecation.Enabled {
+			continue
+		}
+		deprecation := deprecation
+		deprecation.Successor = successor
+		active[key] = &deprecation
+	}
+	s.active = active
+}
+
+func (s *DeprecationService) stored() (map[string]models.RouteDeprecation, error) {
+	deprecations, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]models.RouteDeprecation, len(deprecations))
+	for _, deprecation := range deprecations {
+		stored[routeKey(deprecation.Method, deprecation.Path)] = deprecation
+	}
+	return stored, nil
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	devicePairingTTL = 10 * time.Minute
+	// DevicePairingInterval is how long a device waits between polls
+	DevicePairingInterval = 5 * time.Second
+)
+
+var (
+	// ErrPairingPending is returned while the pairing awaits approval
+	ErrPairingPending = errors.New("pairing is awaiting approval")
+	// ErrPairingSlowDown is returned when a device polls more often than
+	// DevicePairingInterval
+	ErrPairingSlowDown = errors.New("polling too often")
+	ErrPairingDenied   = errors.New("pairing was denied")
+	// ErrPairingNotFound is returned for unknown or expired codes
+	ErrPairingNotFound = errors.New("pairing not found or expired")
+)
+
+// DevicePairingStart is a new pairing as the device shows it
+type DevicePairingStart struct {
+	// DeviceCode is the device's secret for polling; only its hash is stored
+	DeviceCode string `json:"device_code"`
+	// UserCode is the code the user enters, e.g. ABCD-EFGH
+	UserCode string `json:"user_code"`
+	// PairingURL is where the user enters the code and PairingURLComplete
+	// the same page with the code filled in, for a QR code; both are empty
+	// without DEVICE_PAIRING_URL
+	PairingURL         string    `json:"pairing_url,omitempty"`
+	PairingURLComplete string    `json:"pairing_url_complete,omitempty"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	// Interval is the number of seconds to wait between polls
+	Interval int `json:"interval"`
+}
+
+// DevicePairingService signs in devices that are awkward to type a password
+// on, such as TV apps. The device starts a pairing and shows a short code,
+// the user approves the code from a device they are signed in on, and the
+// device's next poll returns a session.
+type DevicePairingService struct {
+	pairingRepo *repositories.DevicePairingRepository
+	pairingURL  string
+}
+
+func NewDevicePairingService(pairingRepo *repositories.DevicePairingRepository, pairingURL string) *DevicePairingService {
+	return &DevicePairingService{
+		pairingRepo: pairingRepo,
+		pairingURL:  pairingURL,
+	}
+}
+
+// Start creates a pairing for a device
+func (s *DevicePairingService) Start(deviceName, userAgent, ip string) (*DevicePairingStart, error) {
+	deviceCode, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := newLinkCode()
+	if err != nil {
+		return nil, err
+	}
+
+	pairing := &models.DevicePairing{
+		DeviceCodeHash: hashSecretToken(deviceCode),
+		UserCodeHash:   hashSecretToken(userCode),
+		DeviceName:     deviceName,
+		UserAgent:      userAgent,
+		IP:             ip,
+		Status:         models.DevicePairingPending,
+		ExpiresAt:      time.Now().UTC().Add(devicePairingTTL),
+	}
+	if err := s.pairingRepo.Create(pairing); err != nil {
+		return nil, err
+	}
+
+	start := &DevicePairingStart{
+		DeviceCode: deviceCode,
+		UserCode:   userCode[:linkCodeLength/2] + "-" + userCode[linkCodeLength/2:],
+		ExpiresAt:  pairing.ExpiresAt,
+		Interval:   int(DevicePairingInterval.Seconds()),
+	}
+	if s.pairingURL != "" {
+		start.PairingURL = s.pairingURL
+		start.PairingURLComplete = s.pairingURL + "?code=" + url.QueryEscape(start.UserCode)
+	}
+	return start, nil
+}
+
+// Poll returns the user a device was approved for, once. Until then it
+// returns ErrPairingPending, or ErrPairingSlowDown when the device polls too
+// often.
+func (s *DevicePairingService) Poll(deviceCode string) (primitive.ObjectID, error) {
+	now := time.Now().UTC()
+	deviceCodeHash := hashSecretToken(deviceCode)
+	pairing, err := s.pairingRepo.Poll(deviceCodeHash, now)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if pairing == nil {
+		return primitive.NilObjectID, ErrPairingNotFound
+	}
+
+	switch pairing.Status {
+	case models.DevicePairingApproved:
+		// Only one of two concurrent polls gets the approved pairing
+		taken, err := s.pairingRepo.TakeApproved(deviceCodeHash)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if taken == nil || taken.UserID == nil {
+			return primitive.NilObjectID, ErrPairingNotFound
+		}
+		return *taken.UserID, nil
+	case models.DevicePairingDenied:
+		if err := s.pairingRepo.Delete(pairing.ID); err != nil {
+			return primitive.NilObjectID, err
+		}
+		return primitive.NilObjectID, ErrPairingDenied
+	}
+
+	if pairing.LastPolledAt != nil && now.Sub(*pairing.LastPolledAt) < DevicePairingInterval {
+		return primitive.NilObjectID, ErrPairingSlowDown
+	}
+	return primitive.NilObjectID, ErrPairingPending
+}
+
+// GetPending returns the device waiting for approval under a code, so the
+// user can check it is theirs before approving
+func (s *DevicePairingService) GetPending(userCode string) (*models.DevicePairing, error) {
+	pairing, err := s.pairingRepo.FindPending(hashSecretToken(normalizeUserCode(userCode)), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, ErrPairingNotFound
+	}
+	return pairing, nil
+}
+
+// Approve signs the device waiting under a code in as the user
+func (s *DevicePairingService) Approve(userID primitive.ObjectID, userCode string) error {
+	return s.decide(userID, userCode, models.DevicePairingApproved)
+}
+
+// Deny turns the device waiting under a code away
+func (s *DevicePairingService) Deny(userID primitive.ObjectID, userCode string) error {
+	return s.decide(userID, userCode, models.DevicePairingDenied)
+}
+
+func (s *DevicePairingService) decide(userID primitive.ObjectID, userCode, status string) error {
+	found, err := s.pairingRepo.Decide(hashSecretToken(normalizeUserCode(userCode)), userID, status, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPairingNotFound
+	}
+	return nil
+}
+
+// normalizeUserCode accepts a code as typed, in any case and with or
+// without the separator
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discoveryLLMTimeout bounds one request to the query-parsing LLM
+const discoveryLLMTimeout = 15 * time.Second
+
+// DiscoveryFilters is the structured form of a natural-language discovery
+// query. Runtimes are in minutes; zero values leave a criterion out.
+type DiscoveryFilters struct {
+	Genres     []string `json:"genres"`
+	Keywords   []string `json:"keywords"`
+	MinRuntime int      `json:"min_runtime,omitempty"`
+	MaxRuntime int      `json:"max_runtime,omitempty"`
+	YearFrom   int      `json:"year_from,omitempty"`
+	YearTo     int      `json:"year_to,omitempty"`
+}
+
+// IsEmpty reports whether no criterion was recognised
+func (f *DiscoveryFilters) IsEmpty() bool {
+	return len(f.Genres) == 0 && len(f.Keywords) == 0 && f.MinRuntime == 0 && f.MaxRuntime == 0 && f.YearFrom == 0 && f.YearTo == 0
+}
+
+// QueryParser turns a query such as "feel-good heist movies under 2 hours"
+// into structured filters
+type QueryParser interface {
+	Parse(ctx context.Context, query string) (*DiscoveryFilters, error)
+}
+
+// genreWords maps query words to OMDb genres
+var genreWords = map[string]string{
+	"action":        "Action",
+	"adventure":     "Adventure",
+	"adventures":    "Adventure",
+	"animated":      "Animation",
+	"animation":     "Animation",
+	"cartoon":       "Animation",
+	"cartoons":      "Animation",
+	"biopic":        "Biography",
+	"biopics":       "Biography",
+	"biography":     "Biography",
+	"comedy":        "Comedy",
+	"comedies":      "Comedy",
+	"funny":         "Comedy",
+	"hilarious":     "Comedy",
+	"feel-good":     "Comedy",
+	"feelgood":      "Comedy",
+	"lighthearted":  "Comedy",
+	"crime":         "Crime",
+	"gangster":      "Crime",
+	"mafia":         "Crime",
+	"heist":         "Crime",
+	"documentary":   "Documentary",
+	"documentaries": "Documentary",
+	"drama":         "Drama",
+	"dramas":        "Drama",
+	"family":        "Family",
+	"kids":          "Family",
+	"fantasy":       "Fantasy",
+	"historical":    "History",
+	"history":       "History",
+	"horror":        "Horror",
+	"scary":         "Horror",
+	"musical":       "Musical",
+	"musicals":      "Musical",
+	"mystery":       "Mystery",
+	"mysteries":     "Mystery",
+	"whodunit":      "Mystery",
+	"romance":       "Romance",
+	"romantic":      "Romance",
+	"romcom":        "Romance",
+	"sci-fi":        "Sci-Fi",
+	"scifi":         "Sci-Fi",
+	"sport":         "Sport",
+	"sports":        "Sport",
+	"thriller":      "Thriller",
+	"thrillers":     "Thriller",
+	"suspense":      "Thriller",
+	"war":           "War",
+	"western":       "Western",
+	"westerns":      "Western",
+}
+
+// keywordSpellings lists words that are also searched for in plots, with the
+// spellings a plot is likely to use instead
+var keywordSpellings = map[string][]string{
+	"heist":  {"heist", "robbery", "robbers", "thief", "thieves", "steal"},
+	"zombie": {"zombie", "undead"},
+	"space":  {"space", "astronaut", "planet"},
+}
+
+// discoveryStopWords are dropped instead of becoming keywords
+var discoveryStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true, "with": true,
+	"about": true, "for": true, "to": true, "in": true, "on": true, "from": true, "that": true,
+	"movie": true, "movies": true, "film": true, "films": true, "flick": true, "flicks": true,
+	"show": true, "shows": true, "something": true, "some": true, "me": true, "i": true,
+	"want": true, "watch": true, "like": true, "good": true, "great": true, "best": true,
+	"set": true, "era": true, "made": true, "under": true, "over": true, "than": true,
+}
+
+var (
+	runtimePattern   = regexp.MustCompile(`\b(under|less than|shorter than|below|at most|over|more than|longer than|at least|above)\s+(\d+(?:\.\d+)?|an?|one|two|three)\s*(hours?|hrs?|h|minutes?|mins?|m)\b`)
+	decadePattern    = regexp.MustCompile(`\b(?:(\d{2})(\d)0|'?(\d)0)'?s\b`)
+	yearBoundPattern = regexp.MustCompile(`\b(before|pre|after|since|post)[\s-]+(\d{4})\b`)
+	yearPattern      = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+)
+
+// RuleQueryParser parses discovery queries with a fixed vocabulary of genre
+// words, runtime phrases ("under 2 hours") and eras ("90s", "before 1980").
+// Words it does not recognise become plot keywords.
+type RuleQueryParser struct{}
+
+func NewRuleQueryParser() *RuleQueryParser {
+	return &RuleQueryParser{}
+}
+
+func (p *RuleQueryParser) Parse(ctx context.Context, query string) (*DiscoveryFilters, error) {
+	text := strings.ToLower(strings.TrimSpace(query))
+	text = strings.ReplaceAll(text, "science fiction", "sci-fi")
+	filters := &DiscoveryFilters{Genres: []string{}, Keywords: []string{}}
+
+	for _, match := range runtimePattern.FindAllStringSubmatch(text, -1) {
+		minutes := parseDuration(match[2], match[3])
+		if minutes <= 0 {
+			continue
+		}
+		switch match[1] {
+		case "over", "more than", "longer than", "at least", "above":
+			filters.MinRuntime = minutes
+		default:
+			filters.MaxRuntime = minutes
+		}
+	}
+	text = runtimePattern.ReplaceAllString(text, " ")
+
+	for _, match := range yearBoundPattern.FindAllStringSubmatch(text, -1) {
+		year, _ := strconv.Atoi(match[2])
+		switch match[1] {
+		case "before", "pre":
+			filters.YearTo = year - 1
+		default:
+			filters.YearFrom = year
+		}
+	}
+	text = yearBoundPattern.ReplaceAllString(text, " ")
+
+	if match := decadePattern.FindStringSubmatch(text); match != nil {
+		var start int
+		if match[1] != "" {
+			start, _ = strconv.Atoi(match[1] + match[2] + "0")
+		} else {
+			// Two-digit decades: "20s" is the 1920s, "00s" and "10s" this century
+			digit, _ := strconv.Atoi(match[3])
+			start = 1900 + digit*10
+			if digit < 2 {
+				start = 2000 + digit*10
+			}
+		}
+		filters.YearFrom, filters.YearTo = start, start+9
+	}
+	text = decadePattern.ReplaceAllString(text, " ")
+
+	if match := yearPattern.FindString(text); match != "" && filters.YearFrom == 0 && filters.YearTo == 0 {
+		year, _ := strconv.Atoi(match)
+		filters.YearFrom, filters.YearTo = year, year
+	}
+	text = yearPattern.ReplaceAllString(text, " ")
+
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-')
+	}) {
+		word = strings.Trim(word, "-")
+		switch {
+		case word == "" || discoveryStopWords[word]:
+		case word == "recent" || word == "new" || word == "newer":
+			if filters.YearFrom == 0 {
+				filters.YearFrom = time.Now().UTC().Year() - 5
+			}
+		case word == "classic" || word == "classics" || word == "old":
+			if filters.YearTo == 0 {
+				filters.YearTo = 1979
+			}
+		case word == "short":
+			if filters.MaxRuntime == 0 {
+				filters.MaxRuntime = 95
+			}
+		default:
+			if genre, ok := genreWords[word]; ok {
+				filters.Genres = appendUnique(filters.Genres, genre)
+			}
+			if _, ok := keywordSpellings[word]; ok {
+				filters.Keywords = appendUnique(filters.Keywords, word)
+			} else if _, ok := genreWords[word]; !ok && len(word) > 2 {
+				// Keywords match word prefixes, so "loops" is searched as "loop"
+				if len(word) > 4 {
+					word = strings.TrimSuffix(word, "s")
+				}
+				filters.Keywords = appendUnique(filters.Keywords, word)
+			}
+		}
+	}
+
+	return filters, nil
+}
+
+// parseDuration converts an amount and unit from a runtime phrase to minutes
+func parseDuration(amount, unit string) int {
+	var value float64
+	switch amount {
+	case "a", "an", "one":
+		value = 1
+	case "two":
+		value = 2
+	case "three":
+		value = 3
+	default:
+		value, _ = strconv.ParseFloat(amount, 64)
+	}
+	if strings.HasPrefix(unit, "h") {
+		value *= 60
+	}
+	return int(value)
+}
+
+// appendUnique appends value unless it is already present
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// LLMQueryParser asks an OpenAI-compatible chat completions endpoint to
+// extract the filters, falling back to another parser when the call fails
+type LLMQueryParser struct {
+	baseURL  string
+	apiKey   string
+	model    string
+	client   *http.Client
+	fallback QueryParser
+	logger   *slog.Logger
+}
+
+func NewLLMQueryParser(baseURL, apiKey, model string, fallback QueryParser) *LLMQueryParser {
+	return &LLMQueryParser{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		client:   &http.Client{Timeout: discoveryLLMTimeout},
+		fallback: fallback,
+		logger:   logging.For("services.discovery"),
+	}
+}
+
+const discoveryLLMPrompt = `Extract movie search filters from the user's request. Reply with only a JSON object with these fields:
+"genres": OMDb genre names such as "Comedy", "Crime", "Sci-Fi";
+"keywords": single words expected in the plot;
+"min_runtime", "max_runtime": minutes, 0 if not mentioned;
+"year_from", "year_to": release years, 0 if not mentioned.`
+
+func (p *LLMQueryParser) Parse(ctx context.Context, query string) (*DiscoveryFilters, error) {
+	filters, err := p.complete(ctx, query)
+	if err != nil {
+		p.logger.Warn("llm query parsing failed, using rules", "error", err)
+		return p.fallback.Parse(ctx, query)
+	}
+	return filters, nil
+}
+
+func (p *LLMQueryParser) complete(ctx context.Context, query string) (*DiscoveryFilters, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": discoveryLLMPrompt},
+			{"role": "user", "content": query},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chat API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode chat response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("chat API returned no choices")
+	}
+
+	var filters DiscoveryFilters
+	if err := json.Unmarshal([]byte(result.Choices[0].Message.Content), &filters); err != nil {
+		return nil, fmt.Errorf("failed to decode filters: %w", err)
+	}
+	if filters.Genres == nil {
+		filters.Genres = []string{}
+	}
+	if filters.Keywords == nil {
+		filters.Keywords = []string{}
+	}
+	return &filters, nil
+}
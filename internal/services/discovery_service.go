@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxDiscoveryQueryLength bounds the text handed to the parser
+	maxDiscoveryQueryLength = 200
+	// MaxDiscoveryResults caps the candidates read from the catalogue; the
+	// runtime filter is applied to these in-process
+	MaxDiscoveryResults = 200
+)
+
+// ErrInvalidDiscoveryQuery is returned for empty, overlong or unrecognised queries
+var ErrInvalidDiscoveryQuery = errors.New("invalid discovery query")
+
+// DiscoveryService answers natural-language discovery queries from the local
+// catalogue, leaving out movies the user has already rated or watchlisted
+type DiscoveryService struct {
+	parser    QueryParser
+	movieRepo *repositories.MovieRepository
+}
+
+func NewDiscoveryService(parser QueryParser, movieRepo *repositories.MovieRepository) *DiscoveryService {
+	return &DiscoveryService{
+		parser:    parser,
+		movieRepo: movieRepo,
+	}
+}
+
+// Discover parses the query into filters and returns the matching movies,
+// highest IMDb rating first, along with the filters it understood
+func (s *DiscoveryService) Discover(ctx context.Context, userID primitive.ObjectID, query, maxCertification string) ([]models.Movie, *DiscoveryFilters, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil, fmt.Errorf("%w: query is required", ErrInvalidDiscoveryQuery)
+	}
+	if len(query) > maxDiscoveryQueryLength {
+		return nil, nil, fmt.Errorf("%w: query must be at most %d characters", ErrInvalidDiscoveryQuery, maxDiscoveryQueryLength)
+	}
+
+	filters, err := s.parser.Parse(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filters.MinRuntime < 0 || filters.MaxRuntime < 0 || filters.YearFrom < 0 || filters.YearTo < 0 {
+		return nil, nil, fmt.Errorf("%w: parsed filters are out of range", ErrInvalidDiscoveryQuery)
+	}
+	if filters.IsEmpty() {
+		return nil, nil, fmt.Errorf("%w: no genres, keywords, runtime or era recognised", ErrInvalidDiscoveryQuery)
+	}
+
+	filter := repositories.DiscoverFilter{
+		Genres:   filters.Genres,
+		YearFrom: filters.YearFrom,
+		YearTo:   filters.YearTo,
+		UnseenBy: userID,
+	}
+	for _, keyword := range filters.Keywords {
+		spellings, ok := keywordSpellings[strings.ToLower(keyword)]
+		if !ok {
+			spellings = []string{keyword}
+		}
+		filter.Keywords = append(filter.Keywords, spellings)
+	}
+	if maxCertification != "" {
+		filter.Rated = CertificationsUpTo(maxCertification)
+	}
+
+	candidates, err := s.movieRepo.Discover(filter, MaxDiscoveryResults)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filters.MinRuntime == 0 && filters.MaxRuntime == 0 {
+		return candidates, filters, nil
+	}
+
+	// Runtime is stored as OMDb text ("148 min"), so it is compared here;
+	// movies with an unknown runtime never match a runtime filter
+	movies := make([]models.Movie, 0, len(candidates))
+	for _, movie := range candidates {
+		minutes := movie.RuntimeMinutes()
+		if minutes == 0 {
+			continue
+		}
+		if filters.MinRuntime > 0 && minutes < filters.MinRuntime {
+			continue
+		}
+		if filters.MaxRuntime > 0 && minutes > filters.MaxRuntime {
+			continue
+		}
+		movies = append(movies, movie)
+	}
+	return movies, filters, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/mailer"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// emailChangeTTL is how long a confirmation link for a new email address stays valid
+const emailChangeTTL = 24 * time.Hour
+
+type EmailChangeService struct {
+	userRepo        *repositories.UserRepository
+	emailChangeRepo *repositories.EmailChangeRepository
+	mailer          mailer.Mailer
+	publicBaseURL   string
+	logger          *slog.Logger
+}
+
+func NewEmailChangeService(userRepo *repositories.UserRepository, emailChangeRepo *repositories.EmailChangeRepository, mailer mailer.Mailer, publicBaseURL string) *EmailChangeService {
+	return &EmailChangeService{
+		userRepo:        userRepo,
+		emailChangeRepo: emailChangeRepo,
+		mailer:          mailer,
+		publicBaseURL:   publicBaseURL,
+		logger:          logging.For("services.accounts"),
+	}
+}
+
+// RequestChange verifies the current password and emails a confirmation link
+// to the new address. The account email is unchanged until the link is used.
+func (s *EmailChangeService) RequestChange(userID primitive.ObjectID, currentPassword, newEmail string) (*models.EmailChange, error) {
+	newEmail = strings.TrimSpace(newEmail)
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return nil, errors.New("invalid credentials")
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, errors.New("new email matches current email")
+	}
+
+	existing, err := s.userRepo.FindByEmail(newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("email already exists")
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	change := &models.EmailChange{
+		UserID:    userID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		TokenHash: hashSecretToken(token),
+		ExpiresAt: time.Now().UTC().Add(emailChangeTTL),
+	}
+	if err := s.emailChangeRepo.Replace(change); err != nil {
+		return nil, err
+	}
+
+	link := s.publicBaseURL + "/email/confirm?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nConfirm that you want to use this address for your Movie Watchlist account:\n\n%s\n\nThe link expires in 24 hours. If you didn't request this, ignore this email.\n", user.Username, link)
+	if err := s.mailer.Send(newEmail, "Confirm your new email address", body); err != nil {
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	return change, nil
+}
+
+// ConfirmChange switches the account to the new email address and notifies the old one
+func (s *EmailChangeService) ConfirmChange(token string) (*models.EmailChange, error) {
+	change, err := s.emailChangeRepo.Take(hashSecretToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, errors.New("invalid or expired confirmation link")
+	}
+
+	updated, err := s.userRepo.UpdateEmail(change.UserID, change.NewEmail)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("email already exists")
+	}
+
+	body := fmt.Sprintf("The email address of your Movie Watchlist account was changed to %s.\n\nIf you didn't make this change, contact support immediately.\n", change.NewEmail)
+	if err := s.mailer.Send(change.OldEmail, "Your email address was changed", body); err != nil {
+		// The change itself succeeded; don't report it as failed
+		s.logger.Warn("failed to notify old address of email change", "user_id", change.UserID.Hex(), "error", err)
+	}
+
+	return change, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// embeddingAPITimeout bounds one request to an embedding API
+const embeddingAPITimeout = 30 * time.Second
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// close the texts are in meaning. Vectors from different models are not
+// comparable, so stored vectors are tagged with Model.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// DefaultHashingDimensions is the vector size of the local hashing embedder
+const DefaultHashingDimensions = 256
+
+// HashingEmbedder is a local embedder that needs no model files or network:
+// words and word pairs are hashed into a fixed number of dimensions. It
+// captures shared vocabulary ("time loop", "heist") rather than synonyms, so
+// an API embedder gives better results when one is configured.
+type HashingEmbedder struct {
+	dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{dims: dims}
+}
+
+func (e *HashingEmbedder) Model() string {
+	return fmt.Sprintf("local-hash-%d", e.dims)
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	counts := make(map[string]int)
+	words := embeddingTokens(text)
+	for i, word := range words {
+		counts[word]++
+		if i > 0 {
+			counts[words[i-1]+" "+word]++
+		}
+	}
+
+	vector := make([]float32, e.dims)
+	for feature, count := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The top bit picks the sign so colliding features tend to cancel out
+		weight := float32(1 + math.Log(float64(count)))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(e.dims)] += weight
+	}
+	normalizeVector(vector)
+	return vector
+}
+
+// embeddingStopWords carry no meaning for similarity
+var embeddingStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "he": true,
+	"her": true, "his": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "she": true, "that": true, "the": true,
+	"their": true, "they": true, "this": true, "to": true, "was": true,
+	"who": true, "with": true, "about": true, "movie": true, "movies": true,
+	"film": true, "films": true,
+}
+
+// embeddingTokens lower-cases text, drops stop words and strips a plural "s"
+// so "loops" and "loop" share a feature
+func embeddingTokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if embeddingStopWords[field] {
+			continue
+		}
+		if len(field) > 3 && strings.HasSuffix(field, "s") && !strings.HasSuffix(field, "ss") {
+			field = strings.TrimSuffix(field, "s")
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// APIEmbedder calls an OpenAI-compatible embeddings endpoint
+// (POST {baseURL}/embeddings with a model and a list of inputs)
+type APIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewAPIEmbedder(baseURL, apiKey, model string) *APIEmbedder {
+	return &APIEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: embeddingAPITimeout},
+	}
+}
+
+func (e *APIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *APIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding API returned %d vectors for %d inputs", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding API returned unexpected index %d", item.Index)
+		}
+		normalizeVector(item.Embedding)
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// normalizeVector scales v to unit length in place, so cosine similarity is a dot product
+func normalizeVector(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// dotProduct is the cosine similarity of two unit vectors
+func dotProduct(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/fieldcrypt"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EncryptedField is a document field stored encrypted with fieldcrypt. Its
+// values are sealed with FieldAssociatedData as associated data.
+type EncryptedField struct {
+	Collection string
+	Field      string
+}
+
+// encryptedFields lists every encrypted field so key rotation can find them.
+// Features that store secrets add their fields here.
+var encryptedFields = []EncryptedField{
+	{Collection: "trakt_links", Field: "access_token"},
+	{Collection: "trakt_links", Field: "refresh_token"},
+	{Collection: "chat_webhooks", Field: "url"},
+}
+
+// FieldAssociatedData binds an encrypted value to its collection, field and
+// document
+func FieldAssociatedData(collection, field string, id primitive.ObjectID) string {
+	return collection + "." + field + ":" + id.Hex()
+}
+
+// EncryptionService seals sensitive fields before they are stored and
+// re-encrypts stored values after the current key changes
+type EncryptionService struct {
+	cipher    *fieldcrypt.Cipher
+	fieldRepo *repositories.EncryptedFieldRepository
+	jobQueue  *jobs.Queue
+	logger    *slog.Logger
+}
+
+func NewEncryptionService(cipher *fieldcrypt.Cipher, fieldRepo *repositories.EncryptedFieldRepository, jobQueue *jobs.Queue) *EncryptionService {
+	return &EncryptionService{
+		cipher:    cipher,
+		fieldRepo: fieldRepo,
+		jobQueue:  jobQueue,
+		logger:    logging.For("services.encryption"),
+	}
+}
+
+// Encrypt seals the value of field in the document of the collection
+func (s *EncryptionService) Encrypt(collection, field string, id primitive.ObjectID, plaintext string) (string, error) {
+	return s.cipher.Encrypt(plaintext, FieldAssociatedData(collection, field, id))
+}
+
+// Decrypt opens a value sealed by Encrypt for the same document and field
+func (s *EncryptionService) Decrypt(collection, field string, id primitive.ObjectID, value string) (string, error) {
+	return s.cipher.Decrypt(value, FieldAssociatedData(collection, field, id))
+}
+
+// RequestRotation queues a re-encryption run; does nothing if one is pending
+func (s *EncryptionService) RequestRotation() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeRotateEncryptionKeys, nil, time.Now().UTC())
+}
+
+// RotateJob re-encrypts every stored value that was sealed with an older key
+// under the current key. Plain values written before a field was encrypted
+// are encrypted on the way.
+func (s *EncryptionService) RotateJob(ctx context.Context, payload map[string]interface{}) error {
+	total := 0
+	for _, f := range encryptedFields {
+		rewritten, err := s.fieldRepo.RewriteField(f.Collection, f.Field, func(id primitive.ObjectID, value string) (string, bool, error) {
+			associatedData := FieldAssociatedData(f.Collection, f.Field, id)
+			if !fieldcrypt.IsEncrypted(value) {
+				sealed, err := s.cipher.Encrypt(value, associatedData)
+				return sealed, err == nil, err
+			}
+			stale, err := s.cipher.NeedsRotation(value)
+			if err != nil || !stale {
+				return "", false, err
+			}
+			rotated, err := s.cipher.Rotate(value, associatedData)
+			return rotated, err == nil, err
+		})
+		total += rewritten
+		if err != nil {
+			return fmt.Errorf("failed to rotate %s.%s: %w", f.Collection, f.Field, err)
+		}
+	}
+	s.logger.Info("encrypted fields rotated", "fields", len(encryptedFields), "rewritten", total)
+	return nil
+}
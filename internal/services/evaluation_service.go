@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// evaluationHoldout is the share of each user's latest ratings held out
+	evaluationHoldout = 0.2
+	// evaluationMinRatings skips users with too few ratings to split
+	evaluationMinRatings = 5
+	// evaluationRelevantRating is the rating from which a held-out movie
+	// counts as one the recommender should have suggested
+	evaluationRelevantRating = 4
+)
+
+// EvaluationCutoffs are the list lengths k that precision and recall are reported for
+var EvaluationCutoffs = []int{5, 10, 20}
+
+// EvaluationRetryPolicy gives the evaluation job, which reads every rating,
+// more time than the default and fewer retries
+var EvaluationRetryPolicy = jobs.RetryPolicy{
+	MaxAttempts: 2,
+	BaseDelay:   5 * time.Minute,
+	MaxDelay:    5 * time.Minute,
+	Timeout:     15 * time.Minute,
+}
+
+// offlineRecommender suggests up to k movies for a user from their training
+// ratings alone, so that it can be replayed against held-out ratings
+type offlineRecommender func(catalog *evaluationCatalog, training map[primitive.ObjectID]int, k int) []primitive.ObjectID
+
+// offlineRecommenders are the algorithms compared by an evaluation run:
+// "genre" mirrors GetRecommendations (preferred genres, then top rated),
+// "top_rated" is its fallback alone and "popular" ranks by rating count
+var offlineRecommenders = []struct {
+	name      string
+	recommend offlineRecommender
+}{
+	{"genre", recommendByGenre},
+	{"top_rated", recommendTopRated},
+	{"popular", recommendPopular},
+}
+
+// evaluationCatalog is the movie data the offline recommenders work from
+type evaluationCatalog struct {
+	// topRated lists movie IDs by IMDb score, best first
+	topRated []primitive.ObjectID
+	// popular lists movie IDs by number of training ratings, most first
+	popular []primitive.ObjectID
+	// genres holds each movie's lower-cased genre string
+	genres map[primitive.ObjectID]string
+}
+
+// evaluationSplit is one user's ratings split by time
+type evaluationSplit struct {
+	training map[primitive.ObjectID]int
+	// relevant are the held-out movies the user rated highly
+	relevant map[primitive.ObjectID]bool
+}
+
+type EvaluationService struct {
+	ratingRepo     *repositories.RatingRepository
+	movieRepo      *repositories.MovieRepository
+	evaluationRepo *repositories.EvaluationRepository
+	jobQueue       *jobs.Queue
+	logger         *slog.Logger
+}
+
+func NewEvaluationService(ratingRepo *repositories.RatingRepository, movieRepo *repositories.MovieRepository, evaluationRepo *repositories.EvaluationRepository, jobQueue *jobs.Queue) *EvaluationService {
+	return &EvaluationService{
+		ratingRepo:     ratingRepo,
+		movieRepo:      movieRepo,
+		evaluationRepo: evaluationRepo,
+		jobQueue:       jobQueue,
+		logger:         logging.For("services.evaluation"),
+	}
+}
+
+// RequestEvaluation queues an evaluation run unless one is already pending
+func (s *EvaluationService) RequestEvaluation() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeEvaluateRecommender, nil, time.Now().UTC())
+}
+
+// GetRecentEvaluations returns the latest evaluation runs, newest first
+func (s *EvaluationService) GetRecentEvaluations(limit int) ([]models.RecommendationEvaluation, error) {
+	return s.evaluationRepo.FindRecent(int64(limit))
+}
+
+// EvaluationJob replays the rating history: the latest 20% of each user's
+// ratings are held out, every recommender suggests movies from the rest, and
+// precision@k and recall@k against the held-out movies rated 4+ stars are
+// averaged over users and stored.
+func (s *EvaluationService) EvaluationJob(ctx context.Context, payload map[string]interface{}) error {
+	started := time.Now().UTC()
+
+	ratings, err := s.ratingRepo.GetAllRatings()
+	if err != nil {
+		return err
+	}
+	movies, err := s.movieRepo.FindAll("genre", "imdb_score")
+	if err != nil {
+		return err
+	}
+
+	splits := splitRatingsByTime(ratings)
+	catalog := newEvaluationCatalog(movies, splits)
+
+	maxK := EvaluationCutoffs[len(EvaluationCutoffs)-1]
+	evaluation := &models.RecommendationEvaluation{
+		HoldoutFraction: evaluationHoldout,
+		Users:           len(splits),
+		StartedAt:       started,
+	}
+	for _, recommender := range offlineRecommenders {
+		precision := make([]float64, len(EvaluationCutoffs))
+		recall := make([]float64, len(EvaluationCutoffs))
+		for _, split := range splits {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			suggested := recommender.recommend(catalog, split.training, maxK)
+			for i, k := range EvaluationCutoffs {
+				hits := 0
+				for j := 0; j < k && j < len(suggested); j++ {
+					if split.relevant[suggested[j]] {
+						hits++
+					}
+				}
+				precision[i] += float64(hits) / float64(k)
+				recall[i] += float64(hits) / float64(len(split.relevant))
+			}
+		}
+
+		metrics := models.AlgorithmMetrics{Algorithm: recommender.name}
+		for i, k := range EvaluationCutoffs {
+			result := models.KMetrics{K: k}
+			if len(splits) > 0 {
+				result.Precision = roundMetric(precision[i] / float64(len(splits)))
+				result.Recall = roundMetric(recall[i] / float64(len(splits)))
+			}
+			metrics.AtK = append(metrics.AtK, result)
+		}
+		evaluation.Algorithms = append(evaluation.Algorithms, metrics)
+	}
+
+	evaluation.FinishedAt = time.Now().UTC()
+	if err := s.evaluationRepo.Create(evaluation); err != nil {
+		return err
+	}
+	s.logger.Info("recommendation evaluation finished", "users", evaluation.Users, "duration", evaluation.FinishedAt.Sub(started))
+	return nil
+}
+
+// splitRatingsByTime holds out the latest ratings of every user with enough
+// ratings, keeping only users who rated at least one held-out movie highly
+func splitRatingsByTime(ratings []models.Rating) []evaluationSplit {
+	byUser := make(map[primitive.ObjectID][]models.Rating)
+	for _, rating := range ratings {
+		byUser[rating.UserID] = append(byUser[rating.UserID], rating)
+	}
+
+	// Visit users in ID order so runs over the same data are identical
+	userIDs := make([]primitive.ObjectID, 0, len(byUser))
+	for userID := range byUser {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i].Hex() < userIDs[j].Hex() })
+
+	splits := []evaluationSplit{}
+	for _, userID := range userIDs {
+		userRatings := byUser[userID]
+		if len(userRatings) < evaluationMinRatings {
+			continue
+		}
+		sort.Slice(userRatings, func(i, j int) bool {
+			if !userRatings[i].CreatedAt.Equal(userRatings[j].CreatedAt) {
+				return userRatings[i].CreatedAt.Before(userRatings[j].CreatedAt)
+			}
+			return userRatings[i].ID.Hex() < userRatings[j].ID.Hex()
+		})
+
+		holdout := int(math.Ceil(float64(len(userRatings)) * evaluationHoldout))
+		cut := len(userRatings) - holdout
+		split := evaluationSplit{
+			training: make(map[primitive.ObjectID]int, cut),
+			relevant: make(map[primitive.ObjectID]bool),
+		}
+		for _, rating := range userRatings[:cut] {
+			split.training[rating.MovieID] = rating.Rating
+		}
+		for _, rating := range userRatings[cut:] {
+			if rating.Rating >= evaluationRelevantRating {
+				split.relevant[rating.MovieID] = true
+			}
+		}
+		if len(split.relevant) > 0 {
+			splits = append(splits, split)
+		}
+	}
+	return splits
+}
+
+// newEvaluationCatalog orders the movies for the offline recommenders.
+// Popularity only counts training ratings so held-out ratings cannot leak in.
+func newEvaluationCatalog(movies []models.Movie, splits []evaluationSplit) *evaluationCatalog {
+	catalog := &evaluationCatalog{genres: make(map[primitive.ObjectID]string, len(movies))}
+
+	scores := make(map[primitive.ObjectID]float64, len(movies))
+	for _, movie := range movies {
+		catalog.topRated = append(catalog.topRated, movie.ID)
+		catalog.genres[movie.ID] = strings.ToLower(movie.Genre)
+		scores[movie.ID] = movie.IMDbScore
+	}
+	sort.SliceStable(catalog.topRated, func(i, j int) bool {
+		return scores[catalog.topRated[i]] > scores[catalog.topRated[j]]
+	})
+
+	counts := make(map[primitive.ObjectID]int)
+	for _, split := range splits {
+		for movieID := range split.training {
+			counts[movieID]++
+		}
+	}
+	catalog.popular = append([]primitive.ObjectID{}, catalog.topRated...)
+	sort.SliceStable(catalog.popular, func(i, j int) bool {
+		return counts[catalog.popular[i]] > counts[catalog.popular[j]]
+	})
+
+	return catalog
+}
+
+func recommendTopRated(catalog *evaluationCatalog, training map[primitive.ObjectID]int, k int) []primitive.ObjectID {
+	return takeUnseen(catalog.topRated, training, nil, k)
+}
+
+func recommendPopular(catalog *evaluationCatalog, training map[primitive.ObjectID]int, k int) []primitive.ObjectID {
+	return takeUnseen(catalog.popular, training, nil, k)
+}
+
+// recommendByGenre follows GetRecommendations: the genres of movies rated 4+
+// stars, most frequent first, each filled with its best rated unseen movies,
+// topped up with the best rated movies overall
+func recommendByGenre(catalog *evaluationCatalog, training map[primitive.ObjectID]int, k int) []primitive.ObjectID {
+	genreCounts := make(map[string]int)
+	for movieID, rating := range training {
+		if rating < evaluationRelevantRating {
+			continue
+		}
+		for _, genre := range strings.Split(catalog.genres[movieID], ",") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				genreCounts[genre]++
+			}
+		}
+	}
+	genres := make([]string, 0, len(genreCounts))
+	for genre := range genreCounts {
+		genres = append(genres, genre)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if genreCounts[genres[i]] != genreCounts[genres[j]] {
+			return genreCounts[genres[i]] > genreCounts[genres[j]]
+		}
+		return genres[i] < genres[j]
+	})
+
+	suggested := []primitive.ObjectID{}
+	chosen := make(map[primitive.ObjectID]bool)
+	for _, genre := range genres {
+		if len(suggested) >= k {
+			break
+		}
+		unchosenInGenre := func(movieID primitive.ObjectID) bool {
+			return !chosen[movieID] && strings.Contains(catalog.genres[movieID], genre)
+		}
+		for _, movieID := range takeUnseen(catalog.topRated, training, unchosenInGenre, k-len(suggested)) {
+			chosen[movieID] = true
+			suggested = append(suggested, movieID)
+		}
+	}
+
+	unchosen := func(movieID primitive.ObjectID) bool { return !chosen[movieID] }
+	for _, movieID := range takeUnseen(catalog.topRated, training, unchosen, k-len(suggested)) {
+		suggested = append(suggested, movieID)
+	}
+	return suggested
+}
+
+// takeUnseen returns the first n movies of ordered the user has not rated
+// and, when keep is set, that it accepts
+func takeUnseen(ordered []primitive.ObjectID, training map[primitive.ObjectID]int, keep func(primitive.ObjectID) bool, n int) []primitive.ObjectID {
+	taken := []primitive.ObjectID{}
+	for _, movieID := range ordered {
+		if len(taken) >= n {
+			break
+		}
+		if _, rated := training[movieID]; rated {
+			continue
+		}
+		if keep != nil && !keep(movieID) {
+			continue
+		}
+		taken = append(taken, movieID)
+	}
+	return taken
+}
+
+// roundMetric keeps four decimals, enough to compare runs
+func roundMetric(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
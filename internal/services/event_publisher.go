@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamTimeout bounds one delivery to the event broker
+const streamTimeout = 10 * time.Second
+
+// StreamMessage is one domain event as handed to the broker
+type StreamMessage struct {
+	// ID is the outbox ID, repeated on redelivery so consumers can drop
+	// duplicates
+	ID      string
+	Topic   string
+	Key     string
+	Payload []byte
+}
+
+// EventPublisher delivers streamed events to an external broker. Publish
+// returns once the broker has accepted the message.
+type EventPublisher interface {
+	Publish(ctx context.Context, msg StreamMessage) error
+}
+
+// KafkaRESTPublisher produces to Kafka through a Confluent-compatible REST
+// Proxy (v2 API), keyed by user so each user's events stay in order on one
+// partition. Credentials in the URL are sent as basic auth.
+type KafkaRESTPublisher struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewKafkaRESTPublisher(rawURL string) (*KafkaRESTPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// url.Error repeats the URL, credentials included
+		return nil, fmt.Errorf("invalid event stream URL: %w", errors.Unwrap(err))
+	}
+	p := &KafkaRESTPublisher{client: &http.Client{Timeout: streamTimeout}}
+	if u.User != nil {
+		p.username = u.User.Username()
+		p.password, _ = u.User.Password()
+		u.User = nil
+	}
+	p.baseURL = strings.TrimRight(u.String(), "/")
+	return p, nil
+}
+
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, msg StreamMessage) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{
+			{"key": msg.Key, "value": json.RawMessage(msg.Payload)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/topics/"+url.PathEscape(msg.Topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var result struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode kafka rest proxy response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.Error != "" {
+			return fmt.Errorf("kafka rejected the record: %s", offset.Error)
+		}
+	}
+	return nil
+}
+
+// NATSPublisher publishes to a NATS server over its text protocol. Every
+// publish is followed by a PING, so it only succeeds once the server has
+// processed the message. When the server supports headers the message ID
+// goes in Nats-Msg-Id, which JetStream uses to drop duplicates. Credentials
+// in the URL are sent as user and password, or as a token when there is no
+// password.
+type NATSPublisher struct {
+	address string
+	user    string
+	pass    string
+	token   string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	headers bool
+}
+
+func NewNATSPublisher(rawURL string) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// url.Error repeats the URL, credentials included
+		return nil, fmt.Errorf("invalid event stream URL: %w", errors.Unwrap(err))
+	}
+	p := &NATSPublisher{address: u.Host}
+	if u.Port() == "" {
+		p.address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			p.user, p.pass = u.User.Username(), pass
+		} else {
+			p.token = u.User.Username()
+		}
+	}
+	return p, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, msg StreamMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reused := p.conn != nil
+	err := p.publish(ctx, msg)
+	// The server drops connections that sat idle through its pings, which
+	// only shows on the next use; retry those once on a fresh connection
+	if err != nil && reused {
+		p.close()
+		err = p.publish(ctx, msg)
+	}
+	if err != nil {
+		p.close()
+	}
+	return err
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, msg StreamMessage) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	p.conn.SetDeadline(streamDeadline(ctx))
+
+	var buf bytes.Buffer
+	if p.headers {
+		header := "NATS/1.0\r\nNats-Msg-Id: " + msg.ID + "\r\n\r\n"
+		fmt.Fprintf(&buf, "HPUB %s %d %d\r\n%s", msg.Topic, len(header), len(header)+len(msg.Payload), header)
+	} else {
+		fmt.Fprintf(&buf, "PUB %s %d\r\n", msg.Topic, len(msg.Payload))
+	}
+	buf.Write(msg.Payload)
+	buf.WriteString("\r\nPING\r\n")
+	if _, err := p.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return p.awaitPong()
+}
+
+// connect opens a connection, reads the server's INFO and authenticates
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: streamTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(streamDeadline(ctx))
+	p.conn, p.reader = conn, bufio.NewReader(conn)
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line[len("INFO "):])), &info); err != nil {
+		return fmt.Errorf("failed to decode NATS server info: %w", err)
+	}
+	p.headers = info.Headers
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "movie-watchlist",
+		"lang":     "go",
+		"version":  "1.0",
+		"protocol": 1,
+		"headers":  info.Headers,
+	}
+	if p.user != "" {
+		options["user"], options["pass"] = p.user, p.pass
+	}
+	if p.token != "" {
+		options["auth_token"] = p.token
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return err
+	}
+	return p.awaitPong()
+}
+
+// awaitPong reads until the server answers our PING, answering its own
+// PINGs on the way. Errors such as failed authentication arrive as -ERR.
+func (p *NATSPublisher) awaitPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.Trim(strings.TrimSpace(line[len("-ERR"):]), "'"))
+		}
+	}
+}
+
+func (p *NATSPublisher) close() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.reader = nil, nil
+	}
+}
+
+// streamDeadline is the context's deadline, capped at streamTimeout from now
+func streamDeadline(ctx context.Context) time.Time {
+	limit := time.Now().Add(streamTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(limit) {
+		return d
+	}
+	return limit
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// streamRelayInterval is how often the relay looks for events that were
+	// missed or are due for a retry
+	streamRelayInterval = 5 * time.Second
+	// streamRelayBatch caps the events sent in one relay pass
+	streamRelayBatch = 100
+	// streamRetryBase and streamRetryMax bound the delay before an event
+	// that failed to send is tried again; it doubles after every failure
+	streamRetryBase = 5 * time.Second
+	streamRetryMax  = 5 * time.Minute
+)
+
+// streamedEvents are the domain events forwarded to the broker
+var streamedEvents = []string{
+	events.NameUserRegistered,
+	events.NameMovieRated,
+	events.NameRatingUpdated,
+	events.NameWatchlistItemAdded,
+	events.NameWatchlistItemRemoved,
+	events.NameWatchlistItemUpdated,
+	events.NameRecommendationsRefreshed,
+}
+
+// streamEnvelope is the JSON body of a streamed event
+type streamEnvelope struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	RecordedAt time.Time    `json:"recorded_at"`
+	Data       events.Event `json:"data"`
+}
+
+// EventStreamService forwards domain events to Kafka or NATS through an
+// outbox: events are stored in MongoDB when they are published and a relay
+// sends them in order, retrying until the broker accepts them. Delivery is
+// at least once; consumers drop duplicates by the envelope ID.
+type EventStreamService struct {
+	outboxRepo *repositories.OutboxRepository
+	publisher  EventPublisher
+	prefix     string
+	wake       chan struct{}
+	logger     *slog.Logger
+}
+
+func NewEventStreamService(outboxRepo *repositories.OutboxRepository, publisher EventPublisher, prefix string) *EventStreamService {
+	return &EventStreamService{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		prefix:     prefix,
+		wake:       make(chan struct{}, 1),
+		logger:     logging.For("services.event_stream"),
+	}
+}
+
+// Subscribe records the streamed events in the outbox as they are published
+func (s *EventStreamService) Subscribe(bus *events.Bus) {
+	for _, name := range streamedEvents {
+		bus.Subscribe(name, "event_stream", s.record)
+	}
+}
+
+func (s *EventStreamService) record(event events.Event) error {
+	id := primitive.NewObjectID()
+	payload, err := json.Marshal(streamEnvelope{
+		ID:         id.Hex(),
+		Type:       event.Name(),
+		RecordedAt: time.Now().UTC(),
+		Data:       event,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.outboxRepo.Create(&models.OutboxEvent{
+		ID:      id,
+		Type:    event.Name(),
+		Key:     streamKey(event),
+		Payload: string(payload),
+	}); err != nil {
+		return err
+	}
+
+	// Send right away rather than on the next tick
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start runs the relay until ctx is cancelled
+func (s *EventStreamService) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *EventStreamService) run(ctx context.Context) {
+	ticker := time.NewTicker(streamRelayInterval)
+	defer ticker.Stop()
+
+	for {
+		s.relay(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// relay sends due events oldest first. It stops at the first failure, as the
+// broker is likely down, so later events do not overtake the failed one.
+func (s *EventStreamService) relay(ctx context.Context) {
+	for i := 0; i < streamRelayBatch && ctx.Err() == nil; i++ {
+		now := time.Now().UTC()
+		event, err := s.outboxRepo.ClaimNext(now, now.Add(2*streamTimeout))
+		if err != nil {
+			s.logger.Warn("failed to claim outbox event", "error", err)
+			return
+		}
+		if event == nil {
+			return
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, streamTimeout)
+		err = s.publisher.Publish(sendCtx, StreamMessage{
+			ID:      event.ID.Hex(),
+			Topic:   s.prefix + "." + event.Type,
+			Key:     event.Key,
+			Payload: []byte(event.Payload),
+		})
+		cancel()
+
+		if err != nil {
+			retryAt := time.Now().UTC().Add(streamRetryDelay(event.Attempts))
+			s.logger.Warn("failed to stream event", "event_id", event.ID.Hex(), "type", event.Type, "attempts", event.Attempts, "retry_at", retryAt, "error", err)
+			if err := s.outboxRepo.MarkFailed(event.ID, err.Error(), retryAt); err != nil {
+				s.logger.Warn("failed to record streaming failure", "event_id", event.ID.Hex(), "error", err)
+			}
+			return
+		}
+		if err := s.outboxRepo.MarkDelivered(event.ID, time.Now().UTC()); err != nil {
+			// The lock expires and the event is sent again, which consumers
+			// tolerate
+			s.logger.Warn("failed to mark event delivered", "event_id", event.ID.Hex(), "error", err)
+		}
+	}
+}
+
+// streamRetryDelay doubles from streamRetryBase with every failed attempt
+func streamRetryDelay(attempts int) time.Duration {
+	delay := streamRetryBase
+	for i := 1; i < attempts && delay < streamRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, streamRetryMax)
+}
+
+// streamKey partitions events by user, keeping each user's events in order
+func streamKey(event events.Event) string {
+	switch e := event.(type) {
+	case events.UserRegistered:
+		return e.UserID.Hex()
+	case events.MovieRated:
+		return e.UserID.Hex()
+	case events.WatchlistItemAdded:
+		return e.UserID.Hex()
+	case events.WatchlistItemRemoved:
+		return e.UserID.Hex()
+	case events.WatchlistItemUpdated:
+		return e.UserID.Hex()
+	case events.RecommendationsRefreshed:
+		return e.UserID.Hex()
+	}
+	return ""
+}
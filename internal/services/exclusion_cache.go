@@ -0,0 +1,151 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// exclusionCacheTTL bounds how long a cached exclusion set is trusted.
+	// Events keep it current for writes on this instance; the TTL catches
+	// writes that publish none, such as imports and undone removals, and
+	// writes on other instances.
+	exclusionCacheTTL = 10 * time.Minute
+	// exclusionCacheSize caps the number of users with a cached set
+	exclusionCacheSize = 10000
+	// exclusionLiteralLimit is the largest set passed to MongoDB as a list of
+	// IDs; users with more rated and watchlisted movies are anti-joined
+	exclusionLiteralLimit = 500
+)
+
+// exclusionSet is the movies a user rated or has on their watchlist. Sets
+// larger than exclusionLiteralLimit are not kept, only marked large.
+type exclusionSet struct {
+	ids      map[primitive.ObjectID]bool
+	large    bool
+	loadedAt time.Time
+}
+
+// exclusionCache keeps each user's exclusion set between recommendation
+// requests. Additions are applied to cached sets in place; removals drop the
+// set, since a movie leaving the watchlist stays excluded when it is also
+// rated and only the database knows.
+type exclusionCache struct {
+	mu      sync.Mutex
+	entries map[primitive.ObjectID]*exclusionSet
+	// A load that started before a change to its user is not stored, as it
+	// may have read the database before the write. changed records the
+	// sequence number of each user's last change, and changes before floor
+	// were forgotten to keep changed bounded.
+	seq     uint64
+	floor   uint64
+	changed map[primitive.ObjectID]uint64
+}
+
+func newExclusionCache() *exclusionCache {
+	return &exclusionCache{
+		entries: make(map[primitive.ObjectID]*exclusionSet),
+		changed: make(map[primitive.ObjectID]uint64),
+	}
+}
+
+// get returns a copy of the user's cached set, or nil and whether the set is
+// known to be large, along with the sequence number to pass to store after
+// loading it
+func (c *exclusionCache) get(userID primitive.ObjectID) ([]primitive.ObjectID, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Since(entry.loadedAt) >= exclusionCacheTTL {
+		delete(c.entries, userID)
+		return nil, false, c.seq
+	}
+	if entry.large {
+		return nil, true, c.seq
+	}
+	ids := make([]primitive.ObjectID, 0, len(entry.ids))
+	for id := range entry.ids {
+		ids = append(ids, id)
+	}
+	return ids, false, c.seq
+}
+
+// store caches a set loaded after get returned seq, unless the user changed
+// since. A set over exclusionLiteralLimit is only marked large.
+func (c *exclusionCache) store(userID primitive.ObjectID, ids []primitive.ObjectID, seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq < c.floor || c.changed[userID] > seq {
+		return
+	}
+	if len(c.entries) >= exclusionCacheSize {
+		c.evict()
+	}
+	if len(ids) > exclusionLiteralLimit {
+		c.entries[userID] = &exclusionSet{large: true, loadedAt: time.Now()}
+		return
+	}
+	set := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	c.entries[userID] = &exclusionSet{ids: set, loadedAt: time.Now()}
+}
+
+// add excludes movies from the user's cached set, if there is one
+func (c *exclusionCache) add(userID primitive.ObjectID, ids []primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.change(userID)
+	entry, ok := c.entries[userID]
+	if !ok || entry.large {
+		return
+	}
+	for _, id := range ids {
+		entry.ids[id] = true
+	}
+	if len(entry.ids) > exclusionLiteralLimit {
+		entry.ids, entry.large = nil, true
+	}
+}
+
+// invalidate drops the user's cached set so the next request reloads it
+func (c *exclusionCache) invalidate(userID primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.change(userID)
+	delete(c.entries, userID)
+}
+
+func (c *exclusionCache) change(userID primitive.ObjectID) {
+	c.seq++
+	if len(c.changed) >= exclusionCacheSize {
+		c.changed = make(map[primitive.ObjectID]uint64)
+		c.floor = c.seq
+	}
+	c.changed[userID] = c.seq
+}
+
+// evict drops expired sets, or the oldest one when none expired
+func (c *exclusionCache) evict() {
+	var oldest primitive.ObjectID
+	var oldestAt time.Time
+	for userID, entry := range c.entries {
+		if time.Since(entry.loadedAt) >= exclusionCacheTTL {
+			delete(c.entries, userID)
+			continue
+		}
+		if oldestAt.IsZero() || entry.loadedAt.Before(oldestAt) {
+			oldest, oldestAt = userID, entry.loadedAt
+		}
+	}
+	if len(c.entries) >= exclusionCacheSize {
+		delete(c.entries, oldest)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Export formats for ratings, watchlists and user listings
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+)
+
+// ErrInvalidExportFormat is returned for formats other than json and csv
+var ErrInvalidExportFormat = errors.New("format must be json or csv")
+
+// ExportService writes exports straight to a writer as they are read, so a
+// large export never sits in memory. Ratings exported as CSV can be imported
+// again through the ratings import, and archives through the archive import.
+type ExportService struct {
+	exportRepo *repositories.ExportRepository
+	userRepo   *repositories.UserRepository
+}
+
+func NewExportService(exportRepo *repositories.ExportRepository, userRepo *repositories.UserRepository) *ExportService {
+	return &ExportService{exportRepo: exportRepo, userRepo: userRepo}
+}
+
+// ValidExportFormat reports whether format is json or csv
+func ValidExportFormat(format string) bool {
+	return format == ExportFormatJSON || format == ExportFormatCSV
+}
+
+// WriteRatings writes the user's ratings as a JSON array or as a CSV of
+// imdb_id, rating, date and title
+func (s *ExportService) WriteRatings(userID primitive.ObjectID, format string, w io.Writer) error {
+	out, err := newExportWriter(w, format, []string{"imdb_id", "rating", "date", "title"})
+	if err != nil {
+		return err
+	}
+	err = s.exportRepo.EachRating(userID, func(rating repositories.RatingExport) error {
+		return out.write(rating, func() []string {
+			return []string{rating.IMDbID, strconv.Itoa(rating.Rating), rating.UpdatedAt.UTC().Format(time.RFC3339), csvText(rating.Title)}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// WriteWatchlist writes the user's watchlist as a JSON array or as a CSV of
+// imdb_id, added_at, watched_at, priority and title
+func (s *ExportService) WriteWatchlist(userID primitive.ObjectID, format string, w io.Writer) error {
+	out, err := newExportWriter(w, format, []string{"imdb_id", "added_at", "watched_at", "priority", "title"})
+	if err != nil {
+		return err
+	}
+	err = s.exportRepo.EachWatchlistEntry(userID, func(entry repositories.WatchlistExport) error {
+		return out.write(entry, func() []string {
+			return []string{entry.IMDbID, entry.AddedAt.UTC().Format(time.RFC3339), csvTime(entry.WatchedAt), strconv.Itoa(entry.Priority), csvText(entry.Title)}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// WriteUsers writes every user account with the fields of the admin user
+// listing, as a JSON array or a CSV
+func (s *ExportService) WriteUsers(format string, w io.Writer) error {
+	out, err := newExportWriter(w, format, []string{"id", "username", "email", "last_active_at", "created_at"})
+	if err != nil {
+		return err
+	}
+	err = s.exportRepo.EachUser(func(user models.User) error {
+		listed := struct {
+			ID           primitive.ObjectID `json:"id"`
+			Username     string             `json:"username"`
+			Email        string             `json:"email"`
+			LastActiveAt *time.Time         `json:"last_active_at"`
+			CreatedAt    time.Time          `json:"created_at"`
+		}{user.ID, user.Username, user.Email, user.LastActiveAt, user.CreatedAt}
+		return out.write(listed, func() []string {
+			return []string{user.ID.Hex(), csvText(user.Username), csvText(user.Email), csvTime(user.LastActiveAt), user.CreatedAt.UTC().Format(time.RFC3339)}
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// WriteArchive writes an account archive (see ArchiveFormat) of the user's
+// watchlist, ratings, reviews, helpful votes and preferences as a ZIP. Entries are compressed as they
+// are written, so only the ZIP's central directory grows with the archive.
+func (s *ExportService) WriteArchive(userID primitive.ObjectID, w io.Writer) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	archive := zip.NewWriter(w)
+	if err := writeArchiveJSON(archive, "manifest.json", archiveManifest{Format: ArchiveFormat, Version: ArchiveVersion, ExportedAt: time.Now().UTC()}); err != nil {
+		return err
+	}
+
+	file, err := archive.Create("watchlist.json")
+	if err != nil {
+		return err
+	}
+	out, _ := newExportWriter(file, ExportFormatJSON, nil)
+	err = s.exportRepo.EachWatchlistEntry(userID, func(entry repositories.WatchlistExport) error {
+		return out.write(archiveWatchlistEntry{IMDbID: entry.IMDbID, AddedAt: entry.AddedAt, WatchedAt: entry.WatchedAt, Priority: entry.Priority}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+
+	file, err = archive.Create("ratings.json")
+	if err != nil {
+		return err
+	}
+	out, _ = newExportWriter(file, ExportFormatJSON, nil)
+	err = s.exportRepo.EachRating(userID, func(rating repositories.RatingExport) error {
+		return out.write(archiveRating{IMDbID: rating.IMDbID, Rating: rating.Rating, RatedAt: rating.UpdatedAt}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+
+	file, err = archive.Create("reviews.json")
+	if err != nil {
+		return err
+	}
+	out, _ = newExportWriter(file, ExportFormatJSON, nil)
+	err = s.exportRepo.EachReview(userID, func(review repositories.ReviewExport) error {
+		return out.write(archiveReview{IMDbID: review.IMDbID, Body: review.Body, CreatedAt: review.CreatedAt, UpdatedAt: review.UpdatedAt}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+
+	file, err = archive.Create("review_votes.json")
+	if err != nil {
+		return err
+	}
+	out, _ = newExportWriter(file, ExportFormatJSON, nil)
+	err = s.exportRepo.EachReviewVote(userID, func(vote repositories.ReviewVoteExport) error {
+		return out.write(archiveReviewVote{IMDbID: vote.IMDbID, Username: vote.Username, VotedAt: vote.CreatedAt}, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+
+	preferences := archivePreferences{
+		AudioLanguages:         user.AudioLanguages,
+		SubtitleLanguages:      user.SubtitleLanguages,
+		RecommendationSettings: user.RecommendationSettings,
+	}
+	if err := writeArchiveJSON(archive, "preferences.json", preferences); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, value interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(file).Encode(value)
+}
+
+// exportWriter writes records one at a time as elements of a JSON array or
+// as CSV rows below a header
+type exportWriter struct {
+	w       io.Writer
+	json    *json.Encoder
+	csv     *csv.Writer
+	written bool
+}
+
+func newExportWriter(w io.Writer, format string, header []string) (*exportWriter, error) {
+	switch format {
+	case ExportFormatJSON:
+		return &exportWriter{w: w, json: json.NewEncoder(w)}, nil
+	case ExportFormatCSV:
+		out := &exportWriter{w: w, csv: csv.NewWriter(w)}
+		return out, out.csv.Write(header)
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+}
+
+// write adds value to a JSON array, or the row built by row to a CSV
+func (e *exportWriter) write(value interface{}, row func() []string) error {
+	if e.csv != nil {
+		return e.csv.Write(row())
+	}
+	separator := ","
+	if !e.written {
+		separator = "["
+	}
+	e.written = true
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	return e.json.Encode(value)
+}
+
+// close ends the JSON array, or flushes the CSV
+func (e *exportWriter) close() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	end := "]\n"
+	if !e.written {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// csvTime formats an optional time for a CSV cell, empty when unset
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvText guards a free-text cell against spreadsheets evaluating it as a
+// formula by quoting a leading =, +, - or @
+func csvText(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@") {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxPersonFollows caps how many people one user can follow
+	maxPersonFollows = 100
+	// followingRowYears is how far back the "following" row looks: movies
+	// from this year and the years before it
+	followingRowYears = 3
+)
+
+var (
+	ErrInvalidFollow    = errors.New("invalid follow")
+	ErrPersonNotFound   = errors.New("no cached movie credits this person")
+	ErrAlreadyFollowing = errors.New("already following this person")
+)
+
+// FollowService lets users follow directors and actors by the credits
+// stored on movies. Follows feed the "following" recommendation row and the
+// release calendar.
+type FollowService struct {
+	followRepo *repositories.PersonFollowRepository
+	movieRepo  *repositories.MovieRepository
+}
+
+func NewFollowService(followRepo *repositories.PersonFollowRepository, movieRepo *repositories.MovieRepository) *FollowService {
+	return &FollowService{followRepo: followRepo, movieRepo: movieRepo}
+}
+
+// Follow adds a director or actor to the user's follows. The name must be
+// credited on at least one cached movie and is stored spelled as there.
+func (s *FollowService) Follow(userID primitive.ObjectID, role, name string) (*models.PersonFollow, error) {
+	name = strings.TrimSpace(name)
+	switch role {
+	case models.PersonRoleDirector, models.PersonRoleActor:
+	default:
+		return nil, fmt.Errorf("%w: role must be director or actor", ErrInvalidFollow)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidFollow)
+	}
+
+	movie, err := s.movieRepo.FindByCredit(role, name)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, fmt.Errorf("%w: no movie lists %s as %s", ErrPersonNotFound, name, role)
+	}
+	credits := movie.Director
+	if role == models.PersonRoleActor {
+		credits = movie.Actors
+	}
+	for _, credited := range splitCredits(credits) {
+		if strings.EqualFold(credited, name) {
+			name = credited
+			break
+		}
+	}
+
+	follows, err := s.followRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(follows) >= maxPersonFollows {
+		return nil, fmt.Errorf("%w: at most %d people can be followed", ErrInvalidFollow, maxPersonFollows)
+	}
+
+	follow := &models.PersonFollow{UserID: userID, Name: name, Role: role}
+	created, err := s.followRepo.Create(follow)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyFollowing
+	}
+	return follow, nil
+}
+
+// Unfollow removes one of the user's follows, reporting whether it existed
+func (s *FollowService) Unfollow(userID, followID primitive.ObjectID) (bool, error) {
+	return s.followRepo.Delete(userID, followID)
+}
+
+// GetFollows returns the people the user follows, most recent first
+func (s *FollowService) GetFollows(userID primitive.ObjectID) ([]models.PersonFollow, error) {
+	return s.followRepo.FindByUser(userID)
+}
+
+// FollowedPeople returns the names of the directors and actors the user
+// follows
+func (s *FollowService) FollowedPeople(userID primitive.ObjectID) ([]string, []string, error) {
+	follows, err := s.followRepo.FindByUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	directors, actors := []string{}, []string{}
+	for _, follow := range follows {
+		if follow.Role == models.PersonRoleDirector {
+			directors = append(directors, follow.Name)
+		} else {
+			actors = append(actors, follow.Name)
+		}
+	}
+	return directors, actors, nil
+}
+
+// Followers returns the users who follow any of the directors or actors
+func (s *FollowService) Followers(directors, actors []string) ([]primitive.ObjectID, error) {
+	follows, err := s.followRepo.FindFollowers(directors, actors)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		userIDs = append(userIDs, follow.UserID)
+	}
+	return userIDs, nil
+}
+
+// GetNewFromFollowed returns up to limit recent movies from the people the
+// user follows that they have not rated or added to their watchlist, newest
+// first
+func (s *FollowService) GetNewFromFollowed(userID primitive.ObjectID, limit int) ([]models.Movie, error) {
+	directors, actors, err := s.FollowedPeople(userID)
+	if err != nil {
+		return nil, err
+	}
+	yearFrom := time.Now().UTC().Year() - followingRowYears + 1
+	movies, err := s.movieRepo.FindByPeople(directors, actors, yearFrom, userID, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	for i := range movies {
+		movies[i].RecommendedBy = algorithmFollowing
+	}
+	return movies, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"movie-watchlist/internal/models"
+)
+
+const (
+	// didYouMeanLimit caps the "did you mean" suggestions offered for a miss
+	didYouMeanLimit = 5
+	// didYouMeanThreshold is the minimum similarity for a title to be suggested
+	didYouMeanThreshold = 0.4
+	// titleIndexTTL is how long the in-memory title list is reused before it is
+	// reloaded from the cache
+	titleIndexTTL = 10 * time.Minute
+)
+
+// DidYouMean is a cached title suggested for a search that found nothing
+type DidYouMean struct {
+	IMDbID     string  `json:"imdb_id"`
+	Title      string  `json:"title"`
+	Year       string  `json:"year"`
+	Similarity float64 `json:"similarity"`
+}
+
+// titleIndex keeps a snapshot of cached titles for fuzzy matching so a miss
+// does not scan the movies collection on every request
+type titleIndex struct {
+	mu       sync.Mutex
+	titles   []indexedTitle
+	loadedAt time.Time
+}
+
+type indexedTitle struct {
+	movie      models.Movie
+	normalized string
+	trigrams   map[string]struct{}
+}
+
+// suggestSimilarTitles fuzzy-matches a query against cached titles using
+// trigram overlap and edit distance, returning the closest matches
+func (s *MovieService) suggestSimilarTitles(query string) ([]DidYouMean, error) {
+	titles, err := s.loadTitleIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedQuery := normalizeTitle(query)
+	if normalizedQuery == "" {
+		return []DidYouMean{}, nil
+	}
+	queryTrigrams := trigrams(normalizedQuery)
+
+	suggestions := make([]DidYouMean, 0)
+	for _, title := range titles {
+		similarity := trigramSimilarity(queryTrigrams, title.trigrams)
+		if editSimilarity := levenshteinSimilarity(normalizedQuery, title.normalized); editSimilarity > similarity {
+			similarity = editSimilarity
+		}
+		if similarity < didYouMeanThreshold {
+			continue
+		}
+		suggestions = append(suggestions, DidYouMean{
+			IMDbID:     title.movie.IMDbID,
+			Title:      title.movie.Title,
+			Year:       title.movie.Year,
+			Similarity: float64(int(similarity*1000)) / 1000,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Similarity > suggestions[j].Similarity
+	})
+	if len(suggestions) > didYouMeanLimit {
+		suggestions = suggestions[:didYouMeanLimit]
+	}
+	return suggestions, nil
+}
+
+// loadTitleIndex returns the cached title snapshot, reloading it when stale
+func (s *MovieService) loadTitleIndex() ([]indexedTitle, error) {
+	s.titles.mu.Lock()
+	defer s.titles.mu.Unlock()
+
+	if s.titles.titles != nil && time.Since(s.titles.loadedAt) < titleIndexTTL {
+		return s.titles.titles, nil
+	}
+
+	movies, err := s.movieRepo.FindTitles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cached titles: %w", err)
+	}
+
+	titles := make([]indexedTitle, 0, len(movies))
+	for _, movie := range movies {
+		normalized := normalizeTitle(movie.Title)
+		if normalized == "" {
+			continue
+		}
+		titles = append(titles, indexedTitle{
+			movie:      movie,
+			normalized: normalized,
+			trigrams:   trigrams(normalized),
+		})
+	}
+
+	s.titles.titles = titles
+	s.titles.loadedAt = time.Now()
+	return titles, nil
+}
+
+// normalizeTitle lowercases a title and reduces punctuation to single spaces
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space && b.Len() > 0 {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// trigrams returns the set of three-rune shingles of a padded string
+func trigrams(s string) map[string]struct{} {
+	runes := []rune("  " + s + " ")
+	set := make(map[string]struct{}, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+	return set
+}
+
+// trigramSimilarity is the Jaccard similarity of two trigram sets
+func trigramSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for gram := range a {
+		if _, ok := b[gram]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// levenshteinSimilarity scales the edit distance between two strings to 0..1
+func levenshteinSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
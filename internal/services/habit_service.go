@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxMonthlyGoal caps the monthly movie goal
+const MaxMonthlyGoal = 100
+
+// goalMonthFormat keys goal months, e.g. "2024-05"
+const goalMonthFormat = "2006-01"
+
+// ErrInvalidWatchGoal is returned for out-of-range goals
+var ErrInvalidWatchGoal = errors.New("invalid watch goal")
+
+// WatchStreak counts consecutive weeks (Monday to Sunday in the user's
+// timezone) with at least one movie marked watched. The current streak stays alive through a week
+// without a watched movie until that week ends.
+type WatchStreak struct {
+	CurrentWeeks  int        `json:"current_weeks"`
+	LongestWeeks  int        `json:"longest_weeks"`
+	LastWatchedAt *time.Time `json:"last_watched_at"`
+}
+
+// GoalProgress is how far the user got with this month's goal
+type GoalProgress struct {
+	Month     string `json:"month"`
+	Target    int    `json:"target"`
+	Watched   int    `json:"watched"`
+	Remaining int    `json:"remaining"`
+	Met       bool   `json:"met"`
+}
+
+// WatchHabits are a user's viewing statistics
+type WatchHabits struct {
+	WatchedTotal     int           `json:"watched_total"`
+	WatchedThisMonth int           `json:"watched_this_month"`
+	Streak           WatchStreak   `json:"streak"`
+	Goal             *GoalProgress `json:"goal"`
+	// Sources breaks the watchlist down by where movies were added from
+	Sources []SourceStats `json:"sources,omitempty"`
+}
+
+// HabitService tracks watch streaks and monthly goals from the watched times
+// of watchlist entries, and congratulates users who meet their goal
+type HabitService struct {
+	userRepo         *repositories.UserRepository
+	watchlistRepo    *repositories.WatchlistRepository
+	notificationRepo *repositories.NotificationRepository
+	logger           *slog.Logger
+}
+
+func NewHabitService(userRepo *repositories.UserRepository, watchlistRepo *repositories.WatchlistRepository, notificationRepo *repositories.NotificationRepository) *HabitService {
+	return &HabitService{
+		userRepo:         userRepo,
+		watchlistRepo:    watchlistRepo,
+		notificationRepo: notificationRepo,
+		logger:           logging.For("services.habits"),
+	}
+}
+
+// GetHabits returns the user's streaks, where their watchlist came from and,
+// when a goal is set, this month's progress. For a kids profile there is no
+// goal.
+func (s *HabitService) GetHabits(userID primitive.ObjectID) (*WatchHabits, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	watched, err := s.watchlistRepo.GetWatchedTimes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.watchlistRepo.CountBySource(&userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var goals *models.WatchGoals
+	if user != nil {
+		goals = user.WatchGoals
+	}
+	habits := computeHabits(watched, goals, time.Now().In(loc))
+	habits.Sources = sourceStats(counts)
+	return habits, nil
+}
+
+// UpdateGoals sets the monthly movie goal; 0 removes it
+func (s *HabitService) UpdateGoals(userID primitive.ObjectID, monthlyMovies int) (*models.WatchGoals, error) {
+	if monthlyMovies < 0 || monthlyMovies > MaxMonthlyGoal {
+		return nil, fmt.Errorf("%w: monthly_movies must be between 0 and %d", ErrInvalidWatchGoal, MaxMonthlyGoal)
+	}
+
+	var goals *models.WatchGoals
+	if monthlyMovies > 0 {
+		goals = &models.WatchGoals{MonthlyMovies: monthlyMovies}
+	}
+	found, err := s.userRepo.UpdateWatchGoals(userID, goals)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("user not found")
+	}
+	if goals == nil {
+		goals = &models.WatchGoals{}
+	}
+	return goals, nil
+}
+
+// CheckGoalJob runs after a movie is marked watched and sends a notification
+// the first time the user's monthly goal is met each month
+func (s *HabitService) CheckGoalJob(ctx context.Context, payload map[string]interface{}) error {
+	userHex, _ := payload["user_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid user_id %q", userHex)
+	}
+
+	// Kids profiles have no user document and so no goal; deactivated
+	// accounts are not notified
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.DeactivatedAt != nil || user.WatchGoals == nil || user.WatchGoals.MonthlyMovies == 0 {
+		return nil
+	}
+
+	now := time.Now().In(locationOf(user.Timezone))
+	month := now.Format(goalMonthFormat)
+	if user.GoalMetMonth == month {
+		return nil
+	}
+
+	watched, err := s.watchlistRepo.GetWatchedTimes(userID)
+	if err != nil {
+		return err
+	}
+	habits := computeHabits(watched, user.WatchGoals, now)
+	if !habits.Goal.Met {
+		return nil
+	}
+
+	// Two jobs racing here must not both congratulate
+	first, err := s.userRepo.MarkGoalMet(userID, month)
+	if err != nil || !first {
+		return err
+	}
+
+	message := fmt.Sprintf("You watched %d movies this month and met your goal of %d.", habits.Goal.Watched, habits.Goal.Target)
+	if habits.Streak.CurrentWeeks > 1 {
+		message += fmt.Sprintf(" You're on a %d-week streak.", habits.Streak.CurrentWeeks)
+	}
+	notification := &models.Notification{
+		UserID:  userID,
+		Type:    models.NotificationGoalMet,
+		Title:   "Monthly goal reached!",
+		Message: message,
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		return err
+	}
+	s.logger.Info("watch goal met", "user_id", userID.Hex(), "month", month)
+	return nil
+}
+
+// computeHabits derives streaks and goal progress from watched times as of
+// now. Weeks and months are bucketed in now's location.
+func computeHabits(watched []time.Time, goals *models.WatchGoals, now time.Time) *WatchHabits {
+	habits := &WatchHabits{WatchedTotal: len(watched)}
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	weeks := map[time.Time]bool{}
+	for _, t := range watched {
+		t = t.In(now.Location())
+		weeks[weekStart(t)] = true
+		if !t.Before(monthStart) {
+			habits.WatchedThisMonth++
+		}
+		if habits.Streak.LastWatchedAt == nil || t.After(*habits.Streak.LastWatchedAt) {
+			last := t.UTC()
+			habits.Streak.LastWatchedAt = &last
+		}
+	}
+
+	// The current streak counts back from this week, or from last week while
+	// this week has nothing watched yet
+	week := weekStart(now)
+	if !weeks[week] {
+		week = week.AddDate(0, 0, -7)
+	}
+	for weeks[week] {
+		habits.Streak.CurrentWeeks++
+		week = week.AddDate(0, 0, -7)
+	}
+
+	ordered := make([]time.Time, 0, len(weeks))
+	for week := range weeks {
+		ordered = append(ordered, week)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Before(ordered[j]) })
+	run := 0
+	for i, week := range ordered {
+		if i > 0 && ordered[i-1].AddDate(0, 0, 7).Equal(week) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > habits.Streak.LongestWeeks {
+			habits.Streak.LongestWeeks = run
+		}
+	}
+
+	if goals != nil && goals.MonthlyMovies > 0 {
+		progress := &GoalProgress{
+			Month:   now.Format(goalMonthFormat),
+			Target:  goals.MonthlyMovies,
+			Watched: habits.WatchedThisMonth,
+		}
+		if progress.Watched < progress.Target {
+			progress.Remaining = progress.Target - progress.Watched
+		}
+		progress.Met = progress.Watched >= progress.Target
+		habits.Goal = progress
+	}
+	return habits
+}
+
+// weekStart returns midnight on the Monday of t's week in t's location
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
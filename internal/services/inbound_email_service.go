@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// inboundEmailTokenLength is the length of the secret local part; 32
+	// hex characters are 128 bits
+	inboundEmailTokenLength = 32
+	// inboundEmailNoteLength caps the email text kept as the entry's note
+	inboundEmailNoteLength = 2000
+)
+
+// inboundSubjectPrefix matches the reply and forward markers mail clients
+// put in front of a subject, such as "Fwd: " or "RE: FW: "
+var inboundSubjectPrefix = regexp.MustCompile(`^(?i:\s*(?:re|fw|fwd|aw|wg)\s*:\s*)+`)
+
+// InboundEmailService adds movies to the watchlist from mail sent to the
+// user's inbound address. The subject is read as the movie's title unless
+// the message holds an IMDb link, and the body is kept as the entry's note.
+// The outcome of each message is reported as a notification.
+type InboundEmailService struct {
+	provider         InboundMailProvider
+	domain           string
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	quickAddService  *QuickAddService
+	watchlistService *WatchlistService
+	logger           *slog.Logger
+}
+
+// NewInboundEmailService returns the email-in service; with a nil provider
+// email-in is off
+func NewInboundEmailService(provider InboundMailProvider, domain string, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, quickAddService *QuickAddService, watchlistService *WatchlistService) *InboundEmailService {
+	return &InboundEmailService{
+		provider:         provider,
+		domain:           domain,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		quickAddService:  quickAddService,
+		watchlistService: watchlistService,
+		logger:           logging.For("services.inbound_email"),
+	}
+}
+
+// Enabled reports whether an inbound mail provider is configured
+func (s *InboundEmailService) Enabled() bool {
+	return s.provider != nil
+}
+
+// CreateAddress gives the user a new inbound address, which stops the
+// previous one from working. Only the hash of its local part is stored, so
+// the address cannot be shown again.
+func (s *InboundEmailService) CreateAddress(userID primitive.ObjectID) (string, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	token = token[:inboundEmailTokenLength]
+	found, err := s.userRepo.SetInboundEmailToken(userID, hashSecretToken(token))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("user not found")
+	}
+	return token + "@" + s.domain, nil
+}
+
+// DeleteAddress turns the user's inbound address off
+func (s *InboundEmailService) DeleteAddress(userID primitive.ObjectID) error {
+	_, err := s.userRepo.SetInboundEmailToken(userID, "")
+	return err
+}
+
+// Receive handles one webhook request from the provider. Mail for unknown
+// addresses is dropped without an error, so the provider does not retry it.
+func (s *InboundEmailService) Receive(ctx context.Context, r *http.Request) error {
+	email, err := s.provider.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	for _, recipient := range email.Recipients {
+		local, domain, ok := strings.Cut(recipient, "@")
+		if !ok || domain != s.domain {
+			continue
+		}
+		// Subaddresses such as token+movies@ reach the same user
+		local, _, _ = strings.Cut(local, "+")
+		user, err := s.userRepo.FindByInboundEmailToken(hashSecretToken(local))
+		if err != nil {
+			return err
+		}
+		if user == nil || user.DeactivatedAt != nil || seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+
+		if err := s.add(ctx, user.ID, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add finds the movie a message is about and adds it, reporting the outcome
+// to the user
+func (s *InboundEmailService) add(ctx context.Context, userID primitive.ObjectID, email *InboundEmail) error {
+	subject := strings.TrimSpace(inboundSubjectPrefix.ReplaceAllString(email.Subject, ""))
+	imdbID := quickAddIMDbPattern.FindString(subject)
+	if imdbID == "" {
+		imdbID = quickAddIMDbPattern.FindString(email.Text)
+	}
+	title := ""
+	if imdbID == "" {
+		title = subject
+	}
+	if imdbID == "" && title == "" {
+		return s.notify(userID, "Nothing added from your email", "Put the movie's title in the subject or include an IMDb link.", nil)
+	}
+
+	match, err := s.quickAddService.Resolve(ctx, imdbID, "", title, userID)
+	if err != nil {
+		var unsure *QuickAddError
+		if !errors.As(err, &unsure) {
+			return err
+		}
+		message := fmt.Sprintf("No movie matches %q.", unsure.Query)
+		if len(unsure.Candidates) > 0 {
+			names := make([]string, 0, len(unsure.Candidates))
+			for _, candidate := range unsure.Candidates {
+				names = append(names, fmt.Sprintf("%s (%s) %s", candidate.Title, candidate.Year, candidate.IMDbID))
+			}
+			message = fmt.Sprintf("%q could be %s. Send it again with the IMDb link.", unsure.Query, strings.Join(names, ", "))
+		}
+		return s.notify(userID, "Nothing added from your email", message, nil)
+	}
+
+	movieID := match.Movie.ID
+	source := &models.WatchlistSource{Type: models.WatchlistSourceEmail}
+	if _, err := s.watchlistService.AddWithNote(userID, movieID, source, inboundEmailNote(email.Text)); err != nil {
+		switch {
+		case errors.Is(err, ErrQuotaExceeded):
+			return s.notify(userID, "Nothing added from your email", fmt.Sprintf("Your watchlist is full, so %s was not added.", match.Movie.Title), &movieID)
+		case err.Error() == "movie already in watchlist":
+			return s.notify(userID, "Already on your watchlist", fmt.Sprintf("%s from your email is already on your watchlist.", match.Movie.Title), &movieID)
+		}
+		return err
+	}
+	return s.notify(userID, "Added from your email", fmt.Sprintf("%s (%s) is on your watchlist.", match.Movie.Title, match.Movie.Year), &movieID)
+}
+
+func (s *InboundEmailService) notify(userID primitive.ObjectID, title, message string, movieID *primitive.ObjectID) error {
+	return s.notificationRepo.Create(&models.Notification{
+		UserID:  userID,
+		Type:    models.NotificationEmailIn,
+		Title:   title,
+		Message: message,
+		MovieID: movieID,
+	})
+}
+
+// inboundEmailNote cleans up the body of a message for keeping as a note:
+// control characters other than newlines and tabs are dropped, and long
+// bodies are cut off
+func inboundEmailNote(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, text)
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > inboundEmailNoteLength {
+		text = strings.TrimSpace(string([]rune(text)[:inboundEmailNoteLength-1])) + "…"
+	}
+	return text
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inboundMailMaxAge rejects replayed Mailgun webhook requests
+const inboundMailMaxAge = 5 * time.Minute
+
+var (
+	// ErrInvalidInboundMail is returned for webhook requests that do not
+	// carry the provider's signature or credentials
+	ErrInvalidInboundMail = errors.New("inbound mail request could not be verified")
+	// ErrMalformedInboundMail is returned for webhook requests that cannot
+	// be read
+	ErrMalformedInboundMail = errors.New("inbound mail request is malformed")
+)
+
+// InboundEmail is a received message as the provider hands it over
+type InboundEmail struct {
+	// Recipients are the bare addresses the message was delivered to
+	Recipients []string
+	From       string
+	Subject    string
+	// Text is the plain text body
+	Text string
+}
+
+// InboundMailProvider turns an inbound mail provider's webhook request into
+// a message, after checking the request came from the provider
+type InboundMailProvider interface {
+	Parse(r *http.Request) (*InboundEmail, error)
+}
+
+// NewInboundMailProvider returns the provider for INBOUND_EMAIL_PROVIDER
+func NewInboundMailProvider(name, secret string) (InboundMailProvider, error) {
+	switch name {
+	case "mailgun":
+		return &MailgunInboundProvider{signingKey: secret}, nil
+	case "postmark":
+		return &PostmarkInboundProvider{password: secret}, nil
+	default:
+		return nil, fmt.Errorf("unknown inbound mail provider %q", name)
+	}
+}
+
+// MailgunInboundProvider reads messages forwarded by a Mailgun route, which
+// signs each request with the webhook signing key
+type MailgunInboundProvider struct {
+	signingKey string
+}
+
+func (p *MailgunInboundProvider) Parse(r *http.Request) (*InboundEmail, error) {
+	// Routes post multipart forms when the message has attachments
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedInboundMail, err)
+	}
+
+	timestamp := r.PostFormValue("timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidInboundMail
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > inboundMailMaxAge || age < -inboundMailMaxAge {
+		return nil, ErrInvalidInboundMail
+	}
+	mac := hmac.New(sha256.New, []byte(p.signingKey))
+	mac.Write([]byte(timestamp + r.PostFormValue("token")))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(r.PostFormValue("signature"))) {
+		return nil, ErrInvalidInboundMail
+	}
+
+	return &InboundEmail{
+		Recipients: inboundAddresses(r.PostFormValue("recipient")),
+		From:       r.PostFormValue("from"),
+		Subject:    r.PostFormValue("subject"),
+		Text:       r.PostFormValue("body-plain"),
+	}, nil
+}
+
+// PostmarkInboundProvider reads messages from Postmark's inbound webhook,
+// whose URL carries basic auth credentials with the configured password
+type PostmarkInboundProvider struct {
+	password string
+}
+
+func (p *PostmarkInboundProvider) Parse(r *http.Request) (*InboundEmail, error) {
+	_, password, ok := r.BasicAuth()
+	if !ok || !hmac.Equal([]byte(password), []byte(p.password)) {
+		return nil, ErrInvalidInboundMail
+	}
+
+	var message struct {
+		From              string
+		Subject           string
+		TextBody          string
+		OriginalRecipient string
+		ToFull            []struct{ Email string }
+		CcFull            []struct{ Email string }
+	}
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedInboundMail, err)
+	}
+
+	recipients := inboundAddresses(message.OriginalRecipient)
+	for _, to := range append(message.ToFull, message.CcFull...) {
+		recipients = append(recipients, inboundAddresses(to.Email)...)
+	}
+	return &InboundEmail{
+		Recipients: recipients,
+		From:       message.From,
+		Subject:    message.Subject,
+		Text:       message.TextBody,
+	}, nil
+}
+
+// inboundAddresses returns the bare, lowercased addresses in a recipient
+// list, skipping any it cannot parse
+func inboundAddresses(list string) []string {
+	var addresses []string
+	for _, part := range strings.Split(list, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(part)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, strings.ToLower(address.Address))
+	}
+	return addresses
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// tagPerRun caps the movies tagged by one job run
+	tagPerRun = 500
+	// tagInterval is how long the job waits once every movie is tagged
+	tagInterval = time.Hour
+)
+
+// KeywordExtractor tags a movie with themes. Source names the extractor and
+// its vocabulary version; movies tagged by another source are tagged again.
+type KeywordExtractor interface {
+	Source() string
+	Extract(ctx context.Context, movie models.Movie) ([]string, error)
+}
+
+// movieThemes maps each theme to the words or phrases that signal it in a
+// plot. Bump themeVocabularyVersion when editing the list so the catalogue
+// is tagged again.
+var movieThemes = map[string][]string{
+	"heist":                   {"heist", "robbery", "robber", "bank job", "thief", "thieves", "steal", "con artist"},
+	"time-travel":             {"time travel", "time machine", "back in time", "travels to the future"},
+	"time-loop":               {"time loop", "same day over", "relive the same", "reliving the same"},
+	"revenge":                 {"revenge", "avenge", "vengeance", "retribution"},
+	"prison":                  {"prison", "inmate", "jail", "convict"},
+	"war":                     {"war", "soldier", "battlefield", "army"},
+	"space":                   {"outer space", "astronaut", "spaceship", "spacecraft", "galaxy", "space station"},
+	"alien":                   {"alien", "extraterrestrial"},
+	"zombie":                  {"zombie", "undead"},
+	"serial-killer":           {"serial killer"},
+	"superhero":               {"superhero", "super hero", "superpower", "super power"},
+	"dystopia":                {"dystopia", "dystopian", "totalitarian", "post-apocalyptic"},
+	"artificial-intelligence": {"artificial intelligence", "robot", "android", "cyborg"},
+	"coming-of-age":           {"coming of age", "coming-of-age", "teenager", "adolescence", "growing up"},
+	"road-trip":               {"road trip", "cross-country"},
+	"survival":                {"survival", "survive", "stranded"},
+	"spy":                     {"spy", "espionage", "secret agent", "cia", "mi6"},
+	"organized-crime":         {"mafia", "mob boss", "gangster", "crime family", "cartel"},
+	"courtroom":               {"courtroom", "lawyer", "attorney", "trial"},
+	"haunting":                {"haunted", "ghost", "possession", "demon"},
+	"treasure-hunt":           {"treasure"},
+	"hacker":                  {"hacker", "hacking"},
+	"disaster":                {"earthquake", "tsunami", "asteroid", "disaster"},
+	"conspiracy":              {"conspiracy", "cover-up"},
+	"friendship":              {"friendship", "best friend"},
+	"journalism":              {"journalist", "reporter"},
+	"dreams":                  {"dream"},
+	"music":                   {"musician", "band", "singer"},
+	"sports":                  {"championship", "coach", "tournament"},
+}
+
+// themeVocabularyVersion versions movieThemes in the extractor's Source
+const themeVocabularyVersion = 1
+
+// themePatterns holds one compiled pattern per theme, matching its words as
+// whole words with an optional plural
+var themePatterns = compileThemePatterns(movieThemes)
+
+func compileThemePatterns(themes map[string][]string) map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp, len(themes))
+	for theme, phrases := range themes {
+		quoted := make([]string, len(phrases))
+		for i, phrase := range phrases {
+			quoted[i] = regexp.QuoteMeta(phrase)
+		}
+		patterns[theme] = regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)(?:s|es)?\b`)
+	}
+	return patterns
+}
+
+// PlotKeywordExtractor tags themes from the words in a movie's title and plot
+type PlotKeywordExtractor struct{}
+
+func NewPlotKeywordExtractor() *PlotKeywordExtractor {
+	return &PlotKeywordExtractor{}
+}
+
+func (e *PlotKeywordExtractor) Source() string {
+	return fmt.Sprintf("plot-themes-v%d", themeVocabularyVersion)
+}
+
+func (e *PlotKeywordExtractor) Extract(ctx context.Context, movie models.Movie) ([]string, error) {
+	text := strings.ToLower(movie.Title + ". " + movie.Plot)
+	keywords := []string{}
+	for theme, pattern := range themePatterns {
+		if pattern.MatchString(text) {
+			keywords = append(keywords, theme)
+		}
+	}
+	sort.Strings(keywords)
+	return keywords, nil
+}
+
+// KeywordService tags movies with themes for keyword filters and
+// recommendations
+type KeywordService struct {
+	extractor KeywordExtractor
+	movieRepo *repositories.MovieRepository
+	history   *MovieHistoryService
+	jobQueue  *jobs.Queue
+	logger    *slog.Logger
+}
+
+func NewKeywordService(extractor KeywordExtractor, movieRepo *repositories.MovieRepository, history *MovieHistoryService, jobQueue *jobs.Queue) *KeywordService {
+	return &KeywordService{
+		extractor: extractor,
+		movieRepo: movieRepo,
+		history:   history,
+		jobQueue:  jobQueue,
+		logger:    logging.For("services.keywords"),
+	}
+}
+
+// EnsureScheduled queues the first keyword tagging run if none is pending
+func (s *KeywordService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeTagKeywords, nil, time.Now().UTC())
+}
+
+// TagKeywordsJob tags movies not yet tagged by the current extractor,
+// including movies whose details were refreshed since. It runs again right
+// away while movies are left over and hourly once it has caught up.
+func (s *KeywordService) TagKeywordsJob(ctx context.Context, payload map[string]interface{}) error {
+	source := s.extractor.Source()
+	movies, err := s.movieRepo.FindUntagged(source, tagPerRun)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		keywords, err := s.extractor.Extract(ctx, movie)
+		if err != nil {
+			return err
+		}
+		if err := s.movieRepo.SetKeywords(movie.ID, keywords, source); err != nil {
+			return err
+		}
+		s.history.Record(&movie, map[string]string{"keywords": strings.Join(keywords, ",")}, changedByJob(jobs.TypeTagKeywords))
+	}
+	if len(movies) > 0 {
+		s.logger.Info("tagged movie keywords", "movies", len(movies), "source", source)
+	}
+
+	next := time.Now().UTC().Add(tagInterval)
+	if len(movies) == tagPerRun {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeTagKeywords, nil, next)
+}
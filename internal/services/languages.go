@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"strings"
+)
+
+// maxPreferredLanguages caps each preferred language list
+const maxPreferredLanguages = 10
+
+// ErrInvalidLanguage is returned for a language that is neither a known
+// ISO 639-1 code nor a known language name
+var ErrInvalidLanguage = errors.New("invalid language")
+
+// languageNames maps ISO 639-1 codes to the English names OMDb reports
+var languageNames = map[string]string{
+	"ar": "Arabic", "bn": "Bengali", "cs": "Czech", "da": "Danish",
+	"de": "German", "el": "Greek", "en": "English", "es": "Spanish",
+	"fa": "Persian", "fi": "Finnish", "fr": "French", "he": "Hebrew",
+	"hi": "Hindi", "hu": "Hungarian", "id": "Indonesian", "it": "Italian",
+	"ja": "Japanese", "ko": "Korean", "ml": "Malayalam", "mr": "Marathi",
+	"nl": "Dutch", "no": "Norwegian", "pl": "Polish", "pt": "Portuguese",
+	"ro": "Romanian", "ru": "Russian", "sv": "Swedish", "ta": "Tamil",
+	"te": "Telugu", "th": "Thai", "tr": "Turkish", "uk": "Ukrainian",
+	"ur": "Urdu", "vi": "Vietnamese", "zh": "Chinese",
+}
+
+// LanguagePreferences are a user's preferred audio and subtitle languages
+type LanguagePreferences struct {
+	Audio     []string `json:"audio"`
+	Subtitles []string `json:"subtitles"`
+}
+
+// normalizeLanguages resolves codes and names to canonical language names,
+// dropping duplicates while keeping the caller's order
+func normalizeLanguages(languages []string) ([]string, error) {
+	if len(languages) > maxPreferredLanguages {
+		return nil, fmt.Errorf("%w: at most %d languages allowed", ErrInvalidLanguage, maxPreferredLanguages)
+	}
+
+	normalized := make([]string, 0, len(languages))
+	seen := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		name, ok := canonicalLanguage(language)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLanguage, language)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
+
+// canonicalLanguage resolves an ISO 639-1 code or a language name
+func canonicalLanguage(language string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(language))
+	if name, ok := languageNames[key]; ok {
+		return name, true
+	}
+	for _, name := range languageNames {
+		if strings.ToLower(name) == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AudioLanguageMatch reports whether the movie is spoken in one of the
+// preferred audio languages. It returns nil when the user has no audio
+// preference or the movie's languages are unknown.
+func AudioLanguageMatch(movie models.Movie, prefs LanguagePreferences) *bool {
+	if len(prefs.Audio) == 0 || strings.TrimSpace(movie.Language) == "" {
+		return nil
+	}
+
+	match := false
+	for _, spoken := range strings.Split(movie.Language, ",") {
+		for _, preferred := range prefs.Audio {
+			if strings.EqualFold(strings.TrimSpace(spoken), preferred) {
+				match = true
+			}
+		}
+	}
+	return &match
+}
+
+// FilterByAudioLanguage drops movies known not to be available in any
+// preferred audio language; movies with unknown languages are kept
+func FilterByAudioLanguage(movies []models.Movie, prefs LanguagePreferences) []models.Movie {
+	filtered := make([]models.Movie, 0, len(movies))
+	for _, movie := range movies {
+		if match := AudioLanguageMatch(movie, prefs); match != nil && !*match {
+			continue
+		}
+		filtered = append(filtered, movie)
+	}
+	return filtered
+}
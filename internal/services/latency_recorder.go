@@ -0,0 +1,72 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySampleSize is the number of most recent samples kept per recorder
+const latencySampleSize = 1000
+
+// LatencyPercentiles summarises recent latencies in milliseconds
+type LatencyPercentiles struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+}
+
+// latencyRecorder keeps a fixed-size ring buffer of recent durations in memory
+type latencyRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{samples: make([]time.Duration, 0, latencySampleSize)}
+}
+
+func (r *latencyRecorder) Record(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.samples) < latencySampleSize {
+		r.samples = append(r.samples, d)
+		return
+	}
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % latencySampleSize
+}
+
+func (r *latencyRecorder) Percentiles() LatencyPercentiles {
+	r.mu.Lock()
+	sorted := make([]time.Duration, len(r.samples))
+	copy(sorted, r.samples)
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return LatencyPercentiles{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return LatencyPercentiles{
+		Samples: len(sorted),
+		P50:     percentileMillis(sorted, 0.50),
+		P90:     percentileMillis(sorted, 0.90),
+		P99:     percentileMillis(sorted, 0.99),
+	}
+}
+
+// percentileMillis uses the nearest-rank method on an ascending slice
+func percentileMillis(sorted []time.Duration, p float64) float64 {
+	rank := int(p*float64(len(sorted)+1)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return float64(sorted[rank].Microseconds()) / 1000
+}
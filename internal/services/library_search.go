@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxLibrarySearchTerms caps the words of a personal search query
+const MaxLibrarySearchTerms = 8
+
+// ErrInvalidLibraryQuery is returned for an empty or overly long query
+var ErrInvalidLibraryQuery = errors.New("invalid search query")
+
+// librarySearchFields are the movie fields a personal search looks at, with
+// the weight a match in each adds to the hit's score
+var librarySearchFields = []struct {
+	name   string
+	weight float64
+	value  func(m *models.Movie) string
+}{
+	{"title", 3, func(m *models.Movie) string { return m.Title }},
+	{"genre", 2, func(m *models.Movie) string { return m.Genre }},
+	{"language", 2, func(m *models.Movie) string { return m.Language }},
+	{"director", 2, func(m *models.Movie) string { return m.Director }},
+	{"plot", 1, func(m *models.Movie) string { return m.Plot }},
+}
+
+// LibraryHit is a movie from the user's own watchlist or ratings that
+// matches a personal search
+type LibraryHit struct {
+	Movie       models.Movie
+	InWatchlist bool
+	WatchedAt   *time.Time
+	// Rating is the user's star rating, or 0 when unrated
+	Rating  int
+	Matched []string
+	Score   float64
+}
+
+// LibraryService searches the movies a user has collected
+type LibraryService struct {
+	movieRepo     *repositories.MovieRepository
+	watchlistRepo *repositories.WatchlistRepository
+	ratingRepo    *repositories.RatingRepository
+}
+
+func NewLibraryService(movieRepo *repositories.MovieRepository, watchlistRepo *repositories.WatchlistRepository, ratingRepo *repositories.RatingRepository) *LibraryService {
+	return &LibraryService{
+		movieRepo:     movieRepo,
+		watchlistRepo: watchlistRepo,
+		ratingRepo:    ratingRepo,
+	}
+}
+
+// Search finds movies on the user's watchlist or among their ratings where
+// every query word appears in the title, genre, language, director or plot,
+// so "korean thriller" finds Korean-language thrillers. Hits are ordered by
+// how strongly they match, title matches first.
+func (s *LibraryService) Search(userID primitive.ObjectID, query string) ([]LibraryHit, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: search query is required", ErrInvalidLibraryQuery)
+	}
+	if len(terms) > MaxLibrarySearchTerms {
+		return nil, fmt.Errorf("%w: use at most %d words", ErrInvalidLibraryQuery, MaxLibrarySearchTerms)
+	}
+
+	watchlist, err := s.watchlistRepo.GetUserWatchlist(userID)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := s.ratingRepo.GetUserRatings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[primitive.ObjectID]*LibraryHit)
+	ids := []primitive.ObjectID{}
+	hitFor := func(movieID primitive.ObjectID) *LibraryHit {
+		hit, ok := hits[movieID]
+		if !ok {
+			hit = &LibraryHit{}
+			hits[movieID] = hit
+			ids = append(ids, movieID)
+		}
+		return hit
+	}
+	for _, entry := range watchlist {
+		hit := hitFor(entry.MovieID)
+		hit.InWatchlist = true
+		hit.WatchedAt = entry.WatchedAt
+	}
+	for _, rating := range ratings {
+		hitFor(rating.MovieID).Rating = rating.Rating
+	}
+
+	movies, err := s.movieRepo.SearchWithin(ids, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]LibraryHit, 0, len(movies))
+	for _, movie := range movies {
+		hit := hits[movie.ID]
+		hit.Movie = movie
+		for _, field := range librarySearchFields {
+			value := strings.ToLower(field.value(&movie))
+			matched := false
+			for _, term := range terms {
+				if strings.Contains(value, term) {
+					hit.Score += field.weight
+					matched = true
+				}
+			}
+			if matched {
+				hit.Matched = append(hit.Matched, field.name)
+			}
+		}
+		results = append(results, *hit)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Movie.Title < results[j].Movie.Title
+	})
+	return results, nil
+}
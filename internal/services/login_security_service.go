@@ -0,0 +1,190 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/mailer"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// recentLoginsLimit is how many login attempts the security page lists
+	recentLoginsLimit = 50
+	// revokeLinkTTL is how long the "revoke sessions" link in a new-location
+	// alert stays valid
+	revokeLinkTTL = 7 * 24 * time.Hour
+)
+
+// LoginSecurityService records login attempts and alerts users to logins
+// from locations their account has not been used from before
+type LoginSecurityService struct {
+	attemptRepo      *repositories.LoginAttemptRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	sessionService   *SessionService
+	mailer           mailer.Mailer
+	publicBaseURL    string
+	logger           *slog.Logger
+}
+
+func NewLoginSecurityService(attemptRepo *repositories.LoginAttemptRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, sessionService *SessionService, mailer mailer.Mailer, publicBaseURL string) *LoginSecurityService {
+	return &LoginSecurityService{
+		attemptRepo:      attemptRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		sessionService:   sessionService,
+		mailer:           mailer,
+		publicBaseURL:    publicBaseURL,
+		logger:           logging.For("services.security"),
+	}
+}
+
+// RecordLogin stores a successful login. The first login from a new location
+// is flagged and, unless it is the account's first login ever, the user is
+// notified in-app and by email with a link that revokes all sessions.
+// Failures are logged; they never fail the login itself.
+func (s *LoginSecurityService) RecordLogin(user *models.User, ip, userAgent, country string) {
+	locations, devices, err := s.attemptRepo.KnownLocations(user.ID)
+	if err != nil {
+		s.logger.Warn("failed to load known login locations", "user_id", user.ID.Hex(), "error", err)
+		return
+	}
+
+	attempt := newLoginAttempt(user.ID, true, ip, userAgent, country)
+	// The first login sets the baseline rather than raising an alert
+	firstLogin := len(locations) == 0
+	attempt.NewLocation = !firstLogin && !locations[attempt.Location]
+	attempt.NewDevice = !firstLogin && !devices[attempt.DeviceFingerprint]
+
+	var revokeToken string
+	if attempt.NewLocation {
+		revokeToken, err = newSecretToken()
+		if err != nil {
+			s.logger.Warn("failed to create revoke token", "user_id", user.ID.Hex(), "error", err)
+		} else {
+			expiresAt := time.Now().UTC().Add(revokeLinkTTL)
+			attempt.RevokeTokenHash = hashSecretToken(revokeToken)
+			attempt.RevokeExpiresAt = &expiresAt
+		}
+	}
+
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		s.logger.Warn("failed to record login", "user_id", user.ID.Hex(), "error", err)
+		return
+	}
+	if attempt.NewLocation {
+		s.alert(user, attempt, revokeToken)
+	}
+}
+
+// RecordFailedLogin stores a failed login for the account with the email, if any
+func (s *LoginSecurityService) RecordFailedLogin(email, ip, userAgent, country string) {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil || user == nil {
+		return
+	}
+	if err := s.attemptRepo.Create(newLoginAttempt(user.ID, false, ip, userAgent, country)); err != nil {
+		s.logger.Warn("failed to record failed login", "user_id", user.ID.Hex(), "error", err)
+	}
+}
+
+// GetRecentLogins returns the user's latest login attempts, newest first
+func (s *LoginSecurityService) GetRecentLogins(userID primitive.ObjectID) ([]models.LoginAttempt, error) {
+	return s.attemptRepo.FindRecentByUser(userID, recentLoginsLimit)
+}
+
+// RevokeSessions handles the link from a new-location alert: it logs the
+// account out everywhere. Each link works once.
+func (s *LoginSecurityService) RevokeSessions(token string) (int64, error) {
+	attempt, err := s.attemptRepo.TakeRevokeToken(hashSecretToken(token))
+	if err != nil {
+		return 0, err
+	}
+	if attempt == nil {
+		return 0, errors.New("invalid or expired revoke link")
+	}
+
+	revoked, err := s.sessionService.RevokeAllSessions(attempt.UserID)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("sessions revoked from login alert", "user_id", attempt.UserID.Hex(), "sessions", revoked)
+	return revoked, nil
+}
+
+func (s *LoginSecurityService) alert(user *models.User, attempt *models.LoginAttempt, revokeToken string) {
+	device := attempt.UserAgent
+	if device == "" {
+		device = "an unknown device"
+	}
+
+	notification := &models.Notification{
+		UserID:  user.ID,
+		Type:    models.NotificationSuspiciousLogin,
+		Title:   "New sign-in location",
+		Message: fmt.Sprintf("Your account was signed in to from %s (%s) on %s. If this wasn't you, change your password and log out all sessions.", attempt.Location, attempt.IP, device),
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		s.logger.Warn("failed to create login alert", "user_id", user.ID.Hex(), "error", err)
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour Movie Watchlist account was just signed in to from a new location:\n\nLocation: %s\nIP address: %s\nDevice: %s\nTime: %s\n\n",
+		user.Username, attempt.Location, attempt.IP, device, attempt.CreatedAt.Format(time.RFC1123))
+	if revokeToken != "" {
+		link := s.publicBaseURL + "/security/revoke?token=" + url.QueryEscape(revokeToken)
+		body += fmt.Sprintf("If this wasn't you, log out every device with this link, then change your password:\n\n%s\n\nThe link expires in 7 days.\n", link)
+	} else {
+		body += "If this wasn't you, change your password and log out all sessions.\n"
+	}
+	if err := s.mailer.Send(user.Email, "New sign-in to your Movie Watchlist account", body); err != nil {
+		s.logger.Warn("failed to email login alert", "user_id", user.ID.Hex(), "error", err)
+	}
+}
+
+func newLoginAttempt(userID primitive.ObjectID, success bool, ip, userAgent, country string) *models.LoginAttempt {
+	return &models.LoginAttempt{
+		UserID:            userID,
+		Success:           success,
+		IP:                ip,
+		Location:          loginLocation(ip, country),
+		UserAgent:         userAgent,
+		DeviceFingerprint: deviceFingerprint(userAgent),
+	}
+}
+
+// loginLocation is the country code when the proxy reports one, otherwise
+// the IP's /24 (IPv4) or /48 (IPv6) network, so a new address from the same
+// provider does not count as a new location
+func loginLocation(ip, country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	// Cloudflare reports XX when it does not know the country
+	if len(country) == 2 && country != "XX" {
+		return country
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// deviceFingerprint identifies a device by a hash of its user agent
+func deviceFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"sync"
+	"time"
+)
+
+const (
+	// metadataCooldown is how long a provider that rate-limited us is skipped
+	metadataCooldown = 5 * time.Minute
+	// MetadataStaleAfter is the age after which cached details are reported stale
+	MetadataStaleAfter = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrProviderRateLimited is wrapped by providers whose quota or rate limit is hit
+	ErrProviderRateLimited = errors.New("metadata provider rate limited")
+	// ErrMovieNotFound is wrapped by providers that have no movie for the ID
+	ErrMovieNotFound = errors.New("movie not found")
+	// ErrMetadataUnavailable is returned when no provider could be asked or
+	// answered properly, as opposed to every provider not knowing the movie
+	ErrMetadataUnavailable = errors.New("movie metadata unavailable")
+)
+
+// MetadataProvider fetches the full details of a movie by IMDb ID. Details
+// come back in OMDb's shape, which is what the movie cache stores.
+type MetadataProvider interface {
+	// Name identifies the provider in config and on movie documents
+	Name() string
+	FetchDetails(ctx context.Context, imdbID string) (*OMDbResponse, error)
+}
+
+// MetadataChain asks providers in order and fails over to the next one when a
+// provider errors or rate-limits. Rate-limited providers are skipped for a
+// cooldown. The local cache is the last resort and is up to the caller.
+type MetadataChain struct {
+	providers []MetadataProvider
+	mu        sync.Mutex
+	cooldown  map[string]time.Time
+	logger    *slog.Logger
+}
+
+func NewMetadataChain(providers ...MetadataProvider) *MetadataChain {
+	return &MetadataChain{
+		providers: providers,
+		cooldown:  make(map[string]time.Time),
+		logger:    logging.For("services.metadata"),
+	}
+}
+
+// Providers returns the provider names in failover order
+func (c *MetadataChain) Providers() []string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
+// FetchDetails returns the details from the first provider that has them and
+// that provider's name. The error joins every provider's failure and wraps
+// ErrMetadataUnavailable unless every provider reported ErrMovieNotFound.
+func (c *MetadataChain) FetchDetails(ctx context.Context, imdbID string) (*OMDbResponse, string, error) {
+	return c.fetchExcept(ctx, imdbID, "")
+}
+
+// fetchExcept is FetchDetails without the provider named skip
+func (c *MetadataChain) fetchExcept(ctx context.Context, imdbID, skip string) (*OMDbResponse, string, error) {
+	var errs []error
+	notFound := true
+	for _, provider := range c.providers {
+		name := provider.Name()
+		if name == skip {
+			continue
+		}
+		if c.coolingDown(name) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrProviderRateLimited))
+			notFound = false
+			continue
+		}
+
+		details, err := provider.FetchDetails(ctx, imdbID)
+		if err == nil {
+			return details, name, nil
+		}
+		if errors.Is(err, ErrProviderRateLimited) {
+			c.startCooldown(name)
+		}
+		if ctx.Err() != nil {
+			return nil, "", err
+		}
+		c.logger.Warn("metadata provider failed, trying the next one", "provider", name, "imdb_id", imdbID, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if !errors.Is(err, ErrMovieNotFound) {
+			notFound = false
+		}
+	}
+	if len(errs) == 0 {
+		return nil, "", fmt.Errorf("%w: no metadata provider configured", ErrMetadataUnavailable)
+	}
+	if notFound {
+		return nil, "", errors.Join(errs...)
+	}
+	return nil, "", fmt.Errorf("%w: %w", ErrMetadataUnavailable, errors.Join(errs...))
+}
+
+func (c *MetadataChain) coolingDown(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.cooldown[name]
+	return ok && time.Now().Before(until)
+}
+
+func (c *MetadataChain) startCooldown(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cooldown[name] = time.Now().Add(metadataCooldown)
+	c.logger.Warn("metadata provider rate limited, skipping it for a while", "provider", name, "cooldown", metadataCooldown)
+}
+
+// omdbMetadataProvider fetches details from OMDb, within the daily quota guard
+type omdbMetadataProvider struct {
+	movieService *MovieService
+}
+
+func (p *omdbMetadataProvider) Name() string {
+	return models.MovieSourceOMDb
+}
+
+func (p *omdbMetadataProvider) FetchDetails(ctx context.Context, imdbID string) (*OMDbResponse, error) {
+	if p.movieService.apiKey == "" {
+		return nil, fmt.Errorf("OMDb API key not configured")
+	}
+	// Leave the rest of the quota to searches
+	if p.movieService.usageService.IsQuotaNearlyExhausted() {
+		return nil, fmt.Errorf("OMDb quota nearly exhausted: %w", ErrProviderRateLimited)
+	}
+	return p.movieService.fetchMovieDetails(ctx, imdbID)
+}
+
+// annotateFreshness describes the cached details of movie as of now. Stubs
+// that never got full details count as stale.
+func annotateFreshness(movie *models.Movie, now time.Time) {
+	provider := movie.MetadataProvider
+	if provider == "" {
+		provider = movie.Source
+	}
+	movie.Freshness = &models.MetadataFreshness{
+		Provider:  provider,
+		FetchedAt: movie.CachedAt,
+		Stale:     !movie.HasDetails() || now.Sub(movie.CachedAt) > MetadataStaleAfter,
+	}
+}
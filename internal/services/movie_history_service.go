@@ -0,0 +1,108 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChangedByRequest marks movie changes made while serving an API request
+const ChangedByRequest = "request"
+
+// changedByJob names a background job as the author of a movie change
+func changedByJob(jobType string) string {
+	return "job:" + jobType
+}
+
+// changedByAdmin names an admin as the author of a movie change
+func changedByAdmin(userID primitive.ObjectID) string {
+	return "admin:" + userID.Hex()
+}
+
+// MovieHistoryService keeps a revision history of movie documents, so
+// shifts in recommendations can be traced to metadata changes
+type MovieHistoryService struct {
+	revisionRepo *repositories.MovieRevisionRepository
+	logger       *slog.Logger
+}
+
+func NewMovieHistoryService(revisionRepo *repositories.MovieRevisionRepository) *MovieHistoryService {
+	return &MovieHistoryService{
+		revisionRepo: revisionRepo,
+		logger:       logging.For("services.movie_history"),
+	}
+}
+
+// GetRevisions returns a page of the movie's revisions, oldest first
+func (s *MovieHistoryService) GetRevisions(movieID primitive.ObjectID, cursor pagination.Cursor) ([]models.MovieRevision, bool, error) {
+	return s.revisionRepo.FindByMovie(movieID, cursor)
+}
+
+// Record stores the fields in after whose values differ from before. New
+// documents (before is nil) and writes that changed nothing are not
+// recorded. The write already happened, so a failure is only logged.
+func (s *MovieHistoryService) Record(before *models.Movie, after map[string]string, changedBy string) {
+	if before == nil {
+		return
+	}
+
+	old := movieFieldValues(before)
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	changes := []models.FieldChange{}
+	for _, field := range fields {
+		if old[field] != after[field] {
+			changes = append(changes, models.FieldChange{Field: field, Old: old[field], New: after[field]})
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	revision := &models.MovieRevision{
+		MovieID:   before.ID,
+		Changes:   changes,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now().UTC(),
+	}
+	if err := s.revisionRepo.Create(revision); err != nil {
+		s.logger.Warn("failed to record movie revision", "movie_id", before.ID.Hex(), "changed_by", changedBy, "error", err)
+	}
+}
+
+// movieDetailValues are the provider details of a movie by field name
+func movieDetailValues(movie *models.Movie) map[string]string {
+	return map[string]string{
+		"title":             movie.Title,
+		"year":              movie.Year,
+		"genre":             movie.Genre,
+		"director":          movie.Director,
+		"actors":            movie.Actors,
+		"plot":              movie.Plot,
+		"poster":            movie.Poster,
+		"runtime":           movie.Runtime,
+		"imdb_rating":       movie.IMDbRating,
+		"language":          movie.Language,
+		"rated":             movie.Rated,
+		"metadata_provider": movie.MetadataProvider,
+	}
+}
+
+// movieFieldValues are the tracked fields of a movie: its details and the
+// keywords tagged from them
+func movieFieldValues(movie *models.Movie) map[string]string {
+	values := movieDetailValues(movie)
+	values["keywords"] = strings.Join(movie.Keywords, ",")
+	return values
+}
@@ -4,28 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
 	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/repositories"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// OMDbResponse is an OMDb title record. Clients also send it back when adding a
+// search result to the watchlist, so its text fields carry sanitize limits.
 type OMDbResponse struct {
-	Title      string `json:"Title"`
-	Year       string `json:"Year"`
-	IMDbID     string `json:"imdbID"`
-	Genre      string `json:"Genre"`
-	Director   string `json:"Director"`
-	Plot       string `json:"Plot"`
-	Poster     string `json:"Poster"`
-	Runtime    string `json:"Runtime"`
-	IMDbRating string `json:"imdbRating"`
+	Title      string `json:"Title" sanitize:"line,max=300"`
+	Year       string `json:"Year" sanitize:"line,max=20"`
+	IMDbID     string `json:"imdbID" sanitize:"line,max=12"`
+	Genre      string `json:"Genre" sanitize:"line,max=300"`
+	Director   string `json:"Director" sanitize:"line,max=500"`
+	Actors     string `json:"Actors,omitempty" sanitize:"line,max=500"`
+	Plot       string `json:"Plot" sanitize:"text,max=5000"`
+	Poster     string `json:"Poster" sanitize:"line,max=2048"`
+	Runtime    string `json:"Runtime" sanitize:"line,max=20"`
+	IMDbRating string `json:"imdbRating" sanitize:"line,max=10"`
+	Language   string `json:"Language" sanitize:"line,max=300"`
+	Rated      string `json:"Rated" sanitize:"line,max=20"`
+	Released   string `json:"Released,omitempty" sanitize:"line,max=30"`
 	Response   string `json:"Response"`
 	Error      string `json:"Error"`
+	// Source is set on unified search hits: omdb, local or both
+	Source string `json:"source,omitempty"`
 }
 
 type OMDbSearchResponse struct {
@@ -35,230 +48,479 @@ type OMDbSearchResponse struct {
 	Error        string          `json:"Error"`
 }
 
+// imdbIDPattern matches IMDb title IDs such as tt0111161
+var imdbIDPattern = regexp.MustCompile(`^tt\d{7,10}$`)
+
+// omdbNotFoundError is the error OMDb reports when a search has no hits
+const omdbNotFoundError = "Movie not found!"
+
+// omdbMissingErrors are the errors OMDb reports for a details lookup of an
+// ID it has no title for, as opposed to key, quota and server problems
+var omdbMissingErrors = map[string]bool{
+	omdbNotFoundError:    true,
+	"Incorrect IMDb ID.": true,
+}
+
+// SearchPageSize is the fixed number of results per search page, matching OMDb's page size
+const SearchPageSize = 10
+
+// SuggestLimit caps the number of typeahead suggestions returned per keystroke
+const SuggestLimit = 8
+
+// maxSuggestPrefixLength bounds the prefix used for typeahead lookups
+const maxSuggestPrefixLength = 100
+
+// SearchResult is one page of movie search results
+type SearchResult struct {
+	Movies []OMDbResponse
+	Total  int64
+	// CacheOnly reports whether the search was served from the local cache
+	// because the daily OMDb quota is nearly exhausted
+	CacheOnly bool
+	// DidYouMean holds fuzzy title matches offered when nothing was found
+	DidYouMean []DidYouMean
+}
+
 type MovieService struct {
-	movieRepo *repositories.MovieRepository
-	apiKey    string
-	client    *http.Client
+	movieRepo    *repositories.MovieRepository
+	recommendationRepo *repositories.RecommendationRepository
+	usageService *OMDbUsageService
+	history      *MovieHistoryService
+	jobQueue     *jobs.Queue
+	apiKey       string
+	baseURL      string
+	client       *http.Client
+	metadata     *MetadataChain
+	titles       titleIndex
+	logger       *slog.Logger
 }
 
-func NewMovieService(movieRepo *repositories.MovieRepository, apiKey string) *MovieService {
-	return &MovieService{
-		movieRepo: movieRepo,
-		apiKey:    apiKey,
+// NewMovieService creates the service. metadataChain names the providers
+// asked for movie details, in failover order: "omdb" is built in and other
+// names are looked up among providers; unknown names are skipped. transport
+// carries OMDb requests; nil uses the default.
+func NewMovieService(movieRepo *repositories.MovieRepository, usageService *OMDbUsageService, history *MovieHistoryService, jobQueue *jobs.Queue, apiKey, baseURL string, transport http.RoundTripper, metadataChain []string, providers ...MetadataProvider) *MovieService {
+	s := &MovieService{
+		movieRepo:    movieRepo,
+		recommendationRepo: repositories.NewRecommendationRepository(movieRepo.GetDB()),
+		usageService: usageService,
+		history:      history,
+		jobQueue:     jobQueue,
+		apiKey:       apiKey,
+		baseURL:      strings.TrimRight(baseURL, "/"),
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
 		},
+		logger: logging.For("services.movies"),
 	}
-}
 
-func (s *MovieService) SearchMovies(ctx context.Context, query string) ([]OMDbResponse, error) {
-	if s.apiKey == "" {
-		return nil, fmt.Errorf("OMDb API key not configured")
+	byName := map[string]MetadataProvider{models.MovieSourceOMDb: &omdbMetadataProvider{movieService: s}}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	chain := []MetadataProvider{}
+	for _, name := range metadataChain {
+		if provider, ok := byName[name]; ok {
+			chain = append(chain, provider)
+		}
 	}
+	s.metadata = NewMetadataChain(chain...)
+	return s
+}
 
+// SearchMovies returns one page of unified search results: OMDb hits merged
+// with local text-index matches, deduplicated by IMDb ID and ranked for the
+// user when one is signed in. It falls back to the local cache alone when the
+// daily OMDb quota is nearly exhausted or OMDb fails. When nothing matches, fuzzy "did you
+// mean" suggestions from cached titles are attached instead of an error.
+func (s *MovieService) SearchMovies(ctx context.Context, query string, page int, userID *primitive.ObjectID) (*SearchResult, error) {
 	if strings.TrimSpace(query) == "" {
 		return nil, fmt.Errorf("search query cannot be empty")
 	}
 
-	// URL encode the query for safe HTTP requests
-	encodedQuery := url.QueryEscape(query)
-	requestURL := fmt.Sprintf("http://www.omdbapi.com/?apikey=%s&s=%s", s.apiKey, encodedQuery)
+	var result *SearchResult
+	// Without an OMDb key (e.g. a demo deployment) only the cache is searched
+	if s.apiKey == "" || s.usageService.IsQuotaNearlyExhausted() {
+		cached, err := s.searchCachedMovies(query, page, nil)
+		if err != nil {
+			return nil, err
+		}
+		result = cached
+	} else if remote, err := s.searchOMDb(ctx, query, page); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// The cache is the last link of the failover chain
+		s.logger.Warn("search: OMDb failed, serving the local cache", "error", err)
+		cached, cacheErr := s.searchCachedMovies(query, page, nil)
+		if cacheErr != nil {
+			return nil, err
+		}
+		result = cached
+	} else {
+		local, err := s.movieRepo.SearchByText(strings.TrimSpace(query), SearchPageSize)
+		if err != nil {
+			// Local matches only enrich the OMDb page; serve that page on its own
+			s.logger.Warn("search: local text search failed", "error", err)
+			local = nil
+		}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+		result = s.blendSearchResults(remote, local, page, userID)
+	}
+
+	// A miss is often a misspelling; offer close cached titles instead
+	if len(result.Movies) == 0 && page == 1 {
+		suggestions, err := s.suggestSimilarTitles(query)
+		if err != nil {
+			s.logger.Warn("search: fuzzy matching failed", "error", err)
+		}
+		result.DidYouMean = suggestions
+	}
+
+	return result, nil
+}
+
+// SuggestTitles returns typeahead suggestions for a title prefix from the local
+// cache only, so search-as-you-type never spends OMDb quota
+func (s *MovieService) SuggestTitles(prefix string) ([]models.Movie, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+	if len(prefix) > maxSuggestPrefixLength {
+		prefix = prefix[:maxSuggestPrefixLength]
+	}
+
+	movies, err := s.movieRepo.SuggestByTitlePrefix(prefix, SuggestLimit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to load suggestions: %w", err)
 	}
+	return movies, nil
+}
 
-	resp, err := s.client.Do(req)
+// SearchMoviesUpTo searches for a kids profile capped at maxCertification.
+// OMDb search hits carry no certification and hits without cached details
+// count as above every cap, so only cached movies are searched, with the cap
+// in the query; Total then counts just the movies the profile may see.
+func (s *MovieService) SearchMoviesUpTo(query string, page int, maxCertification string) (*SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	result, err := s.searchCachedMovies(query, page, CertificationsUpTo(maxCertification))
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to OMDb API: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	// Searching the cache is the rule for profiles, not a quota fallback
+	result.CacheOnly = false
+	return result, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OMDb API returned status code: %d", resp.StatusCode)
+// searchCachedMovies serves a search from locally cached movies only,
+// limited to the rated certifications when given
+func (s *MovieService) searchCachedMovies(query string, page int, rated []string) (*SearchResult, error) {
+	skip := int64((page - 1) * SearchPageSize)
+	cached, total, err := s.movieRepo.SearchByTitle(strings.TrimSpace(query), rated, skip, SearchPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search cached movies: %w", err)
 	}
 
-	var searchResp OMDbSearchResponse
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode OMDb API response: %w", err)
+	results := make([]OMDbResponse, 0, len(cached))
+	for i := range cached {
+		result := omdbResponseFromMovie(&cached[i])
+		result.Source = SearchSourceLocal
+		results = append(results, result)
+	}
+	return &SearchResult{Movies: results, Total: total, CacheOnly: true}, nil
+}
+
+func (s *MovieService) searchOMDb(ctx context.Context, query string, page int) (*SearchResult, error) {
+	searchResp, err := s.omdbSearchRequest(ctx, query, page, 0)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check for API-level errors
 	if searchResp.Response == "False" {
+		// No OMDb hits is not a failure: local matches may still fill the page
+		if searchResp.Error == omdbNotFoundError {
+			return &SearchResult{Movies: []OMDbResponse{}}, nil
+		}
 		if searchResp.Error != "" {
 			return nil, fmt.Errorf("OMDb API error: %s", searchResp.Error)
 		}
 		return nil, fmt.Errorf("OMDb API returned an error response")
 	}
 
+	total, _ := strconv.ParseInt(searchResp.TotalResults, 10, 64)
 	if len(searchResp.Search) == 0 {
-		return []OMDbResponse{}, nil
+		return &SearchResult{Movies: []OMDbResponse{}, Total: total}, nil
 	}
 
 	// Cache full movie details for each search result
 	for _, item := range searchResp.Search {
-		// 1. Check if movie already exists
+		// 1. Skip movies whose full details are already cached
 		existing, _ := s.movieRepo.FindByIMDbID(item.IMDbID)
-		if existing != nil {
+		if existing != nil && existing.HasDetails() {
 			continue
 		}
 
-		// 2. Fetch FULL movie details
-		details, err := s.fetchMovieDetails(ctx, item.IMDbID)
-		if err != nil {
-			continue
-		}
-
-		// 3. Save FULL movie (genre INCLUDED)
-		movie := &models.Movie{
-			IMDbID:     details.IMDbID,
-			Title:      strings.TrimSpace(details.Title),
-			Year:       strings.TrimSpace(details.Year),
-			Genre:      strings.TrimSpace(details.Genre),        // THIS WAS MISSING
-			Director:   strings.TrimSpace(details.Director),
-			Plot:       strings.TrimSpace(details.Plot),
-			Poster:     strings.TrimSpace(details.Poster),
-			Runtime:    strings.TrimSpace(details.Runtime),
-			IMDbRating: strings.TrimSpace(details.IMDbRating),
-			CachedAt:   time.Now(),
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
+		// Stop spending quota on detail lookups once the guard kicks in
+		if s.usageService.IsQuotaNearlyExhausted() {
+			break
 		}
 
-		_ = s.movieRepo.Create(movie)
+		// 2. Fetch and save FULL movie details (genre INCLUDED)
+		_ = s.cacheMovieDetails(ctx, item.IMDbID, ChangedByRequest)
 	}
 
-	return searchResp.Search, nil
+	return &SearchResult{Movies: searchResp.Search, Total: total}, nil
 }
 
-// Helper method to fetch movie details by IMDb ID
-func (s *MovieService) fetchMovieDetails(ctx context.Context, imdbID string) (*OMDbResponse, error) {
-	// URL encode the IMDb ID for safe HTTP requests
-	encodedIMDbID := url.QueryEscape(imdbID)
-	requestURL := fmt.Sprintf("http://www.omdbapi.com/?apikey=%s&i=%s", s.apiKey, encodedIMDbID)
+// omdbSearchRequest runs one OMDb title search, optionally restricted to a
+// release year (0 for any). API-level errors are left to the caller.
+func (s *MovieService) omdbSearchRequest(ctx context.Context, query string, page, year int) (*OMDbSearchResponse, error) {
+	if s.apiKey == "" {
+		return nil, fmt.Errorf("OMDb API key not configured")
+	}
+
+	// URL encode the query for safe HTTP requests
+	encodedQuery := url.QueryEscape(query)
+	requestURL := fmt.Sprintf("%s/?apikey=%s&s=%s&page=%d", s.baseURL, s.apiKey, encodedQuery, page)
+	if year > 0 {
+		requestURL += fmt.Sprintf("&type=movie&y=%d", year)
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	s.usageService.RecordRequest()
 	resp, err := s.client.Do(req)
 	if err != nil {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("failed to make request to OMDb API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("OMDb API returned status code: %d", resp.StatusCode)
 	}
 
-	var omdbResp OMDbResponse
+	var searchResp OMDbSearchResponse
 	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&omdbResp); err != nil {
+	if err := decoder.Decode(&searchResp); err != nil {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("failed to decode OMDb API response: %w", err)
 	}
+	return &searchResp, nil
+}
 
-	// Check for API-level errors
-	if omdbResp.Response == "False" {
-		if omdbResp.Error != "" {
-			return nil, fmt.Errorf("OMDb API error: %s", omdbResp.Error)
-		}
-		return nil, fmt.Errorf("OMDb API returned an error response")
+// cacheMovieDetails fetches full details through the metadata provider chain
+// and stores them, completing any stub document for the same IMDb ID
+func (s *MovieService) cacheMovieDetails(ctx context.Context, imdbID, changedBy string) error {
+	details, provider, err := s.metadata.FetchDetails(ctx, imdbID)
+	if err != nil {
+		return err
 	}
+	return s.storeMovieDetails(details, provider, changedBy)
+}
 
-	return &omdbResp, nil
+// storeMovieDetails caches full details served by provider, completing any
+// stub document, and records what changed on an existing document
+func (s *MovieService) storeMovieDetails(details *OMDbResponse, provider, changedBy string) error {
+	movie := &models.Movie{
+		IMDbID:     details.IMDbID,
+		Title:      strings.TrimSpace(details.Title),
+		Year:       strings.TrimSpace(details.Year),
+		Genre:      strings.TrimSpace(details.Genre),
+		Director:   strings.TrimSpace(details.Director),
+		Actors:     strings.TrimSpace(details.Actors),
+		Plot:       strings.TrimSpace(details.Plot),
+		Poster:     strings.TrimSpace(details.Poster),
+		Runtime:    strings.TrimSpace(details.Runtime),
+		IMDbRating: strings.TrimSpace(details.IMDbRating),
+		Language:   strings.TrimSpace(details.Language),
+		Rated:      strings.TrimSpace(details.Rated),
+		Source:     provider,
+		MetadataProvider: provider,
+	}
+	previous, err := s.movieRepo.UpsertDetails(movie)
+	if err != nil {
+		return err
+	}
+	s.history.Record(previous, movieDetailValues(movie), changedBy)
+	return nil
 }
 
-func (s *MovieService) GetMovieDetails(ctx context.Context, imdbID string) (*models.Movie, error) {
-	// Validate IMDb ID format
-	if strings.TrimSpace(imdbID) == "" {
-		return nil, fmt.Errorf("IMDb ID cannot be empty")
+// UpsertFromSearchResult makes sure a movie exists for an OMDb search result so
+// it can be referenced immediately. When only a stub could be stored, a job is
+// queued to fetch the full details.
+func (s *MovieService) UpsertFromSearchResult(result OMDbResponse) (*models.Movie, error) {
+	imdbID := models.NormalizeIMDbID(result.IMDbID)
+	if !imdbIDPattern.MatchString(imdbID) {
+		return nil, fmt.Errorf("invalid IMDb ID: %q", result.IMDbID)
+	}
+	if strings.TrimSpace(result.Title) == "" {
+		return nil, fmt.Errorf("movie title is required")
 	}
 
-	// Check cache first
-	if movie, err := s.movieRepo.FindByIMDbID(imdbID); err == nil {
-		return movie, nil
+	movie, err := s.movieRepo.UpsertStub(&models.Movie{
+		IMDbID:     imdbID,
+		Title:      strings.TrimSpace(result.Title),
+		Year:       strings.TrimSpace(result.Year),
+		Genre:      strings.TrimSpace(result.Genre),
+		Director:   strings.TrimSpace(result.Director),
+		Plot:       strings.TrimSpace(result.Plot),
+		Poster:     strings.TrimSpace(result.Poster),
+		Runtime:    strings.TrimSpace(result.Runtime),
+		IMDbRating: strings.TrimSpace(result.IMDbRating),
+		Language:   strings.TrimSpace(result.Language),
+		Rated:      strings.TrimSpace(result.Rated),
+		Source:     models.MovieSourceOMDb,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store movie: %w", err)
 	}
 
-	if s.apiKey == "" {
-		return nil, fmt.Errorf("OMDb API key not configured")
+	if !movie.HasDetails() {
+		if err := s.jobQueue.Enqueue(jobs.TypeRefreshMovieMetadata, map[string]interface{}{"imdb_id": imdbID}); err != nil {
+			s.logger.Warn("failed to queue details fetch", "imdb_id", imdbID, "error", err)
+		}
 	}
 
+	return movie, nil
+}
+
+// RefreshMetadataJob is the job handler that fetches and caches full details
+// for the movie in the payload's imdb_id. While every provider fails, the
+// cached stub is kept and the job is retried.
+func (s *MovieService) RefreshMetadataJob(ctx context.Context, payload map[string]interface{}) error {
+	imdbID, _ := payload["imdb_id"].(string)
+	if imdbID == "" {
+		return fmt.Errorf("payload is missing imdb_id")
+	}
+
+	return s.cacheMovieDetails(ctx, imdbID, changedByJob(jobs.TypeRefreshMovieMetadata))
+}
+
+// Helper method to fetch movie details by IMDb ID
+func (s *MovieService) fetchMovieDetails(ctx context.Context, imdbID string) (*OMDbResponse, error) {
 	// URL encode the IMDb ID for safe HTTP requests
 	encodedIMDbID := url.QueryEscape(imdbID)
-	requestURL := fmt.Sprintf("http://www.omdbapi.com/?apikey=%s&i=%s", s.apiKey, encodedIMDbID)
+	requestURL := fmt.Sprintf("%s/?apikey=%s&i=%s", s.baseURL, s.apiKey, encodedIMDbID)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	s.usageService.RecordRequest()
 	resp, err := s.client.Do(req)
 	if err != nil {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("failed to make request to OMDb API: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// OMDb answers 401 with "Request limit reached!" once the key's quota is spent
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests {
+		s.usageService.RecordError()
+		return nil, fmt.Errorf("OMDb API returned status code %d: %w", resp.StatusCode, ErrProviderRateLimited)
+	}
 	if resp.StatusCode != http.StatusOK {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("OMDb API returned status code: %d", resp.StatusCode)
 	}
 
 	var omdbResp OMDbResponse
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&omdbResp); err != nil {
+		s.usageService.RecordError()
 		return nil, fmt.Errorf("failed to decode OMDb API response: %w", err)
 	}
 
 	// Check for API-level errors
 	if omdbResp.Response == "False" {
+		if strings.Contains(omdbResp.Error, "limit") {
+			return nil, fmt.Errorf("OMDb API error: %s: %w", omdbResp.Error, ErrProviderRateLimited)
+		}
+		if omdbMissingErrors[omdbResp.Error] {
+			return nil, fmt.Errorf("OMDb API error: %s: %w", omdbResp.Error, ErrMovieNotFound)
+		}
 		if omdbResp.Error != "" {
 			return nil, fmt.Errorf("OMDb API error: %s", omdbResp.Error)
 		}
 		return nil, fmt.Errorf("OMDb API returned an error response")
 	}
 
-	// Validate required fields
-	if omdbResp.IMDbID == "" {
-		return nil, fmt.Errorf("invalid movie data: missing IMDb ID")
-	}
-	if omdbResp.Title == "" {
-		return nil, fmt.Errorf("invalid movie data: missing title")
-	}
+	return &omdbResp, nil
+}
 
-	movie := &models.Movie{
-		ID:         primitive.NewObjectID(),
-		IMDbID:     omdbResp.IMDbID,
-		Title:      strings.TrimSpace(omdbResp.Title),
-		Year:       strings.TrimSpace(omdbResp.Year),
-		Genre:      strings.TrimSpace(omdbResp.Genre),
-		Director:   strings.TrimSpace(omdbResp.Director),
-		Plot:       strings.TrimSpace(omdbResp.Plot),
-		Poster:     strings.TrimSpace(omdbResp.Poster),
-		Runtime:    strings.TrimSpace(omdbResp.Runtime),
-		IMDbRating: strings.TrimSpace(omdbResp.IMDbRating),
-		CachedAt:   time.Now(),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+// GetMovieDetails returns the cached movie, fetching and caching it through
+// the metadata provider chain when it is not cached yet
+func (s *MovieService) GetMovieDetails(ctx context.Context, imdbID string) (*models.Movie, error) {
+	// Validate IMDb ID format
+	if strings.TrimSpace(imdbID) == "" {
+		return nil, fmt.Errorf("IMDb ID cannot be empty")
 	}
+	return s.getOrFetch(ctx, imdbID)
+}
 
-	if err := s.movieRepo.Create(movie); err != nil {
-		return nil, fmt.Errorf("failed to cache movie data: %w", err)
+// GetMovieByID returns the movie annotated with the freshness of its details
+func (s *MovieService) GetMovieByID(id primitive.ObjectID) (*models.Movie, error) {
+	movie, err := s.movieRepo.FindByID(id)
+	if err != nil || movie == nil {
+		return movie, err
 	}
-
+	annotateFreshness(movie, time.Now().UTC())
 	return movie, nil
 }
 
-func (s *MovieService) GetMovieByID(id primitive.ObjectID) (*models.Movie, error) {
-	return s.movieRepo.FindByID(id)
+// GetMoviesByIDs fetches movies in bulk, keyed by ID
+func (s *MovieService) GetMoviesByIDs(ids []primitive.ObjectID) (map[primitive.ObjectID]models.Movie, error) {
+	return s.movieRepo.FindByIDs(ids)
 }
 
-// GetOrCreateByIMDbID fetches movie by IMDb ID, creating from OMDb if not found
+// GetOrCreateByIMDbID fetches movie by IMDb ID, creating it through the
+// metadata provider chain if not found
 func (s *MovieService) GetOrCreateByIMDbID(imdbID string) (*models.Movie, error) {
-	movie, err := s.movieRepo.GetOrCreateByIMDbID(imdbID)
+	return s.getOrFetch(context.Background(), imdbID)
+}
+
+// getOrFetch returns the cached movie or fetches, stores and returns it. The
+// cache is the last link of the failover chain: whatever is cached, even a
+// stub, is served without asking a provider.
+func (s *MovieService) getOrFetch(ctx context.Context, imdbID string) (*models.Movie, error) {
+	imdbID = models.NormalizeIMDbID(imdbID)
+	movie, err := s.movieRepo.FindByIMDbID(imdbID)
 	if err != nil {
 		return nil, err
 	}
+
+	if movie == nil {
+		details, provider, err := s.metadata.FetchDetails(ctx, imdbID)
+		if err != nil {
+			return nil, err
+		}
+		if details.Title == "" {
+			return nil, fmt.Errorf("%w: invalid movie data: missing title", ErrMetadataUnavailable)
+		}
+		details.IMDbID = imdbID
+		if err := s.storeMovieDetails(details, provider, ChangedByRequest); err != nil {
+			return nil, fmt.Errorf("failed to cache movie data: %w", err)
+		}
+		if movie, err = s.movieRepo.FindByIMDbID(imdbID); err != nil {
+			return nil, err
+		}
+		if movie == nil {
+			return nil, fmt.Errorf("failed to cache movie data")
+		}
+	}
+
+	annotateFreshness(movie, time.Now().UTC())
 	return movie, nil
 }
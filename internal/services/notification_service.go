@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/mailer"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationService struct {
+	notificationRepo *repositories.NotificationRepository
+	userRepo         *repositories.UserRepository
+	watchlistRepo    *repositories.WatchlistRepository
+	ratingRepo       *repositories.RatingRepository
+	movieRepo        *repositories.MovieRepository
+	mailer           mailer.Mailer
+	emailReminders   bool
+	logger           *slog.Logger
+}
+
+func NewNotificationService(notificationRepo *repositories.NotificationRepository, userRepo *repositories.UserRepository, watchlistRepo *repositories.WatchlistRepository, ratingRepo *repositories.RatingRepository, movieRepo *repositories.MovieRepository, mailer mailer.Mailer, emailReminders bool) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		watchlistRepo:    watchlistRepo,
+		ratingRepo:       ratingRepo,
+		movieRepo:        movieRepo,
+		mailer:           mailer,
+		emailReminders:   emailReminders,
+		logger:           logging.For("services.notifications"),
+	}
+}
+
+// Subscribe greets new accounts with a welcome notification
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameUserRegistered, "notifications", func(event events.Event) error {
+		registered := event.(events.UserRegistered)
+		return s.notificationRepo.Create(&models.Notification{
+			UserID:  registered.UserID,
+			Type:    models.NotificationWelcome,
+			Title:   "Welcome!",
+			Message: fmt.Sprintf("Hi %s! Add movies to your watchlist and rate the ones you have seen to get recommendations.", registered.Username),
+		})
+	})
+}
+
+// GetUserNotificationsPage returns one page of the user's notifications and the total count
+func (s *NotificationService) GetUserNotificationsPage(userID primitive.ObjectID, unreadOnly bool, offset, limit int) ([]models.Notification, int64, error) {
+	return s.notificationRepo.GetUserNotificationsPage(userID, unreadOnly, int64(offset), int64(limit))
+}
+
+// GetNotification returns the notification, or nil when it does not exist.
+// Callers check ownership with the authz policy.
+func (s *NotificationService) GetNotification(notificationID primitive.ObjectID) (*models.Notification, error) {
+	return s.notificationRepo.FindByID(notificationID)
+}
+
+func (s *NotificationService) MarkRead(userID, notificationID primitive.ObjectID) error {
+	found, err := s.notificationRepo.MarkRead(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("notification not found")
+	}
+	return nil
+}
+
+// RatingReminderJob is the job handler that nudges the user in the payload
+// to rate a movie they marked watched, unless they rated it in the meantime
+func (s *NotificationService) RatingReminderJob(ctx context.Context, payload map[string]interface{}) error {
+	userHex, _ := payload["user_id"].(string)
+	movieHex, _ := payload["movie_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid user_id %q", userHex)
+	}
+	movieID, err := primitive.ObjectIDFromHex(movieHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid movie_id %q", movieHex)
+	}
+
+	// Deactivated accounts are not reminded
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user != nil && user.DeactivatedAt != nil {
+		return nil
+	}
+
+	// Skip if the movie was removed or unmarked since
+	entry, err := s.watchlistRepo.FindEntry(userID, movieID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.WatchedAt == nil {
+		return nil
+	}
+
+	_, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return err
+	}
+	rating, err := s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
+	if err != nil {
+		return err
+	}
+	if rating != nil {
+		return nil
+	}
+
+	// Marking a movie watched twice must not remind twice
+	exists, err := s.notificationRepo.ExistsForMovie(userID, models.NotificationRatingReminder, movieID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return err
+	}
+	if movie == nil {
+		return nil
+	}
+
+	notification := &models.Notification{
+		UserID:  userID,
+		Type:    models.NotificationRatingReminder,
+		Title:   fmt.Sprintf("How was %s?", movie.Title),
+		Message: fmt.Sprintf("You watched %s. Rate it to get better recommendations.", movie.Title),
+		MovieID: &movieID,
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		return err
+	}
+
+	if s.emailReminders {
+		s.sendEmail(userID, notification)
+	}
+	return nil
+}
+
+// sendEmail mirrors a notification by email; failures are logged only since
+// the in-app notification already exists
+func (s *NotificationService) sendEmail(userID primitive.ObjectID, notification *models.Notification) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		s.logger.Warn("failed to load user for notification email", "user_id", userID.Hex(), "error", err)
+		return
+	}
+
+	if err := s.mailer.Send(user.Email, notification.Title, notification.Message+"\n"); err != nil {
+		s.logger.Warn("failed to email notification", "user_id", userID.Hex(), "error", err)
+	}
+}
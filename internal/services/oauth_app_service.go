@@ -0,0 +1,420 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// oauthCodeTTL is how long an app has to exchange an authorization code
+	oauthCodeTTL = 5 * time.Minute
+	// maxOAuthApps caps the apps one user may register
+	maxOAuthApps = 10
+	// maxRedirectURIs caps the redirect URIs of one app
+	maxRedirectURIs = 5
+	// oauthClientIDLength is the length of a client ID in hex characters
+	oauthClientIDLength = 32
+	// oauthAppCheckInterval is how long a successful app check is reused,
+	// bounding how long a deleted app's own tokens keep working
+	oauthAppCheckInterval = time.Minute
+)
+
+// OAuthScope describes a scope to the user asked to grant it
+type OAuthScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthScopes are the scopes apps can be granted, in the order they are listed
+var OAuthScopes = []OAuthScope{
+	{models.ScopeWatchlistRead, "See your watchlist"},
+	{models.ScopeWatchlistWrite, "Add, change and remove movies on your watchlist"},
+	{models.ScopeRatingsRead, "See your ratings"},
+	{models.ScopeRatingsWrite, "Rate movies and change your ratings"},
+	{models.ScopeRecommendationsRead, "See your recommendations"},
+}
+
+var (
+	ErrOAuthAppNotFound = errors.New("app not found")
+	ErrOAuthAppLimit    = errors.New("app limit reached")
+	// ErrInvalidOAuthApp is returned for app registrations with a bad name,
+	// redirect URI or scope
+	ErrInvalidOAuthApp = errors.New("invalid app")
+	// ErrInvalidAuthorization is returned for authorization requests that
+	// do not match a registered app
+	ErrInvalidAuthorization = errors.New("invalid authorization request")
+	// ErrInvalidClient is returned for unknown client IDs and wrong secrets
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidGrant is returned for unknown, expired or used authorization
+	// codes, and codes sent with the wrong redirect URI or PKCE verifier
+	ErrInvalidGrant = errors.New("invalid authorization code")
+)
+
+// OAuthAuthorizationRequest is an app asking a user for access, as the
+// app's authorization URL describes it
+type OAuthAuthorizationRequest struct {
+	ClientID    string
+	RedirectURI string
+	// Scope is space-separated; empty asks for all of the app's scopes
+	Scope string
+	// CodeChallenge and CodeChallengeMethod are the optional PKCE challenge;
+	// only S256 is supported
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthAuthorization is a checked authorization request, for the page that
+// asks the user to approve it
+type OAuthAuthorization struct {
+	App    *models.OAuthApp `json:"app"`
+	Scopes []OAuthScope     `json:"scopes"`
+}
+
+// OAuthAppService registers third-party apps and grants them access to
+// users' data through OAuth 2.0: a user authorizes an app for some scopes
+// and the app exchanges the authorization code for tokens of its own
+// session, which the user or the app's owner can revoke.
+type OAuthAppService struct {
+	appRepo        *repositories.OAuthAppRepository
+	codeRepo       *repositories.OAuthCodeRepository
+	sessionService *SessionService
+	consentURL     string
+	checked        *checkCache // client IDs
+}
+
+// NewOAuthAppService returns the app service. consentURL is the web app page
+// that asks users to approve apps; without it apps send users to that page
+// themselves.
+func NewOAuthAppService(appRepo *repositories.OAuthAppRepository, codeRepo *repositories.OAuthCodeRepository, sessionService *SessionService, consentURL string) *OAuthAppService {
+	return &OAuthAppService{
+		appRepo:        appRepo,
+		codeRepo:       codeRepo,
+		sessionService: sessionService,
+		consentURL:     consentURL,
+		checked:        newCheckCache(oauthAppCheckInterval),
+	}
+}
+
+// ConsentURL returns the page /oauth/authorize sends users to, if any
+func (s *OAuthAppService) ConsentURL() string {
+	return s.consentURL
+}
+
+// Register creates an app for the user and returns it with its client
+// secret, which is not stored and cannot be shown again
+func (s *OAuthAppService) Register(userID primitive.ObjectID, name string, redirectURIs, scopes []string) (*models.OAuthApp, string, error) {
+	redirectURIs, scopes = nonNilStrings(redirectURIs), nonNilStrings(scopes)
+	if err := validateOAuthApp(name, redirectURIs, scopes); err != nil {
+		return nil, "", err
+	}
+	count, err := s.appRepo.CountByUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxOAuthApps {
+		return nil, "", fmt.Errorf("%w: at most %d apps per user", ErrOAuthAppLimit, maxOAuthApps)
+	}
+
+	clientID, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+	app := &models.OAuthApp{
+		UserID:           userID,
+		Name:             name,
+		ClientID:         clientID[:oauthClientIDLength],
+		ClientSecretHash: hashSecretToken(secret),
+		RedirectURIs:     redirectURIs,
+		Scopes:           scopes,
+	}
+	if err := s.appRepo.Create(app); err != nil {
+		return nil, "", err
+	}
+	return app, secret, nil
+}
+
+// List returns the apps the user registered
+func (s *OAuthAppService) List(userID primitive.ObjectID) ([]models.OAuthApp, error) {
+	apps, err := s.appRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if apps == nil {
+		apps = []models.OAuthApp{}
+	}
+	return apps, nil
+}
+
+// Get returns one of the user's apps
+func (s *OAuthAppService) Get(userID primitive.ObjectID, clientID string) (*models.OAuthApp, error) {
+	app, err := s.appRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil || app.UserID != userID {
+		return nil, ErrOAuthAppNotFound
+	}
+	return app, nil
+}
+
+// Update replaces the name, redirect URIs and scopes of one of the user's
+// apps. Sessions keep the scopes they were granted, but lose removed ones
+// the next time the app refreshes them.
+func (s *OAuthAppService) Update(userID primitive.ObjectID, clientID, name string, redirectURIs, scopes []string) (*models.OAuthApp, error) {
+	redirectURIs, scopes = nonNilStrings(redirectURIs), nonNilStrings(scopes)
+	if err := validateOAuthApp(name, redirectURIs, scopes); err != nil {
+		return nil, err
+	}
+	app, err := s.appRepo.Update(userID, clientID, name, redirectURIs, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, ErrOAuthAppNotFound
+	}
+	return app, nil
+}
+
+// RotateSecret gives one of the user's apps a new client secret; the old
+// one stops working
+func (s *OAuthAppService) RotateSecret(userID primitive.ObjectID, clientID string) (string, error) {
+	secret, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	app, err := s.appRepo.SetSecret(userID, clientID, hashSecretToken(secret))
+	if err != nil {
+		return "", err
+	}
+	if app == nil {
+		return "", ErrOAuthAppNotFound
+	}
+	return secret, nil
+}
+
+// Delete removes one of the user's apps and revokes all access it was given
+func (s *OAuthAppService) Delete(userID primitive.ObjectID, clientID string) error {
+	deleted, err := s.appRepo.Delete(userID, clientID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOAuthAppNotFound
+	}
+
+	s.checked.Delete(clientID)
+	if err := s.codeRepo.DeleteByClient(clientID); err != nil {
+		return err
+	}
+	_, err = s.sessionService.RevokeAppSessions(clientID)
+	return err
+}
+
+// Authenticate returns the app with the client ID if the secret is its own
+func (s *OAuthAppService) Authenticate(clientID, secret string) (*models.OAuthApp, error) {
+	if clientID == "" || secret == "" {
+		return nil, ErrInvalidClient
+	}
+	app, err := s.appRepo.FindByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil || subtle.ConstantTimeCompare([]byte(app.ClientSecretHash), []byte(hashSecretToken(secret))) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return app, nil
+}
+
+// IsActive reports whether the app still exists. Lookups are cached for
+// oauthAppCheckInterval.
+func (s *OAuthAppService) IsActive(clientID string) bool {
+	if s.checked.Fresh(clientID) {
+		return true
+	}
+
+	app, err := s.appRepo.FindByClientID(clientID)
+	if err != nil {
+		// Don't lock apps out while the database is unavailable
+		return true
+	}
+	if app == nil {
+		s.checked.Delete(clientID)
+		return false
+	}
+	s.checked.Store(clientID)
+	return true
+}
+
+// CheckAuthorization validates an authorization request against the app's
+// registration and returns what the user is asked to approve
+func (s *OAuthAppService) CheckAuthorization(req OAuthAuthorizationRequest) (*OAuthAuthorization, []string, error) {
+	app, err := s.appRepo.FindByClientID(req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if app == nil {
+		return nil, nil, fmt.Errorf("%w: unknown client_id", ErrInvalidAuthorization)
+	}
+	if !containsString(app.RedirectURIs, req.RedirectURI) {
+		return nil, nil, fmt.Errorf("%w: redirect_uri is not registered for the app", ErrInvalidAuthorization)
+	}
+
+	scopes := app.Scopes
+	if requested := strings.Fields(req.Scope); len(requested) > 0 {
+		for _, scope := range requested {
+			if !containsString(app.Scopes, scope) {
+				return nil, nil, fmt.Errorf("%w: the app may not ask for scope %q", ErrInvalidAuthorization, scope)
+			}
+		}
+		scopes = s.LimitScopes(app, requested)
+	}
+
+	if req.CodeChallenge != "" || req.CodeChallengeMethod != "" {
+		if req.CodeChallengeMethod != "S256" {
+			return nil, nil, fmt.Errorf("%w: code_challenge_method must be S256", ErrInvalidAuthorization)
+		}
+		if decoded, err := base64.RawURLEncoding.DecodeString(req.CodeChallenge); err != nil || len(decoded) != sha256.Size {
+			return nil, nil, fmt.Errorf("%w: code_challenge must be a base64url SHA-256 hash", ErrInvalidAuthorization)
+		}
+	}
+
+	described := make([]OAuthScope, 0, len(scopes))
+	for _, scope := range OAuthScopes {
+		if containsString(scopes, scope.Name) {
+			described = append(described, scope)
+		}
+	}
+	return &OAuthAuthorization{App: app, Scopes: described}, scopes, nil
+}
+
+// Authorize records that the user approved an authorization request and
+// returns the code for the app to exchange
+func (s *OAuthAppService) Authorize(userID primitive.ObjectID, req OAuthAuthorizationRequest) (string, error) {
+	_, scopes, err := s.CheckAuthorization(req)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.codeRepo.Create(&models.OAuthCode{
+		CodeHash:      hashSecretToken(code),
+		ClientID:      req.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(oauthCodeTTL),
+	}); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeCode turns an authorization code the app was given into a
+// session of the app with the user, returned with its refresh token
+func (s *OAuthAppService) ExchangeCode(app *models.OAuthApp, code, redirectURI, codeVerifier, userAgent, ip string) (*models.Session, string, error) {
+	grant, err := s.codeRepo.Take(hashSecretToken(code))
+	if err != nil {
+		return nil, "", err
+	}
+	if grant == nil || grant.ClientID != app.ClientID || grant.RedirectURI != redirectURI {
+		return nil, "", ErrInvalidGrant
+	}
+	if grant.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(grant.CodeChallenge)) != 1 {
+			return nil, "", ErrInvalidGrant
+		}
+	}
+
+	return s.sessionService.CreateAppSession(grant.UserID, app.ClientID, s.LimitScopes(app, grant.Scopes), userAgent, ip)
+}
+
+// LimitScopes returns the scopes the app may still be given, in the order
+// they are listed
+func (s *OAuthAppService) LimitScopes(app *models.OAuthApp, scopes []string) []string {
+	limited := []string{}
+	for _, scope := range OAuthScopes {
+		if containsString(scopes, scope.Name) && containsString(app.Scopes, scope.Name) {
+			limited = append(limited, scope.Name)
+		}
+	}
+	return limited
+}
+
+// validateOAuthApp checks an app's registration. Apps without redirect URIs
+// can only use client credentials.
+func validateOAuthApp(name string, redirectURIs, scopes []string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOAuthApp)
+	}
+	if len(redirectURIs) > maxRedirectURIs {
+		return fmt.Errorf("%w: at most %d redirect URIs", ErrInvalidOAuthApp, maxRedirectURIs)
+	}
+	for _, uri := range redirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+	for _, scope := range scopes {
+		if !isOAuthScope(scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidOAuthApp, scope)
+		}
+	}
+	return nil
+}
+
+// nonNilStrings stores missing lists as empty ones
+func nonNilStrings(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func isOAuthScope(name string) bool {
+	for _, scope := range OAuthScopes {
+		if scope.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRedirectURI allows absolute https URLs, and http ones on the
+// loopback interface for apps in development
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("%w: redirect URI %q must be an absolute URL without a fragment", ErrInvalidOAuthApp, uri)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	if u.Scheme == "http" {
+		if host := u.Hostname(); host == "localhost" {
+			return nil
+		} else if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: redirect URI %q must use https, or http on localhost", ErrInvalidOAuthApp, uri)
+}
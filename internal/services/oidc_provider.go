@@ -0,0 +1,198 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"movie-watchlist/internal/models"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// idTokenTTL is how long an ID token is valid. Apps keep using the access
+// and refresh tokens after it expires; the ID token only proves who signed in.
+const idTokenTTL = time.Hour
+
+// oidcScopes are the scopes the token endpoint grants, in the order they are
+// reported
+var oidcScopes = []string{"openid", "profile", "email"}
+
+// OIDCUserInfo is the claims about a user returned by the userinfo endpoint
+// and carried in ID tokens
+type OIDCUserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	Zoneinfo          string `json:"zoneinfo"`
+}
+
+type idTokenClaims struct {
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	SessionID         string           `json:"sid,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	Zoneinfo          string           `json:"zoneinfo,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider issues OpenID Connect ID tokens to companion apps and
+// describes itself in the metadata and keys standard client libraries fetch.
+// Access and refresh tokens are the API's usual session tokens.
+type OIDCProvider struct {
+	issuer    string
+	clientIDs []string
+	key       *rsa.PrivateKey
+	keyID     string
+}
+
+// NewOIDCProvider creates a provider for issuer, the API's public base URL.
+// It is disabled when clientIDs is empty.
+func NewOIDCProvider(issuer string, clientIDs []string, key *rsa.PrivateKey) *OIDCProvider {
+	p := &OIDCProvider{issuer: issuer, clientIDs: clientIDs, key: key}
+	if key != nil {
+		der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		sum := sha256.Sum256(der)
+		p.keyID = base64.RawURLEncoding.EncodeToString(sum[:12])
+	}
+	return p
+}
+
+// LoadOIDCSigningKey reads an RSA private key from a PEM file in PKCS #1 or
+// PKCS #8 form. With an empty path it generates a key that lasts until the
+// process exits, so ID tokens stop verifying after a restart.
+func LoadOIDCSigningKey(path string) (*rsa.PrivateKey, error) {
+	if path == "" {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("OIDC signing key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("OIDC signing key must be an RSA key")
+	}
+	return key, nil
+}
+
+// Enabled reports whether any client may use the provider
+func (p *OIDCProvider) Enabled() bool {
+	return len(p.clientIDs) > 0 && p.key != nil
+}
+
+// AllowsClient reports whether clientID is a registered companion app
+func (p *OIDCProvider) AllowsClient(clientID string) bool {
+	return clientID != "" && containsString(p.clientIDs, clientID)
+}
+
+// GrantedScopes returns the supported scopes out of the space-separated
+// requested ones, or all of them when none were requested
+func (p *OIDCProvider) GrantedScopes(requested string) []string {
+	fields := strings.Fields(requested)
+	if len(fields) == 0 {
+		return oidcScopes
+	}
+	granted := []string{}
+	for _, scope := range oidcScopes {
+		if containsString(fields, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
+
+// UserInfo returns the claims about the user
+func (p *OIDCProvider) UserInfo(user *models.User) OIDCUserInfo {
+	return OIDCUserInfo{
+		Subject:           user.ID.Hex(),
+		PreferredUsername: user.Username,
+		Email:             user.Email,
+		Zoneinfo:          locationOf(user.Timezone).String(),
+	}
+}
+
+// IDToken signs an ID token for the user, issued to clientID. The session
+// is the one the app's access and refresh tokens belong to; it started when
+// the user entered their password.
+func (p *OIDCProvider) IDToken(user *models.User, clientID string, session *models.Session) (string, error) {
+	info := p.UserInfo(user)
+	now := time.Now()
+	claims := &idTokenClaims{
+		AuthTime:          jwt.NewNumericDate(session.CreatedAt),
+		SessionID:         session.ID.Hex(),
+		PreferredUsername: info.PreferredUsername,
+		Email:             info.Email,
+		Zoneinfo:          info.Zoneinfo,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.issuer,
+			Subject:   info.Subject,
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign ID token: %w", err)
+	}
+	return signed, nil
+}
+
+// JWKS returns the JSON Web Key Set with the public key ID tokens are
+// signed with
+func (p *OIDCProvider) JWKS() map[string]interface{} {
+	return map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.PublicKey.E)).Bytes()),
+		}},
+	}
+}
+
+// Discovery returns the OpenID provider metadata. There is no authorization
+// endpoint for companion apps: they exchange the user's password, then
+// refresh tokens, at the token endpoint. Registered third-party apps use the
+// authorization code and client credentials grants with their secret.
+func (p *OIDCProvider) Discovery() map[string]interface{} {
+	return map[string]interface{}{
+		"issuer":                                p.issuer,
+		"token_endpoint":                        p.issuer + "/oauth/token",
+		"userinfo_endpoint":                     p.issuer + "/oauth/userinfo",
+		"jwks_uri":                              p.issuer + "/oauth/jwks",
+		"grant_types_supported":                 []string{"password", "refresh_token", "authorization_code", "client_credentials"},
+		"response_types_supported":              []string{},
+		"scopes_supported":                      oidcScopes,
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_basic", "client_secret_post"},
+		"claims_supported":                      []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "sid", "preferred_username", "email", "zoneinfo"},
+	}
+}
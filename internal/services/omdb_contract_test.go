@@ -0,0 +1,191 @@
+//go:build integration
+
+package services_test
+
+import (
+	"context"
+	"errors"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"movie-watchlist/internal/services"
+	"movie-watchlist/internal/testutil"
+	"testing"
+)
+
+// These contract tests replay the recorded OMDb responses in fixtures/omdb
+// through MovieService, so a change in how the service reads OMDb's shapes
+// shows up without calling OMDb. Record new fixtures with
+// OMDB_FIXTURES=record when OMDb changes.
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// newReplayMovieService returns a MovieService whose OMDb client answers from
+// the fixtures and never touches the network
+func newReplayMovieService(t *testing.T) (*services.MovieService, *database.MongoDB) {
+	t.Helper()
+
+	db := testutil.Database(t)
+	movieRepo := repositories.NewMovieRepository(db)
+	usageService := services.NewOMDbUsageService(repositories.NewOMDbUsageRepository(db), 0)
+	history := services.NewMovieHistoryService(repositories.NewMovieRevisionRepository(db))
+	jobQueue := jobs.NewQueue(repositories.NewJobRepository(db), 1)
+	transport := services.NewOMDbFixtureTransport(services.OMDbFixturesReplay, testutil.FixturesDir())
+
+	movieService := services.NewMovieService(movieRepo, usageService, history, jobQueue, "replay", "http://omdb.invalid", transport, []string{models.MovieSourceOMDb})
+	return movieService, db
+}
+
+func TestOMDbContractMovieDetails(t *testing.T) {
+	movieService, _ := newReplayMovieService(t)
+
+	movie, err := movieService.GetMovieDetails(context.Background(), "tt1375666")
+	if err != nil {
+		t.Fatalf("GetMovieDetails: %v", err)
+	}
+
+	got := []string{movie.IMDbID, movie.Title, movie.Year, movie.Genre, movie.Director, movie.Runtime, movie.IMDbRating, movie.Language, movie.Rated}
+	want := []string{"tt1375666", "Inception", "2010", "Action, Adventure, Sci-Fi", "Christopher Nolan", "148 min", "8.8", "English, Japanese, French", "PG-13"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("movie fields = %q, want %q", got, want)
+			break
+		}
+	}
+	if movie.MetadataProvider != models.MovieSourceOMDb {
+		t.Errorf("metadata provider = %q, want %q", movie.MetadataProvider, models.MovieSourceOMDb)
+	}
+	if !movie.HasDetails() {
+		t.Error("movie has no details")
+	}
+}
+
+func TestOMDbContractNotApplicableFields(t *testing.T) {
+	movieService, _ := newReplayMovieService(t)
+
+	// Carmencita has no poster, writer or awards, and "None" for a language
+	movie, err := movieService.GetMovieDetails(context.Background(), "tt0000001")
+	if err != nil {
+		t.Fatalf("GetMovieDetails: %v", err)
+	}
+
+	// "N/A" is stored as OMDb sends it; readers such as the poster service
+	// treat it as missing
+	if movie.Poster != "N/A" {
+		t.Errorf("poster = %q, want N/A", movie.Poster)
+	}
+	if movie.Title != "Carmencita" || movie.Year != "1894" || movie.Runtime != "1 min" {
+		t.Errorf("movie = %q (%s, %s), want Carmencita (1894, 1 min)", movie.Title, movie.Year, movie.Runtime)
+	}
+	if movie.Rated != "Not Rated" || movie.Language != "None" {
+		t.Errorf("rated %q, language %q; want Not Rated, None", movie.Rated, movie.Language)
+	}
+	if !movie.HasDetails() {
+		t.Error("movie with N/A fields has no details")
+	}
+}
+
+func TestOMDbContractSeries(t *testing.T) {
+	movieService, _ := newReplayMovieService(t)
+
+	movie, err := movieService.GetMovieDetails(context.Background(), "tt0903747")
+	if err != nil {
+		t.Fatalf("GetMovieDetails: %v", err)
+	}
+
+	if movie.Title != "Breaking Bad" || movie.Rated != "TV-MA" {
+		t.Errorf("series = %q rated %q, want Breaking Bad rated TV-MA", movie.Title, movie.Rated)
+	}
+	// Series have a year range and no director
+	if start, end := models.ParseYearRange(movie.Year); start != 2008 || end != 2013 {
+		t.Errorf("year %q parses as %d-%d, want 2008-2013", movie.Year, start, end)
+	}
+	if movie.Director != "N/A" {
+		t.Errorf("director = %q, want N/A", movie.Director)
+	}
+}
+
+func TestOMDbContractIncorrectIMDbID(t *testing.T) {
+	movieService, db := newReplayMovieService(t)
+
+	_, err := movieService.GetMovieDetails(context.Background(), "tt9999999999")
+	if !errors.Is(err, services.ErrMovieNotFound) {
+		t.Fatalf("error = %v, want ErrMovieNotFound", err)
+	}
+	if errors.Is(err, services.ErrMetadataUnavailable) {
+		t.Errorf("error = %v, a missing title is not an outage", err)
+	}
+
+	movie, err := repositories.NewMovieRepository(db).FindByIMDbID("tt9999999999")
+	if err != nil {
+		t.Fatalf("FindByIMDbID: %v", err)
+	}
+	if movie != nil {
+		t.Error("a title OMDb does not know was cached")
+	}
+}
+
+func TestOMDbContractSearchNotFound(t *testing.T) {
+	movieService, _ := newReplayMovieService(t)
+
+	// OMDb answers a search without hits with Response "False" and
+	// "Movie not found!", which is an empty page rather than an error
+	result, err := movieService.SearchMovies(context.Background(), "qwxzv no such title", 1, nil)
+	if err != nil {
+		t.Fatalf("SearchMovies: %v", err)
+	}
+	if len(result.Movies) != 0 || result.Total != 0 {
+		t.Errorf("got %d movies (total %d), want none", len(result.Movies), result.Total)
+	}
+	if result.CacheOnly {
+		t.Error("search fell back to the cache")
+	}
+}
+
+func TestOMDbContractSearchMixesMoviesAndSeries(t *testing.T) {
+	movieService, db := newReplayMovieService(t)
+
+	result, err := movieService.SearchMovies(context.Background(), "Breaking Bad", 1, nil)
+	if err != nil {
+		t.Fatalf("SearchMovies: %v", err)
+	}
+	if result.Total != 3 {
+		t.Errorf("total = %d, want 3", result.Total)
+	}
+
+	found := map[string]services.OMDbResponse{}
+	for _, hit := range result.Movies {
+		found[hit.IMDbID] = hit
+	}
+	for _, imdbID := range []string{"tt0903747", "tt9243946", "tt7476944"} {
+		if _, ok := found[imdbID]; !ok {
+			t.Errorf("search results lack %s", imdbID)
+		}
+	}
+	if poster := found["tt9243946"].Poster; poster != "N/A" {
+		t.Errorf("poster of El Camino = %q, want N/A", poster)
+	}
+
+	// Search caches the details of its hits; only the series has a fixture,
+	// and the hits without one are skipped
+	series, err := repositories.NewMovieRepository(db).FindByIMDbID("tt0903747")
+	if err != nil {
+		t.Fatalf("FindByIMDbID: %v", err)
+	}
+	if series == nil || !series.HasDetails() {
+		t.Errorf("series details were not cached: %+v", series)
+	}
+}
+
+func TestOMDbContractReplayWithoutFixture(t *testing.T) {
+	movieService, _ := newReplayMovieService(t)
+
+	// A request that was never recorded fails like an unreachable OMDb
+	_, err := movieService.GetMovieDetails(context.Background(), "tt0000002")
+	if !errors.Is(err, services.ErrMetadataUnavailable) {
+		t.Errorf("error = %v, want ErrMetadataUnavailable", err)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// OMDb fixture modes
+const (
+	OMDbFixturesRecord = "record"
+	OMDbFixturesReplay = "replay"
+)
+
+// omdbFixture is one recorded OMDb response. Request is the query it
+// answers, without the API key.
+type omdbFixture struct {
+	Request string          `json:"request"`
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// OMDbFixtureTransport sits between the OMDb client and the network. In
+// record mode it passes requests through and saves each successful response
+// to a JSON file named after the query; in replay mode it answers from those
+// files and never touches the network, so real response shapes, including
+// "N/A" fields, series and error payloads, can be exercised offline and
+// without spending quota.
+type OMDbFixtureTransport struct {
+	mode   string
+	dir    string
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+func NewOMDbFixtureTransport(mode, dir string) *OMDbFixtureTransport {
+	return &OMDbFixtureTransport{
+		mode:   mode,
+		dir:    dir,
+		next:   http.DefaultTransport,
+		logger: logging.For("services.omdb_fixtures"),
+	}
+}
+
+func (t *OMDbFixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := omdbFixtureQuery(req)
+	path := filepath.Join(t.dir, query+".json")
+
+	if t.mode == OMDbFixturesReplay {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no recorded OMDb fixture for %q in %s", query, t.dir)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fixture omdbFixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid OMDb fixture %s: %w", path, err)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+			StatusCode:    fixture.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+			Body:          io.NopCloser(bytes.NewReader(fixture.Body)),
+			ContentLength: int64(len(fixture.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Only 200s are kept: OMDb reports missing titles and bad IDs in a 200
+	// body, while other statuses are quota or key problems that would
+	// poison the fixture set
+	if resp.StatusCode == http.StatusOK && json.Valid(body) {
+		if err := t.save(path, omdbFixture{Request: query, Status: resp.StatusCode, Body: body}); err != nil {
+			// The caller still gets the live response
+			t.logger.Warn("failed to record OMDb fixture", "request", query, "error", err)
+		}
+	}
+	return resp, nil
+}
+
+func (t *OMDbFixtureTransport) save(path string, fixture omdbFixture) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(fixture); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data.Bytes(), 0o644)
+}
+
+// omdbFixtureQuery is the request's query without the API key, with the
+// parameters sorted, e.g. "page=1&s=Inception"
+func omdbFixtureQuery(req *http.Request) string {
+	params := req.URL.Query()
+	params.Del("apikey")
+	return params.Encode()
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+)
+
+// quotaGuardThreshold is the fraction of the daily OMDb quota after which
+// search falls back to the local cache only
+const quotaGuardThreshold = 0.9
+
+type OMDbUsageSummary struct {
+	DailyLimit     int64              `json:"daily_limit"`
+	TodayCount     int64              `json:"today_count"`
+	Remaining      int64              `json:"remaining"`
+	GuardThreshold int64              `json:"guard_threshold"`
+	CacheOnlyMode  bool               `json:"cache_only_mode"`
+	History        []models.OMDbUsage `json:"history"`
+}
+
+type OMDbUsageService struct {
+	usageRepo  *repositories.OMDbUsageRepository
+	dailyLimit int64
+	logger     *slog.Logger
+}
+
+func NewOMDbUsageService(usageRepo *repositories.OMDbUsageRepository, dailyLimit int) *OMDbUsageService {
+	return &OMDbUsageService{
+		usageRepo:  usageRepo,
+		dailyLimit: int64(dailyLimit),
+		logger:     logging.For("services.omdb"),
+	}
+}
+
+// RecordRequest counts a single outbound OMDb request; failures are logged, not returned
+func (s *OMDbUsageService) RecordRequest() {
+	if err := s.usageRepo.RecordRequest(); err != nil {
+		s.logger.Warn("failed to record OMDb usage", "error", err)
+	}
+}
+
+// RecordError counts a failed OMDb request; failures are logged, not returned
+func (s *OMDbUsageService) RecordError() {
+	if err := s.usageRepo.RecordError(); err != nil {
+		s.logger.Warn("failed to record OMDb error", "error", err)
+	}
+}
+
+// IsQuotaNearlyExhausted reports whether today's usage has reached the guard threshold
+func (s *OMDbUsageService) IsQuotaNearlyExhausted() bool {
+	if s.dailyLimit <= 0 {
+		return false
+	}
+
+	count, err := s.usageRepo.GetTodayCount()
+	if err != nil {
+		s.logger.Warn("failed to read OMDb usage", "error", err)
+		return false
+	}
+
+	return count >= s.guardThreshold()
+}
+
+// GetUsageSummary returns today's usage against the quota plus recent daily history
+func (s *OMDbUsageService) GetUsageSummary(days int) (*OMDbUsageSummary, error) {
+	count, err := s.usageRepo.GetTodayCount()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.usageRepo.GetRecentUsage(days)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.OMDbUsage{}
+	}
+
+	remaining := s.dailyLimit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &OMDbUsageSummary{
+		DailyLimit:     s.dailyLimit,
+		TodayCount:     count,
+		Remaining:      remaining,
+		GuardThreshold: s.guardThreshold(),
+		CacheOnlyMode:  s.dailyLimit > 0 && count >= s.guardThreshold(),
+		History:        history,
+	}, nil
+}
+
+func (s *OMDbUsageService) guardThreshold() int64 {
+	return int64(float64(s.dailyLimit) * quotaGuardThreshold)
+}
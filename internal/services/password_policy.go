@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"movie-watchlist/internal/logging"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxPasswordBytes is the bcrypt input limit; longer passwords would be silently truncated
+const maxPasswordBytes = 72
+
+// breachCheckTimeout bounds how long registration waits for the breach checker
+const breachCheckTimeout = 3 * time.Second
+
+// BreachChecker reports whether a password is known from public data breaches
+type BreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordPolicyError lists every rule a password violates
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the password policy: " + strings.Join(e.Problems, "; ")
+}
+
+// PasswordPolicy is applied to new passwords
+type PasswordPolicy struct {
+	MinLength int
+	// Checker is optional; when set, breached passwords are rejected
+	Checker BreachChecker
+}
+
+// Check validates the password against the policy, returning a
+// *PasswordPolicyError listing every violated rule
+func (p *PasswordPolicy) Check(password, username, email string) error {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", maxPasswordBytes))
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		problems = append(problems, "is too common")
+	}
+	if lower == strings.ToLower(username) || lower == strings.ToLower(email) || lower == strings.ToLower(strings.SplitN(email, "@", 2)[0]) {
+		problems = append(problems, "must not match the username or email")
+	}
+
+	// Only consult the breach checker for passwords that pass the local rules
+	if len(problems) == 0 && p.Checker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), breachCheckTimeout)
+		defer cancel()
+
+		breached, err := p.Checker.IsBreached(ctx, password)
+		if err != nil {
+			// Don't block registration while the breach service is unavailable
+			logging.For("services.passwords").Warn("password breach check failed", "error", err)
+		} else if breached {
+			problems = append(problems, "appears in a known data breach")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
+
+// HIBPChecker checks passwords against the Have I Been Pwned range API using
+// k-anonymity: only the first 5 hex characters of the SHA-1 hash are sent
+type HIBPChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHIBPChecker() *HIBPChecker {
+	return &HIBPChecker{
+		baseURL: "https://api.pwnedpasswords.com/range/",
+		client:  &http.Client{Timeout: breachCheckTimeout},
+	}
+}
+
+func (h *HIBPChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the real number of matching suffixes from observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach check returned status %d", resp.StatusCode)
+	}
+
+	// Each line is SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if found && candidate == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// commonPasswords are rejected regardless of length
+var commonPasswords = map[string]bool{
+	"123456": true, "123456789": true, "12345678": true, "1234567890": true,
+	"12345": true, "1234567": true, "123123": true, "111111": true,
+	"000000": true, "666666": true, "654321": true, "121212": true,
+	"987654321": true, "11111111": true, "112233": true, "123321": true,
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"p@ssw0rd": true, "qwerty": true, "qwerty123": true, "qwertyuiop": true,
+	"1q2w3e4r": true, "1qaz2wsx": true, "zaq12wsx": true, "asdfghjkl": true,
+	"abc123": true, "abcd1234": true, "iloveyou": true, "admin": true,
+	"admin123": true, "welcome": true, "welcome1": true, "letmein": true,
+	"monkey": true, "dragon": true, "football": true, "baseball": true,
+	"sunshine": true, "princess": true, "starwars": true, "superman": true,
+	"batman": true, "master": true, "shadow": true, "michael": true,
+	"trustno1": true, "whatever": true, "freedom": true, "mustang": true,
+	"secret": true, "changeme": true, "computer": true, "internet": true,
+	"login": true, "hello123": true, "test1234": true, "football1": true,
+	"movies": true, "movie123": true, "watchlist": true, "netflix": true,
+	"cinema": true, "matrix": true, "titanic": true, "avengers": true,
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxPollMovies caps how many movies one poll can offer
+	maxPollMovies = 10
+	// maxPollParticipants caps how many users one poll can invite
+	maxPollParticipants = 20
+)
+
+var (
+	ErrInvalidPoll   = errors.New("invalid poll")
+	ErrPollNotFound  = errors.New("poll not found")
+	ErrNotPollOwner  = errors.New("only the poll owner can do this")
+	ErrPollClosed    = errors.New("poll is closed")
+	ErrInvalidBallot = errors.New("invalid ballot")
+)
+
+// PollDetail is a poll with its movies and the caller's ballot
+type PollDetail struct {
+	*models.Poll
+	Movies  []models.Movie `json:"movies"`
+	Ballots int            `json:"ballots"`
+	// MyRanking is nil until the caller votes
+	MyRanking []primitive.ObjectID `json:"my_ranking"`
+}
+
+// PollTally is the number of ballots counting for a movie in one round
+type PollTally struct {
+	MovieID primitive.ObjectID `json:"movie_id"`
+	Votes   int                `json:"votes"`
+}
+
+// PollRound is one instant runoff round: each ballot counts for its highest
+// ranked movie still in the running, and the movies with the fewest votes
+// are eliminated
+type PollRound struct {
+	Votes      []PollTally          `json:"votes"`
+	Eliminated []primitive.ObjectID `json:"eliminated"`
+}
+
+// PollResults is the instant runoff count of a poll's ballots. Winner is nil
+// while nobody has voted or when the last movies tie, which are then listed
+// in Tied.
+type PollResults struct {
+	PollID  primitive.ObjectID   `json:"poll_id"`
+	Closed  bool                 `json:"closed"`
+	Ballots int                  `json:"ballots"`
+	Rounds  []PollRound          `json:"rounds"`
+	Winner  *primitive.ObjectID  `json:"winner"`
+	Tied    []primitive.ObjectID `json:"tied,omitempty"`
+}
+
+// PollService runs movie night polls among invited users
+type PollService struct {
+	pollRepo         *repositories.PollRepository
+	userRepo         *repositories.UserRepository
+	movieRepo        *repositories.MovieRepository
+	notificationRepo *repositories.NotificationRepository
+	bus              *events.Bus
+	logger           *slog.Logger
+}
+
+func NewPollService(pollRepo *repositories.PollRepository, userRepo *repositories.UserRepository, movieRepo *repositories.MovieRepository, notificationRepo *repositories.NotificationRepository, bus *events.Bus) *PollService {
+	return &PollService{
+		pollRepo:         pollRepo,
+		userRepo:         userRepo,
+		movieRepo:        movieRepo,
+		notificationRepo: notificationRepo,
+		bus:              bus,
+		logger:           logging.For("services.polls"),
+	}
+}
+
+// Create starts a poll over the given cached movies and invites the users
+// with the given usernames, who get a notification
+func (s *PollService) Create(ownerID primitive.ObjectID, title string, movieIDs []primitive.ObjectID, usernames []string, closesAt, scheduledFor *time.Time) (*models.Poll, error) {
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return nil, errors.New("user not found")
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(movieIDs))
+	unique := make([]primitive.ObjectID, 0, len(movieIDs))
+	for _, movieID := range movieIDs {
+		if !seen[movieID] {
+			seen[movieID] = true
+			unique = append(unique, movieID)
+		}
+	}
+	if len(unique) < 2 || len(unique) > maxPollMovies {
+		return nil, fmt.Errorf("%w: a poll needs 2 to %d different movies", ErrInvalidPoll, maxPollMovies)
+	}
+	movies, err := s.movieRepo.FindByIDs(unique, "_id")
+	if err != nil {
+		return nil, err
+	}
+	for _, movieID := range unique {
+		if _, ok := movies[movieID]; !ok {
+			return nil, fmt.Errorf("%w: movie %s not found", ErrInvalidPoll, movieID.Hex())
+		}
+	}
+
+	if len(usernames) > maxPollParticipants {
+		return nil, fmt.Errorf("%w: at most %d users can be invited", ErrInvalidPoll, maxPollParticipants)
+	}
+	participants := []models.PollParticipant{}
+	invited := make(map[primitive.ObjectID]bool)
+	for _, username := range usernames {
+		user, err := s.userRepo.FindByUsername(username)
+		if err != nil {
+			return nil, err
+		}
+		// Deactivated and demo users cannot be invited, and look unknown
+		if user == nil || user.DeactivatedAt != nil || user.DemoExpiresAt != nil {
+			return nil, fmt.Errorf("%w: user %s not found", ErrInvalidPoll, username)
+		}
+		if user.ID == ownerID || invited[user.ID] {
+			continue
+		}
+		invited[user.ID] = true
+		participants = append(participants, models.PollParticipant{UserID: user.ID, Username: user.Username})
+	}
+
+	if err := validatePollTimes(closesAt, scheduledFor); err != nil {
+		return nil, err
+	}
+
+	poll := &models.Poll{
+		UserID:       ownerID,
+		OwnerName:    owner.Username,
+		Title:        title,
+		MovieIDs:     unique,
+		Participants: participants,
+		ClosesAt:     closesAt,
+		ScheduledFor: scheduledFor,
+	}
+	if err := s.pollRepo.Create(poll); err != nil {
+		return nil, err
+	}
+
+	for _, participant := range participants {
+		notification := &models.Notification{
+			UserID:  participant.UserID,
+			Type:    models.NotificationPollInvite,
+			Title:   fmt.Sprintf("%s invited you to a poll", owner.Username),
+			Message: fmt.Sprintf("Rank the movies in %q to help pick what to watch.", title),
+		}
+		// The poll exists already, so a failed notification does not fail it
+		if err := s.notificationRepo.Create(notification); err != nil {
+			s.logger.Warn("failed to notify poll participant", "poll_id", poll.ID.Hex(), "user_id", participant.UserID.Hex(), "error", err)
+		}
+	}
+
+	s.bus.Publish(events.PollCreated{
+		PollID:       poll.ID,
+		UserID:       ownerID,
+		Title:        title,
+		Movies:       len(unique),
+		ClosesAt:     closesAt,
+		ScheduledFor: scheduledFor,
+		At:           poll.CreatedAt,
+	})
+	return poll, nil
+}
+
+// List returns one page of the polls the user owns or was invited to,
+// newest first, and the total count
+func (s *PollService) List(userID primitive.ObjectID, offset, limit int) ([]models.Poll, int64, error) {
+	polls, total, err := s.pollRepo.FindForUserPage(userID, int64(offset), int64(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now().UTC()
+	for i := range polls {
+		polls[i].Closed = polls[i].IsClosed(now)
+	}
+	return polls, total, nil
+}
+
+// Get returns the poll with its movies and the user's ballot
+func (s *PollService) Get(userID, pollID primitive.ObjectID) (*PollDetail, error) {
+	poll, err := s.load(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	movies, err := s.movieRepo.FindByIDs(poll.MovieIDs)
+	if err != nil {
+		return nil, err
+	}
+	detail := &PollDetail{Poll: poll, Movies: []models.Movie{}}
+	for _, movieID := range poll.MovieIDs {
+		if movie, ok := movies[movieID]; ok {
+			detail.Movies = append(detail.Movies, movie)
+		}
+	}
+
+	ballots, err := s.pollRepo.FindBallots(pollID)
+	if err != nil {
+		return nil, err
+	}
+	detail.Ballots = len(ballots)
+	for _, ballot := range ballots {
+		if ballot.UserID == userID {
+			detail.MyRanking = ballot.Ranking
+		}
+	}
+	return detail, nil
+}
+
+// Update changes an open poll's title, closing time and movie night time.
+// Only the owner can update it.
+func (s *PollService) Update(userID, pollID primitive.ObjectID, title string, closesAt, scheduledFor *time.Time) (*models.Poll, error) {
+	poll, err := s.loadOwned(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, ErrPollClosed
+	}
+	if err := validatePollTimes(closesAt, scheduledFor); err != nil {
+		return nil, err
+	}
+
+	found, err := s.pollRepo.Update(pollID, title, closesAt, scheduledFor)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrPollNotFound
+	}
+
+	s.bus.Publish(events.PollUpdated{
+		PollID:       pollID,
+		UserID:       userID,
+		Title:        title,
+		ClosesAt:     closesAt,
+		ScheduledFor: scheduledFor,
+		Rescheduled:  !sameTime(poll.ScheduledFor, scheduledFor),
+		At:           time.Now().UTC(),
+	})
+	return s.load(userID, pollID)
+}
+
+// Close ends voting on the poll. Only the owner can close it.
+func (s *PollService) Close(userID, pollID primitive.ObjectID) (*models.Poll, error) {
+	poll, err := s.loadOwned(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, ErrPollClosed
+	}
+
+	now := time.Now().UTC()
+	closed, err := s.pollRepo.Close(pollID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, ErrPollClosed
+	}
+
+	s.bus.Publish(events.PollClosed{PollID: pollID, UserID: userID, At: now})
+	return s.load(userID, pollID)
+}
+
+// Delete removes the poll with its ballots. Only the owner can delete it.
+func (s *PollService) Delete(userID, pollID primitive.ObjectID) error {
+	if _, err := s.loadOwned(userID, pollID); err != nil {
+		return err
+	}
+	return s.pollRepo.Delete(pollID)
+}
+
+// Vote records the user's ranking of the poll's movies, most preferred
+// first, replacing an earlier vote. The ranking may leave movies out.
+func (s *PollService) Vote(userID, pollID primitive.ObjectID, ranking []primitive.ObjectID) (*models.PollBallot, error) {
+	poll, err := s.load(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, ErrPollClosed
+	}
+
+	if len(ranking) == 0 {
+		return nil, fmt.Errorf("%w: rank at least one movie", ErrInvalidBallot)
+	}
+	offered := make(map[primitive.ObjectID]bool, len(poll.MovieIDs))
+	for _, movieID := range poll.MovieIDs {
+		offered[movieID] = true
+	}
+	ranked := make(map[primitive.ObjectID]bool, len(ranking))
+	for _, movieID := range ranking {
+		if !offered[movieID] {
+			return nil, fmt.Errorf("%w: movie %s is not in the poll", ErrInvalidBallot, movieID.Hex())
+		}
+		if ranked[movieID] {
+			return nil, fmt.Errorf("%w: movie %s is ranked twice", ErrInvalidBallot, movieID.Hex())
+		}
+		ranked[movieID] = true
+	}
+
+	return s.pollRepo.SaveBallot(pollID, userID, ranking)
+}
+
+// Results counts the poll's ballots by instant runoff. Results of an open
+// poll are provisional.
+func (s *PollService) Results(userID, pollID primitive.ObjectID) (*PollResults, error) {
+	poll, err := s.load(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	ballots, err := s.pollRepo.FindBallots(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &PollResults{PollID: pollID, Closed: poll.Closed, Ballots: len(ballots)}
+	results.Rounds, results.Winner, results.Tied = instantRunoff(poll.MovieIDs, ballots)
+	return results, nil
+}
+
+// load returns the poll when the user owns it or was invited to it; to
+// anyone else it does not exist
+func (s *PollService) load(userID, pollID primitive.ObjectID) (*models.Poll, error) {
+	poll, err := s.pollRepo.FindByID(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll == nil {
+		return nil, ErrPollNotFound
+	}
+	allowed := poll.UserID == userID
+	for _, participant := range poll.Participants {
+		allowed = allowed || participant.UserID == userID
+	}
+	if !allowed {
+		return nil, ErrPollNotFound
+	}
+	poll.Closed = poll.IsClosed(time.Now().UTC())
+	return poll, nil
+}
+
+// loadOwned is load for changes only the owner may make
+func (s *PollService) loadOwned(userID, pollID primitive.ObjectID) (*models.Poll, error) {
+	poll, err := s.load(userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.UserID != userID {
+		return nil, ErrNotPollOwner
+	}
+	return poll, nil
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// validatePollTimes checks that voting closes and the movie night starts in
+// the future, and that voting closes by the time the movie night starts
+func validatePollTimes(closesAt, scheduledFor *time.Time) error {
+	now := time.Now().UTC()
+	if closesAt != nil && !closesAt.After(now) {
+		return fmt.Errorf("%w: closes_at must be in the future", ErrInvalidPoll)
+	}
+	if scheduledFor != nil && !scheduledFor.After(now) {
+		return fmt.Errorf("%w: scheduled_for must be in the future", ErrInvalidPoll)
+	}
+	if closesAt != nil && scheduledFor != nil && closesAt.After(*scheduledFor) {
+		return fmt.Errorf("%w: closes_at must not be after scheduled_for", ErrInvalidPoll)
+	}
+	return nil
+}
+
+// instantRunoff counts the ballots in rounds until one movie has a majority
+// of the ballots still counting. Each round eliminates every movie tied for
+// the fewest votes, unless that would eliminate all of them, in which case
+// they tie.
+func instantRunoff(movieIDs []primitive.ObjectID, ballots []models.PollBallot) ([]PollRound, *primitive.ObjectID, []primitive.ObjectID) {
+	remaining := append([]primitive.ObjectID{}, movieIDs...)
+	rounds := []PollRound{}
+	for len(remaining) > 0 {
+		running := make(map[primitive.ObjectID]bool, len(remaining))
+		for _, movieID := range remaining {
+			running[movieID] = true
+		}
+		counts := make(map[primitive.ObjectID]int, len(remaining))
+		active := 0
+		for _, ballot := range ballots {
+			for _, movieID := range ballot.Ranking {
+				if running[movieID] {
+					counts[movieID]++
+					active++
+					break
+				}
+			}
+		}
+
+		round := PollRound{Votes: make([]PollTally, 0, len(remaining)), Eliminated: []primitive.ObjectID{}}
+		fewest := -1
+		for _, movieID := range remaining {
+			votes := counts[movieID]
+			round.Votes = append(round.Votes, PollTally{MovieID: movieID, Votes: votes})
+			if fewest == -1 || votes < fewest {
+				fewest = votes
+			}
+		}
+		if active == 0 {
+			rounds = append(rounds, round)
+			return rounds, nil, nil
+		}
+		for _, tally := range round.Votes {
+			if tally.Votes*2 > active {
+				rounds = append(rounds, round)
+				winner := tally.MovieID
+				return rounds, &winner, nil
+			}
+		}
+
+		kept := make([]primitive.ObjectID, 0, len(remaining))
+		for _, movieID := range remaining {
+			if counts[movieID] == fewest {
+				round.Eliminated = append(round.Eliminated, movieID)
+			} else {
+				kept = append(kept, movieID)
+			}
+		}
+		if len(kept) == 0 {
+			round.Eliminated = []primitive.ObjectID{}
+			rounds = append(rounds, round)
+			return rounds, nil, remaining
+		}
+		rounds = append(rounds, round)
+		remaining = kept
+	}
+	return rounds, nil, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// posterCheckPerRun caps the poster links checked by one job run
+	posterCheckPerRun = 100
+	// posterCheckInterval is how often the poster checker runs
+	posterCheckInterval = time.Hour
+	// posterRecheckAfter is how long a checked poster, or a broken one
+	// waiting for a new link, is left alone
+	posterRecheckAfter = 7 * 24 * time.Hour
+	// placeholderLineLength and placeholderMaxLines wrap the title on
+	// generated placeholders
+	placeholderLineLength = 18
+	placeholderMaxLines   = 5
+)
+
+// posterStatus is what checking a poster link found
+type posterStatus int
+
+const (
+	posterOK posterStatus = iota
+	posterBroken
+	// posterUnknown covers timeouts and server errors, which may pass
+	posterUnknown
+)
+
+// PosterService finds poster links that stopped resolving and replaces them
+// with a fresh link from the metadata providers or a generated placeholder
+type PosterService struct {
+	movieService *MovieService
+	movieRepo    *repositories.MovieRepository
+	jobQueue     *jobs.Queue
+	baseURL      string
+	client       *http.Client
+	logger       *slog.Logger
+}
+
+func NewPosterService(movieService *MovieService, movieRepo *repositories.MovieRepository, jobQueue *jobs.Queue, baseURL string) *PosterService {
+	return &PosterService{
+		movieService: movieService,
+		movieRepo:    movieRepo,
+		jobQueue:     jobQueue,
+		baseURL:      baseURL,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		logger:       logging.For("services.posters"),
+	}
+}
+
+// EnsureScheduled queues the first poster check if none is pending
+func (s *PosterService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeCheckPosters, nil, time.Now().UTC())
+}
+
+// CheckPostersJob checks the poster links not checked for a week. A dead
+// link is replaced by the provider's current poster when that one works,
+// and by the placeholder otherwise; broken posters get another chance at a
+// new link every week. It runs hourly.
+func (s *PosterService) CheckPostersJob(ctx context.Context, payload map[string]interface{}) error {
+	now := time.Now().UTC()
+	movies, err := s.movieRepo.FindPostersDue(now.Add(-posterRecheckAfter), posterCheckPerRun)
+	if err != nil {
+		return err
+	}
+
+	broken, repaired := 0, 0
+	for _, movie := range movies {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		dead := movie.BrokenPoster
+		if !movie.PosterBroken {
+			if s.probe(ctx, movie.Poster) != posterBroken {
+				if err := s.movieRepo.MarkPosterChecked(movie.ID, now); err != nil {
+					return err
+				}
+				continue
+			}
+			dead = movie.Poster
+		}
+
+		if poster := s.resolve(ctx, movie.IMDbID, dead); poster != "" {
+			if err := s.movieRepo.SetPoster(movie.ID, poster, now); err != nil {
+				return err
+			}
+			s.movieService.history.Record(&movie, map[string]string{"poster": poster}, changedByJob(jobs.TypeCheckPosters))
+			repaired++
+			continue
+		}
+		placeholder := s.PlaceholderURL(movie.ID)
+		if err := s.movieRepo.MarkPosterBroken(movie.ID, dead, placeholder, now); err != nil {
+			return err
+		}
+		s.movieService.history.Record(&movie, map[string]string{"poster": placeholder}, changedByJob(jobs.TypeCheckPosters))
+		if !movie.PosterBroken {
+			broken++
+		}
+	}
+	if broken > 0 || repaired > 0 {
+		s.logger.Info("checked poster links", "movies", len(movies), "broken", broken, "repaired", repaired)
+	}
+
+	return s.jobQueue.EnqueueAt(jobs.TypeCheckPosters, nil, time.Now().UTC().Add(posterCheckInterval))
+}
+
+// resolve asks the metadata providers for the movie's poster and returns it
+// when it differs from the dead link and works, or "" otherwise
+func (s *PosterService) resolve(ctx context.Context, imdbID, dead string) string {
+	details, provider, err := s.movieService.metadata.FetchDetails(ctx, imdbID)
+	if err != nil {
+		s.logger.Warn("failed to re-resolve poster", "imdb_id", imdbID, "error", err)
+		return ""
+	}
+	poster := details.Poster
+	if poster == "" || poster == "N/A" || poster == dead {
+		return ""
+	}
+	if s.probe(ctx, poster) != posterOK {
+		return ""
+	}
+	s.logger.Info("repaired poster link", "imdb_id", imdbID, "provider", provider)
+	return poster
+}
+
+// probe checks a poster link with a HEAD request, falling back to GET for
+// servers that do not support HEAD
+func (s *PosterService) probe(ctx context.Context, poster string) posterStatus {
+	status, contentType, err := s.request(ctx, http.MethodHead, poster)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, contentType, err = s.request(ctx, http.MethodGet, poster)
+	}
+	switch {
+	case err != nil:
+		return posterUnknown
+	case status >= 200 && status < 300:
+		// A page where an image was expected, such as a parked domain
+		if strings.HasPrefix(contentType, "text/html") {
+			return posterBroken
+		}
+		return posterOK
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests:
+		return posterUnknown
+	case status >= 400 && status < 500:
+		return posterBroken
+	default:
+		return posterUnknown
+	}
+}
+
+func (s *PosterService) request(ctx context.Context, method, poster string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, poster, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Content-Type"), nil
+}
+
+// PlaceholderURL is where the generated placeholder of a movie is served
+func (s *PosterService) PlaceholderURL(movieID primitive.ObjectID) string {
+	return fmt.Sprintf("%s/api/v1/movies/%s/poster-placeholder.svg", s.baseURL, movieID.Hex())
+}
+
+// PosterPlaceholderSVG draws a plain 2:3 poster with the movie's title and year
+func PosterPlaceholderSVG(movie *models.Movie) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="300" height="450" viewBox="0 0 300 450">`)
+	buf.WriteString(`<rect width="300" height="450" fill="#1f2933"/>`)
+	buf.WriteString(`<rect x="12" y="12" width="276" height="426" fill="none" stroke="#52606d" stroke-width="2"/>`)
+
+	lines := wrapTitle(movie.Title)
+	y := 225 - (len(lines)-1)*15
+	for _, line := range lines {
+		fmt.Fprintf(&buf, `<text x="150" y="%d" fill="#f5f7fa" font-family="sans-serif" font-size="24" text-anchor="middle">%s</text>`, y, html.EscapeString(line))
+		y += 30
+	}
+	if movie.Year != "" && movie.Year != "N/A" {
+		fmt.Fprintf(&buf, `<text x="150" y="%d" fill="#9aa5b1" font-family="sans-serif" font-size="18" text-anchor="middle">%s</text>`, y+10, html.EscapeString(movie.Year))
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// wrapTitle splits a title into lines that fit the placeholder, ending the
+// last line with an ellipsis when the title does not fit
+func wrapTitle(title string) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(title) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > placeholderLineLength {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > placeholderMaxLines {
+		lines = lines[:placeholderMaxLines]
+		lines[placeholderMaxLines-1] += "…"
+	}
+	return lines
+}
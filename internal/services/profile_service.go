@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxProfilesPerAccount caps how many kids profiles one account can create
+const MaxProfilesPerAccount = 6
+
+type ProfileService struct {
+	profileRepo *repositories.ProfileRepository
+}
+
+func NewProfileService(profileRepo *repositories.ProfileRepository) *ProfileService {
+	return &ProfileService{profileRepo: profileRepo}
+}
+
+// CreateProfile adds a kids profile to the parent account
+func (s *ProfileService) CreateProfile(parentID primitive.ObjectID, name, maxCertification string) (*models.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("profile name is required")
+	}
+	certification, err := normalizeCertification(maxCertification)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.profileRepo.CountByParent(parentID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxProfilesPerAccount {
+		return nil, errors.New("profile limit reached")
+	}
+
+	profile := &models.Profile{
+		ParentID:         parentID,
+		Name:             name,
+		MaxCertification: certification,
+	}
+	if err := s.profileRepo.Create(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *ProfileService) ListProfiles(parentID primitive.ObjectID) ([]models.Profile, error) {
+	return s.profileRepo.ListByParent(parentID)
+}
+
+// GetProfile returns the parent's profile, or nil when the parent has no such profile
+func (s *ProfileService) GetProfile(profileID, parentID primitive.ObjectID) (*models.Profile, error) {
+	return s.profileRepo.FindForParent(profileID, parentID)
+}
+
+// GetProfileByID returns the profile whoever owns it, or nil. Callers check
+// ownership with the authz policy.
+func (s *ProfileService) GetProfileByID(profileID primitive.ObjectID) (*models.Profile, error) {
+	return s.profileRepo.FindByID(profileID)
+}
+
+// UpdateProfile renames the profile and changes its certification cap
+func (s *ProfileService) UpdateProfile(profileID, parentID primitive.ObjectID, name, maxCertification string) (*models.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("profile name is required")
+	}
+	certification, err := normalizeCertification(maxCertification)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.profileRepo.Update(profileID, parentID, name, certification)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("profile not found")
+	}
+	return s.profileRepo.FindForParent(profileID, parentID)
+}
+
+// DeleteProfile removes the profile and everything stored under it
+func (s *ProfileService) DeleteProfile(profileID, parentID primitive.ObjectID) error {
+	found, err := s.profileRepo.Delete(profileID, parentID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("profile not found")
+	}
+	return nil
+}
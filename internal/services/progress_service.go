@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// progressCompleteRatio is the share of the runtime after which a movie counts
+// as finished, so skipping the end credits still completes it
+const progressCompleteRatio = 0.95
+
+type ProgressService struct {
+	progressRepo     *repositories.ProgressRepository
+	movieRepo        *repositories.MovieRepository
+	watchlistService *WatchlistService
+}
+
+func NewProgressService(progressRepo *repositories.ProgressRepository, movieRepo *repositories.MovieRepository, watchlistService *WatchlistService) *ProgressService {
+	return &ProgressService{
+		progressRepo:     progressRepo,
+		movieRepo:        movieRepo,
+		watchlistService: watchlistService,
+	}
+}
+
+// RecordProgress stores how many minutes of the movie the user has watched.
+// Reaching the end of a movie on the user's watchlist marks the entry watched.
+func (s *ProgressService) RecordProgress(userID primitive.ObjectID, movieID primitive.ObjectID, minutesWatched int) (*models.WatchProgress, error) {
+	if minutesWatched < 0 {
+		return nil, errors.New("minutes watched cannot be negative")
+	}
+
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, errors.New("movie not found")
+	}
+
+	canonicalID, _, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	var completedAt *time.Time
+	if runtime := movie.RuntimeMinutes(); runtime > 0 && float64(minutesWatched) >= float64(runtime)*progressCompleteRatio {
+		now := time.Now().UTC()
+		completedAt = &now
+	}
+
+	progress, err := s.progressRepo.Upsert(userID, canonicalID, minutesWatched, completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt != nil {
+		if _, err := s.watchlistService.MarkWatched(userID, canonicalID, nil); err != nil && err.Error() != "movie not in watchlist" {
+			return nil, err
+		}
+	}
+
+	return progress, nil
+}
+
+// GetContinueWatchingPage returns one page of the user's unfinished movies and the total count
+func (s *ProgressService) GetContinueWatchingPage(userID primitive.ObjectID, offset, limit int) ([]models.WatchProgress, int64, error) {
+	return s.progressRepo.GetInProgressPage(userID, int64(offset), int64(limit))
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"movie-watchlist/internal/models"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// quickAddThreshold is the lowest confidence at which a title is added
+	// without asking the user to pick from the candidates
+	quickAddThreshold = 0.75
+	// quickAddCandidates caps the candidates returned for an unsure match
+	quickAddCandidates = 5
+)
+
+// How a quick-add input was matched to a movie
+const (
+	QuickAddMatchedIMDbID = "imdb_id"
+	QuickAddMatchedTitle  = "title"
+)
+
+var (
+	ErrQuickAddEmpty = errors.New("url, title or imdb_id is required")
+	// ErrNoQuickAddMatch carries the closest candidates in QuickAddError
+	ErrNoQuickAddMatch = errors.New("no confident match for the title")
+)
+
+var (
+	// quickAddIMDbPattern finds an IMDb ID anywhere in a URL
+	quickAddIMDbPattern = regexp.MustCompile(`\btt\d{7,10}\b`)
+	// quickAddYearPattern finds a release year such as "(1999)" or
+	// "(TV Series 2008–2013)" in a title
+	quickAddYearPattern = regexp.MustCompile(`\s*\((?:[A-Za-z ]+ )?(19\d{2}|20\d{2})(?:[–-]\d{0,4})?\)`)
+	// quickAddSlugID strips numeric IDs from URL slugs such as
+	// "603-the-matrix" (TMDb)
+	quickAddSlugID = regexp.MustCompile(`^\d+[-_]?`)
+)
+
+// quickAddTitleSeparators split a page title from the site name, as in
+// "Inception | Netflix" or "The Matrix (1999) - IMDb"
+var quickAddTitleSeparators = []string{" | ", " - ", " – ", " — ", " · "}
+
+// QuickAddMatch is the movie a quick-add input resolved to
+type QuickAddMatch struct {
+	Movie *models.Movie `json:"movie"`
+	// Confidence runs from 0 to 1; a known IMDb ID is always 1
+	Confidence float64 `json:"confidence"`
+	MatchedBy  string  `json:"matched_by"`
+	// Query is the title that was looked up, after cleaning up the page title
+	Query string `json:"query,omitempty"`
+}
+
+// QuickAddCandidate is a possible match offered when none was confident
+type QuickAddCandidate struct {
+	IMDbID     string  `json:"imdb_id"`
+	Title      string  `json:"title"`
+	Year       string  `json:"year"`
+	Confidence float64 `json:"confidence"`
+}
+
+// QuickAddError is returned when the input did not resolve to a movie
+// confidently enough to add it
+type QuickAddError struct {
+	Query      string
+	Candidates []QuickAddCandidate
+}
+
+func (e *QuickAddError) Error() string {
+	return ErrNoQuickAddMatch.Error()
+}
+
+func (e *QuickAddError) Unwrap() error {
+	return ErrNoQuickAddMatch
+}
+
+// QuickAddService resolves what a browser extension saw on a page, a URL or
+// a title, to a movie
+type QuickAddService struct {
+	movieService *MovieService
+}
+
+func NewQuickAddService(movieService *MovieService) *QuickAddService {
+	return &QuickAddService{movieService: movieService}
+}
+
+// Resolve finds the movie for a page URL and/or title. An IMDb ID, given
+// directly after the user picked a candidate or found in the URL, is looked
+// up as is; otherwise the title, or the URL's slug when there is no title,
+// is searched and the results are fuzzy matched against it.
+func (s *QuickAddService) Resolve(ctx context.Context, imdbID, pageURL, title string, userID primitive.ObjectID) (*QuickAddMatch, error) {
+	imdbID, pageURL, title = strings.TrimSpace(imdbID), strings.TrimSpace(pageURL), strings.TrimSpace(title)
+	if imdbID == "" && pageURL == "" && title == "" {
+		return nil, ErrQuickAddEmpty
+	}
+
+	if imdbID == "" {
+		imdbID = quickAddIMDbPattern.FindString(pageURL)
+	}
+	if imdbID != "" {
+		movie, err := s.movieService.GetMovieDetails(ctx, imdbID)
+		if err != nil {
+			return nil, err
+		}
+		return &QuickAddMatch{Movie: movie, Confidence: 1, MatchedBy: QuickAddMatchedIMDbID}, nil
+	}
+
+	query, year := cleanPageTitle(title)
+	if query == "" {
+		query = titleFromURL(pageURL)
+	}
+	if query == "" {
+		return nil, &QuickAddError{Candidates: []QuickAddCandidate{}}
+	}
+
+	result, err := s.movieService.SearchMovies(ctx, query, 1, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]QuickAddCandidate, 0, len(result.Movies)+len(result.DidYouMean))
+	for _, hit := range result.Movies {
+		candidates = append(candidates, QuickAddCandidate{IMDbID: hit.IMDbID, Title: hit.Title, Year: hit.Year})
+	}
+	for _, suggestion := range result.DidYouMean {
+		candidates = append(candidates, QuickAddCandidate{IMDbID: suggestion.IMDbID, Title: suggestion.Title, Year: suggestion.Year})
+	}
+	ranked := rankQuickAddCandidates(query, year, candidates)
+
+	if len(ranked) == 0 || ranked[0].Confidence < quickAddThreshold {
+		if len(ranked) > quickAddCandidates {
+			ranked = ranked[:quickAddCandidates]
+		}
+		return nil, &QuickAddError{Query: query, Candidates: ranked}
+	}
+
+	movie, err := s.movieService.GetMovieDetails(ctx, ranked[0].IMDbID)
+	if err != nil {
+		return nil, err
+	}
+	return &QuickAddMatch{Movie: movie, Confidence: ranked[0].Confidence, MatchedBy: QuickAddMatchedTitle, Query: query}, nil
+}
+
+// rankQuickAddCandidates scores each candidate by title similarity, nudged
+// up when its year matches the one on the page and down when it does not,
+// and returns them best first without duplicates
+func rankQuickAddCandidates(query string, year int, candidates []QuickAddCandidate) []QuickAddCandidate {
+	normalizedQuery := normalizeTitle(query)
+	queryTrigrams := trigrams(normalizedQuery)
+
+	seen := make(map[string]bool)
+	ranked := make([]QuickAddCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.IMDbID == "" || seen[candidate.IMDbID] {
+			continue
+		}
+		seen[candidate.IMDbID] = true
+
+		normalized := normalizeTitle(candidate.Title)
+		confidence := trigramSimilarity(queryTrigrams, trigrams(normalized))
+		if edit := levenshteinSimilarity(normalizedQuery, normalized); edit > confidence {
+			confidence = edit
+		}
+		if year != 0 {
+			if start, _ := models.ParseYearRange(candidate.Year); start == year {
+				confidence = math.Min(1, confidence+0.1)
+			} else {
+				confidence *= 0.8
+			}
+		}
+		candidate.Confidence = math.Round(confidence*1000) / 1000
+		ranked = append(ranked, candidate)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Confidence > ranked[j].Confidence
+	})
+	return ranked
+}
+
+// cleanPageTitle strips the site name, a "Watch" prefix and the year from a
+// page title, returning the year separately (0 when there is none)
+func cleanPageTitle(title string) (string, int) {
+	for _, separator := range quickAddTitleSeparators {
+		if i := strings.Index(title, separator); i > 0 {
+			title = title[:i]
+		}
+	}
+	title = strings.TrimSpace(title)
+	if rest, ok := cutPrefixFold(title, "watch "); ok {
+		title = rest
+	}
+
+	year := 0
+	if match := quickAddYearPattern.FindStringSubmatch(title); match != nil {
+		year, _ = strconv.Atoi(match[1])
+		title = strings.Replace(title, match[0], "", 1)
+	}
+	return strings.TrimSpace(title), year
+}
+
+// titleFromURL guesses a title from the last path segment of a URL, such
+// as "the-matrix" in letterboxd.com/film/the-matrix/. Numeric segments, as
+// on Netflix, give nothing.
+func titleFromURL(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	segment := path.Base(strings.TrimRight(u.Path, "/"))
+	slug := quickAddSlugID.ReplaceAllString(segment, "")
+	slug = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ", "+", " ").Replace(slug))
+	if slug == "" || slug == "." || slug == "/" || strings.Trim(slug, "0123456789 ") == "" {
+		return ""
+	}
+	return slug
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ratingFeedItems caps the ratings in a feed, most recently changed first
+const ratingFeedItems = 50
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"`
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssLink is the atom:link pointing at the feed itself, which feed
+// validators expect
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// RatingFeedService publishes the ratings of users who opt in as an RSS
+// feed anyone can subscribe to
+type RatingFeedService struct {
+	userRepo   *repositories.UserRepository
+	ratingRepo *repositories.RatingRepository
+	movieRepo  *repositories.MovieRepository
+	baseURL    string
+}
+
+func NewRatingFeedService(userRepo *repositories.UserRepository, ratingRepo *repositories.RatingRepository, movieRepo *repositories.MovieRepository, baseURL string) *RatingFeedService {
+	return &RatingFeedService{
+		userRepo:   userRepo,
+		ratingRepo: ratingRepo,
+		movieRepo:  movieRepo,
+		baseURL:    baseURL,
+	}
+}
+
+// SetPublic sets whether the user's ratings are published
+func (s *RatingFeedService) SetPublic(userID primitive.ObjectID, public bool) error {
+	found, err := s.userRepo.SetRatingsPublic(userID, public)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// PublicFeed returns the RSS feed of the latest ratings of the user with the
+// given username, or nil when the user does not exist, is deactivated or
+// keeps ratings private
+func (s *RatingFeedService) PublicFeed(username string) ([]byte, error) {
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil || user == nil || user.DeactivatedAt != nil || !user.RatingsPublic {
+		return nil, err
+	}
+
+	ratings, _, err := s.ratingRepo.GetUserRatingsPage(user.ID, 0, ratingFeedItems)
+	if err != nil {
+		return nil, err
+	}
+	movieIDs := make([]primitive.ObjectID, 0, len(ratings))
+	for _, rating := range ratings {
+		movieIDs = append(movieIDs, rating.MovieID)
+	}
+	movies, err := s.movieRepo.FindByIDs(movieIDs, "_id", "imdb_id", "title", "year")
+	if err != nil {
+		return nil, err
+	}
+
+	feedURL := s.baseURL + "/api/v1/users/" + url.PathEscape(user.Username) + "/ratings.rss"
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       user.Username + "'s movie ratings",
+			Link:        feedURL,
+			Description: "The latest movies " + user.Username + " rated",
+			TTL:         60,
+			Self:        rssLink{Href: feedURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+	for _, rating := range ratings {
+		movie, ok := movies[rating.MovieID]
+		if !ok {
+			continue
+		}
+		feed.Channel.Items = append(feed.Channel.Items, s.ratingItem(user.Username, rating, movie))
+	}
+	if len(ratings) > 0 {
+		feed.Channel.LastBuildDate = ratings[0].UpdatedAt.UTC().Format(time.RFC1123Z)
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// ratingItem is the feed item for one rating. Its GUID changes with the
+// rating's version, so readers show a changed rating as a new item.
+func (s *RatingFeedService) ratingItem(username string, rating models.Rating, movie models.Movie) rssItem {
+	title := movie.Title
+	if movie.Year != "" {
+		title += " (" + movie.Year + ")"
+	}
+	stars := strings.Repeat("★", rating.Rating) + strings.Repeat("☆", 5-rating.Rating)
+
+	link := s.baseURL + "/api/v1/movies/" + movie.ID.Hex()
+	if movie.IMDbID != "" {
+		link = "https://www.imdb.com/title/" + movie.IMDbID + "/"
+	}
+	return rssItem{
+		Title:       fmt.Sprintf("%s rated %s %s", username, title, stars),
+		Link:        link,
+		Description: fmt.Sprintf("%s gave %s %d out of 5 stars.", username, title, rating.Rating),
+		GUID:        rssGUID{Value: fmt.Sprintf("rating-%s-%d", rating.ID.Hex(), rating.Version)},
+		PubDate:     rating.UpdatedAt.UTC().Format(time.RFC1123Z),
+	}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxRatingImportRows caps the data rows accepted in one CSV import
+const MaxRatingImportRows = 1000
+
+// Actions reported for each imported row
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionConflict  = "conflict"
+	ImportActionInvalid   = "invalid"
+	ImportActionFailed    = "failed"
+)
+
+// ErrInvalidRatingImport is returned when the CSV as a whole cannot be read
+var ErrInvalidRatingImport = errors.New("invalid ratings CSV")
+
+// RatingImportRow is the outcome for one CSV row
+type RatingImportRow struct {
+	Line           int    `json:"line"`
+	IMDbID         string `json:"imdb_id"`
+	Rating         int    `json:"rating,omitempty"`
+	Date           string `json:"date,omitempty"`
+	Action         string `json:"action"`
+	ExistingRating int    `json:"existing_rating,omitempty"`
+	// MovieCached is false when the movie still has to be fetched from OMDb
+	MovieCached bool   `json:"movie_cached"`
+	Error       string `json:"error,omitempty"`
+
+	ratedAt         time.Time
+	movieID         primitive.ObjectID
+	existingVersion int
+}
+
+// RatingImportReport summarises an import; in a dry run nothing was written
+type RatingImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Summary map[string]int    `json:"summary"`
+	Rows    []RatingImportRow `json:"rows"`
+}
+
+type RatingImportService struct {
+	ratingRepo   *repositories.RatingRepository
+	movieRepo    *repositories.MovieRepository
+	movieService *MovieService
+	logger       *slog.Logger
+}
+
+func NewRatingImportService(ratingRepo *repositories.RatingRepository, movieRepo *repositories.MovieRepository, movieService *MovieService) *RatingImportService {
+	return &RatingImportService{
+		ratingRepo:   ratingRepo,
+		movieRepo:    movieRepo,
+		movieService: movieService,
+		logger:       logging.For("services.rating_import"),
+	}
+}
+
+// ImportCSV validates a CSV of imdb_id, rating and optional date columns and
+// plans each row against the user's existing ratings. A row whose rating
+// differs from an existing one is a conflict unless its date is newer than the
+// existing rating or overwrite is set. Unless dryRun is set, the planned
+// creates and updates are then applied.
+func (s *RatingImportService) ImportCSV(userID primitive.ObjectID, input io.Reader, dryRun, overwrite bool) (*RatingImportReport, error) {
+	rows, err := parseRatingsCSV(input)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		if rows[i].Action == ImportActionInvalid {
+			continue
+		}
+		if err := s.planRow(userID, &rows[i], overwrite); err != nil {
+			return nil, err
+		}
+	}
+
+	if !dryRun {
+		for i := range rows {
+			s.applyRow(userID, &rows[i])
+		}
+	}
+
+	report := &RatingImportReport{DryRun: dryRun, Summary: map[string]int{}, Rows: rows}
+	for _, action := range []string{ImportActionCreate, ImportActionUpdate, ImportActionUnchanged, ImportActionConflict, ImportActionInvalid, ImportActionFailed} {
+		report.Summary[action] = 0
+	}
+	for _, row := range rows {
+		report.Summary[row.Action]++
+	}
+	return report, nil
+}
+
+// planRow decides what importing the row would do
+func (s *RatingImportService) planRow(userID primitive.ObjectID, row *RatingImportRow, overwrite bool) error {
+	movie, err := s.movieRepo.FindByIMDbID(row.IMDbID)
+	if err != nil {
+		return err
+	}
+	if movie == nil {
+		// Nobody can have rated a movie that was never cached
+		row.Action = ImportActionCreate
+		return nil
+	}
+	row.MovieCached = true
+
+	canonicalID, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movie.ID)
+	if err != nil {
+		return err
+	}
+	row.movieID = canonicalID
+
+	existing, err := s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case existing == nil:
+		row.Action = ImportActionCreate
+	case existing.Rating == row.Rating:
+		row.Action = ImportActionUnchanged
+		row.ExistingRating = existing.Rating
+	case overwrite || (!row.ratedAt.IsZero() && row.ratedAt.After(existing.UpdatedAt)):
+		row.Action = ImportActionUpdate
+		row.ExistingRating = existing.Rating
+		row.movieID = existing.MovieID
+		row.existingVersion = existing.Version
+	default:
+		row.Action = ImportActionConflict
+		row.ExistingRating = existing.Rating
+		row.Error = "you already rated this movie differently, more recently than this row"
+	}
+	return nil
+}
+
+// applyRow writes a planned create or update, fetching uncached movies from
+// OMDb while the daily quota allows
+func (s *RatingImportService) applyRow(userID primitive.ObjectID, row *RatingImportRow) {
+	if row.Action != ImportActionCreate && row.Action != ImportActionUpdate {
+		return
+	}
+
+	ratedAt := row.ratedAt
+	if ratedAt.IsZero() {
+		ratedAt = time.Now().UTC()
+	}
+
+	if !row.MovieCached {
+		if s.movieService.usageService.IsQuotaNearlyExhausted() {
+			row.Action = ImportActionFailed
+			row.Error = "daily OMDb quota nearly exhausted; import this row again tomorrow"
+			return
+		}
+		movie, err := s.movieService.GetOrCreateByIMDbID(row.IMDbID)
+		if err != nil {
+			row.Action = ImportActionFailed
+			row.Error = err.Error()
+			return
+		}
+		row.movieID = movie.ID
+		row.MovieCached = true
+	}
+
+	var err error
+	updated := true
+	if row.Action == ImportActionCreate {
+		err = s.ratingRepo.Create(&models.Rating{
+			UserID:    userID,
+			MovieID:   row.movieID,
+			Rating:    row.Rating,
+			CreatedAt: ratedAt,
+			UpdatedAt: ratedAt,
+		})
+	} else {
+		// Only overwrite the rating that was planned against
+		updated, err = s.ratingRepo.UpdateAt(userID, row.movieID, row.Rating, ratedAt, &row.existingVersion)
+	}
+	if err != nil {
+		s.logger.Warn("failed to import rating", "user_id", userID.Hex(), "imdb_id", row.IMDbID, "error", err)
+		row.Action = ImportActionFailed
+		row.Error = "failed to save rating"
+	} else if !updated {
+		row.Action = ImportActionFailed
+		row.Error = "the rating changed while importing"
+	}
+}
+
+// parseRatingsCSV reads the rows, validating each one on its own so a bad
+// row is reported instead of failing the import. The header row is optional;
+// without it the columns are imdb_id, rating, date.
+func parseRatingsCSV(input io.Reader) ([]RatingImportRow, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := map[string]int{"imdb_id": 0, "rating": 1, "date": 2}
+	rows := []RatingImportRow{}
+	seen := make(map[string]int)
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRatingImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "imdb_id") {
+			columns = map[string]int{}
+			for i, name := range record {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			if _, ok := columns["rating"]; !ok {
+				return nil, fmt.Errorf("%w: header must have imdb_id and rating columns", ErrInvalidRatingImport)
+			}
+			continue
+		}
+
+		if len(rows) == MaxRatingImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidRatingImport, MaxRatingImportRows)
+		}
+
+		row := parseRatingRecord(record, columns)
+		row.Line = line
+		if row.Action != ImportActionInvalid {
+			if previous, ok := seen[row.IMDbID]; ok {
+				row.Action = ImportActionInvalid
+				row.Error = fmt.Sprintf("duplicate of line %d", previous)
+			} else {
+				seen[row.IMDbID] = line
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows to import", ErrInvalidRatingImport)
+	}
+	return rows, nil
+}
+
+// parseRatingRecord validates one CSV record, marking it invalid on failure
+func parseRatingRecord(record []string, columns map[string]int) RatingImportRow {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := RatingImportRow{
+		IMDbID: models.NormalizeIMDbID(field("imdb_id")),
+		Date:   field("date"),
+	}
+	invalid := func(message string) RatingImportRow {
+		row.Action = ImportActionInvalid
+		row.Error = message
+		return row
+	}
+
+	if !imdbIDPattern.MatchString(row.IMDbID) {
+		return invalid("imdb_id must look like tt0111161")
+	}
+
+	rating, err := strconv.Atoi(field("rating"))
+	if err != nil || rating < 1 || rating > 5 {
+		return invalid("rating must be a whole number from 1 to 5")
+	}
+	row.Rating = rating
+
+	if row.Date != "" {
+		ratedAt, err := parseImportDate(row.Date)
+		if err != nil {
+			return invalid("date must be YYYY-MM-DD or RFC 3339")
+		}
+		if ratedAt.After(time.Now()) {
+			return invalid("date cannot be in the future")
+		}
+		row.ratedAt = ratedAt
+	}
+	return row
+}
+
+func parseImportDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
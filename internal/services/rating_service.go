@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"movie-watchlist/internal/events"
 	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/repositories"
 
@@ -10,54 +11,112 @@ import (
 
 type RatingService struct {
 	ratingRepo *repositories.RatingRepository
+	movieRepo  *repositories.MovieRepository
+	bus        *events.Bus
 }
 
-func NewRatingService(ratingRepo *repositories.RatingRepository) *RatingService {
-	return &RatingService{ratingRepo: ratingRepo}
+func NewRatingService(ratingRepo *repositories.RatingRepository, movieRepo *repositories.MovieRepository, bus *events.Bus) *RatingService {
+	return &RatingService{
+		ratingRepo: ratingRepo,
+		movieRepo:  movieRepo,
+		bus:        bus,
+	}
 }
 
-func (s *RatingService) RateMovie(userID primitive.ObjectID, movieID primitive.ObjectID, rating int) error {
+// RateMovie rates the canonical copy of the movie; a rating on any duplicate
+// document with the same IMDb ID counts as an existing rating
+func (s *RatingService) RateMovie(userID primitive.ObjectID, movieID primitive.ObjectID, rating int) (*models.Rating, error) {
 	if rating < 1 || rating > 5 {
-		return errors.New("rating must be between 1 and 5 stars")
+		return nil, errors.New("rating must be between 1 and 5 stars")
+	}
+
+	canonicalID, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if user has already rated this movie
-	existing, err := s.ratingRepo.GetUserRating(userID, movieID)
+	existing, err := s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
 	if err == nil && existing != nil {
-		return errors.New("user has already rated this movie")
+		return nil, errors.New("user has already rated this movie")
 	}
 
 	newRating := &models.Rating{
 		UserID:  userID,
-		MovieID: movieID,
+		MovieID: canonicalID,
 		Rating:  rating,
 	}
 
-	return s.ratingRepo.Create(newRating)
+	if err := s.ratingRepo.Create(newRating); err != nil {
+		return nil, err
+	}
+	s.bus.Publish(events.MovieRated{
+		UserID:        userID,
+		MovieID:       canonicalID,
+		EquivalentIDs: equivalentIDs,
+		Rating:        rating,
+		At:            newRating.CreatedAt,
+	})
+	return newRating, nil
 }
 
-func (s *RatingService) UpdateRating(userID primitive.ObjectID, movieID primitive.ObjectID, rating int) error {
+// UpdateRating updates the user's rating on the movie or any duplicate of it
+// and returns the updated rating. When expectedVersion is set, the rating must
+// still be at that version or ErrVersionConflict is returned.
+func (s *RatingService) UpdateRating(userID primitive.ObjectID, movieID primitive.ObjectID, rating int, expectedVersion *int) (*models.Rating, error) {
 	if rating < 1 || rating > 5 {
-		return errors.New("rating must be between 1 and 5 stars")
+		return nil, errors.New("rating must be between 1 and 5 stars")
+	}
+
+	canonicalID, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if rating exists before updating
-	existing, err := s.ratingRepo.GetUserRating(userID, movieID)
+	existing, err := s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
 	if err != nil {
-		return errors.New("rating not found")
+		return nil, errors.New("rating not found")
 	}
 
 	if existing == nil {
-		return errors.New("rating not found")
+		return nil, errors.New("rating not found")
 	}
 
-	return s.ratingRepo.Update(userID, movieID, rating)
+	updated, err := s.ratingRepo.Update(userID, existing.MovieID, rating, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		// The rating exists, so only the version can have failed to match
+		return nil, ErrVersionConflict
+	}
+
+	updatedRating, err := s.ratingRepo.GetUserRating(userID, existing.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if updatedRating != nil {
+		s.bus.Publish(events.RatingUpdated{
+			UserID:        userID,
+			MovieID:       canonicalID,
+			EquivalentIDs: equivalentIDs,
+			Rating:        updatedRating.Rating,
+			At:            updatedRating.UpdatedAt,
+		})
+	}
+	return updatedRating, nil
 }
 
 func (s *RatingService) GetUserRatings(userID primitive.ObjectID) ([]models.Rating, error) {
 	return s.ratingRepo.GetUserRatings(userID)
 }
 
+// GetUserRatingsPage returns one page of the user's ratings and the total rating count
+func (s *RatingService) GetUserRatingsPage(userID primitive.ObjectID, offset, limit int) ([]models.Rating, int64, error) {
+	return s.ratingRepo.GetUserRatingsPage(userID, int64(offset), int64(limit))
+}
+
 func (s *RatingService) GetUserRating(userID primitive.ObjectID, movieID primitive.ObjectID) (*models.Rating, error) {
 	return s.ratingRepo.GetUserRating(userID, movieID)
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recentlyViewedCap is how many movies are kept in each user's history
+const recentlyViewedCap = 50
+
+type RecentViewService struct {
+	recentViewRepo *repositories.RecentViewRepository
+	movieRepo      *repositories.MovieRepository
+	logger         *slog.Logger
+}
+
+func NewRecentViewService(recentViewRepo *repositories.RecentViewRepository, movieRepo *repositories.MovieRepository) *RecentViewService {
+	return &RecentViewService{
+		recentViewRepo: recentViewRepo,
+		movieRepo:      movieRepo,
+		logger:         logging.For("services.recently_viewed"),
+	}
+}
+
+// RecordView notes that the user opened a movie's detail page. The write
+// happens in the background so it never slows down the detail response.
+func (s *RecentViewService) RecordView(userID, movieID primitive.ObjectID) {
+	viewedAt := time.Now().UTC()
+	go func() {
+		if err := s.recordView(userID, movieID, viewedAt); err != nil {
+			s.logger.Warn("failed to record view", "movie_id", movieID.Hex(), "user_id", userID.Hex(), "error", err)
+		}
+	}()
+}
+
+func (s *RecentViewService) recordView(userID, movieID primitive.ObjectID, viewedAt time.Time) error {
+	canonicalID, _, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return err
+	}
+	if err := s.recentViewRepo.Record(userID, canonicalID, viewedAt); err != nil {
+		return err
+	}
+	return s.recentViewRepo.Trim(userID, recentlyViewedCap)
+}
+
+// GetRecentlyViewedPage returns one page of the user's viewing history
+func (s *RecentViewService) GetRecentlyViewedPage(userID primitive.ObjectID, offset, limit int) ([]models.RecentView, int64, error) {
+	return s.recentViewRepo.GetRecentPage(userID, int64(offset), int64(limit))
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// conversionWindow is how long after an impression a watchlist add or a
+// rating of the movie still counts as a conversion
+const conversionWindow = 7 * 24 * time.Hour
+
+// ConversionStats are the impressions of one algorithm, row or genre and how
+// many led to a watchlist add or a rating. CTR is the share of impressions
+// that led to either.
+type ConversionStats struct {
+	Name          string  `json:"name"`
+	Impressions   int64   `json:"impressions"`
+	WatchlistAdds int64   `json:"watchlist_adds"`
+	Ratings       int64   `json:"ratings"`
+	Conversions   int64   `json:"conversions"`
+	CTR           float64 `json:"ctr"`
+}
+
+// RecommendationAnalytics is the click-through report of recommendations
+// shown since Since
+type RecommendationAnalytics struct {
+	Days       int               `json:"days"`
+	Since      time.Time         `json:"since"`
+	Total      ConversionStats   `json:"total"`
+	Algorithms []ConversionStats `json:"algorithms"`
+	Rows       []ConversionStats `json:"rows"`
+	Genres     []ConversionStats `json:"genres"`
+}
+
+// RecommendationAnalyticsService logs the recommendations users are shown and
+// whether they added or rated them afterwards
+type RecommendationAnalyticsService struct {
+	impressionRepo *repositories.RecommendationImpressionRepository
+	logger         *slog.Logger
+}
+
+func NewRecommendationAnalyticsService(impressionRepo *repositories.RecommendationImpressionRepository) *RecommendationAnalyticsService {
+	return &RecommendationAnalyticsService{
+		impressionRepo: impressionRepo,
+		logger:         logging.For("services.recommendation_analytics"),
+	}
+}
+
+// RecordImpressions logs the page of a recommendation row shown to the user;
+// offset is the position of the first movie in the full row. Logging must
+// not fail the response, so errors are only logged.
+func (s *RecommendationAnalyticsService) RecordImpressions(userID primitive.ObjectID, row string, movies []models.Movie, offset int) {
+	now := time.Now().UTC()
+	impressions := make([]models.RecommendationImpression, 0, len(movies))
+	for i, movie := range movies {
+		impressions = append(impressions, models.RecommendationImpression{
+			UserID:    userID,
+			MovieID:   movie.ID,
+			Row:       row,
+			Algorithm: movie.RecommendedBy,
+			Genres:    movieGenres(movie.Genre),
+			Position:  offset + i + 1,
+			Day:       now.Format("2006-01-02"),
+			ShownAt:   now,
+		})
+	}
+	if err := s.impressionRepo.Record(impressions); err != nil {
+		s.logger.Warn("failed to record recommendation impressions", "user_id", userID.Hex(), "row", row, "error", err)
+	}
+}
+
+// Subscribe credits the user's recent impressions of a movie, or of any
+// duplicate of it, with the watchlist adds and ratings that follow them
+func (s *RecommendationAnalyticsService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameWatchlistItemAdded, "recommendation_analytics", func(event events.Event) error {
+		added := event.(events.WatchlistItemAdded)
+		return s.recordConversion(added.UserID, added.EquivalentIDs, "watchlisted_at", added.At)
+	})
+	bus.Subscribe(events.NameMovieRated, "recommendation_analytics", func(event events.Event) error {
+		rated := event.(events.MovieRated)
+		return s.recordConversion(rated.UserID, rated.EquivalentIDs, "rated_at", rated.At)
+	})
+}
+
+func (s *RecommendationAnalyticsService) recordConversion(userID primitive.ObjectID, movieIDs []primitive.ObjectID, field string, at time.Time) error {
+	return s.impressionRepo.MarkConverted(userID, movieIDs, field, at.Add(-conversionWindow), at)
+}
+
+// GetAnalytics reports the CTR of the recommendations shown in the last days
+// days per algorithm, row and genre
+func (s *RecommendationAnalyticsService) GetAnalytics(days int) (*RecommendationAnalytics, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	summary, err := s.impressionRepo.Summarize(since)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &RecommendationAnalytics{
+		Days:       days,
+		Since:      since,
+		Total:      ConversionStats{Name: "total"},
+		Algorithms: conversionStats(summary.Algorithms),
+		Rows:       conversionStats(summary.Rows),
+		Genres:     conversionStats(summary.Genres),
+	}
+	// Every impression has exactly one algorithm, so the algorithms add up
+	// to the total
+	for _, stats := range analytics.Algorithms {
+		analytics.Total.Impressions += stats.Impressions
+		analytics.Total.WatchlistAdds += stats.WatchlistAdds
+		analytics.Total.Ratings += stats.Ratings
+		analytics.Total.Conversions += stats.Conversions
+	}
+	analytics.Total.CTR = ratio(analytics.Total.Conversions, analytics.Total.Impressions)
+	return analytics, nil
+}
+
+func conversionStats(counts []repositories.ImpressionCount) []ConversionStats {
+	stats := make([]ConversionStats, 0, len(counts))
+	for _, count := range counts {
+		name := count.Key
+		if name == "" {
+			name = "unknown"
+		}
+		stats = append(stats, ConversionStats{
+			Name:          name,
+			Impressions:   count.Impressions,
+			WatchlistAdds: count.Watchlisted,
+			Ratings:       count.Rated,
+			Conversions:   count.Converted,
+			CTR:           ratio(count.Converted, count.Impressions),
+		})
+	}
+	return stats
+}
+
+// movieGenres splits a comma-separated genre list, dropping OMDb's "N/A"
+func movieGenres(genre string) []string {
+	genres := []string{}
+	for _, name := range strings.Split(genre, ",") {
+		if name = strings.TrimSpace(name); name != "" && name != "N/A" {
+			genres = append(genres, name)
+		}
+	}
+	return genres
+}
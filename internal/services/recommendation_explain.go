@@ -0,0 +1,134 @@
+package services
+
+import (
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recommendation pipeline stages reported by ExplainRecommendations
+const (
+	StageGenreInference = repositories.RecommendationStepGenres
+	StageExclusionBuild = repositories.RecommendationStepExclusions
+	StageCandidateFetch = repositories.RecommendationStepCandidates
+	StageScoring        = "scoring"
+)
+
+// RecommendationStage is the time spent in one pipeline stage and how many
+// items it produced: preferred genres, excluded movies, candidates of all
+// recommenders, or recommendations picked from them. The first three stages
+// run in MongoDB as parts of one aggregation; scoring merges their results.
+type RecommendationStage struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+	Candidates int     `json:"candidates"`
+}
+
+// ExplainedRecommendation is one recommended movie and the recommender that
+// suggested it
+type ExplainedRecommendation struct {
+	MovieID       primitive.ObjectID `json:"movie_id"`
+	Title         string             `json:"title"`
+	RecommendedBy string             `json:"recommended_by"`
+}
+
+// RecommendationExplanation is a traced run of the recommendation pipeline
+// for one user, for diagnosing slow or empty results
+type RecommendationExplanation struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	Limit  int                `json:"limit"`
+	// RatedGenres come from 4+ star ratings and ViewedGenres from recently
+	// viewed movies; Keywords are the themes of 4+ star ratings
+	RatedGenres  []string `json:"rated_genres"`
+	ViewedGenres []string `json:"viewed_genres"`
+	Keywords     []string `json:"keywords"`
+	// Exclusions is how rated and watchlisted movies were left out:
+	// "cached" for a short list from the per-user cache, "anti_join" for a
+	// lookup of each candidate in the user's ratings and watchlist
+	Exclusions string                `json:"exclusions"`
+	Stages     []RecommendationStage `json:"stages"`
+	// Sources counts the recommendations each recommender contributed
+	Sources         map[string]int            `json:"sources"`
+	Recommendations []ExplainedRecommendation `json:"recommendations"`
+	TotalMs         float64                   `json:"total_ms"`
+}
+
+// ExplainRecommendations computes the user's recommendations like
+// GetRecommendations, recording stage timings and candidate counts. The
+// aggregation is run once per stage to time them, so a traced run is slower
+// than a normal one and is left out of the latency percentiles.
+func (s *RecommendationService) ExplainRecommendations(userID primitive.ObjectID, limit int) (*RecommendationExplanation, error) {
+	trace := &RecommendationExplanation{
+		UserID:       userID,
+		Limit:        limit,
+		RatedGenres:  []string{},
+		ViewedGenres: []string{},
+		Keywords:     []string{},
+		Stages:       []RecommendationStage{},
+		Sources:      map[string]int{},
+	}
+
+	start := time.Now()
+	movies, err := s.recommend(userID, limit, trace)
+	if err != nil {
+		return nil, err
+	}
+	trace.TotalMs = milliseconds(time.Since(start))
+
+	trace.Recommendations = make([]ExplainedRecommendation, 0, len(movies))
+	for _, movie := range movies {
+		trace.Recommendations = append(trace.Recommendations, explainedRecommendation(movie))
+	}
+	return trace, nil
+}
+
+// stage records the time since started as the named stage
+func (t *RecommendationExplanation) stage(name string, started time.Time, candidates int) {
+	if t == nil {
+		return
+	}
+	t.Stages = append(t.Stages, RecommendationStage{Name: name, DurationMs: milliseconds(time.Since(started)), Candidates: candidates})
+}
+
+func (t *RecommendationExplanation) source(algorithm string, count int) {
+	if t == nil {
+		return
+	}
+	t.Sources[algorithm] += count
+}
+
+// record adds the aggregation's signals and per-step timings. excluded is
+// the cached exclusion set passed to the aggregation, if any, and collected
+// is set when the aggregation collected the set instead.
+func (t *RecommendationExplanation) record(candidates *repositories.RecommendationCandidates, timings map[string]time.Duration, excluded []primitive.ObjectID, collected bool) {
+	t.RatedGenres = append(t.RatedGenres, candidates.RatedGenres...)
+	t.ViewedGenres = append(t.ViewedGenres, candidates.ViewedGenres...)
+	t.Keywords = append(t.Keywords, candidates.Keywords...)
+	t.Exclusions = "anti_join"
+	if excluded != nil {
+		t.Exclusions = "cached"
+	} else if collected {
+		excluded = candidates.ExcludedIDs
+	}
+
+	// The exclusion count is capped just above exclusionLiteralLimit when
+	// collected, and zero for a set known to be large
+	counts := map[string]int{
+		StageGenreInference: len(candidates.PreferredGenres),
+		StageExclusionBuild: len(excluded),
+		StageCandidateFetch: len(candidates.KeywordMovies) + len(candidates.GenreMovies) + len(candidates.TopRatedMovies),
+	}
+	for _, name := range []string{StageGenreInference, StageExclusionBuild, StageCandidateFetch} {
+		t.Stages = append(t.Stages, RecommendationStage{Name: name, DurationMs: milliseconds(timings[name]), Candidates: counts[name]})
+	}
+}
+
+func explainedRecommendation(movie models.Movie) ExplainedRecommendation {
+	return ExplainedRecommendation{MovieID: movie.ID, Title: movie.Title, RecommendedBy: movie.RecommendedBy}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
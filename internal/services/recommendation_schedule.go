@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/mailer"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxRecommendationCount caps the items per recommendation row
+	MaxRecommendationCount = 50
+	// precomputePerRun caps the users refreshed by one scheduler run
+	precomputePerRun = 200
+	// precomputeInterval is how long the scheduler waits once no user is due
+	precomputeInterval = time.Hour
+	// digestLocalHour is the hour of the user's day at which scheduled
+	// recommendations are refreshed and digests sent
+	digestLocalHour = 8
+)
+
+// ErrInvalidRecommendationSettings is returned for unknown frequencies or
+// rows and out-of-range counts
+var ErrInvalidRecommendationSettings = errors.New("invalid recommendation settings")
+
+// DefaultRecommendationSettings apply until a user changes them: computed on
+// every request, with no digest
+func DefaultRecommendationSettings() models.RecommendationSettings {
+	return models.RecommendationSettings{
+		Frequency: models.RecommendationFrequencyOnDemand,
+		Count:     10,
+		Rows:      []string{models.RecommendationRowForYou, models.RecommendationRowTrending},
+	}
+}
+
+// RecommendationScheduler stores per-user recommendation settings and, for
+// users on a daily or weekly schedule, precomputes their rows and emails the
+// digest
+type RecommendationScheduler struct {
+	userRepo              *repositories.UserRepository
+	snapshotRepo          *repositories.RecommendationSnapshotRepository
+	movieRepo             *repositories.MovieRepository
+	recommendationService *RecommendationService
+	followService         *FollowService
+	mailer                mailer.Mailer
+	bus                   *events.Bus
+	jobQueue              *jobs.Queue
+	logger                *slog.Logger
+}
+
+func NewRecommendationScheduler(userRepo *repositories.UserRepository, snapshotRepo *repositories.RecommendationSnapshotRepository, movieRepo *repositories.MovieRepository, recommendationService *RecommendationService, followService *FollowService, mailer mailer.Mailer, bus *events.Bus, jobQueue *jobs.Queue) *RecommendationScheduler {
+	return &RecommendationScheduler{
+		userRepo:              userRepo,
+		snapshotRepo:          snapshotRepo,
+		movieRepo:             movieRepo,
+		recommendationService: recommendationService,
+		followService:         followService,
+		mailer:                mailer,
+		bus:                   bus,
+		jobQueue:              jobQueue,
+		logger:                logging.For("services.recommendation_schedule"),
+	}
+}
+
+// GetSettings returns the user's recommendation settings, or the defaults
+func (s *RecommendationScheduler) GetSettings(userID primitive.ObjectID) (*models.RecommendationSettings, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return settingsOrDefault(user), nil
+}
+
+// UpdateSettings validates and stores the user's recommendation settings.
+// A daily or weekly schedule is computed on the scheduler's next run.
+func (s *RecommendationScheduler) UpdateSettings(userID primitive.ObjectID, settings models.RecommendationSettings) (*models.RecommendationSettings, error) {
+	switch settings.Frequency {
+	case models.RecommendationFrequencyDaily, models.RecommendationFrequencyWeekly, models.RecommendationFrequencyOnDemand:
+	default:
+		return nil, fmt.Errorf("%w: frequency must be daily, weekly or on-demand", ErrInvalidRecommendationSettings)
+	}
+	if settings.Count < 1 || settings.Count > MaxRecommendationCount {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidRecommendationSettings, MaxRecommendationCount)
+	}
+
+	rows := []string{}
+	for _, row := range settings.Rows {
+		row = strings.TrimSpace(row)
+		switch row {
+		case models.RecommendationRowForYou, models.RecommendationRowTrending, models.RecommendationRowFollowing:
+		default:
+			return nil, fmt.Errorf("%w: unknown row %q, expected for_you, trending or following", ErrInvalidRecommendationSettings, row)
+		}
+		rows = appendUnique(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: at least one row is required", ErrInvalidRecommendationSettings)
+	}
+	settings.Rows = rows
+	if settings.EmailDigest && settings.Frequency == models.RecommendationFrequencyOnDemand {
+		return nil, fmt.Errorf("%w: the email digest needs a daily or weekly frequency", ErrInvalidRecommendationSettings)
+	}
+
+	found, err := s.userRepo.UpdateRecommendationSettings(userID, settings)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("user not found")
+	}
+	return &settings, nil
+}
+
+// ScheduledRecommendations returns the precomputed "for you" row of a user on
+// a daily or weekly schedule and when it was computed. It returns nil movies
+// when recommendations should be computed on demand instead.
+func (s *RecommendationScheduler) ScheduledRecommendations(userID primitive.ObjectID) ([]models.Movie, *time.Time, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+	if settingsOrDefault(user).Frequency == models.RecommendationFrequencyOnDemand {
+		return nil, nil, nil
+	}
+
+	snapshot, err := s.snapshotRepo.FindByUser(userID)
+	if err != nil || snapshot == nil {
+		return nil, nil, err
+	}
+	for _, row := range snapshot.Rows {
+		if row.Name != models.RecommendationRowForYou {
+			continue
+		}
+		movies, err := s.moviesInOrder(row.MovieIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Rows stored before recommenders were recorded have no algorithms
+		if len(row.Algorithms) == len(row.MovieIDs) {
+			algorithms := make(map[primitive.ObjectID]string, len(row.MovieIDs))
+			for i, id := range row.MovieIDs {
+				algorithms[id] = row.Algorithms[i]
+			}
+			for i := range movies {
+				movies[i].RecommendedBy = algorithms[movies[i].ID]
+			}
+		}
+		return movies, &snapshot.ComputedAt, nil
+	}
+	return nil, nil, nil
+}
+
+// Subscribe drops movies the user rates or adds to their watchlist from the
+// precomputed rows, which would otherwise keep suggesting them until the
+// next refresh
+func (s *RecommendationScheduler) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameMovieRated, "recommendation_snapshots", func(event events.Event) error {
+		rated := event.(events.MovieRated)
+		return s.dropFromRows(rated.UserID, rated.EquivalentIDs)
+	})
+	bus.Subscribe(events.NameWatchlistItemAdded, "recommendation_snapshots", func(event events.Event) error {
+		added := event.(events.WatchlistItemAdded)
+		return s.dropFromRows(added.UserID, added.EquivalentIDs)
+	})
+}
+
+func (s *RecommendationScheduler) dropFromRows(userID primitive.ObjectID, movieIDs []primitive.ObjectID) error {
+	snapshot, err := s.snapshotRepo.FindByUser(userID)
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	drop := make(map[primitive.ObjectID]bool, len(movieIDs))
+	for _, id := range movieIDs {
+		drop[id] = true
+	}
+	changed := false
+	for i, row := range snapshot.Rows {
+		aligned := len(row.Algorithms) == len(row.MovieIDs)
+		kept := models.RecommendationRow{Name: row.Name, MovieIDs: make([]primitive.ObjectID, 0, len(row.MovieIDs))}
+		for j, id := range row.MovieIDs {
+			if drop[id] {
+				changed = true
+				continue
+			}
+			kept.MovieIDs = append(kept.MovieIDs, id)
+			if aligned {
+				kept.Algorithms = append(kept.Algorithms, row.Algorithms[j])
+			}
+		}
+		snapshot.Rows[i] = kept
+	}
+	if !changed {
+		return nil
+	}
+	return s.snapshotRepo.UpdateRows(userID, snapshot.Rows)
+}
+
+// EnsureScheduled queues the first precompute run if none is pending
+func (s *RecommendationScheduler) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypePrecomputeRecs, nil, time.Now().UTC())
+}
+
+// PrecomputeJob refreshes the rows of users whose daily or weekly schedule is
+// due and emails the digest to those who asked for it. It runs again right
+// away while users are left over and hourly once it has caught up.
+func (s *RecommendationScheduler) PrecomputeJob(ctx context.Context, payload map[string]interface{}) error {
+	now := time.Now().UTC()
+	users, err := s.userRepo.FindDueForRecommendations(now, precomputePerRun)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := s.refresh(user, now); err != nil {
+			// One failing user should not hold up the others; it stays due
+			s.logger.Warn("failed to precompute recommendations", "user_id", user.ID.Hex(), "error", err)
+		}
+	}
+	if len(users) > 0 {
+		s.logger.Info("precomputed recommendations", "users", len(users))
+	}
+
+	next := now.Add(precomputeInterval)
+	if len(users) == precomputePerRun {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypePrecomputeRecs, nil, next)
+}
+
+// refresh computes and stores the user's rows, then sends the digest
+func (s *RecommendationScheduler) refresh(user models.User, now time.Time) error {
+	settings := settingsOrDefault(&user)
+	snapshot := &models.RecommendationSnapshot{UserID: user.ID, ComputedAt: now}
+	titles := map[string][]models.Movie{}
+
+	for _, name := range settings.Rows {
+		var movies []models.Movie
+		var err error
+		switch name {
+		case models.RecommendationRowForYou:
+			movies, err = s.recommendationService.GetRecommendations(user.ID, settings.Count)
+		case models.RecommendationRowTrending:
+			movies, err = s.recommendationService.GetTrendingMovies(settings.Count)
+		case models.RecommendationRowFollowing:
+			movies, err = s.followService.GetNewFromFollowed(user.ID, settings.Count)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Keeps avoided advisories out of the digest too
+		movies = FilterByAdvisories(movies, user.AvoidAdvisories)
+
+		row := models.RecommendationRow{Name: name, MovieIDs: make([]primitive.ObjectID, 0, len(movies)), Algorithms: make([]string, 0, len(movies))}
+		for _, movie := range movies {
+			row.MovieIDs = append(row.MovieIDs, movie.ID)
+			row.Algorithms = append(row.Algorithms, movie.RecommendedBy)
+		}
+		snapshot.Rows = append(snapshot.Rows, row)
+		titles[name] = movies
+	}
+
+	if err := s.snapshotRepo.Upsert(snapshot); err != nil {
+		return err
+	}
+	// The next refresh is due one interval after the digest hour nearest to
+	// now in the user's timezone, so digests arrive in the local morning
+	slot := nearestLocalHour(now, locationOf(user.Timezone), digestLocalHour)
+	if err := s.userRepo.MarkRecommendationsRefreshed(user.ID, slot); err != nil {
+		return err
+	}
+	s.bus.Publish(events.RecommendationsRefreshed{UserID: user.ID, At: now})
+
+	// Demo sandbox addresses cannot receive mail
+	if settings.EmailDigest && user.DemoExpiresAt == nil {
+		if err := s.mailer.Send(user.Email, "Your movie recommendations", digestBody(user, settings, titles)); err != nil {
+			// The rows are stored; a lost digest waits for the next refresh
+			s.logger.Warn("failed to send recommendation digest", "user_id", user.ID.Hex(), "error", err)
+		}
+	}
+	return nil
+}
+
+// digestRowTitles are the headings of each row in the email digest
+var digestRowTitles = map[string]string{
+	models.RecommendationRowForYou:    "Picked for you",
+	models.RecommendationRowTrending:  "Trending this week",
+	models.RecommendationRowFollowing: "New from people you follow",
+}
+
+func digestBody(user models.User, settings *models.RecommendationSettings, rows map[string][]models.Movie) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\nHere are your %s movie recommendations.\n", user.Username, settings.Frequency)
+	for _, name := range settings.Rows {
+		fmt.Fprintf(&b, "\n%s\n", digestRowTitles[name])
+		if len(rows[name]) == 0 {
+			b.WriteString("- Nothing new this time\n")
+		}
+		for _, movie := range rows[name] {
+			fmt.Fprintf(&b, "- %s (%s)\n", movie.Title, movie.Year)
+		}
+	}
+	b.WriteString("\nYou can change how often you get these in your recommendation settings.\n")
+	return b.String()
+}
+
+// moviesInOrder loads the movies keeping the order of ids, skipping deleted ones
+func (s *RecommendationScheduler) moviesInOrder(ids []primitive.ObjectID) ([]models.Movie, error) {
+	found, err := s.movieRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	movies := make([]models.Movie, 0, len(ids))
+	for _, id := range ids {
+		if movie, ok := found[id]; ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+func settingsOrDefault(user *models.User) *models.RecommendationSettings {
+	if user.RecommendationSettings != nil {
+		return user.RecommendationSettings
+	}
+	settings := DefaultRecommendationSettings()
+	return &settings
+}
@@ -1,18 +1,41 @@
 package services
 
 import (
+	"movie-watchlist/internal/events"
 	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/repositories"
+	"sort"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// recentViewSignalSize is how many recently viewed movies feed genre preferences
+const recentViewSignalSize = 20
+
+// trendingWindow is how far back activity counts towards trending movies
+const trendingWindow = 7 * 24 * time.Hour
+
+// keywordSignalSize is how many of the user's favourite themes are matched
+const keywordSignalSize = 10
+
+// Recommenders a recommended movie can come from, as reported in analytics
+const (
+	algorithmKeyword   = "keyword"
+	algorithmGenre     = "genre"
+	algorithmTopRated  = "top_rated"
+	algorithmTrending  = "trending"
+	algorithmFollowing = "following"
+)
+
 type RecommendationService struct {
 	movieRepo              *repositories.MovieRepository
 	ratingRepo             *repositories.RatingRepository
 	watchlistRepo          *repositories.WatchlistRepository
 	recommendationRepo      *repositories.RecommendationRepository
+	exclusions             *exclusionCache
+	latency                *latencyRecorder
 }
 
 func NewRecommendationService(movieRepo *repositories.MovieRepository, ratingRepo *repositories.RatingRepository, watchlistRepo *repositories.WatchlistRepository) *RecommendationService {
@@ -21,33 +44,159 @@ func NewRecommendationService(movieRepo *repositories.MovieRepository, ratingRep
 		ratingRepo:        ratingRepo,
 		watchlistRepo:     watchlistRepo,
 		recommendationRepo: repositories.NewRecommendationRepository(movieRepo.GetDB()),
+		exclusions:         newExclusionCache(),
+		latency:            newLatencyRecorder(),
 	}
 }
 
+// Subscribe keeps the cached exclusion sets current. It must be subscribed
+// before consumers that compute recommendations, such as DashboardService.
+func (s *RecommendationService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameMovieRated, "recommendation_exclusions", func(event events.Event) error {
+		rated := event.(events.MovieRated)
+		s.exclusions.add(rated.UserID, append([]primitive.ObjectID{rated.MovieID}, rated.EquivalentIDs...))
+		return nil
+	})
+	bus.Subscribe(events.NameWatchlistItemAdded, "recommendation_exclusions", func(event events.Event) error {
+		added := event.(events.WatchlistItemAdded)
+		s.exclusions.add(added.UserID, append([]primitive.ObjectID{added.MovieID}, added.EquivalentIDs...))
+		return nil
+	})
+	bus.Subscribe(events.NameWatchlistItemRemoved, "recommendation_exclusions", func(event events.Event) error {
+		s.exclusions.invalidate(event.(events.WatchlistItemRemoved).UserID)
+		return nil
+	})
+}
+
+// LatencyPercentiles returns percentiles over recent recommendation computations
+func (s *RecommendationService) LatencyPercentiles() LatencyPercentiles {
+	return s.latency.Percentiles()
+}
+
 func (s *RecommendationService) GetRecommendations(userID primitive.ObjectID, limit int) ([]models.Movie, error) {
-	// Step 1: Get user's preferred genres (rated 4+ stars)
-	preferredGenres, err := s.recommendationRepo.GetHighRatedGenres(userID, 4)
+	start := time.Now()
+	defer func() { s.latency.Record(time.Since(start)) }()
+
+	return s.recommend(userID, limit, nil)
+}
+
+// recommend runs the recommendation pipeline, recording stage timings and
+// candidate counts on trace unless it is nil
+func (s *RecommendationService) recommend(userID primitive.ObjectID, limit int, trace *RecommendationExplanation) ([]models.Movie, error) {
+	if limit <= 0 {
+		return []models.Movie{}, nil
+	}
+
+	// Step 1: One aggregation collects the genres of movies rated 4+ stars
+	// (extended by recently viewed genres), the favourite themes and every
+	// recommender's candidates, leaving out movies already rated or in the
+	// watchlist. A small cached exclusion set is passed in; otherwise the
+	// candidates are anti-joined and the set collected for next time,
+	// unless it is known to be large.
+	excluded, large, seq := s.exclusions.get(userID)
+	collect := 0
+	if excluded == nil && !large {
+		collect = exclusionLiteralLimit
+	}
+	query := repositories.RecommendationQuery{
+		UserID:       userID,
+		Threshold:    4,
+		RecentViews:  recentViewSignalSize,
+		Keywords:     keywordSignalSize,
+		KeywordLimit: limit / 2,
+		Limit:        limit,
+		ExcludedIDs:  excluded,
+		// Collected only to decide how to exclude next time
+		CollectExclusions: collect,
+	}
+	var candidates *repositories.RecommendationCandidates
+	var err error
+	if trace != nil {
+		var timings map[string]time.Duration
+		if candidates, timings, err = s.recommendationRepo.ProfileRecommendationCandidates(query); err == nil {
+			trace.record(candidates, timings, excluded, collect > 0)
+		}
+	} else {
+		candidates, err = s.recommendationRepo.GetRecommendationCandidates(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if collect > 0 {
+		s.exclusions.store(userID, candidates.ExcludedIDs, seq)
+	}
+
+	// Step 2: Movies sharing the most themes with the user's favourites come
+	// first, filling at most half the list so genre matches still show up.
+	// Genre matches follow, then top rated movies as a fallback.
+	stageStart := time.Now()
+	recommendations := make([]models.Movie, 0, limit)
+	picked := make(map[primitive.ObjectID]bool, limit)
+	add := func(movies []models.Movie, algorithm string, upTo int) {
+		count := 0
+		for _, movie := range movies {
+			if len(recommendations) >= upTo {
+				break
+			}
+			if picked[movie.ID] {
+				continue
+			}
+			picked[movie.ID] = true
+			movie.RecommendedBy = algorithm
+			recommendations = append(recommendations, movie)
+			count++
+		}
+		trace.source(algorithm, count)
+	}
+	add(candidates.KeywordMovies, algorithmKeyword, limit/2)
+	add(candidates.GenreMovies, algorithmGenre, limit)
+	add(candidates.TopRatedMovies, algorithmTopRated, limit)
+	trace.stage(StageScoring, stageStart, len(recommendations))
+
+	return recommendations, nil
+}
+
+// GetTrendingMovies returns the movies most added to watchlists or rated
+// within the trending window, topped up with the highest rated cached movies
+func (s *RecommendationService) GetTrendingMovies(limit int) ([]models.Movie, error) {
+	counts, err := s.recommendationRepo.GetActivityCountsSince(time.Now().UTC().Add(-trendingWindow))
 	if err != nil {
 		return nil, err
 	}
 
-	// Step 2: Get movies to exclude (already rated + in watchlist)
-	excludeMovieIDs, err := s.recommendationRepo.GetMoviesToExclude(userID)
+	ids := make([]primitive.ObjectID, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	// Most active first; ties broken by ID for a stable order
+	sort.Slice(ids, func(i, j int) bool {
+		if counts[ids[i]] != counts[ids[j]] {
+			return counts[ids[i]] > counts[ids[j]]
+		}
+		return ids[i].Hex() < ids[j].Hex()
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	movies, err := s.movieRepo.FindByIDs(ids)
 	if err != nil {
 		return nil, err
 	}
 
-	// Step 3: Generate recommendations based on preferred genres
-	recommendations := s.generateGenreBasedRecommendations(preferredGenres, excludeMovieIDs, limit)
+	trending := make([]models.Movie, 0, limit)
+	for _, id := range ids {
+		if movie, ok := movies[id]; ok {
+			movie.RecommendedBy = algorithmTrending
+			trending = append(trending, movie)
+		}
+	}
 
-	// Step 4: If not enough recommendations, add popular movies as fallback
-	if len(recommendations) < limit {
-		fallbackMovies := s.getFallbackRecommendations(excludeMovieIDs, limit-len(recommendations))
-		recommendations = append(recommendations, fallbackMovies...)
+	if len(trending) < limit {
+		trending = append(trending, recommendedBy(s.getFallbackRecommendations(ids, limit-len(trending)), algorithmTopRated)...)
 	}
 
-	// Step 5: Return limited results (deterministic ordering)
-	return s.limitResults(recommendations, limit), nil
+	return trending, nil
 }
 
 // getPreferredGenres identifies genres user rated 4+ stars
@@ -60,61 +209,22 @@ func (s *RecommendationService) getExcludedMovieIDs(userID primitive.ObjectID) (
 	return s.recommendationRepo.GetMoviesToExclude(userID)
 }
 
-// generateGenreBasedRecommendations creates recommendations from preferred genres
-func (s *RecommendationService) generateGenreBasedRecommendations(preferredGenres []string, excludeMovieIDs []primitive.ObjectID, limit int) []models.Movie {
-	var recommendations []models.Movie
-
-	// Process each preferred genre in order
-	for _, genre := range preferredGenres {
-		if len(recommendations) >= limit {
-			break
-		}
-
-		// Get movies in this genre, excluding already watched/rated movies
-		movies, err := s.recommendationRepo.GetMoviesByGenreExcludingIDs(genre, excludeMovieIDs, limit-len(recommendations))
-		if err != nil {
-			continue
-		}
-
-		// Add movies (deterministic order by IMDb rating)
-		for _, movie := range movies {
-			if len(recommendations) >= limit {
-				break
-			}
-			recommendations = append(recommendations, movie)
-		}
-	}
-
-	return recommendations
-}
-
-// getFallbackRecommendations provides popular movies when genre-based recommendations are insufficient
+// getFallbackRecommendations provides the highest rated movies when other
+// recommendations are insufficient
 func (s *RecommendationService) getFallbackRecommendations(excludeMovieIDs []primitive.ObjectID, limit int) []models.Movie {
-	var fallback []models.Movie
-
-	// Get all movies as fallback
-	allMovies, err := s.movieRepo.FindAll()
+	fallback, err := s.movieRepo.FindTopRated(excludeMovieIDs, int64(limit))
 	if err != nil {
-		return fallback
-	}
-
-	// Create exclusion map for faster lookup
-	excludeMap := make(map[primitive.ObjectID]bool)
-	for _, id := range excludeMovieIDs {
-		excludeMap[id] = true
+		return []models.Movie{}
 	}
+	return fallback
+}
 
-	// Add movies that aren't excluded (deterministic order by IMDb rating)
-	for _, movie := range allMovies {
-		if len(fallback) >= limit {
-			break
-		}
-		if !excludeMap[movie.ID] {
-			fallback = append(fallback, movie)
-		}
+// recommendedBy marks the movies as suggested by the given recommender
+func recommendedBy(movies []models.Movie, algorithm string) []models.Movie {
+	for i := range movies {
+		movies[i].RecommendedBy = algorithm
 	}
-
-	return fallback
+	return movies
 }
 
 // limitResults returns a deterministic slice of results
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// reconcilePerRun caps the movies cross-checked by one job run, which
+	// spends one secondary provider request each
+	reconcilePerRun = 20
+	// reconcileInterval is how often the reconciliation job runs
+	reconcileInterval = time.Hour
+	// runtimeTolerance is how many minutes providers may disagree on a
+	// runtime before it counts as a conflict
+	runtimeTolerance = 2
+)
+
+var (
+	ErrConflictNotFound = errors.New("metadata conflict not found")
+	ErrConflictResolved = errors.New("metadata conflict already resolved")
+)
+
+// genreAliases maps genre names that differ between providers to OMDb's
+var genreAliases = map[string]string{
+	"science fiction": "sci-fi",
+}
+
+// ReconciliationService cross-checks cached movie details against a
+// secondary provider and lets admins settle the conflicts it finds
+type ReconciliationService struct {
+	movieService *MovieService
+	movieRepo    *repositories.MovieRepository
+	conflictRepo *repositories.MetadataConflictRepository
+	jobQueue     *jobs.Queue
+	logger       *slog.Logger
+}
+
+func NewReconciliationService(movieService *MovieService, movieRepo *repositories.MovieRepository, conflictRepo *repositories.MetadataConflictRepository, jobQueue *jobs.Queue) *ReconciliationService {
+	return &ReconciliationService{
+		movieService: movieService,
+		movieRepo:    movieRepo,
+		conflictRepo: conflictRepo,
+		jobQueue:     jobQueue,
+		logger:       logging.For("services.reconciliation"),
+	}
+}
+
+// EnsureScheduled queues the first reconciliation run if none is pending
+func (s *ReconciliationService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeReconcileMetadata, nil, time.Now().UTC())
+}
+
+// ReconcileJob fetches movies not checked for MetadataStaleAfter from the
+// first other provider in the chain and records runtime and genre
+// disagreements. It runs hourly and stops early once the other providers
+// are rate limited.
+func (s *ReconciliationService) ReconcileJob(ctx context.Context, payload map[string]interface{}) error {
+	next := time.Now().UTC().Add(reconcileInterval)
+
+	// With a single provider there is nothing to cross-check against
+	if len(s.movieService.metadata.Providers()) < 2 {
+		return s.jobQueue.EnqueueAt(jobs.TypeReconcileMetadata, nil, next)
+	}
+
+	now := time.Now().UTC()
+	movies, err := s.movieRepo.FindForReconciliation(now.Add(-MetadataStaleAfter), reconcilePerRun)
+	if err != nil {
+		return err
+	}
+
+	checked, conflicts := 0, 0
+	for _, movie := range movies {
+		primary := movie.MetadataProvider
+		if primary == "" {
+			primary = movie.Source
+		}
+
+		details, secondary, err := s.movieService.metadata.fetchExcept(ctx, movie.IMDbID, primary)
+		if err != nil {
+			if errors.Is(err, ErrProviderRateLimited) || ctx.Err() != nil {
+				break
+			}
+			// Movies the other providers do not know are not retried until
+			// they are due again
+			s.logger.Warn("failed to fetch movie for reconciliation", "imdb_id", movie.IMDbID, "error", err)
+		} else {
+			fields := compareDetails(&movie, details)
+			if err := s.record(&movie, primary, secondary, fields, now); err != nil {
+				return err
+			}
+			if len(fields) > 0 {
+				conflicts++
+			}
+		}
+
+		if err := s.movieRepo.MarkReconciled(movie.ID, now); err != nil {
+			return err
+		}
+		checked++
+	}
+	if checked > 0 {
+		s.logger.Info("reconciled movie metadata", "movies", checked, "conflicts", conflicts)
+	}
+
+	return s.jobQueue.EnqueueAt(jobs.TypeReconcileMetadata, nil, next)
+}
+
+// record stores the movie's conflict, or drops its open conflict when the
+// providers now agree. A conflict an admin settled stays settled while the
+// secondary values do not change.
+func (s *ReconciliationService) record(movie *models.Movie, primary, secondary string, fields []models.FieldConflict, now time.Time) error {
+	existing, err := s.conflictRepo.FindByMovie(movie.ID)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		if existing != nil && existing.Status == models.ConflictOpen {
+			return s.conflictRepo.DeleteOpen(movie.ID)
+		}
+		return nil
+	}
+	if existing != nil && existing.Status != models.ConflictOpen && sameFieldConflicts(existing.Fields, fields) {
+		return nil
+	}
+
+	return s.conflictRepo.Open(&models.MetadataConflict{
+		MovieID:           movie.ID,
+		IMDbID:            movie.IMDbID,
+		Title:             movie.Title,
+		CachedProvider:    primary,
+		SecondaryProvider: secondary,
+		Fields:            fields,
+		DetectedAt:        now,
+	})
+}
+
+// CountConflicts counts conflicts per status
+func (s *ReconciliationService) CountConflicts() (map[string]int64, error) {
+	return s.conflictRepo.CountByStatus()
+}
+
+// ListConflicts returns a page of conflicts with the given status, or of all
+// conflicts when status is empty
+func (s *ReconciliationService) ListConflicts(status string, cursor pagination.Cursor) ([]models.MetadataConflict, bool, error) {
+	return s.conflictRepo.List(status, cursor)
+}
+
+// Accept copies the secondary provider's values onto the movie
+func (s *ReconciliationService) Accept(conflictID, adminID primitive.ObjectID) (*models.MetadataConflict, error) {
+	conflict, err := s.openConflict(conflictID)
+	if err != nil {
+		return nil, err
+	}
+
+	movie, err := s.movieRepo.FindByID(conflict.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, ErrConflictNotFound
+	}
+
+	values := make(map[string]string, len(conflict.Fields))
+	for _, field := range conflict.Fields {
+		values[field.Field] = field.Secondary
+	}
+	found, err := s.movieRepo.SetReconciledFields(conflict.MovieID, values)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrConflictNotFound
+	}
+	s.movieService.history.Record(movie, values, changedByAdmin(adminID))
+	return s.resolve(conflict, models.ConflictAccepted, adminID)
+}
+
+// Override keeps the cached values
+func (s *ReconciliationService) Override(conflictID, adminID primitive.ObjectID) (*models.MetadataConflict, error) {
+	conflict, err := s.openConflict(conflictID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(conflict, models.ConflictOverridden, adminID)
+}
+
+func (s *ReconciliationService) openConflict(conflictID primitive.ObjectID) (*models.MetadataConflict, error) {
+	conflict, err := s.conflictRepo.FindByID(conflictID)
+	if err != nil {
+		return nil, err
+	}
+	if conflict == nil {
+		return nil, ErrConflictNotFound
+	}
+	if conflict.Status != models.ConflictOpen {
+		return nil, ErrConflictResolved
+	}
+	return conflict, nil
+}
+
+func (s *ReconciliationService) resolve(conflict *models.MetadataConflict, status string, adminID primitive.ObjectID) (*models.MetadataConflict, error) {
+	now := time.Now().UTC()
+	resolved, err := s.conflictRepo.Resolve(conflict.ID, status, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return nil, ErrConflictResolved
+	}
+	conflict.Status = status
+	conflict.ResolvedAt = &now
+	conflict.ResolvedBy = &adminID
+	return conflict, nil
+}
+
+// compareDetails lists the fields where the cached movie and the secondary
+// details disagree. Values either side does not know are not compared.
+func compareDetails(movie *models.Movie, details *OMDbResponse) []models.FieldConflict {
+	fields := []models.FieldConflict{}
+
+	secondary := models.Movie{Runtime: details.Runtime}
+	cachedMinutes, secondaryMinutes := movie.RuntimeMinutes(), secondary.RuntimeMinutes()
+	if cachedMinutes > 0 && secondaryMinutes > 0 {
+		diff := cachedMinutes - secondaryMinutes
+		if diff > runtimeTolerance || diff < -runtimeTolerance {
+			fields = append(fields, models.FieldConflict{Field: "runtime", Cached: movie.Runtime, Secondary: details.Runtime})
+		}
+	}
+
+	cachedGenres, secondaryGenres := genreSet(movie.Genre), genreSet(details.Genre)
+	if cachedGenres != "" && secondaryGenres != "" && cachedGenres != secondaryGenres {
+		fields = append(fields, models.FieldConflict{Field: "genre", Cached: movie.Genre, Secondary: details.Genre})
+	}
+	return fields
+}
+
+// genreSet normalizes a comma-separated genre list into a sorted, lower-case
+// key, so lists with the same genres in another order or spelling compare equal
+func genreSet(genre string) string {
+	seen := map[string]bool{}
+	genres := []string{}
+	for _, name := range strings.Split(genre, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := genreAliases[name]; ok {
+			name = alias
+		}
+		if name == "" || name == "n/a" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		genres = append(genres, name)
+	}
+	sort.Strings(genres)
+	return strings.Join(genres, ",")
+}
+
+func sameFieldConflicts(a, b []models.FieldConflict) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// omdbReleasedLayout is how OMDb formats release dates, e.g. "16 Jul 2010"
+const omdbReleasedLayout = "02 Jan 2006"
+
+// ReleaseInfo is an announced movie returned by a ReleaseProvider
+type ReleaseInfo struct {
+	Details     OMDbResponse
+	ReleaseDate time.Time
+}
+
+// ReleaseProvider looks up announced movies. Implementations may spend
+// external API quota, so callers keep the number of lookups small.
+type ReleaseProvider interface {
+	// UpcomingForFranchise returns movies matching the title keyword that
+	// release after from
+	UpcomingForFranchise(ctx context.Context, keyword string, from time.Time) ([]ReleaseInfo, error)
+}
+
+// omdbReleaseProvider finds releases through OMDb title search restricted to
+// this year and next. OMDb cannot search by person, so directors are matched
+// against what franchise lookups find rather than searched directly.
+type omdbReleaseProvider struct {
+	movieService *MovieService
+}
+
+func NewOMDbReleaseProvider(movieService *MovieService) ReleaseProvider {
+	return &omdbReleaseProvider{movieService: movieService}
+}
+
+func (p *omdbReleaseProvider) UpcomingForFranchise(ctx context.Context, keyword string, from time.Time) ([]ReleaseInfo, error) {
+	s := p.movieService
+	releases := []ReleaseInfo{}
+
+	for _, year := range []int{from.Year(), from.Year() + 1} {
+		if s.usageService.IsQuotaNearlyExhausted() {
+			break
+		}
+		searchResp, err := s.omdbSearchRequest(ctx, keyword, 1, year)
+		if err != nil {
+			return releases, err
+		}
+		if searchResp.Response == "False" {
+			continue
+		}
+
+		for _, hit := range searchResp.Search {
+			if s.usageService.IsQuotaNearlyExhausted() {
+				break
+			}
+			details, err := s.fetchMovieDetails(ctx, hit.IMDbID)
+			if err != nil {
+				s.logger.Warn("calendar: failed to fetch release details", "imdb_id", hit.IMDbID, "error", err)
+				continue
+			}
+
+			// Titles without an announced date cannot go on a calendar
+			released, err := time.Parse(omdbReleasedLayout, strings.TrimSpace(details.Released))
+			if err != nil || !released.After(from) {
+				continue
+			}
+			releases = append(releases, ReleaseInfo{Details: *details, ReleaseDate: released})
+		}
+	}
+	return releases, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxReviewLength caps a review's text, in characters
+	maxReviewLength = 5000
+	// topReviewersLimit is how many reviewers the top reviewers board lists
+	topReviewersLimit = 20
+)
+
+// Review sort orders
+const (
+	ReviewSortHelpful = "helpful"
+	ReviewSortNewest  = "newest"
+)
+
+var (
+	ErrReviewNotFound      = errors.New("review not found")
+	ErrReviewMovieNotFound = errors.New("movie not found")
+	ErrInvalidReview       = errors.New("invalid review")
+	ErrVoteOwnReview       = errors.New("cannot vote for your own review")
+)
+
+// ReviewService lets users write one review per movie and vote other users'
+// reviews helpful. Votes order a movie's reviews and the top reviewers board.
+type ReviewService struct {
+	reviewRepo *repositories.ReviewRepository
+	userRepo   *repositories.UserRepository
+	movieRepo  *repositories.MovieRepository
+}
+
+func NewReviewService(reviewRepo *repositories.ReviewRepository, userRepo *repositories.UserRepository, movieRepo *repositories.MovieRepository) *ReviewService {
+	return &ReviewService{
+		reviewRepo: reviewRepo,
+		userRepo:   userRepo,
+		movieRepo:  movieRepo,
+	}
+}
+
+// ValidReviewSort reports whether sort is a review sort order
+func ValidReviewSort(sort string) bool {
+	return sort == ReviewSortHelpful || sort == ReviewSortNewest
+}
+
+// Write stores the user's review of a cached movie, replacing an earlier one.
+// The review keeps the votes it already had.
+func (s *ReviewService) Write(userID, movieID primitive.ObjectID, body string) (*models.Review, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body must not be empty", ErrInvalidReview)
+	}
+	if len([]rune(body)) > maxReviewLength {
+		return nil, fmt.Errorf("%w: body must be at most %d characters", ErrInvalidReview, maxReviewLength)
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, ErrReviewMovieNotFound
+	}
+
+	return s.reviewRepo.Save(&models.Review{
+		UserID:   userID,
+		Username: user.Username,
+		MovieID:  movieID,
+		Body:     body,
+	})
+}
+
+// Get returns a review, or nil when there is none with the ID. Callers check
+// ownership with the authz policy.
+func (s *ReviewService) Get(id primitive.ObjectID) (*models.Review, error) {
+	return s.reviewRepo.FindByID(id)
+}
+
+// Delete removes a review and its votes
+func (s *ReviewService) Delete(id primitive.ObjectID) error {
+	return s.reviewRepo.Delete(id)
+}
+
+// ListForMovie returns one page of the movie's reviews in the given sort
+// order and the total count. With a reader, each review tells whether the
+// reader voted it helpful.
+func (s *ReviewService) ListForMovie(movieID primitive.ObjectID, sort string, reader *primitive.ObjectID, offset, limit int) ([]models.Review, int64, error) {
+	reviews, total, err := s.reviewRepo.FindByMoviePage(movieID, sort != ReviewSortNewest, int64(offset), int64(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	if reader == nil {
+		return reviews, total, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(reviews))
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+	}
+	voted, err := s.reviewRepo.FindVotedIDs(*reader, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range reviews {
+		votedHelpful := voted[reviews[i].ID]
+		reviews[i].VotedHelpful = &votedHelpful
+	}
+	return reviews, total, nil
+}
+
+// Vote marks a review helpful for the user and returns it with its new
+// count. Voting again changes nothing.
+func (s *ReviewService) Vote(userID, reviewID primitive.ObjectID) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+	if review.UserID == userID {
+		return nil, ErrVoteOwnReview
+	}
+
+	if _, err := s.reviewRepo.AddVote(reviewID, userID); err != nil {
+		return nil, err
+	}
+	return s.votedReview(reviewID, true)
+}
+
+// Unvote withdraws the user's helpful vote on a review and returns it with
+// its new count. Withdrawing a vote that was not cast changes nothing.
+func (s *ReviewService) Unvote(userID, reviewID primitive.ObjectID) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+
+	if _, err := s.reviewRepo.RemoveVote(reviewID, userID); err != nil {
+		return nil, err
+	}
+	return s.votedReview(reviewID, false)
+}
+
+// TopReviewers returns the reviewers whose reviews got the most helpful votes
+func (s *ReviewService) TopReviewers() ([]models.ReviewerStanding, error) {
+	return s.reviewRepo.TopReviewers(topReviewersLimit)
+}
+
+// votedReview reads a review back after a vote changed its count
+func (s *ReviewService) votedReview(reviewID primitive.ObjectID, voted bool) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+	review.VotedHelpful = &voted
+	return review, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Search result sources reported on each unified search hit
+const (
+	SearchSourceOMDb  = "omdb"
+	SearchSourceLocal = "local"
+	SearchSourceBoth  = "both"
+)
+
+// Blend weights for unified search ranking; they sum to 1
+const (
+	searchRelevanceWeight  = 0.6
+	searchPopularityWeight = 0.25
+	searchAffinityWeight   = 0.15
+)
+
+// searchAffinityGenres is how many of the user's favourite genres count
+// towards search affinity
+const searchAffinityGenres = 5
+
+// searchCandidate is one deduplicated hit being ranked
+type searchCandidate struct {
+	result    OMDbResponse
+	relevance float64
+	score     float64
+}
+
+// blendSearchResults merges one page of OMDb hits with local text-index
+// matches, dedupes them by IMDb ID and orders them by a blend of relevance,
+// local popularity and the user's genre affinity. Local-only matches are
+// folded into the first page; later pages drop them so they are not repeated.
+func (s *MovieService) blendSearchResults(remote *SearchResult, local []repositories.ScoredMovie, page int, userID *primitive.ObjectID) *SearchResult {
+	localRelevance := make(map[string]float64, len(local))
+	maxScore := 0.0
+	for _, match := range local {
+		maxScore = math.Max(maxScore, match.Score)
+	}
+	for _, match := range local {
+		relevance := 0.0
+		if maxScore > 0 {
+			relevance = match.Score / maxScore
+		}
+		localRelevance[models.NormalizeIMDbID(match.IMDbID)] = relevance
+	}
+
+	candidates := make([]*searchCandidate, 0, len(remote.Movies)+len(local))
+	byID := make(map[string]*searchCandidate)
+	for i, hit := range remote.Movies {
+		id := models.NormalizeIMDbID(hit.IMDbID)
+		if _, seen := byID[id]; seen {
+			continue
+		}
+
+		relevance, isLocal := localRelevance[id]
+		if page > 1 && isLocal {
+			continue
+		}
+
+		hit.Source = SearchSourceOMDb
+		remoteRelevance := 1 - float64(i)/float64(len(remote.Movies))
+		if isLocal {
+			hit.Source = SearchSourceBoth
+			remoteRelevance = math.Max(remoteRelevance, relevance)
+		}
+		candidate := &searchCandidate{result: hit, relevance: remoteRelevance}
+		byID[id] = candidate
+		candidates = append(candidates, candidate)
+	}
+
+	localOnly := 0
+	if page == 1 {
+		for _, match := range local {
+			id := models.NormalizeIMDbID(match.IMDbID)
+			if _, seen := byID[id]; seen {
+				continue
+			}
+			result := omdbResponseFromMovie(&match.Movie)
+			result.Source = SearchSourceLocal
+			candidate := &searchCandidate{result: result, relevance: localRelevance[id]}
+			byID[id] = candidate
+			candidates = append(candidates, candidate)
+			localOnly++
+		}
+	}
+
+	s.scoreSearchCandidates(candidates, userID)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	movies := make([]OMDbResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		movies = append(movies, candidate.result)
+	}
+	return &SearchResult{Movies: movies, Total: remote.Total + int64(localOnly)}
+}
+
+// scoreSearchCandidates fills in each candidate's blended score, enriching
+// OMDb hits with cached details along the way. Popularity and affinity are
+// best-effort: a lookup failure just leaves that signal at zero.
+func (s *MovieService) scoreSearchCandidates(candidates []*searchCandidate, userID *primitive.ObjectID) {
+	imdbIDs := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		imdbIDs = append(imdbIDs, candidate.result.IMDbID)
+	}
+
+	cached, err := s.movieRepo.FindByIMDbIDs(imdbIDs, "genre")
+	if err != nil {
+		s.logger.Warn("search: failed to load cached movies", "error", err)
+		cached = map[string]models.Movie{}
+	}
+
+	movieIDs := make([]primitive.ObjectID, 0, len(cached))
+	for _, movie := range cached {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+	counts, err := s.recommendationRepo.GetActivityCountsForMovies(movieIDs)
+	if err != nil {
+		s.logger.Warn("search: failed to load popularity", "error", err)
+		counts = map[primitive.ObjectID]int64{}
+	}
+	var maxCount int64
+	for _, count := range counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	affinity := make(map[string]float64)
+	if userID != nil {
+		genres, err := s.recommendationRepo.GetHighRatedGenres(*userID, 4)
+		if err != nil {
+			s.logger.Warn("search: failed to load genre affinity", "user_id", userID.Hex(), "error", err)
+		}
+		if len(genres) > searchAffinityGenres {
+			genres = genres[:searchAffinityGenres]
+		}
+		// Favourite genres weigh more the higher they rank
+		for i, genre := range genres {
+			affinity[strings.ToLower(genre)] = 1 - float64(i)/float64(len(genres))
+		}
+	}
+
+	for _, candidate := range candidates {
+		popularity, genreAffinity := 0.0, 0.0
+		if movie, ok := cached[models.NormalizeIMDbID(candidate.result.IMDbID)]; ok {
+			if candidate.result.Genre == "" {
+				candidate.result.Genre = movie.Genre
+			}
+			if maxCount > 0 {
+				popularity = math.Log1p(float64(counts[movie.ID])) / math.Log1p(float64(maxCount))
+			}
+		}
+		for _, genre := range strings.Split(candidate.result.Genre, ",") {
+			genreAffinity = math.Max(genreAffinity, affinity[strings.ToLower(strings.TrimSpace(genre))])
+		}
+
+		candidate.score = searchRelevanceWeight*candidate.relevance +
+			searchPopularityWeight*popularity +
+			searchAffinityWeight*genreAffinity
+	}
+}
+
+// omdbResponseFromMovie presents a cached movie in the OMDb result shape
+func omdbResponseFromMovie(movie *models.Movie) OMDbResponse {
+	return OMDbResponse{
+		Title:      movie.Title,
+		Year:       movie.Year,
+		IMDbID:     movie.IMDbID,
+		Genre:      movie.Genre,
+		Director:   movie.Director,
+		Plot:       movie.Plot,
+		Poster:     movie.Poster,
+		Runtime:    movie.Runtime,
+		IMDbRating: movie.IMDbRating,
+		Language:   movie.Language,
+		Rated:      movie.Rated,
+		Response:   "True",
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// embedBatchSize is how many plots go to the embedder per call
+	embedBatchSize = 32
+	// embedPerRun caps the plots embedded by one job run
+	embedPerRun = 512
+	// embedInterval is how long the job waits once every plot is embedded
+	embedInterval = time.Hour
+	// MaxSemanticResults caps similar-movie and free-text result lists
+	MaxSemanticResults = 100
+)
+
+var (
+	// ErrNoPlot is returned for similarity lookups on a movie without a plot
+	ErrNoPlot = errors.New("movie has no plot to compare")
+	// ErrInvalidSemanticQuery is returned for an empty free-text query
+	ErrInvalidSemanticQuery = errors.New("invalid semantic query")
+)
+
+// SemanticMatch is a movie with its similarity (cosine, -1 to 1) to a query
+type SemanticMatch struct {
+	Movie      models.Movie
+	Similarity float64
+}
+
+// SemanticService embeds movie plots and finds movies by meaning, either
+// similar to another movie or matching a free-text description
+type SemanticService struct {
+	embedder      Embedder
+	embeddingRepo *repositories.EmbeddingRepository
+	movieRepo     *repositories.MovieRepository
+	jobQueue      *jobs.Queue
+	// vectorIndex is an Atlas Vector Search index name; when empty,
+	// similarity is computed in-process over all stored vectors
+	vectorIndex string
+	logger      *slog.Logger
+}
+
+func NewSemanticService(embedder Embedder, embeddingRepo *repositories.EmbeddingRepository, movieRepo *repositories.MovieRepository, jobQueue *jobs.Queue, vectorIndex string) *SemanticService {
+	return &SemanticService{
+		embedder:      embedder,
+		embeddingRepo: embeddingRepo,
+		movieRepo:     movieRepo,
+		jobQueue:      jobQueue,
+		vectorIndex:   vectorIndex,
+		logger:        logging.For("services.semantic"),
+	}
+}
+
+// EnsureScheduled queues the first plot embedding run if none is pending
+func (s *SemanticService) EnsureScheduled() error {
+	return s.jobQueue.EnsureScheduled(jobs.TypeEmbedPlots, nil, time.Now().UTC())
+}
+
+// EmbedPlotsJob embeds the plots of movies that have no embedding under the
+// current model, or whose plot changed since. It runs again right away while
+// plots are left over and hourly once it has caught up.
+func (s *SemanticService) EmbedPlotsJob(ctx context.Context, payload map[string]interface{}) error {
+	model := s.embedder.Model()
+	movies, err := s.movieRepo.FindPlots()
+	if err != nil {
+		return err
+	}
+	hashes, err := s.embeddingRepo.PlotHashes(model)
+	if err != nil {
+		return err
+	}
+
+	pending := []models.Movie{}
+	for _, movie := range movies {
+		if hashes[movie.ID] != plotHash(movie.Plot) {
+			pending = append(pending, movie)
+		}
+	}
+	leftOver := len(pending) > embedPerRun
+	if leftOver {
+		pending = pending[:embedPerRun]
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if _, err := s.embedMovies(ctx, pending[start:end]); err != nil {
+			return err
+		}
+	}
+	if len(pending) > 0 {
+		s.logger.Info("embedded movie plots", "movies", len(pending), "model", model)
+	}
+
+	next := time.Now().UTC().Add(embedInterval)
+	if leftOver {
+		next = time.Now().UTC()
+	}
+	return s.jobQueue.EnqueueAt(jobs.TypeEmbedPlots, nil, next)
+}
+
+// embedMovies embeds and stores the plots of the movies, returning the vectors
+func (s *SemanticService) embedMovies(ctx context.Context, movies []models.Movie) ([][]float32, error) {
+	plots := make([]string, len(movies))
+	for i, movie := range movies {
+		plots[i] = movie.Plot
+	}
+
+	vectors, err := s.embedder.Embed(ctx, plots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed plots: %w", err)
+	}
+
+	for i, movie := range movies {
+		err := s.embeddingRepo.Upsert(&models.MovieEmbedding{
+			MovieID:  movie.ID,
+			Model:    s.embedder.Model(),
+			Vector:   vectors[i],
+			PlotHash: plotHash(movie.Plot),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vectors, nil
+}
+
+// SimilarMovies returns the movies whose plots are closest to the movie's,
+// most similar first. A movie not embedded yet is embedded on the spot.
+func (s *SemanticService) SimilarMovies(ctx context.Context, movieID primitive.ObjectID, limit int) ([]SemanticMatch, error) {
+	embedding, err := s.embeddingRepo.FindForMovie(movieID, s.embedder.Model())
+	if err != nil {
+		return nil, err
+	}
+
+	var vector []float32
+	if embedding != nil {
+		vector = embedding.Vector
+	} else {
+		movie, err := s.movieRepo.FindByID(movieID)
+		if err != nil {
+			return nil, err
+		}
+		if movie == nil {
+			return nil, errors.New("movie not found")
+		}
+		if !hasPlot(movie.Plot) {
+			return nil, ErrNoPlot
+		}
+		vectors, err := s.embedMovies(ctx, []models.Movie{*movie})
+		if err != nil {
+			return nil, err
+		}
+		vector = vectors[0]
+	}
+
+	return s.nearest(vector, limit, movieID)
+}
+
+// SearchByText returns the movies whose plots best match a free-text
+// description such as "movies about time loops"
+func (s *SemanticService) SearchByText(ctx context.Context, query string, limit int) ([]SemanticMatch, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidSemanticQuery)
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return s.nearest(vectors[0], limit, primitive.NilObjectID)
+}
+
+// nearest returns the movies closest to vector, leaving out exclude
+func (s *SemanticService) nearest(vector []float32, limit int, exclude primitive.ObjectID) ([]SemanticMatch, error) {
+	var matches []repositories.VectorMatch
+	if s.vectorIndex != "" {
+		found, err := s.embeddingRepo.VectorSearch(s.vectorIndex, s.embedder.Model(), vector, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		matches = found
+	} else {
+		embeddings, err := s.embeddingRepo.FindAllVectors(s.embedder.Model())
+		if err != nil {
+			return nil, err
+		}
+		for _, embedding := range embeddings {
+			matches = append(matches, repositories.VectorMatch{
+				MovieID: embedding.MovieID,
+				Score:   dotProduct(vector, embedding.Vector),
+			})
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].Score != matches[j].Score {
+				return matches[i].Score > matches[j].Score
+			}
+			return matches[i].MovieID.Hex() < matches[j].MovieID.Hex()
+		})
+	}
+
+	ids := make([]primitive.ObjectID, 0, limit)
+	scores := make(map[primitive.ObjectID]float64, limit)
+	for _, match := range matches {
+		if len(ids) == limit {
+			break
+		}
+		// Plots with no words in common with the query are not matches
+		if match.MovieID == exclude || match.Score <= 0 {
+			continue
+		}
+		ids = append(ids, match.MovieID)
+		scores[match.MovieID] = match.Score
+	}
+
+	movies, err := s.movieRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SemanticMatch, 0, len(ids))
+	for _, id := range ids {
+		if movie, ok := movies[id]; ok {
+			results = append(results, SemanticMatch{Movie: movie, Similarity: scores[id]})
+		}
+	}
+	return results, nil
+}
+
+func hasPlot(plot string) bool {
+	plot = strings.TrimSpace(plot)
+	return plot != "" && plot != "N/A"
+}
+
+// plotHash fingerprints a plot so changed plots are embedded again
+func plotHash(plot string) string {
+	sum := sha256.Sum256([]byte(plot))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refreshTokenTTL is how long a refresh token stays valid without being used
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// sessionCheckInterval is how long a successful session check is reused
+// before the session is looked up again, bounding how long a revoked
+// session's access tokens keep working on this instance
+const sessionCheckInterval = time.Minute
+
+type SessionService struct {
+	sessionRepo *repositories.SessionRepository
+	checked     *checkCache // session IDs
+	logger      *slog.Logger
+}
+
+func NewSessionService(sessionRepo *repositories.SessionRepository) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		checked:     newCheckCache(sessionCheckInterval),
+		logger:      logging.For("services.sessions"),
+	}
+}
+
+// CreateSession starts a session for the device and returns it with its refresh token
+func (s *SessionService) CreateSession(userID primitive.ObjectID, userAgent, ip string) (*models.Session, string, error) {
+	return s.createSession(&models.Session{UserID: userID, UserAgent: userAgent, IP: ip, ExpiresAt: time.Now().UTC().Add(refreshTokenTTL)})
+}
+
+// CreateAppSession starts a session for a third-party app the user
+// authorized, limited to scopes, and returns it with its refresh token
+func (s *SessionService) CreateAppSession(userID primitive.ObjectID, clientID string, scopes []string, userAgent, ip string) (*models.Session, string, error) {
+	return s.createSession(&models.Session{
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenTTL),
+		ClientID:  clientID,
+		Scopes:    scopes,
+	})
+}
+
+// CreateDemoSession starts a demo sandbox session that ends at expiresAt.
+// Its refresh token is never handed out, so the sandbox cannot be extended.
+func (s *SessionService) CreateDemoSession(userID primitive.ObjectID, userAgent, ip string, expiresAt time.Time) (*models.Session, error) {
+	session, _, err := s.createSession(&models.Session{UserID: userID, UserAgent: userAgent, IP: ip, ExpiresAt: expiresAt})
+	return session, err
+}
+
+func (s *SessionService) createSession(session *models.Session) (*models.Session, string, error) {
+	refreshToken, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session.RefreshTokenHash = hashSecretToken(refreshToken)
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// Refresh exchanges a refresh token for a new one. The old token stops
+// working. clientID is the third-party app refreshing its session, empty
+// for the API's own clients; a refresh token only works for its holder.
+func (s *SessionService) Refresh(refreshToken, clientID, userAgent, ip string) (*models.Session, string, error) {
+	oldHash := hashSecretToken(refreshToken)
+	session, err := s.sessionRepo.FindActiveByTokenHash(oldHash)
+	if err != nil {
+		return nil, "", err
+	}
+	if session == nil || session.ClientID != clientID {
+		return nil, "", errors.New("invalid refresh token")
+	}
+
+	newToken, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rotated, err := s.sessionRepo.Rotate(session.ID, oldHash, hashSecretToken(newToken), userAgent, ip, time.Now().UTC().Add(refreshTokenTTL))
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		return nil, "", errors.New("invalid refresh token")
+	}
+
+	return session, newToken, nil
+}
+
+// GetActiveSessions lists the user's active sessions
+func (s *SessionService) GetActiveSessions(userID primitive.ObjectID) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	return sessions, nil
+}
+
+// GetSession returns the active session, or nil when there is none. Callers
+// check ownership with the authz policy.
+func (s *SessionService) GetSession(sessionID primitive.ObjectID) (*models.Session, error) {
+	return s.sessionRepo.FindActiveByID(sessionID)
+}
+
+// RevokeSession logs out a single device
+func (s *SessionService) RevokeSession(userID, sessionID primitive.ObjectID) error {
+	revoked, err := s.sessionRepo.Revoke(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+
+	s.checked.Delete(sessionID)
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere
+func (s *SessionService) RevokeAllSessions(userID primitive.ObjectID) (int64, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.sessionRepo.RevokeAll(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		s.checked.Delete(session.ID)
+	}
+	return revoked, nil
+}
+
+// RevokeAppSessions ends every session held by a third-party app, such as
+// when the app is deleted
+func (s *SessionService) RevokeAppSessions(clientID string) (int64, error) {
+	sessions, err := s.sessionRepo.FindActiveByClient(clientID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.sessionRepo.RevokeByClient(clientID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		s.checked.Delete(session.ID)
+	}
+	return revoked, nil
+}
+
+// IsActive reports whether access tokens issued for the session are still
+// accepted. Lookups are cached for sessionCheckInterval and also refresh the
+// session's last used time.
+func (s *SessionService) IsActive(sessionID primitive.ObjectID) bool {
+	if s.checked.Fresh(sessionID) {
+		return true
+	}
+
+	session, err := s.sessionRepo.FindActiveByID(sessionID)
+	if err != nil {
+		// Don't lock everyone out while the database is unavailable
+		s.logger.Warn("failed to check session", "session_id", sessionID.Hex(), "error", err)
+		return true
+	}
+	if session == nil {
+		s.checked.Delete(sessionID)
+		return false
+	}
+
+	s.checked.Store(sessionID)
+	if err := s.sessionRepo.Touch(sessionID); err != nil {
+		s.logger.Warn("failed to update session", "session_id", sessionID.Hex(), "error", err)
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSharesPerDay caps how many movies one user can share in 24 hours, so
+// the inbox cannot be used to spam strangers
+const maxSharesPerDay = 20
+
+var (
+	ErrRecipientNotFound  = errors.New("recipient not found")
+	ErrShareWithSelf      = errors.New("cannot share a movie with yourself")
+	ErrShareMovieNotFound = errors.New("movie not found")
+	ErrAlreadyShared      = errors.New("movie already shared with this user")
+	ErrShareLimit         = errors.New("share limit reached")
+)
+
+// ShareService lets users recommend single movies to each other. Shares go
+// to the recipient's inbox and notifications.
+type ShareService struct {
+	shareRepo        *repositories.MovieShareRepository
+	userRepo         *repositories.UserRepository
+	movieRepo        *repositories.MovieRepository
+	notificationRepo *repositories.NotificationRepository
+	logger           *slog.Logger
+}
+
+func NewShareService(shareRepo *repositories.MovieShareRepository, userRepo *repositories.UserRepository, movieRepo *repositories.MovieRepository, notificationRepo *repositories.NotificationRepository) *ShareService {
+	return &ShareService{
+		shareRepo:        shareRepo,
+		userRepo:         userRepo,
+		movieRepo:        movieRepo,
+		notificationRepo: notificationRepo,
+		logger:           logging.For("services.shares"),
+	}
+}
+
+// Share sends the movie with an optional note to the user with the given
+// username. Deactivated and demo users cannot be found as recipients.
+func (s *ShareService) Share(senderID primitive.ObjectID, username string, movieID primitive.ObjectID, note string) (*models.MovieShare, error) {
+	sender, err := s.userRepo.FindByID(senderID)
+	if err != nil {
+		return nil, err
+	}
+	if sender == nil {
+		return nil, errors.New("user not found")
+	}
+	recipient, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if recipient == nil || recipient.DeactivatedAt != nil || recipient.DemoExpiresAt != nil {
+		return nil, ErrRecipientNotFound
+	}
+	if recipient.ID == senderID {
+		return nil, ErrShareWithSelf
+	}
+
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, ErrShareMovieNotFound
+	}
+
+	sent, err := s.shareRepo.CountSentSince(senderID, time.Now().UTC().Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if sent >= maxSharesPerDay {
+		return nil, fmt.Errorf("%w: at most %d movies can be shared a day", ErrShareLimit, maxSharesPerDay)
+	}
+
+	share := &models.MovieShare{
+		UserID:     recipient.ID,
+		SenderID:   senderID,
+		SenderName: sender.Username,
+		MovieID:    movieID,
+		Note:       note,
+	}
+	created, err := s.shareRepo.Create(share)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyShared
+	}
+
+	message := fmt.Sprintf("%s thinks you would like %s.", sender.Username, movie.Title)
+	if note != "" {
+		message = note
+	}
+	notification := &models.Notification{
+		UserID:  recipient.ID,
+		Type:    models.NotificationMovieShared,
+		Title:   fmt.Sprintf("%s recommends %s", sender.Username, movie.Title),
+		Message: message,
+		MovieID: &movieID,
+	}
+	// The share is in the inbox already, so a failed notification does not
+	// fail it
+	if err := s.notificationRepo.Create(notification); err != nil {
+		s.logger.Warn("failed to notify share recipient", "user_id", recipient.ID.Hex(), "error", err)
+	}
+
+	share.Movie = movie
+	return share, nil
+}
+
+// GetInbox returns one page of the movies shared with the user, newest
+// first, and the total count
+func (s *ShareService) GetInbox(userID primitive.ObjectID, offset, limit int) ([]models.MovieShare, int64, error) {
+	shares, total, err := s.shareRepo.FindByUserPage(userID, int64(offset), int64(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	movieIDs := make([]primitive.ObjectID, 0, len(shares))
+	for _, share := range shares {
+		movieIDs = append(movieIDs, share.MovieID)
+	}
+	movies, err := s.movieRepo.FindByIDs(movieIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range shares {
+		if movie, ok := movies[shares[i].MovieID]; ok {
+			shares[i].Movie = &movie
+		}
+	}
+	return shares, total, nil
+}
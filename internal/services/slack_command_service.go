@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// slackLinkCodeTTL is how long a link code can be typed into Slack
+	slackLinkCodeTTL = 10 * time.Minute
+	// slackSignatureMaxAge rejects replayed slash command requests
+	slackSignatureMaxAge = 5 * time.Minute
+	// slackSearchResults caps the movies listed by the search command
+	slackSearchResults = 5
+)
+
+var (
+	ErrInvalidSlackSignature = errors.New("invalid Slack request signature")
+	ErrSlackNotLinked        = errors.New("no Slack user is linked")
+)
+
+// slackHelp lists the slash command's subcommands; %[1]s is the command
+const slackHelp = "Try `%[1]s search <title>`, `%[1]s add <title or IMDb ID>` or `%[1]s link <code>`. Get a link code from the app to connect your Slack user to your account."
+
+// SlackLinkStatus is the user's Slack link as shown to them
+type SlackLinkStatus struct {
+	Linked bool `json:"linked"`
+	*models.SlackLink
+}
+
+// SlackCommandService runs the Slack slash command, which searches movies
+// and adds them to the watchlist of the account the Slack user linked
+type SlackCommandService struct {
+	signingSecret    string
+	linkRepo         *repositories.SlackLinkRepository
+	userRepo         *repositories.UserRepository
+	movieService     *MovieService
+	quickAddService  *QuickAddService
+	watchlistService *WatchlistService
+}
+
+func NewSlackCommandService(signingSecret string, linkRepo *repositories.SlackLinkRepository, userRepo *repositories.UserRepository, movieService *MovieService, quickAddService *QuickAddService, watchlistService *WatchlistService) *SlackCommandService {
+	return &SlackCommandService{
+		signingSecret:    signingSecret,
+		linkRepo:         linkRepo,
+		userRepo:         userRepo,
+		movieService:     movieService,
+		quickAddService:  quickAddService,
+		watchlistService: watchlistService,
+	}
+}
+
+// Enabled reports whether a Slack signing secret is configured
+func (s *SlackCommandService) Enabled() bool {
+	return s.signingSecret != ""
+}
+
+// Verify checks Slack's signature of a request body, computed over the
+// request timestamp so a captured request cannot be replayed later
+func (s *SlackCommandService) Verify(timestamp, signature string, body []byte, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSlackSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > slackSignatureMaxAge || age < -slackSignatureMaxAge {
+		return ErrInvalidSlackSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSlackSignature
+	}
+	return nil
+}
+
+// Status returns the user's link; Linked is false until a code was used
+func (s *SlackCommandService) Status(userID primitive.ObjectID) (*SlackLinkStatus, error) {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return &SlackLinkStatus{Linked: false}, nil
+	}
+	return &SlackLinkStatus{Linked: true, SlackLink: link}, nil
+}
+
+// CreateLinkCode returns a one-time code the user types into Slack to link
+// their Slack user, replacing any earlier code
+func (s *SlackCommandService) CreateLinkCode(userID primitive.ObjectID) (string, time.Time, error) {
+	code, err := newLinkCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().UTC().Add(slackLinkCodeTTL)
+	if err := s.linkRepo.SetCode(userID, hashSecretToken(code), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expiresAt, nil
+}
+
+// Unlink removes the user's Slack link
+func (s *SlackCommandService) Unlink(userID primitive.ObjectID) error {
+	deleted, err := s.linkRepo.Delete(userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSlackNotLinked
+	}
+	return nil
+}
+
+// Run answers one slash command from a Slack user. The answer is only shown
+// to that user.
+func (s *SlackCommandService) Run(ctx context.Context, command, teamID, slackUserID, text string) string {
+	subcommand, argument, _ := strings.Cut(strings.TrimSpace(text), " ")
+	argument = strings.TrimSpace(argument)
+
+	var reply string
+	var err error
+	switch strings.ToLower(subcommand) {
+	case "link":
+		reply, err = s.link(teamID, slackUserID, argument)
+	case "search":
+		reply, err = s.search(ctx, argument)
+	case "add":
+		reply, err = s.add(ctx, command, teamID, slackUserID, argument)
+	default:
+		reply = fmt.Sprintf(slackHelp, command)
+	}
+	if err != nil {
+		return "Something went wrong, please try again later."
+	}
+	return reply
+}
+
+func (s *SlackCommandService) link(teamID, slackUserID, code string) (string, error) {
+	codeHash := hashSecretToken(strings.ToUpper(code))
+	link, err := s.linkRepo.FindByCode(codeHash, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "That link code is wrong or expired. Get a new one from the app.", nil
+	}
+	linked, err := s.linkRepo.Complete(link.ID, codeHash, teamID, slackUserID, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if !linked {
+		return "That link code was already used. Get a new one from the app.", nil
+	}
+	return "Your Slack user is linked. Movies you add go to your watchlist.", nil
+}
+
+func (s *SlackCommandService) search(ctx context.Context, query string) (string, error) {
+	if query == "" {
+		return "What should I search for?", nil
+	}
+	result, err := s.movieService.SearchMovies(ctx, query, 1, nil)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, movie := range result.Movies {
+		if len(lines) == slackSearchResults {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• %s (%s) `%s`", slackEscaper.Replace(movie.Title), movie.Year, movie.IMDbID))
+	}
+	if len(lines) == 0 {
+		return "No movies found for " + chatQuote(models.ChatWebhookSlack, query) + ".", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *SlackCommandService) add(ctx context.Context, command, teamID, slackUserID, argument string) (string, error) {
+	if argument == "" {
+		return "Which movie should I add?", nil
+	}
+	link, err := s.linkRepo.FindBySlackUser(teamID, slackUserID)
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return fmt.Sprintf("Link your Slack user first: get a link code from the app and type `%s link <code>`.", command), nil
+	}
+	user, err := s.userRepo.FindByID(link.UserID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil || user.DeactivatedAt != nil {
+		return "The linked account no longer exists.", nil
+	}
+
+	imdbID, title := "", argument
+	if quickAddIMDbPattern.MatchString(argument) {
+		imdbID, title = quickAddIMDbPattern.FindString(argument), ""
+	}
+	match, err := s.quickAddService.Resolve(ctx, imdbID, "", title, user.ID)
+	if err != nil {
+		var unsure *QuickAddError
+		if !errors.As(err, &unsure) {
+			return "", err
+		}
+		if len(unsure.Candidates) == 0 {
+			return "No movies found for " + chatQuote(models.ChatWebhookSlack, argument) + ".", nil
+		}
+		lines := []string{fmt.Sprintf("Which one? Add it with `%s add <IMDb ID>`:", command)}
+		for _, candidate := range unsure.Candidates {
+			lines = append(lines, fmt.Sprintf("• %s (%s) `%s`", slackEscaper.Replace(candidate.Title), candidate.Year, candidate.IMDbID))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	movieTitle := slackEscaper.Replace(match.Movie.Title)
+	source := &models.WatchlistSource{Type: models.WatchlistSourceChat, Detail: "slack"}
+	if _, err := s.watchlistService.AddWithServerSource(user.ID, match.Movie.ID, 0, source); err != nil {
+		switch {
+		case errors.Is(err, ErrQuotaExceeded):
+			return "Your watchlist is full.", nil
+		case err.Error() == "movie already in watchlist":
+			return fmt.Sprintf("*%s* is already on your watchlist.", movieTitle), nil
+		}
+		return "", err
+	}
+	return fmt.Sprintf("Added *%s* (%s) to your watchlist.", movieTitle, match.Movie.Year), nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"movie-watchlist/internal/repositories"
+	"sync"
+	"time"
+)
+
+// statsCacheTTL is how long computed admin stats are reused
+const statsCacheTTL = time.Minute
+
+// statsWindowDays is the length of the time series in the admin stats
+const statsWindowDays = 30
+
+type UserStats struct {
+	Total         int64              `json:"total"`
+	NewToday      int64              `json:"new_today"`
+	DailyActive   int                `json:"daily_active"`
+	WeeklyActive  int                `json:"weekly_active"`
+	MonthlyActive int                `json:"monthly_active"`
+	ActiveSeries  []ActiveUsersPoint `json:"active_series"`
+}
+
+type OMDbStats struct {
+	RequestsToday   int64   `json:"requests_today"`
+	ErrorsToday     int64   `json:"errors_today"`
+	ErrorRate       float64 `json:"error_rate"`
+	Requests        int64   `json:"requests_window"`
+	Errors          int64   `json:"errors_window"`
+	WindowErrorRate float64 `json:"error_rate_window"`
+}
+
+// SystemStats is the payload of the admin stats endpoint
+type SystemStats struct {
+	Users                 UserStats                 `json:"users"`
+	MoviesCached          int64                     `json:"movies_cached"`
+	RatingsTotal          int64                     `json:"ratings_total"`
+	WatchlistEntries      int64                     `json:"watchlist_entries"`
+	WatchlistSources      []SourceStats             `json:"watchlist_sources"`
+	RatingsPerDay         []repositories.DailyCount `json:"ratings_per_day"`
+	OMDb                  OMDbStats                 `json:"omdb"`
+	RecommendationLatency LatencyPercentiles        `json:"recommendation_latency"`
+	GeneratedAt           time.Time                 `json:"generated_at"`
+}
+
+type StatsService struct {
+	statsRepo             *repositories.StatsRepository
+	usageRepo             *repositories.OMDbUsageRepository
+	activityService       *ActivityService
+	recommendationService *RecommendationService
+	watchlistService      *WatchlistService
+
+	mu       sync.Mutex
+	cached   *SystemStats
+	cachedAt time.Time
+}
+
+func NewStatsService(statsRepo *repositories.StatsRepository, usageRepo *repositories.OMDbUsageRepository, activityService *ActivityService, recommendationService *RecommendationService, watchlistService *WatchlistService) *StatsService {
+	return &StatsService{
+		statsRepo:             statsRepo,
+		usageRepo:             usageRepo,
+		activityService:       activityService,
+		recommendationService: recommendationService,
+		watchlistService:      watchlistService,
+	}
+}
+
+// GetSystemStats returns the system stats, recomputing them at most once per statsCacheTTL
+func (s *StatsService) GetSystemStats() (*SystemStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cachedAt) < statsCacheTTL {
+		return s.cached, nil
+	}
+
+	stats, err := s.computeStats()
+	if err != nil {
+		return nil, err
+	}
+
+	s.cached = stats
+	s.cachedAt = time.Now()
+	return stats, nil
+}
+
+func (s *StatsService) computeStats() (*SystemStats, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	stats := &SystemStats{GeneratedAt: now}
+
+	var err error
+	if stats.Users.Total, err = s.statsRepo.CountDocuments("users"); err != nil {
+		return nil, err
+	}
+	if stats.Users.NewToday, err = s.statsRepo.CountCreatedSince("users", today); err != nil {
+		return nil, err
+	}
+	if stats.Users.ActiveSeries, err = s.activityService.GetActiveUsersSeries(statsWindowDays); err != nil {
+		return nil, err
+	}
+	if latest := len(stats.Users.ActiveSeries) - 1; latest >= 0 {
+		stats.Users.DailyActive = stats.Users.ActiveSeries[latest].DAU
+		stats.Users.WeeklyActive = stats.Users.ActiveSeries[latest].WAU
+		stats.Users.MonthlyActive = stats.Users.ActiveSeries[latest].MAU
+	}
+	if stats.MoviesCached, err = s.statsRepo.CountDocuments("movies"); err != nil {
+		return nil, err
+	}
+	if stats.RatingsTotal, err = s.statsRepo.CountDocuments("ratings"); err != nil {
+		return nil, err
+	}
+	if stats.WatchlistEntries, err = s.statsRepo.CountDocuments("watchlists"); err != nil {
+		return nil, err
+	}
+	if stats.WatchlistSources, err = s.watchlistService.GetSourceStats(nil); err != nil {
+		return nil, err
+	}
+	if stats.RatingsPerDay, err = s.statsRepo.DailyCreatedCounts("ratings", statsWindowDays); err != nil {
+		return nil, err
+	}
+	if stats.RatingsPerDay == nil {
+		stats.RatingsPerDay = []repositories.DailyCount{}
+	}
+
+	usage, err := s.usageRepo.GetRecentUsage(statsWindowDays)
+	if err != nil {
+		return nil, err
+	}
+	todayKey := today.Format("2006-01-02")
+	for _, day := range usage {
+		stats.OMDb.Requests += day.Count
+		stats.OMDb.Errors += day.Errors
+		if day.Date == todayKey {
+			stats.OMDb.RequestsToday = day.Count
+			stats.OMDb.ErrorsToday = day.Errors
+		}
+	}
+	stats.OMDb.ErrorRate = ratio(stats.OMDb.ErrorsToday, stats.OMDb.RequestsToday)
+	stats.OMDb.WindowErrorRate = ratio(stats.OMDb.Errors, stats.OMDb.Requests)
+
+	stats.RecommendationLatency = s.recommendationService.LatencyPercentiles()
+
+	return stats, nil
+}
+
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
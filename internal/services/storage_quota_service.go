@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrQuotaExceeded is returned when a write would take a user past one of
+// their storage limits
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// ErrInvalidStorageQuota is returned for limit overrides below 0
+var ErrInvalidStorageQuota = errors.New("storage limits cannot be negative")
+
+// StorageLimits are the storage limits that apply to a user; 0 is unlimited
+type StorageLimits struct {
+	MaxWatchlistEntries int `json:"max_watchlist_entries"`
+}
+
+// StorageUsage is a user's limits next to how much of them is used
+type StorageUsage struct {
+	Limits           StorageLimits `json:"limits"`
+	WatchlistEntries int64         `json:"watchlist_entries"`
+	// Overridden is set when an admin replaced the configured defaults
+	Overridden bool `json:"overridden"`
+}
+
+// StorageQuotaService enforces per-user storage limits, so one account
+// cannot fill a shared deployment's database. The limits come from the
+// configuration unless an admin overrode them for the user. Kids profiles
+// use the configured defaults.
+type StorageQuotaService struct {
+	userRepo      *repositories.UserRepository
+	watchlistRepo *repositories.WatchlistRepository
+	defaults      StorageLimits
+}
+
+func NewStorageQuotaService(userRepo *repositories.UserRepository, watchlistRepo *repositories.WatchlistRepository, defaults StorageLimits) *StorageQuotaService {
+	return &StorageQuotaService{userRepo: userRepo, watchlistRepo: watchlistRepo, defaults: defaults}
+}
+
+// Limits returns the limits that apply to the user
+func (s *StorageQuotaService) Limits(userID primitive.ObjectID) (StorageLimits, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return s.defaults, err
+	}
+	limits, _ := s.limitsFor(user)
+	return limits, nil
+}
+
+// limitsFor applies the user's overrides, if any, to the defaults, reporting
+// whether there were any. A nil user, such as a kids profile, gets the
+// defaults.
+func (s *StorageQuotaService) limitsFor(user *models.User) (StorageLimits, bool) {
+	limits := s.defaults
+	if user == nil || user.StorageQuota == nil {
+		return limits, false
+	}
+	if limit := user.StorageQuota.MaxWatchlistEntries; limit != nil {
+		limits.MaxWatchlistEntries = *limit
+	}
+	return limits, true
+}
+
+// Usage returns the user's limits and how much of them is used, or nil when
+// the user does not exist
+func (s *StorageQuotaService) Usage(userID primitive.ObjectID) (*StorageUsage, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	limits, overridden := s.limitsFor(user)
+	entries, err := s.watchlistRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageUsage{Limits: limits, WatchlistEntries: entries, Overridden: overridden}, nil
+}
+
+// WatchlistRoom returns how many more entries the user's watchlist can take,
+// or -1 when it is unlimited, along with the limit
+func (s *StorageQuotaService) WatchlistRoom(userID primitive.ObjectID) (int, int, error) {
+	limits, err := s.Limits(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limits.MaxWatchlistEntries == 0 {
+		return -1, 0, nil
+	}
+	entries, err := s.watchlistRepo.CountByUser(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return max(limits.MaxWatchlistEntries-int(entries), 0), limits.MaxWatchlistEntries, nil
+}
+
+// CheckWatchlistAdd returns an ErrQuotaExceeded error when the user's
+// watchlist is full
+func (s *StorageQuotaService) CheckWatchlistAdd(userID primitive.ObjectID) error {
+	limits, err := s.Limits(userID)
+	if err != nil || limits.MaxWatchlistEntries == 0 {
+		return err
+	}
+	entries, err := s.watchlistRepo.CountByUser(userID)
+	if err != nil {
+		return err
+	}
+	if entries >= int64(limits.MaxWatchlistEntries) {
+		return watchlistQuotaError(limits.MaxWatchlistEntries)
+	}
+	return nil
+}
+
+func watchlistQuotaError(limit int) error {
+	return fmt.Errorf("%w: watchlists are limited to %d entries; remove some to add more", ErrQuotaExceeded, limit)
+}
+
+// SetOverride replaces the configured limits for the user, or restores them
+// when quota is nil. It reports false when the user does not exist. Existing
+// entries above a lowered limit are kept; only new ones are refused.
+func (s *StorageQuotaService) SetOverride(userID primitive.ObjectID, quota *models.StorageQuota) (bool, error) {
+	if quota != nil {
+		if quota.MaxWatchlistEntries != nil && *quota.MaxWatchlistEntries < 0 {
+			return false, ErrInvalidStorageQuota
+		}
+		if quota.MaxWatchlistEntries == nil {
+			quota = nil
+		}
+	}
+	return s.userRepo.SetStorageQuota(userID, quota)
+}
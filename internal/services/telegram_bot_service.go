@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// telegramLinkCodeTTL is how long a link code can be sent to the bot
+	telegramLinkCodeTTL = 10 * time.Minute
+	// telegramPollTimeout is how long one long poll waits for updates
+	telegramPollTimeout = 25 * time.Second
+	// telegramRetryDelay is the pause after a failed long poll
+	telegramRetryDelay = 5 * time.Second
+	// telegramNotifyInterval is how often new notifications are sent on
+	telegramNotifyInterval = 30 * time.Second
+	// telegramNotifyBatch caps the notifications sent to a chat in one pass
+	telegramNotifyBatch = 20
+	// telegramSearchResults caps the movies listed by /search
+	telegramSearchResults = 5
+)
+
+var ErrTelegramNotLinked = errors.New("no Telegram chat is linked")
+
+const telegramHelp = `/search <title> finds movies
+/add <title or IMDb ID> adds a movie to your watchlist
+/notify on or /notify off turns notifications in this chat on or off
+/unlink disconnects this chat from your account
+
+To link this chat, get a link code in the app and send /link <code>.`
+
+// TelegramLinkStatus is the user's Telegram link as shown to them
+type TelegramLinkStatus struct {
+	Linked bool `json:"linked"`
+	*models.TelegramLink
+}
+
+// TelegramBotService is the Telegram bot: a thin adapter that turns chat
+// commands into calls to the movie, quick add and watchlist services and
+// forwards the notifications of linked accounts to their chats. Updates
+// arrive by long polling or through the webhook handler.
+type TelegramBotService struct {
+	client           *TelegramClient
+	linkRepo         *repositories.TelegramLinkRepository
+	userRepo         *repositories.UserRepository
+	notificationRepo *repositories.NotificationRepository
+	movieService     *MovieService
+	quickAddService  *QuickAddService
+	watchlistService *WatchlistService
+	webhookURL       string
+	webhookSecret    string
+	botUsername      atomic.Value
+	logger           *slog.Logger
+}
+
+// NewTelegramBotService returns the bot. With a nil client the bot is off;
+// with an empty webhookURL it long polls.
+func NewTelegramBotService(client *TelegramClient, linkRepo *repositories.TelegramLinkRepository, userRepo *repositories.UserRepository, notificationRepo *repositories.NotificationRepository, movieService *MovieService, quickAddService *QuickAddService, watchlistService *WatchlistService, webhookURL, webhookSecret string) *TelegramBotService {
+	s := &TelegramBotService{
+		client:           client,
+		linkRepo:         linkRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		movieService:     movieService,
+		quickAddService:  quickAddService,
+		watchlistService: watchlistService,
+		webhookURL:       webhookURL,
+		webhookSecret:    webhookSecret,
+		logger:           logging.For("services.telegram"),
+	}
+	s.botUsername.Store("")
+	return s
+}
+
+// Enabled reports whether a bot token is configured
+func (s *TelegramBotService) Enabled() bool {
+	return s.client != nil
+}
+
+// UsesWebhook reports whether updates arrive through the webhook handler
+func (s *TelegramBotService) UsesWebhook() bool {
+	return s.webhookURL != ""
+}
+
+// Start registers the webhook or starts long polling, and starts
+// forwarding notifications, until ctx is cancelled
+func (s *TelegramBotService) Start(ctx context.Context) {
+	go s.setUp(ctx)
+	go s.forwardNotifications(ctx)
+}
+
+func (s *TelegramBotService) setUp(ctx context.Context) {
+	if username, err := s.client.BotUsername(ctx); err != nil {
+		s.logger.Warn("failed to look up the Telegram bot", "error", err)
+	} else {
+		s.botUsername.Store(username)
+	}
+
+	if s.UsesWebhook() {
+		if err := s.client.SetWebhook(ctx, s.webhookURL, s.webhookSecret); err != nil {
+			s.logger.Error("failed to register the Telegram webhook", "error", err)
+		}
+		return
+	}
+	if err := s.client.DeleteWebhook(ctx); err != nil {
+		s.logger.Warn("failed to remove the Telegram webhook", "error", err)
+	}
+	s.poll(ctx)
+}
+
+// poll long polls for updates. Telegram forgets updates once a later
+// offset is requested, so a message whose reply fails is not retried.
+func (s *TelegramBotService) poll(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		updates, err := s.client.Updates(ctx, offset, telegramPollTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("failed to fetch Telegram updates", "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(telegramRetryDelay):
+				}
+			}
+			continue
+		}
+		for _, update := range updates {
+			s.HandleUpdate(ctx, update)
+			offset = update.UpdateID + 1
+		}
+	}
+}
+
+// VerifyWebhook checks the secret Telegram sends with webhook updates
+func (s *TelegramBotService) VerifyWebhook(secret string) bool {
+	return s.webhookSecret != "" && hmac.Equal([]byte(secret), []byte(s.webhookSecret))
+}
+
+// HandleUpdate answers a message sent to the bot. Only private chats are
+// served, so the bot stays quiet when added to a group.
+func (s *TelegramBotService) HandleUpdate(ctx context.Context, update TelegramUpdate) {
+	message := update.Message
+	if message == nil || message.Chat.Type != "private" || message.Text == "" {
+		return
+	}
+	username := ""
+	if message.From != nil {
+		username = message.From.Username
+	}
+
+	reply := s.run(ctx, message.Chat.ID, username, message.Text)
+	if err := s.client.SendMessage(ctx, message.Chat.ID, reply); err != nil {
+		s.logger.Warn("failed to reply on Telegram", "error", err)
+	}
+}
+
+// run answers one command; commands may carry the bot's name, as in
+// /search@moviebot
+func (s *TelegramBotService) run(ctx context.Context, chatID int64, username, text string) string {
+	command, argument, _ := strings.Cut(strings.TrimSpace(text), " ")
+	command, _, _ = strings.Cut(strings.ToLower(command), "@")
+	argument = strings.TrimSpace(argument)
+
+	var reply string
+	var err error
+	switch command {
+	case "/start":
+		// Deep links open the bot with /start <code>
+		if argument == "" {
+			return "Hi! " + telegramHelp
+		}
+		reply, err = s.link(chatID, username, argument)
+	case "/link":
+		reply, err = s.link(chatID, username, argument)
+	case "/search":
+		reply, err = s.search(ctx, argument)
+	case "/add":
+		reply, err = s.add(ctx, chatID, argument)
+	case "/notify":
+		reply, err = s.notify(chatID, argument)
+	case "/unlink":
+		reply, err = s.unlinkChat(chatID)
+	default:
+		reply = telegramHelp
+	}
+	if err != nil {
+		s.logger.Warn("Telegram command failed", "command", command, "error", err)
+		return "Something went wrong, please try again later."
+	}
+	return reply
+}
+
+func (s *TelegramBotService) link(chatID int64, username, code string) (string, error) {
+	if code == "" {
+		return "Send /link with the code from the app.", nil
+	}
+	codeHash := hashSecretToken(strings.ToUpper(code))
+	link, err := s.linkRepo.FindByCode(codeHash, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "That link code is wrong or expired. Get a new one from the app.", nil
+	}
+	linked, err := s.linkRepo.Complete(link.ID, codeHash, chatID, username, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	if !linked {
+		return "That link code was already used. Get a new one from the app.", nil
+	}
+	return "This chat is linked. Movies you add go to your watchlist, and your notifications show up here.", nil
+}
+
+func (s *TelegramBotService) search(ctx context.Context, query string) (string, error) {
+	if query == "" {
+		return "What should I search for? Send /search <title>.", nil
+	}
+	result, err := s.movieService.SearchMovies(ctx, query, 1, nil)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, movie := range result.Movies {
+		if len(lines) == telegramSearchResults {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• %s (%s) %s", movie.Title, movie.Year, movie.IMDbID))
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("No movies found for %q.", query), nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// linkedUser returns the active account linked to the chat, or a reply
+// explaining why there is none
+func (s *TelegramBotService) linkedUser(chatID int64) (*models.User, string, error) {
+	link, err := s.linkRepo.FindByChat(chatID)
+	if err != nil {
+		return nil, "", err
+	}
+	if link == nil {
+		return nil, "Link this chat first: get a link code in the app and send /link <code>.", nil
+	}
+	user, err := s.userRepo.FindByID(link.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil || user.DeactivatedAt != nil {
+		return nil, "The linked account no longer exists.", nil
+	}
+	return user, "", nil
+}
+
+func (s *TelegramBotService) add(ctx context.Context, chatID int64, argument string) (string, error) {
+	if argument == "" {
+		return "Which movie should I add? Send /add <title or IMDb ID>.", nil
+	}
+	user, reply, err := s.linkedUser(chatID)
+	if user == nil {
+		return reply, err
+	}
+
+	imdbID, title := "", argument
+	if quickAddIMDbPattern.MatchString(argument) {
+		imdbID, title = quickAddIMDbPattern.FindString(argument), ""
+	}
+	match, err := s.quickAddService.Resolve(ctx, imdbID, "", title, user.ID)
+	if err != nil {
+		var unsure *QuickAddError
+		if !errors.As(err, &unsure) {
+			return "", err
+		}
+		if len(unsure.Candidates) == 0 {
+			return fmt.Sprintf("No movies found for %q.", argument), nil
+		}
+		lines := []string{"Which one? Add it with /add <IMDb ID>:"}
+		for _, candidate := range unsure.Candidates {
+			lines = append(lines, fmt.Sprintf("• %s (%s) %s", candidate.Title, candidate.Year, candidate.IMDbID))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	source := &models.WatchlistSource{Type: models.WatchlistSourceChat, Detail: "telegram"}
+	if _, err := s.watchlistService.AddWithServerSource(user.ID, match.Movie.ID, 0, source); err != nil {
+		switch {
+		case errors.Is(err, ErrQuotaExceeded):
+			return "Your watchlist is full.", nil
+		case err.Error() == "movie already in watchlist":
+			return fmt.Sprintf("%s is already on your watchlist.", match.Movie.Title), nil
+		}
+		return "", err
+	}
+	return fmt.Sprintf("Added %s (%s) to your watchlist.", match.Movie.Title, match.Movie.Year), nil
+}
+
+func (s *TelegramBotService) notify(chatID int64, argument string) (string, error) {
+	var on bool
+	switch strings.ToLower(argument) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return "Send /notify on or /notify off.", nil
+	}
+	link, err := s.linkRepo.FindByChat(chatID)
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "Link this chat first: get a link code in the app and send /link <code>.", nil
+	}
+	if _, err := s.linkRepo.SetNotify(link.UserID, on); err != nil {
+		return "", err
+	}
+	if on {
+		return "Notifications are on.", nil
+	}
+	return "Notifications are off.", nil
+}
+
+func (s *TelegramBotService) unlinkChat(chatID int64) (string, error) {
+	link, err := s.linkRepo.FindByChat(chatID)
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "This chat is not linked.", nil
+	}
+	if _, err := s.linkRepo.Delete(link.UserID); err != nil {
+		return "", err
+	}
+	return "This chat is unlinked.", nil
+}
+
+// Status returns the user's link; Linked is false until a code was used
+func (s *TelegramBotService) Status(userID primitive.ObjectID) (*TelegramLinkStatus, error) {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return &TelegramLinkStatus{Linked: false}, nil
+	}
+	return &TelegramLinkStatus{Linked: true, TelegramLink: link}, nil
+}
+
+// CreateLinkCode returns a one-time code the user sends to the bot to link
+// their chat, replacing any earlier code, and a t.me link that sends it
+// when the bot's username is known
+func (s *TelegramBotService) CreateLinkCode(userID primitive.ObjectID) (string, string, time.Time, error) {
+	code, err := newLinkCode()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	expiresAt := time.Now().UTC().Add(telegramLinkCodeTTL)
+	if err := s.linkRepo.SetCode(userID, hashSecretToken(code), expiresAt); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	linkURL := ""
+	if username := s.botUsername.Load().(string); username != "" {
+		linkURL = "https://t.me/" + username + "?start=" + code
+	}
+	return code, linkURL, expiresAt, nil
+}
+
+// SetNotify sets whether the user's notifications are sent to their chat
+func (s *TelegramBotService) SetNotify(userID primitive.ObjectID, notify bool) error {
+	found, err := s.linkRepo.SetNotify(userID, notify)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrTelegramNotLinked
+	}
+	return nil
+}
+
+// Unlink removes the user's Telegram link
+func (s *TelegramBotService) Unlink(userID primitive.ObjectID) error {
+	found, err := s.linkRepo.Delete(userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrTelegramNotLinked
+	}
+	return nil
+}
+
+func (s *TelegramBotService) forwardNotifications(ctx context.Context) {
+	ticker := time.NewTicker(telegramNotifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.sendNotifications(ctx); err != nil {
+			s.logger.Warn("failed to forward notifications to Telegram", "error", err)
+		}
+	}
+}
+
+// sendNotifications sends each linked chat the notifications created since
+// the last pass. The chat's cursor moves before sending, so with several
+// instances each notification is sent at most once; one that fails to send
+// stays in the app.
+func (s *TelegramBotService) sendNotifications(ctx context.Context) error {
+	links, err := s.linkRepo.FindNotified()
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if link.NotifiedUntil == nil {
+			continue
+		}
+		notifications, err := s.notificationRepo.FindCreatedAfter(link.UserID, *link.NotifiedUntil, telegramNotifyBatch)
+		if err != nil {
+			return err
+		}
+		if len(notifications) == 0 {
+			continue
+		}
+		claimed, err := s.linkRepo.AdvanceNotified(link.ID, *link.NotifiedUntil, notifications[len(notifications)-1].CreatedAt)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		for _, notification := range notifications {
+			err := s.client.SendMessage(ctx, link.ChatID, notification.Title+"\n"+notification.Message)
+			if errors.Is(err, ErrTelegramBlocked) {
+				// The user blocked the bot or deleted the chat
+				if _, err := s.linkRepo.SetNotify(link.UserID, false); err != nil {
+					return err
+				}
+				break
+			}
+			if err != nil {
+				s.logger.Warn("failed to send a notification to Telegram", "user_id", link.UserID.Hex(), "error", err)
+				break
+			}
+		}
+	}
+	return nil
+}
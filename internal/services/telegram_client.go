@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrTelegramBlocked is returned when Telegram refuses to deliver to a chat,
+// for example because the user blocked the bot
+var ErrTelegramBlocked = errors.New("Telegram refused to deliver to the chat")
+
+// TelegramUpdate is an incoming update; only messages are requested
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+type TelegramMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *TelegramUser `json:"from"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type TelegramUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// TelegramClient calls the Telegram Bot API as the bot whose token it holds
+type TelegramClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewTelegramClient(baseURL, token string) *TelegramClient {
+	return &TelegramClient{
+		baseURL: baseURL,
+		token:   token,
+		// Long enough for a long poll of telegramPollTimeout
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// BotUsername returns the bot's username, used in t.me links
+func (c *TelegramClient) BotUsername(ctx context.Context) (string, error) {
+	var me TelegramUser
+	if err := c.call(ctx, "getMe", nil, &me); err != nil {
+		return "", err
+	}
+	return me.Username, nil
+}
+
+// Updates waits up to timeout for messages sent to the bot from offset on
+func (c *TelegramClient) Updates(ctx context.Context, offset int64, timeout time.Duration) ([]TelegramUpdate, error) {
+	var updates []TelegramUpdate
+	err := c.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// SendMessage sends plain text to a chat
+func (c *TelegramClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// SetWebhook has Telegram post updates to webhookURL with secret in the
+// X-Telegram-Bot-Api-Secret-Token header
+func (c *TelegramClient) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	return c.call(ctx, "setWebhook", map[string]interface{}{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message"},
+	}, nil)
+}
+
+// DeleteWebhook turns a webhook off, which long polling needs
+func (c *TelegramClient) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", nil, nil)
+}
+
+// call invokes one Bot API method with body as JSON, decoding the result
+// into out when it is set
+func (c *TelegramClient) call(ctx context.Context, method string, body, out interface{}) error {
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The URL holds the token, which must not end up in logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to make request to Telegram Bot API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return ErrTelegramBlocked
+	}
+
+	var result struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode Telegram Bot API response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram Bot API %s failed with status code %d: %s", method, resp.StatusCode, result.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Result, out); err != nil {
+		return fmt.Errorf("failed to decode Telegram Bot API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrTermsNotAccepted is returned when the current terms version was not accepted
+	ErrTermsNotAccepted = errors.New("the current terms must be accepted")
+	// ErrNoTerms is returned when no terms version is configured
+	ErrNoTerms = errors.New("no terms to accept")
+)
+
+// TermsService tracks which terms of service and privacy policy version each
+// user accepted. With an empty version nothing needs accepting.
+type TermsService struct {
+	userRepo *repositories.UserRepository
+	version  string
+}
+
+func NewTermsService(userRepo *repositories.UserRepository, version string) *TermsService {
+	return &TermsService{userRepo: userRepo, version: version}
+}
+
+// Version returns the current terms version, empty when none is configured
+func (s *TermsService) Version() string {
+	return s.version
+}
+
+// Required reports whether users must accept a terms version
+func (s *TermsService) Required() bool {
+	return s.version != ""
+}
+
+// CheckRegistration verifies that a new user accepted the current version
+func (s *TermsService) CheckRegistration(acceptedVersion string) error {
+	if s.Required() && acceptedVersion != s.version {
+		return ErrTermsNotAccepted
+	}
+	return nil
+}
+
+// HasAccepted reports whether the user accepted the current version. Kids
+// profiles and other IDs without a user document have nothing to accept.
+func (s *TermsService) HasAccepted(userID primitive.ObjectID) (bool, error) {
+	if !s.Required() {
+		return true, nil
+	}
+	accepted, found, err := s.userRepo.FindTermsVersion(userID)
+	if err != nil {
+		return false, err
+	}
+	return !found || accepted == s.version, nil
+}
+
+// Accept records that the user accepted version, which must be the current one
+func (s *TermsService) Accept(userID primitive.ObjectID, version string) (time.Time, error) {
+	if !s.Required() {
+		return time.Time{}, ErrNoTerms
+	}
+	if version != s.version {
+		return time.Time{}, ErrTermsNotAccepted
+	}
+
+	now := time.Now().UTC()
+	found, err := s.userRepo.AcceptTerms(userID, version, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !found {
+		return time.Time{}, errors.New("user not found")
+	}
+	return now, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// dayStartHour and dayEndHour bound the local hours in which reminders
+	// are delivered
+	dayStartHour = 9
+	dayEndHour   = 21
+)
+
+// ErrInvalidTimezone is returned for a name that is not an IANA timezone
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// normalizeTimezone checks that name is an IANA timezone and returns its
+// canonical spelling; empty and "UTC" both mean UTC and are stored as empty
+func normalizeTimezone(name string) (string, error) {
+	if name == "" || name == "UTC" {
+		return "", nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc.String(), nil
+}
+
+// locationOf returns the location of a stored timezone, falling back to UTC
+// for empty or no longer known names
+func locationOf(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// userLocation returns the location of the user or kids profile
+func userLocation(userRepo *repositories.UserRepository, userID primitive.ObjectID) (*time.Location, error) {
+	timezone, err := userRepo.FindTimezone(userID)
+	if err != nil {
+		return nil, err
+	}
+	return locationOf(timezone), nil
+}
+
+// localDaytime moves t forward to the start of the local day when it falls
+// at night in loc, so users are not nudged while asleep
+func localDaytime(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), dayStartHour, 0, 0, 0, loc)
+	switch {
+	case local.Hour() < dayStartHour:
+		return start.UTC()
+	case local.Hour() >= dayEndHour:
+		return start.AddDate(0, 0, 1).UTC()
+	}
+	return t
+}
+
+// nearestLocalHour returns the time closest to t at which the clock in loc
+// shows hour:00
+func nearestLocalHour(t time.Time, loc *time.Location, hour int) time.Time {
+	local := t.In(loc)
+	slot := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	switch {
+	case t.Sub(slot) > 12*time.Hour:
+		slot = slot.AddDate(0, 0, 1)
+	case slot.Sub(t) > 12*time.Hour:
+		slot = slot.AddDate(0, 0, -1)
+	}
+	return slot.UTC()
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"movie-watchlist/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tmdbBaseURL = "https://api.themoviedb.org/3"
+	// tmdbPosterBaseURL serves posters at a width close to OMDb's
+	tmdbPosterBaseURL = "https://image.tmdb.org/t/p/w500"
+	// tmdbReleaseLayout is how TMDb formats dates, e.g. "2010-07-16"
+	tmdbReleaseLayout = "2006-01-02"
+	// tmdbTopCast is how many actors are kept, as many as OMDb lists
+	tmdbTopCast = 3
+)
+
+// tmdbMetadataProvider fetches details from The Movie Database. TMDb has no
+// IMDb rating, so that field stays empty; the certification is the US one.
+type tmdbMetadataProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewTMDbMetadataProvider returns a provider for the TMDb v3 API
+func NewTMDbMetadataProvider(apiKey string) MetadataProvider {
+	return &tmdbMetadataProvider{
+		apiKey:  apiKey,
+		baseURL: tmdbBaseURL,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *tmdbMetadataProvider) Name() string {
+	return models.MovieSourceTMDb
+}
+
+type tmdbFindResponse struct {
+	MovieResults []struct {
+		ID int `json:"id"`
+	} `json:"movie_results"`
+}
+
+type tmdbMovie struct {
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date"`
+	Overview    string `json:"overview"`
+	PosterPath  string `json:"poster_path"`
+	Runtime     int    `json:"runtime"`
+	Genres      []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	SpokenLanguages []struct {
+		EnglishName string `json:"english_name"`
+	} `json:"spoken_languages"`
+	Credits struct {
+		Cast []struct {
+			Name string `json:"name"`
+		} `json:"cast"`
+		Crew []struct {
+			Name string `json:"name"`
+			Job  string `json:"job"`
+		} `json:"crew"`
+	} `json:"credits"`
+	ReleaseDates struct {
+		Results []struct {
+			Country  string `json:"iso_3166_1"`
+			Releases []struct {
+				Certification string `json:"certification"`
+			} `json:"release_dates"`
+		} `json:"results"`
+	} `json:"release_dates"`
+}
+
+func (p *tmdbMetadataProvider) FetchDetails(ctx context.Context, imdbID string) (*OMDbResponse, error) {
+	var found tmdbFindResponse
+	if err := p.get(ctx, "/find/"+url.PathEscape(imdbID), url.Values{"external_source": {"imdb_id"}}, &found); err != nil {
+		return nil, err
+	}
+	if len(found.MovieResults) == 0 {
+		return nil, fmt.Errorf("TMDb has no movie for %s: %w", imdbID, ErrMovieNotFound)
+	}
+
+	var movie tmdbMovie
+	path := "/movie/" + strconv.Itoa(found.MovieResults[0].ID)
+	if err := p.get(ctx, path, url.Values{"append_to_response": {"credits,release_dates"}}, &movie); err != nil {
+		return nil, err
+	}
+	return movie.toOMDb(imdbID), nil
+}
+
+// get calls one TMDb endpoint and decodes the JSON response into out
+func (p *tmdbMetadataProvider) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	query.Set("api_key", p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to TMDb API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("TMDb API returned status code %d: %w", resp.StatusCode, ErrProviderRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TMDb API returned status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode TMDb API response: %w", err)
+	}
+	return nil
+}
+
+// toOMDb maps TMDb details onto the OMDb fields the movie cache stores
+func (m *tmdbMovie) toOMDb(imdbID string) *OMDbResponse {
+	details := &OMDbResponse{
+		Title:    m.Title,
+		IMDbID:   imdbID,
+		Plot:     m.Overview,
+		Response: "True",
+	}
+	if released, err := time.Parse(tmdbReleaseLayout, m.ReleaseDate); err == nil {
+		details.Year = strconv.Itoa(released.Year())
+		details.Released = released.Format(omdbReleasedLayout)
+	}
+	if m.PosterPath != "" {
+		details.Poster = tmdbPosterBaseURL + m.PosterPath
+	}
+	if m.Runtime > 0 {
+		details.Runtime = fmt.Sprintf("%d min", m.Runtime)
+	}
+
+	genres := make([]string, 0, len(m.Genres))
+	for _, genre := range m.Genres {
+		genres = append(genres, genre.Name)
+	}
+	details.Genre = strings.Join(genres, ", ")
+
+	languages := make([]string, 0, len(m.SpokenLanguages))
+	for _, language := range m.SpokenLanguages {
+		if language.EnglishName != "" {
+			languages = append(languages, language.EnglishName)
+		}
+	}
+	details.Language = strings.Join(languages, ", ")
+
+	directors := []string{}
+	for _, member := range m.Credits.Crew {
+		if member.Job == "Director" {
+			directors = append(directors, member.Name)
+		}
+	}
+	details.Director = strings.Join(directors, ", ")
+
+	// The cast is in billing order; OMDb lists the top-billed actors
+	actors := []string{}
+	for _, member := range m.Credits.Cast {
+		if len(actors) == tmdbTopCast {
+			break
+		}
+		actors = append(actors, member.Name)
+	}
+	details.Actors = strings.Join(actors, ", ")
+
+	for _, result := range m.ReleaseDates.Results {
+		if result.Country != "US" {
+			continue
+		}
+		for _, release := range result.Releases {
+			if release.Certification != "" {
+				details.Rated = release.Certification
+				break
+			}
+		}
+	}
+	return details
+}
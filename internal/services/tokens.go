@@ -0,0 +1,45 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+)
+
+const (
+	// linkCodeAlphabet leaves out characters that are easily mistaken for
+	// one another
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	linkCodeLength   = 8
+)
+
+// newSecretToken returns a random token for refresh tokens and confirmation links
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecretToken returns the form of a secret token that is stored in the database
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newLinkCode returns a short random code that is easy to type, for linking
+// chat accounts
+func newLinkCode() (string, error) {
+	alphabet := big.NewInt(int64(len(linkCodeAlphabet)))
+	code := make([]byte, linkCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabet)
+		if err != nil {
+			return "", err
+		}
+		code[i] = linkCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
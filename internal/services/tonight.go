@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"movie-watchlist/internal/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Weights of the "watch tonight" score components, each normalised to 0..1
+const (
+	tonightPriorityWeight = 0.45
+	tonightRatingWeight   = 0.35
+	tonightAgeWeight      = 0.20
+
+	// tonightMaxAgeDays is the watchlist age at which the age component maxes out
+	tonightMaxAgeDays = 180
+)
+
+// TonightPick is a watchlist movie that fits the viewer's available time
+type TonightPick struct {
+	Entry   models.Watchlist
+	Movie   models.Movie
+	Score   float64
+	Reasons []string
+}
+
+// GetTonightPicks returns up to limit unwatched watchlist movies whose runtime
+// fits in availableMinutes, best first. Movies with an unknown runtime are
+// skipped since they cannot be shown to fit.
+func (s *WatchlistService) GetTonightPicks(userID primitive.ObjectID, availableMinutes, limit int) ([]TonightPick, error) {
+	entries, err := s.watchlistRepo.GetUserWatchlist(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var movieIDs []primitive.ObjectID
+	for _, entry := range entries {
+		if entry.WatchedAt == nil {
+			movieIDs = append(movieIDs, entry.MovieID)
+		}
+	}
+
+	movies, err := s.movieRepo.FindByIDs(movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	picks := []TonightPick{}
+	for _, entry := range entries {
+		if entry.WatchedAt != nil {
+			continue
+		}
+		movie, ok := movies[entry.MovieID]
+		if !ok {
+			continue
+		}
+		runtime := movie.RuntimeMinutes()
+		if runtime == 0 || runtime > availableMinutes {
+			continue
+		}
+
+		picks = append(picks, scoreTonightPick(entry, movie, runtime, availableMinutes, now))
+	}
+
+	sort.SliceStable(picks, func(i, j int) bool {
+		if picks[i].Score != picks[j].Score {
+			return picks[i].Score > picks[j].Score
+		}
+		return picks[i].Entry.AddedAt.Before(picks[j].Entry.AddedAt)
+	})
+
+	if len(picks) > limit {
+		picks = picks[:limit]
+	}
+	return picks, nil
+}
+
+func scoreTonightPick(entry models.Watchlist, movie models.Movie, runtime, availableMinutes int, now time.Time) TonightPick {
+	priority := entry.EffectivePriority()
+	priorityScore := float64(priority-models.MinWatchlistPriority) / float64(models.MaxWatchlistPriority-models.MinWatchlistPriority)
+
+	rating, ratingErr := strconv.ParseFloat(movie.IMDbRating, 64)
+	ratingScore := 0.5 // unknown ratings are treated as average
+	if ratingErr == nil {
+		ratingScore = rating / 10
+	}
+
+	ageDays := int(now.Sub(entry.AddedAt).Hours() / 24)
+	ageScore := float64(ageDays) / tonightMaxAgeDays
+	if ageScore > 1 {
+		ageScore = 1
+	}
+
+	reasons := []string{fmt.Sprintf("Runs %d min, fits in your %d minutes", runtime, availableMinutes)}
+	if priority > models.DefaultWatchlistPriority {
+		reasons = append(reasons, fmt.Sprintf("You gave it priority %d of %d", priority, models.MaxWatchlistPriority))
+	}
+	if ratingErr == nil && rating >= 7.5 {
+		reasons = append(reasons, fmt.Sprintf("Rated %.1f on IMDb", rating))
+	}
+	if ageDays >= 30 {
+		reasons = append(reasons, fmt.Sprintf("On your watchlist for %d days", ageDays))
+	}
+
+	return TonightPick{
+		Entry:   entry,
+		Movie:   movie,
+		Score:   tonightPriorityWeight*priorityScore + tonightRatingWeight*ratingScore + tonightAgeWeight*ageScore,
+		Reasons: reasons,
+	}
+}
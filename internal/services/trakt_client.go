@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// traktAuthorizeURL is Trakt's consent screen; it lives on the website, not
+// the API host
+const traktAuthorizeURL = "https://trakt.tv/oauth/authorize"
+
+// ErrTraktUnauthorized is returned when Trakt rejects the user's tokens, for
+// example after the app was revoked on trakt.tv
+var ErrTraktUnauthorized = errors.New("Trakt rejected the account's authorization")
+
+// TraktToken is a Trakt OAuth token response
+type TraktToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// ExpiresAt returns when the access token expires
+func (t *TraktToken) ExpiresAt() time.Time {
+	created := time.Unix(t.CreatedAt, 0)
+	if t.CreatedAt == 0 {
+		created = time.Now()
+	}
+	return created.Add(time.Duration(t.ExpiresIn) * time.Second).UTC()
+}
+
+// traktIDs identifies a movie on Trakt; only the IMDb ID is used here
+type traktIDs struct {
+	IMDb string `json:"imdb,omitempty"`
+}
+
+type traktMovie struct {
+	Title string   `json:"title,omitempty"`
+	Year  int      `json:"year,omitempty"`
+	IDs   traktIDs `json:"ids"`
+}
+
+type traktWatchedItem struct {
+	Plays         int        `json:"plays"`
+	LastWatchedAt time.Time  `json:"last_watched_at"`
+	Movie         traktMovie `json:"movie"`
+}
+
+type traktWatchlistItem struct {
+	ListedAt time.Time  `json:"listed_at"`
+	Movie    traktMovie `json:"movie"`
+}
+
+type traktRatingItem struct {
+	RatedAt time.Time  `json:"rated_at"`
+	Rating  int        `json:"rating"`
+	Movie   traktMovie `json:"movie"`
+}
+
+// traktSyncMovie is one movie sent to a /sync endpoint
+type traktSyncMovie struct {
+	IDs       traktIDs   `json:"ids"`
+	Rating    int        `json:"rating,omitempty"`
+	RatedAt   *time.Time `json:"rated_at,omitempty"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+}
+
+// TraktClient calls the Trakt API v2 on behalf of the app registered with
+// clientID
+type TraktClient struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	redirectURI  string
+	client       *http.Client
+}
+
+func NewTraktClient(baseURL, clientID, clientSecret, redirectURI string) *TraktClient {
+	return &TraktClient{
+		baseURL:      baseURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// AuthorizeURL returns the consent screen URL the user is sent to, carrying
+// state back to the redirect URI
+func (c *TraktClient) AuthorizeURL(state string) string {
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.clientID},
+		"redirect_uri":  {c.redirectURI},
+		"state":         {state},
+	}
+	return traktAuthorizeURL + "?" + query.Encode()
+}
+
+// ExchangeCode trades the code from the consent screen for tokens
+func (c *TraktClient) ExchangeCode(ctx context.Context, code string) (*TraktToken, error) {
+	return c.token(ctx, map[string]string{"grant_type": "authorization_code", "code": code})
+}
+
+// Refresh trades a refresh token for new tokens
+func (c *TraktClient) Refresh(ctx context.Context, refreshToken string) (*TraktToken, error) {
+	return c.token(ctx, map[string]string{"grant_type": "refresh_token", "refresh_token": refreshToken})
+}
+
+func (c *TraktClient) token(ctx context.Context, body map[string]string) (*TraktToken, error) {
+	body["client_id"] = c.clientID
+	body["client_secret"] = c.clientSecret
+	body["redirect_uri"] = c.redirectURI
+
+	var token TraktToken
+	if err := c.do(ctx, http.MethodPost, "/oauth/token", "", body, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("Trakt returned no access token")
+	}
+	return &token, nil
+}
+
+// Revoke invalidates an access token
+func (c *TraktClient) Revoke(ctx context.Context, accessToken string) error {
+	body := map[string]string{"token": accessToken, "client_id": c.clientID, "client_secret": c.clientSecret}
+	return c.do(ctx, http.MethodPost, "/oauth/revoke", "", body, nil)
+}
+
+// Username returns the Trakt username the token belongs to
+func (c *TraktClient) Username(ctx context.Context, accessToken string) (string, error) {
+	var settings struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/users/settings", accessToken, nil, &settings); err != nil {
+		return "", err
+	}
+	return settings.User.Username, nil
+}
+
+// WatchedMovies returns every movie the user has watched
+func (c *TraktClient) WatchedMovies(ctx context.Context, accessToken string) ([]traktWatchedItem, error) {
+	var items []traktWatchedItem
+	err := c.do(ctx, http.MethodGet, "/sync/watched/movies", accessToken, nil, &items)
+	return items, err
+}
+
+// WatchlistMovies returns the movies on the user's Trakt watchlist
+func (c *TraktClient) WatchlistMovies(ctx context.Context, accessToken string) ([]traktWatchlistItem, error) {
+	var items []traktWatchlistItem
+	err := c.do(ctx, http.MethodGet, "/sync/watchlist/movies", accessToken, nil, &items)
+	return items, err
+}
+
+// RatedMovies returns the user's movie ratings, from 1 to 10
+func (c *TraktClient) RatedMovies(ctx context.Context, accessToken string) ([]traktRatingItem, error) {
+	var items []traktRatingItem
+	err := c.do(ctx, http.MethodGet, "/sync/ratings/movies", accessToken, nil, &items)
+	return items, err
+}
+
+// SyncMovies posts movies to a /sync endpoint such as /sync/ratings or
+// /sync/watchlist/remove
+func (c *TraktClient) SyncMovies(ctx context.Context, accessToken, path string, movies []traktSyncMovie) error {
+	return c.do(ctx, http.MethodPost, path, accessToken, map[string]interface{}{"movies": movies}, nil)
+}
+
+// do calls one Trakt endpoint, sending body as JSON and decoding the JSON
+// response into out when it is set
+func (c *TraktClient) do(ctx context.Context, method, path, accessToken string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", c.clientID)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to Trakt API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrTraktUnauthorized
+	// The token endpoint answers a used or expired code or refresh token
+	// with 400 invalid_grant
+	case resp.StatusCode == http.StatusBadRequest && path == "/oauth/token":
+		return ErrTraktUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("Trakt API returned status code: %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Trakt API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,595 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// traktStateTTL is how long the user has to approve the link on Trakt
+	traktStateTTL = 15 * time.Minute
+	// traktTokenLeeway refreshes access tokens this long before they expire
+	traktTokenLeeway = time.Hour
+	// traktSyncStale is when a queued or running sync is assumed lost, so a
+	// new one may be requested
+	traktSyncStale = time.Hour
+)
+
+// What a push job sends to Trakt
+const (
+	traktPushRating    = "rating"
+	traktPushWatchlist = "watchlist"
+	traktPushWatched   = "watched"
+)
+
+var (
+	ErrTraktNotLinked       = errors.New("no Trakt account is linked")
+	ErrInvalidTraktState    = errors.New("the Trakt authorization expired or was already used")
+	ErrInvalidTraktSettings = errors.New("invalid Trakt settings")
+	ErrTraktSyncInProgress  = errors.New("a Trakt sync is already queued or running")
+)
+
+// TraktSyncRetryPolicy gives the sync job, which may fetch thousands of
+// movies, more time than the default and fewer retries
+var TraktSyncRetryPolicy = jobs.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   5 * time.Minute,
+	MaxDelay:    30 * time.Minute,
+	Timeout:     15 * time.Minute,
+}
+
+// traktOrigin reports Trakt imports per list and marks the watchlist entries
+// they add
+var traktOrigin = importOrigin{
+	watchlistFile: "watchlist",
+	ratingsFile:   "ratings",
+	source:        models.WatchlistSource{Type: models.WatchlistSourceImport, Detail: "trakt"},
+}
+
+// TraktStatus is the user's Trakt link as shown to them
+type TraktStatus struct {
+	Linked bool `json:"linked"`
+	*models.TraktLink
+}
+
+// TraktService links Trakt accounts, imports their watched history,
+// watchlist and ratings, and pushes local changes back when the user opts
+// in. Imports are written through the archive importer, so they publish no
+// events and are never pushed back to Trakt.
+type TraktService struct {
+	client        *TraktClient
+	linkRepo      *repositories.TraktLinkRepository
+	movieRepo     *repositories.MovieRepository
+	ratingRepo    *repositories.RatingRepository
+	watchlistRepo *repositories.WatchlistRepository
+	importer      *ArchiveImportService
+	encryption    *EncryptionService
+	jobQueue      *jobs.Queue
+	logger        *slog.Logger
+}
+
+// NewTraktService creates the service; it is disabled when client is nil
+func NewTraktService(client *TraktClient, linkRepo *repositories.TraktLinkRepository, movieRepo *repositories.MovieRepository, ratingRepo *repositories.RatingRepository, watchlistRepo *repositories.WatchlistRepository, importer *ArchiveImportService, encryption *EncryptionService, jobQueue *jobs.Queue) *TraktService {
+	return &TraktService{
+		client:        client,
+		linkRepo:      linkRepo,
+		movieRepo:     movieRepo,
+		ratingRepo:    ratingRepo,
+		watchlistRepo: watchlistRepo,
+		importer:      importer,
+		encryption:    encryption,
+		jobQueue:      jobQueue,
+		logger:        logging.For("services.trakt"),
+	}
+}
+
+// Enabled reports whether Trakt sync is configured
+func (s *TraktService) Enabled() bool {
+	return s.client != nil
+}
+
+// Subscribe queues a push to Trakt for new and changed ratings, watchlist
+// additions and removals, and movies marked watched. Only users who turned
+// push on are affected.
+func (s *TraktService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.NameMovieRated, "trakt", func(event events.Event) error {
+		rated := event.(events.MovieRated)
+		return s.queuePush(rated.UserID, rated.MovieID, traktPushRating)
+	})
+	bus.Subscribe(events.NameRatingUpdated, "trakt", func(event events.Event) error {
+		updated := event.(events.RatingUpdated)
+		return s.queuePush(updated.UserID, updated.MovieID, traktPushRating)
+	})
+	bus.Subscribe(events.NameWatchlistItemAdded, "trakt", func(event events.Event) error {
+		added := event.(events.WatchlistItemAdded)
+		return s.queuePush(added.UserID, added.MovieID, traktPushWatchlist)
+	})
+	bus.Subscribe(events.NameWatchlistItemRemoved, "trakt", func(event events.Event) error {
+		removed := event.(events.WatchlistItemRemoved)
+		return s.queuePush(removed.UserID, removed.MovieID, traktPushWatchlist)
+	})
+	// Trakt keeps history, so marking a movie unwatched is not undone there
+	bus.Subscribe(events.NameWatchlistItemUpdated, "trakt", func(event events.Event) error {
+		updated := event.(events.WatchlistItemUpdated)
+		if updated.Change != events.ChangeWatched {
+			return nil
+		}
+		return s.queuePush(updated.UserID, updated.MovieID, traktPushWatched)
+	})
+}
+
+func (s *TraktService) queuePush(userID, movieID primitive.ObjectID, action string) error {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.LinkedAt == nil || !link.Push {
+		return nil
+	}
+	return s.jobQueue.Enqueue(jobs.TypeTraktPush, map[string]interface{}{
+		"user_id":  userID.Hex(),
+		"movie_id": movieID.Hex(),
+		"action":   action,
+	})
+}
+
+// Status returns the user's link; Linked is false until an authorization
+// has completed
+func (s *TraktService) Status(userID primitive.ObjectID) (*TraktStatus, error) {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return &TraktStatus{Linked: false}, nil
+	}
+	return &TraktStatus{Linked: true, TraktLink: link}, nil
+}
+
+// Authorize starts linking and returns the Trakt consent screen URL to send
+// the user to. Linking again replaces the current tokens once approved.
+func (s *TraktService) Authorize(userID primitive.ObjectID) (string, error) {
+	state, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().UTC().Add(traktStateTTL)
+	if _, err := s.linkRepo.BeginAuthorization(userID, hashSecretToken(state), expiresAt, ArchiveConflictMerge); err != nil {
+		return "", err
+	}
+	return s.client.AuthorizeURL(state), nil
+}
+
+// CompleteAuthorization handles Trakt's redirect back with the code and
+// state, stores the account's tokens and queues the first sync
+func (s *TraktService) CompleteAuthorization(ctx context.Context, code, state string) (*models.TraktLink, error) {
+	if code == "" || state == "" {
+		return nil, ErrInvalidTraktState
+	}
+	stateHash := hashSecretToken(state)
+	link, err := s.linkRepo.FindByState(stateHash, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrInvalidTraktState
+	}
+
+	token, err := s.client.ExchangeCode(ctx, code)
+	if errors.Is(err, ErrTraktUnauthorized) {
+		return nil, fmt.Errorf("%w: Trakt did not accept the authorization code", ErrInvalidTraktState)
+	}
+	if err != nil {
+		return nil, err
+	}
+	username, err := s.client.Username(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, refreshToken, err := s.sealTokens(link.ID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	linked, err := s.linkRepo.CompleteAuthorization(link.ID, stateHash, username, accessToken, refreshToken, token.ExpiresAt(), now)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return nil, ErrInvalidTraktState
+	}
+	link.Username = username
+	link.LinkedAt = &now
+	link.StateHash = ""
+	link.StateExpiresAt = nil
+
+	if err := s.queueSync(link); err != nil {
+		s.logger.Warn("failed to queue first Trakt sync", "user_id", link.UserID.Hex(), "error", err)
+	}
+	return link, nil
+}
+
+// UpdateSettings changes whether local changes are pushed to Trakt and how
+// imports resolve conflicts; nil leaves a setting as it is
+func (s *TraktService) UpdateSettings(userID primitive.ObjectID, push *bool, onConflict *string) (*TraktStatus, error) {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return nil, ErrTraktNotLinked
+	}
+
+	if push != nil {
+		link.Push = *push
+	}
+	if onConflict != nil {
+		switch *onConflict {
+		case ArchiveConflictSkip, ArchiveConflictOverwrite, ArchiveConflictMerge:
+			link.OnConflict = *onConflict
+		default:
+			return nil, fmt.Errorf("%w: on_conflict must be skip, overwrite or merge", ErrInvalidTraktSettings)
+		}
+	}
+
+	found, err := s.linkRepo.UpdateSettings(userID, link.Push, link.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrTraktNotLinked
+	}
+	return &TraktStatus{Linked: true, TraktLink: link}, nil
+}
+
+// RequestSync queues an import from Trakt
+func (s *TraktService) RequestSync(userID primitive.ObjectID) (*TraktStatus, error) {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return nil, ErrTraktNotLinked
+	}
+	if run := link.LastSync; run != nil && (run.Status == models.TraktSyncQueued || run.Status == models.TraktSyncRunning) &&
+		time.Since(run.At) < traktSyncStale {
+		return nil, ErrTraktSyncInProgress
+	}
+
+	if err := s.queueSync(link); err != nil {
+		return nil, err
+	}
+	return &TraktStatus{Linked: true, TraktLink: link}, nil
+}
+
+func (s *TraktService) queueSync(link *models.TraktLink) error {
+	run := models.TraktSyncRun{Status: models.TraktSyncQueued, At: time.Now().UTC()}
+	if err := s.linkRepo.SetSyncRun(link.ID, run); err != nil {
+		return err
+	}
+	link.LastSync = &run
+	return s.jobQueue.Enqueue(jobs.TypeTraktSync, map[string]interface{}{"user_id": link.UserID.Hex()})
+}
+
+// Unlink removes the user's link, revoking its token on Trakt when possible
+func (s *TraktService) Unlink(ctx context.Context, userID primitive.ObjectID) error {
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return ErrTraktNotLinked
+	}
+
+	if link.AccessToken != "" {
+		accessToken, err := s.encryption.Decrypt("trakt_links", "access_token", link.ID, link.AccessToken)
+		if err == nil {
+			err = s.client.Revoke(ctx, accessToken)
+		}
+		if err != nil {
+			s.logger.Warn("failed to revoke Trakt token", "user_id", userID.Hex(), "error", err)
+		}
+	}
+
+	deleted, err := s.linkRepo.Delete(userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTraktNotLinked
+	}
+	return nil
+}
+
+// SyncJob imports the watched history, watchlist and ratings of the Trakt
+// account linked by the user in the payload, resolving conflicts with the
+// link's on_conflict setting
+func (s *TraktService) SyncJob(ctx context.Context, payload map[string]interface{}) error {
+	if !s.Enabled() {
+		return nil
+	}
+	userHex, _ := payload["user_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid user_id %q", userHex)
+	}
+
+	// Skip if the account was unlinked since
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.LinkedAt == nil {
+		return nil
+	}
+	if err := s.linkRepo.SetSyncRun(link.ID, models.TraktSyncRun{Status: models.TraktSyncRunning, At: time.Now().UTC()}); err != nil {
+		return err
+	}
+
+	summary, syncErr := s.sync(ctx, link)
+	run := models.TraktSyncRun{Status: models.TraktSyncSucceeded, At: time.Now().UTC(), Summary: summary}
+	if syncErr != nil {
+		run = models.TraktSyncRun{Status: models.TraktSyncFailed, At: time.Now().UTC(), Error: syncErr.Error()}
+		if errors.Is(syncErr, ErrTraktUnauthorized) {
+			run.Error = "Trakt rejected the account's authorization; link it again"
+		}
+	}
+	if err := s.linkRepo.SetSyncRun(link.ID, run); err != nil {
+		s.logger.Warn("failed to record Trakt sync", "user_id", userHex, "error", err)
+	}
+
+	// Retrying cannot help until the user links the account again
+	if errors.Is(syncErr, ErrTraktUnauthorized) {
+		return nil
+	}
+	if syncErr == nil {
+		s.logger.Info("synced Trakt account", "user_id", userHex, "summary", summary)
+	}
+	return syncErr
+}
+
+func (s *TraktService) sync(ctx context.Context, link *models.TraktLink) (map[string]map[string]int, error) {
+	accessToken, err := s.accessToken(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	watched, err := s.client.WatchedMovies(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := s.client.WatchlistMovies(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rated, err := s.client.RatedMovies(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ArchiveImportReport{OnConflict: link.OnConflict, Summary: map[string]map[string]int{}, Items: []ArchiveImportItem{}}
+	watchlist, ratings := traktEntries(watched, listed, rated, report)
+	if err := s.importer.importEntries(link.UserID, watchlist, ratings, traktOrigin, link.OnConflict, false, report); err != nil {
+		return nil, err
+	}
+	return report.Summary, nil
+}
+
+// traktEntries maps Trakt's lists onto archive entries by IMDb ID. Watched
+// movies are watchlist entries marked watched, and ratings go from Trakt's
+// 1-10 scale to stars. Movies without an IMDb ID, and the oldest entries past
+// MaxArchiveEntries, are reported as skipped.
+func traktEntries(watched []traktWatchedItem, listed []traktWatchlistItem, rated []traktRatingItem, report *ArchiveImportReport) ([]archiveWatchlistEntry, []archiveRating) {
+	byIMDbID := map[string]*archiveWatchlistEntry{}
+	watchlist := []*archiveWatchlistEntry{}
+	for _, item := range listed {
+		imdbID := models.NormalizeIMDbID(item.Movie.IDs.IMDb)
+		if imdbID == "" {
+			report.add(ArchiveImportItem{File: traktOrigin.watchlistFile, Action: ImportActionSkipped, Error: fmt.Sprintf("Trakt has no IMDb ID for %s", item.Movie.Title)})
+			continue
+		}
+		if byIMDbID[imdbID] == nil {
+			entry := &archiveWatchlistEntry{IMDbID: imdbID, AddedAt: item.ListedAt}
+			byIMDbID[imdbID] = entry
+			watchlist = append(watchlist, entry)
+		}
+	}
+	for _, item := range watched {
+		imdbID := models.NormalizeIMDbID(item.Movie.IDs.IMDb)
+		if imdbID == "" {
+			report.add(ArchiveImportItem{File: traktOrigin.watchlistFile, Action: ImportActionSkipped, Error: fmt.Sprintf("Trakt has no IMDb ID for %s", item.Movie.Title)})
+			continue
+		}
+		watchedAt := item.LastWatchedAt
+		if entry := byIMDbID[imdbID]; entry != nil {
+			entry.WatchedAt = &watchedAt
+			continue
+		}
+		entry := &archiveWatchlistEntry{IMDbID: imdbID, AddedAt: watchedAt, WatchedAt: &watchedAt}
+		byIMDbID[imdbID] = entry
+		watchlist = append(watchlist, entry)
+	}
+
+	sort.SliceStable(watchlist, func(i, j int) bool {
+		return watchlist[i].AddedAt.After(watchlist[j].AddedAt)
+	})
+	entries := make([]archiveWatchlistEntry, 0, len(watchlist))
+	for i, entry := range watchlist {
+		if i >= MaxArchiveEntries {
+			report.add(ArchiveImportItem{File: traktOrigin.watchlistFile, IMDbID: entry.IMDbID, Action: ImportActionSkipped, Error: fmt.Sprintf("only the %d most recent movies are synced", MaxArchiveEntries)})
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.SliceStable(rated, func(i, j int) bool {
+		return rated[i].RatedAt.After(rated[j].RatedAt)
+	})
+	ratings := make([]archiveRating, 0, len(rated))
+	for _, item := range rated {
+		imdbID := models.NormalizeIMDbID(item.Movie.IDs.IMDb)
+		switch {
+		case imdbID == "":
+			report.add(ArchiveImportItem{File: traktOrigin.ratingsFile, Action: ImportActionSkipped, Error: fmt.Sprintf("Trakt has no IMDb ID for %s", item.Movie.Title)})
+		case len(ratings) >= MaxArchiveEntries:
+			report.add(ArchiveImportItem{File: traktOrigin.ratingsFile, IMDbID: imdbID, Action: ImportActionSkipped, Error: fmt.Sprintf("only the %d most recent ratings are synced", MaxArchiveEntries)})
+		default:
+			ratings = append(ratings, archiveRating{IMDbID: imdbID, Rating: (item.Rating + 1) / 2, RatedAt: item.RatedAt})
+		}
+	}
+	return entries, ratings
+}
+
+// PushJob sends one movie's current state to the Trakt account linked by the
+// user in the payload: their rating, whether it is on their watchlist, or
+// when they watched it. Sending the current state rather than the change
+// keeps Trakt right when pushes run out of order.
+func (s *TraktService) PushJob(ctx context.Context, payload map[string]interface{}) error {
+	if !s.Enabled() {
+		return nil
+	}
+	userHex, _ := payload["user_id"].(string)
+	movieHex, _ := payload["movie_id"].(string)
+	action, _ := payload["action"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid user_id %q", userHex)
+	}
+	movieID, err := primitive.ObjectIDFromHex(movieHex)
+	if err != nil {
+		return fmt.Errorf("payload has invalid movie_id %q", movieHex)
+	}
+
+	// Skip if the account was unlinked or push turned off since
+	link, err := s.linkRepo.FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.LinkedAt == nil || !link.Push {
+		return nil
+	}
+	movie, err := s.movieRepo.FindByID(movieID)
+	if err != nil {
+		return err
+	}
+	if movie == nil || movie.IMDbID == "" {
+		return nil
+	}
+	_, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return err
+	}
+
+	path, syncMovie, err := s.pushRequest(userID, equivalentIDs, movie.IMDbID, action)
+	if err != nil || path == "" {
+		return err
+	}
+
+	accessToken, err := s.accessToken(ctx, link)
+	if err == nil {
+		err = s.client.SyncMovies(ctx, accessToken, path, []traktSyncMovie{syncMovie})
+	}
+	pushErr := ""
+	if err != nil {
+		pushErr = err.Error()
+	}
+	if recordErr := s.linkRepo.SetPushResult(link.ID, time.Now().UTC(), pushErr); recordErr != nil {
+		s.logger.Warn("failed to record Trakt push", "user_id", userHex, "error", recordErr)
+	}
+	if errors.Is(err, ErrTraktUnauthorized) {
+		return nil
+	}
+	return err
+}
+
+// pushRequest returns the /sync endpoint and movie that bring Trakt in line
+// with the user's current state, or no path when there is nothing to send
+func (s *TraktService) pushRequest(userID primitive.ObjectID, equivalentIDs []primitive.ObjectID, imdbID, action string) (string, traktSyncMovie, error) {
+	syncMovie := traktSyncMovie{IDs: traktIDs{IMDb: imdbID}}
+	switch action {
+	case traktPushRating:
+		rating, err := s.ratingRepo.GetUserRatingForAny(userID, equivalentIDs)
+		if err != nil || rating == nil {
+			return "", syncMovie, err
+		}
+		ratedAt := rating.UpdatedAt
+		syncMovie.Rating = rating.Rating * 2
+		syncMovie.RatedAt = &ratedAt
+		return "/sync/ratings", syncMovie, nil
+
+	case traktPushWatchlist:
+		entry, err := s.watchlistRepo.FindEntryForAny(userID, equivalentIDs)
+		if err != nil {
+			return "", syncMovie, err
+		}
+		if entry == nil {
+			return "/sync/watchlist/remove", syncMovie, nil
+		}
+		// Trakt takes watched movies off the watchlist itself
+		if entry.WatchedAt != nil {
+			return "", syncMovie, nil
+		}
+		return "/sync/watchlist", syncMovie, nil
+
+	case traktPushWatched:
+		entry, err := s.watchlistRepo.FindEntryForAny(userID, equivalentIDs)
+		if err != nil || entry == nil || entry.WatchedAt == nil {
+			return "", syncMovie, err
+		}
+		watchedAt := *entry.WatchedAt
+		syncMovie.WatchedAt = &watchedAt
+		return "/sync/history", syncMovie, nil
+	}
+	return "", syncMovie, fmt.Errorf("payload has invalid action %q", action)
+}
+
+// accessToken returns the link's access token, refreshing it first when it
+// is about to expire
+func (s *TraktService) accessToken(ctx context.Context, link *models.TraktLink) (string, error) {
+	if time.Now().Add(traktTokenLeeway).Before(link.TokenExpiresAt) {
+		return s.encryption.Decrypt("trakt_links", "access_token", link.ID, link.AccessToken)
+	}
+
+	refreshToken, err := s.encryption.Decrypt("trakt_links", "refresh_token", link.ID, link.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.client.Refresh(ctx, refreshToken)
+	if err != nil {
+		return "", err
+	}
+	accessToken, sealedRefresh, err := s.sealTokens(link.ID, token)
+	if err != nil {
+		return "", err
+	}
+	if err := s.linkRepo.SaveTokens(link.ID, accessToken, sealedRefresh, token.ExpiresAt()); err != nil {
+		return "", err
+	}
+	link.AccessToken, link.RefreshToken, link.TokenExpiresAt = accessToken, sealedRefresh, token.ExpiresAt()
+	return token.AccessToken, nil
+}
+
+// sealTokens encrypts a token response's access and refresh tokens for the
+// link
+func (s *TraktService) sealTokens(linkID primitive.ObjectID, token *TraktToken) (string, string, error) {
+	accessToken, err := s.encryption.Encrypt("trakt_links", "access_token", linkID, token.AccessToken)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := s.encryption.Encrypt("trakt_links", "refresh_token", linkID, token.RefreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UndoWindow is how long a destructive action can be undone
+const UndoWindow = 30 * time.Second
+
+var (
+	ErrUndoExpired  = errors.New("undo token is invalid or expired")
+	ErrUndoConflict = errors.New("the item has been re-created since it was deleted")
+)
+
+// UndoToken is returned with destructive responses and redeemed at POST /undo
+type UndoToken struct {
+	Token     string    `json:"undo_token"`
+	ExpiresAt time.Time `json:"undo_expires_at"`
+}
+
+// UndoService is the soft-delete layer: destructive actions hand it a copy of
+// what they removed, which can be restored until the undo window closes
+type UndoService struct {
+	deletedItemRepo *repositories.DeletedItemRepository
+	watchlistRepo   *repositories.WatchlistRepository
+	logger          *slog.Logger
+}
+
+func NewUndoService(deletedItemRepo *repositories.DeletedItemRepository, watchlistRepo *repositories.WatchlistRepository) *UndoService {
+	return &UndoService{
+		deletedItemRepo: deletedItemRepo,
+		watchlistRepo:   watchlistRepo,
+		logger:          logging.For("services.undo"),
+	}
+}
+
+// Record keeps a copy of a removed document and returns the token that restores it
+func (s *UndoService) Record(userID primitive.ObjectID, kind string, document interface{}) (*UndoToken, error) {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.DeletedItem{
+		UserID:    userID,
+		Kind:      kind,
+		TokenHash: hashSecretToken(token),
+		Document:  raw,
+		ExpiresAt: time.Now().UTC().Add(UndoWindow),
+	}
+	if err := s.deletedItemRepo.Create(item); err != nil {
+		return nil, err
+	}
+	return &UndoToken{Token: token, ExpiresAt: item.ExpiresAt}, nil
+}
+
+// Undo restores the item behind the token and returns its kind and the
+// restored document
+func (s *UndoService) Undo(userID primitive.ObjectID, token string) (string, interface{}, error) {
+	item, err := s.deletedItemRepo.Take(userID, hashSecretToken(token))
+	if err != nil {
+		return "", nil, err
+	}
+	if item == nil {
+		return "", nil, ErrUndoExpired
+	}
+
+	switch item.Kind {
+	case models.DeletedWatchlistEntry:
+		var entry models.Watchlist
+		if err := bson.Unmarshal(item.Document, &entry); err != nil {
+			return "", nil, err
+		}
+		restored, err := s.watchlistRepo.Restore(&entry)
+		if err != nil {
+			return "", nil, err
+		}
+		if !restored {
+			return "", nil, ErrUndoConflict
+		}
+		s.logger.Info("restored deleted item", "user_id", userID.Hex(), "kind", item.Kind)
+		return item.Kind, &entry, nil
+	default:
+		return "", nil, fmt.Errorf("unknown deleted item kind %q", item.Kind)
+	}
+}
@@ -2,22 +2,37 @@ package services
 
 import (
 	"errors"
+	"movie-watchlist/internal/events"
 	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/pagination"
 	"movie-watchlist/internal/repositories"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	userRepo *repositories.UserRepository
+	userRepo       *repositories.UserRepository
+	passwordPolicy *PasswordPolicy
+	bus            *events.Bus
 }
 
-func NewUserService(userRepo *repositories.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo *repositories.UserRepository, passwordPolicy *PasswordPolicy, bus *events.Bus) *UserService {
+	return &UserService{
+		userRepo:       userRepo,
+		passwordPolicy: passwordPolicy,
+		bus:            bus,
+	}
 }
 
-func (s *UserService) Register(username, email, password string) (*models.User, error) {
+// Register creates a user who accepted the given terms version, empty when
+// no terms are tracked
+func (s *UserService) Register(username, email, password, termsVersion string) (*models.User, error) {
+	if err := s.passwordPolicy.Check(password, username, email); err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
@@ -46,10 +61,21 @@ func (s *UserService) Register(username, email, password string) (*models.User,
 		Email:    email,
 		Password: string(hashedPassword),
 	}
+	if termsVersion != "" {
+		now := time.Now().UTC()
+		user.TermsVersion = termsVersion
+		user.TermsAcceptedAt = &now
+	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
+	s.bus.Publish(events.UserRegistered{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		At:       user.CreatedAt,
+	})
 
 	return user, nil
 }
@@ -64,9 +90,98 @@ func (s *UserService) Login(email, password string) (*models.User, error) {
 		return nil, errors.New("invalid credentials")
 	}
 
+	// A deactivated account is awaiting deletion once its grace period ends
+	if user.DeactivatedAt != nil && time.Since(*user.DeactivatedAt) >= DeactivationGracePeriod {
+		return nil, errors.New("invalid credentials")
+	}
+
 	return user, nil
 }
 
+// Reactivate restores a deactivated account after the user logged in again
+func (s *UserService) Reactivate(userID primitive.ObjectID) error {
+	return s.userRepo.Reactivate(userID)
+}
+
 func (s *UserService) GetByID(id primitive.ObjectID) (*models.User, error) {
 	return s.userRepo.FindByID(id)
 }
+
+// ListUsers returns one keyset page of users and whether more follow
+func (s *UserService) ListUsers(cursor pagination.Cursor) ([]models.User, bool, error) {
+	return s.userRepo.List(cursor)
+}
+
+// GetLanguagePreferences returns the user's preferred languages
+func (s *UserService) GetLanguagePreferences(userID primitive.ObjectID) (*LanguagePreferences, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return &LanguagePreferences{
+		Audio:     nonNilLanguages(user.AudioLanguages),
+		Subtitles: nonNilLanguages(user.SubtitleLanguages),
+	}, nil
+}
+
+// UpdateLanguagePreferences validates and stores the user's preferred languages
+func (s *UserService) UpdateLanguagePreferences(userID primitive.ObjectID, audio, subtitles []string) (*LanguagePreferences, error) {
+	normalizedAudio, err := normalizeLanguages(audio)
+	if err != nil {
+		return nil, err
+	}
+	normalizedSubtitles, err := normalizeLanguages(subtitles)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.userRepo.UpdateLanguages(userID, normalizedAudio, normalizedSubtitles)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("user not found")
+	}
+
+	return &LanguagePreferences{Audio: normalizedAudio, Subtitles: normalizedSubtitles}, nil
+}
+
+// GetTimezone returns the user's timezone, "UTC" when none is set
+func (s *UserService) GetTimezone(userID primitive.ObjectID) (string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", errors.New("user not found")
+	}
+	return locationOf(user.Timezone).String(), nil
+}
+
+// UpdateTimezone validates and stores the user's IANA timezone
+func (s *UserService) UpdateTimezone(userID primitive.ObjectID, timezone string) (string, error) {
+	normalized, err := normalizeTimezone(timezone)
+	if err != nil {
+		return "", err
+	}
+
+	found, err := s.userRepo.UpdateTimezone(userID, normalized)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("user not found")
+	}
+	return locationOf(normalized).String(), nil
+}
+
+func nonNilLanguages(languages []string) []string {
+	if languages == nil {
+		return []string{}
+	}
+	return languages
+}
@@ -0,0 +1,7 @@
+package services
+
+import "errors"
+
+// ErrVersionConflict is returned when an update names a version (If-Match)
+// that is no longer current because another request changed the entry first
+var ErrVersionConflict = errors.New("the entry was changed by another request")
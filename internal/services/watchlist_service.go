@@ -2,41 +2,304 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
 	"movie-watchlist/internal/models"
 	"movie-watchlist/internal/repositories"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrInvalidWatchlistSource is returned for unknown watchlist sources
+var ErrInvalidWatchlistSource = errors.New("invalid watchlist source")
+
+// clientWatchlistSources are the sources clients may report when adding
+var clientWatchlistSources = map[string]bool{
+	models.WatchlistSourceSearch:         true,
+	models.WatchlistSourceBrowse:         true,
+	models.WatchlistSourceDiscover:       true,
+	models.WatchlistSourceRecommendation: true,
+	models.WatchlistSourceSimilar:        true,
+	models.WatchlistSourceCalendar:       true,
+	models.WatchlistSourceSharedList:     true,
+	models.WatchlistSourceProfile:        true,
+	models.WatchlistSourceFriend:         true,
+}
+
+// SourceStats is how many watchlist entries one source added and how many
+// of them were watched
+type SourceStats struct {
+	Type        string  `json:"type"`
+	Detail      string  `json:"detail,omitempty"`
+	Added       int64   `json:"added"`
+	Watched     int64   `json:"watched"`
+	WatchedRate float64 `json:"watched_rate"`
+}
+
 type WatchlistService struct {
 	watchlistRepo *repositories.WatchlistRepository
+	movieRepo     *repositories.MovieRepository
+	userRepo      *repositories.UserRepository
+	undoService   *UndoService
+	quotaService  *StorageQuotaService
+	bus           *events.Bus
+	jobQueue      *jobs.Queue
+	reminderDelay time.Duration
+	logger        *slog.Logger
 }
 
-func NewWatchlistService(watchlistRepo *repositories.WatchlistRepository) *WatchlistService {
-	return &WatchlistService{watchlistRepo: watchlistRepo}
+// NewWatchlistService creates the service; a reminderDelay of zero disables
+// rating reminders for movies marked watched
+func NewWatchlistService(watchlistRepo *repositories.WatchlistRepository, movieRepo *repositories.MovieRepository, userRepo *repositories.UserRepository, undoService *UndoService, quotaService *StorageQuotaService, bus *events.Bus, jobQueue *jobs.Queue, reminderDelay time.Duration) *WatchlistService {
+	return &WatchlistService{
+		watchlistRepo: watchlistRepo,
+		movieRepo:     movieRepo,
+		userRepo:      userRepo,
+		undoService:   undoService,
+		quotaService:  quotaService,
+		bus:           bus,
+		jobQueue:      jobQueue,
+		reminderDelay: reminderDelay,
+		logger:        logging.For("services.watchlist"),
+	}
 }
 
-func (s *WatchlistService) AddToWatchlist(userID primitive.ObjectID, movieID primitive.ObjectID) error {
-	exists, err := s.watchlistRepo.Exists(userID, movieID)
+// AddToWatchlist adds the canonical copy of the movie to the user's watchlist,
+// treating any duplicate document with the same IMDb ID as the same movie.
+// This check is the guard against duplicates across providers: every provider
+// keys movies by IMDb ID, so documents created by different integrations for
+// the same film resolve to one canonical ID here.
+// The source the client reported, if any, is recorded on the entry.
+func (s *WatchlistService) AddToWatchlist(userID primitive.ObjectID, movieID primitive.ObjectID, priority int, source *models.WatchlistSource) (*models.Watchlist, error) {
+	if err := validateWatchlistSource(source); err != nil {
+		return nil, err
+	}
+	return s.add(userID, movieID, priority, source, "")
+}
+
+// AddWithServerSource is AddToWatchlist for adds whose source the server
+// sets, such as extension, which clients may not report themselves
+func (s *WatchlistService) AddWithServerSource(userID primitive.ObjectID, movieID primitive.ObjectID, priority int, source *models.WatchlistSource) (*models.Watchlist, error) {
+	return s.add(userID, movieID, priority, source, "")
+}
+
+// AddWithNote is AddWithServerSource with a note kept on the entry
+func (s *WatchlistService) AddWithNote(userID primitive.ObjectID, movieID primitive.ObjectID, source *models.WatchlistSource, note string) (*models.Watchlist, error) {
+	return s.add(userID, movieID, 0, source, note)
+}
+
+func (s *WatchlistService) add(userID primitive.ObjectID, movieID primitive.ObjectID, priority int, source *models.WatchlistSource, note string) (*models.Watchlist, error) {
+	if priority == 0 {
+		priority = models.DefaultWatchlistPriority
+	}
+	if priority < models.MinWatchlistPriority || priority > models.MaxWatchlistPriority {
+		return nil, errors.New("invalid priority")
+	}
+
+	canonicalID, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	exists, err := s.watchlistRepo.ExistsAny(userID, equivalentIDs)
+	if err != nil {
+		return nil, err
 	}
 	if exists {
-		return errors.New("movie already in watchlist")
+		return nil, errors.New("movie already in watchlist")
+	}
+	if err := s.quotaService.CheckWatchlistAdd(userID); err != nil {
+		return nil, err
 	}
 
 	watchlist := &models.Watchlist{
-		UserID:  userID,
-		MovieID: movieID,
+		UserID:   userID,
+		MovieID:  canonicalID,
+		Priority: priority,
+		Source:   source,
+		Note:     note,
 	}
 
-	return s.watchlistRepo.Add(watchlist)
+	if err := s.watchlistRepo.Add(watchlist); err != nil {
+		return nil, err
+	}
+	s.bus.Publish(events.WatchlistItemAdded{
+		UserID:        userID,
+		MovieID:       canonicalID,
+		EquivalentIDs: equivalentIDs,
+		Source:        source,
+		At:            watchlist.AddedAt,
+	})
+	return watchlist, nil
+}
+
+// validateWatchlistSource checks a client-reported source; recommendation
+// sources must name a recommendation row
+func validateWatchlistSource(source *models.WatchlistSource) error {
+	if source == nil {
+		return nil
+	}
+	if !clientWatchlistSources[source.Type] {
+		return fmt.Errorf("%w: unknown source type %q", ErrInvalidWatchlistSource, source.Type)
+	}
+	if source.Type == models.WatchlistSourceRecommendation {
+		switch source.Detail {
+		case models.RecommendationRowForYou, models.RecommendationRowTrending, models.RecommendationRowFollowing:
+		default:
+			return fmt.Errorf("%w: unknown recommendation row %q, expected for_you, trending or following", ErrInvalidWatchlistSource, source.Detail)
+		}
+	}
+	return nil
 }
 
-func (s *WatchlistService) RemoveFromWatchlist(userID primitive.ObjectID, movieID primitive.ObjectID) error {
-	return s.watchlistRepo.Remove(userID, movieID)
+// GetSourceStats counts the watchlist entries added from each source and
+// how many were watched, for one user or, with a nil userID, for everyone
+func (s *WatchlistService) GetSourceStats(userID *primitive.ObjectID) ([]SourceStats, error) {
+	counts, err := s.watchlistRepo.CountBySource(userID)
+	if err != nil {
+		return nil, err
+	}
+	return sourceStats(counts), nil
+}
+
+func sourceStats(counts []repositories.SourceCount) []SourceStats {
+	stats := make([]SourceStats, 0, len(counts))
+	for _, count := range counts {
+		sourceType := count.Type
+		if sourceType == "" {
+			sourceType = "unknown"
+		}
+		stats = append(stats, SourceStats{
+			Type:        sourceType,
+			Detail:      count.Detail,
+			Added:       count.Added,
+			Watched:     count.Watched,
+			WatchedRate: ratio(count.Watched, count.Added),
+		})
+	}
+	return stats
+}
+
+// RemoveFromWatchlist removes the movie from the user's watchlist and returns
+// a token that restores the entry during the undo window. The movie may be
+// given by any ID of the same film. The token is nil when the movie was not
+// on the watchlist.
+func (s *WatchlistService) RemoveFromWatchlist(userID primitive.ObjectID, movieID primitive.ObjectID) (*UndoToken, error) {
+	_, equivalentIDs, err := s.movieRepo.ResolveCanonicalID(movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.watchlistRepo.RemoveForAny(userID, equivalentIDs)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	s.bus.Publish(events.WatchlistItemRemoved{UserID: userID, MovieID: entry.MovieID, At: time.Now().UTC()})
+
+	undo, err := s.undoService.Record(userID, models.DeletedWatchlistEntry, entry)
+	if err != nil {
+		// The entry is removed either way; only the undo is lost
+		s.logger.Warn("failed to record undo for watchlist removal", "error", err)
+		return nil, nil
+	}
+	return undo, nil
 }
 
 func (s *WatchlistService) GetUserWatchlist(userID primitive.ObjectID) ([]models.Watchlist, error) {
 	return s.watchlistRepo.GetUserWatchlist(userID)
 }
+
+// GetUserWatchlistPage returns one page of the user's watchlist and the total entry count
+func (s *WatchlistService) GetUserWatchlistPage(userID primitive.ObjectID, offset, limit int) ([]models.Watchlist, int64, error) {
+	return s.watchlistRepo.GetUserWatchlistPage(userID, int64(offset), int64(limit))
+}
+
+// GetWatchlistEntry returns the user's watchlist entry for a movie, or nil when absent
+func (s *WatchlistService) GetWatchlistEntry(userID primitive.ObjectID, movieID primitive.ObjectID) (*models.Watchlist, error) {
+	return s.watchlistRepo.FindEntry(userID, movieID)
+}
+
+// MarkWatched records that the user watched a movie on their watchlist and
+// schedules a reminder to rate it. expectedVersion works as in SetPriority.
+func (s *WatchlistService) MarkWatched(userID primitive.ObjectID, movieID primitive.ObjectID, expectedVersion *int) (*models.Watchlist, error) {
+	now := time.Now().UTC()
+	found, err := s.watchlistRepo.SetWatched(userID, movieID, &now, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, s.updateMissError(userID, movieID)
+	}
+	s.bus.Publish(events.WatchlistItemUpdated{UserID: userID, MovieID: movieID, Change: events.ChangeWatched, At: now})
+
+	if s.reminderDelay > 0 {
+		// Reminders falling at night in the user's timezone wait for morning
+		runAt := now.Add(s.reminderDelay)
+		if loc, err := userLocation(s.userRepo, userID); err != nil {
+			s.logger.Warn("failed to look up timezone for rating reminder", "error", err)
+		} else {
+			runAt = localDaytime(runAt, loc)
+		}
+		payload := map[string]interface{}{"user_id": userID.Hex(), "movie_id": movieID.Hex()}
+		if err := s.jobQueue.EnqueueAt(jobs.TypeRatingReminder, payload, runAt); err != nil {
+			// The entry is marked watched either way; only the nudge is lost
+			s.logger.Warn("failed to schedule rating reminder", "error", err)
+		}
+	}
+	if err := s.jobQueue.Enqueue(jobs.TypeCheckWatchGoal, map[string]interface{}{"user_id": userID.Hex()}); err != nil {
+		s.logger.Warn("failed to schedule watch goal check", "error", err)
+	}
+
+	return s.watchlistRepo.FindEntry(userID, movieID)
+}
+
+// MarkUnwatched clears the watched state of a watchlist entry.
+// expectedVersion works as in SetPriority.
+func (s *WatchlistService) MarkUnwatched(userID primitive.ObjectID, movieID primitive.ObjectID, expectedVersion *int) (*models.Watchlist, error) {
+	found, err := s.watchlistRepo.SetWatched(userID, movieID, nil, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, s.updateMissError(userID, movieID)
+	}
+	s.bus.Publish(events.WatchlistItemUpdated{UserID: userID, MovieID: movieID, Change: events.ChangeUnwatched, At: time.Now().UTC()})
+
+	return s.watchlistRepo.FindEntry(userID, movieID)
+}
+
+// SetPriority changes the priority of a watchlist entry. When expectedVersion
+// is set, the entry must still be at that version or ErrVersionConflict is returned.
+func (s *WatchlistService) SetPriority(userID primitive.ObjectID, movieID primitive.ObjectID, priority int, expectedVersion *int) (*models.Watchlist, error) {
+	if priority < models.MinWatchlistPriority || priority > models.MaxWatchlistPriority {
+		return nil, errors.New("invalid priority")
+	}
+
+	found, err := s.watchlistRepo.SetPriority(userID, movieID, priority, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, s.updateMissError(userID, movieID)
+	}
+	s.bus.Publish(events.WatchlistItemUpdated{UserID: userID, MovieID: movieID, Change: events.ChangePriority, Priority: priority, At: time.Now().UTC()})
+
+	return s.watchlistRepo.FindEntry(userID, movieID)
+}
+
+// updateMissError explains why a conditional update matched no entry: either
+// the movie is not on the watchlist or the entry is at another version
+func (s *WatchlistService) updateMissError(userID primitive.ObjectID, movieID primitive.ObjectID) error {
+	exists, err := s.watchlistRepo.Exists(userID, movieID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return errors.New("movie not in watchlist")
+}
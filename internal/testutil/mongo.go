@@ -0,0 +1,106 @@
+//go:build integration
+
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"movie-watchlist/internal/database"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mongoImage is the image started when TEST_MONGODB_URI is not set
+const mongoImage = "mongo:7"
+
+var (
+	mongoOnce      sync.Once
+	mongoURI       string
+	mongoErr       error
+	mongoContainer *mongodb.MongoDBContainer
+)
+
+// Main runs a package's tests and then stops the mongo container, if one was
+// started. Packages using Database call it from TestMain:
+//
+//	func TestMain(m *testing.M) { testutil.Main(m) }
+func Main(m *testing.M) {
+	code := m.Run()
+	stopMongoContainer()
+	os.Exit(code)
+}
+
+// Database returns a database of its own for the test, with the server's
+// indexes, dropped when the test ends. The test fails when no MongoDB is
+// available.
+func Database(t testing.TB) *database.MongoDB {
+	t.Helper()
+
+	uri, err := mongoServer()
+	if err != nil {
+		t.Fatalf("MongoDB not available: %v", err)
+	}
+
+	db, err := database.ConnectTo(uri, "movie_watchlist_test_"+primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Fatalf("connect to MongoDB: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Database.Drop(ctx); err != nil {
+			t.Logf("drop test database: %v", err)
+		}
+		db.Close()
+	})
+	return db
+}
+
+// mongoServer returns the URI of the MongoDB the tests use, starting a
+// container on first use when TEST_MONGODB_URI is not set
+func mongoServer() (string, error) {
+	mongoOnce.Do(func() {
+		if uri := os.Getenv("TEST_MONGODB_URI"); uri != "" {
+			mongoURI = uri
+			return
+		}
+		mongoURI, mongoErr = startMongoContainer()
+	})
+	return mongoURI, mongoErr
+}
+
+// startMongoContainer runs mongoImage with testcontainers, which waits until
+// the server accepts connections
+func startMongoContainer() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	container, err := mongodb.RunContainer(ctx, testcontainers.WithImage(mongoImage))
+	if err != nil {
+		return "", fmt.Errorf("start %s container (set TEST_MONGODB_URI to use a running MongoDB): %w", mongoImage, err)
+	}
+	mongoContainer = container
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		stopMongoContainer()
+		return "", fmt.Errorf("read mongo container address: %w", err)
+	}
+	return uri, nil
+}
+
+func stopMongoContainer() {
+	if mongoContainer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_ = mongoContainer.Terminate(ctx)
+	mongoContainer = nil
+}
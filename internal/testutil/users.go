@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/middleware"
+	"movie-watchlist/internal/models"
+	"movie-watchlist/internal/repositories"
+	"movie-watchlist/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Password is the password of the users CreateUser registers
+const Password = "correct-horse-battery-staple"
+
+// Tokens signs and checks the access tokens in tests
+var Tokens = middleware.TokenConfig{
+	Secret:   "test-secret",
+	TTL:      time.Hour,
+	Issuer:   "movie-watchlist-api",
+	Audience: "movie-watchlist-api",
+}
+
+// CreateUser registers a user with Password and the email
+// <username>@example.com
+func CreateUser(t testing.TB, db *database.MongoDB, username string) *models.User {
+	t.Helper()
+
+	userService := services.NewUserService(repositories.NewUserRepository(db), &services.PasswordPolicy{MinLength: 8}, events.NewBus())
+	user, err := userService.Register(username, username+"@example.com", Password, "")
+	if err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+	return user
+}
+
+// Token returns an access token for the user, signed with Tokens
+func Token(t testing.TB, userID primitive.ObjectID) string {
+	t.Helper()
+
+	token, err := middleware.GenerateToken(userID, Tokens)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// Do sends a request to handler and returns the recorded response. A
+// non-nil body is sent as JSON, and a non-empty token as a bearer token.
+func Do(t testing.TB, handler http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// Decode decodes a JSON response into v, failing the test when the response
+// does not have the wanted status
+func Decode(t testing.TB, recorder *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+
+	if recorder.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", recorder.Code, status, recorder.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response: %v; body: %s", err, recorder.Body.String())
+	}
+}
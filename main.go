@@ -1,16 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"io/fs"
 	"log"
+	"movie-watchlist/internal/authz"
 	"movie-watchlist/internal/config"
 	"movie-watchlist/internal/database"
+	"movie-watchlist/internal/errorreport"
+	"movie-watchlist/internal/events"
+	"movie-watchlist/internal/fieldcrypt"
 	"movie-watchlist/internal/handlers"
+	"movie-watchlist/internal/jobs"
+	"movie-watchlist/internal/logging"
+	"movie-watchlist/internal/mailer"
 	"movie-watchlist/internal/middleware"
 	"movie-watchlist/internal/repositories"
 	"movie-watchlist/internal/services"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	// User timezones must resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func main() {
@@ -19,62 +39,902 @@ func main() {
 		log.Println("Warning: Could not load .env file:", err)
 	}
 
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := logging.Setup(logging.Options{
+		Level:            cfg.LogLevel,
+		Format:           cfg.LogFormat,
+		ModuleLevels:     cfg.LogModuleLevels,
+		SampleInitial:    cfg.LogSampleInitial,
+		SampleThereafter: cfg.LogSampleThereafter,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	gin.SetMode(cfg.GinMode)
+	logger := logging.For("main")
 
-	// Validate required configuration
-	if cfg.OMDbAPIKey == "" {
-		log.Fatal("OMDb API key not configured. Please set OMDB_API_KEY in .env file or environment variables")
+	logger.Info("configuration loaded",
+		"environment", cfg.Environment,
+		"database", cfg.RedactedDatabaseURL(),
+		"omdb_api_key_configured", cfg.OMDbAPIKey != "")
+	if cfg.JWTSecret == config.DefaultJWTSecret {
+		logger.Warn("using the default JWT secret; this is only allowed in dev")
 	}
 
-	log.Println("Configuration loaded successfully")
-	log.Printf("Database URL: %s", cfg.DatabaseURL)
-	log.Println("OMDb API key: configured")
+	// Keys were checked by config validation
+	encryptionKeys, err := fieldcrypt.ParseKeys(cfg.FieldEncryptionKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
+	// Demo sandboxes are seeded from the seed catalogue
+	if *cfg.SeedData || cfg.DemoMode {
+		if err := db.SeedMovies(); err != nil {
+			logger.Warn("failed to seed data", "error", err)
+		}
+	}
+
+	if backfilled, err := db.BackfillMovieNumericFields(); err != nil {
+		logger.Warn("failed to backfill movie years and scores", "error", err)
+	} else if backfilled > 0 {
+		logger.Info("backfilled movie years and scores", "movies", backfilled)
+	}
+	if normalized, err := db.NormalizeMovieIMDbIDs(); err != nil {
+		logger.Warn("failed to normalize movie IMDb IDs", "error", err)
+	} else if normalized > 0 {
+		logger.Info("normalized movie IMDb IDs", "movies", normalized)
+	}
+
 	userRepo := repositories.NewUserRepository(db)
-	movieRepo := repositories.NewMovieRepository(db, cfg.OMDbAPIKey)
+	movieRepo := repositories.NewMovieRepository(db)
 	watchlistRepo := repositories.NewWatchlistRepository(db)
 	ratingRepo := repositories.NewRatingRepository(db)
+	omdbUsageRepo := repositories.NewOMDbUsageRepository(db)
+	jobRepo := repositories.NewJobRepository(db)
+	statsRepo := repositories.NewStatsRepository(db)
+	activityRepo := repositories.NewActivityRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	emailChangeRepo := repositories.NewEmailChangeRepository(db)
+	devicePairingRepo := repositories.NewDevicePairingRepository(db)
+	oauthAppRepo := repositories.NewOAuthAppRepository(db)
+	oauthCodeRepo := repositories.NewOAuthCodeRepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(db)
+	encryptedFieldRepo := repositories.NewEncryptedFieldRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	progressRepo := repositories.NewProgressRepository(db)
+	recentViewRepo := repositories.NewRecentViewRepository(db)
+	profileRepo := repositories.NewProfileRepository(db)
+	calendarRepo := repositories.NewCalendarRepository(db)
+	deprecationRepo := repositories.NewDeprecationRepository(db)
+	deletedItemRepo := repositories.NewDeletedItemRepository(db)
+	evaluationRepo := repositories.NewEvaluationRepository(db)
+	embeddingRepo := repositories.NewEmbeddingRepository(db)
+	snapshotRepo := repositories.NewRecommendationSnapshotRepository(db)
+	demoRepo := repositories.NewDemoRepository(db)
+	accountRepo := repositories.NewAccountRepository(db)
+	achievementRepo := repositories.NewAchievementRepository(db)
+	conflictRepo := repositories.NewMetadataConflictRepository(db)
+	revisionRepo := repositories.NewMovieRevisionRepository(db)
+	impressionRepo := repositories.NewRecommendationImpressionRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	dashboardRepo := repositories.NewDashboardRepository(db)
+	exportRepo := repositories.NewExportRepository(db)
+	flagRepo := repositories.NewAccountFlagRepository(db)
+	followRepo := repositories.NewPersonFollowRepository(db)
+	movieShareRepo := repositories.NewMovieShareRepository(db)
+	reviewRepo := repositories.NewReviewRepository(db)
+	pollRepo := repositories.NewPollRepository(db)
+	advisoryReportRepo := repositories.NewAdvisoryReportRepository(db)
+	traktLinkRepo := repositories.NewTraktLinkRepository(db)
+	chatWebhookRepo := repositories.NewChatWebhookRepository(db)
+	slackLinkRepo := repositories.NewSlackLinkRepository(db)
+	telegramLinkRepo := repositories.NewTelegramLinkRepository(db)
+
+	var mail mailer.Mailer = mailer.LogMailer{}
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		logger.Info("SMTP_HOST not set; outgoing emails will be logged instead of sent")
+	}
+
+	jobQueue := jobs.NewQueue(jobRepo, cfg.JobWorkers)
+	eventBus := events.NewBus()
+
+	passwordPolicy := &services.PasswordPolicy{MinLength: cfg.PasswordMinLength}
+	if cfg.PasswordBreachCheck {
+		passwordPolicy.Checker = services.NewHIBPChecker()
+	}
+
+	captchaPolicy := &services.CaptchaPolicy{
+		OnRegister:         cfg.CaptchaOnRegister,
+		LoginAfterFailures: cfg.CaptchaLoginAfterFailures,
+		LoginFailureWindow: 15 * time.Minute,
+	}
+	if cfg.CaptchaProvider != "" {
+		captchaPolicy.Verifier = services.NewSiteverifyCaptcha(cfg.CaptchaProvider, cfg.CaptchaSecret)
+	}
+
+	var embedder services.Embedder = services.NewHashingEmbedder(services.DefaultHashingDimensions)
+	if cfg.EmbeddingProvider == "api" {
+		embedder = services.NewAPIEmbedder(cfg.EmbeddingAPIURL, cfg.EmbeddingAPIKey, cfg.EmbeddingModel)
+	}
+
+	var queryParser services.QueryParser = services.NewRuleQueryParser()
+	if cfg.DiscoveryParser == "llm" {
+		queryParser = services.NewLLMQueryParser(cfg.DiscoveryLLMURL, cfg.DiscoveryLLMKey, cfg.DiscoveryLLMModel, queryParser)
+	}
 
-	userService := services.NewUserService(userRepo)
-	movieService := services.NewMovieService(movieRepo, cfg.OMDbAPIKey)
-	watchlistService := services.NewWatchlistService(watchlistRepo)
-	ratingService := services.NewRatingService(ratingRepo)
+	userService := services.NewUserService(userRepo, passwordPolicy, eventBus)
+	sessionService := services.NewSessionService(sessionRepo)
+	emailChangeService := services.NewEmailChangeService(userRepo, emailChangeRepo, mail, cfg.PublicBaseURL)
+	devicePairingService := services.NewDevicePairingService(devicePairingRepo, cfg.DevicePairingURL)
+	oauthAppService := services.NewOAuthAppService(oauthAppRepo, oauthCodeRepo, sessionService, cfg.OAuthConsentURL)
+	encryptionService := services.NewEncryptionService(fieldcrypt.NewCipher(encryptionKeys), encryptedFieldRepo, jobQueue)
+	loginSecurityService := services.NewLoginSecurityService(loginAttemptRepo, userRepo, notificationRepo, sessionService, mail, cfg.PublicBaseURL)
+	omdbUsageService := services.NewOMDbUsageService(omdbUsageRepo, cfg.OMDbDailyLimit)
+	// TMDb joins the metadata failover chain once it has a key
+	var metadataProviders []services.MetadataProvider
+	if cfg.TMDbAPIKey != "" {
+		metadataProviders = append(metadataProviders, services.NewTMDbMetadataProvider(cfg.TMDbAPIKey))
+	}
+	movieHistoryService := services.NewMovieHistoryService(revisionRepo)
+	var omdbTransport http.RoundTripper
+	omdbAPIKey := cfg.OMDbAPIKey
+	if cfg.OMDbFixtures != "" {
+		omdbTransport = services.NewOMDbFixtureTransport(cfg.OMDbFixtures, cfg.OMDbFixturesDir)
+		// Replayed fixtures are found without a key, but the client only
+		// calls OMDb when it has one
+		if cfg.OMDbFixtures == services.OMDbFixturesReplay && omdbAPIKey == "" {
+			omdbAPIKey = "replay"
+		}
+		logger.Info("OMDb fixtures enabled", "mode", cfg.OMDbFixtures, "dir", cfg.OMDbFixturesDir)
+	}
+	movieService := services.NewMovieService(movieRepo, omdbUsageService, movieHistoryService, jobQueue, omdbAPIKey, cfg.OMDbBaseURL, omdbTransport, cfg.MetadataProviders, metadataProviders...)
+	undoService := services.NewUndoService(deletedItemRepo, watchlistRepo)
+	recommendationAnalyticsService := services.NewRecommendationAnalyticsService(impressionRepo)
+	storageQuotaService := services.NewStorageQuotaService(userRepo, watchlistRepo, services.StorageLimits{MaxWatchlistEntries: cfg.MaxWatchlistEntries})
+	watchlistService := services.NewWatchlistService(watchlistRepo, movieRepo, userRepo, undoService, storageQuotaService, eventBus, jobQueue, time.Duration(cfg.RatingReminderDays)*24*time.Hour)
+	ratingService := services.NewRatingService(ratingRepo, movieRepo, eventBus)
+	reviewService := services.NewReviewService(reviewRepo, userRepo, movieRepo)
+	ratingImportService := services.NewRatingImportService(ratingRepo, movieRepo, movieService)
 	recommendationService := services.NewRecommendationService(movieRepo, ratingRepo, watchlistRepo)
+	followService := services.NewFollowService(followRepo, movieRepo)
+	compatibilityService := services.NewCompatibilityService(userRepo, ratingRepo)
+	ratingFeedService := services.NewRatingFeedService(userRepo, ratingRepo, movieRepo, cfg.PublicBaseURL)
+	shareService := services.NewShareService(movieShareRepo, userRepo, movieRepo, notificationRepo)
+	pollService := services.NewPollService(pollRepo, userRepo, movieRepo, notificationRepo, eventBus)
+	calendarFeedService := services.NewCalendarFeedService(userRepo, pollRepo, movieRepo, watchlistRepo, calendarRepo, cfg.PublicBaseURL)
+	recommendationScheduler := services.NewRecommendationScheduler(userRepo, snapshotRepo, movieRepo, recommendationService, followService, mail, eventBus, jobQueue)
+	archiveImportService := services.NewArchiveImportService(userRepo, ratingRepo, watchlistRepo, reviewRepo, movieRepo, movieService, storageQuotaService, recommendationScheduler)
+	// Trakt sync stays off until a Trakt app is configured
+	var traktClient *services.TraktClient
+	if cfg.TraktEnabled() {
+		traktClient = services.NewTraktClient(cfg.TraktBaseURL, cfg.TraktClientID, cfg.TraktClientSecret, cfg.PublicBaseURL+"/integrations/trakt/callback")
+	}
+	traktService := services.NewTraktService(traktClient, traktLinkRepo, movieRepo, ratingRepo, watchlistRepo, archiveImportService, encryptionService, jobQueue)
+	exportService := services.NewExportService(exportRepo, userRepo)
+	activityService := services.NewActivityService(activityRepo)
+	progressService := services.NewProgressService(progressRepo, movieRepo, watchlistService)
+	recentViewService := services.NewRecentViewService(recentViewRepo, movieRepo)
+	libraryService := services.NewLibraryService(movieRepo, watchlistRepo, ratingRepo)
+	evaluationService := services.NewEvaluationService(ratingRepo, movieRepo, evaluationRepo, jobQueue)
+	discoveryService := services.NewDiscoveryService(queryParser, movieRepo)
+	keywordService := services.NewKeywordService(services.NewPlotKeywordExtractor(), movieRepo, movieHistoryService, jobQueue)
+	advisoryService := services.NewAdvisoryService(services.NewPlotAdvisoryProvider(), movieRepo, advisoryReportRepo, userRepo, movieHistoryService, jobQueue)
+	quickAddService := services.NewQuickAddService(movieService)
+	assistantService := services.NewAssistantService(quickAddService, watchlistService, ratingService, movieRepo, dashboardRepo)
+	chatWebhookService := services.NewChatWebhookService(chatWebhookRepo, pollRepo, movieRepo, encryptionService, jobQueue)
+	slackCommandService := services.NewSlackCommandService(cfg.SlackSigningSecret, slackLinkRepo, userRepo, movieService, quickAddService, watchlistService)
+	var telegramClient *services.TelegramClient
+	if cfg.TelegramBotToken != "" {
+		telegramClient = services.NewTelegramClient(cfg.TelegramBaseURL, cfg.TelegramBotToken)
+	}
+	telegramBotService := services.NewTelegramBotService(telegramClient, telegramLinkRepo, userRepo, notificationRepo, movieService, quickAddService, watchlistService, cfg.TelegramWebhookURL(), cfg.TelegramWebhookSecret)
+	var inboundMailProvider services.InboundMailProvider
+	if cfg.InboundEmailProvider != "" {
+		provider, err := services.NewInboundMailProvider(cfg.InboundEmailProvider, cfg.InboundEmailSecret)
+		if err != nil {
+			logger.Error("failed to configure inbound email", "error", err)
+			os.Exit(1)
+		}
+		inboundMailProvider = provider
+	}
+	inboundEmailService := services.NewInboundEmailService(inboundMailProvider, cfg.InboundEmailDomain, userRepo, notificationRepo, quickAddService, watchlistService)
+	semanticService := services.NewSemanticService(embedder, embeddingRepo, movieRepo, jobQueue, cfg.EmbeddingVectorIndex)
+	profileService := services.NewProfileService(profileRepo)
+	calendarService := services.NewCalendarService(calendarRepo, ratingRepo, userRepo, notificationRepo, followService, movieService, services.NewOMDbReleaseProvider(movieService), jobQueue)
+	notificationService := services.NewNotificationService(notificationRepo, userRepo, watchlistRepo, ratingRepo, movieRepo, mail, cfg.RatingReminderEmail)
+	habitService := services.NewHabitService(userRepo, watchlistRepo, notificationRepo)
+	achievementService := services.NewAchievementService(achievementRepo, userRepo, ratingRepo, watchlistRepo, movieRepo, activityRepo, notificationRepo, jobQueue)
+	demoService := services.NewDemoService(userRepo, ratingRepo, watchlistRepo, movieRepo, demoRepo, sessionService, jobQueue, time.Duration(cfg.DemoSessionMinutes)*time.Minute)
+	termsService := services.NewTermsService(userRepo, cfg.TermsVersion)
+	deactivationService := services.NewAccountDeactivationService(userRepo, accountRepo, sessionService, jobQueue)
+	reconciliationService := services.NewReconciliationService(movieService, movieRepo, conflictRepo, jobQueue)
+	deprecationService := services.NewDeprecationService(deprecationRepo)
+	posterService := services.NewPosterService(movieService, movieRepo, jobQueue, cfg.PublicBaseURL)
+	cacheEvictionService := services.NewCacheEvictionService(movieRepo, jobQueue, time.Duration(cfg.MovieCacheMaxAgeDays)*24*time.Hour)
+	anomalyService := services.NewAnomalyService(flagRepo, cfg.AnomalyRatingBurst, cfg.AnomalyWatchlistChurn, time.Duration(cfg.AnomalyThrottleMinutes)*time.Minute)
+	dashboardService := services.NewDashboardService(dashboardRepo, movieRepo, recommendationService, recommendationScheduler)
+	statsService := services.NewStatsService(statsRepo, omdbUsageRepo, activityService, recommendationService, watchlistService)
 
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
-	movieHandler := handlers.NewMovieHandler(movieService)
-	watchlistHandler := handlers.NewWatchlistHandler(watchlistService)
-	ratingHandler := handlers.NewRatingHandler(ratingService)
-	recommendationHandler := handlers.NewRecommendationHandler(recommendationService)
+	// Side effects of domain events
+	recommendationService.Subscribe(eventBus)
+	notificationService.Subscribe(eventBus)
+	recommendationAnalyticsService.Subscribe(eventBus)
+	recommendationScheduler.Subscribe(eventBus)
+	dashboardService.Subscribe(eventBus)
+	anomalyService.Subscribe(eventBus)
+	if traktService.Enabled() {
+		traktService.Subscribe(eventBus)
+	}
+	if cfg.ChatWebhooks {
+		chatWebhookService.Subscribe(eventBus)
+	}
+	if err := anomalyService.LoadThrottles(); err != nil {
+		logger.Warn("failed to load account throttles", "error", err)
+	}
+	if cfg.EventStream != "" {
+		var publisher services.EventPublisher
+		var err error
+		if cfg.EventStream == "kafka" {
+			publisher, err = services.NewKafkaRESTPublisher(cfg.EventStreamURL)
+		} else {
+			publisher, err = services.NewNATSPublisher(cfg.EventStreamURL)
+		}
+		if err != nil {
+			logger.Error("failed to configure event streaming", "error", err)
+			os.Exit(1)
+		}
+		eventStreamService := services.NewEventStreamService(outboxRepo, publisher, cfg.EventStreamPrefix)
+		eventStreamService.Subscribe(eventBus)
+		eventStreamService.Start(context.Background())
+		logger.Info("streaming domain events", "broker", cfg.EventStream, "prefix", cfg.EventStreamPrefix)
+	}
 
-	r := gin.Default()
+	jobQueue.Register(jobs.TypeRefreshMovieMetadata, movieService.RefreshMetadataJob, jobs.DefaultRetryPolicy)
+	jobQueue.Register(jobs.TypeRatingReminder, notificationService.RatingReminderJob, jobs.DefaultRetryPolicy)
+	jobQueue.Register(jobs.TypeUpcomingReleases, calendarService.UpcomingReleasesJob, jobs.DefaultRetryPolicy)
+	if err := calendarService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule upcoming releases job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeEmbedPlots, semanticService.EmbedPlotsJob, jobs.DefaultRetryPolicy)
+	if err := semanticService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule plot embedding job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeTagKeywords, keywordService.TagKeywordsJob, jobs.DefaultRetryPolicy)
+	if err := keywordService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule keyword tagging job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeTagAdvisories, advisoryService.TagAdvisoriesJob, jobs.DefaultRetryPolicy)
+	if err := advisoryService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule advisory tagging job", "error", err)
+	}
+	jobQueue.Register(jobs.TypePrecomputeRecs, recommendationScheduler.PrecomputeJob, jobs.DefaultRetryPolicy)
+	if err := recommendationScheduler.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule recommendation precompute job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeCheckWatchGoal, habitService.CheckGoalJob, jobs.DefaultRetryPolicy)
+	jobQueue.Register(jobs.TypeAwardAchievements, achievementService.AwardJob, jobs.DefaultRetryPolicy)
+	if err := achievementService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule achievements job", "error", err)
+	}
+	// Cleanup also runs with demo mode off so sandboxes left over from
+	// before it was turned off are still deleted
+	jobQueue.Register(jobs.TypeDemoCleanup, demoService.CleanupJob, jobs.DefaultRetryPolicy)
+	if err := demoService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule demo cleanup job", "error", err)
+	}
+	jobQueue.Register(jobs.TypePurgeDeactivated, deactivationService.PurgeJob, jobs.DefaultRetryPolicy)
+	if err := deactivationService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule deactivated account purge job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeReconcileMetadata, reconciliationService.ReconcileJob, jobs.DefaultRetryPolicy)
+	if err := reconciliationService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule metadata reconciliation job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeCheckPosters, posterService.CheckPostersJob, jobs.DefaultRetryPolicy)
+	if err := posterService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule poster check job", "error", err)
+	}
+	// Registered with eviction off too, so a run scheduled before it was
+	// turned off ends the cycle
+	jobQueue.Register(jobs.TypeEvictMovies, cacheEvictionService.EvictJob, jobs.DefaultRetryPolicy)
+	if err := cacheEvictionService.EnsureScheduled(); err != nil {
+		logger.Warn("failed to schedule movie cache eviction job", "error", err)
+	}
+	jobQueue.Register(jobs.TypeEvaluateRecommender, evaluationService.EvaluationJob, services.EvaluationRetryPolicy)
+	jobQueue.Register(jobs.TypeRotateEncryptionKeys, encryptionService.RotateJob, jobs.DefaultRetryPolicy)
+	// Registered with Trakt sync off too, so jobs queued before it was
+	// turned off finish as no-ops
+	jobQueue.Register(jobs.TypeTraktSync, traktService.SyncJob, services.TraktSyncRetryPolicy)
+	jobQueue.Register(jobs.TypeTraktPush, traktService.PushJob, jobs.DefaultRetryPolicy)
+	jobQueue.Register(jobs.TypeChatPost, chatWebhookService.PostJob, jobs.DefaultRetryPolicy)
+	jobQueue.Register(jobs.TypeChatPollResult, chatWebhookService.PollResultJob, jobs.DefaultRetryPolicy)
+	jobQueue.Start(context.Background())
+	if telegramBotService.Enabled() {
+		telegramBotService.Start(context.Background())
+		logger.Info("started Telegram bot", "mode", cfg.TelegramMode)
+	}
 
-	r.POST("/register", authHandler.Register)
-	r.POST("/login", authHandler.Login)
+	tokens := middleware.TokenConfig{
+		Secret:   cfg.JWTSecret,
+		TTL:      time.Duration(cfg.JWTAccessTTLMinutes) * time.Minute,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+	}
+
+	// OpenID Connect provider mode for companion apps signs ID tokens with
+	// its own RSA key
+	var oidcKey *rsa.PrivateKey
+	if len(cfg.OIDCClientIDs) > 0 {
+		if cfg.OIDCSigningKeyFile == "" {
+			logger.Warn("OIDC_SIGNING_KEY_FILE is not set, signing ID tokens with a throwaway key")
+		}
+		oidcKey, err = services.LoadOIDCSigningKey(cfg.OIDCSigningKeyFile)
+		if err != nil {
+			logger.Error("failed to load OIDC signing key", "error", err)
+			os.Exit(1)
+		}
+	}
+	oidcProvider := services.NewOIDCProvider(cfg.PublicBaseURL, cfg.OIDCClientIDs, oidcKey)
+
+	// Ownership and admin checks used by the handlers
+	policy := authz.NewPolicy(cfg.AdminUserIDs)
+
+	authHandler := handlers.NewAuthHandler(userService, sessionService, loginSecurityService, tokens, cfg.GeoCountryHeader, captchaPolicy, termsService)
+	movieHandler := handlers.NewMovieHandler(movieService, recentViewService, semanticService, advisoryService)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistService, movieService, policy)
+	quickAddHandler := handlers.NewQuickAddHandler(quickAddService, watchlistService, movieService)
+	assistantHandler := handlers.NewAssistantHandler(assistantService)
+	ratingHandler := handlers.NewRatingHandler(ratingService, movieService, ratingImportService, policy)
+	reviewHandler := handlers.NewReviewHandler(reviewService, movieService, policy)
+	sessionHandler := handlers.NewSessionHandler(sessionService, policy)
+	securityHandler := handlers.NewSecurityHandler(loginSecurityService)
+	devicePairingHandler := handlers.NewDevicePairingHandler(devicePairingService, userService, authHandler)
+	oidcHandler := handlers.NewOIDCHandler(oidcProvider, oauthAppService, authHandler, userService, sessionService, tokens)
+	oauthAppHandler := handlers.NewOAuthAppHandler(oauthAppService)
+	termsHandler := handlers.NewTermsHandler(termsService)
+	accountHandler := handlers.NewAccountHandler(emailChangeService, userService, deactivationService)
+	archiveHandler := handlers.NewArchiveHandler(archiveImportService)
+	traktHandler := handlers.NewTraktHandler(traktService)
+	chatWebhookHandler := handlers.NewChatWebhookHandler(chatWebhookService)
+	slackHandler := handlers.NewSlackHandler(slackCommandService)
+	telegramHandler := handlers.NewTelegramHandler(telegramBotService)
+	inboundEmailHandler := handlers.NewInboundEmailHandler(inboundEmailService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	habitHandler := handlers.NewHabitHandler(habitService)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	achievementHandler := handlers.NewAchievementHandler(achievementService)
+	profileHandler := handlers.NewProfileHandler(profileService, policy)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	undoHandler := handlers.NewUndoHandler(undoService)
+	libraryHandler := handlers.NewLibraryHandler(libraryService)
+	discoveryHandler := handlers.NewDiscoveryHandler(discoveryService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, policy)
+	progressHandler := handlers.NewProgressHandler(progressService, movieService)
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, userService, recommendationScheduler, recommendationAnalyticsService, followService, advisoryService)
+	followHandler := handlers.NewFollowHandler(followService)
+	compatibilityHandler := handlers.NewCompatibilityHandler(compatibilityService)
+	ratingFeedHandler := handlers.NewRatingFeedHandler(ratingFeedService)
+	shareHandler := handlers.NewShareHandler(shareService)
+	pollHandler := handlers.NewPollHandler(pollService)
+	calendarFeedHandler := handlers.NewCalendarFeedHandler(calendarFeedService, policy)
+	deprecationHandler := handlers.NewDeprecationHandler(deprecationService)
+	adminHandler := handlers.NewAdminHandler(omdbUsageService, statsService, userService, evaluationService, encryptionService, reconciliationService, movieHistoryService, recommendationAnalyticsService, recommendationService, recommendationScheduler, jobQueue)
+	demoHandler := handlers.NewDemoHandler(demoService, tokens)
+	v2Handler := handlers.NewV2Handler(movieService, watchlistService, ratingService, recommendationService, recentViewService, userService, recommendationScheduler, recommendationAnalyticsService, advisoryService, undoService, progressService, policy)
+
+	// Search has its own allowance because each search can spend OMDb quota
+	requestLimiter := middleware.NewRateLimiter(cfg.RateLimitPerMinute, time.Minute)
+	searchLimiter := middleware.NewRateLimiter(cfg.SearchRateLimitPerHour, time.Hour)
+	// Demo sandbox users get a lower allowance on top of the regular one, and
+	// each IP may start only a few sandboxes per hour
+	demoLimiter := middleware.NewRateLimiter(cfg.DemoRateLimitPerMinute, time.Minute)
+	demoSessionLimiter := middleware.NewRateLimiter(cfg.DemoSessionsPerHour, time.Hour)
+	// Each pairing is stored until it expires, so an IP may start only a few
+	devicePairingLimiter := middleware.NewRateLimiter(cfg.DevicePairingsPerHour, time.Hour)
+	// Accounts the anomaly detector throttled get a much lower allowance for
+	// writes until the throttle runs out or an admin dismisses their flag
+	throttleLimiter := middleware.NewRateLimiter(cfg.AnomalyThrottlePerMinute, time.Minute)
+	throttled := middleware.ThrottleMiddleware(anomalyService.IsThrottled, throttleLimiter)
+	anomalyHandler := handlers.NewAnomalyHandler(anomalyService)
+	advisoryHandler := handlers.NewAdvisoryHandler(advisoryService)
+	quotaHandler := handlers.NewQuotaHandler(requestLimiter, searchLimiter, omdbUsageService, storageQuotaService)
+
+	var reporter errorreport.Reporter = errorreport.NewLogReporter()
+	if cfg.ErrorReportingDSN != "" {
+		sentry, err := errorreport.NewSentryReporter(cfg.ErrorReportingDSN, cfg.Environment)
+		if err != nil {
+			logger.Error("invalid error reporting DSN", "error", err)
+			os.Exit(1)
+		}
+		reporter = sentry
+	}
+
+	r := gin.New()
+	// Client IPs come from forwarding headers only when a trusted proxy or
+	// the hosting platform set them
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Error("invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
+	r.RemoteIPHeaders = cfg.RealIPHeaders
+	r.TrustedPlatform = cfg.TrustedPlatformHeader()
+	r.Use(gin.Logger())
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.RecoveryMiddleware(reporter))
+	r.Use(middleware.CORSMiddleware(cfg.CORSAllowedOrigins))
+	r.Use(middleware.BodyLimitMiddleware(cfg.MaxBodyBytes))
+	r.Use(middleware.DeprecationMiddleware(deprecationService.Lookup))
+
+	// Credential and account endpoints reject unknown JSON fields
+	strictJSON := middleware.StrictJSONMiddleware()
+	// Account settings stay with the parent while a kids profile is selected
+	accountOnly := middleware.AccountOnlyMiddleware()
+	// Demo sandbox users cannot send email or run bulk imports
+	notInDemo := middleware.DemoForbiddenMiddleware()
+	// Writes wait until a changed terms version is accepted; accepting it
+	// and leaving stay possible
+	termsAccepted := middleware.TermsMiddleware(termsService.Version(), termsService.HasAccepted, "/api/v1/me/accept-terms", "/api/v1/me/deactivate")
+
+	r.POST("/register", strictJSON, authHandler.Register)
+	r.POST("/login", strictJSON, authHandler.Login)
+	r.POST("/refresh", strictJSON, authHandler.Refresh)
+	r.POST("/auth/device/start", middleware.RateLimitMiddleware(devicePairingLimiter), devicePairingHandler.StartDevicePairing)
+	r.POST("/auth/device/token", strictJSON, devicePairingHandler.PollDevicePairing)
+	r.GET("/email/confirm", accountHandler.ConfirmEmailChange)
+	r.GET("/security/revoke", securityHandler.RevokeSessions)
+	r.GET("/calendar/:token", calendarFeedHandler.GetCalendarFeed)
+	if traktService.Enabled() {
+		r.GET("/integrations/trakt/callback", traktHandler.TraktCallback)
+	}
+	if slackCommandService.Enabled() {
+		r.POST("/integrations/slack/commands", slackHandler.SlackCommand)
+	}
+	if telegramBotService.Enabled() && telegramBotService.UsesWebhook() {
+		r.POST("/integrations/telegram/webhook", telegramHandler.TelegramWebhook)
+	}
+	if inboundEmailService.Enabled() {
+		r.POST("/integrations/inbound-email", inboundEmailHandler.ReceiveInboundEmail)
+	}
+	if cfg.DemoMode {
+		r.POST("/demo/session", middleware.RateLimitMiddleware(demoSessionLimiter), demoHandler.StartSession)
+	}
+	// Registered third-party apps use the token endpoint without OIDC
+	r.POST("/oauth/token", oidcHandler.Token)
+	if oauthAppService.ConsentURL() != "" {
+		r.GET("/oauth/authorize", oauthAppHandler.RedirectToConsent)
+	}
+	if oidcProvider.Enabled() {
+		r.GET("/.well-known/openid-configuration", oidcHandler.GetConfiguration)
+		r.GET("/oauth/jwks", oidcHandler.GetKeys)
+		userinfo := r.Group("/oauth/userinfo", middleware.AuthMiddleware(tokens), middleware.SessionMiddleware(sessionService.IsActive))
+		userinfo.GET("", oidcHandler.GetUserInfo)
+		userinfo.POST("", oidcHandler.GetUserInfo)
+	}
+
+	// Read-only browsing is open to guests; a valid token still identifies the user
+	public := r.Group("/api/v1")
+	public.Use(middleware.OptionalAuthMiddleware(tokens))
+	public.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	public.Use(middleware.AppMiddleware(oauthAppService.IsActive))
+	public.Use(middleware.ActivityMiddleware(activityService.RecordActivity))
+	public.Use(middleware.RateLimitMiddleware(requestLimiter))
+	public.Use(middleware.DemoRateLimitMiddleware(demoLimiter))
+	public.Use(middleware.ProfileMiddleware(profileService.GetProfile))
+	{
+		public.GET("/movies/search", middleware.RateLimitMiddleware(searchLimiter), movieHandler.SearchMovies)
+		public.GET("/movies/suggest", movieHandler.SuggestMovies)
+		public.GET("/movies/browse", movieHandler.BrowseMovies)
+		public.GET("/movies/trending", recommendationHandler.GetTrendingMovies)
+		public.GET("/movies/semantic-search", middleware.RateLimitMiddleware(searchLimiter), movieHandler.SemanticSearch)
+		public.GET("/movies/:id", movieHandler.GetMovie)
+		public.GET("/movies/:id/similar", movieHandler.GetSimilarMovies)
+		public.GET("/movies/:id/poster-placeholder.svg", movieHandler.GetPosterPlaceholder)
+		public.GET("/movies/:id/reviews", reviewHandler.GetMovieReviews)
+		public.GET("/reviewers/top", reviewHandler.GetTopReviewers)
+		public.GET("/users/:username/achievements", achievementHandler.GetPublicAchievements)
+		public.GET("/users/:username/ratings.rss", ratingFeedHandler.GetRatingFeed)
+	}
 
 	api := r.Group("/api/v1")
-	api.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	api.Use(middleware.AuthMiddleware(tokens))
+	api.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	api.Use(middleware.AppScopeMiddleware())
+	api.Use(middleware.ActivityMiddleware(activityService.RecordActivity))
+	api.Use(middleware.RateLimitMiddleware(requestLimiter))
+	api.Use(middleware.DemoRateLimitMiddleware(demoLimiter))
+	api.Use(termsAccepted)
+	api.Use(middleware.ProfileMiddleware(profileService.GetProfile))
+	api.Use(throttled)
 	{
-		api.GET("/movies/search", movieHandler.SearchMovies)
-		api.GET("/movies/:id", movieHandler.GetMovie)
 		api.GET("/movies/by-imdb", movieHandler.GetMovieByIMDbID)
+		api.POST("/movies/:id/progress", progressHandler.RecordProgress)
+		api.POST("/movies/:id/advisories", accountOnly, notInDemo, strictJSON, advisoryHandler.ReportAdvisory)
+		api.GET("/continue-watching", progressHandler.GetContinueWatching)
+		api.GET("/me/recently-viewed", movieHandler.GetRecentlyViewed)
+		api.GET("/me/search", libraryHandler.SearchLibrary)
+		api.GET("/discover", discoveryHandler.Discover)
 		api.POST("/watchlist", watchlistHandler.AddToWatchlist)
+		api.POST("/quick-add", middleware.RateLimitMiddleware(searchLimiter), quickAddHandler.QuickAdd)
+		api.POST("/assistant/intent", middleware.RateLimitMiddleware(searchLimiter), strictJSON, assistantHandler.HandleIntent)
 		api.DELETE("/watchlist/:movieId", watchlistHandler.RemoveFromWatchlist)
 		api.GET("/watchlist", watchlistHandler.GetWatchlist)
+		api.GET("/watchlist/tonight", watchlistHandler.GetTonightPicks)
+		api.GET("/watchlist/:movieId", watchlistHandler.GetWatchlistItem)
+		api.PATCH("/watchlist/:movieId", watchlistHandler.UpdateWatchlistItem)
+		api.POST("/watchlist/:movieId/watched", watchlistHandler.MarkWatched)
+		api.DELETE("/watchlist/:movieId/watched", watchlistHandler.MarkUnwatched)
+		api.POST("/undo", undoHandler.Undo)
 		api.POST("/ratings", ratingHandler.RateMovie)
+		api.POST("/ratings/import", accountOnly, notInDemo, ratingHandler.ImportRatings)
 		api.PUT("/ratings/:movieId", ratingHandler.UpdateRating)
 		api.GET("/ratings", ratingHandler.GetUserRatings)
+		api.GET("/ratings/:movieId", ratingHandler.GetRating)
+		api.PUT("/movies/:id/review", accountOnly, notInDemo, strictJSON, reviewHandler.WriteReview)
+		api.DELETE("/reviews/:id", accountOnly, reviewHandler.DeleteReview)
+		api.PUT("/reviews/:id/vote", accountOnly, notInDemo, reviewHandler.VoteHelpful)
+		api.DELETE("/reviews/:id/vote", accountOnly, notInDemo, reviewHandler.UnvoteHelpful)
 		api.GET("/recommendations", recommendationHandler.GetRecommendations)
+		api.GET("/recommendations/following", recommendationHandler.GetFollowingRecommendations)
+		api.GET("/calendar", calendarHandler.GetCalendar)
+		api.GET("/follow/people", followHandler.GetFollows)
+		api.POST("/follow/person", followHandler.FollowPerson)
+		api.DELETE("/follow/person/:id", followHandler.UnfollowPerson)
+		api.POST("/share", accountOnly, notInDemo, strictJSON, shareHandler.ShareMovie)
+		api.GET("/inbox", accountOnly, shareHandler.GetInbox)
+		api.POST("/polls", accountOnly, notInDemo, strictJSON, pollHandler.CreatePoll)
+		api.GET("/polls", accountOnly, pollHandler.GetPolls)
+		api.GET("/polls/:id", accountOnly, pollHandler.GetPoll)
+		api.PUT("/polls/:id", accountOnly, strictJSON, pollHandler.UpdatePoll)
+		api.DELETE("/polls/:id", accountOnly, pollHandler.DeletePoll)
+		api.POST("/polls/:id/close", accountOnly, pollHandler.ClosePoll)
+		api.PUT("/polls/:id/vote", accountOnly, strictJSON, pollHandler.VotePoll)
+		api.GET("/polls/:id/results", accountOnly, pollHandler.GetPollResults)
+		api.POST("/me/email", accountOnly, notInDemo, strictJSON, accountHandler.RequestEmailChange)
+		api.GET("/me/device-pairings/:code", accountOnly, devicePairingHandler.GetDevicePairing)
+		api.POST("/me/device-pairings/:code/approve", accountOnly, notInDemo, devicePairingHandler.ApproveDevicePairing)
+		api.POST("/me/device-pairings/:code/deny", accountOnly, devicePairingHandler.DenyDevicePairing)
+		api.GET("/me/apps", accountOnly, oauthAppHandler.GetOAuthApps)
+		api.POST("/me/apps", accountOnly, notInDemo, strictJSON, oauthAppHandler.CreateOAuthApp)
+		api.GET("/me/apps/:clientId", accountOnly, oauthAppHandler.GetOAuthApp)
+		api.PUT("/me/apps/:clientId", accountOnly, strictJSON, oauthAppHandler.UpdateOAuthApp)
+		api.POST("/me/apps/:clientId/secret", accountOnly, oauthAppHandler.RotateOAuthAppSecret)
+		api.DELETE("/me/apps/:clientId", accountOnly, oauthAppHandler.DeleteOAuthApp)
+		api.GET("/oauth/authorize", accountOnly, oauthAppHandler.GetAuthorization)
+		api.POST("/oauth/authorize", accountOnly, notInDemo, strictJSON, oauthAppHandler.Authorize)
+		api.POST("/me/deactivate", accountOnly, notInDemo, strictJSON, accountHandler.Deactivate)
+		api.POST("/me/accept-terms", accountOnly, notInDemo, strictJSON, termsHandler.AcceptTerms)
+		api.POST("/me/import/archive", accountOnly, notInDemo, archiveHandler.ImportArchive)
+		api.GET("/me/export/archive", accountOnly, exportHandler.ExportArchive)
+		api.GET("/me/export/ratings", exportHandler.ExportRatings)
+		api.GET("/me/export/watchlist", exportHandler.ExportWatchlist)
+		api.GET("/me/quota", accountOnly, quotaHandler.GetQuota)
+		api.GET("/me/advisories", accountOnly, advisoryHandler.GetAvoided)
+		api.PUT("/me/advisories", accountOnly, strictJSON, advisoryHandler.UpdateAvoided)
+		api.GET("/me/languages", accountOnly, accountHandler.GetLanguages)
+		api.PUT("/me/languages", accountOnly, strictJSON, accountHandler.UpdateLanguages)
+		api.GET("/me/timezone", accountOnly, accountHandler.GetTimezone)
+		api.PUT("/me/timezone", accountOnly, strictJSON, accountHandler.UpdateTimezone)
+		api.GET("/me/recommendation-settings", accountOnly, recommendationHandler.GetRecommendationSettings)
+		api.PUT("/me/recommendation-settings", accountOnly, strictJSON, recommendationHandler.UpdateRecommendationSettings)
+		api.GET("/me/stats", habitHandler.GetStats)
+		api.GET("/me/dashboard", dashboardHandler.GetDashboard)
+		api.PUT("/me/goals", accountOnly, strictJSON, habitHandler.UpdateGoals)
+		api.GET("/me/achievements", accountOnly, achievementHandler.GetAchievements)
+		api.PUT("/me/achievements/visibility", accountOnly, strictJSON, achievementHandler.UpdateVisibility)
+		api.PUT("/me/compatibility/visibility", accountOnly, strictJSON, compatibilityHandler.UpdateVisibility)
+		api.PUT("/me/ratings/visibility", accountOnly, strictJSON, ratingFeedHandler.UpdateVisibility)
+		api.GET("/users/:username/compatibility", accountOnly, compatibilityHandler.GetCompatibility)
+		api.GET("/me/notifications", notificationHandler.GetNotifications)
+		api.POST("/me/notifications/:id/read", notificationHandler.MarkNotificationRead)
+		api.GET("/me/sessions", accountOnly, sessionHandler.GetSessions)
+		api.DELETE("/me/sessions", accountOnly, sessionHandler.RevokeAllSessions)
+		api.GET("/me/security/logins", accountOnly, securityHandler.GetLogins)
+		api.DELETE("/me/sessions/:id", accountOnly, sessionHandler.RevokeSession)
+		api.GET("/me/profiles", accountOnly, profileHandler.GetProfiles)
+		api.POST("/me/profiles", accountOnly, strictJSON, profileHandler.CreateProfile)
+		api.PATCH("/me/profiles/:id", accountOnly, strictJSON, profileHandler.UpdateProfile)
+		api.DELETE("/me/profiles/:id", accountOnly, profileHandler.DeleteProfile)
+		api.POST("/me/calendar-feed", accountOnly, calendarFeedHandler.CreateCalendarFeed)
+		api.DELETE("/me/calendar-feed", accountOnly, calendarFeedHandler.DeleteCalendarFeed)
+		if traktService.Enabled() {
+			api.GET("/me/integrations/trakt", accountOnly, traktHandler.GetTrakt)
+			api.POST("/me/integrations/trakt/authorize", accountOnly, notInDemo, traktHandler.AuthorizeTrakt)
+			api.PUT("/me/integrations/trakt", accountOnly, strictJSON, traktHandler.UpdateTrakt)
+			api.POST("/me/integrations/trakt/sync", accountOnly, notInDemo, traktHandler.SyncTrakt)
+			api.DELETE("/me/integrations/trakt", accountOnly, traktHandler.UnlinkTrakt)
+		}
+		if cfg.ChatWebhooks {
+			api.GET("/me/integrations/chat-webhook", accountOnly, chatWebhookHandler.GetChatWebhook)
+			api.PUT("/me/integrations/chat-webhook", accountOnly, notInDemo, strictJSON, chatWebhookHandler.SetChatWebhook)
+			api.POST("/me/integrations/chat-webhook/test", accountOnly, notInDemo, chatWebhookHandler.TestChatWebhook)
+			api.DELETE("/me/integrations/chat-webhook", accountOnly, chatWebhookHandler.DeleteChatWebhook)
+		}
+		if slackCommandService.Enabled() {
+			api.GET("/me/integrations/slack", accountOnly, slackHandler.GetSlackLink)
+			api.POST("/me/integrations/slack/link-code", accountOnly, notInDemo, slackHandler.CreateSlackLinkCode)
+			api.DELETE("/me/integrations/slack", accountOnly, slackHandler.UnlinkSlack)
+		}
+		if telegramBotService.Enabled() {
+			api.GET("/me/integrations/telegram", accountOnly, telegramHandler.GetTelegramLink)
+			api.POST("/me/integrations/telegram/link-code", accountOnly, notInDemo, telegramHandler.CreateTelegramLinkCode)
+			api.PUT("/me/integrations/telegram", accountOnly, strictJSON, telegramHandler.UpdateTelegramLink)
+			api.DELETE("/me/integrations/telegram", accountOnly, telegramHandler.UnlinkTelegram)
+		}
+		if inboundEmailService.Enabled() {
+			api.POST("/me/inbound-email", accountOnly, notInDemo, inboundEmailHandler.CreateInboundAddress)
+			api.DELETE("/me/inbound-email", accountOnly, inboundEmailHandler.DeleteInboundAddress)
+		}
+	}
+
+	admin := api.Group("/admin")
+	admin.Use(accountOnly)
+	admin.Use(middleware.AdminMiddleware(policy))
+	{
+		admin.GET("/stats", adminHandler.GetStats)
+		admin.GET("/users", adminHandler.GetUsers)
+		admin.GET("/users/export", exportHandler.ExportUsers)
+		admin.GET("/users/:id/storage-quota", quotaHandler.GetUserStorageQuota)
+		admin.PUT("/users/:id/storage-quota", quotaHandler.SetUserStorageQuota)
+		admin.DELETE("/users/:id/storage-quota", quotaHandler.ResetUserStorageQuota)
+		admin.GET("/flags", anomalyHandler.GetFlags)
+		admin.POST("/flags/:id/dismiss", anomalyHandler.DismissFlag)
+		admin.POST("/flags/:id/confirm", anomalyHandler.ConfirmFlag)
+		admin.GET("/advisory-reports", advisoryHandler.GetReports)
+		admin.POST("/advisory-reports/:id/approve", advisoryHandler.ApproveReport)
+		admin.POST("/advisory-reports/:id/reject", advisoryHandler.RejectReport)
+		admin.GET("/omdb-usage", adminHandler.GetOMDbUsage)
+		admin.GET("/recommendations/evaluations", adminHandler.GetRecommendationEvaluations)
+		admin.POST("/recommendations/evaluations", adminHandler.RunRecommendationEvaluation)
+		admin.GET("/recommendations/analytics", adminHandler.GetRecommendationAnalytics)
+		admin.GET("/recommendations/explain", adminHandler.ExplainRecommendations)
+		admin.GET("/jobs", adminHandler.GetJobsStatus)
+		admin.POST("/jobs/:id/retry", adminHandler.RetryJob)
+		admin.POST("/encryption/rotate", adminHandler.RotateEncryptionKeys)
+		admin.GET("/movies/:id/history", adminHandler.GetMovieHistory)
+		admin.GET("/reconciliation", adminHandler.GetReconciliationReport)
+		admin.GET("/reconciliation/conflicts", adminHandler.GetConflicts)
+		admin.POST("/reconciliation/:id/accept", adminHandler.AcceptConflict)
+		admin.POST("/reconciliation/:id/override", adminHandler.OverrideConflict)
+		admin.GET("/deprecations", deprecationHandler.GetDeprecations)
+		admin.PUT("/deprecations", strictJSON, deprecationHandler.UpdateDeprecation)
+	}
+
+	publicV2 := r.Group("/api/v2")
+	publicV2.Use(middleware.OptionalAuthMiddleware(tokens))
+	publicV2.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	publicV2.Use(middleware.AppMiddleware(oauthAppService.IsActive))
+	publicV2.Use(middleware.ActivityMiddleware(activityService.RecordActivity))
+	publicV2.Use(middleware.RateLimitMiddleware(requestLimiter))
+	publicV2.Use(middleware.DemoRateLimitMiddleware(demoLimiter))
+	publicV2.Use(middleware.ProfileMiddleware(profileService.GetProfile))
+	{
+		publicV2.GET("/movies/search", middleware.RateLimitMiddleware(searchLimiter), v2Handler.SearchMovies)
+		publicV2.GET("/movies/trending", v2Handler.GetTrendingMovies)
+		publicV2.GET("/movies/:id", v2Handler.GetMovie)
+	}
+
+	// v2 uses the cleaned-up representations; continue watching and tonight
+	// picks are still shared with v1
+	v2 := r.Group("/api/v2")
+	v2.Use(middleware.AuthMiddleware(tokens))
+	v2.Use(middleware.SessionMiddleware(sessionService.IsActive))
+	v2.Use(middleware.AppScopeMiddleware())
+	v2.Use(middleware.ActivityMiddleware(activityService.RecordActivity))
+	v2.Use(middleware.RateLimitMiddleware(requestLimiter))
+	v2.Use(middleware.DemoRateLimitMiddleware(demoLimiter))
+	v2.Use(termsAccepted)
+	v2.Use(middleware.ProfileMiddleware(profileService.GetProfile))
+	v2.Use(throttled)
+	{
+		v2.GET("/movies/by-imdb", v2Handler.GetMovieByIMDbID)
+		v2.POST("/movies/:id/progress", v2Handler.RecordProgress)
+		v2.GET("/continue-watching", progressHandler.GetContinueWatching)
+		v2.POST("/watchlist", v2Handler.AddToWatchlist)
+		v2.DELETE("/watchlist/:movieId", v2Handler.RemoveFromWatchlist)
+		v2.GET("/watchlist", v2Handler.GetWatchlist)
+		v2.GET("/watchlist/tonight", watchlistHandler.GetTonightPicks)
+		v2.GET("/watchlist/:movieId", v2Handler.GetWatchlistItem)
+		v2.PATCH("/watchlist/:movieId", v2Handler.UpdateWatchlistItem)
+		v2.POST("/watchlist/:movieId/watched", v2Handler.MarkWatched)
+		v2.DELETE("/watchlist/:movieId/watched", v2Handler.MarkUnwatched)
+		v2.POST("/undo", v2Handler.Undo)
+		v2.POST("/ratings", v2Handler.RateMovie)
+		v2.PUT("/ratings/:movieId", v2Handler.UpdateRating)
+		v2.GET("/ratings", v2Handler.GetRatings)
+		v2.GET("/ratings/:movieId", v2Handler.GetRating)
+		v2.GET("/recommendations", v2Handler.GetRecommendations)
+	}
+	registerV1Deprecations(r.Routes(), deprecationService)
+
+	logger.Info("server starting", "port", cfg.Port, "tls", cfg.TLSEnabled(), "unix_socket", cfg.UnixSocket, "admin_listen", cfg.AdminListen)
+	if err := serve(r.Handler(), cfg); err != nil {
+		logger.Error("failed to start server", "error", err)
+		os.Exit(1)
+	}
+}
+
+// registerV1Deprecations lets admins deprecate each v1 route that has a v2
+// counterpart, which becomes its successor
+func registerV1Deprecations(routes gin.RoutesInfo, deprecations *services.DeprecationService) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/v2/") {
+			continue
+		}
+		v1Path := "/api/v1/" + strings.TrimPrefix(route.Path, "/api/v2/")
+		if registered[route.Method+" "+v1Path] {
+			deprecations.Register(route.Method, v1Path, route.Path)
+		}
+	}
+}
+
+// adminPrefix is the path of the routes moved to ADMIN_LISTEN
+const adminPrefix = "/api/v1/admin"
+
+// unixPeerAddr stands in for the remote address of Unix socket connections,
+// which have none, so the proxy on the other end can be listed in
+// TRUSTED_PROXIES
+const unixPeerAddr = "127.0.0.1:0"
+
+// serve listens on PORT, terminating TLS when a certificate file or
+// Let's Encrypt domains are configured. TLS connections negotiate HTTP/2.
+// UNIX_SOCKET and ADMIN_LISTEN add listeners next to it; all listeners are
+// opened before any is served, and serve returns when the first one fails.
+func serve(handler http.Handler, cfg *config.Config) error {
+	public := handler
+	if cfg.AdminListen != "" {
+		public = adminRoutes(handler, false)
+	}
+
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		return err
+	}
+	server := newServer(public)
+	errs := make(chan error, 4)
+
+	if cfg.UnixSocket != "" {
+		socket, err := listenUnix(cfg.UnixSocket)
+		if err != nil {
+			return err
+		}
+		go func() { errs <- newServer(fromUnixSocket(public)).Serve(socket) }()
+	}
+
+	if cfg.AdminListen != "" {
+		admin, err := listenAdmin(cfg.AdminListen)
+		if err != nil {
+			return err
+		}
+		adminHandler := adminRoutes(handler, true)
+		if admin.Addr().Network() == "unix" {
+			adminHandler = fromUnixSocket(adminHandler)
+		}
+		go func() { errs <- newServer(adminHandler).Serve(admin) }()
+	}
+
+	if !cfg.TLSEnabled() {
+		go func() { errs <- server.Serve(listener) }()
+		return <-errs
+	}
+
+	redirect := http.HandlerFunc(redirectToHTTPS(cfg.Port))
+	var plain http.Handler = redirect
+	if len(cfg.TLSAutocertDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		// Offers h2 and answers TLS-ALPN challenges on PORT, so Let's Encrypt
+		// works without HTTP_REDIRECT_PORT when PORT is 443
+		server.TLSConfig = manager.TLSConfig()
+		plain = manager.HTTPHandler(redirect)
+	} else {
+		server.TLSConfig = &tls.Config{}
+	}
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+
+	if cfg.HTTPRedirectPort != "" {
+		go func() {
+			plainServer := newServer(plain)
+			plainServer.Addr = ":" + cfg.HTTPRedirectPort
+			if err := plainServer.ListenAndServe(); err != nil {
+				logging.For("main").Error("HTTP redirect server stopped", "port", cfg.HTTPRedirectPort, "error", err)
+			}
+		}()
+	}
+
+	go func() { errs <- server.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile) }()
+	return <-errs
+}
+
+func newServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+}
+
+// listenAdmin listens on ADMIN_LISTEN, a host:port or unix:/path
+func listenAdmin(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix listens on a Unix socket at path, replacing a socket left behind
+// by a previous run. The socket is readable and writable by the owner and
+// group only.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, errors.New(path + " exists and is not a socket")
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// adminRoutes serves only the admin routes when admin is true, and
+// everything except them otherwise, answering other paths with 404
+func adminRoutes(handler http.Handler, admin bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		isAdmin := path == adminPrefix || strings.HasPrefix(path, adminPrefix+"/")
+		if isAdmin != admin {
+			http.NotFound(w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// fromUnixSocket gives requests on a Unix socket a loopback remote address
+func fromUnixSocket(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.RemoteAddr = unixPeerAddr
+		handler.ServeHTTP(w, req)
+	})
+}
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on port
+func redirectToHTTPS(port string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, req, "https://"+host+req.URL.RequestURI(), http.StatusMovedPermanently)
 	}
 }